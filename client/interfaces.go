@@ -81,6 +81,7 @@ type InstanceServer interface {
 	GetCertificates() (certificates []api.Certificate, err error)
 	GetCertificate(fingerprint string) (certificate *api.Certificate, ETag string, err error)
 	CreateCertificate(certificate api.CertificatesPost) (err error)
+	CreateCertificateToken(certificate api.CertificatesPost) (op Operation, err error)
 	UpdateCertificate(fingerprint string, certificate api.CertificatePut, ETag string) (err error)
 	DeleteCertificate(fingerprint string) (err error)
 
@@ -182,10 +183,12 @@ type InstanceServer interface {
 	RenameInstanceBackup(instanceName string, name string, backup api.InstanceBackupPost) (op Operation, err error)
 	DeleteInstanceBackup(instanceName string, name string) (op Operation, err error)
 	GetInstanceBackupFile(instanceName string, name string, req *BackupFileRequest) (resp *BackupFileResponse, err error)
+	VerifyInstanceBackup(instanceName string, name string) (op Operation, err error)
 	CreateInstanceFromBackup(args InstanceBackupArgs) (op Operation, err error)
 
 	GetInstanceState(name string) (state *api.InstanceState, ETag string, err error)
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
+	UpdateInstances(state api.InstancesPut, ETag string) (op Operation, err error)
 
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
 	GetInstanceLogfile(name string, filename string) (content io.ReadCloser, err error)
@@ -220,12 +223,48 @@ type InstanceServer interface {
 	GetNetworks() (networks []api.Network, err error)
 	GetNetwork(name string) (network *api.Network, ETag string, err error)
 	GetNetworkLeases(name string) (leases []api.NetworkLease, err error)
+	CreateNetworkLease(name string, lease api.NetworkLeasesPost) (err error)
+	DeleteNetworkLease(name string, address string) (err error)
 	GetNetworkState(name string) (state *api.NetworkState, err error)
 	CreateNetwork(network api.NetworksPost) (err error)
 	UpdateNetwork(name string, network api.NetworkPut, ETag string) (err error)
 	RenameNetwork(name string, network api.NetworkPost) (err error)
 	DeleteNetwork(name string) (err error)
 
+	// Network forward functions ("network_forward" API extension)
+	GetNetworkForwardAddresses(networkName string) (addresses []string, err error)
+	GetNetworkForwards(networkName string) (forwards []api.NetworkForward, err error)
+	GetNetworkForward(networkName string, listenAddress string) (forward *api.NetworkForward, ETag string, err error)
+	CreateNetworkForward(networkName string, forward api.NetworkForwardsPost) (err error)
+	UpdateNetworkForward(networkName string, listenAddress string, forward api.NetworkForwardPut, ETag string) (err error)
+	DeleteNetworkForward(networkName string, listenAddress string) (err error)
+
+	// Network load balancer functions ("network_load_balancer" API extension)
+	GetNetworkLoadBalancerAddresses(networkName string) (addresses []string, err error)
+	GetNetworkLoadBalancers(networkName string) (loadBalancers []api.NetworkLoadBalancer, err error)
+	GetNetworkLoadBalancer(networkName string, listenAddress string) (loadBalancer *api.NetworkLoadBalancer, ETag string, err error)
+	GetNetworkLoadBalancerState(networkName string, listenAddress string) (state *api.NetworkLoadBalancerState, err error)
+	CreateNetworkLoadBalancer(networkName string, loadBalancer api.NetworkLoadBalancersPost) (err error)
+	UpdateNetworkLoadBalancer(networkName string, listenAddress string, loadBalancer api.NetworkLoadBalancerPut, ETag string) (err error)
+	DeleteNetworkLoadBalancer(networkName string, listenAddress string) (err error)
+
+	// Network ACL functions ("network_acl" API extension)
+	GetNetworkACLNames() (names []string, err error)
+	GetNetworkACLs() (acls []api.NetworkACL, err error)
+	GetNetworkACL(name string) (acl *api.NetworkACL, ETag string, err error)
+	CreateNetworkACL(acl api.NetworkACLsPost) (err error)
+	UpdateNetworkACL(name string, acl api.NetworkACLPut, ETag string) (err error)
+	RenameNetworkACL(name string, acl api.NetworkACLPost) (err error)
+	DeleteNetworkACL(name string) (err error)
+
+	// Network zone functions ("network_dns" API extension)
+	GetNetworkZoneNames() (names []string, err error)
+	GetNetworkZones() (zones []api.NetworkZone, err error)
+	GetNetworkZone(name string) (zone *api.NetworkZone, ETag string, err error)
+	CreateNetworkZone(zone api.NetworkZonesPost) (err error)
+	UpdateNetworkZone(name string, zone api.NetworkZonePut, ETag string) (err error)
+	DeleteNetworkZone(name string) (err error)
+
 	// Operation functions
 	GetOperationUUIDs() (uuids []string, err error)
 	GetOperations() (operations []api.Operation, err error)
@@ -239,8 +278,10 @@ type InstanceServer interface {
 	GetProfileNames() (names []string, err error)
 	GetProfiles() (profiles []api.Profile, err error)
 	GetProfile(name string) (profile *api.Profile, ETag string, err error)
+	GetProfileExpanded(name string) (profile *api.Profile, ETag string, err error)
 	CreateProfile(profile api.ProfilesPost) (err error)
 	UpdateProfile(name string, profile api.ProfilePut, ETag string) (err error)
+	UpdateProfileDryRun(name string, profile api.ProfilePut, ETag string) (impacts []api.ProfileInstanceImpact, err error)
 	RenameProfile(name string, profile api.ProfilePost) (err error)
 	DeleteProfile(name string) (err error)
 
@@ -248,10 +289,12 @@ type InstanceServer interface {
 	GetProjectNames() (names []string, err error)
 	GetProjects() (projects []api.Project, err error)
 	GetProject(name string) (project *api.Project, ETag string, err error)
+	GetProjectState(name string) (project *api.ProjectState, err error)
 	CreateProject(project api.ProjectsPost) (err error)
 	UpdateProject(name string, project api.ProjectPut, ETag string) (err error)
 	RenameProject(name string, project api.ProjectPost) (op Operation, err error)
 	DeleteProject(name string) (err error)
+	DeleteProjectForce(name string) (op Operation, err error)
 
 	// Storage pool functions ("storage" API extension)
 	GetStoragePoolNames() (names []string, err error)
@@ -292,6 +335,24 @@ type InstanceServer interface {
 	GetClusterMember(name string) (member *api.ClusterMember, ETag string, err error)
 	UpdateClusterMember(name string, member api.ClusterMemberPut, ETag string) (err error)
 	RenameClusterMember(name string, member api.ClusterMemberPost) (err error)
+	CreateClusterMember(member api.ClusterMembersPost) (op Operation, err error)
+	UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (op Operation, err error)
+	GetClusterUpgrade() (upgrade *api.ClusterUpgrade, err error)
+
+	// Cluster group functions ("cluster_groups" API extension)
+	GetClusterGroupNames() (names []string, err error)
+	GetClusterGroups() (groups []api.ClusterGroup, err error)
+	GetClusterGroup(name string) (group *api.ClusterGroup, ETag string, err error)
+	CreateClusterGroup(group api.ClusterGroupsPost) (err error)
+	UpdateClusterGroup(name string, group api.ClusterGroupPut, ETag string) (err error)
+	DeleteClusterGroup(name string) (err error)
+
+	// Warning functions ("warnings" API extension)
+	GetWarningUUIDs() (uuids []string, err error)
+	GetWarnings() (warnings []api.Warning, err error)
+	GetWarning(UUID string) (warning *api.Warning, ETag string, err error)
+	UpdateWarning(UUID string, warning api.WarningPut, ETag string) (err error)
+	DeleteWarning(UUID string) (err error)
 
 	// Internal functions (for internal use)
 	RawQuery(method string, path string, data interface{}, queryETag string) (resp *api.Response, ETag string, err error)
@@ -365,6 +426,14 @@ type ImageFileRequest struct {
 	// Path retriever for image delta downloads
 	// If set, it must return the path to the image file or an empty string if not available
 	DeltaSourceRetriever func(fingerprint string, file string) string
+
+	// Fingerprints of the local images which may be used as the source of a delta (LXD only)
+	// Requires DeltaSourceRetriever to be set
+	DeltaSources []string
+
+	// Format to retrieve the image in, either "unified" or "split" (LXD only)
+	// Converted images don't match their fingerprint
+	Format string
 }
 
 // The ImageFileResponse struct is used as the response for image downloads.
@@ -429,6 +498,9 @@ type InstanceBackupArgs struct {
 
 	// Storage pool to use
 	PoolName string
+
+	// Whether to check the backup against its checksum manifest before restoring it
+	Verify bool
 }
 
 // The InstanceCopyArgs struct is used to pass additional options during instance copy.
@@ -448,6 +520,14 @@ type InstanceCopyArgs struct {
 	// API extension: container_incremental_copy
 	// Perform an incremental copy
 	Refresh bool
+
+	// API extension: migration_resume
+	// Keep the data transferred so far on failure so that the copy can be retried with Refresh
+	Resumable bool
+
+	// API extension: migration_bandwidth_limit
+	// Maximum transfer rate of the migration (e.g. 10MB), lowered to the source server's limit if any
+	BandwidthLimit string
 }
 
 // The InstanceSnapshotCopyArgs struct is used to pass additional options during instance copy.
@@ -141,3 +141,46 @@ func (r *ProtocolLXD) RenameClusterMember(name string, member api.ClusterMemberP
 
 	return nil
 }
+
+// CreateClusterMember generates a join token to add a cluster member
+func (r *ProtocolLXD) CreateClusterMember(member api.ClusterMembersPost) (Operation, error) {
+	if !r.HasExtension("clustering_join_token") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/members", member, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// UpdateClusterMemberState evacuates or restores a cluster member
+func (r *ProtocolLXD) UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (Operation, error) {
+	if !r.HasExtension("clustering_evacuation") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_evacuation\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/cluster/members/%s/state", name), state, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// GetClusterUpgrade returns the progress of a rolling upgrade of the cluster
+func (r *ProtocolLXD) GetClusterUpgrade() (*api.ClusterUpgrade, error) {
+	if !r.HasExtension("clustering_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_upgrade\" API extension")
+	}
+
+	upgrade := api.ClusterUpgrade{}
+	_, err := r.queryStruct("GET", "/cluster/upgrade", nil, "", &upgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upgrade, nil
+}
@@ -0,0 +1,112 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetClusterGroupNames returns a list of cluster group names
+func (r *ProtocolLXD) GetClusterGroupNames() ([]string, error) {
+	if !r.HasExtension("cluster_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/cluster/groups", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/cluster/groups/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetClusterGroups returns a list of ClusterGroup struct
+func (r *ProtocolLXD) GetClusterGroups() ([]api.ClusterGroup, error) {
+	if !r.HasExtension("cluster_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	groups := []api.ClusterGroup{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/cluster/groups?recursion=1", nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetClusterGroup returns a ClusterGroup entry for the provided name
+func (r *ProtocolLXD) GetClusterGroup(name string) (*api.ClusterGroup, string, error) {
+	if !r.HasExtension("cluster_groups") {
+		return nil, "", fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	group := api.ClusterGroup{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
+// CreateClusterGroup defines a new cluster group using the provided ClusterGroup struct
+func (r *ProtocolLXD) CreateClusterGroup(group api.ClusterGroupsPost) error {
+	if !r.HasExtension("cluster_groups") {
+		return fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", "/cluster/groups", group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateClusterGroup updates the cluster group to match the provided ClusterGroup struct
+func (r *ProtocolLXD) UpdateClusterGroup(name string, group api.ClusterGroupPut, ETag string) error {
+	if !r.HasExtension("cluster_groups") {
+		return fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), group, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteClusterGroup deletes an existing cluster group
+func (r *ProtocolLXD) DeleteClusterGroup(name string) error {
+	if !r.HasExtension("cluster_groups") {
+		return fmt.Errorf("The server is missing the required \"cluster_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
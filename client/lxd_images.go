@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/lxc/lxd/shared"
@@ -117,6 +118,17 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		return nil, err
 	}
 
+	if req.Format != "" {
+		if !r.HasExtension("image_export_format") {
+			return nil, fmt.Errorf("The server is missing the required \"image_export_format\" API extension")
+		}
+
+		uri, err = setQueryParam(uri, "format", req.Format)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Attempt to download from host
 	if secret == "" && shared.PathExists("/dev/lxd/sock") && os.Geteuid() == 0 {
 		unixURI := fmt.Sprintf("http://unix.socket%s", uri)
@@ -140,6 +152,22 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		}
 	}
 
+	// Offer the images we already have as a base for a delta of the root filesystem
+	if len(req.DeltaSources) > 0 && req.DeltaSourceRetriever != nil && r.HasExtension("image_delta_transfer") {
+		_, err := exec.LookPath("xdelta3")
+		if err == nil {
+			fields, err := url.Parse(uri)
+			if err != nil {
+				return nil, err
+			}
+
+			values := fields.Query()
+			values.Set("delta_from", strings.Join(req.DeltaSources, ","))
+			fields.RawQuery = values.Encode()
+			uri = fields.String()
+		}
+	}
+
 	return lxdDownloadImage(fingerprint, uri, r.httpUserAgent, r.http, req)
 }
 
@@ -189,20 +217,37 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 
 		if response.ContentLength > 0 {
 			reader.Tracker.Handler = func(percent int64, speed int64) {
-				req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
+				req.ProgressHandler(ioprogress.ProgressData{
+					Text:             fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2)),
+					Percentage:       int(percent),
+					TransferredBytes: reader.Tracker.Transferred(),
+					TotalBytes:       response.ContentLength,
+					Speed:            speed,
+				})
 			}
 		} else {
 			reader.Tracker.Handler = func(received int64, speed int64) {
-				req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(received, 2), units.GetByteSizeString(speed, 2))})
-
+				req.ProgressHandler(ioprogress.ProgressData{
+					Text:             fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(received, 2), units.GetByteSizeString(speed, 2)),
+					TransferredBytes: received,
+					Speed:            speed,
+				})
 			}
 		}
 
 		body = reader
 	}
 
-	// Hashing
+	// Hashing, images converted to the requested format no longer match their fingerprint and are checked
+	// against the hash of the converted image sent by the server instead.
 	sha256 := sha256.New()
+	expectedHash := fingerprint
+	if req.Format != "" && response.Header.Get("X-LXD-Export-Format") != "" {
+		expectedHash = response.Header.Get("X-LXD-Export-SHA256")
+		if expectedHash == "" {
+			return nil, fmt.Errorf("Missing hash of the converted image")
+		}
+	}
 
 	// Deal with split images
 	if ctype == "multipart/form-data" {
@@ -236,11 +281,15 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 			return nil, err
 		}
 
-		if !shared.StringInSlice(part.FormName(), []string{"rootfs", "rootfs.img"}) {
+		if !shared.StringInSlice(part.FormName(), []string{"rootfs", "rootfs.img", "rootfs.delta"}) {
 			return nil, fmt.Errorf("Invalid multipart image")
 		}
 
-		size, err = io.Copy(io.MultiWriter(req.RootfsFile, sha256), part)
+		if part.FormName() == "rootfs.delta" {
+			size, err = lxdApplyImageDelta(response.Header.Get("X-LXD-Delta-Base"), part, io.MultiWriter(req.RootfsFile, sha256), req)
+		} else {
+			size, err = io.Copy(io.MultiWriter(req.RootfsFile, sha256), part)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -249,8 +298,8 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 
 		// Check the hash
 		hash := fmt.Sprintf("%x", sha256.Sum(nil))
-		if !strings.HasPrefix(hash, fingerprint) {
-			return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
+		if !strings.HasPrefix(hash, expectedHash) {
+			return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, expectedHash)
 		}
 
 		return &resp, nil
@@ -276,13 +325,54 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 
 	// Check the hash
 	hash := fmt.Sprintf("%x", sha256.Sum(nil))
-	if !strings.HasPrefix(hash, fingerprint) {
-		return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
+	if !strings.HasPrefix(hash, expectedHash) {
+		return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, expectedHash)
 	}
 
 	return &resp, nil
 }
 
+// lxdApplyImageDelta applies a root filesystem delta against the given local image and writes the result to target.
+func lxdApplyImageDelta(base string, delta io.Reader, target io.Writer, req ImageFileRequest) (int64, error) {
+	if req.DeltaSourceRetriever == nil || !shared.StringInSlice(base, req.DeltaSources) {
+		return -1, fmt.Errorf("Received a delta against unknown image %q", base)
+	}
+
+	srcPath := req.DeltaSourceRetriever(base, "rootfs")
+	if srcPath == "" {
+		return -1, fmt.Errorf("Source image %q of the delta isn't available", base)
+	}
+
+	// Store the delta in a temporary file
+	deltaFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer deltaFile.Close()
+	defer os.Remove(deltaFile.Name())
+
+	_, err = io.Copy(deltaFile, delta)
+	if err != nil {
+		return -1, err
+	}
+
+	// Create temporary file for the patched rootfs
+	patchedFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer patchedFile.Close()
+	defer os.Remove(patchedFile.Name())
+
+	// Apply it
+	_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
+	if err != nil {
+		return -1, err
+	}
+
+	return io.Copy(target, patchedFile)
+}
+
 // GetImageAliases returns the list of available aliases as ImageAliasesEntry structs
 func (r *ProtocolLXD) GetImageAliases() ([]api.ImageAliasesEntry, error) {
 	aliases := []api.ImageAliasesEntry{}
@@ -356,6 +446,18 @@ func (r *ProtocolLXD) GetImageAliasArchitectures(imageType string, name string)
 		return nil, err
 	}
 
+	// Aliases pointing to an image for each architecture
+	if len(alias.Architectures) > 0 {
+		entries := map[string]*api.ImageAliasesEntry{}
+		for architecture, fingerprint := range alias.Architectures {
+			entry := *alias
+			entry.Target = fingerprint
+			entries[architecture] = &entry
+		}
+
+		return entries, nil
+	}
+
 	img, _, err := r.GetImage(alias.Target)
 	if err != nil {
 		return nil, err
@@ -372,6 +474,14 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		}
 	}
 
+	if image.Diff && !r.HasExtension("image_publish_diff") {
+		return nil, fmt.Errorf("The server is missing the required \"image_publish_diff\" API extension")
+	}
+
+	if image.Source != nil && image.Source.Type == "oci" && !r.HasExtension("image_import_oci") {
+		return nil, fmt.Errorf("The server is missing the required \"image_import_oci\" API extension")
+	}
+
 	// Send the JSON based request
 	if args == nil {
 		op, _, err := r.queryOperation("POST", "/images", image, "")
@@ -830,6 +940,14 @@ func (r *ProtocolLXD) CopyImage(source ImageServer, image api.Image, args *Image
 
 // UpdateImage updates the image definition
 func (r *ProtocolLXD) UpdateImage(fingerprint string, image api.ImagePut, ETag string) error {
+	if image.Pinned && !r.HasExtension("image_alias_auto_update") {
+		return fmt.Errorf("The server is missing the required \"image_alias_auto_update\" API extension")
+	}
+
+	if len(image.Annotations) > 0 && !r.HasExtension("image_annotations") {
+		return fmt.Errorf("The server is missing the required \"image_annotations\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/images/%s", url.PathEscape(fingerprint)), image, ETag)
 	if err != nil {
@@ -878,6 +996,14 @@ func (r *ProtocolLXD) CreateImageSecret(fingerprint string) (Operation, error) {
 
 // CreateImageAlias sets up a new image alias
 func (r *ProtocolLXD) CreateImageAlias(alias api.ImageAliasesPost) error {
+	if alias.UpdateSource != nil && !r.HasExtension("image_alias_auto_update") {
+		return fmt.Errorf("The server is missing the required \"image_alias_auto_update\" API extension")
+	}
+
+	if len(alias.Architectures) > 0 && !r.HasExtension("image_alias_architectures") {
+		return fmt.Errorf("The server is missing the required \"image_alias_architectures\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/images/aliases", alias, "")
 	if err != nil {
@@ -889,6 +1015,14 @@ func (r *ProtocolLXD) CreateImageAlias(alias api.ImageAliasesPost) error {
 
 // UpdateImageAlias updates the image alias definition
 func (r *ProtocolLXD) UpdateImageAlias(name string, alias api.ImageAliasesEntryPut, ETag string) error {
+	if alias.UpdateSource != nil && !r.HasExtension("image_alias_auto_update") {
+		return fmt.Errorf("The server is missing the required \"image_alias_auto_update\" API extension")
+	}
+
+	if len(alias.Architectures) > 0 && !r.HasExtension("image_alias_architectures") {
+		return fmt.Errorf("The server is missing the required \"image_alias_architectures\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/images/aliases/%s", url.PathEscape(name)), alias, ETag)
 	if err != nil {
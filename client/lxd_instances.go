@@ -145,7 +145,7 @@ func (r *ProtocolLXD) CreateInstanceFromBackup(args InstanceBackupArgs) (Operati
 		return nil, err
 	}
 
-	if args.PoolName == "" {
+	if args.PoolName == "" && !args.Verify {
 		// Send the request
 		op, _, err := r.queryOperation("POST", path, args.BackupFile, "")
 		if err != nil {
@@ -155,10 +155,14 @@ func (r *ProtocolLXD) CreateInstanceFromBackup(args InstanceBackupArgs) (Operati
 		return op, nil
 	}
 
-	if !r.HasExtension("container_backup_override_pool") {
+	if args.PoolName != "" && !r.HasExtension("container_backup_override_pool") {
 		return nil, fmt.Errorf("The server is missing the required \"container_backup_override_pool\" API extension")
 	}
 
+	if args.Verify && !r.HasExtension("backup_verify") {
+		return nil, fmt.Errorf("The server is missing the required \"backup_verify\" API extension")
+	}
+
 	// Prepare the HTTP request
 	reqURL, err := r.setQueryAttributes(fmt.Sprintf("%s/1.0%s", r.httpHost, path))
 	if err != nil {
@@ -171,7 +175,13 @@ func (r *ProtocolLXD) CreateInstanceFromBackup(args InstanceBackupArgs) (Operati
 	}
 
 	req.Header.Set("Content-Type", "application/octet-stream")
-	req.Header.Set("X-LXD-pool", args.PoolName)
+	if args.PoolName != "" {
+		req.Header.Set("X-LXD-pool", args.PoolName)
+	}
+
+	if args.Verify {
+		req.Header.Set("X-LXD-verify", "true")
+	}
 
 	// Set the user agent
 	if r.httpUserAgent != "" {
@@ -396,6 +406,14 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 			}
 		}
 
+		if args.Resumable && !r.HasExtension("migration_resume") {
+			return nil, fmt.Errorf("The target server is missing the required \"migration_resume\" API extension")
+		}
+
+		if args.BandwidthLimit != "" && !source.HasExtension("migration_bandwidth_limit") {
+			return nil, fmt.Errorf("The source server is missing the required \"migration_bandwidth_limit\" API extension")
+		}
+
 		// Allow overriding the target name
 		if args.Name != "" {
 			req.Name = args.Name
@@ -405,6 +423,7 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 		req.Source.InstanceOnly = args.InstanceOnly
 		req.Source.ContainerOnly = args.InstanceOnly // For legacy servers.
 		req.Source.Refresh = args.Refresh
+		req.Source.Resumable = args.Resumable
 	}
 
 	if req.Source.Live {
@@ -464,6 +483,10 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 		InstanceOnly:  req.Source.InstanceOnly,
 	}
 
+	if args != nil {
+		sourceReq.BandwidthLimit = args.BandwidthLimit
+	}
+
 	// Push mode migration
 	if args != nil && args.Mode == "push" {
 		// Get target server connection information
@@ -666,6 +689,20 @@ func (r *ProtocolLXD) MigrateInstance(name string, instance api.InstancePost) (O
 		}
 	}
 
+	if instance.Pool != "" {
+		if !r.HasExtension("instance_pool_move") {
+			return nil, fmt.Errorf("The server is missing the required \"instance_pool_move\" API extension")
+		}
+	}
+
+	if instance.Project != "" && !r.HasExtension("instance_project_move") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_project_move\" API extension")
+	}
+
+	if instance.BandwidthLimit != "" && !r.HasExtension("migration_bandwidth_limit") {
+		return nil, fmt.Errorf("The server is missing the required \"migration_bandwidth_limit\" API extension")
+	}
+
 	// Sanity check
 	if !instance.Migration {
 		return nil, fmt.Errorf("Can't ask for a rename through MigrateInstance")
@@ -702,6 +739,10 @@ func (r *ProtocolLXD) ExecInstance(instanceName string, exec api.InstanceExecPos
 		if !r.HasExtension("container_exec_recording") {
 			return nil, fmt.Errorf("The server is missing the required \"container_exec_recording\" API extension")
 		}
+
+		if exec.WaitForWS && !r.HasExtension("exec_recording_websocket") {
+			return nil, fmt.Errorf("The server is missing the required \"exec_recording_websocket\" API extension")
+		}
 	}
 
 	if exec.User > 0 || exec.Group > 0 || exec.Cwd != "" {
@@ -1522,6 +1563,26 @@ func (r *ProtocolLXD) UpdateInstanceState(name string, state api.InstanceStatePu
 	return op, nil
 }
 
+// UpdateInstances updates the state of many instances at once.
+func (r *ProtocolLXD) UpdateInstances(state api.InstancesPut, ETag string) (Operation, error) {
+	if !r.HasExtension("instance_bulk_state_change") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_bulk_state_change\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("PUT", path, state, ETag)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // GetInstanceLogfiles returns a list of logfiles for the instance.
 func (r *ProtocolLXD) GetInstanceLogfiles(name string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -1886,6 +1947,10 @@ func (r *ProtocolLXD) ConsoleInstanceDynamic(instanceName string, console api.In
 		return nil, nil, fmt.Errorf("The server is missing the required \"console_vga_type\" API extension")
 	}
 
+	if console.Type == "vnc" && !r.HasExtension("console_vnc_type") {
+		return nil, nil, fmt.Errorf("The server is missing the required \"console_vnc_type\" API extension")
+	}
+
 	// Send the request.
 	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/console", path, url.PathEscape(instanceName)), console, "")
 	if err != nil {
@@ -2101,6 +2166,18 @@ func (r *ProtocolLXD) CreateInstanceBackup(instanceName string, backup api.Insta
 		return nil, fmt.Errorf("The server is missing the required \"container_backup\" API extension")
 	}
 
+	if backup.DeltaFrom != "" && !r.HasExtension("backup_delta") {
+		return nil, fmt.Errorf("The server is missing the required \"backup_delta\" API extension")
+	}
+
+	if backup.Target != "" && !r.HasExtension("backup_s3") {
+		return nil, fmt.Errorf("The server is missing the required \"backup_s3\" API extension")
+	}
+
+	if backup.BandwidthLimit != "" && !r.HasExtension("migration_bandwidth_limit") {
+		return nil, fmt.Errorf("The server is missing the required \"migration_bandwidth_limit\" API extension")
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/backups", path, url.PathEscape(instanceName)), backup, "")
 	if err != nil {
@@ -2150,6 +2227,26 @@ func (r *ProtocolLXD) DeleteInstanceBackup(instanceName string, name string) (Op
 	return op, nil
 }
 
+// VerifyInstanceBackup checks the content of a stored instance backup against its checksum manifest.
+func (r *ProtocolLXD) VerifyInstanceBackup(instanceName string, name string) (Operation, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("backup_verify") {
+		return nil, fmt.Errorf("The server is missing the required \"backup_verify\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/backups/%s/verify", path, url.PathEscape(instanceName), url.PathEscape(name)), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // GetInstanceBackupFile requests the instance backup content.
 func (r *ProtocolLXD) GetInstanceBackupFile(instanceName string, name string, req *BackupFileRequest) (*BackupFileResponse, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
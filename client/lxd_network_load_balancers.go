@@ -0,0 +1,129 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkLoadBalancerAddresses returns a list of the listen addresses of the network's load balancers
+func (r *ProtocolLXD) GetNetworkLoadBalancerAddresses(networkName string) ([]string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	addresses := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/load-balancers/")
+		addresses = append(addresses, fields[len(fields)-1])
+	}
+
+	return addresses, nil
+}
+
+// GetNetworkLoadBalancers returns a list of NetworkLoadBalancer struct
+func (r *ProtocolLXD) GetNetworkLoadBalancers(networkName string) ([]api.NetworkLoadBalancer, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancers := []api.NetworkLoadBalancer{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers?recursion=1", url.PathEscape(networkName)), nil, "", &loadBalancers)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancers, nil
+}
+
+// GetNetworkLoadBalancer returns a NetworkLoadBalancer entry for the provided network and listen address
+func (r *ProtocolLXD) GetNetworkLoadBalancer(networkName string, listenAddress string) (*api.NetworkLoadBalancer, string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, "", fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancer := api.NetworkLoadBalancer{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &loadBalancer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &loadBalancer, etag, nil
+}
+
+// CreateNetworkLoadBalancer defines a new load balancer on the network using the provided NetworkLoadBalancer struct
+func (r *ProtocolLXD) CreateNetworkLoadBalancer(networkName string, loadBalancer api.NetworkLoadBalancersPost) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), loadBalancer, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNetworkLoadBalancer updates the load balancer to match the provided NetworkLoadBalancer struct
+func (r *ProtocolLXD) UpdateNetworkLoadBalancer(networkName string, listenAddress string, loadBalancer api.NetworkLoadBalancerPut, ETag string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), loadBalancer, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkLoadBalancer deletes an existing load balancer
+func (r *ProtocolLXD) DeleteNetworkLoadBalancer(networkName string, listenAddress string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetNetworkLoadBalancerState returns the runtime state of the load balancer for the provided network and listen address
+func (r *ProtocolLXD) GetNetworkLoadBalancerState(networkName string, listenAddress string) (*api.NetworkLoadBalancerState, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	state := api.NetworkLoadBalancerState{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers/%s/state", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
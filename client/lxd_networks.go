@@ -83,6 +83,36 @@ func (r *ProtocolLXD) GetNetworkLeases(name string) ([]api.NetworkLease, error)
 	return leases, nil
 }
 
+// CreateNetworkLease reserves an address for a MAC address on a network
+func (r *ProtocolLXD) CreateNetworkLease(name string, lease api.NetworkLeasesPost) error {
+	if !r.HasExtension("network_lease_reservations") {
+		return fmt.Errorf("The server is missing the required \"network_lease_reservations\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/leases", url.PathEscape(name)), lease, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkLease removes an address reservation from a network
+func (r *ProtocolLXD) DeleteNetworkLease(name string, address string) error {
+	if !r.HasExtension("network_lease_reservations") {
+		return fmt.Errorf("The server is missing the required \"network_lease_reservations\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/leases/%s", url.PathEscape(name), url.PathEscape(address)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // GetNetworkState returns metrics and information on the running network
 func (r *ProtocolLXD) GetNetworkState(name string) (*api.NetworkState, error) {
 	if !r.HasExtension("network_state") {
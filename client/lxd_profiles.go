@@ -56,8 +56,30 @@ func (r *ProtocolLXD) GetProfile(name string) (*api.Profile, string, error) {
 	return &profile, etag, nil
 }
 
+// GetProfileExpanded returns a Profile entry for the provided name with the config and devices of its parents
+// applied, along with the order in which the profiles were applied
+func (r *ProtocolLXD) GetProfileExpanded(name string) (*api.Profile, string, error) {
+	if !r.HasExtension("profile_parents") {
+		return nil, "", fmt.Errorf("The server is missing the required \"profile_parents\" API extension")
+	}
+
+	profile := api.Profile{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/profiles/%s?expanded=1", url.PathEscape(name)), nil, "", &profile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &profile, etag, nil
+}
+
 // CreateProfile defines a new container profile
 func (r *ProtocolLXD) CreateProfile(profile api.ProfilesPost) error {
+	if len(profile.Parents) > 0 && !r.HasExtension("profile_parents") {
+		return fmt.Errorf("The server is missing the required \"profile_parents\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/profiles", profile, "")
 	if err != nil {
@@ -69,6 +91,10 @@ func (r *ProtocolLXD) CreateProfile(profile api.ProfilesPost) error {
 
 // UpdateProfile updates the profile to match the provided Profile struct
 func (r *ProtocolLXD) UpdateProfile(name string, profile api.ProfilePut, ETag string) error {
+	if len(profile.Parents) > 0 && !r.HasExtension("profile_parents") {
+		return fmt.Errorf("The server is missing the required \"profile_parents\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/profiles/%s", url.PathEscape(name)), profile, ETag)
 	if err != nil {
@@ -78,6 +104,24 @@ func (r *ProtocolLXD) UpdateProfile(name string, profile api.ProfilePut, ETag st
 	return nil
 }
 
+// UpdateProfileDryRun returns the effect updating the profile to match the provided Profile struct would have on
+// the instances using it, without updating the profile
+func (r *ProtocolLXD) UpdateProfileDryRun(name string, profile api.ProfilePut, ETag string) ([]api.ProfileInstanceImpact, error) {
+	if !r.HasExtension("profile_update_dry_run") {
+		return nil, fmt.Errorf("The server is missing the required \"profile_update_dry_run\" API extension")
+	}
+
+	impacts := []api.ProfileInstanceImpact{}
+
+	// Send the request
+	_, err := r.queryStruct("PUT", fmt.Sprintf("/profiles/%s?dry-run=true", url.PathEscape(name)), profile, ETag, &impacts)
+	if err != nil {
+		return nil, err
+	}
+
+	return impacts, nil
+}
+
 // RenameProfile renames an existing profile entry
 func (r *ProtocolLXD) RenameProfile(name string, profile api.ProfilePost) error {
 	// Send the request
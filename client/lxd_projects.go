@@ -68,12 +68,33 @@ func (r *ProtocolLXD) GetProject(name string) (*api.Project, string, error) {
 	return &project, etag, nil
 }
 
+// GetProjectState returns a Project state for the provided name
+func (r *ProtocolLXD) GetProjectState(name string) (*api.ProjectState, error) {
+	if !r.HasExtension("project_usage") {
+		return nil, fmt.Errorf("The server is missing the required \"project_usage\" API extension")
+	}
+
+	projectState := api.ProjectState{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/projects/%s/state", url.PathEscape(name)), nil, "", &projectState)
+	if err != nil {
+		return nil, err
+	}
+
+	return &projectState, nil
+}
+
 // CreateProject defines a new container project
 func (r *ProtocolLXD) CreateProject(project api.ProjectsPost) error {
 	if !r.HasExtension("projects") {
 		return fmt.Errorf("The server is missing the required \"projects\" API extension")
 	}
 
+	if project.DefaultProfileSource != nil && !r.HasExtension("projects_default_profile_source") {
+		return fmt.Errorf("The server is missing the required \"projects_default_profile_source\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/projects", project, "")
 	if err != nil {
@@ -127,3 +148,18 @@ func (r *ProtocolLXD) DeleteProject(name string) error {
 
 	return nil
 }
+
+// DeleteProjectForce deletes a project along with all its instances, images, profiles and storage volumes
+func (r *ProtocolLXD) DeleteProjectForce(name string) (Operation, error) {
+	if !r.HasExtension("projects_force_delete") {
+		return nil, fmt.Errorf("The server is missing the required \"projects_force_delete\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("DELETE", fmt.Sprintf("/projects/%s?force=1", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
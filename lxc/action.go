@@ -215,10 +215,82 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 	return nil
 }
 
+// doBulkAction changes the state of the given instances of a server in a single request.
+func (c *cmdAction) doBulkAction(action string, resource remoteResource, names []string) []batchResult {
+	// Pause is called freeze
+	if action == "pause" {
+		action = "freeze"
+	}
+
+	results := []batchResult{}
+	setAll := func(err error) []batchResult {
+		for _, name := range names {
+			results = append(results, batchResult{err, fmt.Sprintf("%s:%s", resource.remote, name)})
+		}
+
+		return results
+	}
+
+	req := api.InstancesPut{
+		State: &api.InstanceStatePut{
+			Action:   action,
+			Timeout:  c.flagTimeout,
+			Force:    c.flagForce,
+			Stateful: action == "stop" && c.flagStateful,
+		},
+		Instances: names,
+	}
+
+	op, err := resource.server.UpdateInstances(req, "")
+	if err != nil {
+		return setAll(err)
+	}
+
+	progress := utils.ProgressRenderer{
+		Quiet: c.global.flagQuiet,
+	}
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return setAll(err)
+	}
+
+	// Wait for operation to finish
+	waitErr := utils.CancelableWait(op, &progress)
+	progress.Done("")
+
+	// Report the result of each instance
+	opResults, _ := op.Get().Metadata["results"].(map[string]interface{})
+	for _, name := range names {
+		fullName := fmt.Sprintf("%s:%s", resource.remote, name)
+
+		msg, ok := opResults[name].(string)
+		if !ok {
+			err := waitErr
+			if err == nil {
+				err = fmt.Errorf(i18n.G("No result reported by the server"))
+			}
+
+			results = append(results, batchResult{err, fullName})
+			continue
+		}
+
+		if msg != "" {
+			results = append(results, batchResult{fmt.Errorf("%s\n"+i18n.G("Try `lxc info --show-log %s` for more info"), msg, fullName), fullName})
+			continue
+		}
+
+		results = append(results, batchResult{nil, fullName})
+	}
+
+	return results
+}
+
 func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 	conf := c.global.conf
 
 	var names []string
+	bulk := map[remoteResource][]string{}
 	if c.flagAll {
 		// If no server passed, use current default.
 		if len(args) == 0 {
@@ -242,6 +314,7 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 				return err
 			}
 
+			resourceNames := []string{}
 			for _, ct := range ctslist {
 				switch cmd.Name() {
 				case "start":
@@ -253,7 +326,18 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 						continue
 					}
 				}
-				names = append(names, fmt.Sprintf("%s:%s", resource.remote, ct.Name))
+				resourceNames = append(resourceNames, ct.Name)
+			}
+
+			// Act on all the instances of the server in a single request when supported.
+			// Starting is left out as the state to restore depends on each instance.
+			if cmd.Name() != "start" && len(resourceNames) > 0 && resource.server.HasExtension("instance_bulk_state_change") {
+				bulk[resource] = resourceNames
+				continue
+			}
+
+			for _, name := range resourceNames {
+				names = append(names, fmt.Sprintf("%s:%s", resource.remote, name))
 			}
 		}
 	} else {
@@ -277,6 +361,9 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 
 	// Run the action for every listed instance
 	results := runBatch(names, func(name string) error { return c.doAction(cmd.Name(), conf, name) })
+	for resource, resourceNames := range bulk {
+		results = append(results, c.doBulkAction(cmd.Name(), resource, resourceNames)...)
+	}
 
 	// Single instance is easy
 	if len(results) == 1 {
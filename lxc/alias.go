@@ -88,8 +88,6 @@ func (c *cmdAliasAdd) Run(cmd *cobra.Command, args []string) error {
 type cmdAliasList struct {
 	global *cmdGlobal
 	alias  *cmdAlias
-
-	flagFormat string
 }
 
 func (c *cmdAliasList) Command() *cobra.Command {
@@ -99,7 +97,6 @@ func (c *cmdAliasList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List aliases")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List aliases`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -127,7 +124,7 @@ func (c *cmdAliasList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("TARGET"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, conf.Aliases)
+	return utils.RenderTable(c.global.flagFormat, header, data, conf.Aliases)
 }
 
 // Rename
@@ -55,6 +55,30 @@ func (c *cmdCluster) Command() *cobra.Command {
 	clusterEditCmd := cmdClusterEdit{global: c.global, cluster: c}
 	cmd.AddCommand(clusterEditCmd.Command())
 
+	// Add
+	clusterAddCmd := cmdClusterAdd{global: c.global, cluster: c}
+	cmd.AddCommand(clusterAddCmd.Command())
+
+	// Evacuate
+	clusterEvacuateCmd := cmdClusterEvacuate{global: c.global, cluster: c, action: "evacuate"}
+	cmd.AddCommand(clusterEvacuateCmd.Command())
+
+	// Restore
+	clusterRestoreCmd := cmdClusterEvacuate{global: c.global, cluster: c, action: "restore"}
+	cmd.AddCommand(clusterRestoreCmd.Command())
+
+	// Group
+	clusterGroupCmd := cmdClusterGroup{global: c.global}
+	cmd.AddCommand(clusterGroupCmd.Command())
+
+	// Role
+	clusterRoleCmd := cmdClusterRole{global: c.global}
+	cmd.AddCommand(clusterRoleCmd.Command())
+
+	// Upgrade
+	clusterUpgradeCmd := cmdClusterUpgrade{global: c.global, cluster: c}
+	cmd.AddCommand(clusterUpgradeCmd.Command())
+
 	return cmd
 }
 
@@ -62,8 +86,6 @@ func (c *cmdCluster) Command() *cobra.Command {
 type cmdClusterList struct {
 	global  *cmdGlobal
 	cluster *cmdCluster
-
-	flagFormat string
 }
 
 func (c *cmdClusterList) Command() *cobra.Command {
@@ -73,7 +95,6 @@ func (c *cmdClusterList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List all the cluster members")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List all the cluster members`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -123,7 +144,7 @@ func (c *cmdClusterList) Run(cmd *cobra.Command, args []string) error {
 		if member.Database {
 			database = "YES"
 		}
-		line := []string{member.ServerName, member.URL, database, strings.ToUpper(member.Status), member.Message, member.Architecture, member.FailureDomain}
+		line := []string{member.ServerName, member.URL, database, strings.ToUpper(member.Status), member.Message, member.Architecture, member.FailureDomain, member.Description}
 		data = append(data, line)
 	}
 	sort.Sort(byName(data))
@@ -136,9 +157,10 @@ func (c *cmdClusterList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("MESSAGE"),
 		i18n.G("ARCHITECTURE"),
 		i18n.G("FAILURE DOMAIN"),
+		i18n.G("DESCRIPTION"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, members)
+	return utils.RenderTable(c.global.flagFormat, header, data, members)
 }
 
 // Show
@@ -519,3 +541,169 @@ func (c *cmdClusterEdit) Run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// Evacuate and restore
+type cmdClusterEvacuate struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+	action  string
+
+	flagForce bool
+}
+
+func (c *cmdClusterEvacuate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	if c.action == "evacuate" {
+		cmd.Use = i18n.G("evacuate [<remote>:]<member>")
+		cmd.Short = i18n.G("Evacuate cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Evacuate cluster member
+
+The instances of the member are moved to other members or stopped, according to
+their cluster.evacuate configuration, and no new instance will be placed on it
+until it's restored.`))
+	} else {
+		cmd.Use = i18n.G("restore [<remote>:]<member>")
+		cmd.Short = i18n.G("Restore cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Restore cluster member
+
+The instances which were moved away from the member during its evacuation are
+moved back and the ones which were stopped are started again.`))
+	}
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagForce, "force", false, i18n.G(`Don't require user confirmation`))
+
+	return cmd
+}
+
+func (c *cmdClusterEvacuate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	if !c.flagForce {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf(i18n.G("Are you sure you want to %s cluster member %q? (yes/no) [default=no]: "), c.action, resource.name)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSuffix(input, "\n")
+
+		if !shared.StringInSlice(strings.ToLower(input), []string{i18n.G("yes")}) {
+			return nil
+		}
+	}
+
+	op, err := resource.server.UpdateClusterMemberState(resource.name, api.ClusterMemberStatePost{Action: c.action})
+	if err != nil {
+		return err
+	}
+
+	progress := utils.ProgressRenderer{
+		Quiet: c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	return nil
+}
+
+// Add
+type cmdClusterAdd struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<name>")
+	cmd.Short = i18n.G("Request a join token for adding a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Request a join token for adding a cluster member
+
+The token is single-use and should be provided to "lxd init" on the new member,
+which doesn't need to know the cluster trust password.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	// Request the join token
+	op, err := resource.server.CreateClusterMember(api.ClusterMembersPost{ServerName: resource.name})
+	if err != nil {
+		return err
+	}
+
+	opAPI := op.Get()
+	joinToken := api.ClusterMemberJoinToken{}
+
+	joinToken.ServerName, _ = opAPI.Metadata["serverName"].(string)
+	joinToken.Secret, _ = opAPI.Metadata["secret"].(string)
+	joinToken.Fingerprint, _ = opAPI.Metadata["fingerprint"].(string)
+
+	addresses, _ := opAPI.Metadata["addresses"].([]interface{})
+	for _, address := range addresses {
+		addressString, ok := address.(string)
+		if ok {
+			joinToken.Addresses = append(joinToken.Addresses, addressString)
+		}
+	}
+
+	if joinToken.Secret == "" || joinToken.Fingerprint == "" || len(joinToken.Addresses) == 0 {
+		return fmt.Errorf(i18n.G("Invalid join token returned by the server"))
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Member %s join token:")+"\n", resource.name)
+	}
+
+	fmt.Println(joinToken.String())
+
+	return nil
+}
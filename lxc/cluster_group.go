@@ -0,0 +1,529 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdClusterGroup struct {
+	global *cmdGlobal
+}
+
+func (c *cmdClusterGroup) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("group")
+	cmd.Short = i18n.G("Manage cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage cluster groups
+
+Instances can be placed on the least busy member of a group with --target=@<group>.`))
+
+	// Add
+	clusterGroupAddCmd := cmdClusterGroupAdd{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupAddCmd.Command())
+
+	// Create
+	clusterGroupCreateCmd := cmdClusterGroupCreate{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupCreateCmd.Command())
+
+	// Delete
+	clusterGroupDeleteCmd := cmdClusterGroupDelete{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupDeleteCmd.Command())
+
+	// Edit
+	clusterGroupEditCmd := cmdClusterGroupEdit{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupEditCmd.Command())
+
+	// List
+	clusterGroupListCmd := cmdClusterGroupList{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupListCmd.Command())
+
+	// Remove
+	clusterGroupRemoveCmd := cmdClusterGroupRemove{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupRemoveCmd.Command())
+
+	// Show
+	clusterGroupShowCmd := cmdClusterGroupShow{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupShowCmd.Command())
+
+	return cmd
+}
+
+// Add
+type cmdClusterGroupAdd struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<group> <member>")
+	cmd.Short = i18n.G("Add members to cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add members to cluster groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	group, etag, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(args[1], group.Members) {
+		return fmt.Errorf(i18n.G("Cluster member %s is already in group %s"), args[1], resource.name)
+	}
+
+	group.Members = append(group.Members, args[1])
+
+	return resource.server.UpdateClusterGroup(resource.name, group.Writable(), etag)
+}
+
+// Create
+type cmdClusterGroupCreate struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<group> [<member>...]")
+	cmd.Short = i18n.G("Create new cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new cluster groups`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc cluster group create ssd server1 server2
+    Create a group called "ssd" with the members "server1" and "server2"`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	group := api.ClusterGroupsPost{}
+
+	// If stdin isn't a terminal, read the configuration from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(contents, &group.ClusterGroupPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	group.Name = resource.name
+	group.Members = append(group.Members, args[1:]...)
+
+	err = resource.server.CreateClusterGroup(group)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Cluster group %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdClusterGroupDelete struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<group>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete cluster groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	// Delete the group
+	err = resource.server.DeleteClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Cluster group %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdClusterGroupEdit struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<group>")
+	cmd.Short = i18n.G("Edit cluster groups as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit cluster groups as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the cluster group.
+### Any line starting with a '# will be ignored.
+###
+### An example would look like:
+### name: ssd
+### description: Members with SSD storage
+### members:
+### - server1
+### - server2
+###
+### Note that the name cannot be changed.`)
+}
+
+func (c *cmdClusterGroupEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.ClusterGroupPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateClusterGroup(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	group, etag, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.ClusterGroupPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateClusterGroup(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// List
+type cmdClusterGroupList struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List all the cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List all the cluster groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// List the groups
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	groups, err := resource.server.GetClusterGroups()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, group := range groups {
+		details := []string{
+			group.Name,
+			group.Description,
+			strings.Join(group.Members, "\n"),
+		}
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("MEMBERS"),
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, groups)
+}
+
+// Remove
+type cmdClusterGroupRemove struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<group> <member>")
+	cmd.Short = i18n.G("Remove members from cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove members from cluster groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	group, etag, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	members := []string{}
+	for _, member := range group.Members {
+		if member != args[1] {
+			members = append(members, member)
+		}
+	}
+
+	if len(members) == len(group.Members) {
+		return fmt.Errorf(i18n.G("Cluster member %s isn't in group %s"), args[1], resource.name)
+	}
+
+	group.Members = members
+
+	return resource.server.UpdateClusterGroup(resource.name, group.Writable(), etag)
+}
+
+// Show
+type cmdClusterGroupShow struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<group>")
+	cmd.Short = i18n.G("Show cluster group configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show cluster group configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	// Show the group
+	group, _, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
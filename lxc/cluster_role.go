@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/shared"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdClusterRole struct {
+	global *cmdGlobal
+}
+
+func (c *cmdClusterRole) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("role")
+	cmd.Short = i18n.G("Manage cluster roles")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage cluster roles
+
+The database-pinned role makes a member always be a database voter while the
+database-client role prevents it from ever holding a database role.`))
+
+	// Add
+	clusterRoleAddCmd := cmdClusterRoleAdd{global: c.global, clusterRole: c}
+	cmd.AddCommand(clusterRoleAddCmd.Command())
+
+	// Remove
+	clusterRoleRemoveCmd := cmdClusterRoleRemove{global: c.global, clusterRole: c}
+	cmd.AddCommand(clusterRoleRemoveCmd.Command())
+
+	return cmd
+}
+
+// Add
+type cmdClusterRoleAdd struct {
+	global      *cmdGlobal
+	clusterRole *cmdClusterRole
+}
+
+func (c *cmdClusterRoleAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<member> <role[,role...]>")
+	cmd.Short = i18n.G("Add roles to a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add roles to a cluster member`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc cluster role add server1 database-pinned
+    Always make "server1" a database voter`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterRoleAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	member, etag, err := resource.server.GetClusterMember(resource.name)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range strings.Split(args[1], ",") {
+		if shared.StringInSlice(role, member.Roles) {
+			return fmt.Errorf(i18n.G("Cluster member %s already has role %s"), resource.name, role)
+		}
+
+		member.Roles = append(member.Roles, role)
+	}
+
+	return resource.server.UpdateClusterMember(resource.name, member.Writable(), etag)
+}
+
+// Remove
+type cmdClusterRoleRemove struct {
+	global      *cmdGlobal
+	clusterRole *cmdClusterRole
+}
+
+func (c *cmdClusterRoleRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<member> <role[,role...]>")
+	cmd.Short = i18n.G("Remove roles from a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove roles from a cluster member`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterRoleRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	member, etag, err := resource.server.GetClusterMember(resource.name)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range strings.Split(args[1], ",") {
+		if !shared.StringInSlice(role, member.Roles) {
+			return fmt.Errorf(i18n.G("Cluster member %s doesn't have role %s"), resource.name, role)
+		}
+
+		roles := []string{}
+		for _, memberRole := range member.Roles {
+			if memberRole != role {
+				roles = append(roles, memberRole)
+			}
+		}
+
+		member.Roles = roles
+	}
+
+	return resource.server.UpdateClusterMember(resource.name, member.Writable(), etag)
+}
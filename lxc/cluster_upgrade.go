@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdClusterUpgrade struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagShow bool
+}
+
+func (c *cmdClusterUpgrade) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("upgrade [<remote>:]")
+	cmd.Short = i18n.G("Upgrade the cluster members one at a time")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Upgrade the cluster members one at a time
+
+Each member is upgraded in turn, waiting for it to report its new version
+before moving on to the next one. The member the command is connected to is
+upgraded last. Upgraded members only resume serving requests once all members
+have been upgraded.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagShow, "show", false, i18n.G("Only show the versions of the cluster members"))
+
+	return cmd
+}
+
+func (c *cmdClusterUpgrade) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	upgrade, err := resource.server.GetClusterUpgrade()
+	if err != nil {
+		return err
+	}
+
+	err = c.render(upgrade)
+	if err != nil {
+		return err
+	}
+
+	if c.flagShow {
+		return nil
+	}
+
+	server, _, err := resource.server.GetServer()
+	if err != nil {
+		return err
+	}
+
+	// Leave the member we're connected to for last, as it stops serving requests once upgraded.
+	members := upgrade.Members
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[j].ServerName == server.Environment.ServerName
+	})
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, member := range members {
+		if member.Status == "Upgraded" && upgrade.InProgress {
+			continue
+		}
+
+		fmt.Printf(i18n.G("Upgrade and restart LXD on member %q, then press ENTER: "), member.ServerName)
+		_, err = reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(i18n.G("Waiting for member %q to report its new version")+"\n", member.ServerName)
+		err = c.wait(resource.server, member, member.ServerName == server.Environment.ServerName)
+		if err != nil {
+			return err
+		}
+	}
+
+	upgrade, err = resource.server.GetClusterUpgrade()
+	if err != nil {
+		return err
+	}
+
+	return c.render(upgrade)
+}
+
+// wait polls the cluster until the given member reports a newer version. When it's the member we're connected
+// to, errors are expected until all members have been upgraded and it's serving requests again.
+func (c *cmdClusterUpgrade) wait(server lxd.InstanceServer, member api.ClusterMemberVersion, local bool) error {
+	for {
+		time.Sleep(2 * time.Second)
+
+		upgrade, err := server.GetClusterUpgrade()
+		if err != nil {
+			if local {
+				continue
+			}
+
+			return err
+		}
+
+		for _, current := range upgrade.Members {
+			if current.ServerName != member.ServerName {
+				continue
+			}
+
+			if current.Schema > member.Schema || current.APIExtensions > member.APIExtensions {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *cmdClusterUpgrade) render(upgrade *api.ClusterUpgrade) error {
+	data := [][]string{}
+	for _, member := range upgrade.Members {
+		data = append(data, []string{member.ServerName, strconv.Itoa(member.Schema), strconv.Itoa(member.APIExtensions), member.Status})
+	}
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("SCHEMA"),
+		i18n.G("API EXTENSIONS"),
+		i18n.G("STATUS"),
+	}
+
+	return utils.RenderTable(utils.TableFormatTable, header, data, upgrade.Members)
+}
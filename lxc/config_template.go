@@ -225,8 +225,6 @@ type cmdConfigTemplateList struct {
 	global         *cmdGlobal
 	config         *cmdConfig
 	configTemplate *cmdConfigTemplate
-
-	flagFormat string
 }
 
 func (c *cmdConfigTemplateList) Command() *cobra.Command {
@@ -235,7 +233,6 @@ func (c *cmdConfigTemplateList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List instance file templates")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List instance file templates`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -278,7 +275,7 @@ func (c *cmdConfigTemplateList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("FILENAME"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, templates)
+	return utils.RenderTable(c.global.flagFormat, header, data, templates)
 }
 
 // Show
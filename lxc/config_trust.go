@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -48,14 +49,42 @@ type cmdConfigTrustAdd struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
+
+	flagName       string
+	flagRestricted bool
+	flagProjects   string
+	flagType       string
 }
 
 func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("add [<remote>:] <cert>")
+	cmd.Use = i18n.G("add [<remote>:] [<cert>]")
 	cmd.Short = i18n.G("Add new trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Add new trusted clients`))
+		`Add new trusted clients
+
+When no certificate is given, a single-use token is issued instead, which the
+new client can pass to "lxc remote add" to add its own certificate.
+
+Restricted clients only have access to the given projects, with the
+permissions of their role in each of them (viewer, operator or admin).
+
+Metrics clients only have access to the metrics of the server.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc config trust add client.crt --restricted --projects foo=operator,bar=viewer
+    Trust client.crt in the foo and bar projects only.
+
+lxc config trust add --name laptop
+    Issue a token for a new client named laptop.
+
+lxc config trust add prometheus.crt --type=metrics
+    Trust prometheus.crt to scrape the metrics of the server.`))
+
+	cmd.Flags().StringVar(&c.flagName, "name", "", i18n.G("Name of the client")+"``")
+
+	cmd.Flags().BoolVar(&c.flagRestricted, "restricted", false, i18n.G("Restrict the certificate to the given projects"))
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("Roles of the certificate in its projects (<project>=<role>,...)")+"``")
+	cmd.Flags().StringVar(&c.flagType, "type", "client", i18n.G("Type of certificate (client or metrics)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -64,15 +93,27 @@ func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 
 func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 	// Sanity checks
-	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	exit, err := c.global.CheckArgs(cmd, args, 0, 2)
 	if exit {
 		return err
 	}
 
-	// Parse remote
+	// Parse remote and certificate
 	remote := ""
-	if len(args) > 0 {
+	fname := ""
+	if len(args) == 2 {
 		remote = args[0]
+		fname = args[1]
+	} else if len(args) == 1 {
+		if strings.HasSuffix(args[0], ":") {
+			remote = args[0]
+		} else {
+			fname = args[0]
+		}
+	}
+
+	if fname == "" && c.flagName == "" {
+		return fmt.Errorf(i18n.G("A client name must be provided to issue a token"))
 	}
 
 	resources, err := c.global.ParseServers(remote)
@@ -82,20 +123,84 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 
 	resource := resources[0]
 
-	// Add trust relationship
-	fname := args[len(args)-1]
-	x509Cert, err := shared.ReadCert(shared.HostPath(fname))
+	cert := api.CertificatesPost{}
+	cert.Name = c.flagName
+	cert.Type = c.flagType
+
+	if cert.Type == "metrics" && !resource.server.HasExtension("metrics") {
+		return fmt.Errorf(i18n.G("The server doesn't support metrics certificates"))
+	}
+
+	if fname != "" {
+		x509Cert, err := shared.ReadCert(shared.HostPath(fname))
+		if err != nil {
+			return err
+		}
+
+		cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
+		if cert.Name == "" {
+			cert.Name, _ = shared.SplitExt(fname)
+		}
+	}
+
+	if c.flagRestricted {
+		if !resource.server.HasExtension("certificate_project") {
+			return fmt.Errorf(i18n.G("The server doesn't support restricted certificates"))
+		}
+
+		cert.Restricted = true
+		cert.Projects = map[string]string{}
+		if c.flagProjects != "" {
+			for _, entry := range strings.Split(c.flagProjects, ",") {
+				fields := strings.SplitN(entry, "=", 2)
+				if len(fields) != 2 {
+					return fmt.Errorf(i18n.G("Bad project role %q, expected <project>=<role>"), entry)
+				}
+
+				cert.Projects[fields[0]] = fields[1]
+			}
+		}
+	} else if c.flagProjects != "" {
+		return fmt.Errorf(i18n.G("--projects requires --restricted"))
+	}
+
+	if fname != "" {
+		return resource.server.CreateCertificate(cert)
+	}
+
+	// Request a token
+	cert.Token = true
+	op, err := resource.server.CreateCertificateToken(cert)
 	if err != nil {
 		return err
 	}
-	name, _ := shared.SplitExt(fname)
 
-	cert := api.CertificatesPost{}
-	cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
-	cert.Name = name
-	cert.Type = "client"
+	opAPI := op.Get()
+	token := api.CertificateAddToken{}
 
-	return resource.server.CreateCertificate(cert)
+	token.ClientName, _ = opAPI.Metadata["clientName"].(string)
+	token.Secret, _ = opAPI.Metadata["secret"].(string)
+	token.Fingerprint, _ = opAPI.Metadata["fingerprint"].(string)
+
+	addresses, _ := opAPI.Metadata["addresses"].([]interface{})
+	for _, address := range addresses {
+		addressString, ok := address.(string)
+		if ok {
+			token.Addresses = append(token.Addresses, addressString)
+		}
+	}
+
+	if token.Secret == "" || token.Fingerprint == "" || len(token.Addresses) == 0 {
+		return fmt.Errorf(i18n.G("Invalid certificate add token returned by the server"))
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Client %s certificate add token:")+"\n", token.ClientName)
+	}
+
+	fmt.Println(token.String())
+
+	return nil
 }
 
 // List
@@ -103,8 +208,6 @@ type cmdConfigTrustList struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
-
-	flagFormat string
 }
 
 func (c *cmdConfigTrustList) Command() *cobra.Command {
@@ -114,7 +217,6 @@ func (c *cmdConfigTrustList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List trusted clients`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -150,6 +252,7 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 	data := [][]string{}
 	for _, cert := range trust {
 		fp := cert.Fingerprint[0:12]
+		certType := cert.Type
 
 		certBlock, _ := pem.Decode([]byte(cert.Certificate))
 		if certBlock == nil {
@@ -164,18 +267,19 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 		const layout = "Jan 2, 2006 at 3:04pm (MST)"
 		issue := cert.NotBefore.Format(layout)
 		expiry := cert.NotAfter.Format(layout)
-		data = append(data, []string{fp, cert.Subject.CommonName, issue, expiry})
+		data = append(data, []string{certType, fp, cert.Subject.CommonName, issue, expiry})
 	}
 	sort.Sort(stringList(data))
 
 	header := []string{
+		i18n.G("TYPE"),
 		i18n.G("FINGERPRINT"),
 		i18n.G("COMMON NAME"),
 		i18n.G("ISSUE DATE"),
 		i18n.G("EXPIRY DATE"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, trust)
+	return utils.RenderTable(c.global.flagFormat, header, data, trust)
 }
 
 // Remove
@@ -40,7 +40,7 @@ as well as retrieve past log entries from it.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagShowLog, "show-log", false, i18n.G("Retrieve the instance's console log"))
-	cmd.Flags().StringVarP(&c.flagType, "type", "t", "console", i18n.G("Type of connection to establish: 'console' for serial console, 'vga' for SPICE graphical output"))
+	cmd.Flags().StringVarP(&c.flagType, "type", "t", "console", i18n.G("Type of connection to establish: 'console' for serial console, 'vga' for SPICE graphical output, 'vnc' for VNC graphical output"))
 
 	return cmd
 }
@@ -107,7 +107,7 @@ func (c *cmdConsole) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Validate flags.
-	if !shared.StringInSlice(c.flagType, []string{"console", "vga"}) {
+	if !shared.StringInSlice(c.flagType, []string{"console", "vga", "vnc"}) {
 		return fmt.Errorf("Unknown output type %q", c.flagType)
 	}
 
@@ -153,8 +153,8 @@ func (c *cmdConsole) Console(d lxd.InstanceServer, name string) error {
 	switch c.flagType {
 	case "console":
 		return c.console(d, name)
-	case "vga":
-		return c.vga(d, name)
+	case "vga", "vnc":
+		return c.graphical(d, name, c.flagType)
 	}
 	return fmt.Errorf("Unknown console type %q", c.flagType)
 }
@@ -217,7 +217,9 @@ func (c *cmdConsole) console(d lxd.InstanceServer, name string) error {
 	return nil
 }
 
-func (c *cmdConsole) vga(d lxd.InstanceServer, name string) error {
+// graphical proxies the SPICE ("vga") or VNC ("vnc") display of a virtual machine
+// through a local unix socket and attaches a viewer to it when one is available.
+func (c *cmdConsole) graphical(d lxd.InstanceServer, name string, protocol string) error {
 	conf := c.global.conf
 
 	// We currently use the control websocket just to abort in case of errors.
@@ -230,7 +232,7 @@ func (c *cmdConsole) vga(d lxd.InstanceServer, name string) error {
 
 	// Prepare the remote console.
 	req := api.InstanceConsolePost{
-		Type: "vga",
+		Type: protocol,
 	}
 
 	consoleDisconnect := make(chan bool)
@@ -247,7 +249,12 @@ func (c *cmdConsole) vga(d lxd.InstanceServer, name string) error {
 		close(consoleDisconnect)
 	}()
 
-	// Create a temporary unix socket mirroring the instance's spice socket.
+	// Create a temporary unix socket mirroring the instance's spice or VNC socket.
+	socketPattern := "*.spice"
+	if protocol == "vnc" {
+		socketPattern = "*.vnc"
+	}
+
 	if !shared.PathExists(conf.ConfigPath("sockets")) {
 		err := os.MkdirAll(conf.ConfigPath("sockets"), 0700)
 		if err != nil {
@@ -255,7 +262,7 @@ func (c *cmdConsole) vga(d lxd.InstanceServer, name string) error {
 		}
 	}
 
-	path, err := ioutil.TempFile(conf.ConfigPath("sockets"), "*.spice")
+	path, err := ioutil.TempFile(conf.ConfigPath("sockets"), socketPattern)
 	if err != nil {
 		return err
 	}
@@ -303,30 +310,42 @@ func (c *cmdConsole) vga(d lxd.InstanceServer, name string) error {
 		}
 	}()
 
-	// Use either spicy or remote-viewer if available.
-	remoteViewer, _ := exec.LookPath("remote-viewer")
-	spicy, _ := exec.LookPath("spicy")
+	var viewer *exec.Cmd
+	if protocol == "vnc" {
+		// Use vncviewer if available.
+		vncViewer, _ := exec.LookPath("vncviewer")
+		if vncViewer != "" {
+			viewer = exec.Command(vncViewer, socket)
+		}
+	} else {
+		// Use either spicy or remote-viewer if available.
+		remoteViewer, _ := exec.LookPath("remote-viewer")
+		spicy, _ := exec.LookPath("spicy")
 
-	if remoteViewer != "" || spicy != "" {
-		var cmd *exec.Cmd
 		if remoteViewer != "" {
-			cmd = exec.Command(remoteViewer, fmt.Sprintf("spice+unix://%s", socket))
-		} else {
-			cmd = exec.Command(spicy, fmt.Sprintf("--uri=spice+unix://%s", socket))
+			viewer = exec.Command(remoteViewer, fmt.Sprintf("spice+unix://%s", socket))
+		} else if spicy != "" {
+			viewer = exec.Command(spicy, fmt.Sprintf("--uri=spice+unix://%s", socket))
 		}
+	}
 
+	if viewer != nil {
 		// Start the command.
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Start()
+		viewer.Stdout = os.Stdout
+		viewer.Stderr = os.Stderr
+		viewer.Start()
 
 		defer func() {
-			if cmd.Process == nil {
+			if viewer.Process == nil {
 				return
 			}
 
-			cmd.Process.Kill()
+			viewer.Process.Kill()
 		}()
+	} else if protocol == "vnc" {
+		fmt.Println(i18n.G("LXD automatically uses vncviewer when present."))
+		fmt.Println(i18n.G("As it couldn't be found, the raw VNC socket can be found at:"))
+		fmt.Printf("  %s\n", socket)
 	} else {
 		fmt.Println(i18n.G("LXD automatically uses either spicy or remote-viewer when present."))
 		fmt.Println(i18n.G("As neither could be found, the raw SPICE socket can be found at:"))
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -30,6 +31,8 @@ type cmdCopy struct {
 	flagTarget        string
 	flagTargetProject string
 	flagRefresh       bool
+	flagRetries       int
+	flagBandwidth     string
 }
 
 func (c *cmdCopy) Command() *cobra.Command {
@@ -53,6 +56,8 @@ func (c *cmdCopy) Command() *cobra.Command {
 	cmd.Flags().StringVar(&c.flagTargetProject, "target-project", "", i18n.G("Copy to a project different from the source")+"``")
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagRefresh, "refresh", false, i18n.G("Perform an incremental copy"))
+	cmd.Flags().IntVar(&c.flagRetries, "retries", 0, i18n.G("Number of times to retry an interrupted transfer as a refresh")+"``")
+	cmd.Flags().StringVar(&c.flagBandwidth, "bandwidth-limit", "", i18n.G("Maximum transfer rate in bytes per second (e.g. 10MB)")+"``")
 
 	return cmd
 }
@@ -144,6 +149,7 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 
 	var op lxd.RemoteOperation
 	var writable api.InstancePut
+	var retry func() (lxd.RemoteOperation, error)
 	var start bool
 
 	if shared.IsSnapshot(sourceName) {
@@ -249,11 +255,12 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 	} else {
 		// Prepare the instance creation request
 		args := lxd.InstanceCopyArgs{
-			Name:         destName,
-			Live:         stateful,
-			InstanceOnly: instanceOnly,
-			Mode:         mode,
-			Refresh:      c.flagRefresh,
+			Name:           destName,
+			Live:           stateful,
+			InstanceOnly:   instanceOnly,
+			Mode:           mode,
+			Refresh:        c.flagRefresh,
+			BandwidthLimit: c.flagBandwidth,
 		}
 
 		// Copy of an instance into a new instance
@@ -339,6 +346,17 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 			dest = dest.UseTarget(c.flagTarget)
 		}
 
+		// Keep what was transferred if the copy gets interrupted so that it can be retried as a refresh.
+		if c.flagRetries > 0 && destName != "" {
+			args.Resumable = true
+
+			retry = func() (lxd.RemoteOperation, error) {
+				args.Refresh = true
+				args.Live = false
+				return dest.CopyInstance(source, *entry, &args)
+			}
+		}
+
 		op, err = dest.CopyInstance(source, *entry, &args)
 		if err != nil {
 			return err
@@ -361,6 +379,29 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 
 	// Wait for the copy to complete
 	err = utils.CancelableWait(op, &progress)
+	for attempt := 1; err != nil && retry != nil && attempt <= c.flagRetries; attempt++ {
+		progress.Done("")
+		fmt.Fprintf(os.Stderr, i18n.G("Transfer interrupted, retrying as a refresh (attempt %d/%d): %v")+"\n", attempt, c.flagRetries, err)
+
+		op, err = retry()
+		if err != nil {
+			return err
+		}
+
+		progress = utils.ProgressRenderer{
+			Format: i18n.G("Transferring instance: %s"),
+			Quiet:  c.global.flagQuiet,
+		}
+
+		_, err = op.AddHandler(progress.UpdateOp)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		err = utils.CancelableWait(op, &progress)
+	}
+
 	if err != nil {
 		progress.Done("")
 		return err
@@ -23,6 +23,8 @@ type cmdExport struct {
 	flagInstanceOnly         bool
 	flagOptimizedStorage     bool
 	flagCompressionAlgorithm string
+	flagDeltaFrom            string
+	flagBandwidthLimit       string
 }
 
 func (c *cmdExport) Command() *cobra.Command {
@@ -33,14 +35,19 @@ func (c *cmdExport) Command() *cobra.Command {
 		`Export instances as backup tarballs.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc export u1 backup0.tar.gz
-    Download a backup tarball of the u1 instance.`))
+    Download a backup tarball of the u1 instance.
+
+lxc export u1 backup1.tar.gz --optimized-storage --delta-from snap0
+    Download an incremental backup of the u1 instance made of the changes since its snap0 snapshot.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagInstanceOnly, "instance-only", false,
 		i18n.G("Whether or not to only backup the instance (without snapshots)"))
 	cmd.Flags().BoolVar(&c.flagOptimizedStorage, "optimized-storage", false,
 		i18n.G("Use storage driver optimized format (can only be restored on a similar pool)"))
-	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm: for backup or none")+"``")
+	cmd.Flags().StringVar(&c.flagDeltaFrom, "delta-from", "", i18n.G("Only export the changes made since the given snapshot (requires --optimized-storage)")+"``")
+	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm and its arguments: for backup or none (e.g. \"zstd -T0 -3\")")+"``")
+	cmd.Flags().StringVar(&c.flagBandwidthLimit, "bandwidth-limit", "", i18n.G("Maximum rate at which the backup tarball is written in bytes per second (e.g. 10MB)")+"``")
 
 	return cmd
 }
@@ -74,6 +81,8 @@ func (c *cmdExport) Run(cmd *cobra.Command, args []string) error {
 		InstanceOnly:         instanceOnly,
 		OptimizedStorage:     c.flagOptimizedStorage,
 		CompressionAlgorithm: c.flagCompressionAlgorithm,
+		DeltaFrom:            c.flagDeltaFrom,
+		BandwidthLimit:       c.flagBandwidthLimit,
 	}
 
 	op, err := d.CreateInstanceBackup(name, req)
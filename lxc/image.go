@@ -84,6 +84,10 @@ hash or alias name (if one is set).`))
 	imageListCmd := cmdImageList{global: c.global, image: c}
 	cmd.AddCommand(imageListCmd.Command())
 
+	// Pin
+	imagePinCmd := cmdImagePin{global: c.global, image: c}
+	cmd.AddCommand(imagePinCmd.Command())
+
 	// Refresh
 	imageRefreshCmd := cmdImageRefresh{global: c.global, image: c}
 	cmd.AddCommand(imageRefreshCmd.Command())
@@ -92,6 +96,10 @@ hash or alias name (if one is set).`))
 	imageShowCmd := cmdImageShow{global: c.global, image: c}
 	cmd.AddCommand(imageShowCmd.Command())
 
+	// Unpin
+	imageUnpinCmd := cmdImageUnpin{global: c.global, image: c}
+	cmd.AddCommand(imageUnpinCmd.Command())
+
 	return cmd
 }
 
@@ -443,7 +451,8 @@ type cmdImageExport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagVM bool
+	flagVM     bool
+	flagFormat string
 }
 
 func (c *cmdImageExport) Command() *cobra.Command {
@@ -453,9 +462,20 @@ func (c *cmdImageExport) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Export and download images
 
-The output target is optional and defaults to the working directory.`))
+The output target is optional and defaults to the working directory.
+A target of "-" writes the image to standard output as a unified tarball.
+
+Images from LXD servers can be converted to the unified or split format
+as they're exported, in which case they no longer match their fingerprint.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image export ubuntu - | tar -tf -
+    List the content of the ubuntu image.
+
+lxc image export ubuntu /tmp/ubuntu --format=split
+    Export the ubuntu image as separate metadata and rootfs files.`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "", i18n.G("Format of the exported image (unified or split)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -487,6 +507,34 @@ func (c *cmdImageExport) Run(cmd *cobra.Command, args []string) error {
 
 	fingerprint := c.image.dereferenceAlias(remoteServer, imageType, name)
 
+	if !shared.StringInSlice(c.flagFormat, []string{"", "unified", "split"}) {
+		return fmt.Errorf(i18n.G("Invalid image format %q"), c.flagFormat)
+	}
+
+	// Stream the image to standard output
+	if len(args) > 1 && args[1] == "-" {
+		if c.flagFormat == "split" {
+			return fmt.Errorf(i18n.G("Split images can't be written to standard output"))
+		}
+
+		server, ok := remoteServer.(lxd.InstanceServer)
+		if !ok {
+			return fmt.Errorf(i18n.G("Only images from LXD servers can be written to standard output"))
+		}
+
+		req := lxd.ImageFileRequest{
+			MetaFile: os.Stdout,
+		}
+
+		// Older servers can still send images which are already unified.
+		if server.HasExtension("image_export_format") {
+			req.Format = "unified"
+		}
+
+		_, err = server.GetImageFile(fingerprint, req)
+		return err
+	}
+
 	// Default target is current directory
 	target := "."
 	targetMeta := fingerprint
@@ -524,6 +572,7 @@ func (c *cmdImageExport) Run(cmd *cobra.Command, args []string) error {
 		MetaFile:        io.WriteSeeker(dest),
 		RootfsFile:      io.WriteSeeker(destRootfs),
 		ProgressHandler: progress.UpdateProgress,
+		Format:          c.flagFormat,
 	}
 
 	// Download the image
@@ -601,8 +650,9 @@ type cmdImageImport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagPublic  bool
-	flagAliases []string
+	flagPublic     bool
+	flagAliases    []string
+	flagEntrypoint string
 }
 
 func (c *cmdImageImport) Command() *cobra.Command {
@@ -612,10 +662,19 @@ func (c *cmdImageImport) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Import image into the image store
 
-Directory import is only available on Linux and must be performed as root.`))
+Directory import is only available on Linux and must be performed as root.
+
+Images from OCI registries can be imported with a docker:// URL, which the server
+pulls and converts to a container image. Their entrypoint is either run by a
+generated /sbin/init wrapper or, with --entrypoint=config, through the oci.*
+configuration keys of the instances created from them.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image import docker://docker.io/library/nginx:latest --alias nginx
+    Import the nginx image from Docker Hub.`))
 
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().StringVar(&c.flagEntrypoint, "entrypoint", "", i18n.G("How to run the entrypoint of OCI images (wrapper or config)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -734,7 +793,16 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	imageType := "container"
-	if strings.HasPrefix(imageFile, "https://") {
+	if strings.HasPrefix(imageFile, "docker://") {
+		image.Source = &api.ImagesPostSource{}
+		image.Source.Type = "oci"
+		image.Source.Mode = "pull"
+		image.Source.URL = imageFile
+		image.Source.Entrypoint = c.flagEntrypoint
+		createArgs = nil
+	} else if c.flagEntrypoint != "" {
+		return fmt.Errorf(i18n.G("--entrypoint can only be used with docker:// images"))
+	} else if strings.HasPrefix(imageFile, "https://") {
 		image.Source = &api.ImagesPostSource{}
 		image.Source.Type = "url"
 		image.Source.Mode = "pull"
@@ -859,6 +927,11 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	remoteServer, err := c.global.conf.GetImageServer(remoteName)
 	if err != nil {
 		return err
@@ -876,6 +949,10 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if rawFormat {
+		return utils.RenderTable(c.global.flagFormat, nil, nil, info)
+	}
+
 	public := i18n.G("no")
 	if info.Public {
 		public = i18n.G("yes")
@@ -891,6 +968,11 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		autoUpdate = i18n.G("enabled")
 	}
 
+	pinned := i18n.G("no")
+	if info.Pinned {
+		pinned = i18n.G("yes")
+	}
+
 	imgType := "container"
 	if info.Type != "" {
 		imgType = info.Type
@@ -927,6 +1009,13 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		fmt.Printf("    %s: %s\n", key, value)
 	}
 
+	if len(info.Annotations) > 0 {
+		fmt.Println(i18n.G("Annotations:"))
+		for key, value := range info.Annotations {
+			fmt.Printf("    %s: %s\n", key, value)
+		}
+	}
+
 	fmt.Println(i18n.G("Aliases:"))
 	for _, alias := range info.Aliases {
 		if alias.Description != "" {
@@ -938,6 +1027,11 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf(i18n.G("Cached: %s")+"\n", cached)
 	fmt.Printf(i18n.G("Auto update: %s")+"\n", autoUpdate)
+	fmt.Printf(i18n.G("Pinned: %s")+"\n", pinned)
+
+	if info.BaseImage != "" {
+		fmt.Printf(i18n.G("Base image: %s")+"\n", info.BaseImage)
+	}
 
 	if info.UpdateSource != nil {
 		fmt.Println(i18n.G("Source:"))
@@ -963,7 +1057,6 @@ type cmdImageList struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagFormat  string
 	flagColumns string
 }
 
@@ -998,7 +1091,6 @@ Column shorthand chars:
     t - Type`))
 
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "lfpdatsu", i18n.G("Columns")+"``")
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1257,7 +1349,68 @@ func (c *cmdImageList) Run(cmd *cobra.Command, args []string) error {
 		headers = append(headers, column.Name)
 	}
 
-	return utils.RenderTable(c.flagFormat, headers, data, rawData)
+	return utils.RenderTable(c.global.flagFormat, headers, data, rawData)
+}
+
+// Pin
+type cmdImagePin struct {
+	global *cmdGlobal
+	image  *cmdImage
+}
+
+func (c *cmdImagePin) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("pin [<remote>:]<image> [[<remote>:]<image>...]")
+	cmd.Short = i18n.G("Pin images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Pin images
+
+Pinned images are never refreshed, nor removed when an alias tracking a remote image moves away from them.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImagePin) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	return c.image.setPinned(args, true)
+}
+
+// setPinned pins or unpins the given images.
+func (c *cmdImage) setPinned(args []string, pinned bool) error {
+	// Parse remote
+	resources, err := c.global.ParseServers(args...)
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range resources {
+		if resource.name == "" {
+			return fmt.Errorf(i18n.G("Image identifier missing"))
+		}
+
+		image := c.dereferenceAlias(resource.server, "", resource.name)
+		info, etag, err := resource.server.GetImage(image)
+		if err != nil {
+			return err
+		}
+
+		put := info.Writable()
+		put.Pinned = pinned
+
+		err = resource.server.UpdateImage(info.Fingerprint, put, etag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Refresh
@@ -1398,3 +1551,31 @@ func (c *cmdImageShow) Run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// Unpin
+type cmdImageUnpin struct {
+	global *cmdGlobal
+	image  *cmdImage
+}
+
+func (c *cmdImageUnpin) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("unpin [<remote>:]<image> [[<remote>:]<image>...]")
+	cmd.Short = i18n.G("Unpin images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Unpin images`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageUnpin) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	return c.image.setPinned(args, false)
+}
@@ -41,6 +41,14 @@ func (c *cmdImageAlias) Command() *cobra.Command {
 	imageAliasRenameCmd := cmdImageAliasRename{global: c.global, image: c.image, imageAlias: c}
 	cmd.AddCommand(imageAliasRenameCmd.Command())
 
+	// Track
+	imageAliasTrackCmd := cmdImageAliasTrack{global: c.global, image: c.image, imageAlias: c}
+	cmd.AddCommand(imageAliasTrackCmd.Command())
+
+	// Untrack
+	imageAliasUntrackCmd := cmdImageAliasUntrack{global: c.global, image: c.image, imageAlias: c}
+	cmd.AddCommand(imageAliasUntrackCmd.Command())
+
 	return cmd
 }
 
@@ -53,10 +61,17 @@ type cmdImageAliasCreate struct {
 
 func (c *cmdImageAliasCreate) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("create [<remote>:]<alias> <fingerprint>")
+	cmd.Use = i18n.G("create [<remote>:]<alias> <fingerprint> [<fingerprint>...]")
 	cmd.Short = i18n.G("Create aliases for existing images")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Create aliases for existing images`))
+		`Create aliases for existing images
+
+Additional images for other architectures can be given, in which case the alias
+resolves to the image matching the architecture of the server using it, the
+first image being used for any other architecture.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image alias create myimage 5ab8d5e2b4e8 0c5c26a1bd4c
+    Create the myimage alias for an amd64 and an arm64 image.`))
 
 	cmd.RunE = c.Run
 
@@ -65,7 +80,7 @@ func (c *cmdImageAliasCreate) Command() *cobra.Command {
 
 func (c *cmdImageAliasCreate) Run(cmd *cobra.Command, args []string) error {
 	// Sanity checks
-	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
 	if exit {
 		return err
 	}
@@ -87,6 +102,25 @@ func (c *cmdImageAliasCreate) Run(cmd *cobra.Command, args []string) error {
 	alias.Name = resource.name
 	alias.Target = args[1]
 
+	// Add the images for other architectures
+	for _, fingerprint := range args[2:] {
+		image, _, err := resource.server.GetImage(fingerprint)
+		if err != nil {
+			return err
+		}
+
+		if alias.Architectures == nil {
+			alias.Architectures = map[string]string{}
+		}
+
+		_, ok := alias.Architectures[image.Architecture]
+		if ok {
+			return fmt.Errorf(i18n.G("Multiple images given for architecture %s"), image.Architecture)
+		}
+
+		alias.Architectures[image.Architecture] = image.Fingerprint
+	}
+
 	return resource.server.CreateImageAlias(alias)
 }
 
@@ -138,8 +172,6 @@ type cmdImageAliasList struct {
 	global     *cmdGlobal
 	image      *cmdImage
 	imageAlias *cmdImageAlias
-
-	flagFormat string
 }
 
 func (c *cmdImageAliasList) Command() *cobra.Command {
@@ -152,7 +184,6 @@ func (c *cmdImageAliasList) Command() *cobra.Command {
 
 Filters may be part of the image hash or part of the image alias name.
 `))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -168,6 +199,12 @@ func (c *cmdImageAliasList) aliasShouldShow(filters []string, state *api.ImageAl
 		if strings.Contains(state.Name, filter) || strings.Contains(state.Target, filter) {
 			return true
 		}
+
+		for _, fingerprint := range state.Architectures {
+			if strings.Contains(fingerprint, filter) {
+				return true
+			}
+		}
 	}
 
 	return false
@@ -234,7 +271,7 @@ func (c *cmdImageAliasList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("DESCRIPTION"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, aliases)
+	return utils.RenderTable(c.global.flagFormat, header, data, aliases)
 }
 
 // Rename
@@ -279,3 +316,133 @@ func (c *cmdImageAliasRename) Run(cmd *cobra.Command, args []string) error {
 	// Rename the alias
 	return resource.server.RenameImageAlias(resource.name, api.ImageAliasesEntryPost{Name: args[1]})
 }
+
+// Track
+type cmdImageAliasTrack struct {
+	global     *cmdGlobal
+	image      *cmdImage
+	imageAlias *cmdImageAlias
+}
+
+func (c *cmdImageAliasTrack) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("track [<remote>:]<alias> <remote>:<source alias>")
+	cmd.Short = i18n.G("Keep image aliases pointing at the latest image of a remote alias")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Keep image aliases pointing at the latest image of a remote alias
+
+The alias is moved to the new image whenever the remote alias changes. The image it previously pointed at
+is then deleted, unless it's pinned or still referenced by another alias.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasTrack) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Alias name missing"))
+	}
+
+	// Resolve the source
+	sourceRemote, sourceAlias, err := c.global.conf.ParseRemote(args[1])
+	if err != nil {
+		return err
+	}
+
+	if sourceAlias == "" {
+		return fmt.Errorf(i18n.G("Source alias name missing"))
+	}
+
+	sourceServer, err := c.global.conf.GetImageServer(sourceRemote)
+	if err != nil {
+		return err
+	}
+
+	info, err := sourceServer.GetConnectionInfo()
+	if err != nil {
+		return err
+	}
+
+	// Update the alias
+	alias, etag, err := resource.server.GetImageAlias(resource.name)
+	if err != nil {
+		return err
+	}
+
+	put := alias.ImageAliasesEntryPut
+	put.AutoUpdate = true
+	put.UpdateSource = &api.ImageSource{
+		Server:      info.URL,
+		Protocol:    info.Protocol,
+		Certificate: info.Certificate,
+		Alias:       sourceAlias,
+	}
+
+	return resource.server.UpdateImageAlias(resource.name, put, etag)
+}
+
+// Untrack
+type cmdImageAliasUntrack struct {
+	global     *cmdGlobal
+	image      *cmdImage
+	imageAlias *cmdImageAlias
+}
+
+func (c *cmdImageAliasUntrack) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("untrack [<remote>:]<alias>")
+	cmd.Short = i18n.G("Stop updating image aliases from a remote alias")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Stop updating image aliases from a remote alias`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasUntrack) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Alias name missing"))
+	}
+
+	// Update the alias
+	alias, etag, err := resource.server.GetImageAlias(resource.name)
+	if err != nil {
+		return err
+	}
+
+	put := alias.ImageAliasesEntryPut
+	put.AutoUpdate = false
+	put.UpdateSource = nil
+
+	return resource.server.UpdateImageAlias(resource.name, put, etag)
+}
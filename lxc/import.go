@@ -19,6 +19,7 @@ type cmdImport struct {
 	global *cmdGlobal
 
 	flagStorage string
+	flagVerify  bool
 }
 
 func (c *cmdImport) Command() *cobra.Command {
@@ -33,6 +34,7 @@ func (c *cmdImport) Command() *cobra.Command {
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
+	cmd.Flags().BoolVar(&c.flagVerify, "verify", false, i18n.G("Check the backup against its checksum manifest before restoring it"))
 
 	return cmd
 }
@@ -84,6 +86,7 @@ func (c *cmdImport) Run(cmd *cobra.Command, args []string) error {
 			},
 		},
 		PoolName: c.flagStorage,
+		Verify:   c.flagVerify,
 	}
 
 	op, err := resource.server.CreateInstanceFromBackup(createArgs)
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"archive/tar"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// importImageFormats maps the supported foreign disk formats to the matching qemu-img format name.
+var importImageFormats = map[string]string{
+	"ova":   "",
+	"vmdk":  "vmdk",
+	"vhd":   "vpc",
+	"vhdx":  "vhdx",
+	"qcow2": "qcow2",
+	"raw":   "raw",
+	"img":   "raw",
+}
+
+type cmdImportImage struct {
+	global *cmdGlobal
+
+	flagFormat   string
+	flagStorage  string
+	flagProfile  []string
+	flagNoConfig bool
+}
+
+func (c *cmdImportImage) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("import-image <file> [[<remote>:]<name>]")
+	cmd.Short = i18n.G("Create virtual machines from foreign disk images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create virtual machines from foreign disk images
+
+The disk is converted to qcow2 locally using qemu-img and uploaded to the
+server, after which a new virtual machine is created from it.
+
+Supported formats are ova, vmdk, vhd, vhdx, qcow2 and raw. When importing
+an OVA appliance, the CPU count and memory size found in its OVF descriptor
+are applied to the new virtual machine.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc import-image appliance.ova v1 --format=ova
+    Create a new virtual machine called v1 from appliance.ova.
+
+lxc import-image disk.vhdx v2
+    Create a new virtual machine called v2 from a Hyper-V disk.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagFormat, "format", "", i18n.G("Format of the disk image (ova|vmdk|vhd|vhdx|qcow2|raw)")+"``")
+	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
+	cmd.Flags().StringArrayVarP(&c.flagProfile, "profile", "p", nil, i18n.G("Profile to apply to the new virtual machine")+"``")
+	cmd.Flags().BoolVar(&c.flagNoConfig, "no-config", false, i18n.G("Don't apply the hardware settings found in the OVF descriptor"))
+
+	return cmd
+}
+
+func (c *cmdImportImage) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	if exit {
+		return err
+	}
+
+	path := shared.HostPath(filepath.Clean(args[0]))
+
+	// Figure out the format
+	format := c.flagFormat
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	qemuFormat, ok := importImageFormats[format]
+	if !ok {
+		return fmt.Errorf(i18n.G("Unsupported disk image format %q"), format)
+	}
+
+	_, err = exec.LookPath("qemu-img")
+	if err != nil {
+		return fmt.Errorf(i18n.G("qemu-img is required to convert disk images"))
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 1 {
+		remote = args[1]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	name := resource.name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	tmpDir, err := ioutil.TempDir("", "lxc_import_image_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Foreign images aren't signed for use with secure boot.
+	config := map[string]string{"security.secureboot": "false"}
+	diskPath := path
+
+	// Unpack the appliance and look for its hardware description.
+	if format == "ova" {
+		ovf, err := importImageUnpackOVA(path, tmpDir)
+		if err != nil {
+			return err
+		}
+
+		if len(ovf.Disks()) == 0 {
+			return fmt.Errorf(i18n.G("No disk found in the OVF descriptor"))
+		}
+
+		if len(ovf.Disks()) > 1 {
+			fmt.Fprintf(os.Stderr, i18n.G("Only the first of %d disks will be imported")+"\n", len(ovf.Disks()))
+		}
+
+		diskPath = filepath.Join(tmpDir, filepath.Base(ovf.Disks()[0]))
+		qemuFormat = ""
+
+		if !c.flagNoConfig {
+			for k, v := range ovf.Config() {
+				config[k] = v
+			}
+		}
+	}
+
+	// Convert the disk to qcow2.
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Converting disk image %s")+"\n", filepath.Base(diskPath))
+	}
+
+	rootfsPath := filepath.Join(tmpDir, "rootfs.img")
+	convertArgs := []string{"convert"}
+	if qemuFormat != "" {
+		convertArgs = append(convertArgs, "-f", qemuFormat)
+	}
+
+	convertArgs = append(convertArgs, "-O", "qcow2", diskPath, rootfsPath)
+	_, err = shared.RunCommand("qemu-img", convertArgs...)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Failed to convert disk image: %v"), err)
+	}
+
+	// Generate the image metadata.
+	server, _, err := resource.server.GetServer()
+	if err != nil {
+		return err
+	}
+
+	if len(server.Environment.Architectures) == 0 {
+		return fmt.Errorf(i18n.G("Couldn't determine the server architecture"))
+	}
+
+	metaPath := filepath.Join(tmpDir, "metadata.tar")
+	err = importImageWriteMetadata(metaPath, api.ImageMetadata{
+		Architecture: server.Environment.Architectures[0],
+		CreationDate: time.Now().UTC().Unix(),
+		Properties: map[string]string{
+			"description": fmt.Sprintf("Imported from %s", filepath.Base(path)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Upload the image.
+	meta, err := os.Open(metaPath)
+	if err != nil {
+		return err
+	}
+	defer meta.Close()
+
+	rootfs, err := os.Open(rootfsPath)
+	if err != nil {
+		return err
+	}
+	defer rootfs.Close()
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Transferring image: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	createArgs := &lxd.ImageCreateArgs{
+		MetaFile:        meta,
+		MetaName:        filepath.Base(metaPath),
+		RootfsFile:      rootfs,
+		RootfsName:      filepath.Base(rootfsPath),
+		ProgressHandler: progress.UpdateProgress,
+		Type:            string(api.InstanceTypeVM),
+	}
+
+	op, err := resource.server.CreateImage(api.ImagesPost{Filename: createArgs.MetaName}, createArgs)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	fingerprint := op.Get().Metadata["fingerprint"].(string)
+
+	// The image is only used as a vehicle to create the instance.
+	defer func() {
+		op, err := resource.server.DeleteImage(fingerprint)
+		if err == nil {
+			op.Wait()
+		}
+	}()
+
+	// Create the virtual machine.
+	req := api.InstancesPost{
+		Name: name,
+		Type: api.InstanceTypeVM,
+		Source: api.InstanceSource{
+			Type:        "image",
+			Fingerprint: fingerprint,
+		},
+	}
+
+	req.Config = config
+	req.Profiles = c.flagProfile
+
+	if c.flagStorage != "" {
+		req.Devices = map[string]map[string]string{
+			"root": {
+				"type": "disk",
+				"path": "/",
+				"pool": c.flagStorage,
+			},
+		}
+	}
+
+	progress = utils.ProgressRenderer{
+		Format: i18n.G("Creating virtual machine: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	op, err = resource.server.CreateInstance(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Virtual machine %s created")+"\n", name)
+	}
+
+	return nil
+}
+
+// importImageOVF represents the parts of an OVF descriptor used when importing an appliance.
+type importImageOVF struct {
+	Files []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"References>File"`
+
+	DiskRefs []struct {
+		FileRef string `xml:"fileRef,attr"`
+	} `xml:"DiskSection>Disk"`
+
+	VirtualSystem struct {
+		ID    string `xml:"id,attr"`
+		Items []struct {
+			ResourceType    int    `xml:"ResourceType"`
+			VirtualQuantity int64  `xml:"VirtualQuantity"`
+			AllocationUnits string `xml:"AllocationUnits"`
+		} `xml:"VirtualHardwareSection>Item"`
+	} `xml:"VirtualSystem"`
+}
+
+// Disks returns the file names of the appliance disks in the order they are declared.
+func (o *importImageOVF) Disks() []string {
+	disks := []string{}
+	for _, disk := range o.DiskRefs {
+		for _, file := range o.Files {
+			if file.ID == disk.FileRef {
+				disks = append(disks, file.Href)
+				break
+			}
+		}
+	}
+
+	return disks
+}
+
+// Config maps the virtual hardware of the appliance to instance configuration keys.
+func (o *importImageOVF) Config() map[string]string {
+	config := map[string]string{}
+
+	for _, item := range o.VirtualSystem.Items {
+		if item.VirtualQuantity <= 0 {
+			continue
+		}
+
+		switch item.ResourceType {
+		case 3: // Processor
+			config["limits.cpu"] = fmt.Sprintf("%d", item.VirtualQuantity)
+		case 4: // Memory
+			units := strings.ToLower(strings.Replace(item.AllocationUnits, " ", "", -1))
+			switch {
+			case units == "byte":
+				config["limits.memory"] = fmt.Sprintf("%dB", item.VirtualQuantity)
+			case units == "byte*2^10" || units == "kilobytes":
+				config["limits.memory"] = fmt.Sprintf("%dKiB", item.VirtualQuantity)
+			case units == "byte*2^30" || units == "gigabytes":
+				config["limits.memory"] = fmt.Sprintf("%dGiB", item.VirtualQuantity)
+			default:
+				config["limits.memory"] = fmt.Sprintf("%dMiB", item.VirtualQuantity)
+			}
+		}
+	}
+
+	return config
+}
+
+// importImageParseOVF parses an OVF descriptor.
+func importImageParseOVF(r io.Reader) (*importImageOVF, error) {
+	ovf := importImageOVF{}
+
+	err := xml.NewDecoder(r).Decode(&ovf)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse OVF descriptor: %v", err)
+	}
+
+	return &ovf, nil
+}
+
+// importImageUnpackOVA extracts an OVA appliance into the target directory and returns its descriptor.
+func importImageUnpackOVA(path string, target string) (*importImageOVF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ovf *importImageOVF
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Base(hdr.Name)
+		if strings.HasSuffix(strings.ToLower(name), ".ovf") {
+			ovf, err = importImageParseOVF(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		out, err := os.Create(filepath.Join(target, name))
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ovf == nil {
+		return nil, fmt.Errorf("No OVF descriptor found in %s", filepath.Base(path))
+	}
+
+	return ovf, nil
+}
+
+// importImageWriteMetadata writes a metadata tarball for a split virtual machine image.
+func importImageWriteMetadata(path string, metadata api.ImageMetadata) error {
+	data, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    "metadata.yaml",
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const importImageTestOVF = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData">
+  <References>
+    <File ovf:id="file2" ovf:href="appliance-disk2.vmdk"/>
+    <File ovf:id="file1" ovf:href="appliance-disk1.vmdk"/>
+  </References>
+  <DiskSection>
+    <Disk ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:capacity="10737418240"/>
+    <Disk ovf:diskId="vmdisk2" ovf:fileRef="file2" ovf:capacity="1073741824"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="appliance">
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>2</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>2048</rasd:VirtualQuantity>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`
+
+type importImageTestSuite struct {
+	suite.Suite
+}
+
+func TestImportImageTestSuite(t *testing.T) {
+	suite.Run(t, new(importImageTestSuite))
+}
+
+// Disks are returned in the order of the disk section.
+func (s *importImageTestSuite) Test_importImageParseOVF_disks() {
+	ovf, err := importImageParseOVF(strings.NewReader(importImageTestOVF))
+	s.Require().NoError(err)
+	s.Equal([]string{"appliance-disk1.vmdk", "appliance-disk2.vmdk"}, ovf.Disks())
+}
+
+// CPU and memory are mapped to instance limits.
+func (s *importImageTestSuite) Test_importImageParseOVF_config() {
+	ovf, err := importImageParseOVF(strings.NewReader(importImageTestOVF))
+	s.Require().NoError(err)
+	s.Equal(map[string]string{
+		"limits.cpu":    "2",
+		"limits.memory": "2048MiB",
+	}, ovf.Config())
+}
+
+// Invalid descriptors are rejected.
+func (s *importImageTestSuite) Test_importImageParseOVF_invalid() {
+	_, err := importImageParseOVF(strings.NewReader("<Envelope>"))
+	s.Error(err)
+}
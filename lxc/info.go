@@ -11,6 +11,7 @@ import (
 
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
@@ -312,6 +313,11 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 		d = d.UseTarget(c.flagTarget)
 	}
 
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	if c.flagResources {
 		if !d.HasExtension("resources_v2") {
 			return fmt.Errorf("The server doesn't implement the newer v2 resources API")
@@ -322,6 +328,10 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 			return err
 		}
 
+		if rawFormat {
+			return utils.RenderTable(c.global.flagFormat, nil, nil, resources)
+		}
+
 		// CPU
 		if len(resources.CPU.Sockets) == 1 {
 			fmt.Printf(i18n.G("CPU (%s):")+"\n", resources.CPU.Architecture)
@@ -407,6 +417,10 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 		return err
 	}
 
+	if rawFormat {
+		return utils.RenderTable(c.global.flagFormat, nil, nil, serverStatus)
+	}
+
 	data, err := yaml.Marshal(&serverStatus)
 	if err != nil {
 		return err
@@ -423,6 +437,11 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 		return fmt.Errorf(i18n.G("--target cannot be used with instances"))
 	}
 
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	ct, _, err := d.GetInstance(name)
 	if err != nil {
 		return err
@@ -433,6 +452,25 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 		return err
 	}
 
+	// Render the full instance for machine readable formats
+	if rawFormat {
+		full := api.InstanceFull{Instance: *ct, State: cs}
+
+		full.Snapshots, err = d.GetInstanceSnapshots(name)
+		if err != nil {
+			return err
+		}
+
+		if d.HasExtension("container_backup") {
+			full.Backups, err = d.GetInstanceBackups(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return utils.RenderTable(c.global.flagFormat, nil, nil, full)
+	}
+
 	const layout = "2006/01/02 15:04 UTC"
 
 	fmt.Printf(i18n.G("Name: %s")+"\n", ct.Name)
@@ -537,6 +575,19 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 			fmt.Printf(memoryInfo)
 		}
 
+		// GPU usage
+		gpuInfo := ""
+		for pciAddress, gpu := range cs.GPU {
+			gpuInfo += fmt.Sprintf("    %s:\n", pciAddress)
+			gpuInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Processes"), gpu.Processes)
+			gpuInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Memory"), units.GetByteSizeString(gpu.MemoryUsage, 2))
+		}
+
+		if gpuInfo != "" {
+			fmt.Println(fmt.Sprintf("  %s", i18n.G("GPU usage:")))
+			fmt.Printf(gpuInfo)
+		}
+
 		// Network usage
 		networkInfo := ""
 		if cs.Network != nil {
@@ -546,6 +597,10 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 				networkInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(net.Counters.BytesSent, 2))
 				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets received"), net.Counters.PacketsReceived)
 				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets sent"), net.Counters.PacketsSent)
+				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Errors received"), net.Counters.ErrorsReceived)
+				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Errors sent"), net.Counters.ErrorsSent)
+				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets dropped inbound"), net.Counters.PacketsDroppedInbound)
+				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets dropped outbound"), net.Counters.PacketsDroppedOutbound)
 			}
 		}
 
@@ -553,6 +608,23 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 			fmt.Println(fmt.Sprintf("  %s", i18n.G("Network usage:")))
 			fmt.Printf(networkInfo)
 		}
+
+		// Proxy usage
+		proxyInfo := ""
+		if cs.Proxies != nil {
+			for proxyName, proxy := range cs.Proxies {
+				proxyInfo += fmt.Sprintf("    %s:\n", proxyName)
+				proxyInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Connections"), proxy.ConnectionsTotal)
+				proxyInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Active connections"), proxy.ConnectionsActive)
+				proxyInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes received"), units.GetByteSizeString(proxy.BytesReceived, 2))
+				proxyInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(proxy.BytesSent, 2))
+			}
+		}
+
+		if proxyInfo != "" {
+			fmt.Println(fmt.Sprintf("  %s", i18n.G("Proxy usage:")))
+			fmt.Printf(proxyInfo)
+		}
 	}
 
 	// List snapshots
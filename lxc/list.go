@@ -34,7 +34,6 @@ type cmdList struct {
 
 	flagColumns string
 	flagFast    bool
-	flagFormat  string
 }
 
 func (c *cmdList) Command() *cobra.Command {
@@ -115,7 +114,6 @@ lxc list -c ns,user.comment:comment
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", defaultColumns, i18n.G("Columns")+"``")
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 	cmd.Flags().BoolVar(&c.flagFast, "fast", false, i18n.G("Fast mode (same as --columns=nsacPt)"))
 
 	return cmd
@@ -337,7 +335,7 @@ func (c *cmdList) showInstances(cts []api.InstanceFull, filters []string, column
 		headers = append(headers, column.Name)
 	}
 
-	return utils.RenderTable(c.flagFormat, headers, data, cts)
+	return utils.RenderTable(c.global.flagFormat, headers, data, cts)
 }
 
 func (c *cmdList) Run(cmd *cobra.Command, args []string) error {
@@ -3,6 +3,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -19,6 +23,7 @@ type cmdMonitor struct {
 	global *cmdGlobal
 
 	flagType     []string
+	flagAction   []string
 	flagPretty   bool
 	flagLogLevel string
 }
@@ -30,7 +35,10 @@ func (c *cmdMonitor) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Monitor a local or remote LXD server
 
-By default the monitor will listen to all message types.`))
+By default the monitor will listen to all message types.
+
+The monitor reconnects to the server when the connection is lost, and gets
+the events it missed in the meantime.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc monitor --type=logging
     Only show log messages.
@@ -39,13 +47,17 @@ lxc monitor --pretty --type=logging --loglevel=info
     Show a pretty log of messages with info level or higher.
 
 lxc monitor --type=lifecycle
-    Only show lifecycle events.`))
+    Only show lifecycle events.
+
+lxc monitor --type=lifecycle --action=container-*
+    Only show lifecycle events of containers.`))
 	cmd.Hidden = true
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagPretty, "pretty", false, i18n.G("Pretty rendering"))
 	cmd.Flags().StringArrayVar(&c.flagType, "type", nil, i18n.G("Event type to listen for")+"``")
 	cmd.Flags().StringVar(&c.flagLogLevel, "loglevel", "", i18n.G("Minimum level for log messages")+"``")
+	cmd.Flags().StringArrayVar(&c.flagAction, "action", nil, i18n.G("Lifecycle event action to listen for (with an optional trailing *)")+"``")
 
 	return cmd
 }
@@ -79,11 +91,6 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	listener, err := d.GetEvents()
-	if err != nil {
-		return err
-	}
-
 	logLvl := log15.LvlDebug
 	if c.flagLogLevel != "" {
 		logLvl, err = log15.LvlFromString(c.flagLogLevel)
@@ -92,26 +99,45 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	chError := make(chan error, 1)
+	// Let the server filter the events, so that it can replay the missed ones after a disconnection.
+	values := url.Values{}
+	if len(c.flagType) > 0 {
+		values.Set("type", strings.Join(c.flagType, ","))
+	}
+
+	if c.flagLogLevel != "" {
+		values.Set("level", c.flagLogLevel)
+	}
+
+	if len(c.flagAction) > 0 {
+		values.Set("action", strings.Join(c.flagAction, ","))
+	}
+
+	project := conf.ProjectOverride
+	if project == "" {
+		project = conf.Remotes[remote].Project
+	}
+
+	if project != "" {
+		values.Set("project", project)
+	}
 
-	handler := func(event api.Event) {
+	handler := func(event api.Event) error {
 		// Special handling for logging only output
 		if c.flagPretty && len(c.flagType) == 1 && shared.StringInSlice("logging", c.flagType) {
 			logEntry := api.EventLogging{}
 			err = json.Unmarshal(event.Metadata, &logEntry)
 			if err != nil {
-				chError <- err
-				return
+				return err
 			}
 
 			lvl, err := log15.LvlFromString(logEntry.Level)
 			if err != nil {
-				chError <- err
-				return
+				return err
 			}
 
 			if lvl > logLvl {
-				return
+				return nil
 			}
 
 			ctx := []interface{}{}
@@ -129,42 +155,75 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 
 			format := logging.TerminalFormat()
 			fmt.Printf("%s", format.Format(&record))
-			return
+			return nil
 		}
 
 		// Render as JSON (to expand RawMessage)
 		jsonRender, err := json.Marshal(&event)
 		if err != nil {
-			chError <- err
-			return
+			return err
 		}
 
 		// Read back to a clean interface
 		var rawEvent interface{}
 		err = json.Unmarshal(jsonRender, &rawEvent)
 		if err != nil {
-			chError <- err
-			return
+			return err
 		}
 
 		// And now print as YAML
 		render, err := yaml.Marshal(&rawEvent)
 		if err != nil {
-			chError <- err
-			return
+			return err
 		}
 
 		fmt.Printf("%s\n\n", render)
+		return nil
 	}
 
-	_, err = listener.AddHandler(c.flagType, handler)
-	if err != nil {
-		return err
-	}
+	var lastTimestamp time.Time
+	connected := false
+	for {
+		// Ask for the events missed since the last one received.
+		if !lastTimestamp.IsZero() {
+			values.Set("since", lastTimestamp.Format(time.RFC3339Nano))
+		}
+
+		conn, err := d.RawWebsocket("/events?" + values.Encode())
+		if err != nil {
+			if !connected {
+				return err
+			}
+
+			time.Sleep(time.Second)
+			continue
+		}
 
-	go func() {
-		chError <- listener.Wait()
-	}()
+		connected = true
 
-	return <-chError
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+
+			event := api.Event{}
+			err = json.Unmarshal(data, &event)
+			if err != nil || event.Type == "" {
+				continue
+			}
+
+			err = handler(event)
+			if err != nil {
+				conn.Close()
+				return err
+			}
+
+			lastTimestamp = event.Timestamp
+		}
+
+		conn.Close()
+		fmt.Fprintf(os.Stderr, i18n.G("Lost connection to the server, reconnecting...")+"\n")
+		time.Sleep(time.Second)
+	}
 }
@@ -43,7 +43,10 @@ lxc move <old name> <new name> [--instance-only]
     Rename a local instance.
 
 lxc move <instance>/<old snapshot name> <instance>/<new snapshot name>
-    Rename a snapshot.`))
+    Rename a snapshot.
+
+lxc move <instance> --storage <pool> [--instance-only]
+    Move a stopped instance to another storage pool on the same server.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the target instance")+"``")
@@ -64,7 +67,7 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 	conf := c.global.conf
 
 	// Sanity checks
-	if c.flagTarget == "" {
+	if c.flagTarget == "" && c.flagStorage == "" {
 		exit, err := c.global.CheckArgs(cmd, args, 2, 2)
 		if exit {
 			return err
@@ -148,6 +151,34 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 		destResource = args[1]
 	}
 
+	// Moving an instance between storage pools of the same server is done
+	// server side through the dedicated API.
+	if sourceRemote == destRemote && c.flagTarget == "" && c.flagStorage != "" && c.flagTargetProject == "" {
+		if c.flagConfig != nil || c.flagDevice != nil || c.flagProfile != nil || c.flagNoProfiles {
+			return fmt.Errorf(i18n.G("Can't override configuration or profiles when moving to another storage pool"))
+		}
+
+		if c.flagMode != moveDefaultMode {
+			return fmt.Errorf(i18n.G("The --mode flag can't be used with --storage"))
+		}
+
+		return movePoolInstance(conf, sourceResource, destResource, api.InstancePost{Pool: c.flagStorage, InstanceOnly: c.flagInstanceOnly})
+	}
+
+	// Moving an instance between projects of the same server is also done
+	// server side.
+	if sourceRemote == destRemote && c.flagTarget == "" && c.flagStorage == "" && c.flagTargetProject != "" {
+		if c.flagConfig != nil || c.flagDevice != nil || c.flagProfile != nil || c.flagNoProfiles {
+			return fmt.Errorf(i18n.G("Can't override configuration or profiles when moving to another project"))
+		}
+
+		if c.flagMode != moveDefaultMode {
+			return fmt.Errorf(i18n.G("The --mode flag can't be used with --target-project"))
+		}
+
+		return movePoolInstance(conf, sourceResource, destResource, api.InstancePost{Project: c.flagTargetProject, InstanceOnly: c.flagInstanceOnly})
+	}
+
 	// If the target option was specified, we're moving an instance from a
 	// cluster member to another, let's use the dedicated API.
 	if c.flagTarget != "" {
@@ -246,5 +277,55 @@ func moveClusterInstance(conf *config.Config, sourceResource, destResource, targ
 	return nil
 }
 
+// Move an instance to another storage pool or project of the same server using the POST /instances/<name> API.
+func movePoolInstance(conf *config.Config, sourceResource, destResource string, req api.InstancePost) error {
+	// Parse the source.
+	sourceRemote, sourceName, err := conf.ParseRemote(sourceResource)
+	if err != nil {
+		return err
+	}
+
+	// Parse the destination.
+	_, destName, err := conf.ParseRemote(destResource)
+	if err != nil {
+		return err
+	}
+
+	// Make sure we have an instance name.
+	if sourceName == "" {
+		return fmt.Errorf(i18n.G("You must specify a source instance name"))
+	}
+
+	if shared.IsSnapshot(sourceName) {
+		return fmt.Errorf(i18n.G("Snapshots can't be moved on their own"))
+	}
+
+	// The destination name is optional.
+	if destName == "" {
+		destName = sourceName
+	}
+
+	// Connect to the server.
+	source, err := conf.GetInstanceServer(sourceRemote)
+	if err != nil {
+		return err
+	}
+
+	req.Name = destName
+	req.Migration = true
+
+	op, err := source.MigrateInstance(sourceName, req)
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Migration API failure"))
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Migration operation failure"))
+	}
+
+	return nil
+}
+
 // Default migration mode when moving an instance.
 const moveDefaultMode = "pull"
@@ -33,6 +33,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Manage and attach instances to networks`))
 
+	// ACL
+	networkACLCmd := cmdNetworkACL{global: c.global}
+	cmd.AddCommand(networkACLCmd.Command())
+
 	// Attach
 	networkAttachCmd := cmdNetworkAttach{global: c.global, network: c}
 	cmd.AddCommand(networkAttachCmd.Command())
@@ -61,6 +65,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkEditCmd := cmdNetworkEdit{global: c.global, network: c}
 	cmd.AddCommand(networkEditCmd.Command())
 
+	// Forward
+	networkForwardCmd := cmdNetworkForward{global: c.global}
+	cmd.AddCommand(networkForwardCmd.Command())
+
 	// Get
 	networkGetCmd := cmdNetworkGet{global: c.global, network: c}
 	cmd.AddCommand(networkGetCmd.Command())
@@ -69,6 +77,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkInfoCmd := cmdNetworkInfo{global: c.global, network: c}
 	cmd.AddCommand(networkInfoCmd.Command())
 
+	// Lease
+	networkLeaseCmd := cmdNetworkLease{global: c.global}
+	cmd.AddCommand(networkLeaseCmd.Command())
+
 	// List
 	networkListCmd := cmdNetworkList{global: c.global, network: c}
 	cmd.AddCommand(networkListCmd.Command())
@@ -77,6 +89,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkListLeasesCmd := cmdNetworkListLeases{global: c.global, network: c}
 	cmd.AddCommand(networkListLeasesCmd.Command())
 
+	// Load balancer
+	networkLoadBalancerCmd := cmdNetworkLoadBalancer{global: c.global}
+	cmd.AddCommand(networkLoadBalancerCmd.Command())
+
 	// Rename
 	networkRenameCmd := cmdNetworkRename{global: c.global, network: c}
 	cmd.AddCommand(networkRenameCmd.Command())
@@ -93,6 +109,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkUnsetCmd := cmdNetworkUnset{global: c.global, network: c, networkSet: &networkSetCmd}
 	cmd.AddCommand(networkUnsetCmd.Command())
 
+	// Zone
+	networkZoneCmd := cmdNetworkZone{global: c.global}
+	cmd.AddCommand(networkZoneCmd.Command())
+
 	return cmd
 }
 
@@ -756,6 +776,11 @@ func (c *cmdNetworkInfo) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Missing network name"))
 	}
 
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Targeting
 	if c.network.flagTarget != "" {
 		if !client.IsClustered() {
@@ -770,6 +795,10 @@ func (c *cmdNetworkInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if rawFormat {
+		return utils.RenderTable(c.global.flagFormat, nil, nil, state)
+	}
+
 	// Interface information
 	fmt.Printf(i18n.G("Name: %s")+"\n", resource.name)
 	fmt.Printf(i18n.G("MAC address: %s")+"\n", state.Hwaddr)
@@ -790,6 +819,10 @@ func (c *cmdNetworkInfo) Run(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(state.Counters.BytesSent, 2))
 	fmt.Printf("  %s: %d\n", i18n.G("Packets received"), state.Counters.PacketsReceived)
 	fmt.Printf("  %s: %d\n", i18n.G("Packets sent"), state.Counters.PacketsSent)
+	fmt.Printf("  %s: %d\n", i18n.G("Errors received"), state.Counters.ErrorsReceived)
+	fmt.Printf("  %s: %d\n", i18n.G("Errors sent"), state.Counters.ErrorsSent)
+	fmt.Printf("  %s: %d\n", i18n.G("Packets dropped inbound"), state.Counters.PacketsDroppedInbound)
+	fmt.Printf("  %s: %d\n", i18n.G("Packets dropped outbound"), state.Counters.PacketsDroppedOutbound)
 
 	return nil
 }
@@ -798,8 +831,6 @@ func (c *cmdNetworkInfo) Run(cmd *cobra.Command, args []string) error {
 type cmdNetworkList struct {
 	global  *cmdGlobal
 	network *cmdNetwork
-
-	flagFormat string
 }
 
 func (c *cmdNetworkList) Command() *cobra.Command {
@@ -811,7 +842,6 @@ func (c *cmdNetworkList) Command() *cobra.Command {
 		`List available networks`))
 
 	cmd.RunE = c.Run
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	return cmd
 }
@@ -887,15 +917,13 @@ func (c *cmdNetworkList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("STATE"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, networks)
+	return utils.RenderTable(c.global.flagFormat, header, data, networks)
 }
 
 // List leases
 type cmdNetworkListLeases struct {
 	global  *cmdGlobal
 	network *cmdNetwork
-
-	flagFormat string
 }
 
 func (c *cmdNetworkListLeases) Command() *cobra.Command {
@@ -904,7 +932,6 @@ func (c *cmdNetworkListLeases) Command() *cobra.Command {
 	cmd.Short = i18n.G("List DHCP leases")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List DHCP leases`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -957,7 +984,7 @@ func (c *cmdNetworkListLeases) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, leases)
+	return utils.RenderTable(c.global.flagFormat, header, data, leases)
 }
 
 // Rename
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkACL struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkACL) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("acl")
+	cmd.Short = i18n.G("Manage network ACLs")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network ACLs`))
+
+	// Create
+	networkACLCreateCmd := cmdNetworkACLCreate{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLCreateCmd.Command())
+
+	// Delete
+	networkACLDeleteCmd := cmdNetworkACLDelete{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLDeleteCmd.Command())
+
+	// Edit
+	networkACLEditCmd := cmdNetworkACLEdit{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLEditCmd.Command())
+
+	// List
+	networkACLListCmd := cmdNetworkACLList{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLListCmd.Command())
+
+	// Rename
+	networkACLRenameCmd := cmdNetworkACLRename{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLRenameCmd.Command())
+
+	// Show
+	networkACLShowCmd := cmdNetworkACLShow{global: c.global, networkACL: c}
+	cmd.AddCommand(networkACLShowCmd.Command())
+
+	return cmd
+}
+
+// Create
+type cmdNetworkACLCreate struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<ACL> [key=value...]")
+	cmd.Short = i18n.G("Create new network ACLs")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network ACLs`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network acl create web
+    Create an empty ACL called "web"
+
+lxc network acl create web < web.yaml
+    Create an ACL called "web" with the rules and configuration from web.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network ACL name"))
+	}
+
+	acl := api.NetworkACLsPost{}
+
+	// If stdin isn't a terminal, read the rules and configuration from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(contents, &acl.NetworkACLPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	acl.Name = resource.name
+	if acl.Config == nil {
+		acl.Config = map[string]string{}
+	}
+
+	for i := 1; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		acl.Config[entry[0]] = entry[1]
+	}
+
+	err = resource.server.CreateNetworkACL(acl)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network ACL %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkACLDelete struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<ACL>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network ACLs")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network ACLs`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network ACL name"))
+	}
+
+	// Delete the ACL
+	err = resource.server.DeleteNetworkACL(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network ACL %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkACLEdit struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<ACL>")
+	cmd.Short = i18n.G("Edit network ACL configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network ACL configurations as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the network ACL.
+### Any line starting with a '# will be ignored.
+###
+### A network ACL consists of ordered lists of ingress and egress rules,
+### the first matching rule decides what happens to the traffic.
+###
+### An example would look like:
+### name: web
+### description: Web servers
+### ingress:
+### - action: allow
+###   protocol: tcp
+###   destination_port: 80,443
+###   state: enabled
+### egress:
+### - action: reject
+###   destination: 10.0.0.0/8
+###   state: enabled
+### config:
+###   user.owner: ops
+###
+### Note that the name and used_by fields cannot be changed.`)
+}
+
+func (c *cmdNetworkACLEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network ACL name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkACLPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkACL(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	acl, etag, err := resource.server.GetNetworkACL(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&acl)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkACLPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkACL(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// List
+type cmdNetworkACLList struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network ACLs")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network ACLs`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// List the ACLs
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	acls, err := resource.server.GetNetworkACLs()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, acl := range acls {
+		details := []string{
+			acl.Name,
+			acl.Description,
+			fmt.Sprintf("%d", len(acl.Ingress)),
+			fmt.Sprintf("%d", len(acl.Egress)),
+			fmt.Sprintf("%d", len(acl.UsedBy)),
+		}
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("INGRESS RULES"),
+		i18n.G("EGRESS RULES"),
+		i18n.G("USED BY"),
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, acls)
+}
+
+// Rename
+type cmdNetworkACLRename struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLRename) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("rename [<remote>:]<ACL> <new-name>")
+	cmd.Aliases = []string{"mv"}
+	cmd.Short = i18n.G("Rename network ACLs")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Rename network ACLs`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLRename) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network ACL name"))
+	}
+
+	// Rename the ACL
+	err = resource.server.RenameNetworkACL(resource.name, api.NetworkACLPost{Name: args[1]})
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network ACL %s renamed to %s")+"\n", resource.name, args[1])
+	}
+
+	return nil
+}
+
+// Show
+type cmdNetworkACLShow struct {
+	global     *cmdGlobal
+	networkACL *cmdNetworkACL
+}
+
+func (c *cmdNetworkACLShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<ACL>")
+	cmd.Short = i18n.G("Show network ACL configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network ACL configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkACLShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network ACL name"))
+	}
+
+	// Show the ACL
+	acl, _, err := resource.server.GetNetworkACL(resource.name)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(acl.UsedBy)
+
+	data, err := yaml.Marshal(&acl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkForward struct {
+	global *cmdGlobal
+
+	flagTarget string
+}
+
+func (c *cmdNetworkForward) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("forward")
+	cmd.Short = i18n.G("Manage network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network address forwards`))
+
+	// Create
+	networkForwardCreateCmd := cmdNetworkForwardCreate{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardCreateCmd.Command())
+
+	// Delete
+	networkForwardDeleteCmd := cmdNetworkForwardDelete{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardDeleteCmd.Command())
+
+	// Edit
+	networkForwardEditCmd := cmdNetworkForwardEdit{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardEditCmd.Command())
+
+	// List
+	networkForwardListCmd := cmdNetworkForwardList{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardListCmd.Command())
+
+	// Port
+	networkForwardPortCmd := cmdNetworkForwardPort{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardPortCmd.Command())
+
+	// Show
+	networkForwardShowCmd := cmdNetworkForwardShow{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardShowCmd.Command())
+
+	return cmd
+}
+
+// Create
+type cmdNetworkForwardCreate struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<network> <listen address> [key=value...]")
+	cmd.Short = i18n.G("Create new network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network address forwards`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network forward create lxdbr0 192.0.2.1 target_address=10.0.0.2
+    Forward all traffic for 192.0.2.1 to 10.0.0.2
+
+lxc network forward create lxdbr0 192.0.2.1 < forward.yaml
+    Create an address forward with the ports and configuration from forward.yaml`))
+
+	cmd.Flags().StringVar(&c.networkForward.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	client := resource.server
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	forward := api.NetworkForwardsPost{}
+
+	// If stdin isn't a terminal, read the ports and configuration from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(contents, &forward.NetworkForwardPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	forward.ListenAddress = args[1]
+	if forward.Config == nil {
+		forward.Config = map[string]string{}
+	}
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		forward.Config[entry[0]] = entry[1]
+	}
+
+	// The forward is applied on the target member.
+	if c.networkForward.flagTarget != "" {
+		client = client.UseTarget(c.networkForward.flagTarget)
+	}
+
+	err = client.CreateNetworkForward(resource.name, forward)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network forward %s created")+"\n", forward.ListenAddress)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkForwardDelete struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<network> <listen address>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network address forwards`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// Delete the forward
+	err = resource.server.DeleteNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network forward %s deleted")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkForwardEdit struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Edit network address forwards as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network address forwards as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the network address forward.
+### Any line starting with a '# will be ignored.
+###
+### An address forward consists of a list of port specifications and an
+### optional default target address for all other traffic.
+###
+### An example would look like:
+### listen_address: 192.0.2.1
+### description: Web servers
+### ports:
+### - protocol: tcp
+###   listen_port: 80,443
+###   target_address: 10.0.0.2
+### - protocol: tcp
+###   listen_port: "2222"
+###   target_port: "22"
+###   target_address: 10.0.0.3
+### config:
+###   target_address: 10.0.0.4
+###
+### Note that the listen_address and location fields cannot be changed.`)
+}
+
+func (c *cmdNetworkForwardEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkForwardPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkForward(resource.name, args[1], newdata, "")
+	}
+
+	// Extract the current value
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&forward)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkForwardPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkForward(resource.name, args[1], newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// List
+type cmdNetworkForwardList struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<network>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network address forwards`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	forwards, err := resource.server.GetNetworkForwards(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, forward := range forwards {
+		details := []string{
+			forward.ListenAddress,
+			forward.Description,
+			forward.Config["target_address"],
+			fmt.Sprintf("%d", len(forward.Ports)),
+		}
+
+		if resource.server.IsClustered() {
+			details = append(details, forward.Location)
+		}
+
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("LISTEN ADDRESS"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("DEFAULT TARGET ADDRESS"),
+		i18n.G("PORTS"),
+	}
+
+	if resource.server.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, forwards)
+}
+
+// Show
+type cmdNetworkForwardShow struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Show network address forward configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network address forward configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// Show the forward
+	forward, _, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&forward)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Port
+type cmdNetworkForwardPort struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardPort) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("port")
+	cmd.Short = i18n.G("Manage network address forward ports")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network address forward ports`))
+
+	// Add
+	networkForwardPortAddCmd := cmdNetworkForwardPortAdd{global: c.global, networkForward: c.networkForward}
+	cmd.AddCommand(networkForwardPortAddCmd.Command())
+
+	// Remove
+	networkForwardPortRemoveCmd := cmdNetworkForwardPortRemove{global: c.global, networkForward: c.networkForward}
+	cmd.AddCommand(networkForwardPortRemoveCmd.Command())
+
+	return cmd
+}
+
+// Port add
+type cmdNetworkForwardPortAdd struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardPortAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<network> <listen address> <protocol> <listen port(s)> <target address> [<target port(s)>]")
+	cmd.Short = i18n.G("Add ports to network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add ports to network address forwards`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardPortAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 5, 6)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	port := api.NetworkForwardPort{
+		Protocol:      args[2],
+		ListenPort:    args[3],
+		TargetAddress: args[4],
+	}
+
+	if len(args) > 5 {
+		port.TargetPort = args[5]
+	}
+
+	forward.Ports = append(forward.Ports, port)
+
+	return resource.server.UpdateNetworkForward(resource.name, forward.ListenAddress, forward.Writable(), etag)
+}
+
+// Port remove
+type cmdNetworkForwardPortRemove struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardPortRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<network> <listen address> <protocol> <listen port(s)>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Remove ports from network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove ports from network address forwards`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardPortRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 4)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	ports := []api.NetworkForwardPort{}
+	for _, port := range forward.Ports {
+		if port.Protocol == args[2] && port.ListenPort == args[3] {
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	if len(ports) == len(forward.Ports) {
+		return fmt.Errorf(i18n.G("No matching port found"))
+	}
+
+	forward.Ports = ports
+
+	return resource.server.UpdateNetworkForward(resource.name, forward.ListenAddress, forward.Writable(), etag)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdNetworkLease struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkLease) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("lease")
+	cmd.Short = i18n.G("Manage DHCP lease reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage DHCP lease reservations`))
+
+	// Reserve
+	networkLeaseReserveCmd := cmdNetworkLeaseReserve{global: c.global, networkLease: c}
+	cmd.AddCommand(networkLeaseReserveCmd.Command())
+
+	// Revoke
+	networkLeaseRevokeCmd := cmdNetworkLeaseRevoke{global: c.global, networkLease: c}
+	cmd.AddCommand(networkLeaseRevokeCmd.Command())
+
+	return cmd
+}
+
+// Reserve
+type cmdNetworkLeaseReserve struct {
+	global       *cmdGlobal
+	networkLease *cmdNetworkLease
+}
+
+func (c *cmdNetworkLeaseReserve) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("reserve [<remote>:]<network> <MAC address> <IP address> <hostname>")
+	cmd.Short = i18n.G("Reserve an address for a MAC address")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Reserve an address for a MAC address`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network lease reserve lxdbr0 00:16:3e:aa:bb:cc 10.0.0.250 printer
+    Hand out 10.0.0.250 to the device with MAC address 00:16:3e:aa:bb:cc`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLeaseReserve) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 4)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	lease := api.NetworkLeasesPost{
+		Hwaddr:   args[1],
+		Address:  args[2],
+		Hostname: args[3],
+	}
+
+	err = resource.server.CreateNetworkLease(resource.name, lease)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Address %s reserved for %s")+"\n", lease.Address, lease.Hwaddr)
+	}
+
+	return nil
+}
+
+// Revoke
+type cmdNetworkLeaseRevoke struct {
+	global       *cmdGlobal
+	networkLease *cmdNetworkLease
+}
+
+func (c *cmdNetworkLeaseRevoke) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke [<remote>:]<network> <IP address>")
+	cmd.Short = i18n.G("Remove an address reservation")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove an address reservation`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLeaseRevoke) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	err = resource.server.DeleteNetworkLease(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Address reservation %s removed")+"\n", args[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,809 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkLoadBalancer struct {
+	global *cmdGlobal
+
+	flagTarget string
+}
+
+func (c *cmdNetworkLoadBalancer) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("load-balancer")
+	cmd.Short = i18n.G("Manage network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancers`))
+
+	// Create
+	networkLoadBalancerCreateCmd := cmdNetworkLoadBalancerCreate{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerCreateCmd.Command())
+
+	// Delete
+	networkLoadBalancerDeleteCmd := cmdNetworkLoadBalancerDelete{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerDeleteCmd.Command())
+
+	// Backend
+	networkLoadBalancerBackendCmd := cmdNetworkLoadBalancerBackend{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerBackendCmd.Command())
+
+	// Edit
+	networkLoadBalancerEditCmd := cmdNetworkLoadBalancerEdit{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerEditCmd.Command())
+
+	// Info
+	networkLoadBalancerInfoCmd := cmdNetworkLoadBalancerInfo{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerInfoCmd.Command())
+
+	// List
+	networkLoadBalancerListCmd := cmdNetworkLoadBalancerList{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerListCmd.Command())
+
+	// Port
+	networkLoadBalancerPortCmd := cmdNetworkLoadBalancerPort{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerPortCmd.Command())
+
+	// Show
+	networkLoadBalancerShowCmd := cmdNetworkLoadBalancerShow{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerShowCmd.Command())
+
+	return cmd
+}
+
+// Create
+type cmdNetworkLoadBalancerCreate struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<network> <listen address> [key=value...]")
+	cmd.Short = i18n.G("Create new network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network load balancers`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network load-balancer create lxdbr0 192.0.2.1 healthcheck=true
+    Create a load balancer for 192.0.2.1 with backend health checks enabled
+
+lxc network load-balancer create lxdbr0 192.0.2.1 < lb.yaml
+    Create a load balancer with the ports and configuration from lb.yaml`))
+
+	cmd.Flags().StringVar(&c.networkLoadBalancer.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	client := resource.server
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancer := api.NetworkLoadBalancersPost{}
+
+	// If stdin isn't a terminal, read the ports and configuration from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(contents, &loadBalancer.NetworkLoadBalancerPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	loadBalancer.ListenAddress = args[1]
+	if loadBalancer.Config == nil {
+		loadBalancer.Config = map[string]string{}
+	}
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		loadBalancer.Config[entry[0]] = entry[1]
+	}
+
+	// The load balancer is applied on the target member.
+	if c.networkLoadBalancer.flagTarget != "" {
+		client = client.UseTarget(c.networkLoadBalancer.flagTarget)
+	}
+
+	err = client.CreateNetworkLoadBalancer(resource.name, loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network load balancer %s created")+"\n", loadBalancer.ListenAddress)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkLoadBalancerDelete struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<network> <listen address>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// Delete the load balancer
+	err = resource.server.DeleteNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network load balancer %s deleted")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkLoadBalancerEdit struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Edit network load balancers as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network load balancers as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the network load balancer.
+### Any line starting with a '# will be ignored.
+###
+### A load balancer consists of a list of backends and a list of port
+### specifications spreading the traffic over some of those backends.
+###
+### An example would look like:
+### listen_address: 192.0.2.1
+### description: Web servers
+### backends:
+### - name: web1
+###   target_address: 10.0.0.2
+### - name: web2
+###   target_address: 10.0.0.3
+###   target_port: "8080"
+### ports:
+### - protocol: tcp
+###   listen_port: "80"
+###   target_backend:
+###   - web1
+###   - web2
+### config:
+###   healthcheck: "true"
+###
+### Note that the listen_address and location fields cannot be changed.`)
+}
+
+func (c *cmdNetworkLoadBalancerEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkLoadBalancerPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkLoadBalancer(resource.name, args[1], newdata, "")
+	}
+
+	// Extract the current value
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkLoadBalancerPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkLoadBalancer(resource.name, args[1], newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// List
+type cmdNetworkLoadBalancerList struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<network>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancers, err := resource.server.GetNetworkLoadBalancers(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, loadBalancer := range loadBalancers {
+		details := []string{
+			loadBalancer.ListenAddress,
+			loadBalancer.Description,
+			fmt.Sprintf("%d", len(loadBalancer.Backends)),
+			fmt.Sprintf("%d", len(loadBalancer.Ports)),
+		}
+
+		if resource.server.IsClustered() {
+			details = append(details, loadBalancer.Location)
+		}
+
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("LISTEN ADDRESS"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("BACKENDS"),
+		i18n.G("PORTS"),
+	}
+
+	if resource.server.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, loadBalancers)
+}
+
+// Show
+type cmdNetworkLoadBalancerShow struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Show network load balancer configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network load balancer configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	// Show the load balancer
+	loadBalancer, _, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Info
+type cmdNetworkLoadBalancerInfo struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerInfo) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("info [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Get current load balancer status")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Get current load balancer status`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerInfo) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
+	state, err := resource.server.GetNetworkLoadBalancerState(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if rawFormat {
+		return utils.RenderTable(c.global.flagFormat, nil, nil, state)
+	}
+
+	names := []string{}
+	for name := range state.BackendHealth {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println(i18n.G("Backend health:"))
+	for _, name := range names {
+		health := state.BackendHealth[name]
+		fmt.Printf("  %s (%s): %s\n", name, health.Address, health.Status)
+	}
+
+	return nil
+}
+
+// Backend
+type cmdNetworkLoadBalancerBackend struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerBackend) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("backend")
+	cmd.Short = i18n.G("Manage network load balancer backends")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancer backends`))
+
+	// Add
+	networkLoadBalancerBackendAddCmd := cmdNetworkLoadBalancerBackendAdd{global: c.global, networkLoadBalancer: c.networkLoadBalancer}
+	cmd.AddCommand(networkLoadBalancerBackendAddCmd.Command())
+
+	// Remove
+	networkLoadBalancerBackendRemoveCmd := cmdNetworkLoadBalancerBackendRemove{global: c.global, networkLoadBalancer: c.networkLoadBalancer}
+	cmd.AddCommand(networkLoadBalancerBackendRemoveCmd.Command())
+
+	return cmd
+}
+
+// Backend add
+type cmdNetworkLoadBalancerBackendAdd struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerBackendAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<network> <listen address> <backend name> <target address> [<target port>]")
+	cmd.Short = i18n.G("Add backends to network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add backends to network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerBackendAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 5)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	backend := api.NetworkLoadBalancerBackend{
+		Name:          args[2],
+		TargetAddress: args[3],
+	}
+
+	if len(args) > 4 {
+		backend.TargetPort = args[4]
+	}
+
+	loadBalancer.Backends = append(loadBalancer.Backends, backend)
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+// Backend remove
+type cmdNetworkLoadBalancerBackendRemove struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerBackendRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<network> <listen address> <backend name>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Remove backends from network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove backends from network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerBackendRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	backends := []api.NetworkLoadBalancerBackend{}
+	for _, backend := range loadBalancer.Backends {
+		if backend.Name == args[2] {
+			continue
+		}
+
+		backends = append(backends, backend)
+	}
+
+	if len(backends) == len(loadBalancer.Backends) {
+		return fmt.Errorf(i18n.G("No matching backend found"))
+	}
+
+	loadBalancer.Backends = backends
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+// Port
+type cmdNetworkLoadBalancerPort struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerPort) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("port")
+	cmd.Short = i18n.G("Manage network load balancer ports")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancer ports`))
+
+	// Add
+	networkLoadBalancerPortAddCmd := cmdNetworkLoadBalancerPortAdd{global: c.global, networkLoadBalancer: c.networkLoadBalancer}
+	cmd.AddCommand(networkLoadBalancerPortAddCmd.Command())
+
+	// Remove
+	networkLoadBalancerPortRemoveCmd := cmdNetworkLoadBalancerPortRemove{global: c.global, networkLoadBalancer: c.networkLoadBalancer}
+	cmd.AddCommand(networkLoadBalancerPortRemoveCmd.Command())
+
+	return cmd
+}
+
+// Port add
+type cmdNetworkLoadBalancerPortAdd struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerPortAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<network> <listen address> <protocol> <listen port(s)> <backend name>[,<backend name>...]")
+	cmd.Short = i18n.G("Add ports to network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add ports to network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerPortAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 5, 5)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	port := api.NetworkLoadBalancerPort{
+		Protocol:      args[2],
+		ListenPort:    args[3],
+		TargetBackend: strings.Split(args[4], ","),
+	}
+
+	loadBalancer.Ports = append(loadBalancer.Ports, port)
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+// Port remove
+type cmdNetworkLoadBalancerPortRemove struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerPortRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<network> <listen address> <protocol> <listen port(s)>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Remove ports from network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove ports from network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerPortRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 4)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	ports := []api.NetworkLoadBalancerPort{}
+	for _, port := range loadBalancer.Ports {
+		if port.Protocol == args[2] && port.ListenPort == args[3] {
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	if len(ports) == len(loadBalancer.Ports) {
+		return fmt.Errorf(i18n.G("No matching port found"))
+	}
+
+	loadBalancer.Ports = ports
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
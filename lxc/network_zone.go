@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkZone struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkZone) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("zone")
+	cmd.Short = i18n.G("Manage network zones")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network zones`))
+
+	// Create
+	networkZoneCreateCmd := cmdNetworkZoneCreate{global: c.global, networkZone: c}
+	cmd.AddCommand(networkZoneCreateCmd.Command())
+
+	// Delete
+	networkZoneDeleteCmd := cmdNetworkZoneDelete{global: c.global, networkZone: c}
+	cmd.AddCommand(networkZoneDeleteCmd.Command())
+
+	// Edit
+	networkZoneEditCmd := cmdNetworkZoneEdit{global: c.global, networkZone: c}
+	cmd.AddCommand(networkZoneEditCmd.Command())
+
+	// List
+	networkZoneListCmd := cmdNetworkZoneList{global: c.global, networkZone: c}
+	cmd.AddCommand(networkZoneListCmd.Command())
+
+	// Show
+	networkZoneShowCmd := cmdNetworkZoneShow{global: c.global, networkZone: c}
+	cmd.AddCommand(networkZoneShowCmd.Command())
+
+	return cmd
+}
+
+// Create
+type cmdNetworkZoneCreate struct {
+	global      *cmdGlobal
+	networkZone *cmdNetworkZone
+}
+
+func (c *cmdNetworkZoneCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<Zone> [key=value...]")
+	cmd.Short = i18n.G("Create new network zones")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network zones`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network zone create lxd.example.net
+    Create an empty zone called "lxd.example.net"
+
+lxc network zone create lxd.example.net < zone.yaml
+    Create a zone called "lxd.example.net" with the configuration from zone.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkZoneCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network zone name"))
+	}
+
+	zone := api.NetworkZonesPost{}
+
+	// If stdin isn't a terminal, read the configuration from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(contents, &zone.NetworkZonePut)
+		if err != nil {
+			return err
+		}
+	}
+
+	zone.Name = resource.name
+	if zone.Config == nil {
+		zone.Config = map[string]string{}
+	}
+
+	for i := 1; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		zone.Config[entry[0]] = entry[1]
+	}
+
+	err = resource.server.CreateNetworkZone(zone)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network zone %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkZoneDelete struct {
+	global      *cmdGlobal
+	networkZone *cmdNetworkZone
+}
+
+func (c *cmdNetworkZoneDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<Zone>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network zones")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network zones`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkZoneDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network zone name"))
+	}
+
+	// Delete the zone
+	err = resource.server.DeleteNetworkZone(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network zone %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkZoneEdit struct {
+	global      *cmdGlobal
+	networkZone *cmdNetworkZone
+}
+
+func (c *cmdNetworkZoneEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<Zone>")
+	cmd.Short = i18n.G("Edit network zone configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network zone configurations as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkZoneEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the network zone.
+### Any line starting with a '# will be ignored.
+###
+### A network zone holds the DNS records of the instances on the networks
+### using it, served by the built-in DNS server.
+###
+### An example would look like:
+### name: lxd.example.net
+### description: Instances
+### config:
+###   dns.nameservers: ns1.example.net
+###   dns.peers.ns1.address: 192.0.2.53
+###
+### Note that the name and used_by fields cannot be changed.`)
+}
+
+func (c *cmdNetworkZoneEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network zone name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkZonePut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkZone(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	zone, etag, err := resource.server.GetNetworkZone(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&zone)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkZonePut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkZone(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// List
+type cmdNetworkZoneList struct {
+	global      *cmdGlobal
+	networkZone *cmdNetworkZone
+}
+
+func (c *cmdNetworkZoneList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network zones")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network zones`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkZoneList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// List the zones
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	zones, err := resource.server.GetNetworkZones()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, zone := range zones {
+		details := []string{
+			zone.Name,
+			zone.Description,
+			fmt.Sprintf("%d", len(zone.UsedBy)),
+		}
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("USED BY"),
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, zones)
+}
+
+// Show
+type cmdNetworkZoneShow struct {
+	global      *cmdGlobal
+	networkZone *cmdNetworkZone
+}
+
+func (c *cmdNetworkZoneShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<Zone>")
+	cmd.Short = i18n.G("Show network zone configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network zone configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkZoneShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing network zone name"))
+	}
+
+	// Show the zone
+	zone, _, err := resource.server.GetNetworkZone(resource.name)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(zone.UsedBy)
+
+	data, err := yaml.Marshal(&zone)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
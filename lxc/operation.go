@@ -90,8 +90,6 @@ func (c *cmdOperationDelete) Run(cmd *cobra.Command, args []string) error {
 type cmdOperationList struct {
 	global    *cmdGlobal
 	operation *cmdOperation
-
-	flagFormat string
 }
 
 func (c *cmdOperationList) Command() *cobra.Command {
@@ -101,7 +99,6 @@ func (c *cmdOperationList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List background operations")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List background operations`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -165,7 +162,7 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, operations)
+	return utils.RenderTable(c.global.flagFormat, header, data, operations)
 }
 
 // Show
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -395,6 +396,8 @@ func (c *cmdProfileDelete) Run(cmd *cobra.Command, args []string) error {
 type cmdProfileEdit struct {
 	global  *cmdGlobal
 	profile *cmdProfile
+
+	flagDryRun bool
 }
 
 func (c *cmdProfileEdit) Command() *cobra.Command {
@@ -405,8 +408,12 @@ func (c *cmdProfileEdit) Command() *cobra.Command {
 		`Edit profile configurations as YAML`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc profile edit <profile> < profile.yaml
-    Update a profile using the content of profile.yaml`))
+    Update a profile using the content of profile.yaml
+
+lxc profile edit <profile> --dry-run < profile.yaml
+    Show the instances the update would change or break, without updating the profile`))
 
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, i18n.G("Show the effect of the update on instances without applying it"))
 	cmd.RunE = c.Run
 
 	return cmd
@@ -465,7 +472,7 @@ func (c *cmdProfileEdit) Run(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		return resource.server.UpdateProfile(resource.name, newdata, "")
+		return c.update(resource.server, resource.name, newdata, "")
 	}
 
 	// Extract the current value
@@ -490,7 +497,7 @@ func (c *cmdProfileEdit) Run(cmd *cobra.Command, args []string) error {
 		newdata := api.ProfilePut{}
 		err = yaml.Unmarshal(content, &newdata)
 		if err == nil {
-			err = resource.server.UpdateProfile(resource.name, newdata, etag)
+			err = c.update(resource.server, resource.name, newdata, etag)
 		}
 
 		// Respawn the editor
@@ -515,6 +522,27 @@ func (c *cmdProfileEdit) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Update the profile, or show the effect the update would have on its instances in dry-run mode.
+func (c *cmdProfileEdit) update(server lxd.InstanceServer, name string, profile api.ProfilePut, etag string) error {
+	if !c.flagDryRun {
+		return server.UpdateProfile(name, profile, etag)
+	}
+
+	impacts, err := server.UpdateProfileDryRun(name, profile, etag)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&impacts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
 // Get
 type cmdProfileGet struct {
 	global  *cmdGlobal
@@ -564,9 +592,8 @@ func (c *cmdProfileGet) Run(cmd *cobra.Command, args []string) error {
 
 // List
 type cmdProfileList struct {
-	global     *cmdGlobal
-	profile    *cmdProfile
-	flagFormat string
+	global  *cmdGlobal
+	profile *cmdProfile
 }
 
 func (c *cmdProfileList) Command() *cobra.Command {
@@ -578,7 +605,6 @@ func (c *cmdProfileList) Command() *cobra.Command {
 		`List profiles`))
 
 	cmd.RunE = c.Run
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	return cmd
 }
@@ -620,7 +646,7 @@ func (c *cmdProfileList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("NAME"),
 		i18n.G("USED BY")}
 
-	return utils.RenderTable(c.flagFormat, header, data, profiles)
+	return utils.RenderTable(c.global.flagFormat, header, data, profiles)
 }
 
 // Remove
@@ -812,6 +838,8 @@ func (c *cmdProfileSet) Run(cmd *cobra.Command, args []string) error {
 type cmdProfileShow struct {
 	global  *cmdGlobal
 	profile *cmdProfile
+
+	flagExpanded bool
 }
 
 func (c *cmdProfileShow) Command() *cobra.Command {
@@ -819,8 +847,12 @@ func (c *cmdProfileShow) Command() *cobra.Command {
 	cmd.Use = i18n.G("show [<remote>:]<profile>")
 	cmd.Short = i18n.G("Show profile configurations")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Show profile configurations`))
+		`Show profile configurations
+
+The expanded configuration includes the config and devices inherited from the parents
+of the profile, along with the order in which the profiles were applied.`))
 
+	cmd.Flags().BoolVarP(&c.flagExpanded, "expanded", "e", false, i18n.G("Show the expanded configuration"))
 	cmd.RunE = c.Run
 
 	return cmd
@@ -846,7 +878,12 @@ func (c *cmdProfileShow) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show the profile
-	profile, _, err := resource.server.GetProfile(resource.name)
+	var profile *api.Profile
+	if c.flagExpanded {
+		profile, _, err = resource.server.GetProfileExpanded(resource.name)
+	} else {
+		profile, _, err = resource.server.GetProfile(resource.name)
+	}
 	if err != nil {
 		return err
 	}
@@ -16,6 +16,7 @@ import (
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/lxc/lxd/shared/termios"
+	"github.com/lxc/lxd/shared/units"
 )
 
 type cmdProject struct {
@@ -45,6 +46,10 @@ func (c *cmdProject) Command() *cobra.Command {
 	projectGetCmd := cmdProjectGet{global: c.global, project: c}
 	cmd.AddCommand(projectGetCmd.Command())
 
+	// Info
+	projectInfoCmd := cmdProjectInfo{global: c.global, project: c}
+	cmd.AddCommand(projectInfoCmd.Command())
+
 	// List
 	projectListCmd := cmdProjectList{global: c.global, project: c}
 	cmd.AddCommand(projectListCmd.Command())
@@ -74,9 +79,10 @@ func (c *cmdProject) Command() *cobra.Command {
 
 // Create
 type cmdProjectCreate struct {
-	global     *cmdGlobal
-	project    *cmdProject
-	flagConfig []string
+	global            *cmdGlobal
+	project           *cmdProject
+	flagConfig        []string
+	flagProfileSource string
 }
 
 func (c *cmdProjectCreate) Command() *cobra.Command {
@@ -86,6 +92,7 @@ func (c *cmdProjectCreate) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Create projects`))
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the new project")+"``")
+	cmd.Flags().StringVar(&c.flagProfileSource, "profile-source", "", i18n.G("Profile to seed the default profile from, in the [<project>/]<profile> form")+"``")
 
 	cmd.RunE = c.Run
 
@@ -125,6 +132,16 @@ func (c *cmdProjectCreate) Run(cmd *cobra.Command, args []string) error {
 		project.Config[fields[0]] = fields[1]
 	}
 
+	if c.flagProfileSource != "" {
+		project.DefaultProfileSource = &api.ProjectProfileSource{Profile: c.flagProfileSource}
+
+		fields := strings.SplitN(c.flagProfileSource, "/", 2)
+		if len(fields) == 2 {
+			project.DefaultProfileSource.Project = fields[0]
+			project.DefaultProfileSource.Profile = fields[1]
+		}
+	}
+
 	err = resource.server.CreateProject(project)
 	if err != nil {
 		return err
@@ -141,6 +158,8 @@ func (c *cmdProjectCreate) Run(cmd *cobra.Command, args []string) error {
 type cmdProjectDelete struct {
 	global  *cmdGlobal
 	project *cmdProject
+
+	flagForce bool
 }
 
 func (c *cmdProjectDelete) Command() *cobra.Command {
@@ -149,7 +168,10 @@ func (c *cmdProjectDelete) Command() *cobra.Command {
 	cmd.Aliases = []string{"rm"}
 	cmd.Short = i18n.G("Delete projects")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Delete projects`))
+		`Delete projects
+
+With --force, all the instances, images, profiles and storage volumes of the project are deleted along with it.`))
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Delete the project along with everything it contains"))
 
 	cmd.RunE = c.Run
 
@@ -181,9 +203,35 @@ func (c *cmdProjectDelete) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Delete the project
-	err = resource.server.DeleteProject(resource.name)
-	if err != nil {
-		return err
+	if c.flagForce {
+		op, err := resource.server.DeleteProjectForce(resource.name)
+		if err != nil {
+			return err
+		}
+
+		progress := utils.ProgressRenderer{
+			Format: i18n.G("Deleting the project: %s"),
+			Quiet:  c.global.flagQuiet,
+		}
+
+		_, err = op.AddHandler(progress.UpdateOp)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		err = utils.CancelableWait(op, &progress)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		progress.Done("")
+	} else {
+		err = resource.server.DeleteProject(resource.name)
+		if err != nil {
+			return err
+		}
 	}
 
 	if !c.global.flagQuiet {
@@ -368,12 +416,82 @@ func (c *cmdProjectGet) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Info
+type cmdProjectInfo struct {
+	global  *cmdGlobal
+	project *cmdProject
+}
+
+func (c *cmdProjectInfo) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("info [<remote>:]<project>")
+	cmd.Short = i18n.G("Get a summary of resource allocations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Get a summary of resource allocations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdProjectInfo) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing project name"))
+	}
+
+	// Get the current allocations
+	projectState, err := resource.server.GetProjectState(resource.name)
+	if err != nil {
+		return err
+	}
+
+	// Render the output
+	format := func(k string, value int64) string {
+		if k == "memory" || k == "disk" || strings.HasPrefix(k, "disk.") {
+			return units.GetByteSizeString(value, 2)
+		}
+
+		return fmt.Sprintf("%d", value)
+	}
+
+	data := [][]string{}
+	for k, v := range projectState.Resources {
+		limit := i18n.G("UNLIMITED")
+		if v.Limit >= 0 {
+			limit = format(k, v.Limit)
+		}
+
+		data = append(data, []string{strings.ToUpper(k), limit, format(k, v.Usage)})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("RESOURCE"),
+		i18n.G("LIMIT"),
+		i18n.G("USAGE"),
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, projectState)
+}
+
 // List
 type cmdProjectList struct {
 	global  *cmdGlobal
 	project *cmdProject
-
-	flagFormat string
 }
 
 func (c *cmdProjectList) Command() *cobra.Command {
@@ -383,7 +501,6 @@ func (c *cmdProjectList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List projects")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List projects`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -441,13 +558,18 @@ func (c *cmdProjectList) Run(cmd *cobra.Command, args []string) error {
 			storageVolumes = i18n.G("YES")
 		}
 
+		networks := i18n.G("NO")
+		if shared.IsTrue(project.Config["features.networks"]) {
+			networks = i18n.G("YES")
+		}
+
 		name := project.Name
 		if name == currentProject {
 			name = fmt.Sprintf("%s (%s)", name, i18n.G("current"))
 		}
 
 		strUsedBy := fmt.Sprintf("%d", len(project.UsedBy))
-		data = append(data, []string{name, images, profiles, storageVolumes, strUsedBy})
+		data = append(data, []string{name, images, profiles, storageVolumes, networks, strUsedBy})
 	}
 	sort.Sort(byName(data))
 
@@ -456,10 +578,11 @@ func (c *cmdProjectList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("IMAGES"),
 		i18n.G("PROFILES"),
 		i18n.G("STORAGE VOLUMES"),
+		i18n.G("NETWORKS"),
 		i18n.G("USED BY"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, projects)
+	return utils.RenderTable(c.global.flagFormat, header, data, projects)
 }
 
 // Rename
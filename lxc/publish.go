@@ -22,6 +22,8 @@ type cmdPublish struct {
 	flagCompressionAlgorithm string
 	flagMakePublic           bool
 	flagForce                bool
+	flagDiff                 bool
+	flagSquash               bool
 }
 
 func (c *cmdPublish) Command() *cobra.Command {
@@ -29,13 +31,18 @@ func (c *cmdPublish) Command() *cobra.Command {
 	cmd.Use = i18n.G("publish [<remote>:]<instance>[/<snapshot>] [<remote>:] [flags] [key=value...]")
 	cmd.Short = i18n.G("Publish instances as images")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Publish instances as images`))
+		`Publish instances as images
+
+Images are self-contained unless --diff is passed, in which case the image only
+contains the changes from the image the instance was created from and references it.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagMakePublic, "public", false, i18n.G("Make the image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New alias to define at target")+"``")
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Stop the instance if currently running"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm: for image or none")+"``")
+	cmd.Flags().BoolVar(&c.flagDiff, "diff", false, i18n.G("Only include the changes from the instance's base image"))
+	cmd.Flags().BoolVar(&c.flagSquash, "squash", false, i18n.G("Include the full instance content, not depending on any other image (default)"))
 
 	return cmd
 }
@@ -59,6 +66,10 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if c.flagDiff && c.flagSquash {
+		return fmt.Errorf(i18n.G("--diff and --squash can't be used together"))
+	}
+
 	if len(args) >= 2 && !strings.Contains(args[1], "=") {
 		firstprop = 2
 		iRemote, iName, err = conf.ParseRemote(args[1])
@@ -79,6 +90,10 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("There is no \"image name\".  Did you want an alias?"))
 	}
 
+	if c.flagDiff && cRemote != iRemote {
+		return fmt.Errorf(i18n.G("Layered images can only be published on the instance's server"))
+	}
+
 	d, err := conf.GetInstanceServer(iRemote)
 	if err != nil {
 		return err
@@ -197,6 +212,7 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 			Name: cName,
 		},
 		CompressionAlgorithm: c.flagCompressionAlgorithm,
+		Diff:                 c.flagDiff,
 	}
 	req.Properties = properties
 
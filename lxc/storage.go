@@ -414,6 +414,11 @@ func (c *cmdStorageInfo) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Missing pool name"))
 	}
 
+	rawFormat, err := utils.IsRawFormat(c.global.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Targeting
 	if c.storage.flagTarget != "" {
 		if !resource.server.IsClustered() {
@@ -434,6 +439,10 @@ func (c *cmdStorageInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if rawFormat {
+		return utils.RenderTable(c.global.flagFormat, nil, nil, map[string]interface{}{"pool": pool, "resources": res})
+	}
+
 	// Declare the poolinfo map of maps in order to build up the yaml
 	poolinfo := make(map[string]map[string]string)
 	poolusedby := make(map[string]map[string][]string)
@@ -496,8 +505,6 @@ func (c *cmdStorageInfo) Run(cmd *cobra.Command, args []string) error {
 type cmdStorageList struct {
 	global  *cmdGlobal
 	storage *cmdStorage
-
-	flagFormat string
 }
 
 func (c *cmdStorageList) Command() *cobra.Command {
@@ -507,7 +514,6 @@ func (c *cmdStorageList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List available storage pools")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List available storage pools`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -566,7 +572,7 @@ func (c *cmdStorageList) Run(cmd *cobra.Command, args []string) error {
 	}
 	header = append(header, i18n.G("USED BY"))
 
-	return utils.RenderTable(c.flagFormat, header, data, pools)
+	return utils.RenderTable(c.global.flagFormat, header, data, pools)
 }
 
 // Set
@@ -1069,8 +1069,6 @@ type cmdStorageVolumeList struct {
 	global        *cmdGlobal
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
-
-	flagFormat string
 }
 
 func (c *cmdStorageVolumeList) Command() *cobra.Command {
@@ -1080,7 +1078,6 @@ func (c *cmdStorageVolumeList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List storage volumes")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List storage volumes`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -1142,7 +1139,7 @@ func (c *cmdStorageVolumeList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, volumes)
+	return utils.RenderTable(c.global.flagFormat, header, data, volumes)
 }
 
 // Move
@@ -13,10 +13,11 @@ import (
 
 // Table list format
 const (
-	TableFormatCSV   = "csv"
-	TableFormatJSON  = "json"
-	TableFormatTable = "table"
-	TableFormatYAML  = "yaml"
+	TableFormatCSV     = "csv"
+	TableFormatCompact = "compact"
+	TableFormatJSON    = "json"
+	TableFormatTable   = "table"
+	TableFormatYAML    = "yaml"
 )
 
 // RenderTable renders tabular data in various formats.
@@ -30,6 +31,20 @@ func RenderTable(format string, header []string, data [][]string, raw interface{
 		table.SetHeader(header)
 		table.AppendBulk(data)
 		table.Render()
+	case TableFormatCompact:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoFormatHeaders(false)
+		table.SetBorder(false)
+		table.SetCenterSeparator("")
+		table.SetColumnSeparator("")
+		table.SetRowSeparator("")
+		table.SetHeaderLine(false)
+		table.SetHeader(header)
+		table.AppendBulk(data)
+		table.Render()
 	case TableFormatCSV:
 		w := csv.NewWriter(os.Stdout)
 		w.WriteAll(data)
@@ -58,3 +73,16 @@ func RenderTable(format string, header []string, data [][]string, raw interface{
 
 	return nil
 }
+
+// IsRawFormat returns whether info commands should render their raw data in the
+// given format rather than their own human readable output.
+func IsRawFormat(format string) (bool, error) {
+	switch format {
+	case TableFormatJSON, TableFormatYAML:
+		return true, nil
+	case TableFormatTable, TableFormatCompact:
+		return false, nil
+	default:
+		return false, fmt.Errorf(i18n.G("Invalid format %q"), format)
+	}
+}
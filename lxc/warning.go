@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdWarning struct {
+	global *cmdGlobal
+}
+
+func (c *cmdWarning) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("warning")
+	cmd.Short = i18n.G("Manage warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage warnings
+
+Warnings are raised by the server when one of its subsystems runs into a problem.`))
+
+	// Acknowledge
+	warningAcknowledgeCmd := cmdWarningAcknowledge{global: c.global, warning: c}
+	cmd.AddCommand(warningAcknowledgeCmd.Command())
+
+	// Delete
+	warningDeleteCmd := cmdWarningDelete{global: c.global, warning: c}
+	cmd.AddCommand(warningDeleteCmd.Command())
+
+	// List
+	warningListCmd := cmdWarningList{global: c.global, warning: c}
+	cmd.AddCommand(warningListCmd.Command())
+
+	// Show
+	warningShowCmd := cmdWarningShow{global: c.global, warning: c}
+	cmd.AddCommand(warningShowCmd.Command())
+
+	return cmd
+}
+
+// Acknowledge
+type cmdWarningAcknowledge struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningAcknowledge) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("acknowledge [<remote>:]<warning>")
+	cmd.Aliases = []string{"ack"}
+	cmd.Short = i18n.G("Acknowledge warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Acknowledge warnings
+
+Acknowledged warnings are hidden from the warning list until they occur again after being resolved.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningAcknowledge) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing warning UUID"))
+	}
+
+	return resource.server.UpdateWarning(resource.name, api.WarningPut{Status: "acknowledged"}, "")
+}
+
+// Delete
+type cmdWarningDelete struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<warning>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete warnings`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing warning UUID"))
+	}
+
+	err = resource.server.DeleteWarning(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Warning %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// List
+type cmdWarningList struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+
+	flagAll bool
+}
+
+func (c *cmdWarningList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List warnings
+
+Acknowledged and resolved warnings are only shown with --all.`))
+	cmd.Flags().BoolVarP(&c.flagAll, "all", "a", false, i18n.G("Show all warnings"))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	// Get the warnings
+	allWarnings, err := resource.server.GetWarnings()
+	if err != nil {
+		return err
+	}
+
+	warnings := []api.Warning{}
+	for _, warning := range allWarnings {
+		if !c.flagAll && warning.Status != "new" {
+			continue
+		}
+
+		warnings = append(warnings, warning)
+	}
+
+	// Render the table
+	data := [][]string{}
+	for _, warning := range warnings {
+		entry := []string{
+			warning.UUID,
+			strings.ToUpper(warning.Status),
+			strings.ToUpper(warning.Severity),
+			fmt.Sprintf("%d", warning.Count),
+			warning.Project,
+			fmt.Sprintf("%s/%s", warning.Module, warning.Type),
+			warning.LastMessage,
+			warning.LastSeenAt.UTC().Format("2006/01/02 15:04 UTC"),
+		}
+
+		if resource.server.IsClustered() {
+			entry = append(entry, warning.Location)
+		}
+
+		data = append(data, entry)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("UUID"),
+		i18n.G("STATUS"),
+		i18n.G("SEVERITY"),
+		i18n.G("COUNT"),
+		i18n.G("PROJECT"),
+		i18n.G("TYPE"),
+		i18n.G("MESSAGE"),
+		i18n.G("LAST SEEN")}
+	if resource.server.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	return utils.RenderTable(c.global.flagFormat, header, data, warnings)
+}
+
+// Show
+type cmdWarningShow struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<warning>")
+	cmd.Short = i18n.G("Show warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show warnings`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing warning UUID"))
+	}
+
+	// Get the warning
+	warning, _, err := resource.server.GetWarning(resource.name)
+	if err != nil {
+		return err
+	}
+
+	// Render as YAML
+	data, err := yaml.Marshal(&warning)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
@@ -33,8 +33,9 @@ type devLxdResponse struct {
 }
 
 type instanceData struct {
-	Name   string            `json:"name"`
-	Config map[string]string `json:"config,omitempty"`
+	Name    string                       `json:"name"`
+	Config  map[string]string            `json:"config,omitempty"`
+	Devices map[string]map[string]string `json:"devices,omitempty"`
 }
 
 func okResponse(ct interface{}, ctype string) *devLxdResponse {
@@ -118,6 +119,51 @@ var devlxdMetadataGet = devLxdHandler{"/1.0/meta-data", func(d *Daemon, w http.R
 	return okResponse(fmt.Sprintf("#cloud-config\ninstance-id: %s\nlocal-hostname: %s\n%s", instance.Name, instance.Name, value), "raw")
 }}
 
+var devlxdDevicesGet = devLxdHandler{"/1.0/devices", func(d *Daemon, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	data, err := ioutil.ReadFile("instance-data")
+	if err != nil {
+		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+	}
+
+	var instance instanceData
+
+	err = json.Unmarshal(data, &instance)
+	if err != nil {
+		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+	}
+
+	devices := instance.Devices
+	if devices == nil {
+		devices = map[string]map[string]string{}
+	}
+
+	return okResponse(devices, "json")
+}}
+
+// devlxdCloudInitGet returns a handler serving the raw value of the given cloud-init config key.
+func devlxdCloudInitGet(path string, key string) devLxdHandler {
+	return devLxdHandler{path, func(d *Daemon, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+		data, err := ioutil.ReadFile("instance-data")
+		if err != nil {
+			return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+		}
+
+		var instance instanceData
+
+		err = json.Unmarshal(data, &instance)
+		if err != nil {
+			return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+		}
+
+		value, ok := instance.Config[key]
+		if !ok {
+			return &devLxdResponse{"not found", http.StatusNotFound, "raw"}
+		}
+
+		return okResponse(value, "raw")
+	}}
+}
+
 var devLxdEventsGet = devLxdHandler{"/1.0/events", func(d *Daemon, w http.ResponseWriter, r *http.Request) *devLxdResponse {
 	err := eventsGet(d, r).Render(w)
 	if err != nil {
@@ -137,6 +183,10 @@ var handlers = []devLxdHandler{
 	devlxdConfigGet,
 	devlxdConfigKeyGet,
 	devlxdMetadataGet,
+	devlxdCloudInitGet("/1.0/user-data", "user.user-data"),
+	devlxdCloudInitGet("/1.0/vendor-data", "user.vendor-data"),
+	devlxdCloudInitGet("/1.0/network-config", "user.network-config"),
+	devlxdDevicesGet,
 	devLxdEventsGet,
 }
 
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -12,6 +14,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/ucred"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -31,6 +34,10 @@ func restServer(d *Daemon) *http.Server {
 		mux.HandleFunc(endpoint, f)
 	}
 
+	for endpoint, f := range simpleStreamsHandlers(d) {
+		mux.HandleFunc(endpoint, f)
+	}
+
 	for _, c := range api10 {
 		d.createCmd(mux, "1.0", c)
 
@@ -55,7 +62,24 @@ func restServer(d *Daemon) *http.Server {
 		response.NotFound(nil).Render(w)
 	})
 
-	return &http.Server{Handler: &lxdHttpServer{r: mux, d: d}}
+	return &http.Server{
+		Handler: &lxdHttpServer{r: mux, d: d},
+
+		// Record the credentials of local unix socket clients, used for socket group access control.
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			unixConn, ok := conn.(*net.UnixConn)
+			if !ok {
+				return ctx
+			}
+
+			cred, err := ucred.GetCred(unixConn)
+			if err != nil {
+				return ctx
+			}
+
+			return context.WithValue(ctx, "ucred", cred)
+		},
+	}
 }
 
 type lxdHttpServer struct {
@@ -33,13 +33,19 @@ var api10Cmd = APIEndpoint{
 var api10 = []APIEndpoint{
 	api10Cmd,
 	api10ResourcesCmd,
+	auditCmd,
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
+	clusterGroupCmd,
+	clusterGroupsCmd,
 	clusterNodeCmd,
+	clusterNodeStateCmd,
 	clusterNodesCmd,
+	clusterUpgradeCmd,
 	instanceBackupCmd,
 	instanceBackupExportCmd,
+	instanceBackupVerifyCmd,
 	instanceBackupsCmd,
 	instanceCmd,
 	instanceConsoleCmd,
@@ -61,8 +67,19 @@ var api10 = []APIEndpoint{
 	imageRefreshCmd,
 	imagesCmd,
 	imageSecretCmd,
+	metricsCmd,
+	networkACLCmd,
+	networkACLsCmd,
 	networkCmd,
+	networkForwardCmd,
+	networkForwardsCmd,
+	networkLeaseCmd,
 	networkLeasesCmd,
+	networkLoadBalancerCmd,
+	networkLoadBalancersCmd,
+	networkLoadBalancerStateCmd,
+	networkZoneCmd,
+	networkZonesCmd,
 	networksCmd,
 	networkStateCmd,
 	operationCmd,
@@ -72,6 +89,7 @@ var api10 = []APIEndpoint{
 	profileCmd,
 	profilesCmd,
 	projectCmd,
+	projectStateCmd,
 	projectsCmd,
 	storagePoolCmd,
 	storagePoolResourcesCmd,
@@ -84,6 +102,8 @@ var api10 = []APIEndpoint{
 	storagePoolVolumeTypeCustomCmd,
 	storagePoolVolumeTypeImageCmd,
 	storagePoolVolumeTypeVMCmd,
+	warningCmd,
+	warningsCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
@@ -215,6 +235,7 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 	}
 
 	env.KernelFeatures = map[string]string{
+		"idmapped_mounts":           fmt.Sprintf("%v", d.os.IdmappedMounts),
 		"netnsid_getifaddrs":        fmt.Sprintf("%v", d.os.NetnsGetifaddrs),
 		"uevent_injection":          fmt.Sprintf("%v", d.os.UeventInjection),
 		"unpriv_fscaps":             fmt.Sprintf("%v", d.os.VFS3Fscaps),
@@ -481,6 +502,7 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	maasChanged := false
 	candidChanged := false
 	rbacChanged := false
+	auditChanged := false
 
 	for key := range clusterChanged {
 		switch key {
@@ -507,6 +529,10 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 				d.taskAutoUpdate.Reset()
 			}
 		case "images.remote_cache_expiry":
+			fallthrough
+		case "images.remote_cache_max_size":
+			fallthrough
+		case "images.remote_cache_prune_interval":
 			if !d.os.MockMode {
 				d.taskPruneImages.Reset()
 			}
@@ -524,6 +550,8 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "rbac.expiry":
 			rbacChanged = true
+		case "audit.webhook":
+			auditChanged = true
 		}
 	}
 
@@ -537,6 +565,33 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		maasChanged = true
 	}
 
+	for _, key := range []string{"audit.file", "audit.syslog"} {
+		_, ok := nodeChanged[key]
+		if ok {
+			auditChanged = true
+		}
+	}
+
+	if auditChanged {
+		// The node configuration isn't passed along with cluster notifications.
+		auditConfig := nodeConfig
+		if auditConfig == nil {
+			err := d.db.Transaction(func(tx *db.NodeTx) error {
+				var err error
+				auditConfig, err = node.ConfigLoad(tx)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := d.audit.Configure(auditConfig.AuditFile(), auditConfig.AuditSyslog(), clusterConfig.AuditWebhook())
+		if err != nil {
+			return err
+		}
+	}
+
 	value, ok := nodeChanged["core.https_address"]
 	if ok {
 		err := d.endpoints.NetworkUpdateAddress(value)
@@ -561,6 +616,43 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	value, ok = nodeChanged["core.dns_address"]
+	if ok && d.dns != nil {
+		err := d.dns.Reconfigure(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	value, ok = nodeChanged["core.socket_groups"]
+	if ok {
+		groups, err := node.ParseSocketGroups(value)
+		if err != nil {
+			return err
+		}
+
+		err = d.setupSocketGroups(groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	bgpChanged := false
+	for _, key := range []string{"core.bgp_address", "core.bgp_routerid", "core.bgp_peers"} {
+		_, ok := nodeChanged[key]
+		if ok {
+			bgpChanged = true
+		}
+	}
+
+	_, ok = clusterChanged["core.bgp_asn"]
+	if ok || bgpChanged {
+		err := d.setupBGPServer()
+		if err != nil {
+			return err
+		}
+	}
+
 	value, ok = nodeChanged["storage.backups_volume"]
 	if ok {
 		err := daemonStorageMove(s, "backups", value)
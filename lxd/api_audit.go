@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var auditCmd = APIEndpoint{
+	Path: "audit",
+
+	Get: APIEndpointAction{Handler: auditGet},
+}
+
+// auditGet returns the entries of the audit log file of the member, filtered by the since, username, project and
+// limit query parameters.
+func auditGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	filter := audit.Filter{
+		Username: queryParam(r, "username"),
+		Project:  queryParam(r, "project"),
+	}
+
+	since := queryParam(r, "since")
+	if since != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid since parameter"))
+		}
+	}
+
+	limit := queryParam(r, "limit")
+	if limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid limit parameter"))
+		}
+	}
+
+	entries, err := d.audit.Entries(filter)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, entries)
+}
+
+// auditRequest returns the audit log entry of a mutating API request along with the response writer to use for
+// the request, which captures the result. The entry is recorded by calling the returned function once the request
+// has been handled, or once the operation it started is done for requests running as a local operation. Nothing is
+// recorded for requests between cluster members, as the member which received the original request already
+// recorded it.
+func auditRequest(d *Daemon, w http.ResponseWriter, r *http.Request, username string, protocol string) (http.ResponseWriter, func()) {
+	if r.Method == "GET" || protocol == "cluster" || !d.audit.Enabled() {
+		return w, func() {}
+	}
+
+	entry := api.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Username:  username,
+		Protocol:  protocol,
+		Address:   r.RemoteAddr,
+		Method:    r.Method,
+		URL:       r.URL.RequestURI(),
+		Project:   projectParam(r),
+	}
+
+	if util.IsJSONRequest(r) {
+		body := &bytes.Buffer{}
+		_, err := io.Copy(body, r.Body)
+		if err == nil {
+			entry.Request = audit.Summarize(body.Bytes())
+			r.Body = shared.BytesReadCloser{Buf: body}
+		}
+	} else if r.ContentLength > 0 {
+		entry.Request = fmt.Sprintf("<%d bytes of %s>", r.ContentLength, r.Header.Get("Content-Type"))
+	}
+
+	aw := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+	return aw, func() {
+		entry.StatusCode = aw.status
+		entry.Error = aw.errorMessage()
+		entry.Location = d.ServerName()
+
+		// Record the final status of the operation started by the request rather than its creation.
+		if aw.status == http.StatusAccepted {
+			op, err := operations.OperationGetInternal(path.Base(aw.Header().Get("Location")))
+			if err == nil {
+				go func() {
+					op.WaitFinal(-1)
+					entry.StatusCode = int(op.Status())
+					entry.Error = op.Err()
+					d.audit.Record(entry)
+				}()
+
+				return
+			}
+		}
+
+		d.audit.Record(entry)
+	}
+}
+
+// Maximum size of an error response kept to extract its message.
+const auditErrorBodyMax = 4096
+
+// auditResponseWriter captures the status code of a response, along with the body of error responses.
+type auditResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status >= 400 && w.body.Len() < auditErrorBodyMax {
+		w.body.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Hijack lets websocket upgrades go through the wrapped response writer.
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Response writer doesn't support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
+// errorMessage returns the message of an error response, if any.
+func (w *auditResponseWriter) errorMessage() string {
+	if w.status < 400 {
+		return ""
+	}
+
+	resp := api.ResponseRaw{}
+	err := json.Unmarshal(w.body.Bytes(), &resp)
+	if err != nil || resp.Error == "" {
+		return http.StatusText(w.status)
+	}
+
+	return resp.Error
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -23,6 +26,7 @@ import (
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/version"
@@ -38,7 +42,8 @@ var clusterCmd = APIEndpoint{
 var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
-	Get: APIEndpointAction{Handler: clusterNodesGet, AccessHandler: allowAuthenticated},
+	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterNodesPost},
 }
 
 var clusterNodeCmd = APIEndpoint{
@@ -192,6 +197,26 @@ func clusterPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Turn a join token into the address, certificate and password of the
+	// cluster to join.
+	if req.ClusterToken != "" {
+		joinToken, err := clusterJoinTokenDecode(req.ClusterToken)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.ServerName != joinToken.ServerName {
+			return response.BadRequest(fmt.Errorf("The join token is for cluster member %q, not %q", joinToken.ServerName, req.ServerName))
+		}
+
+		req.ClusterAddress, req.ClusterCertificate, err = clusterJoinTokenResolve(joinToken)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		req.ClusterPassword = joinToken.Secret
+	}
+
 	// Sanity checks
 	if req.ServerName == "" && req.Enabled {
 		return response.BadRequest(fmt.Errorf("ServerName is required when enabling clustering"))
@@ -226,7 +251,7 @@ func clusterPutBootstrap(d *Daemon, req api.ClusterPut) response.Response {
 			return err
 		}
 
-		return nil
+		return d.refreshServerName()
 	}
 	resources := map[string][]string{}
 	resources["cluster"] = []string{}
@@ -423,12 +448,14 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 			return err
 		}
 
-		for _, name := range networkNames {
-			_, network, err := d.cluster.GetNetworkInAnyState(name)
-			if err != nil {
-				return err
+		for networkProject, names := range networkNames {
+			for _, name := range names {
+				_, network, err := d.cluster.GetNetworkInAnyState(networkProject, name)
+				if err != nil {
+					return err
+				}
+				networks = append(networks, *network)
 			}
-			networks = append(networks, *network)
 		}
 
 		// Now request for this node to be added to the list of cluster nodes.
@@ -468,6 +495,11 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 			return err
 		}
 
+		err = d.refreshServerName()
+		if err != nil {
+			return err
+		}
+
 		// Remove our old server certificate from the trust store, since it's not needed anymore.
 		_, err = d.cluster.GetCertificate(fingerprint)
 		if err != db.ErrNoSuchObject {
@@ -682,6 +714,11 @@ func clusterPutDisable(d *Daemon) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = d.refreshServerName()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Stop the clustering tasks
 	d.stopClusterTasks()
 
@@ -837,6 +874,156 @@ func clusterAcceptMember(
 	return info, nil
 }
 
+// clusterNodesPost issues a single-use join token allowing a new member to join the cluster without
+// knowing the trust password.
+func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterMembersPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.ServerName == "" {
+		return response.BadRequest(fmt.Errorf("No server name provided"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server isn't clustered"))
+	}
+
+	// List the addresses of the online members, starting with the local one as the token is only known by
+	// the member which issued it.
+	addresses := []string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.GetNodeByName(req.ServerName)
+		if err == nil {
+			return fmt.Errorf("The cluster already has a member with name %q", req.ServerName)
+		}
+
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		localAddress, err := tx.GetLocalNodeAddress()
+		if err != nil {
+			return errors.Wrap(err, "Failed to get local node address")
+		}
+
+		addresses = append(addresses, localAddress)
+
+		offlineThreshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.Address == localAddress || node.IsOffline(offlineThreshold) {
+				continue
+			}
+
+			addresses = append(addresses, node.Address)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	meta := shared.Jmap{
+		"serverName":  req.ServerName,
+		"secret":      secret,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassToken, db.OperationClusterJoinToken, nil, meta, nil, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterJoinTokenDecode decodes a base64 and JSON encoded cluster member join token.
+func clusterJoinTokenDecode(input string) (*api.ClusterMemberJoinToken, error) {
+	joinTokenJSON, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid join token")
+	}
+
+	var joinToken api.ClusterMemberJoinToken
+	err = json.Unmarshal(joinTokenJSON, &joinToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid join token")
+	}
+
+	if joinToken.ServerName == "" || joinToken.Secret == "" || joinToken.Fingerprint == "" || len(joinToken.Addresses) == 0 {
+		return nil, fmt.Errorf("Invalid join token: missing fields")
+	}
+
+	return &joinToken, nil
+}
+
+// clusterJoinTokenResolve returns the address of the first reachable member listed in the join token, along
+// with the cluster certificate after checking it against the fingerprint of the token.
+func clusterJoinTokenResolve(joinToken *api.ClusterMemberJoinToken) (string, string, error) {
+	for _, address := range joinToken.Addresses {
+		cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", address), version.UserAgent)
+		if err != nil {
+			logger.Warn("Failed to connect to cluster member from join token", log.Ctx{"address": address, "err": err})
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != joinToken.Fingerprint {
+			return "", "", fmt.Errorf("Certificate fingerprint mismatch for cluster member %q", address)
+		}
+
+		return address, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})), nil
+	}
+
+	return "", "", fmt.Errorf("Unable to connect to any of the cluster members specified in the join token")
+}
+
+// clusterJoinTokenValid checks whether the given secret belongs to a pending join token, in which case the
+// token is cancelled as it's single-use.
+func clusterJoinTokenValid(secret string) bool {
+	for _, op := range operations.Operations() {
+		if op.Type() != db.OperationClusterJoinToken || op.Status() != api.Running {
+			continue
+		}
+
+		opSecret, ok := op.Metadata()["secret"]
+		if !ok {
+			continue
+		}
+
+		if opSecret == secret {
+			op.Cancel()
+			return true
+		}
+	}
+
+	return false
+}
+
 func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
@@ -870,7 +1057,16 @@ func clusterNodeGet(d *Daemon, r *http.Request) response.Response {
 
 	for _, node := range nodes {
 		if node.ServerName == name {
-			return response.SyncResponseETag(true, node, node.Roles)
+			config, err := clusterNodeConfig(d, name)
+			if err != nil {
+				logger.Warn("Failed to get cluster member configuration", log.Ctx{"member": name, "err": err})
+			}
+
+			for key, value := range config {
+				node.Config[key] = value
+			}
+
+			return response.SyncResponseETag(true, node, []interface{}{node.Roles, node.FailureDomain, node.Description, node.Config})
 		}
 	}
 
@@ -886,30 +1082,39 @@ func clusterNodePut(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
 	// Find the requested one.
-	var current db.NodeInfo
-	var currentFailureDomain string
-	var err error
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		current, err = tx.GetNodeByName(name)
-		if err != nil {
-			return errors.Wrap(err, "Load current node state")
-		}
+	members, err := cluster.List(d.State(), d.gateway)
+	if err != nil {
+		return response.SmartError(err)
+	}
 
-		currentFailureDomain, err = tx.GetNodeFailureDomain(current.ID)
-		if err != nil {
-			return errors.Wrap(err, "Load current failure domain")
+	var current *api.ClusterMember
+	for i := range members {
+		if members[i].ServerName == name {
+			current = &members[i]
+			break
 		}
+	}
 
-		return nil
-	})
+	if current == nil {
+		return response.NotFound(fmt.Errorf("Member '%s' not found", name))
+	}
+
+	// Keep the server configuration apart from the user.* keys, as they're stored in different databases.
+	currentServerConfig, err := clusterNodeConfig(d, name)
 	if err != nil {
-		return response.SmartError(err)
+		logger.Warn("Failed to get cluster member configuration", log.Ctx{"member": name, "err": err})
+	}
+
+	for key, value := range currentServerConfig {
+		current.Config[key] = value
 	}
 
 	// Validate the request is fine
 	etag := []interface{}{
 		current.Roles,
-		currentFailureDomain,
+		current.FailureDomain,
+		current.Description,
+		current.Config,
 	}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -924,41 +1129,259 @@ func clusterNodePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	// Validate the request
-	if shared.StringInSlice(string(db.ClusterRoleDatabase), current.Roles) && !shared.StringInSlice(string(db.ClusterRoleDatabase), req.Roles) {
-		return response.BadRequest(fmt.Errorf("The '%s' role cannot be dropped at this time", db.ClusterRoleDatabase))
+	// Validate the request. The database roles reflect the raft configuration and can only be influenced
+	// through the database-pinned and database-client roles.
+	for _, role := range []db.ClusterRole{db.ClusterRoleDatabase, db.ClusterRoleDatabaseStandBy, db.ClusterRoleDatabaseLeader} {
+		if shared.StringInSlice(string(role), current.Roles) != shared.StringInSlice(string(role), req.Roles) {
+			return response.BadRequest(fmt.Errorf("The '%s' role cannot be changed directly, use the '%s' or '%s' roles instead", role, db.ClusterRoleDatabasePinned, db.ClusterRoleDatabaseClient))
+		}
 	}
 
-	if !shared.StringInSlice(string(db.ClusterRoleDatabase), current.Roles) && shared.StringInSlice(string(db.ClusterRoleDatabase), req.Roles) {
-		return response.BadRequest(fmt.Errorf("The '%s' role cannot be added at this time", db.ClusterRoleDatabase))
+	dbRoles := []db.ClusterRole{}
+	for _, role := range req.Roles {
+		switch db.ClusterRole(role) {
+		case db.ClusterRoleDatabase, db.ClusterRoleDatabaseStandBy, db.ClusterRoleDatabaseLeader:
+			continue
+		}
+
+		known := false
+		for _, clusterRole := range db.ClusterRoles {
+			if clusterRole == db.ClusterRole(role) {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return response.BadRequest(fmt.Errorf("Invalid cluster role '%s'", role))
+		}
+
+		dbRoles = append(dbRoles, db.ClusterRole(role))
+	}
+
+	pinned := shared.StringInSlice(string(db.ClusterRoleDatabasePinned), req.Roles)
+	if pinned && shared.StringInSlice(string(db.ClusterRoleDatabaseClient), req.Roles) {
+		return response.BadRequest(fmt.Errorf("The '%s' and '%s' roles are mutually exclusive", db.ClusterRoleDatabasePinned, db.ClusterRoleDatabaseClient))
+	}
+
+	// Members which haven't been upgraded yet don't know about the new roles.
+	for _, role := range []db.ClusterRole{db.ClusterRoleDatabasePinned, db.ClusterRoleDatabaseClient} {
+		if shared.StringInSlice(string(role), current.Roles) || !shared.StringInSlice(string(role), req.Roles) {
+			continue
+		}
+
+		var enabled bool
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			enabled, err = cluster.APIExtensionEnabled(tx, "clustering_database_roles")
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if !enabled {
+			return response.BadRequest(fmt.Errorf("The '%s' role can't be used until all cluster members have been upgraded", role))
+		}
+	}
+
+	// Update the member specific server configuration first, as it's the part most likely to fail. A missing
+	// config leaves it untouched.
+	var userConfig map[string]string
+	if req.Config != nil {
+		serverConfig := map[string]string{}
+		userConfig = map[string]string{}
+		for key, value := range req.Config {
+			if strings.HasPrefix(key, "user.") {
+				userConfig[key] = value
+				continue
+			}
+
+			_, ok := node.ConfigSchema[key]
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Configuration key '%s' isn't specific to cluster members", key))
+			}
+
+			serverConfig[key] = value
+		}
+
+		err = clusterNodeUpdateConfig(d, name, currentServerConfig, serverConfig)
+		if err != nil {
+			return response.SmartError(err)
+		}
 	}
 
 	// Update the database
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		dbRoles := []db.ClusterRole{}
-		for _, role := range req.Roles {
-			dbRoles = append(dbRoles, db.ClusterRole(role))
+		node, err := tx.GetNodeByName(name)
+		if err != nil {
+			return errors.Wrap(err, "Load current node state")
+		}
+
+		if pinned {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return errors.Wrap(err, "Load cluster configuration")
+			}
+
+			nodes, err := tx.GetNodes()
+			if err != nil {
+				return errors.Wrap(err, "Load nodes")
+			}
+
+			count := 1
+			for _, other := range nodes {
+				if other.ID != node.ID && shared.StringInSlice(string(db.ClusterRoleDatabasePinned), other.Roles) {
+					count++
+				}
+			}
+
+			if int64(count) > config.MaxVoters() {
+				return fmt.Errorf("Can't pin more than %d members as database voters (cluster.max_voters)", config.MaxVoters())
+			}
 		}
 
-		err := tx.UpdateNodeRoles(current.ID, dbRoles)
+		err = tx.UpdateNodeRoles(node.ID, dbRoles)
 		if err != nil {
 			return errors.Wrap(err, "Update roles")
 		}
 
-		err = tx.UpdateNodeFailureDomain(current.ID, req.FailureDomain)
+		err = tx.UpdateNodeFailureDomain(node.ID, req.FailureDomain)
 		if err != nil {
 			return errors.Wrap(err, "Update failure domain")
 		}
 
+		err = tx.UpdateNodeDescription(node.ID, req.Description)
+		if err != nil {
+			return errors.Wrap(err, "Update description")
+		}
+
+		if userConfig != nil {
+			err = tx.UpdateNodeConfig(node.ID, userConfig)
+			if err != nil {
+				return errors.Wrap(err, "Update config")
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Apply pinned or excluded database roles straight away.
+	for _, role := range []db.ClusterRole{db.ClusterRoleDatabasePinned, db.ClusterRoleDatabaseClient} {
+		if shared.StringInSlice(string(role), current.Roles) == shared.StringInSlice(string(role), req.Roles) {
+			continue
+		}
+
+		err = clusterRebalanceRoles(d)
+		if err != nil {
+			logger.Warnf("Failed to rebalance dqlite nodes: %v", err)
+		}
+
+		break
+	}
+
 	return response.EmptySyncResponse
 }
 
+// clusterNodeConfig returns the server configuration keys specific to the given cluster member.
+func clusterNodeConfig(d *Daemon, name string) (map[string]string, error) {
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if address == "" {
+		err = d.db.Transaction(func(tx *db.NodeTx) error {
+			config, err := node.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			values = config.Dump()
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to load node config")
+		}
+	} else {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return nil, err
+		}
+
+		server, _, err := client.GetServer()
+		if err != nil {
+			return nil, err
+		}
+
+		values = server.Config
+	}
+
+	config := map[string]string{}
+	for key, value := range values {
+		_, ok := node.ConfigSchema[key]
+		if ok {
+			config[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	return config, nil
+}
+
+// clusterNodeUpdateConfig replaces the server configuration keys specific to the given cluster member.
+func clusterNodeUpdateConfig(d *Daemon, name string, current map[string]string, config map[string]string) error {
+	values := map[string]interface{}{}
+	for key := range current {
+		values[key] = ""
+	}
+
+	for key, value := range config {
+		values[key] = value
+	}
+
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return err
+	}
+
+	// Apply the changes on the member itself, as its config lives in its local database.
+	if address == "" {
+		resp := doApi10Update(d, api.ServerPut{Config: values}, true)
+		if resp != response.EmptySyncResponse {
+			return fmt.Errorf("%s", resp.String())
+		}
+
+		return nil
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.RawQuery("PATCH", "/1.0", api.ServerPut{Config: values}, "")
+	return err
+}
+
+// clusterRebalanceRoles asks the leader to rebalance the database roles of the cluster members.
+func clusterRebalanceRoles(d *Daemon) error {
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get leader address")
+	}
+
+	client, err := cluster.Connect(leader, d.endpoints.NetworkCert(), true)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.RawQuery("POST", "/internal/cluster/rebalance", nil, "")
+	return err
+}
+
 func clusterNodePost(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -977,6 +1400,12 @@ func clusterNodePost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Other members pick up their new name on their next heartbeat.
+	err = d.refreshServerName()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1038,10 +1467,12 @@ func clusterNodeDelete(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		for _, name := range networks {
-			err := client.DeleteNetwork(name)
-			if err != nil {
-				return response.SmartError(err)
+		for networkProject, names := range networks {
+			for _, name := range names {
+				err := client.UseProject(networkProject).DeleteNetwork(name)
+				if err != nil {
+					return response.SmartError(err)
+				}
 			}
 		}
 
@@ -1070,6 +1501,14 @@ func clusterNodeDelete(d *Daemon, r *http.Request) response.Response {
 		logger.Warnf("Failed to rebalance dqlite nodes: %v", err)
 	}
 
+	// Replace the copies of the images which were on the removed member.
+	go func() {
+		err := autoSyncImages(context.Background(), d)
+		if err != nil {
+			logger.Warnf("Failed to synchronize images across the cluster: %v", err)
+		}
+	}()
+
 	if force != 1 {
 		// Try to gracefully reset the database on the node.
 		cert := d.endpoints.NetworkCert()
@@ -1495,27 +1934,35 @@ func clusterCheckNetworksMatch(cluster *db.Cluster, reqNetworks []api.Network) e
 	if err != nil && err != db.ErrNoSuchObject {
 		return err
 	}
-	for _, name := range networkNames {
-		found := false
-		for _, reqNetwork := range reqNetworks {
-			if reqNetwork.Name != name {
-				continue
-			}
-			found = true
-			_, network, err := cluster.GetNetworkInAnyState(name)
+	for networkProject, names := range networkNames {
+		for _, name := range names {
+			_, network, err := cluster.GetNetworkInAnyState(networkProject, name)
 			if err != nil {
 				return err
 			}
-			// Exclude the keys which are node-specific.
-			exclude := db.NodeSpecificNetworkConfig
-			err = util.CompareConfigs(network.Config, reqNetwork.Config, exclude)
-			if err != nil {
-				return fmt.Errorf("Mismatching config for network %s: %v", name, err)
+
+			// Networks of different projects may share a name, so any of the requested networks with
+			// that name can match.
+			found := false
+			var mismatchErr error
+			for _, reqNetwork := range reqNetworks {
+				if reqNetwork.Name != name {
+					continue
+				}
+				found = true
+				// Exclude the keys which are node-specific.
+				exclude := db.NodeSpecificNetworkConfig
+				mismatchErr = util.CompareConfigs(network.Config, reqNetwork.Config, exclude)
+				if mismatchErr == nil {
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("Missing network %s", name)
+			}
+			if mismatchErr != nil {
+				return fmt.Errorf("Mismatching config for network %s: %v", name, mismatchErr)
 			}
-			break
-		}
-		if !found {
-			return fmt.Errorf("Missing network %s", name)
 		}
 	}
 	return nil
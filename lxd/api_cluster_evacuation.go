@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterNodeStateCmd = APIEndpoint{
+	Path: "cluster/members/{name}/state",
+
+	Post: APIEndpointAction{Handler: clusterNodeStatePost},
+}
+
+// clusterNodeStatePost evacuates or restores a cluster member.
+func clusterNodeStatePost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server isn't clustered"))
+	}
+
+	// Forward the request to the member being evacuated or restored, as it's the one handling its instances.
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address != "" {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	req := api.ClusterMemberStatePost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var member db.NodeInfo
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		member, err = tx.GetNodeByName(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var opType db.OperationType
+	var run func(op *operations.Operation) error
+
+	switch req.Action {
+	case "evacuate":
+		if member.State == db.ClusterMemberStateEvacuated {
+			return response.BadRequest(fmt.Errorf("Cluster member %q is already evacuated", name))
+		}
+
+		opType = db.OperationClusterMemberEvacuate
+		run = func(op *operations.Operation) error {
+			return clusterNodeEvacuate(d, op, member)
+		}
+	case "restore":
+		if member.State != db.ClusterMemberStateEvacuated {
+			return response.BadRequest(fmt.Errorf("Cluster member %q isn't evacuated", name))
+		}
+
+		opType = db.OperationClusterMemberRestore
+		run = func(op *operations.Operation) error {
+			return clusterNodeRestore(d, op, member)
+		}
+	default:
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, opType, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterNodeEvacuate moves or stops all the instances of the local member, according to their
+// cluster.evacuate config key, and stops new instances from being placed on it.
+func clusterNodeEvacuate(d *Daemon, op *operations.Operation, member db.NodeInfo) error {
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateNodeState(member.ID, db.ClusterMemberStateEvacuated)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to mark cluster member as evacuated")
+	}
+
+	instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load instances")
+	}
+
+	client, err := clusterNodeLocalClient(d)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		op.UpdateMetadata(map[string]interface{}{"evacuation_progress": fmt.Sprintf("Evacuating %q in project %q", inst.Name(), inst.Project())})
+
+		mode, err := clusterNodeEvacuateMode(d, inst)
+		if err != nil {
+			return err
+		}
+
+		running := inst.IsRunning()
+
+		if mode == "stop" {
+			if !running {
+				continue
+			}
+
+			err = clusterNodeEvacuateStop(inst, false)
+			if err != nil {
+				return err
+			}
+
+			// Record where the instance should be started again on restore.
+			err = clusterNodeEvacuateSetOrigin(d, inst.Project(), inst.Name(), member.Name)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		stateful := running && mode == "stateful-migrate"
+		if running {
+			err = clusterNodeEvacuateStop(inst, stateful)
+			if err != nil {
+				return err
+			}
+		}
+
+		target, err := clusterNodeEvacuateTarget(d, inst)
+		if err != nil {
+			return err
+		}
+
+		if target == "" {
+			return fmt.Errorf("No cluster member available to move instance %q in project %q to", inst.Name(), inst.Project())
+		}
+
+		err = clusterNodeMoveInstance(d, client, inst.Project(), inst.Name(), target, member.Name, running, stateful)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterNodeRestore brings back the instances evacuated from the local member and allows new instances to be
+// placed on it again.
+func clusterNodeRestore(d *Daemon, op *operations.Operation, member db.NodeInfo) error {
+	var instances []db.Instance
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.UpdateNodeState(member.ID, db.ClusterMemberStateCreated)
+		if err != nil {
+			return errors.Wrap(err, "Failed to mark cluster member as restored")
+		}
+
+		instances, err = tx.GetInstances(db.InstanceFilter{Type: instancetype.Any})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	client, err := clusterNodeLocalClient(d)
+	if err != nil {
+		return err
+	}
+
+	for _, dbInst := range instances {
+		if dbInst.Config["volatile.evacuate.origin"] != member.Name {
+			continue
+		}
+
+		op.UpdateMetadata(map[string]interface{}{"evacuation_progress": fmt.Sprintf("Restoring %q in project %q", dbInst.Name, dbInst.Project)})
+
+		// Instances which were stopped in place only need starting again.
+		if dbInst.Node == member.Name {
+			err = clusterNodeEvacuateSetOrigin(d, dbInst.Project, dbInst.Name, "")
+			if err != nil {
+				return err
+			}
+
+			inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to load instance %q in project %q", dbInst.Name, dbInst.Project)
+			}
+
+			err = inst.Start(false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q in project %q", dbInst.Name, dbInst.Project)
+			}
+
+			continue
+		}
+
+		// Instances which were moved away come back, stopping them on their current member first.
+		projectClient := client.UseProject(dbInst.Project)
+		inst, _, err := projectClient.GetInstance(dbInst.Name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get instance %q in project %q", dbInst.Name, dbInst.Project)
+		}
+
+		running := inst.StatusCode == api.Running
+		stateful := running && inst.ExpandedConfig["cluster.evacuate"] == "stateful-migrate"
+		if running {
+			err = clusterNodeUpdateInstanceState(projectClient, dbInst.Name, "stop", stateful)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = clusterNodeMoveInstance(d, client, dbInst.Project, dbInst.Name, member.Name, "", running, stateful)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterNodeEvacuateMode returns how the given instance should be evacuated. Unless set otherwise, instances
+// on ceph storage pools are migrated while the others are stopped.
+func clusterNodeEvacuateMode(d *Daemon, inst instance.Instance) (string, error) {
+	mode := inst.ExpandedConfig()["cluster.evacuate"]
+	if mode != "" && mode != "auto" {
+		return mode, nil
+	}
+
+	onCeph, err := clusterNodeInstanceOnCeph(d, inst.Project(), inst.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if onCeph {
+		return "migrate", nil
+	}
+
+	return "stop", nil
+}
+
+// clusterNodeInstanceOnCeph returns whether the given instance is on a ceph storage pool, making it reachable
+// from all cluster members.
+func clusterNodeInstanceOnCeph(d *Daemon, projectName string, name string) (bool, error) {
+	poolName, err := d.cluster.GetInstancePool(projectName, name)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to fetch instance's pool name")
+	}
+
+	_, pool, err := d.cluster.GetStoragePool(poolName)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to fetch instance's pool info")
+	}
+
+	return pool.Driver == "ceph", nil
+}
+
+// clusterNodeEvacuateStop stops the given instance, giving it boot.host_shutdown_timeout seconds to shut down
+// cleanly unless it's stopped statefully.
+func clusterNodeEvacuateStop(inst instance.Instance, stateful bool) error {
+	if stateful {
+		err := inst.Stop(true)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to statefully stop instance %q in project %q", inst.Name(), inst.Project())
+		}
+
+		return nil
+	}
+
+	timeoutSeconds := 30
+	value, ok := inst.ExpandedConfig()["boot.host_shutdown_timeout"]
+	if ok {
+		timeoutSeconds, _ = strconv.Atoi(value)
+	}
+
+	err := inst.Shutdown(time.Second * time.Duration(timeoutSeconds))
+	if err != nil {
+		logger.Warn("Failed to shut down instance, forcing stop", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+
+		err = inst.Stop(false)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to stop instance %q in project %q", inst.Name(), inst.Project())
+		}
+	}
+
+	return nil
+}
+
+// clusterNodeEvacuateTarget picks the member the given instance should be moved to.
+func clusterNodeEvacuateTarget(d *Daemon, inst instance.Instance) (string, error) {
+	var candidates []db.NodeInfo
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		groups, err := projecthelpers.GetRestrictedClusterGroups(tx, inst.Project())
+		if err != nil {
+			return err
+		}
+
+		candidates, err = tx.GetCandidateMembers([]int{inst.Architecture()}, groups)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req := api.InstancesPost{
+		Name: inst.Name(),
+		Type: api.InstanceType(inst.Type().String()),
+		InstancePut: api.InstancePut{
+			Profiles: inst.Profiles(),
+			Config:   inst.LocalConfig(),
+			Devices:  inst.LocalDevices().CloneNative(),
+		},
+	}
+
+	return instancePlacement(d, inst.Project(), &req, candidates)
+}
+
+// clusterNodeMoveInstance moves a stopped instance to the target member, records the member it was evacuated
+// from (or clears it if origin is empty) and starts it again if requested.
+func clusterNodeMoveInstance(d *Daemon, client lxd.InstanceServer, projectName string, name string, target string, origin string, start bool, stateful bool) error {
+	projectClient := client.UseProject(projectName)
+
+	moveOp, err := projectClient.UseTarget(target).MigrateInstance(name, api.InstancePost{Name: name, Migration: true})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move instance %q in project %q to %q", name, projectName, target)
+	}
+
+	err = moveOp.Wait()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move instance %q in project %q to %q", name, projectName, target)
+	}
+
+	err = clusterNodeEvacuateSetOrigin(d, projectName, name, origin)
+	if err != nil {
+		return err
+	}
+
+	if !start {
+		return nil
+	}
+
+	return clusterNodeUpdateInstanceState(projectClient, name, "start", stateful)
+}
+
+// clusterNodeUpdateInstanceState starts or stops an instance through the API, waiting for the operation.
+func clusterNodeUpdateInstanceState(client lxd.InstanceServer, name string, action string, stateful bool) error {
+	op, err := client.UpdateInstanceState(name, api.InstanceStatePut{Action: action, Stateful: stateful, Timeout: -1}, "")
+	if err == nil {
+		err = op.Wait()
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to %s instance %q", action, name)
+	}
+
+	return nil
+}
+
+// clusterNodeEvacuateSetOrigin sets the volatile.evacuate.origin key of an instance directly in the database,
+// as the instance may not be on the local member. An empty origin removes the key.
+func clusterNodeEvacuateSetOrigin(d *Daemon, projectName string, name string, origin string) error {
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		id, err := tx.GetInstanceID(projectName, name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get ID of instance %q", name)
+		}
+
+		err = tx.DeleteInstanceConfigKey(id, "volatile.evacuate.origin")
+		if err != nil {
+			return errors.Wrap(err, "Failed to remove volatile.evacuate.origin config key")
+		}
+
+		if origin == "" {
+			return nil
+		}
+
+		err = tx.CreateInstanceConfig(int(id), map[string]string{"volatile.evacuate.origin": origin})
+		if err != nil {
+			return errors.Wrap(err, "Failed to set volatile.evacuate.origin config key")
+		}
+
+		return nil
+	})
+}
+
+// clusterNodeLocalClient returns a client connected to the local member, used to move instances around through
+// the regular instance API.
+func clusterNodeLocalClient(d *Daemon) (lxd.InstanceServer, error) {
+	var address string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		address, err = tx.GetLocalNodeAddress()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get local node address")
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to connect to local cluster member")
+	}
+
+	return client, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var clusterGroupsCmd = APIEndpoint{
+	Path: "cluster/groups",
+
+	Get:  APIEndpointAction{Handler: clusterGroupsGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterGroupsPost},
+}
+
+var clusterGroupCmd = APIEndpoint{
+	Path: "cluster/groups/{name}",
+
+	Delete: APIEndpointAction{Handler: clusterGroupDelete},
+	Get:    APIEndpointAction{Handler: clusterGroupGet, AccessHandler: allowAuthenticated},
+	Patch:  APIEndpointAction{Handler: clusterGroupPatch},
+	Put:    APIEndpointAction{Handler: clusterGroupPut},
+}
+
+// API endpoints
+func clusterGroupsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []api.ClusterGroup{}
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		names, err := tx.GetClusterGroupNames()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if !recursion {
+				resultString = append(resultString, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, name))
+			} else {
+				_, group, err := tx.GetClusterGroup(name)
+				if err != nil {
+					return err
+				}
+
+				resultMap = append(resultMap, *group)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+func clusterGroupsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterGroupsPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	err = clusterGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = clusterGroupValidate(&req.ClusterGroupPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.CreateClusterGroup(req)
+		return err
+	})
+	if err != nil {
+		if err == db.ErrAlreadyDefined {
+			return response.Conflict(fmt.Errorf("Cluster group %q already exists", req.Name))
+		}
+
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, req.Name))
+}
+
+func clusterGroupGet(d *Daemon, r *http.Request) response.Response {
+	_, group, err := doClusterGroupGet(d, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{group.Name, group.Description, group.Members}
+
+	return response.SyncResponseETag(true, group, etag)
+}
+
+// doClusterGroupGet returns the cluster group with the given name along with its ID.
+func doClusterGroupGet(d *Daemon, name string) (int64, *api.ClusterGroup, error) {
+	var id int64
+	var group *api.ClusterGroup
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		id, group, err = tx.GetClusterGroup(name)
+		return err
+	})
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, group, nil
+}
+
+func clusterGroupPut(d *Daemon, r *http.Request) response.Response {
+	id, group, err := doClusterGroupGet(d, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{group.Name, group.Description, group.Members}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.ClusterGroupPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doClusterGroupUpdate(d, id, req)
+}
+
+func clusterGroupPatch(d *Daemon, r *http.Request) response.Response {
+	id, group, err := doClusterGroupGet(d, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{group.Name, group.Description, group.Members}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current group so that omitted fields are left untouched.
+	req := group.Writable()
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doClusterGroupUpdate(d, id, req)
+}
+
+func doClusterGroupUpdate(d *Daemon, id int64, req api.ClusterGroupPut) response.Response {
+	err := clusterGroupValidate(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateClusterGroup(id, req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func clusterGroupDelete(d *Daemon, r *http.Request) response.Response {
+	id, _, err := doClusterGroupGet(d, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.DeleteClusterGroup(id)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterGroupValidateName checks the name of a cluster group is valid. The name is used after an "@" in instance
+// placement targets, so it can't be mistaken for a member name or a "<key>=<value>" label selector.
+func clusterGroupValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.ContainsAny(name, "/@= ") {
+		return fmt.Errorf("Cluster group names may not contain slashes, spaces, @ or =")
+	}
+
+	if shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid cluster group name %q", name)
+	}
+
+	return nil
+}
+
+// clusterGroupValidate checks the modifiable fields of a cluster group, removing duplicate members.
+func clusterGroupValidate(req *api.ClusterGroupPut) error {
+	members := []string{}
+	for _, member := range req.Members {
+		if member == "" {
+			return fmt.Errorf("Empty cluster member name")
+		}
+
+		if !shared.StringInSlice(member, members) {
+			members = append(members, member)
+		}
+	}
+
+	req.Members = members
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var clusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Get: APIEndpointAction{Handler: clusterUpgradeGet, AccessHandler: allowAuthenticated},
+}
+
+// clusterUpgradeGet reports the database schema and API extensions versions of all cluster members.
+//
+// Upgraded members record their new versions before waiting for the rest of the cluster, so the members still
+// serving requests can tell which ones are left to upgrade.
+func clusterUpgradeGet(d *Daemon, r *http.Request) response.Response {
+	var nodes []db.NodeInfo
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		nodes, err = tx.GetNodes()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	schema := 0
+	apiExtensions := 0
+	for _, node := range nodes {
+		if node.Schema > schema {
+			schema = node.Schema
+		}
+
+		if node.APIExtensions > apiExtensions {
+			apiExtensions = node.APIExtensions
+		}
+	}
+
+	upgrade := api.ClusterUpgrade{Members: []api.ClusterMemberVersion{}}
+	for _, node := range nodes {
+		member := api.ClusterMemberVersion{
+			ServerName:    node.Name,
+			Schema:        node.Schema,
+			APIExtensions: node.APIExtensions,
+			Status:        "Upgraded",
+		}
+
+		if node.Schema < schema || node.APIExtensions < apiExtensions {
+			member.Status = "Pending"
+			upgrade.InProgress = true
+		}
+
+		upgrade.Members = append(upgrade.Members, member)
+	}
+
+	return response.SyncResponse(true, upgrade)
+}
@@ -50,6 +50,7 @@ var apiInternal = []APIEndpoint{
 	internalRAFTSnapshotCmd,
 	internalClusterHandoverCmd,
 	internalClusterRaftNodeCmd,
+	internalClusterImageSyncCmd,
 }
 
 var internalShutdownCmd = APIEndpoint{
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/netutils"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+var metricsCmd = APIEndpoint{
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet},
+}
+
+// metricsNetworkCounters lists the network metrics along with their help text and the counter they report.
+var metricsNetworkCounters = []struct {
+	name    string
+	help    string
+	counter func(counters api.NetworkStateCounters) int64
+}{
+	{"receive_bytes_total", "Number of bytes received.", func(c api.NetworkStateCounters) int64 { return c.BytesReceived }},
+	{"transmit_bytes_total", "Number of bytes sent.", func(c api.NetworkStateCounters) int64 { return c.BytesSent }},
+	{"receive_packets_total", "Number of packets received.", func(c api.NetworkStateCounters) int64 { return c.PacketsReceived }},
+	{"transmit_packets_total", "Number of packets sent.", func(c api.NetworkStateCounters) int64 { return c.PacketsSent }},
+	{"receive_errs_total", "Number of receive errors.", func(c api.NetworkStateCounters) int64 { return c.ErrorsReceived }},
+	{"transmit_errs_total", "Number of transmit errors.", func(c api.NetworkStateCounters) int64 { return c.ErrorsSent }},
+	{"receive_drop_total", "Number of received packets dropped.", func(c api.NetworkStateCounters) int64 { return c.PacketsDroppedInbound }},
+	{"transmit_drop_total", "Number of sent packets dropped.", func(c api.NetworkStateCounters) int64 { return c.PacketsDroppedOutbound }},
+}
+
+// metricsGet returns the usage of the local instances, the network counters of their NICs and of the managed
+// networks, along with the internals of the daemon, in the Prometheus text format. Network counters are read from
+// /proc/net/dev in one pass for host interfaces and over netlink for the interfaces inside containers, without
+// running any command.
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	hostCounters, err := shared.NetworkGetAllCounters()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	set := metrics.NewMetricSet(nil)
+
+	for _, inst := range instances {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		labels := map[string]string{"name": inst.Name(), "project": inst.Project(), "type": inst.Type().String()}
+
+		for devName, counters := range metricsInstanceCounters(d, inst, hostCounters) {
+			metricsAddNetworkCounters(set, "lxd_instance_network", counters, metricsLabels(labels, "device", devName))
+		}
+
+		state, err := inst.RenderState()
+		if err != nil {
+			logger.Warn("Failed getting instance state for metrics", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		metricsAddInstanceState(set, state, labels)
+	}
+
+	networks, err := d.cluster.GetNonPendingNetworks()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only networks backed by a host interface have counters, and their names are unique across projects.
+	for _, names := range networks {
+		for _, name := range names {
+			counters, ok := hostCounters[name]
+			if !ok {
+				continue
+			}
+
+			metricsAddNetworkCounters(set, "lxd_network", counters, map[string]string{"name": name})
+		}
+	}
+
+	err = metricsAddDaemon(d, set)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.WriteHeader(http.StatusOK)
+
+		_, err := w.Write([]byte(set.String()))
+		return err
+	})
+}
+
+// metricsLabels returns a copy of the labels with an additional label.
+func metricsLabels(labels map[string]string, key string, value string) map[string]string {
+	result := map[string]string{key: value}
+	for k, v := range labels {
+		result[k] = v
+	}
+
+	return result
+}
+
+// metricsAddNetworkCounters adds the network counters of an interface to the metrics with the given prefix.
+func metricsAddNetworkCounters(set *metrics.MetricSet, prefix string, counters api.NetworkStateCounters, labels map[string]string) {
+	for _, metric := range metricsNetworkCounters {
+		set.Add(fmt.Sprintf("%s_%s", prefix, metric.name), metrics.Counter, metric.help, float64(metric.counter(counters)), labels)
+	}
+}
+
+// metricsAddInstanceState adds the CPU, memory, disk and process usage of a running instance. Values which aren't
+// available for the instance (reported as -1) are skipped.
+func metricsAddInstanceState(set *metrics.MetricSet, state *api.InstanceState, labels map[string]string) {
+	if state.CPU.Usage >= 0 {
+		set.Add("lxd_instance_cpu_seconds_total", metrics.Counter, "CPU time used, in seconds.", float64(state.CPU.Usage)/1e9, labels)
+	}
+
+	for _, gauge := range []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"lxd_instance_memory_usage_bytes", "Memory usage, in bytes.", state.Memory.Usage},
+		{"lxd_instance_memory_usage_peak_bytes", "Peak memory usage, in bytes.", state.Memory.UsagePeak},
+		{"lxd_instance_memory_swap_usage_bytes", "Swap usage, in bytes.", state.Memory.SwapUsage},
+		{"lxd_instance_processes", "Number of processes.", state.Processes},
+	} {
+		if gauge.value >= 0 {
+			set.Add(gauge.name, metrics.Gauge, gauge.help, float64(gauge.value), labels)
+		}
+	}
+
+	for devName, disk := range state.Disk {
+		set.Add("lxd_instance_disk_usage_bytes", metrics.Gauge, "Disk usage, in bytes.", float64(disk.Usage), metricsLabels(labels, "device", devName))
+	}
+
+	for pciAddress, gpu := range state.GPU {
+		gpuLabels := metricsLabels(labels, "pci", pciAddress)
+		set.Add("lxd_instance_gpu_processes", metrics.Gauge, "Number of processes using the GPU.", float64(gpu.Processes), gpuLabels)
+		set.Add("lxd_instance_gpu_memory_usage_bytes", metrics.Gauge, "GPU memory usage, in bytes.", float64(gpu.MemoryUsage), gpuLabels)
+	}
+}
+
+// metricsAddDaemon adds the internals of the daemon: its operations, the latency of its databases, the images
+// cached on the member and the Go runtime.
+func metricsAddDaemon(d *Daemon, set *metrics.MetricSet) error {
+	operations.Lock()
+	ops := operations.Operations()
+	operations.Unlock()
+
+	opsByStatus := map[string]int{}
+	for _, op := range ops {
+		opsByStatus[op.Status().String()]++
+	}
+
+	for status, count := range opsByStatus {
+		set.Add("lxd_operations", metrics.Gauge, "Number of operations.", float64(count), map[string]string{"status": status})
+	}
+
+	// Time a trivial query on each database.
+	start := time.Now()
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.GetLocalNodeName()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	set.Add("lxd_database_latency_seconds", metrics.Gauge, "Duration of a query on the database, in seconds.", time.Since(start).Seconds(), map[string]string{"database": "cluster"})
+
+	start = time.Now()
+	err = d.db.Transaction(func(tx *db.NodeTx) error {
+		_, err := tx.GetRaftNodes()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	set.Add("lxd_database_latency_seconds", metrics.Gauge, "Duration of a query on the database, in seconds.", time.Since(start).Seconds(), map[string]string{"database": "local"})
+
+	images, err := d.cluster.GetImagesOnLocalNode()
+	if err != nil {
+		return err
+	}
+
+	var imagesSize int64
+	for fingerprint := range images {
+		for _, path := range []string{fingerprint, fingerprint + ".rootfs"} {
+			info, err := os.Stat(shared.VarPath("images", path))
+			if err == nil {
+				imagesSize += info.Size()
+			}
+		}
+	}
+
+	set.Add("lxd_image_cache_images", metrics.Gauge, "Number of images stored on the member.", float64(len(images)), nil)
+	set.Add("lxd_image_cache_bytes", metrics.Gauge, "Size of the images stored on the member, in bytes.", float64(imagesSize), nil)
+	set.Add("lxd_image_cache_pruned_total", metrics.Counter, "Number of cached images pruned.", float64(atomic.LoadInt64(&imagesPrunedCount)), nil)
+	set.Add("lxd_image_cache_pruned_bytes_total", metrics.Counter, "Size of the cached images pruned, in bytes.", float64(atomic.LoadInt64(&imagesPrunedBytes)), nil)
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	set.Add("lxd_go_goroutines", metrics.Gauge, "Number of goroutines.", float64(runtime.NumGoroutine()), nil)
+	set.Add("lxd_go_alloc_bytes", metrics.Gauge, "Memory allocated by the daemon, in bytes.", float64(memStats.Alloc), nil)
+
+	return nil
+}
+
+// metricsInstanceCounters returns the counters of the NICs of a running instance keyed by device name, from the
+// point of view of the instance. Container interfaces are read over netlink inside the container, whilst the
+// host side of VM NICs is read from the host counters (swapping receive and transmit).
+func metricsInstanceCounters(d *Daemon, inst instance.Instance, hostCounters map[string]api.NetworkStateCounters) map[string]api.NetworkStateCounters {
+	result := map[string]api.NetworkStateCounters{}
+
+	var nsNetworks map[string]api.InstanceStateNetwork
+	if inst.Type() == instancetype.Container && d.os.NetnsGetifaddrs {
+		nsNetworks, _ = netutils.NetnsGetifaddrs(int32(inst.InitPID()))
+	}
+
+	config := inst.ExpandedConfig()
+	for devName, dev := range inst.ExpandedDevices() {
+		if dev["type"] != "nic" {
+			continue
+		}
+
+		if nsNetworks != nil {
+			ifName := dev["name"]
+			if ifName == "" {
+				ifName = config[fmt.Sprintf("volatile.%s.name", devName)]
+			}
+
+			network, ok := nsNetworks[ifName]
+			if ok {
+				c := network.Counters
+				result[devName] = api.NetworkStateCounters{
+					BytesReceived:          c.BytesReceived,
+					BytesSent:              c.BytesSent,
+					PacketsReceived:        c.PacketsReceived,
+					PacketsSent:            c.PacketsSent,
+					ErrorsReceived:         c.ErrorsReceived,
+					ErrorsSent:             c.ErrorsSent,
+					PacketsDroppedInbound:  c.PacketsDroppedInbound,
+					PacketsDroppedOutbound: c.PacketsDroppedOutbound,
+				}
+
+				continue
+			}
+		}
+
+		hostName := config[fmt.Sprintf("volatile.%s.host_name", devName)]
+		c, ok := hostCounters[hostName]
+		if hostName == "" || !ok {
+			continue
+		}
+
+		result[devName] = api.NetworkStateCounters{
+			BytesReceived:          c.BytesSent,
+			BytesSent:              c.BytesReceived,
+			PacketsReceived:        c.PacketsSent,
+			PacketsSent:            c.PacketsReceived,
+			ErrorsReceived:         c.ErrorsSent,
+			ErrorsSent:             c.ErrorsReceived,
+			PacketsDroppedInbound:  c.PacketsDroppedOutbound,
+			PacketsDroppedOutbound: c.PacketsDroppedInbound,
+		}
+	}
+
+	return result
+}
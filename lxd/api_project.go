@@ -5,23 +5,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/operations"
 	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/version"
 )
 
-var projectFeatures = []string{"features.images", "features.profiles", "features.storage.volumes"}
+var projectFeatures = []string{"features.images", "features.profiles", "features.storage.volumes", "features.networks"}
+
+// projectFeaturesDefaults lists the features enabled on new projects unless explicitly disabled.
+var projectFeaturesDefaults = []string{"features.images", "features.profiles", "features.storage.volumes"}
 
 var projectsCmd = APIEndpoint{
 	Path: "projects",
@@ -40,6 +49,12 @@ var projectCmd = APIEndpoint{
 	Put:    APIEndpointAction{Handler: projectPut, AccessHandler: allowAuthenticated},
 }
 
+var projectStateCmd = APIEndpoint{
+	Path: "projects/{name}/state",
+
+	Get: APIEndpointAction{Handler: projectStateGet, AccessHandler: allowAuthenticated},
+}
+
 func projectsGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
@@ -99,7 +114,7 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 	if project.Config == nil {
 		project.Config = map[string]string{}
 	}
-	for _, feature := range projectFeatures {
+	for _, feature := range projectFeaturesDefaults {
 		_, ok := project.Config[feature]
 		if !ok {
 			project.Config[feature] = "true"
@@ -123,6 +138,22 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Load the profile seeding the default profile of the project
+	var seed *db.Profile
+	if project.DefaultProfileSource != nil {
+		if !shared.IsTrue(project.Config["features.profiles"]) {
+			return response.BadRequest(fmt.Errorf("A default profile source requires features.profiles"))
+		}
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			seed, err = projectGetProfileSource(tx, *project.DefaultProfileSource)
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	var id int64
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		id, err = tx.CreateProject(project)
@@ -131,7 +162,7 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		if shared.IsTrue(project.Config["features.profiles"]) {
-			err = projectCreateDefaultProfile(tx, project.Name)
+			err = projectCreateDefaultProfile(tx, project.Name, seed)
 			if err != nil {
 				return err
 			}
@@ -160,21 +191,63 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/projects/%s", version.APIVersion, project.Name))
 }
 
-// Create the default profile of a project.
-func projectCreateDefaultProfile(tx *db.ClusterTx, project string) error {
+// Create the default profile of a project, seeding its config and devices from the given profile if not nil.
+func projectCreateDefaultProfile(tx *db.ClusterTx, project string, seed *db.Profile) error {
 	// Create a default profile
 	profile := db.Profile{}
 	profile.Project = project
 	profile.Name = projecthelpers.Default
 	profile.Description = fmt.Sprintf("Default LXD profile for project %s", project)
 
+	if seed != nil {
+		profile.Config = seed.Config
+		profile.Devices = seed.Devices
+	}
+
 	_, err := tx.CreateProfile(profile)
 	if err != nil {
 		return errors.Wrap(err, "Add default profile to database")
 	}
+
+	if seed != nil {
+		err = projecthelpers.AllowProfileUpdate(tx, project, profile.Name, api.ProfilePut{Config: profile.Config, Devices: profile.Devices})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Load the profile referenced by the given source, taking the features of its project into account.
+func projectGetProfileSource(tx *db.ClusterTx, source api.ProjectProfileSource) (*db.Profile, error) {
+	projectName := source.Project
+	if projectName == "" {
+		projectName = projecthelpers.Default
+	}
+
+	profileName := source.Profile
+	if profileName == "" {
+		profileName = projecthelpers.Default
+	}
+
+	enabled, err := tx.ProjectHasProfiles(projectName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Check if project %q has profiles", projectName)
+	}
+
+	if !enabled {
+		projectName = projecthelpers.Default
+	}
+
+	profile, err := tx.GetProfile(projectName, profileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch profile %q of project %q", profileName, projectName)
+	}
+
+	return profile, nil
+}
+
 func projectGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -202,6 +275,28 @@ func projectGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, project, etag)
 }
 
+// projectStateGet returns the current usage of the project's resources, along with their limits.
+func projectStateGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	// Check user permissions
+	if !d.userHasPermission(r, name, "view") {
+		return response.Forbidden(nil)
+	}
+
+	state := api.ProjectState{}
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		state.Resources, err = projecthelpers.GetCurrentAllocations(tx, name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, &state)
+}
+
 func projectPut(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -239,7 +334,7 @@ func projectPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return projectChange(d, project, req)
+	return projectChange(d, r, project, req)
 }
 
 func projectPatch(d *Daemon, r *http.Request) response.Response {
@@ -307,11 +402,11 @@ func projectPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	return projectChange(d, project, req)
+	return projectChange(d, r, project, req)
 }
 
 // Common logic between PUT and PATCH.
-func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response.Response {
+func projectChange(d *Daemon, r *http.Request, project *api.Project, req api.ProjectPut) response.Response {
 	// Make a list of config keys that have changed.
 	configChanged := []string{}
 	for key := range project.Config {
@@ -327,6 +422,16 @@ func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response
 		}
 	}
 
+	// Only server administrators may change the restrictions, limits and features of a project, as those
+	// confine what its own administrators can do.
+	if !d.userIsAdmin(r) {
+		for _, key := range configChanged {
+			if projectConfigKeyAdminOnly(key) {
+				return response.Forbidden(fmt.Errorf("Only server administrators can change %q", key))
+			}
+		}
+	}
+
 	// Flag indicating if any feature has changed.
 	featuresChanged := false
 	for _, featureKey := range projectFeatures {
@@ -345,6 +450,25 @@ func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response
 		return response.BadRequest(fmt.Errorf("Features can only be changed on empty projects"))
 	}
 
+	// Networks aren't part of the project's used by list.
+	if featuresChanged {
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			networks, err := tx.GetProjectNetworksConfig(project.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Fetch networks of project %q", project.Name)
+			}
+
+			if len(networks) > 0 {
+				return fmt.Errorf("Features can only be changed on empty projects")
+			}
+
+			return nil
+		})
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	// Validate the configuration.
 	err := projectValidateConfig(req.Config)
 	if err != nil {
@@ -365,7 +489,7 @@ func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response
 
 		if shared.StringInSlice("features.profiles", configChanged) {
 			if shared.IsTrue(req.Config["features.profiles"]) {
-				err = projectCreateDefaultProfile(tx, project.Name)
+				err = projectCreateDefaultProfile(tx, project.Name, nil)
 				if err != nil {
 					return err
 				}
@@ -468,6 +592,20 @@ func projectDelete(d *Daemon, r *http.Request) response.Response {
 		return response.Forbidden(fmt.Errorf("The 'default' project cannot be deleted"))
 	}
 
+	if shared.IsTrue(queryParam(r, "force")) {
+		return projectDeleteForce(d, name)
+	}
+
+	err := doProjectDelete(d, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// doProjectDelete removes the given project, which must be empty.
+func doProjectDelete(d *Daemon, name string) error {
 	var id int64
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		project, err := tx.GetProject(name)
@@ -478,6 +616,15 @@ func projectDelete(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("Only empty projects can be removed")
 		}
 
+		networks, err := tx.GetProjectNetworksConfig(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch networks of project %q", name)
+		}
+
+		if len(networks) > 0 {
+			return fmt.Errorf("Only empty projects can be removed")
+		}
+
 		id, err = tx.GetProjectID(name)
 		if err != nil {
 			return errors.Wrapf(err, "Fetch project id %q", name)
@@ -485,19 +632,339 @@ func projectDelete(d *Daemon, r *http.Request) response.Response {
 
 		return tx.DeleteProject(name)
 	})
-
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
 	if d.rbac != nil {
 		err = d.rbac.DeleteProject(id)
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 	}
 
-	return response.EmptySyncResponse
+	return nil
+}
+
+// projectDeleteForce deletes the project along with all its instances, images, profiles and custom storage
+// volumes. This is done as a single operation which reports its progress through the "delete_progress" metadata.
+func projectDeleteForce(d *Daemon, name string) response.Response {
+	var project *api.Project
+	var networks map[string]map[string]string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		project, err = tx.GetProject(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch project %q", name)
+		}
+
+		networks, err = tx.GetProjectNetworksConfig(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch networks of project %q", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Networks are backed by host interfaces which aren't torn down as part of the project.
+	if len(networks) > 0 {
+		return response.BadRequest(fmt.Errorf("The networks of the project must be deleted first"))
+	}
+
+	run := func(op *operations.Operation) error {
+		err := projectDeleteContents(d, op, project)
+		if err != nil {
+			return err
+		}
+
+		return doProjectDelete(d, name)
+	}
+
+	resources := map[string][]string{}
+	resources["projects"] = []string{name}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationProjectDelete, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// projectDeleteContents deletes the instances of the project, followed by the images, custom storage volumes
+// and profiles it doesn't share with the default project.
+func projectDeleteContents(d *Daemon, op *operations.Operation, project *api.Project) error {
+	progress := func(format string, args ...interface{}) {
+		op.UpdateMetadata(map[string]interface{}{"delete_progress": fmt.Sprintf(format, args...)})
+	}
+
+	// Instances, by node address.
+	var instances map[string][]string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		instances, err = tx.GetInstanceNamesByNodeAddress(project.Name, instancetype.Any)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, names := range instances {
+		total += len(names)
+	}
+
+	count := 0
+	for address, names := range instances {
+		for _, instName := range names {
+			count++
+			progress("Deleting instances: %d/%d", count, total)
+
+			err := projectDeleteInstance(d, project.Name, address, instName)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to delete instance %q", instName)
+			}
+		}
+	}
+
+	// Images.
+	if shared.IsTrue(project.Config["features.images"]) {
+		fingerprints, err := d.cluster.GetImagesFingerprints(project.Name, false)
+		if err != nil {
+			return err
+		}
+
+		for i, fingerprint := range fingerprints {
+			progress("Deleting images: %d/%d", i+1, len(fingerprints))
+
+			err := doImageDelete(d, project.Name, fingerprint, false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to delete image %q", fingerprint)
+			}
+		}
+	}
+
+	// Custom storage volumes.
+	if shared.IsTrue(project.Config["features.storage.volumes"]) {
+		pools, err := d.cluster.GetStoragePoolNames()
+		if err != nil && err != db.ErrNoSuchObject {
+			return err
+		}
+
+		for _, poolName := range pools {
+			poolID, err := d.cluster.GetStoragePoolID(poolName)
+			if err != nil {
+				return err
+			}
+
+			volumes, err := d.cluster.GetStoragePoolVolumes(project.Name, poolID, []int{db.StoragePoolVolumeTypeCustom})
+			if err != nil {
+				return err
+			}
+
+			// Volumes on local pools are listed once per node.
+			volNames := []string{}
+			for _, vol := range volumes {
+				if !shared.StringInSlice(vol.Name, volNames) {
+					volNames = append(volNames, vol.Name)
+				}
+			}
+
+			for i, volName := range volNames {
+				progress("Deleting storage volumes of pool %q: %d/%d", poolName, i+1, len(volNames))
+
+				err := projectDeleteCustomVolume(d, op, project.Name, poolID, poolName, volName)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to delete storage volume %q in pool %q", volName, poolName)
+				}
+			}
+		}
+	}
+
+	// Profiles, the default one being removed along with the project.
+	if shared.IsTrue(project.Config["features.profiles"]) {
+		progress("Deleting profiles")
+
+		profiles, err := d.cluster.GetProfileNames(project.Name)
+		if err != nil {
+			return err
+		}
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			for _, profile := range profiles {
+				if profile == projecthelpers.Default {
+					continue
+				}
+
+				err := tx.DeleteProfile(project.Name, profile)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to delete profile %q", profile)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// projectDeleteInstance stops and deletes an instance of the project, on the node with the given address.
+func projectDeleteInstance(d *Daemon, projectName string, address string, name string) error {
+	if address == "0.0.0.0" {
+		return fmt.Errorf("Cluster member is unavailable")
+	}
+
+	if address != "" {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to connect to node %s", address)
+		}
+
+		client = client.UseProject(projectName)
+
+		inst, etag, err := client.GetInstance(name)
+		if err != nil {
+			return err
+		}
+
+		if inst.IsActive() {
+			// Prevent an ephemeral instance from deleting itself as it stops.
+			if inst.Ephemeral {
+				req := inst.Writable()
+				req.Ephemeral = false
+
+				op, err := client.UpdateInstance(name, req, etag)
+				if err != nil {
+					return err
+				}
+
+				err = op.Wait()
+				if err != nil {
+					return err
+				}
+			}
+
+			op, err := client.UpdateInstanceState(name, api.InstanceStatePut{Action: string(shared.Stop), Force: true, Timeout: -1}, "")
+			if err != nil {
+				return err
+			}
+
+			err = op.Wait()
+			if err != nil {
+				return err
+			}
+		}
+
+		op, err := client.DeleteInstance(name)
+		if err != nil {
+			return err
+		}
+
+		return op.Wait()
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return err
+	}
+
+	if inst.IsRunning() {
+		// Prevent an ephemeral instance from deleting itself as it stops.
+		if inst.IsEphemeral() {
+			args := db.InstanceArgs{
+				Architecture: inst.Architecture(),
+				Config:       inst.LocalConfig(),
+				Description:  inst.Description(),
+				Devices:      inst.LocalDevices(),
+				Ephemeral:    false,
+				Profiles:     inst.Profiles(),
+				Project:      inst.Project(),
+				Type:         inst.Type(),
+				Snapshot:     inst.IsSnapshot(),
+			}
+
+			err := inst.Update(args, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := inst.Stop(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return inst.Delete()
+}
+
+// projectDeleteCustomVolume deletes a custom storage volume of the project from all the nodes it's defined on.
+func projectDeleteCustomVolume(d *Daemon, op *operations.Operation, projectName string, poolID int64, poolName string, volName string) error {
+	var addresses []string
+	var driver string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		addresses, err = tx.GetStorageVolumeNodeAddresses(poolID, projectName, volName, db.StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+
+		driver, err = tx.GetStoragePoolDriver(poolID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Volumes on remote storage are shared by all nodes.
+	if driver == "ceph" || driver == "cephfs" {
+		addresses = []string{""}
+	}
+
+	for _, address := range addresses {
+		if address != "" {
+			client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to connect to node %s", address)
+			}
+
+			err = client.UseProject(projectName).DeleteStoragePoolVolume(poolName, "custom", volName)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		pool, err := storagePools.GetPoolByName(d.State(), poolName)
+		if err != nil {
+			return err
+		}
+
+		err = pool.DeleteCustomVolume(projectName, volName, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// projectConfigKeyAdminOnly returns whether the given project configuration key can only be changed by a
+// server administrator.
+func projectConfigKeyAdminOnly(key string) bool {
+	if key == "restricted" || strings.HasPrefix(key, "restricted.") || strings.HasPrefix(key, "limits.") {
+		return true
+	}
+
+	return shared.StringInSlice(key, projectFeatures)
 }
 
 // Check if a project is empty.
@@ -525,11 +992,15 @@ var projectConfigKeys = map[string]func(value string) error{
 	"features.profiles":              shared.IsBool,
 	"features.images":                shared.IsBool,
 	"features.storage.volumes":       shared.IsBool,
+	"features.networks":              shared.IsBool,
+	"images.remote_cache_max_size":   shared.IsSize,
 	"limits.containers":              shared.IsUint32,
 	"limits.virtual-machines":        shared.IsUint32,
 	"limits.memory":                  shared.IsSize,
+	"limits.disk":                    shared.IsSize,
 	"limits.processes":               shared.IsUint32,
 	"limits.cpu":                     shared.IsUint32,
+	"limits.networks":                shared.IsUint32,
 	"restricted":                     shared.IsBool,
 	"restricted.containers.nesting":  isEitherAllowOrBlock,
 	"restricted.containers.lowlevel": isEitherAllowOrBlock,
@@ -545,6 +1016,33 @@ var projectConfigKeys = map[string]func(value string) error{
 	"restricted.devices.usb":               isEitherAllowOrBlock,
 	"restricted.devices.nic":               isEitherAllowOrBlockOrManaged,
 	"restricted.devices.disk":              isEitherAllowOrBlockOrManaged,
+	"restricted.cluster.groups":            shared.IsAny,
+	"restricted.networks.access":           shared.IsAny,
+	"restricted.networks.subnets": func(value string) error {
+		for _, subnet := range strings.Split(value, ",") {
+			subnet = strings.TrimSpace(subnet)
+			if subnet == "" {
+				continue
+			}
+
+			_, _, err := net.ParseCIDR(subnet)
+			if err != nil {
+				return fmt.Errorf("Invalid subnet %q", subnet)
+			}
+		}
+
+		return nil
+	},
+	"instances.name.prefix": shared.IsAny,
+	"instances.name.suffix": shared.IsAny,
+	"instances.name.pattern": func(value string) error {
+		_, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("Invalid instance name pattern %q: %v", value, err)
+		}
+
+		return nil
+	},
 }
 
 func projectValidateConfig(config map[string]string) error {
@@ -556,6 +1054,16 @@ func projectValidateConfig(config map[string]string) error {
 			continue
 		}
 
+		// Schemas of user keys
+		if strings.HasPrefix(key, "schema.user.") {
+			err := projecthelpers.UserSchemaKeyValid(key, v)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		// Then validate
 		validator, ok := projectConfigKeys[key]
 		if !ok {
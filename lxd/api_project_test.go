@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A TLS client with the admin role on a project can reconfigure the project, but not lift its restrictions.
+func TestProject_RestrictedCertificateAdmin(t *testing.T) {
+	daemon, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	f := clusterFixture{t: t}
+	f.EnableNetworking(daemon, "")
+
+	client := f.ClientUnix(daemon)
+	err := client.CreateProject(api.ProjectsPost{
+		Name:       "p1",
+		ProjectPut: api.ProjectPut{Config: map[string]string{"restricted": "true"}},
+	})
+	require.NoError(t, err)
+
+	cert := shared.TestingAltKeyPair()
+	block, _ := pem.Decode(cert.PublicKey())
+	require.NotNil(t, block)
+
+	post := api.CertificatesPost{Certificate: base64.StdEncoding.EncodeToString(block.Bytes)}
+	post.Name = "project-admin"
+	post.Type = "client"
+	post.Restricted = true
+	post.Projects = map[string]string{"p1": "admin"}
+	require.NoError(t, client.CreateCertificate(post))
+
+	remote, err := lxd.ConnectLXD(fmt.Sprintf("https://%s", daemon.endpoints.NetworkAddress()), &lxd.ConnectionArgs{
+		TLSClientCert:      string(cert.PublicKey()),
+		TLSClientKey:       string(cert.PrivateKey()),
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+
+	httpClient, err := remote.GetHTTPClient()
+	require.NoError(t, err)
+
+	patch := func(body map[string]interface{}) int {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("PATCH", fmt.Sprintf("https://%s/1.0/projects/p1", daemon.endpoints.NetworkAddress()), bytes.NewReader(data))
+		require.NoError(t, err)
+
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, patch(map[string]interface{}{"description": "Managed by its admin"}))
+	assert.Equal(t, http.StatusForbidden, patch(map[string]interface{}{"config": map[string]string{"restricted": "false"}}))
+	assert.Equal(t, http.StatusForbidden, patch(map[string]interface{}{"config": map[string]string{"restricted.containers.privilege": "allow"}}))
+	assert.Equal(t, http.StatusForbidden, patch(map[string]interface{}{"config": map[string]string{"limits.cpu": "1000"}}))
+
+	project, _, err := client.GetProject("p1")
+	require.NoError(t, err)
+	assert.Equal(t, "Managed by its admin", project.Description)
+	assert.Equal(t, "true", project.Config["restricted"])
+	assert.Equal(t, "", project.Config["restricted.containers.privilege"])
+}
+
+// A member of a socket group with the admin role on a project can't lift the restrictions of the project either.
+func TestProject_SocketGroupAdmin(t *testing.T) {
+	daemon, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	client, err := lxd.ConnectLXDUnix(daemon.UnixSocket(), nil)
+	require.NoError(t, err)
+
+	err = client.CreateProject(api.ProjectsPost{
+		Name:       "p1",
+		ProjectPut: api.ProjectPut{Config: map[string]string{"restricted": "true"}},
+	})
+	require.NoError(t, err)
+
+	// Simulate a user which is only granted access through a socket group.
+	gid := uint32(os.Getgid()) + 4242
+	daemon.socketGroups = map[uint32]map[string]string{gid: {"p1": "admin"}}
+
+	var project *api.Project
+	err = daemon.cluster.Transaction(func(tx *db.ClusterTx) error {
+		project, err = tx.GetProject("p1")
+		return err
+	})
+	require.NoError(t, err)
+
+	change := func(changes map[string]string) int {
+		config := map[string]string{}
+		for k, v := range project.Config {
+			config[k] = v
+		}
+
+		for k, v := range changes {
+			config[k] = v
+		}
+
+		r := httptest.NewRequest("PATCH", "/1.0/projects/p1", nil)
+		r.RemoteAddr = "@"
+		ctx := context.WithValue(r.Context(), "ucred", &unix.Ucred{Uid: uint32(os.Getuid()) + 4242, Gid: gid})
+		ctx = context.WithValue(ctx, "protocol", "unix-group")
+		r = r.WithContext(ctx)
+
+		require.True(t, daemon.userHasPermission(r, "p1", "manage-projects"))
+		require.False(t, daemon.userIsAdmin(r))
+
+		w := httptest.NewRecorder()
+		require.NoError(t, projectChange(daemon, r, project, api.ProjectPut{Config: config}).Render(w))
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, change(map[string]string{"restricted": "false"}))
+	assert.Equal(t, http.StatusForbidden, change(map[string]string{"limits.memory": "100GB"}))
+	assert.Equal(t, http.StatusOK, change(map[string]string{"user.owner": "ci"}))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var warningsCmd = APIEndpoint{
+	Path: "warnings",
+
+	Get: APIEndpointAction{Handler: warningsGet},
+}
+
+var warningCmd = APIEndpoint{
+	Path: "warnings/{uuid}",
+
+	Delete: APIEndpointAction{Handler: warningDelete},
+	Get:    APIEndpointAction{Handler: warningGet},
+	Patch:  APIEndpointAction{Handler: warningPatch},
+	Put:    APIEndpointAction{Handler: warningPut},
+}
+
+// API endpoints
+func warningsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	// Optional project and status filters.
+	projectName := r.FormValue("project")
+	status := r.FormValue("status")
+	if status != "" && !shared.StringInSlice(status, db.WarningStatuses) {
+		return response.BadRequest(fmt.Errorf("Invalid warning status %q", status))
+	}
+
+	var warnings []api.Warning
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		warnings, err = tx.GetWarnings(projectName)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.Warning{}
+	for _, warning := range warnings {
+		if status != "" && warning.Status != status {
+			continue
+		}
+
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/warnings/%s", version.APIVersion, warning.UUID))
+		} else {
+			resultMap = append(resultMap, warning)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+func warningGet(d *Daemon, r *http.Request) response.Response {
+	warning, err := doWarningGet(d, mux.Vars(r)["uuid"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{warning.Status}
+
+	return response.SyncResponseETag(true, warning, etag)
+}
+
+// doWarningGet returns the warning with the given UUID.
+func doWarningGet(d *Daemon, uuid string) (*api.Warning, error) {
+	var warning *api.Warning
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		warning, err = tx.GetWarning(uuid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return warning, nil
+}
+
+func warningPut(d *Daemon, r *http.Request) response.Response {
+	warning, err := doWarningGet(d, mux.Vars(r)["uuid"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{warning.Status}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.WarningPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doWarningUpdate(d, warning.UUID, req)
+}
+
+func warningPatch(d *Daemon, r *http.Request) response.Response {
+	warning, err := doWarningGet(d, mux.Vars(r)["uuid"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{warning.Status}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current warning so that omitted fields are left untouched.
+	req := warning.Writable()
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doWarningUpdate(d, warning.UUID, req)
+}
+
+func doWarningUpdate(d *Daemon, uuid string, req api.WarningPut) response.Response {
+	// Resolving a warning is left to the subsystem which raised it.
+	if !shared.StringInSlice(req.Status, []string{db.WarningStatusNew, db.WarningStatusAcknowledged}) {
+		return response.BadRequest(fmt.Errorf("Warning status can only be set to %q or %q", db.WarningStatusNew, db.WarningStatusAcknowledged))
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateWarningStatus(uuid, req.Status)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func warningDelete(d *Daemon, r *http.Request) response.Response {
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.DeleteWarning(mux.Vars(r)["uuid"])
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// warningRaise records an occurrence of a warning on this member, on top of logging it.
+func warningRaise(s *state.State, projectName string, module string, typ string, entityURL string, severity string, message string) {
+	logger.Warn(message, log.Ctx{"module": module, "type": typ, "project": projectName, "entity": entityURL})
+
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(projectName, module, typ, entityURL, severity, message)
+	})
+	if err != nil {
+		logger.Error("Failed to record warning", log.Ctx{"module": module, "type": typ, "err": err})
+	}
+}
+
+// warningResolve marks the warnings of this member with the given module, type and entity as resolved.
+func warningResolve(s *state.State, projectName string, module string, typ string, entityURL string) {
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.ResolveWarningsLocalNode(projectName, module, typ, entityURL)
+	})
+	if err != nil {
+		logger.Error("Failed to resolve warnings", log.Ctx{"module": module, "type": typ, "err": err})
+	}
+}
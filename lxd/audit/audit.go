@@ -0,0 +1,276 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// Number of entries which can be waiting to be written before new ones get dropped.
+const queueSize = 1024
+
+// Maximum size of the request summary of an entry.
+const requestSummaryMax = 4096
+
+// Logger records audit entries to the configured sinks.
+type Logger struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	syslog  *syslog.Writer
+	webhook string
+
+	queue  chan api.AuditEntry
+	client *http.Client
+}
+
+// Filter selects the entries returned by Entries.
+type Filter struct {
+	Since    time.Time
+	Username string
+	Project  string
+	Limit    int
+}
+
+// NewLogger returns a new audit logger without any sink configured.
+func NewLogger() *Logger {
+	l := &Logger{
+		queue:  make(chan api.AuditEntry, queueSize),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	go l.run()
+
+	return l
+}
+
+// Configure sets the sinks of the logger. An empty path disables the file sink and an empty URL disables the
+// webhook sink.
+func (l *Logger) Configure(path string, useSyslog bool, webhook string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if path != l.path {
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+
+		if path != "" {
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to open audit log file %q", path)
+			}
+
+			l.file = file
+		}
+
+		l.path = path
+	}
+
+	if useSyslog && l.syslog == nil {
+		writer, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, "lxd-audit")
+		if err != nil {
+			return errors.Wrap(err, "Failed to connect to syslog")
+		}
+
+		l.syslog = writer
+	} else if !useSyslog && l.syslog != nil {
+		l.syslog.Close()
+		l.syslog = nil
+	}
+
+	l.webhook = webhook
+
+	return nil
+}
+
+// Enabled returns whether any sink is configured.
+func (l *Logger) Enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file != nil || l.syslog != nil || l.webhook != ""
+}
+
+// Record queues an entry to be written to the sinks. The entry is dropped if the queue is full, so that a slow
+// sink never blocks API requests.
+func (l *Logger) Record(entry api.AuditEntry) {
+	select {
+	case l.queue <- entry:
+	default:
+		logger.Warn("Audit log queue is full, dropping entry", log.Ctx{"method": entry.Method, "url": entry.URL})
+	}
+}
+
+func (l *Logger) run() {
+	for entry := range l.queue {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+
+		l.mu.Lock()
+		file := l.file
+		writer := l.syslog
+		webhook := l.webhook
+
+		if file != nil {
+			_, err := file.Write(append(line, '\n'))
+			if err != nil {
+				logger.Warn("Failed to write audit log entry", log.Ctx{"method": entry.Method, "url": entry.URL, "err": err})
+			}
+		}
+		l.mu.Unlock()
+
+		if writer != nil {
+			err := writer.Notice(string(line))
+			if err != nil {
+				logger.Warn("Failed to send audit log entry to syslog", log.Ctx{"method": entry.Method, "url": entry.URL, "err": err})
+			}
+		}
+
+		if webhook != "" {
+			err := l.post(webhook, line)
+			if err != nil {
+				logger.Warn("Failed to send audit log entry to webhook", log.Ctx{"method": entry.Method, "url": entry.URL, "err": err})
+			}
+		}
+	}
+}
+
+func (l *Logger) post(url string, body []byte) error {
+	resp, err := l.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Entries returns the entries of the file sink matching the filter, oldest first. When a limit is set, only the
+// most recent entries are returned.
+func (l *Logger) Entries(filter Filter) ([]api.AuditEntry, error) {
+	l.mu.Lock()
+	path := l.path
+	l.mu.Unlock()
+
+	if path == "" {
+		return nil, fmt.Errorf("No audit log file is configured")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open audit log file %q", path)
+	}
+	defer file.Close()
+
+	entries := []api.AuditEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := api.AuditEntry{}
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			continue
+		}
+
+		if !filter.Since.IsZero() && entry.Timestamp.Before(filter.Since) {
+			continue
+		}
+
+		if filter.Username != "" && entry.Username != filter.Username {
+			continue
+		}
+
+		if filter.Project != "" && entry.Project != filter.Project {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read audit log file %q", path)
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+
+	return entries, nil
+}
+
+// Summarize returns a summary of a JSON request body suitable for the audit log, with the values of sensitive
+// keys like passwords redacted and the result truncated.
+func Summarize(body []byte) string {
+	var content interface{}
+	err := json.Unmarshal(body, &content)
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+
+	summary, err := json.Marshal(redact(content))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+
+	if len(summary) > requestSummaryMax {
+		return string(summary[:requestSummaryMax]) + "..."
+	}
+
+	return string(summary)
+}
+
+// redact replaces the values of sensitive keys in a decoded JSON document.
+func redact(content interface{}) interface{} {
+	switch value := content.(type) {
+	case map[string]interface{}:
+		for key, entry := range value {
+			if isSensitive(key) {
+				value[key] = "<redacted>"
+				continue
+			}
+
+			value[key] = redact(entry)
+		}
+	case []interface{}:
+		for i, entry := range value {
+			value[i] = redact(entry)
+		}
+	}
+
+	return content
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, word := range []string{"password", "secret", "private_key", "token"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(key, ".key") || strings.HasSuffix(key, "_key")
+}
@@ -0,0 +1,76 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := audit.Summarize([]byte(`{"name": "c1", "password": "foo", "config": {"core.trust_password": "bar", "maas.api.key": "baz", "limits.cpu": "2"}}`))
+	assert.Equal(t, `{"config":{"core.trust_password":"<redacted>","limits.cpu":"2","maas.api.key":"<redacted>"},"name":"c1","password":"<redacted>"}`, summary)
+
+	assert.Equal(t, "<3 bytes>", audit.Summarize([]byte("foo")))
+
+	summary = audit.Summarize([]byte(`{"description": "` + strings.Repeat("x", 8192) + `"}`))
+	assert.Len(t, summary, 4096+len("..."))
+}
+
+func TestEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-audit-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC()
+	path := filepath.Join(dir, "audit.log")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+
+	for i, entry := range []api.AuditEntry{
+		{Timestamp: now.Add(-2 * time.Hour), Username: "alice", Project: "default", Method: "POST"},
+		{Timestamp: now.Add(-time.Hour), Username: "bob", Project: "foo", Method: "PUT"},
+		{Timestamp: now, Username: "alice", Project: "foo", Method: "DELETE"},
+	} {
+		line, err := json.Marshal(entry)
+		require.NoError(t, err, i)
+		_, err = file.Write(append(line, '\n'))
+		require.NoError(t, err, i)
+	}
+	file.Close()
+
+	logger := audit.NewLogger()
+	_, err = logger.Entries(audit.Filter{})
+	assert.Error(t, err)
+
+	err = logger.Configure(path, false, "")
+	require.NoError(t, err)
+	defer logger.Configure("", false, "")
+
+	entries, err := logger.Entries(audit.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	entries, err = logger.Entries(audit.Filter{Username: "alice"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "POST", entries[0].Method)
+
+	entries, err = logger.Entries(audit.Filter{Project: "foo", Since: now.Add(-90 * time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = logger.Entries(audit.Filter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "DELETE", entries[0].Method)
+}
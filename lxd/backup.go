@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"context"
 
 	"github.com/pkg/errors"
+	cron "gopkg.in/robfig/cron.v2"
 	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxd/backup"
@@ -23,17 +25,21 @@ import (
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/cancel"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/instancewriter"
+	"github.com/lxc/lxd/shared/ioprogress"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/logging"
 )
 
 // Create a new backup.
-func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.Instance) error {
+// If op is set, the progress of the backup is reported in its metadata and cancelling it aborts the backup.
+func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.Instance, op *operations.Operation) error {
 	logger := logging.AddContext(logger.Log, log.Ctx{"project": sourceInst.Project(), "instance": sourceInst.Name(), "name": args.Name})
 	logger.Debug("Instance backup started")
 	defer logger.Debug("Instance backup finished")
@@ -52,57 +58,116 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		args.OptimizedStorage = false
 	}
 
-	// Create the database entry.
-	err = s.Cluster.CreateInstanceBackup(args)
-	if err != nil {
-		if err == db.ErrAlreadyDefined {
-			return fmt.Errorf("Backup %q already exists", args.Name)
-		}
+	// Incremental backups rely on the storage driver's optimized format.
+	if args.DeltaFrom != "" && !args.OptimizedStorage {
+		return fmt.Errorf("Storage pool driver %q doesn't support incremental backups", pool.Driver().Info().Name)
+	}
 
-		return errors.Wrap(err, "Insert backup info into database")
+	// Detect compression method.
+	compress := args.CompressionAlgorithm
+	if compress == "" {
+		compress, err = cluster.ConfigGetString(s.Cluster, "backups.compression_algorithm")
+		if err != nil {
+			return err
+		}
 	}
 
-	revert.Add(func() { s.Cluster.DeleteInstanceBackup(args.Name) })
+	// Detect bandwidth limit.
+	maxBandwidth, err := cluster.ConfigGetString(s.Cluster, "backups.max_bandwidth")
+	if err != nil {
+		return err
+	}
 
-	// Get the backup struct.
-	b, err := instance.BackupLoadByName(s, sourceInst.Project(), args.Name)
+	bandwidthLimit, err := util.BandwidthLimit(args.BandwidthLimit, maxBandwidth)
 	if err != nil {
-		return errors.Wrap(err, "Load backup object")
+		return err
 	}
 
-	// Detect compression method.
-	var compress string
-	b.SetCompressionAlgorithm(args.CompressionAlgorithm)
-	if b.CompressionAlgorithm() != "" {
-		compress = b.CompressionAlgorithm()
-	} else {
-		compress, err = cluster.ConfigGetString(s.Cluster, "backups.compression_algorithm")
+	var tarFileWriter io.Writer
+	var uploadWriter *io.PipeWriter
+	uploadRes := make(chan error, 1)
+
+	if args.Target == "s3" {
+		// Stream the tarball straight to object storage rather than staging it in the backups
+		// directory. Such backups aren't tracked in the database.
+		s3, err := backupS3Target(s)
 		if err != nil {
 			return err
 		}
-	}
 
-	// Create the target path if needed.
-	backupsPath := shared.VarPath("backups", project.Instance(sourceInst.Project(), sourceInst.Name()))
-	if !shared.PathExists(backupsPath) {
-		err := os.MkdirAll(backupsPath, 0700)
+		objectName := project.Instance(sourceInst.Project(), args.Name)
+		logger.Debug("Uploading backup tarball", log.Ctx{"endpoint": s3.Endpoint, "bucket": s3.Bucket, "object": objectName})
+
+		var uploadReader *io.PipeReader
+		uploadReader, uploadWriter = io.Pipe()
+		go func() {
+			err := s3.Upload(context.Background(), objectName, uploadReader)
+			uploadReader.CloseWithError(err)
+			uploadRes <- err
+		}()
+
+		// Abort the upload rather than completing it with a partial tarball if anything fails.
+		revert.Add(func() { uploadWriter.CloseWithError(fmt.Errorf("Backup failed")) })
+		tarFileWriter = uploadWriter
+	} else {
+		// Create the database entry.
+		err = s.Cluster.CreateInstanceBackup(args)
 		if err != nil {
-			return err
+			if err == db.ErrAlreadyDefined {
+				return fmt.Errorf("Backup %q already exists", args.Name)
+			}
+
+			return errors.Wrap(err, "Insert backup info into database")
+		}
+
+		revert.Add(func() { s.Cluster.DeleteInstanceBackup(args.Name) })
+
+		// Create the target path if needed.
+		backupsPath := shared.VarPath("backups", project.Instance(sourceInst.Project(), sourceInst.Name()))
+		if !shared.PathExists(backupsPath) {
+			err := os.MkdirAll(backupsPath, 0700)
+			if err != nil {
+				return err
+			}
+
+			revert.Add(func() { os.Remove(backupsPath) })
+		}
+
+		target := shared.VarPath("backups", project.Instance(sourceInst.Project(), args.Name))
+
+		// Setup the tarball writer.
+		logger.Debug("Opening backup tarball for writing", log.Ctx{"path": target})
+		tarFile, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "Error opening backup tarball for writing %q", target)
 		}
+		defer tarFile.Close()
+		revert.Add(func() { os.Remove(target) })
+		tarFileWriter = tarFile
+	}
 
-		revert.Add(func() { os.Remove(backupsPath) })
+	if bandwidthLimit > 0 {
+		tarFileWriter = &util.BandwidthLimitedWriter{Writer: tarFileWriter, Limit: bandwidthLimit}
 	}
 
-	target := shared.VarPath("backups", project.Instance(sourceInst.Project(), b.Name()))
+	if op != nil {
+		// Report the amount of data written and abort if the operation gets cancelled.
+		tracker := &ioprogress.ProgressTracker{}
+		tracker.Handler = func(value, speed int64) {
+			metadata := op.Metadata()
+			if metadata == nil {
+				metadata = make(map[string]interface{})
+			}
 
-	// Setup the tarball writer.
-	logger.Debug("Opening backup tarball for writing", log.Ctx{"path": target})
-	tarFileWriter, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil {
-		return errors.Wrapf(err, "Error opening backup tarball for writing %q", target)
+			shared.SetProgressMetadata(metadata, "create_backup", "Backup", 0, tracker.Transferred(), 0, speed)
+			op.UpdateMetadata(metadata)
+		}
+
+		tarFileWriter = &ioprogress.ProgressWriter{
+			WriteCloser: writeNopCloser{Writer: &cancel.Writer{Writer: tarFileWriter, Context: op.Context()}},
+			Tracker:     tracker,
+		}
 	}
-	defer tarFileWriter.Close()
-	revert.Add(func() { os.Remove(target) })
 
 	// Get IDMap to unshift container as the tarball is created.
 	var idmap *idmap.IdmapSet
@@ -119,6 +184,9 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 	defer tarPipeWriter.Close() // Ensure that go routine below always ends.
 	tarWriter := instancewriter.NewInstanceTarWriter(tarPipeWriter, idmap)
 
+	// The checksums are recorded in the manifest of the backup.
+	tarWriter.EnableChecksums()
+
 	// Setup tar writer go routine, with optional compression.
 	tarWriterRes := make(chan error, 0)
 	var compressErr error
@@ -141,7 +209,7 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 
 	// Write index file.
 	logger.Debug("Adding backup index file")
-	err = backupWriteIndex(sourceInst, pool, b.OptimizedStorage(), !b.InstanceOnly(), tarWriter)
+	err = backupWriteIndex(sourceInst, pool, args.OptimizedStorage, !args.InstanceOnly, args.DeltaFrom, tarWriter)
 
 	// Check compression errors.
 	if compressErr != nil {
@@ -153,11 +221,18 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		return errors.Wrapf(err, "Error writing backup index file")
 	}
 
-	err = pool.BackupInstance(sourceInst, tarWriter, b.OptimizedStorage(), !b.InstanceOnly(), nil)
+	err = pool.BackupInstance(sourceInst, tarWriter, args.OptimizedStorage, !args.InstanceOnly, args.DeltaFrom, op)
 	if err != nil {
 		return errors.Wrap(err, "Backup create")
 	}
 
+	// Write the checksum manifest last so that it covers everything above.
+	logger.Debug("Adding backup manifest file")
+	err = backupWriteManifest(tarWriter)
+	if err != nil {
+		return errors.Wrap(err, "Error writing backup manifest file")
+	}
+
 	// Close off the tarball file.
 	err = tarWriter.Close()
 	if err != nil {
@@ -175,12 +250,33 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		return errors.Wrap(err, "Error writing tarball")
 	}
 
+	// Complete the upload now that the whole tarball has been written.
+	if uploadWriter != nil {
+		uploadWriter.Close()
+
+		err = <-uploadRes
+		if err != nil {
+			return err
+		}
+	}
+
 	revert.Success()
 	return nil
 }
 
+// writeNopCloser is a WriteCloser whose Close does nothing, the underlying writer being closed separately.
+type writeNopCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (w writeNopCloser) Close() error {
+	return nil
+}
+
 // backupWriteIndex generates an index.yaml file and then writes it to the root of the backup tarball.
-func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, optimized bool, snapshots bool, tarWriter *instancewriter.InstanceTarWriter) error {
+// If deltaFrom is set, only the snapshots taken after it are listed.
+func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, optimized bool, snapshots bool, deltaFrom string, tarWriter *instancewriter.InstanceTarWriter) error {
 	// Indicate whether the driver will include a driver-specific optimized header.
 	poolDriverOptimizedHeader := false
 	if optimized {
@@ -195,6 +291,7 @@ func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, opti
 		Type:             api.InstanceType(sourceInst.Type().String()),
 		OptimizedStorage: &optimized,
 		OptimizedHeader:  &poolDriverOptimizedHeader,
+		DeltaFrom:        deltaFrom,
 	}
 
 	if snapshots {
@@ -209,6 +306,22 @@ func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, opti
 		}
 	}
 
+	if deltaFrom != "" {
+		baseIndex := -1
+		for i, snapName := range indexInfo.Snapshots {
+			if snapName == deltaFrom {
+				baseIndex = i
+				break
+			}
+		}
+
+		if baseIndex < 0 {
+			return fmt.Errorf("Base snapshot %q not found", deltaFrom)
+		}
+
+		indexInfo.Snapshots = indexInfo.Snapshots[baseIndex+1:]
+	}
+
 	// Convert to YAML.
 	indexData, err := yaml.Marshal(&indexInfo)
 	if err != nil {
@@ -232,6 +345,211 @@ func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, opti
 	return nil
 }
 
+// backupWriteManifest generates a manifest.yaml file listing the checksums of all the files written to the
+// backup tarball so far and then writes it to the tarball.
+func backupWriteManifest(tarWriter *instancewriter.InstanceTarWriter) error {
+	manifest := backup.Manifest{
+		Files: tarWriter.Checksums(),
+	}
+
+	// Convert to YAML.
+	manifestData, err := yaml.Marshal(&manifest)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(manifestData)
+
+	manifestFileInfo := instancewriter.FileInfo{
+		FileName:    backup.ManifestPath,
+		FileSize:    int64(len(manifestData)),
+		FileMode:    0644,
+		FileModTime: time.Now(),
+	}
+
+	// Write to tarball.
+	return tarWriter.WriteFileFromReader(r, &manifestFileInfo)
+}
+
+func autoCreateInstanceBackupsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		// Load all local instances
+		allInstances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for scheduled backups", log.Ctx{"err": err})
+			return
+		}
+
+		// Truncate the time now back to the start of the minute, before passing to the cron
+		// scheduler, as it will add 1s to the scheduled time and we don't want the next
+		// scheduled time to roll over to the next minute and break the time comparison below.
+		now := time.Now().Truncate(time.Minute)
+
+		// Figure out which need backing up (if any)
+		instances := []instance.Instance{}
+		for _, inst := range allInstances {
+			schedule := inst.ExpandedConfig()["backups.schedule"]
+
+			if schedule == "" {
+				continue
+			}
+
+			// Extend our schedule to one that is accepted by the used cron parser
+			sched, err := cron.Parse(fmt.Sprintf("* %s", schedule))
+			if err != nil {
+				continue
+			}
+
+			// Ignore everything that is more precise than minutes.
+			next := sched.Next(now).Truncate(time.Minute)
+			if !now.Equal(next) {
+				continue
+			}
+
+			// Check if the instance is running
+			if !shared.IsTrue(inst.ExpandedConfig()["backups.schedule.stopped"]) && !inst.IsRunning() {
+				continue
+			}
+
+			instances = append(instances, inst)
+		}
+
+		if len(instances) == 0 {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			return autoCreateInstanceBackups(ctx, d, instances, now)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationBackupCreate, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start create backup operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Creating scheduled instance backups")
+
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to create scheduled instance backups", log.Ctx{"err": err})
+		}
+
+		logger.Info("Done creating scheduled instance backups")
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
+
+func autoCreateInstanceBackups(ctx context.Context, d *Daemon, instances []instance.Instance, now time.Time) error {
+	for _, inst := range instances {
+		ch := make(chan error)
+		go func() {
+			// Scheduled backups are named after their creation time so that uploads to
+			// object storage, which aren't tracked in the database, never collide.
+			backupName := fmt.Sprintf("%s%sscheduled-%s", inst.Name(), shared.SnapshotDelimiter, now.Format("20060102-1504"))
+
+			expiry, err := shared.GetSnapshotExpiry(now, inst.ExpandedConfig()["backups.expiry"])
+			if err != nil {
+				logger.Error("Error getting expiry date", log.Ctx{"err": err, "instance": inst.Name(), "project": inst.Project()})
+				ch <- nil
+				return
+			}
+
+			args := db.InstanceBackup{
+				Name:             backupName,
+				InstanceID:       inst.ID(),
+				CreationDate:     now,
+				ExpiryDate:       expiry,
+				OptimizedStorage: shared.IsTrue(inst.ExpandedConfig()["backups.optimized"]),
+				Target:           inst.ExpandedConfig()["backups.target"],
+			}
+
+			err = backupCreate(d.State(), args, inst, nil)
+			if err != nil {
+				logger.Error("Error creating backup", log.Ctx{"err": err, "instance": inst.Name(), "project": inst.Project()})
+				ch <- nil
+				return
+			}
+
+			// Backups uploaded to object storage aren't tracked in the database, so
+			// pruneExpiredContainerBackups doesn't see them. Expire them here instead.
+			if args.Target == "s3" {
+				err = pruneExpiredS3Backups(ctx, d.State(), inst, now)
+				if err != nil {
+					logger.Error("Error pruning expired backups", log.Ctx{"err": err, "instance": inst.Name(), "project": inst.Project()})
+				}
+			}
+
+			ch <- nil
+		}()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+		}
+	}
+
+	return nil
+}
+
+// pruneExpiredS3Backups deletes the scheduled backups of the instance in object storage which are past
+// backups.expiry. Their creation time is taken from their name.
+func pruneExpiredS3Backups(ctx context.Context, s *state.State, inst instance.Instance, now time.Time) error {
+	s3, err := backupS3Target(s)
+	if err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s%sscheduled-", project.Instance(inst.Project(), inst.Name()), shared.SnapshotDelimiter)
+	names, err := s3.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		created, err := time.ParseInLocation("20060102-1504", strings.TrimPrefix(name, prefix), now.Location())
+		if err != nil {
+			// Not a scheduled backup.
+			continue
+		}
+
+		expiry, err := shared.GetSnapshotExpiry(created, inst.ExpandedConfig()["backups.expiry"])
+		if err != nil {
+			return err
+		}
+
+		if expiry.IsZero() || expiry.After(now) {
+			continue
+		}
+
+		err = s3.Delete(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backupS3Target returns the object storage target configured for backups.
+func backupS3Target(s *state.State) (*backup.S3Target, error) {
+	s3 := &backup.S3Target{}
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		s3.Endpoint, s3.Bucket, s3.AccessKey, s3.SecretKey = config.BackupsS3()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s3, nil
+}
+
 func pruneExpiredContainerBackupsTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		opRun := func(op *operations.Operation) error {
@@ -276,12 +594,26 @@ func pruneExpiredContainerBackups(ctx context.Context, d *Daemon) error {
 		return errors.Wrap(err, "Unable to retrieve the list of expired instance backups")
 	}
 
+	var localName string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		localName, err = tx.GetLocalNodeName()
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to get local member name")
+	}
+
 	for _, b := range backups {
 		inst, err := instance.LoadByID(d.State(), b.InstanceID)
 		if err != nil {
 			return errors.Wrapf(err, "Error deleting instance backup %s", b.Name)
 		}
 
+		// The backups are stored on the member running the instance, which takes care of them.
+		if inst.Location() != localName {
+			continue
+		}
+
 		err = backup.DoBackupDelete(d.State(), inst.Project(), b.Name, inst.Name())
 		if err != nil {
 			return errors.Wrapf(err, "Error deleting instance backup %s", b.Name)
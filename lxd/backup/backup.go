@@ -34,6 +34,7 @@ type Info struct {
 	OptimizedStorage *bool            `json:"optimized,omitempty" yaml:"optimized,omitempty"`               // Optional field to handle older optimized backups that don't have this field.
 	OptimizedHeader  *bool            `json:"optimized_header,omitempty" yaml:"optimized_header,omitempty"` // Optional field to handle older optimized backups that don't have this field.
 	Type             api.InstanceType `json:"type" yaml:"type"`
+	DeltaFrom        string           `json:"delta_from,omitempty" yaml:"delta_from,omitempty"` // Snapshot the backup is an increment from (only for optimized backups).
 }
 
 // GetInfo extracts backup information from a given ReadSeeker.
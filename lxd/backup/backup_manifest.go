@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// ManifestPath is the path of the checksum manifest inside backup tarballs.
+const ManifestPath = "backup/manifest.yaml"
+
+// Manifest lists the SHA256 checksums of the regular files included in a backup tarball.
+type Manifest struct {
+	Files map[string]string `json:"files" yaml:"files"`
+}
+
+// Verify checks the content of a backup tarball against its checksum manifest.
+func Verify(r io.ReadSeeker) error {
+	r.Seek(0, 0)
+	_, _, unpacker, err := shared.DetectCompressionFile(r)
+	if err != nil {
+		return err
+	}
+
+	if unpacker == nil {
+		return fmt.Errorf("Unsupported backup compression")
+	}
+
+	tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), r, unpacker)
+	if err != nil {
+		return err
+	}
+	defer cancelFunc()
+
+	var manifest *Manifest
+	checksums := map[string]string{}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break // End of archive
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Error reading backup file")
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if hdr.Name == ManifestPath {
+			manifest = &Manifest{}
+			err = yaml.NewDecoder(tr).Decode(manifest)
+			if err != nil {
+				return errors.Wrapf(err, "Error parsing backup manifest")
+			}
+
+			continue
+		}
+
+		hash := sha256.New()
+		_, err = io.Copy(hash, tr)
+		if err != nil {
+			return errors.Wrapf(err, "Error reading %q from backup file", hdr.Name)
+		}
+
+		checksums[hdr.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	if manifest == nil {
+		return fmt.Errorf("Backup is missing %s", ManifestPath)
+	}
+
+	failures := []string{}
+	for name, checksum := range manifest.Files {
+		found, ok := checksums[name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%q is missing", name))
+		} else if found != checksum {
+			failures = append(failures, fmt.Sprintf("%q has a checksum mismatch", name))
+		}
+	}
+
+	for name := range checksums {
+		_, ok := manifest.Files[name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%q isn't listed in the manifest", name))
+		}
+	}
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("Backup verification failed: %s", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
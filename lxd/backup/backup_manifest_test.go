@@ -0,0 +1,90 @@
+package backup_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/shared/instancewriter"
+)
+
+// Build an uncompressed backup tarball with the given files, followed by a manifest of the checksums
+// recorded while writing them. The tamper function can alter the recorded checksums.
+func newTestBackup(t *testing.T, files map[string]string, tamper func(map[string]string)) *bytes.Reader {
+	buf := &bytes.Buffer{}
+	tw := instancewriter.NewInstanceTarWriter(buf, nil)
+	tw.EnableChecksums()
+
+	write := func(name string, content string) {
+		fi := instancewriter.FileInfo{
+			FileName:    name,
+			FileSize:    int64(len(content)),
+			FileMode:    0644,
+			FileModTime: time.Now(),
+		}
+
+		err := tw.WriteFileFromReader(strings.NewReader(content), &fi)
+		require.NoError(t, err)
+	}
+
+	for name, content := range files {
+		write(name, content)
+	}
+
+	checksums := tw.Checksums()
+	if tamper != nil {
+		tamper(checksums)
+	}
+
+	data, err := yaml.Marshal(&backup.Manifest{Files: checksums})
+	require.NoError(t, err)
+	write(backup.ManifestPath, string(data))
+
+	require.NoError(t, tw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+var testBackupFiles = map[string]string{
+	"backup/index.yaml":    "name: c1\n",
+	"backup/container.bin": "data",
+}
+
+func TestVerify(t *testing.T) {
+	err := backup.Verify(newTestBackup(t, testBackupFiles, nil))
+	assert.NoError(t, err)
+}
+
+func TestVerify_ChecksumMismatch(t *testing.T) {
+	r := newTestBackup(t, testBackupFiles, func(checksums map[string]string) {
+		checksums["backup/container.bin"] = strings.Repeat("0", 64)
+	})
+
+	err := backup.Verify(r)
+	assert.EqualError(t, err, `Backup verification failed: "backup/container.bin" has a checksum mismatch`)
+}
+
+func TestVerify_MissingFile(t *testing.T) {
+	r := newTestBackup(t, testBackupFiles, func(checksums map[string]string) {
+		checksums["backup/virtual-machine.img"] = strings.Repeat("0", 64)
+	})
+
+	err := backup.Verify(r)
+	assert.EqualError(t, err, `Backup verification failed: "backup/virtual-machine.img" is missing`)
+}
+
+func TestVerify_NoManifest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := instancewriter.NewInstanceTarWriter(buf, nil)
+	fi := instancewriter.FileInfo{FileName: "backup/index.yaml", FileSize: 1, FileMode: 0644, FileModTime: time.Now()}
+	require.NoError(t, tw.WriteFileFromReader(strings.NewReader("a"), &fi))
+	require.NoError(t, tw.Close())
+
+	err := backup.Verify(bytes.NewReader(buf.Bytes()))
+	assert.EqualError(t, err, "Backup is missing backup/manifest.yaml")
+}
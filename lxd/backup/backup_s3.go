@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+)
+
+// s3PartSize is the size of the parts backups are uploaded in. As their size isn't known upfront, the client
+// would otherwise buffer parts sized for the largest possible object.
+const s3PartSize = 64 * 1024 * 1024
+
+// S3Target represents an S3 compatible object storage bucket that backups can be uploaded to.
+type S3Target struct {
+	Endpoint  string // URL of the object storage server, e.g. https://s3.example.net
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// Upload streams the content of the reader to the named object in the bucket.
+// As the size isn't known upfront, the content is uploaded in parts of s3PartSize.
+func (t *S3Target) Upload(ctx context.Context, objectName string, r io.Reader) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutObject(ctx, t.Bucket, objectName, r, -1, minio.PutObjectOptions{ContentType: "application/octet-stream", PartSize: s3PartSize})
+	if err != nil {
+		return errors.Wrapf(err, "Failed uploading %q to S3 bucket %q", objectName, t.Bucket)
+	}
+
+	return nil
+}
+
+// List returns the names of the objects in the bucket starting with the given prefix.
+func (t *S3Target) List(ctx context.Context, prefix string) ([]string, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for object := range client.ListObjects(ctx, t.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, errors.Wrapf(object.Err, "Failed listing objects in S3 bucket %q", t.Bucket)
+		}
+
+		names = append(names, object.Key)
+	}
+
+	return names, nil
+}
+
+// Delete removes the named object from the bucket.
+func (t *S3Target) Delete(ctx context.Context, objectName string) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.RemoveObject(ctx, t.Bucket, objectName, minio.RemoveObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "Failed deleting %q from S3 bucket %q", objectName, t.Bucket)
+	}
+
+	return nil
+}
+
+// client returns a client for the configured endpoint.
+func (t *S3Target) client() (*minio.Client, error) {
+	if t.Endpoint == "" || t.Bucket == "" {
+		return nil, fmt.Errorf("No S3 endpoint and bucket configured for backups")
+	}
+
+	u, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid S3 endpoint %q", t.Endpoint)
+	}
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(t.AccessKey, t.SecretKey, ""),
+		Secure: u.Scheme != "http",
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed connecting to S3 endpoint %q", t.Endpoint)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,314 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	bgpAPI "github.com/osrg/gobgp/api"
+	bgpServer "github.com/osrg/gobgp/pkg/server"
+	"github.com/pkg/errors"
+)
+
+// Peer represents a BGP peer the server announces its prefixes to.
+type Peer struct {
+	Address net.IP
+	ASN     uint32
+}
+
+// path represents a prefix announced by the server on behalf of an owner.
+type path struct {
+	owner   string
+	prefix  net.IPNet
+	nexthop net.IP
+	uuid    []byte
+}
+
+// Server represents the embedded BGP speaker.
+type Server struct {
+	bgp *bgpServer.BgpServer
+
+	address  string
+	asn      uint32
+	routerID net.IP
+	peers    []Peer
+
+	paths map[string]*path
+
+	mu sync.Mutex
+}
+
+// NewServer returns a new server instance, announcing nothing until configured.
+func NewServer() *Server {
+	return &Server{paths: map[string]*path{}}
+}
+
+// Configure (re)starts the server listening on the given address with the given ASN, router ID and peers.
+// The server is stopped if any of address, ASN or router ID is empty. Prefixes added while the server is
+// stopped are announced once it's started.
+func (s *Server) Configure(address string, asn uint32, routerID net.IP, peers []Peer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.stop()
+	if err != nil {
+		return err
+	}
+
+	s.address = address
+	s.asn = asn
+	s.routerID = routerID
+	s.peers = peers
+
+	if address == "" || asn == 0 || routerID == nil {
+		return nil
+	}
+
+	return s.start()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop()
+}
+
+func (s *Server) start() error {
+	// Set default port if required.
+	host, portStr, err := net.SplitHostPort(s.address)
+	if err != nil {
+		host = strings.Trim(s.address, "[]")
+		portStr = "179"
+	}
+
+	port, err := strconv.ParseInt(portStr, 10, 32)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid BGP port %q", portStr)
+	}
+
+	s.bgp = bgpServer.NewBgpServer()
+	go s.bgp.Serve()
+
+	err = s.bgp.StartBgp(context.Background(), &bgpAPI.StartBgpRequest{
+		Global: &bgpAPI.Global{
+			As:              s.asn,
+			RouterId:        s.routerID.String(),
+			ListenAddresses: []string{host},
+			ListenPort:      int32(port),
+		},
+	})
+	if err != nil {
+		s.bgp.Stop()
+		s.bgp = nil
+		return errors.Wrapf(err, "Failed to start BGP server on %q", s.address)
+	}
+
+	for _, peer := range s.peers {
+		err := s.addPeer(peer)
+		if err != nil {
+			s.stop()
+			return err
+		}
+	}
+
+	for _, p := range s.paths {
+		err := s.addPath(p)
+		if err != nil {
+			s.stop()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) stop() error {
+	if s.bgp == nil {
+		return nil
+	}
+
+	err := s.bgp.StopBgp(context.Background(), &bgpAPI.StopBgpRequest{})
+	s.bgp.Stop()
+	s.bgp = nil
+
+	// The paths will be added again on next start.
+	for _, p := range s.paths {
+		p.uuid = nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to stop BGP server")
+	}
+
+	return nil
+}
+
+// family returns the BGP address family of an IP.
+func family(ip net.IP) *bgpAPI.Family {
+	if ip.To4() != nil {
+		return &bgpAPI.Family{Afi: bgpAPI.Family_AFI_IP, Safi: bgpAPI.Family_SAFI_UNICAST}
+	}
+
+	return &bgpAPI.Family{Afi: bgpAPI.Family_AFI_IP6, Safi: bgpAPI.Family_SAFI_UNICAST}
+}
+
+func (s *Server) addPeer(peer Peer) error {
+	// Negotiate both families so that IPv6 prefixes can be announced over IPv4 sessions and vice versa.
+	afiSafis := []*bgpAPI.AfiSafi{}
+	for _, ip := range []net.IP{net.IPv4zero, net.IPv6zero} {
+		afiSafis = append(afiSafis, &bgpAPI.AfiSafi{Config: &bgpAPI.AfiSafiConfig{Family: family(ip), Enabled: true}})
+	}
+
+	err := s.bgp.AddPeer(context.Background(), &bgpAPI.AddPeerRequest{
+		Peer: &bgpAPI.Peer{
+			Conf: &bgpAPI.PeerConf{
+				NeighborAddress: peer.Address.String(),
+				PeerAs:          peer.ASN,
+			},
+			AfiSafis: afiSafis,
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to add BGP peer %q", peer.Address.String())
+	}
+
+	return nil
+}
+
+func (s *Server) addPath(p *path) error {
+	pathFamily := family(p.prefix.IP)
+	ones, _ := p.prefix.Mask.Size()
+
+	nlri, err := ptypes.MarshalAny(&bgpAPI.IPAddressPrefix{Prefix: p.prefix.IP.String(), PrefixLen: uint32(ones)})
+	if err != nil {
+		return err
+	}
+
+	origin, err := ptypes.MarshalAny(&bgpAPI.OriginAttribute{Origin: 0})
+	if err != nil {
+		return err
+	}
+
+	// An unspecified next-hop is replaced by the address of the server on the session.
+	nexthop := p.nexthop
+	if nexthop == nil {
+		nexthop = net.IPv4zero
+		if p.prefix.IP.To4() == nil {
+			nexthop = net.IPv6zero
+		}
+	}
+
+	var nexthopAttr *any.Any
+	if p.prefix.IP.To4() != nil {
+		nexthopAttr, err = ptypes.MarshalAny(&bgpAPI.NextHopAttribute{NextHop: nexthop.String()})
+	} else {
+		nexthopAttr, err = ptypes.MarshalAny(&bgpAPI.MpReachNLRIAttribute{Family: pathFamily, NextHops: []string{nexthop.String()}, Nlris: []*any.Any{nlri}})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.bgp.AddPath(context.Background(), &bgpAPI.AddPathRequest{
+		Path: &bgpAPI.Path{
+			Family: pathFamily,
+			Nlri:   nlri,
+			Pattrs: []*any.Any{origin, nexthopAttr},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to announce prefix %q", p.prefix.String())
+	}
+
+	p.uuid = resp.Uuid
+
+	return nil
+}
+
+// AddPrefix announces a prefix on behalf of an owner, with the given next-hop or the server itself if nil.
+func (s *Server) AddPrefix(prefix net.IPNet, nexthop net.IP, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%s", owner, prefix.String())
+	if s.paths[key] != nil {
+		return nil
+	}
+
+	p := &path{owner: owner, prefix: prefix, nexthop: nexthop}
+	if s.bgp != nil {
+		err := s.addPath(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.paths[key] = p
+
+	return nil
+}
+
+// RemovePrefixByOwner withdraws all the prefixes announced on behalf of an owner.
+func (s *Server) RemovePrefixByOwner(owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, p := range s.paths {
+		if p.owner != owner {
+			continue
+		}
+
+		if s.bgp != nil && p.uuid != nil {
+			err := s.bgp.DeletePath(context.Background(), &bgpAPI.DeletePathRequest{
+				TableType: bgpAPI.TableType_GLOBAL,
+				Family:    family(p.prefix.IP),
+				Uuid:      p.uuid,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Failed to withdraw prefix %q", p.prefix.String())
+			}
+		}
+
+		delete(s.paths, key)
+	}
+
+	return nil
+}
+
+// ParsePeers parses a comma separated list of peers in the <address>=<ASN> format.
+func ParsePeers(value string) ([]Peer, error) {
+	peers := []Peer{}
+	if value == "" {
+		return peers, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		fields := strings.SplitN(entry, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid BGP peer %q, must be in the <address>=<ASN> format", entry)
+		}
+
+		address := net.ParseIP(fields[0])
+		if address == nil {
+			return nil, fmt.Errorf("Invalid BGP peer address %q", fields[0])
+		}
+
+		asn, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || asn == 0 {
+			return nil, fmt.Errorf("Invalid BGP peer ASN %q", fields[1])
+		}
+
+		peers = append(peers, Peer{Address: address, ASN: uint32(asn)})
+	}
+
+	return peers, nil
+}
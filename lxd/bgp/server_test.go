@@ -0,0 +1,27 @@
+package bgp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeers(t *testing.T) {
+	peers, err := ParsePeers("")
+	require.NoError(t, err)
+	assert.Len(t, peers, 0)
+
+	peers, err = ParsePeers("192.0.2.1=65001, 2001:db8::1=65002")
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+	assert.Equal(t, "192.0.2.1", peers[0].Address.String())
+	assert.Equal(t, uint32(65001), peers[0].ASN)
+	assert.Equal(t, "2001:db8::1", peers[1].Address.String())
+	assert.Equal(t, uint32(65002), peers[1].ASN)
+
+	for _, value := range []string{"192.0.2.1", "192.0.2.1=0", "192.0.2.1=foo", "foo=65001", "192.0.2.1=4294967296"} {
+		_, err = ParsePeers(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/subtle"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -8,6 +9,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -15,6 +18,9 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -44,13 +50,23 @@ var certificateCmd = APIEndpoint{
 func certificatesGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
+	// Restricted clients only get to see their own certificate.
+	isAdmin := d.userIsAdmin(r)
+	username, _ := r.Context().Value("username").(string)
+
 	if recursion {
 		certResponses := []api.Certificate{}
 
 		var baseCerts []db.Certificate
+		var projects map[string]map[string]string
 		var err error
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			baseCerts, err = tx.GetCertificates(db.CertificateFilter{})
+			if err != nil {
+				return err
+			}
+
+			projects, err = tx.GetCertificatesProjects()
 			return err
 		})
 		if err != nil {
@@ -58,24 +74,37 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		for _, baseCert := range baseCerts {
+			if !isAdmin && baseCert.Fingerprint != username {
+				continue
+			}
+
 			resp := api.Certificate{}
 			resp.Fingerprint = baseCert.Fingerprint
 			resp.Certificate = baseCert.Certificate
 			resp.Name = baseCert.Name
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
+			resp.Projects = projects[baseCert.Fingerprint]
+			if resp.Projects == nil {
+				resp.Projects = map[string]string{}
 			}
+
+			resp.Restricted = baseCert.Restricted || len(resp.Projects) > 0
+
+			resp.Type = baseCert.ToAPIType()
 			certResponses = append(certResponses, resp)
 		}
 		return response.SyncResponse(true, certResponses)
 	}
 
 	body := []string{}
-	for _, cert := range d.clientCerts {
-		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
-		body = append(body, fingerprint)
+	for _, certs := range []map[string]x509.Certificate{d.clientCerts, d.clientMetricsCerts} {
+		for _, cert := range certs {
+			if !isAdmin && shared.CertFingerprint(&cert) != username {
+				continue
+			}
+
+			fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
+			body = append(body, fingerprint)
+		}
 	}
 
 	return response.SyncResponse(true, body)
@@ -83,11 +112,18 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 
 func readSavedClientCAList(d *Daemon) {
 	d.clientCerts = map[string]x509.Certificate{}
+	d.clientMetricsCerts = map[string]x509.Certificate{}
 
 	var dbCerts []db.Certificate
+	var roles map[string]map[string]string
 	var err error
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		dbCerts, err = tx.GetCertificates(db.CertificateFilter{})
+		if err != nil {
+			return err
+		}
+
+		roles, err = tx.GetCertificatesProjects()
 		return err
 	})
 	if err != nil {
@@ -95,6 +131,20 @@ func readSavedClientCAList(d *Daemon) {
 		return
 	}
 
+	// Certificates with project roles are always restricted to those projects, and restricted certificates
+	// without any project role have no access at all.
+	d.clientRoles = map[string]map[string]string{}
+	for _, dbCert := range dbCerts {
+		if !dbCert.Restricted && len(roles[dbCert.Fingerprint]) == 0 {
+			continue
+		}
+
+		d.clientRoles[dbCert.Fingerprint] = roles[dbCert.Fingerprint]
+		if d.clientRoles[dbCert.Fingerprint] == nil {
+			d.clientRoles[dbCert.Fingerprint] = map[string]string{}
+		}
+	}
+
 	for _, dbCert := range dbCerts {
 		certBlock, _ := pem.Decode([]byte(dbCert.Certificate))
 		if certBlock == nil {
@@ -108,6 +158,12 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 
+		// Metrics certificates are kept apart as they only grant access to the metrics.
+		if dbCert.Type == db.CertificateTypeMetrics {
+			d.clientMetricsCerts[shared.CertFingerprint(cert)] = *cert
+			continue
+		}
+
 		d.clientCerts[shared.CertFingerprint(cert)] = *cert
 	}
 }
@@ -130,15 +186,47 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
+	// Clients restricted to some projects can't add certificates without the trust password either.
+	var token *api.CertificatePut
+	var tokenOp *operations.Operation
+	if (!trusted || (shared.StringInSlice(protocol, []string{"candid", "tls"}) && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
+		// A certificate add token or a cluster join token can be used in place of the trust password, once.
+		// The token is only consumed once the certificate is added, so concurrent uses of the same token are
+		// serialized for it not to be used twice.
 		if req.Password != "" {
-			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			certificateTokenLock.Lock()
+			defer certificateTokenLock.Unlock()
+
+			tokenOp, token = certificateTokenValid(req.Password)
 		}
-		return response.Forbidden(nil)
+
+		if token == nil && (req.Password == "" || !clusterJoinTokenValid(req.Password)) {
+			if req.Password != "" {
+				logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			}
+			return response.Forbidden(nil)
+		}
+	}
+
+	// The certificate gets the name and projects chosen when issuing the token.
+	if token != nil {
+		req.Type = token.Type
+		req.Restricted = token.Restricted
+		req.Projects = token.Projects
 	}
 
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	certType, err := certificateValidate(&req.CertificatePut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Issue a token for a client to add its own certificate.
+	if req.Token {
+		if !trusted || !d.userIsAdmin(r) {
+			return response.Forbidden(nil)
+		}
+
+		return certificateTokenCreate(d, req.CertificatePut)
 	}
 
 	// Extract the certificate
@@ -167,6 +255,9 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		name = remoteHost
+		if token != nil && token.Name != "" {
+			name = token.Name
+		}
 	} else {
 		return response.BadRequest(fmt.Errorf("Can't use TLS data on non-TLS link"))
 	}
@@ -183,8 +274,9 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		if existingCert != nil {
 			// Deal with the cache being potentially out of sync
 			_, ok := d.clientCerts[fingerprint]
-			if !ok {
-				d.clientCerts[fingerprint] = *cert
+			_, okMetrics := d.clientMetricsCerts[fingerprint]
+			if !ok && !okMetrics {
+				readSavedClientCAList(d)
 				return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 			}
 
@@ -194,12 +286,21 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		// Store the certificate in the cluster database
 		dbCert := db.Certificate{
 			Fingerprint: shared.CertFingerprint(cert),
-			Type:        1,
+			Type:        certType,
 			Name:        name,
 			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Restricted:  req.Restricted,
 		}
 
-		err = d.cluster.CreateCertificate(dbCert)
+		// The project roles are stored along with the certificate so that it's never trusted without them.
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			_, err := tx.CreateCertificate(dbCert)
+			if err != nil {
+				return err
+			}
+
+			return tx.UpdateCertificateProjects(dbCert.Fingerprint, req.Projects)
+		})
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -210,25 +311,136 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		if err != nil {
 			return response.SmartError(err)
 		}
-		req := api.CertificatesPost{
+		notifyReq := api.CertificatesPost{
 			Certificate: base64.StdEncoding.EncodeToString(cert.Raw),
 		}
-		req.Name = name
-		req.Type = "client"
+		notifyReq.Name = name
+		notifyReq.Type = req.Type
+		notifyReq.Projects = req.Projects
+		notifyReq.Restricted = req.Restricted
 
 		err = notifier(func(client lxd.InstanceServer) error {
-			return client.CreateCertificate(req)
+			return client.CreateCertificate(notifyReq)
 		})
 		if err != nil {
 			return response.SmartError(err)
 		}
 	}
 
-	d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	// Reload the trusted certificates along with their project roles.
+	readSavedClientCAList(d)
+
+	// The certificate add token is single-use.
+	if tokenOp != nil {
+		_, err = tokenOp.Cancel()
+		if err != nil {
+			logger.Warn("Failed to revoke used certificate add token", log.Ctx{"operation": tokenOp.ID(), "err": err})
+		}
+	}
 
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
 
+// certificateTokenCreate issues a single-use token which a client can use in place of the trust password to add
+// its certificate with the given properties.
+func certificateTokenCreate(d *Daemon, req api.CertificatePut) response.Response {
+	httpsAddress, err := node.HTTPSAddress(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The token is only known by this server, so only list its own addresses.
+	addresses, err := util.ListenAddresses(httpsAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(addresses) == 0 {
+		return response.BadRequest(fmt.Errorf("Can't issue a token when the server isn't listening on the network"))
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	var expiry string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		expiry = config.RemoteTokenExpiry()
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	expiresAt, err := shared.GetSnapshotExpiry(time.Now(), expiry)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	meta := shared.Jmap{
+		"request":     req,
+		"clientName":  req.Name,
+		"secret":      secret,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+		"expiresAt":   expiresAt,
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassToken, db.OperationCertificateAddToken, nil, meta, nil, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Revoke the token once it expires, unless it got used or revoked in the meantime.
+	time.AfterFunc(time.Until(expiresAt), func() {
+		if op.Status() == api.Running {
+			op.Cancel()
+		}
+	})
+
+	return operations.OperationResponse(op)
+}
+
+// Serializes the uses of certificate add tokens.
+var certificateTokenLock sync.Mutex
+
+// certificateTokenValid returns the pending certificate add token operation with the given secret, if any, along
+// with the properties of the certificate to add with it. The caller must hold certificateTokenLock and cancel the
+// operation once the certificate is added, as tokens are single-use.
+func certificateTokenValid(secret string) (*operations.Operation, *api.CertificatePut) {
+	for _, op := range operations.Operations() {
+		if op.Type() != db.OperationCertificateAddToken || op.Status() != api.Running {
+			continue
+		}
+
+		opSecret, ok := op.Metadata()["secret"].(string)
+		if !ok || subtle.ConstantTimeCompare([]byte(opSecret), []byte(secret)) != 1 {
+			continue
+		}
+
+		// Tokens are revoked when they expire, this only covers uses racing with it.
+		expiresAt, ok := op.Metadata()["expiresAt"].(time.Time)
+		if ok && time.Now().After(expiresAt) {
+			continue
+		}
+
+		req, ok := op.Metadata()["request"].(api.CertificatePut)
+		if !ok {
+			continue
+		}
+
+		return op, &req
+	}
+
+	return nil, nil
+}
+
 func certificateGet(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
@@ -237,13 +449,19 @@ func certificateGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Restricted clients only get to see their own certificate.
+	username, _ := r.Context().Value("username").(string)
+	if !d.userIsAdmin(r) && cert.Fingerprint != username {
+		return response.Forbidden(nil)
+	}
+
 	return response.SyncResponseETag(true, cert, cert)
 }
 
-func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, error) {
+func doCertificateGet(dbCluster *db.Cluster, fingerprint string) (api.Certificate, error) {
 	resp := api.Certificate{}
 
-	dbCertInfo, err := db.GetCertificate(fingerprint)
+	dbCertInfo, err := dbCluster.GetCertificate(fingerprint)
 	if err != nil {
 		return resp, err
 	}
@@ -251,12 +469,18 @@ func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, erro
 	resp.Fingerprint = dbCertInfo.Fingerprint
 	resp.Certificate = dbCertInfo.Certificate
 	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
+	resp.Type = dbCertInfo.ToAPIType()
+
+	err = dbCluster.Transaction(func(tx *db.ClusterTx) error {
+		resp.Projects, err = tx.GetCertificateProjects(dbCertInfo.Fingerprint)
+		return err
+	})
+	if err != nil {
+		return resp, err
 	}
 
+	resp.Restricted = dbCertInfo.Restricted || len(resp.Projects) > 0
+
 	return resp, nil
 }
 
@@ -279,7 +503,7 @@ func certificatePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doCertificateUpdate(d, fingerprint, req)
+	return doCertificateUpdate(d, r, fingerprint, req)
 }
 
 func certificatePatch(d *Daemon, r *http.Request) response.Response {
@@ -314,22 +538,111 @@ func certificatePatch(d *Daemon, r *http.Request) response.Response {
 		req.Type = value
 	}
 
-	return doCertificateUpdate(d, fingerprint, req.Writable())
+	// Get restricted, lifting the restriction also drops the project roles unless new ones are given.
+	restricted, err := reqRaw.GetBool("restricted")
+	if err == nil {
+		req.Restricted = restricted
+		if !restricted {
+			req.Projects = map[string]string{}
+		}
+	}
+
+	// Get projects
+	projects, err := reqRaw.GetMap("projects")
+	if err == nil {
+		req.Projects = map[string]string{}
+		for project, role := range projects {
+			roleName, ok := role.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Invalid role for project %q", project))
+			}
+
+			req.Projects[project] = roleName
+		}
+	}
+
+	return doCertificateUpdate(d, r, fingerprint, req.Writable())
 }
 
-func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) response.Response {
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+func doCertificateUpdate(d *Daemon, r *http.Request, fingerprint string, req api.CertificatePut) response.Response {
+	// The certificate was already updated by the member which received the request.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
 	}
 
-	err := d.cluster.RenameCertificate(fingerprint, req.Name)
+	certType, err := certificateValidate(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.RenameCertificate(fingerprint, req.Name)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UpdateCertificateType(fingerprint, certType)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UpdateCertificateRestricted(fingerprint, req.Restricted)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateCertificateProjects(fingerprint, req.Projects)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Notify other members so that they reload the project roles.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.UpdateCertificate(fingerprint, req, "")
+	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	readSavedClientCAList(d)
+
 	return response.EmptySyncResponse
 }
 
+// certificateValidate checks the type and project roles of a certificate, returning its database type. Whether
+// the projects exist is checked when storing them. A certificate given project roles is marked as restricted.
+func certificateValidate(req *api.CertificatePut) (int, error) {
+	certType, err := db.CertificateAPITypeToDBType(req.Type)
+	if err != nil {
+		return -1, err
+	}
+
+	// Metrics certificates don't have access to any project.
+	if certType == db.CertificateTypeMetrics && (req.Restricted || len(req.Projects) > 0) {
+		return -1, fmt.Errorf("Metrics certificates can't be restricted")
+	}
+
+	if len(req.Projects) > 0 {
+		req.Restricted = true
+	}
+
+	for project, role := range req.Projects {
+		_, ok := rbac.ProjectRoles[role]
+		if !ok {
+			return -1, fmt.Errorf("Invalid role %q for project %q", role, project)
+		}
+	}
+
+	return certType, nil
+}
+
 func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
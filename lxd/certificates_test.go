@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A certificate with project roles is restricted, even if its restricted flag isn't set in the database.
+func TestCertificate_ProjectRolesWithoutRestricted(t *testing.T) {
+	daemon, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	client, err := lxd.ConnectLXDUnix(daemon.UnixSocket(), nil)
+	require.NoError(t, err)
+	require.NoError(t, client.CreateProject(api.ProjectsPost{Name: "p1"}))
+
+	cert := shared.TestingAltKeyPair()
+	block, _ := pem.Decode(cert.PublicKey())
+	require.NotNil(t, block)
+
+	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	fingerprint := shared.CertFingerprint(x509Cert)
+
+	err = daemon.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.CreateCertificate(db.Certificate{
+			Fingerprint: fingerprint,
+			Type:        db.CertificateTypeClient,
+			Name:        "legacy",
+			Certificate: string(cert.PublicKey()),
+			Restricted:  false,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateCertificateProjects(fingerprint, map[string]string{"p1": "viewer"})
+	})
+	require.NoError(t, err)
+
+	readSavedClientCAList(daemon)
+	assert.Equal(t, map[string]string{"p1": "viewer"}, daemon.clientRoles[fingerprint])
+
+	r := httptest.NewRequest("GET", "/1.0", nil)
+	ctx := context.WithValue(r.Context(), "protocol", "tls")
+	ctx = context.WithValue(ctx, "username", fingerprint)
+	r = r.WithContext(ctx)
+
+	assert.False(t, daemon.userIsAdmin(r))
+	assert.True(t, daemon.userHasPermission(r, "p1", "view"))
+	assert.False(t, daemon.userHasPermission(r, "p1", "manage-containers"))
+	assert.False(t, daemon.userHasPermission(r, "default", "view"))
+
+	resp, _, err := client.GetCertificate(fingerprint)
+	require.NoError(t, err)
+	assert.True(t, resp.Restricted)
+}
+
+// Adding a certificate with project roles restricts it, and lifting the restriction drops its roles.
+func TestCertificate_ProjectRolesRestrict(t *testing.T) {
+	daemon, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	client, err := lxd.ConnectLXDUnix(daemon.UnixSocket(), nil)
+	require.NoError(t, err)
+	require.NoError(t, client.CreateProject(api.ProjectsPost{Name: "p1"}))
+
+	cert := shared.TestingAltKeyPair()
+	block, _ := pem.Decode(cert.PublicKey())
+	require.NotNil(t, block)
+
+	post := api.CertificatesPost{Certificate: base64.StdEncoding.EncodeToString(block.Bytes)}
+	post.Name = "operator"
+	post.Type = "client"
+	post.Projects = map[string]string{"p1": "operator"}
+	require.NoError(t, client.CreateCertificate(post))
+
+	certs, err := client.GetCertificates()
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.True(t, certs[0].Restricted)
+	assert.Equal(t, map[string]string{"p1": "operator"}, daemon.clientRoles[certs[0].Fingerprint])
+
+	put := certs[0].Writable()
+	put.Restricted = false
+	put.Projects = map[string]string{}
+	require.NoError(t, client.UpdateCertificate(certs[0].Fingerprint, put, ""))
+
+	updated, _, err := client.GetCertificate(certs[0].Fingerprint)
+	require.NoError(t, err)
+	assert.False(t, updated.Restricted)
+	assert.Empty(t, updated.Projects)
+
+	_, restricted := daemon.clientRoles[certs[0].Fingerprint]
+	assert.False(t, restricted)
+}
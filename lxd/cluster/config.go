@@ -5,8 +5,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kballard/go-shellquote"
@@ -14,6 +17,8 @@ import (
 
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/units"
 	"github.com/pkg/errors"
 )
 
@@ -60,6 +65,16 @@ func (c *Config) HTTPSAllowedCredentials() bool {
 	return c.m.GetBool("core.https_allowed_credentials")
 }
 
+// BGPASN returns the ASN of the BGP server.
+func (c *Config) BGPASN() int64 {
+	return c.m.GetInt64("core.bgp_asn")
+}
+
+// RemoteTokenExpiry returns the expiry expression of certificate add tokens.
+func (c *Config) RemoteTokenExpiry() string {
+	return c.m.GetString("core.remote_token_expiry")
+}
+
 // TrustPassword returns the LXD trust password for authenticating clients.
 func (c *Config) TrustPassword() string {
 	return c.m.GetString("core.trust_password")
@@ -102,6 +117,32 @@ func (c *Config) RemoteCacheExpiry() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// RemoteCacheMaxSize returns the maximum total size in bytes of the cached remote images, or 0 if unlimited.
+func (c *Config) RemoteCacheMaxSize() int64 {
+	value := c.m.GetString("images.remote_cache_max_size")
+	if value == "" {
+		return 0
+	}
+
+	size, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// RemoteCachePruneInterval returns the configured interval at which the cached remote images are pruned.
+func (c *Config) RemoteCachePruneInterval() time.Duration {
+	n := c.m.GetInt64("images.remote_cache_prune_interval")
+	return time.Duration(n) * time.Hour
+}
+
+// ImagesSimpleStreams returns whether the public images are exposed through a simplestreams index.
+func (c *Config) ImagesSimpleStreams() bool {
+	return c.m.GetBool("images.simplestreams")
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -117,6 +158,20 @@ func (c *Config) ProxyIgnoreHosts() string {
 	return c.m.GetString("core.proxy_ignore_hosts")
 }
 
+// BackupsS3 returns the S3 compatible object storage endpoint, bucket, access key and secret key that
+// backups can be uploaded to.
+func (c *Config) BackupsS3() (string, string, string, string) {
+	return c.m.GetString("backups.s3.endpoint"),
+		c.m.GetString("backups.s3.bucket"),
+		c.m.GetString("backups.s3.access_key"),
+		c.m.GetString("backups.s3.secret_key")
+}
+
+// AuditWebhook returns the URL the audit log entries are posted to, if any.
+func (c *Config) AuditWebhook() string {
+	return c.m.GetString("audit.webhook")
+}
+
 // MAASController the configured MAAS url and key, if any.
 func (c *Config) MAASController() (string, string) {
 	url := c.m.GetString("maas.api.url")
@@ -132,6 +187,21 @@ func (c *Config) OfflineThreshold() time.Duration {
 	return time.Duration(n) * time.Second
 }
 
+// HeartbeatInterval returns the configured interval between two heartbeat
+// rounds.
+func (c *Config) HeartbeatInterval() time.Duration {
+	n := c.m.GetInt64("cluster.heartbeat_interval")
+	return time.Duration(n) * time.Second
+}
+
+// HealingThreshold returns the number of seconds after which the instances of
+// an offline member are automatically restarted on other members, or zero if
+// automatic healing is disabled.
+func (c *Config) HealingThreshold() time.Duration {
+	n := c.m.GetInt64("cluster.healing_threshold")
+	return time.Duration(n) * time.Second
+}
+
 // ImagesMinimalReplica returns the numbers of nodes for cluster images replication
 func (c *Config) ImagesMinimalReplica() int64 {
 	return c.m.GetInt64("cluster.images_minimal_replica")
@@ -149,6 +219,21 @@ func (c *Config) MaxStandBy() int64 {
 	return c.m.GetInt64("cluster.max_standby")
 }
 
+// SchedulerHook returns the path of the executable used to pick the cluster
+// member new instances are placed on, if any.
+func (c *Config) SchedulerHook() string {
+	return c.m.GetString("cluster.scheduler.hook")
+}
+
+// SchedulerWeights returns the weights given to the instances count, CPU load,
+// memory usage and disk usage of the cluster members when placing instances.
+func (c *Config) SchedulerWeights() (int64, int64, int64, int64) {
+	return c.m.GetInt64("cluster.scheduler.weight.instances"),
+		c.m.GetInt64("cluster.scheduler.weight.cpu"),
+		c.m.GetInt64("cluster.scheduler.weight.memory"),
+		c.m.GetInt64("cluster.scheduler.weight.disk")
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -179,6 +264,17 @@ func (c *Config) update(values map[string]interface{}) (map[string]string, error
 		return nil, err
 	}
 
+	// A member can only be detected as offline after missing a heartbeat.
+	if c.OfflineThreshold() <= c.HeartbeatInterval() {
+		return nil, fmt.Errorf("cluster.offline_threshold must be greater than cluster.heartbeat_interval")
+	}
+
+	// Members self-fence after half the healing threshold, which needs to leave room for a few missed
+	// heartbeats at the configured interval.
+	if c.HealingThreshold() != 0 && c.HealingThreshold() < 6*c.HeartbeatInterval() {
+		return nil, fmt.Errorf("cluster.healing_threshold must be either 0 or at least six times cluster.heartbeat_interval")
+	}
+
 	err = c.tx.UpdateConfig(changed)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot persist configuration changes: %v")
@@ -238,38 +334,60 @@ func configGet(cluster *db.Cluster) (*Config, error) {
 
 // ConfigSchema defines available server configuration keys.
 var ConfigSchema = config.Schema{
-	"backups.compression_algorithm":  {Default: "gzip", Validator: validateCompression},
-	"cluster.offline_threshold":      {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
-	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
-	"cluster.max_voters":             {Type: config.Int64, Default: "3", Validator: maxVotersValidator},
-	"cluster.max_standby":            {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
-	"core.https_allowed_headers":     {},
-	"core.https_allowed_methods":     {},
-	"core.https_allowed_origin":      {},
-	"core.https_allowed_credentials": {Type: config.Bool},
-	"core.proxy_http":                {},
-	"core.proxy_https":               {},
-	"core.proxy_ignore_hosts":        {},
-	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
-	"core.trust_ca_certificates":     {Type: config.Bool},
-	"candid.api.key":                 {},
-	"candid.api.url":                 {},
-	"candid.domains":                 {},
-	"candid.expiry":                  {Type: config.Int64, Default: "3600"},
-	"images.auto_update_cached":      {Type: config.Bool, Default: "true"},
-	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
-	"images.compression_algorithm":   {Default: "gzip", Validator: validateCompression},
-	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
-	"maas.api.key":                   {},
-	"maas.api.url":                   {},
-	"rbac.agent.url":                 {},
-	"rbac.agent.username":            {},
-	"rbac.agent.private_key":         {},
-	"rbac.agent.public_key":          {},
-	"rbac.api.expiry":                {Type: config.Int64, Default: "3600"},
-	"rbac.api.key":                   {},
-	"rbac.api.url":                   {},
-	"rbac.expiry":                    {Type: config.Int64, Default: "3600"},
+	"audit.webhook":                      {Validator: auditWebhookValidator},
+	"backups.compression_algorithm":      {Default: "gzip", Validator: validateCompression},
+	"backups.max_bandwidth":              {Validator: shared.IsSize},
+	"backups.s3.access_key":              {},
+	"backups.s3.bucket":                  {Validator: s3BucketValidator},
+	"backups.s3.endpoint":                {Validator: s3EndpointValidator},
+	"backups.s3.secret_key":              {Hidden: true},
+	"cluster.offline_threshold":          {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
+	"cluster.heartbeat_interval":         {Type: config.Int64, Default: strconv.Itoa(heartbeatIntervalDefault), Validator: heartbeatIntervalValidator},
+	"cluster.healing_threshold":          {Type: config.Int64, Default: "0", Validator: healingThresholdValidator},
+	"cluster.images_minimal_replica":     {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
+	"cluster.max_voters":                 {Type: config.Int64, Default: "3", Validator: maxVotersValidator},
+	"cluster.max_standby":                {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
+	"cluster.scheduler.hook":             {Validator: schedulerHookValidator},
+	"cluster.scheduler.weight.cpu":       {Type: config.Int64, Default: "1", Validator: shared.IsUint32},
+	"cluster.scheduler.weight.disk":      {Type: config.Int64, Default: "1", Validator: shared.IsUint32},
+	"cluster.scheduler.weight.instances": {Type: config.Int64, Default: "1", Validator: shared.IsUint32},
+	"cluster.scheduler.weight.memory":    {Type: config.Int64, Default: "1", Validator: shared.IsUint32},
+	"core.bgp_asn":                       {Type: config.Int64, Default: "0", Validator: validateBGPASN},
+	"core.https_allowed_headers":         {},
+	"core.https_allowed_methods":         {},
+	"core.https_allowed_origin":          {},
+	"core.https_allowed_credentials":     {Type: config.Bool},
+	"core.proxy_http":                    {},
+	"core.proxy_https":                   {},
+	"core.proxy_ignore_hosts":            {},
+	"core.remote_token_expiry":           {Default: "1d", Validator: remoteTokenExpiryValidator},
+	"core.trust_password":                {Hidden: true, Setter: passwordSetter},
+	"core.trust_ca_certificates":         {Type: config.Bool},
+	"candid.api.key":                     {},
+	"candid.api.url":                     {},
+	"candid.domains":                     {},
+	"candid.expiry":                      {Type: config.Int64, Default: "3600"},
+	"images.auto_update_cached":          {Type: config.Bool, Default: "true"},
+	"images.auto_update_interval":        {Type: config.Int64, Default: "6"},
+	"images.compression_algorithm":       {Default: "gzip", Validator: validateCompression},
+	"images.import_compression":          {Validator: validateImportCompression},
+	"images.import_vm_format":            {Validator: validateImportVMFormat},
+	"images.remote_cache_expiry":         {Type: config.Int64, Default: "10"},
+	"images.remote_cache_max_size":       {Validator: shared.IsSize},
+	"images.remote_cache_prune_interval": {Type: config.Int64, Default: "24"},
+	"images.simplestreams":               {Type: config.Bool, Default: "false"},
+	"maas.api.key":                       {},
+	"maas.api.url":                       {},
+	"migration.max_bandwidth":            {Validator: shared.IsSize},
+	"network.ovn.northbound_connection":  {Default: "unix:/var/run/ovn/ovnnb_db.sock"},
+	"rbac.agent.url":                     {},
+	"rbac.agent.username":                {},
+	"rbac.agent.private_key":             {},
+	"rbac.agent.public_key":              {},
+	"rbac.api.expiry":                    {Type: config.Int64, Default: "3600"},
+	"rbac.api.key":                       {},
+	"rbac.api.url":                       {},
+	"rbac.expiry":                        {Type: config.Int64, Default: "3600"},
 
 	// Keys deprecated since the implementation of the storage api.
 	"storage.lvm_fstype":           {Setter: deprecatedStorage, Default: "ext4"},
@@ -287,15 +405,44 @@ func offlineThresholdDefault() string {
 }
 
 func offlineThresholdValidator(value string) error {
-	// Ensure that the given value is greater than the heartbeat interval,
-	// which is the lower bound granularity of the offline check.
+	// Ensure that the given value is greater than the minimum heartbeat
+	// interval, which is the lower bound granularity of the offline check.
 	threshold, err := strconv.Atoi(value)
 	if err != nil {
 		return fmt.Errorf("Offline threshold is not a number")
 	}
 
-	if threshold <= heartbeatInterval {
-		return fmt.Errorf("Value must be greater than '%d'", heartbeatInterval)
+	if threshold <= heartbeatIntervalMin {
+		return fmt.Errorf("Value must be greater than '%d'", heartbeatIntervalMin)
+	}
+
+	return nil
+}
+
+func heartbeatIntervalValidator(value string) error {
+	interval, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Heartbeat interval is not a number")
+	}
+
+	if interval < heartbeatIntervalMin {
+		return fmt.Errorf("Value must be at least '%d'", heartbeatIntervalMin)
+	}
+
+	return nil
+}
+
+func healingThresholdValidator(value string) error {
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Healing threshold is not a number")
+	}
+
+	// Members self-fence after half the threshold, which needs to leave room
+	// for a few missed heartbeats. The configured heartbeat interval is
+	// checked along with it when updating the configuration.
+	if threshold != 0 && threshold < 6*heartbeatIntervalMin {
+		return fmt.Errorf("Value must be either '0' or at least '%d'", 6*heartbeatIntervalMin)
 	}
 
 	return nil
@@ -340,6 +487,94 @@ func maxStandByValidator(value string) error {
 	return nil
 }
 
+func remoteTokenExpiryValidator(value string) error {
+	_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+	return err
+}
+
+func auditWebhookValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Audit webhook must be an HTTP or HTTPS URL")
+	}
+
+	return nil
+}
+
+// s3EndpointValidator checks the URL of an S3 endpoint, whose scheme selects whether TLS is used.
+func s3EndpointValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("S3 endpoint must be an HTTP or HTTPS URL")
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("S3 endpoint must include a host")
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Errorf("S3 endpoint can't include a path")
+	}
+
+	return nil
+}
+
+// s3BucketValidator checks a bucket name against the S3 naming rules.
+func s3BucketValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if len(value) < 3 || len(value) > 63 {
+		return fmt.Errorf("S3 bucket name must be between 3 and 63 characters long")
+	}
+
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '.' {
+			return fmt.Errorf("S3 bucket name can only contain lowercase letters, numbers, dots and hyphens")
+		}
+	}
+
+	first, last := value[0], value[len(value)-1]
+	if first == '-' || first == '.' || last == '-' || last == '.' {
+		return fmt.Errorf("S3 bucket name must start and end with a letter or number")
+	}
+
+	if strings.Contains(value, "..") {
+		return fmt.Errorf("S3 bucket name can't contain consecutive dots")
+	}
+
+	return nil
+}
+
+func schedulerHookValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("Scheduler hook must be an absolute path")
+	}
+
+	return nil
+}
+
 func passwordSetter(value string) (string, error) {
 	// Nothing to do on unset
 	if value == "" {
@@ -384,9 +619,34 @@ func validateCompression(value string) error {
 	return err
 }
 
+// validateImportCompression checks the compression algorithm uploaded images get re-packed with, if any.
+func validateImportCompression(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	return validateCompression(value)
+}
+
+// validateImportVMFormat checks the format the root disk of uploaded virtual-machine images get converted to,
+// if any.
+func validateImportVMFormat(value string) error {
+	return shared.IsOneOf(value, []string{"", "qcow2", "raw"})
+}
+
 func deprecatedStorage(value string) (string, error) {
 	if value == "" {
 		return "", nil
 	}
 	return "", fmt.Errorf("deprecated: use storage pool configuration")
 }
+
+// validateBGPASN checks the ASN fits in 32 bits, 0 disables the BGP server.
+func validateBGPASN(value string) error {
+	_, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid ASN %q", value)
+	}
+
+	return nil
+}
@@ -60,8 +60,62 @@ func TestConfigLoad_OfflineThresholdValidator(t *testing.T) {
 	require.NoError(t, err)
 
 	_, err = config.Patch(map[string]interface{}{"cluster.offline_threshold": "2"})
-	require.EqualError(t, err, "cannot set 'cluster.offline_threshold' to '2': Value must be greater than '10'")
+	require.EqualError(t, err, "cannot set 'cluster.offline_threshold' to '2': Value must be greater than '4'")
 
+	_, err = config.Patch(map[string]interface{}{"cluster.offline_threshold": "8"})
+	require.EqualError(t, err, "cluster.offline_threshold must be greater than cluster.heartbeat_interval")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.offline_threshold": "8", "cluster.heartbeat_interval": "4"})
+	require.NoError(t, err)
+}
+
+// Healing threshold must leave room for a few missed heartbeats at the configured interval.
+func TestConfigLoad_HealingThresholdValidator(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "10"})
+	require.EqualError(t, err, "cannot set 'cluster.healing_threshold' to '10': Value must be either '0' or at least '24'")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "30"})
+	require.EqualError(t, err, "cluster.healing_threshold must be either 0 or at least six times cluster.heartbeat_interval")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "60"})
+	require.NoError(t, err)
+
+	// Raising the heartbeat interval once healing is configured is checked too.
+	_, err = config.Patch(map[string]interface{}{"cluster.heartbeat_interval": "20", "cluster.offline_threshold": "60"})
+	require.EqualError(t, err, "cluster.healing_threshold must be either 0 or at least six times cluster.heartbeat_interval")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.heartbeat_interval": "20", "cluster.offline_threshold": "60", "cluster.healing_threshold": "120"})
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "0", "cluster.heartbeat_interval": "30"})
+	require.NoError(t, err)
+}
+
+// The S3 endpoint must be an HTTP(S) URL and the bucket a valid S3 bucket name.
+func TestConfigLoad_S3Validators(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]interface{}{"backups.s3.endpoint": "s3.example.net"})
+	require.EqualError(t, err, "cannot set 'backups.s3.endpoint' to 's3.example.net': S3 endpoint must be an HTTP or HTTPS URL")
+
+	_, err = config.Patch(map[string]interface{}{"backups.s3.bucket": "My_Backups"})
+	require.EqualError(t, err, "cannot set 'backups.s3.bucket' to 'My_Backups': S3 bucket name can only contain lowercase letters, numbers, dots and hyphens")
+
+	_, err = config.Patch(map[string]interface{}{"backups.s3.bucket": "-backups"})
+	require.EqualError(t, err, "cannot set 'backups.s3.bucket' to '-backups': S3 bucket name must start and end with a letter or number")
+
+	_, err = config.Patch(map[string]interface{}{"backups.s3.endpoint": "https://s3.example.net", "backups.s3.bucket": "lxd-backups"})
+	require.NoError(t, err)
 }
 
 // Max number of voters must be odd.
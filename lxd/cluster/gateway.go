@@ -110,6 +110,14 @@ type Gateway struct {
 	Cluster           *db.Cluster
 	HeartbeatNodeHook func(*APIHeartbeat)
 
+	// Called by the leader when the state of a member changes (online,
+	// degraded or offline).
+	MemberStateHook func(name string, address string, state string)
+
+	// States of the members as of the last heartbeat round.
+	memberStates     map[int64]string
+	memberStatesLock sync.Mutex
+
 	// NodeStore wrapper.
 	store *dqliteNodeStore
 
@@ -120,6 +128,12 @@ type Gateway struct {
 
 	// Keep track of skews
 	timeSkew bool
+
+	// Last time this member was in touch with the rest of the cluster,
+	// either by receiving a heartbeat or, as leader, by reaching another
+	// member.
+	lastContact     time.Time
+	lastContactLock sync.Mutex
 }
 
 // Current dqlite protocol version.
@@ -186,6 +200,8 @@ func (g *Gateway) HandlerFuncs(nodeRefreshTask func(*APIHeartbeat)) map[string]h
 				return
 			}
 
+			g.updateLastContact()
+
 			// Look for time skews
 			if heartbeatData.Time.Add(5 * time.Second).Before(time.Now().UTC()) {
 				if !g.timeSkew {
@@ -834,6 +850,22 @@ func (g *Gateway) isLeader() (bool, error) {
 	return leader != nil && leader.ID == g.info.ID, nil
 }
 
+// LastContact returns the last time this member was in touch with the rest of
+// the cluster, or the zero time if it never was.
+func (g *Gateway) LastContact() time.Time {
+	g.lastContactLock.Lock()
+	defer g.lastContactLock.Unlock()
+
+	return g.lastContact
+}
+
+func (g *Gateway) updateLastContact() {
+	g.lastContactLock.Lock()
+	defer g.lastContactLock.Unlock()
+
+	g.lastContact = time.Now()
+}
+
 // ErrNotLeader signals that a node not the leader.
 var ErrNotLeader = fmt.Errorf("Not leader")
 
@@ -109,14 +109,15 @@ func (hbState *APIHeartbeat) Update(fullStateList bool, raftNodes []db.RaftNode,
 }
 
 // Send sends heartbeat requests to the nodes supplied and updates heartbeat state.
-func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, localAddress string, nodes []db.NodeInfo, delay bool) {
+// If interval is not zero, the requests are spread over the heartbeat interval.
+func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, localAddress string, nodes []db.NodeInfo, interval time.Duration) {
 	heartbeatsWg := sync.WaitGroup{}
-	sendHeartbeat := func(nodeID int64, address string, delay bool, heartbeatData *APIHeartbeat) {
+	sendHeartbeat := func(nodeID int64, address string, interval time.Duration, heartbeatData *APIHeartbeat) {
 		defer heartbeatsWg.Done()
 
-		if delay {
+		if interval > 0 {
 			// Spread in time by waiting up to 3s less than the interval.
-			time.Sleep(time.Duration(rand.Intn((heartbeatInterval*1000)-3000)) * time.Millisecond)
+			time.Sleep(time.Duration(rand.Int63n(int64(interval-3*time.Second)/int64(time.Millisecond))) * time.Millisecond)
 		}
 		logger.Debugf("Sending heartbeat to %s", address)
 
@@ -158,7 +159,7 @@ func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, lo
 
 		// Parallelize the rest.
 		heartbeatsWg.Add(1)
-		go sendHeartbeat(node.ID, node.Address, delay, hbState)
+		go sendHeartbeat(node.ID, node.Address, interval, hbState)
 	}
 	heartbeatsWg.Wait()
 }
@@ -183,11 +184,32 @@ func HeartbeatTask(gateway *Gateway) (task.Func, task.Schedule) {
 		}
 	}
 
-	schedule := task.Every(time.Duration(heartbeatInterval) * time.Second)
+	// The interval is looked up before each round, so that changes to
+	// cluster.heartbeat_interval are picked up.
+	schedule := func() (time.Duration, error) {
+		return gateway.heartbeatInterval(), nil
+	}
 
 	return heartbeatWrapper, schedule
 }
 
+// Return the configured interval between heartbeat rounds, falling back to
+// the default one if it can't be loaded.
+func (g *Gateway) heartbeatInterval() time.Duration {
+	interval := time.Duration(heartbeatIntervalDefault) * time.Second
+	if g.Cluster == nil {
+		return interval
+	}
+
+	n, err := ConfigGetInt64(g.Cluster, "cluster.heartbeat_interval")
+	if err != nil {
+		logger.Warnf("Failed to load heartbeat interval: %v", err)
+		return interval
+	}
+
+	return time.Duration(n) * time.Second
+}
+
 func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	if g.Cluster == nil || g.server == nil || g.memoryDial != nil {
 		// We're not a raft node or we're not clustered
@@ -221,6 +243,7 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	var allNodes []db.NodeInfo
 	var localAddress string // Address of this node
 	var offlineThreshold time.Duration
+	var interval time.Duration
 	err = g.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 		allNodes, err = tx.GetNodes()
@@ -228,6 +251,12 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 			return err
 		}
 
+		config, err := ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+		interval = config.HeartbeatInterval()
+
 		localAddress, err = tx.GetLocalNodeAddress()
 		if err != nil {
 			return err
@@ -262,14 +291,14 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	// Send stale set to all nodes in database to get a fresh set of active nodes.
 	if initialHeartbeat {
 		hbState.Update(false, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, 0)
 
 		// We have the latest set of node states now, lets send that state set to all nodes.
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, 0)
 	} else {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, true)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, interval)
 	}
 
 	// Look for any new node which appeared since sending last heartbeat.
@@ -308,7 +337,7 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	// If any new nodes found, send heartbeat to just them (with full node state).
 	if len(newNodes) > 0 {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, newNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, newNodes, 0)
 	}
 
 	// If the context has been cancelled, return immediately.
@@ -317,6 +346,13 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 		return
 	}
 
+	for _, node := range hbState.Members {
+		if node.updated && node.Address != localAddress {
+			g.updateLastContact()
+			break
+		}
+	}
+
 	err = g.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		for _, node := range hbState.Members {
 			if !node.updated {
@@ -334,6 +370,8 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 		logger.Warnf("Failed to update heartbeat: %v", err)
 	}
 
+	g.updateMemberStates(allNodes, hbState, offlineThreshold)
+
 	// If full node state was sent and node refresh task is specified, run it async.
 	if g.HeartbeatNodeHook != nil {
 		go g.HeartbeatNodeHook(hbState)
@@ -343,8 +381,45 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	logger.Debugf("Completed heartbeat round")
 }
 
-// heartbeatInterval Number of seconds to wait between to heartbeat rounds.
-const heartbeatInterval = 10
+// Work out the state of each cluster member after a heartbeat round and notify
+// MemberStateHook of the ones which changed since the previous round. Members
+// which missed the last heartbeat but aren't yet considered offline are
+// degraded.
+func (g *Gateway) updateMemberStates(nodes []db.NodeInfo, hbState *APIHeartbeat, offlineThreshold time.Duration) {
+	g.memberStatesLock.Lock()
+	defer g.memberStatesLock.Unlock()
+
+	states := make(map[int64]string, len(nodes))
+	for _, node := range nodes {
+		member, ok := hbState.Members[node.ID]
+		if !ok {
+			continue
+		}
+
+		state := "offline"
+		if member.updated {
+			state = "online"
+		} else if !member.LastHeartbeat.Before(time.Now().Add(-offlineThreshold)) {
+			state = "degraded"
+		}
+
+		states[node.ID] = state
+
+		previous, ok := g.memberStates[node.ID]
+		if ok && previous != state && g.MemberStateHook != nil {
+			g.MemberStateHook(node.Name, node.Address, state)
+		}
+	}
+
+	g.memberStates = states
+}
+
+// heartbeatIntervalDefault Default number of seconds to wait between two heartbeat rounds.
+const heartbeatIntervalDefault = 10
+
+// heartbeatIntervalMin Minimum number of seconds between two heartbeat rounds, as
+// heartbeats are spread over the interval minus 3 seconds.
+const heartbeatIntervalMin = 4
 
 // HeartbeatNode performs a single heartbeat request against the node with the given address.
 func HeartbeatNode(taskCtx context.Context, address string, cert *shared.CertInfo, heartbeatData *APIHeartbeat) error {
@@ -407,22 +407,24 @@ func Join(state *state.State, gateway *Gateway, cert *shared.CertInfo, name stri
 		}
 
 		// Networks.
-		ids, err = tx.GetNonPendingNetworkIDs()
+		networkIDs, err := tx.GetNonPendingNetworkIDs()
 		if err != nil {
 			return errors.Wrap(err, "failed to get cluster network IDs")
 		}
-		for name, id := range ids {
-			config, ok := networks[name]
-			if !ok {
-				return fmt.Errorf("joining node has no config for network %s", name)
-			}
-			err := tx.NetworkNodeJoin(id, node.ID)
-			if err != nil {
-				return errors.Wrap(err, "failed to add joining node's to the network")
-			}
-			err = tx.CreateNetworkConfig(id, node.ID, config)
-			if err != nil {
-				return errors.Wrap(err, "failed to add joining node's network config")
+		for _, ids := range networkIDs {
+			for name, id := range ids {
+				config, ok := networks[name]
+				if !ok {
+					return fmt.Errorf("joining node has no config for network %s", name)
+				}
+				err := tx.NetworkNodeJoin(id, node.ID)
+				if err != nil {
+					return errors.Wrap(err, "failed to add joining node's to the network")
+				}
+				err = tx.CreateNetworkConfig(id, node.ID, config)
+				if err != nil {
+					return errors.Wrap(err, "failed to add joining node's network config")
+				}
 			}
 		}
 
@@ -491,6 +493,18 @@ func Rebalance(state *state.State, gateway *Gateway) (string, []db.RaftNode, err
 		return "", nil, errors.Wrap(err, "Get current raft nodes")
 	}
 
+	// Honour the database roles pinned or excluded by the administrator
+	// before letting dqlite balance the rest.
+	address, err := rebalancePinnedRoles(state, gateway, nodes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if address != "" {
+		logger.Infof("Found node %s whose role needs to be changed to match its cluster roles", address)
+		return address, nodes, nil
+	}
+
 	roles, err := newRolesChanges(state, gateway, nodes)
 	if err != nil {
 		return "", nil, err
@@ -504,7 +518,7 @@ func Rebalance(state *state.State, gateway *Gateway) (string, []db.RaftNode, err
 	}
 
 	// Check if we have a spare node that we can promote to the missing role.
-	address := candidates[0].Address
+	address = candidates[0].Address
 	logger.Infof("Found node %s whose role needs to be changed to %s", address, role)
 
 	for i, node := range nodes {
@@ -517,6 +531,93 @@ func Rebalance(state *state.State, gateway *Gateway) (string, []db.RaftNode, err
 	return address, nodes, nil
 }
 
+// Check whether the database role of a member must change because of its
+// database-pinned or database-client roles. If so, its new role is set in the
+// given nodes and its address is returned.
+func rebalancePinnedRoles(state *state.State, gateway *Gateway, nodes []db.RaftNode) (string, error) {
+	var maxVoters int
+	var pinned map[string]bool
+	var excluded map[string]bool
+
+	err := state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := ConfigLoad(tx)
+		if err != nil {
+			return errors.Wrap(err, "Load cluster configuration")
+		}
+		maxVoters = int(config.MaxVoters())
+
+		pinned, excluded, err = loadDatabaseRolePins(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Members which must not hold a database role step back first. The
+	// leader is left alone until leadership moves elsewhere.
+	for i, node := range nodes {
+		if excluded[node.Address] && node.Role != db.RaftSpare && node.ID != gateway.info.ID {
+			nodes[i].Role = db.RaftSpare
+			return node.Address, nil
+		}
+	}
+
+	voters := 0
+	for _, node := range nodes {
+		if node.Role == db.RaftVoter {
+			voters++
+		}
+	}
+
+	for i, node := range nodes {
+		if !pinned[node.Address] || node.Role == db.RaftVoter || !HasConnectivity(gateway.cert, node.Address) {
+			continue
+		}
+
+		if voters < maxVoters {
+			nodes[i].Role = db.RaftVoter
+			return node.Address, nil
+		}
+
+		// Make room for the pinned member by demoting a voter which isn't
+		// pinned, it's then promoted on the next round.
+		for j, other := range nodes {
+			if other.Role != db.RaftVoter || pinned[other.Address] || other.ID == gateway.info.ID {
+				continue
+			}
+
+			nodes[j].Role = db.RaftStandBy
+			return other.Address, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Return the addresses of the members with the database-pinned and
+// database-client roles.
+func loadDatabaseRolePins(tx *db.ClusterTx) (map[string]bool, map[string]bool, error) {
+	nodes, err := tx.GetNodes()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Load nodes")
+	}
+
+	pinned := map[string]bool{}
+	excluded := map[string]bool{}
+	for _, node := range nodes {
+		for _, role := range node.Roles {
+			switch db.ClusterRole(role) {
+			case db.ClusterRoleDatabasePinned:
+				pinned[node.Address] = true
+			case db.ClusterRoleDatabaseClient:
+				excluded[node.Address] = true
+			}
+		}
+	}
+
+	return pinned, excluded, nil
+}
+
 // Assign a new role to the local dqlite node.
 func Assign(state *state.State, gateway *Gateway, nodes []db.RaftNode) error {
 	// Figure out our own address.
@@ -825,6 +926,7 @@ func newRolesChanges(state *state.State, gateway *Gateway, nodes []db.RaftNode)
 	var maxVoters int
 	var maxStandBy int
 	var domains map[string]uint64
+	var excluded map[string]bool
 
 	err := state.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		config, err := ConfigLoad(tx)
@@ -839,6 +941,11 @@ func newRolesChanges(state *state.State, gateway *Gateway, nodes []db.RaftNode)
 			return errors.Wrap(err, "Load failure domains")
 		}
 
+		_, excluded, err = loadDatabaseRolePins(tx)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -848,6 +955,12 @@ func newRolesChanges(state *state.State, gateway *Gateway, nodes []db.RaftNode)
 	cluster := map[client.NodeInfo]*client.NodeMetadata{}
 
 	for _, node := range nodes {
+		// Hide spare members which must not hold a database role, so
+		// that they never get picked.
+		if excluded[node.Address] && node.Role == db.RaftSpare {
+			continue
+		}
+
 		if HasConnectivity(gateway.cert, node.Address) {
 			cluster[node] = &client.NodeMetadata{
 				FailureDomain: domains[node.Address],
@@ -899,6 +1012,8 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 	var nodes []db.NodeInfo
 	var offlineThreshold time.Duration
 	domains := map[string]string{}
+	groups := map[string][]string{}
+	configs := map[string]map[string]string{}
 
 	err = state.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		nodes, err = tx.GetNodes()
@@ -924,6 +1039,16 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 		for _, node := range nodes {
 			domainID := nodesDomains[node.Address]
 			domains[node.Address] = domainsNames[domainID]
+
+			groups[node.Address], err = tx.GetNodeClusterGroups(node.ID)
+			if err != nil {
+				return errors.Wrap(err, "Load nodes cluster groups")
+			}
+
+			configs[node.Address], err = tx.GetNodeConfig(node.ID)
+			if err != nil {
+				return errors.Wrap(err, "Load nodes config")
+			}
 		}
 
 		return nil
@@ -957,6 +1082,19 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 		raftRoles[address] = node.Role
 	}
 
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderAddress := ""
+	if leader != nil {
+		leaderAddress, err = gateway.nodeAddress(leader.Address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	result := make([]api.ClusterMember, len(nodes))
 	now := time.Now()
 	version := nodes[0].Version()
@@ -968,16 +1106,28 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 		if result[i].Database {
 			result[i].Roles = append(result[i].Roles, string(db.ClusterRoleDatabase))
 		}
+		if raftRoles[node.Address] == db.RaftStandBy {
+			result[i].Roles = append(result[i].Roles, string(db.ClusterRoleDatabaseStandBy))
+		}
+		if node.Address == leaderAddress {
+			result[i].Roles = append(result[i].Roles, string(db.ClusterRoleDatabaseLeader))
+		}
 		result[i].Architecture, err = osarch.ArchitectureName(node.Architecture)
 		if err != nil {
 			return nil, err
 		}
 		result[i].FailureDomain = domains[node.Address]
+		result[i].Groups = groups[node.Address]
+		result[i].Description = node.Description
+		result[i].Config = configs[node.Address]
 
 		if node.IsOffline(offlineThreshold) {
 			result[i].Status = "Offline"
 			result[i].Message = fmt.Sprintf(
 				"no heartbeat since %s", now.Sub(node.Heartbeat))
+		} else if node.State == db.ClusterMemberStateEvacuated {
+			result[i].Status = "Evacuated"
+			result[i].Message = "unavailable due to maintenance"
 		} else {
 			result[i].Status = "Online"
 			result[i].Message = "fully operational"
@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"hash/fnv"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// TaskAssignee returns the address of the member which should run the unit of
+// cluster-wide work identified by key, among the given candidate members.
+//
+// Members are ranked using rendezvous hashing, so that all members agree on
+// the assignee without talking to each other and only the work assigned to a
+// member which goes away gets reassigned. The leader, which already carries
+// the database load, is only picked if it's the only candidate.
+//
+// An empty string is returned if there are no candidates.
+func TaskAssignee(key string, candidates []db.NodeInfo, leader string) string {
+	assignee := ""
+	var best uint64
+
+	for _, member := range candidates {
+		if member.Address == leader && len(candidates) > 1 {
+			continue
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(member.Address))
+
+		score := h.Sum64()
+		if assignee == "" || score > best {
+			assignee = member.Address
+			best = score
+		}
+	}
+
+	return assignee
+}
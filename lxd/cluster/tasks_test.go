@@ -0,0 +1,40 @@
+package cluster_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskAssignee(t *testing.T) {
+	members := []db.NodeInfo{
+		{Name: "node1", Address: "10.0.0.1:8443"},
+		{Name: "node2", Address: "10.0.0.2:8443"},
+		{Name: "node3", Address: "10.0.0.3:8443"},
+	}
+
+	// No candidates.
+	assert.Equal(t, "", cluster.TaskAssignee("foo", nil, ""))
+
+	// The leader is only used when it's the only candidate.
+	assert.Equal(t, "10.0.0.1:8443", cluster.TaskAssignee("foo", members[:1], "10.0.0.1:8443"))
+
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("task-%d", i)
+		assignee := cluster.TaskAssignee(key, members, "10.0.0.1:8443")
+		assert.NotEqual(t, "10.0.0.1:8443", assignee)
+
+		// The assignment doesn't depend on the order of the candidates.
+		reversed := []db.NodeInfo{members[2], members[1], members[0]}
+		assert.Equal(t, assignee, cluster.TaskAssignee(key, reversed, "10.0.0.1:8443"))
+
+		counts[assignee]++
+	}
+
+	// The work is spread across the non-leader members.
+	assert.Len(t, counts, 2)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 	"github.com/pkg/errors"
 )
 
@@ -97,6 +98,31 @@ func MaybeUpdate(state *state.State) error {
 	return triggerUpdate()
 }
 
+// APIExtensionEnabled returns true if all the members of the cluster support
+// the given API extension. Members only disagree on this during a rolling
+// upgrade, in which case requests storing data that older members wouldn't
+// understand should be refused.
+func APIExtensionEnabled(tx *db.ClusterTx, extension string) (bool, error) {
+	index := -1
+	for i, name := range version.APIExtensions {
+		if name == extension {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		return false, fmt.Errorf("Unknown API extension %q", extension)
+	}
+
+	count, err := tx.GetNodesAPIExtensionsCount()
+	if err != nil {
+		return false, err
+	}
+
+	return count > index, nil
+}
+
 func triggerUpdate() error {
 	logger.Infof("Node is out-of-date with respect to other cluster nodes")
 
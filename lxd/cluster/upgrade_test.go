@@ -14,6 +14,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/version"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -115,6 +116,38 @@ func TestMaybeUpdate_NothingToDo(t *testing.T) {
 	require.True(t, os.IsNotExist(err))
 }
 
+// An API extension is only enabled once all members support it.
+func TestAPIExtensionEnabled(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	last := version.APIExtensions[len(version.APIExtensions)-1]
+
+	enabled, err := cluster.APIExtensionEnabled(tx, last)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	nodes, err := tx.GetNodes()
+	require.NoError(t, err)
+
+	err = tx.SetNodeVersion(id, [2]int{nodes[0].Schema, len(version.APIExtensions) - 1})
+	require.NoError(t, err)
+
+	enabled, err = cluster.APIExtensionEnabled(tx, last)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	enabled, err = cluster.APIExtensionEnabled(tx, version.APIExtensions[0])
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	_, err = cluster.APIExtensionEnabled(tx, "no_such_extension")
+	assert.Error(t, err)
+}
+
 func TestUpgradeMembersWithoutRole(t *testing.T) {
 	state, cleanup := state.NewTestState(t)
 	defer cleanup()
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/task"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// clusterHealingTask restarts the ceph-backed instances of members which have been offline for longer than
+// cluster.healing_threshold on the remaining members. Only the leader runs it.
+func clusterHealingTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		localAddress, err := node.ClusterAddress(d.db)
+		if err != nil {
+			logger.Errorf("Failed to get current node address: %v", err)
+			return
+		}
+
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil {
+			logger.Errorf("Failed to get leader node address: %v", err)
+			return
+		}
+
+		if localAddress != leader {
+			return
+		}
+
+		var members []db.NodeInfo
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			threshold := config.HealingThreshold()
+			if threshold == 0 {
+				return nil
+			}
+
+			// Never consider a member for healing before it's considered offline.
+			if config.OfflineThreshold() > threshold {
+				threshold = config.OfflineThreshold()
+			}
+
+			nodes, err := tx.GetNodes()
+			if err != nil {
+				return err
+			}
+
+			for _, nodeInfo := range nodes {
+				if nodeInfo.Address == localAddress || nodeInfo.State == db.ClusterMemberStateEvacuated {
+					continue
+				}
+
+				if nodeInfo.IsOffline(threshold) {
+					members = append(members, nodeInfo)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to look for cluster members to heal", log.Ctx{"err": err})
+			return
+		}
+
+		for _, member := range members {
+			// Fencing: a member we can still talk to may well still be running its instances, in which case
+			// starting them elsewhere would corrupt their storage.
+			if cluster.HasConnectivity(d.endpoints.NetworkCert(), member.Address) {
+				logger.Warn("Not healing cluster member which missed heartbeats but is still reachable", log.Ctx{"member": member.Name})
+				continue
+			}
+
+			// Mark the member as evacuated straight away so that it's only healed once and gets no new
+			// instances. It's brought back with a regular restore.
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				return tx.UpdateNodeState(member.ID, db.ClusterMemberStateEvacuated)
+			})
+			if err != nil {
+				logger.Error("Failed to mark cluster member as evacuated", log.Ctx{"member": member.Name, "err": err})
+				continue
+			}
+
+			logger.Warn("Healing offline cluster member", log.Ctx{"member": member.Name, "heartbeat": member.Heartbeat})
+
+			member := member
+			opRun := func(op *operations.Operation) error {
+				return clusterHealMember(d, op, member)
+			}
+
+			op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterHeal, nil, nil, opRun, nil, nil)
+			if err != nil {
+				logger.Error("Failed to start cluster healing operation", log.Ctx{"member": member.Name, "err": err})
+				continue
+			}
+
+			_, err = op.Run()
+			if err != nil {
+				logger.Error("Failed to heal cluster member", log.Ctx{"member": member.Name, "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
+
+// clusterHealMember moves the running ceph-backed instances of an offline member to other members and starts
+// them there. The instances are recorded as evacuated from the member so that restoring it brings them back.
+func clusterHealMember(d *Daemon, op *operations.Operation, member db.NodeInfo) error {
+	var instances []db.Instance
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		instances, err = tx.GetInstances(db.InstanceFilter{Node: member.Name, Type: instancetype.Any})
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load instances")
+	}
+
+	client, err := clusterNodeLocalClient(d)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, dbInst := range instances {
+		if dbInst.Config["volatile.last_state.power"] != "RUNNING" {
+			continue
+		}
+
+		onCeph, err := clusterNodeInstanceOnCeph(d, dbInst.Project, dbInst.Name)
+		if err != nil {
+			return err
+		}
+
+		if !onCeph {
+			continue
+		}
+
+		op.UpdateMetadata(map[string]interface{}{"healing_progress": fmt.Sprintf("Healing %q in project %q", dbInst.Name, dbInst.Project)})
+
+		err = clusterHealInstance(d, client, dbInst, member.Name)
+		if err != nil {
+			logger.Error("Failed to heal instance", log.Ctx{"project": dbInst.Project, "instance": dbInst.Name, "err": err})
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to heal %d instances of cluster member %q", failed, member.Name)
+	}
+
+	return nil
+}
+
+// clusterHealInstance restarts an instance of an offline member on another member.
+func clusterHealInstance(d *Daemon, client lxd.InstanceServer, dbInst db.Instance, origin string) error {
+	inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load instance %q in project %q", dbInst.Name, dbInst.Project)
+	}
+
+	target, err := clusterNodeEvacuateTarget(d, inst)
+	if err != nil {
+		return err
+	}
+
+	if target == "" {
+		return fmt.Errorf("No cluster member available to move instance %q in project %q to", dbInst.Name, dbInst.Project)
+	}
+
+	return clusterNodeMoveInstance(d, client, dbInst.Project, dbInst.Name, target, origin, true, false)
+}
+
+// clusterHealingFenceTask stops the local ceph-backed instances once the member has been out of touch with the
+// rest of the cluster for half of cluster.healing_threshold, ahead of the leader restarting them elsewhere.
+func clusterHealingFenceTask(d *Daemon) (task.Func, task.Schedule) {
+	// The database can't be reached once isolated, so the threshold and the instances to stop are refreshed
+	// while the member is still in touch with the cluster.
+	var threshold time.Duration
+	var instances []instance.Instance
+
+	f := func(ctx context.Context) {
+		lastContact := d.gateway.LastContact()
+		if lastContact.IsZero() {
+			return
+		}
+
+		if threshold > 0 && time.Since(lastContact) > threshold/2 {
+			for _, inst := range instances {
+				if !inst.IsRunning() {
+					continue
+				}
+
+				logger.Warn("Stopping instance as the cluster can't be reached", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "lastContact": lastContact})
+
+				err := inst.Stop(false)
+				if err != nil {
+					logger.Error("Failed to stop instance", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				}
+			}
+
+			instances = nil
+			return
+		}
+
+		var config *cluster.Config
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			config, err = cluster.ConfigLoad(tx)
+			return err
+		})
+		if err != nil {
+			logger.Warn("Failed to load cluster configuration", log.Ctx{"err": err})
+			return
+		}
+
+		threshold = config.HealingThreshold()
+		if threshold == 0 {
+			instances = nil
+			return
+		}
+
+		localInstances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Warn("Failed to load instances", log.Ctx{"err": err})
+			return
+		}
+
+		instances = nil
+		for _, inst := range localInstances {
+			onCeph, err := clusterNodeInstanceOnCeph(d, inst.Project(), inst.Name())
+			if err != nil {
+				logger.Warn("Failed to check instance storage pool", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				continue
+			}
+
+			if onCeph {
+				instances = append(instances, inst)
+			}
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
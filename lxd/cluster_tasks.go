@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/shared"
+)
+
+// clusterTaskIsLocal returns whether the local member should run the unit of cluster-wide work identified by key.
+// The work is assigned to one of the online members which aren't evacuated, restricted to the given addresses if
+// any (e.g. the members holding a resource), and preferably not to the leader.
+func clusterTaskIsLocal(d *Daemon, key string, addresses []string) (bool, error) {
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get local member address")
+	}
+
+	// Not clustered.
+	if localAddress == "" {
+		return true, nil
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get leader address")
+	}
+
+	var candidates []db.NodeInfo
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		offlineThreshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, nodeInfo := range nodes {
+			if nodeInfo.IsOffline(offlineThreshold) || nodeInfo.State == db.ClusterMemberStateEvacuated {
+				continue
+			}
+
+			if addresses != nil && !shared.StringInSlice(nodeInfo.Address, addresses) {
+				continue
+			}
+
+			candidates = append(candidates, nodeInfo)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get cluster members")
+	}
+
+	return cluster.TaskAssignee(key, candidates, leader) == localAddress, nil
+}
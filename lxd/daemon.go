@@ -8,6 +8,7 @@ import (
 	sqldriver "database/sql/driver"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -29,10 +30,13 @@ import (
 	"gopkg.in/macaroon-bakery.v2/bakery/identchecker"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/device"
+	lxdDNS "github.com/lxc/lxd/lxd/dns"
 	"github.com/lxc/lxd/lxd/endpoints"
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/firewall"
@@ -42,7 +46,9 @@ import (
 	_ "github.com/lxc/lxd/lxd/instance/drivers"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/seccomp"
@@ -51,6 +57,7 @@ import (
 	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
@@ -61,6 +68,9 @@ import (
 // A Daemon can respond to requests from a shared client.
 type Daemon struct {
 	clientCerts  map[string]x509.Certificate
+	clientRoles  map[string]map[string]string // Project roles of restricted client certificates by fingerprint
+	socketGroups map[uint32]map[string]string // Project roles of the local unix socket groups by GID, nil for full access
+	audit        *audit.Logger
 	os           *sys.OS
 	db           *db.Node
 	firewall     firewall.Firewall
@@ -88,14 +98,23 @@ type Daemon struct {
 	endpoints *endpoints.Endpoints
 	gateway   *cluster.Gateway
 	seccomp   *seccomp.Server
+	dns       *lxdDNS.Server
+	bgp       *bgp.Server
 
 	proxy func(req *http.Request) (*url.URL, error)
 
 	externalAuth *externalAuth
 
+	// Certificates which only grant access to the metrics endpoint
+	clientMetricsCerts map[string]x509.Certificate
+
 	// Stores last heartbeat node information to detect node changes.
 	lastNodeList *cluster.APIHeartbeat
 
+	// Cached name of the local member, refreshed whenever it may have changed.
+	serverName     string
+	serverNameLock sync.RWMutex
+
 	// Serialize changes to cluster membership (joins, leaves, role
 	// changes).
 	clusterMembershipMutex   sync.RWMutex
@@ -158,6 +177,7 @@ func newDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Daemon{
+		audit:        audit.NewLogger(),
 		config:       config,
 		devlxdEvents: devlxdEvents,
 		events:       lxdEvents,
@@ -167,6 +187,7 @@ func newDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 		shutdownChan: make(chan struct{}),
 		ctx:          ctx,
 		cancel:       cancel,
+		bgp:          bgp.NewServer(),
 	}
 }
 
@@ -238,6 +259,34 @@ func allowProjectPermission(feature string, permission string) func(d *Daemon, r
 	}
 }
 
+// filterUsedBy drops the entries of a used_by list which belong to projects the user can't view.
+func filterUsedBy(d *Daemon, r *http.Request, entries []string) []string {
+	if d.userIsAdmin(r) {
+		return entries
+	}
+
+	usedBy := []string{}
+	for _, entry := range entries {
+		u, err := url.Parse(entry)
+		if err != nil {
+			continue
+		}
+
+		projectName := u.Query().Get("project")
+		if projectName == "" {
+			projectName = project.Default
+		}
+
+		if !d.userHasPermission(r, projectName, "view") {
+			continue
+		}
+
+		usedBy = append(usedBy, entry)
+	}
+
+	return usedBy
+}
+
 // Convenience function around Authenticate
 func (d *Daemon) checkTrustedClient(r *http.Request) error {
 	trusted, _, _, err := d.Authenticate(r)
@@ -272,6 +321,17 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 
 	// Local unix socket queries
 	if r.RemoteAddr == "@" {
+		roles, err := d.socketUserRoles(r)
+		if err != nil {
+			return false, "", "", nil
+		}
+
+		// Users only allowed through a restricted socket group.
+		if roles != nil {
+			cred := r.Context().Value("ucred").(*unix.Ucred)
+			return true, fmt.Sprintf("%d", cred.Uid), "unix-group", nil
+		}
+
 		return true, "", "unix", nil
 	}
 
@@ -330,6 +390,16 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		}
 	}
 
+	// Metrics certificates are only trusted for the metrics endpoint.
+	if r.URL.Path == "/1.0/metrics" {
+		for i := range r.TLS.PeerCertificates {
+			trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.clientMetricsCerts, d.endpoints.NetworkCert(), false)
+			if trusted {
+				return true, username, "tls", nil
+			}
+		}
+	}
+
 	// Reject unauthorized
 	return false, "", "", nil
 }
@@ -364,7 +434,7 @@ func (d *Daemon) State() *state.State {
 	// If the daemon is shutting down, the context will be cancelled.
 	// This information will be available throughout the code, and can be used to prevent new
 	// operations from starting during shutdown.
-	return state.NewState(d.ctx, d.db, d.cluster, d.maas, d.os, d.endpoints, d.events, d.devlxdEvents, d.firewall, d.proxy)
+	return state.NewState(d.ctx, d.db, d.cluster, d.maas, d.os, d.endpoints, d.events, d.devlxdEvents, d.firewall, d.bgp, d.proxy)
 }
 
 // UnixSocket returns the full path to the unix.socket file that this daemon is
@@ -412,6 +482,10 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			}
 		}
 
+		// Record mutating requests in the audit log
+		w, recordAudit := auditRequest(d, w, r, username, protocol)
+		defer recordAudit()
+
 		// Reject internal queries to remote, non-cluster, clients
 		if version == "internal" && !shared.StringInSlice(protocol, []string{"unix", "cluster"}) {
 			// Except for the initial cluster accept request (done over trusted TLS)
@@ -630,6 +704,7 @@ func (d *Daemon) init() error {
 		"cgroup2",
 		"pidfd",
 		"seccomp_allow_deny_syntax",
+		"idmapped_mounts_v2",
 	}
 	for _, extension := range lxcExtensions {
 		d.os.LXCFeatures[extension] = liblxc.HasApiExtension(extension)
@@ -702,6 +777,16 @@ func (d *Daemon) init() error {
 		}
 	}
 
+	// Detect idmapped mounts support.
+	if shared.IsTrue(os.Getenv("LXD_IDMAPPED_MOUNTS_DISABLE")) {
+		logger.Infof(" - idmapped mounts support: disabled")
+	} else if idmap.SupportsIdmappedMounts() && d.os.LXCFeatures["idmapped_mounts_v2"] {
+		d.os.IdmappedMounts = true
+		logger.Infof(" - idmapped mounts support: yes")
+	} else {
+		logger.Infof(" - idmapped mounts support: no")
+	}
+
 	// Validate the devices storage.
 	testDev := shared.VarPath("devices", ".test")
 	testDevNum := int(unix.Mkdev(0, 0))
@@ -743,6 +828,9 @@ func (d *Daemon) init() error {
 		return err
 	}
 	d.gateway.HeartbeatNodeHook = d.NodeRefreshTask
+	d.gateway.MemberStateHook = func(name string, address string, state string) {
+		d.events.SendLifecycle("", fmt.Sprintf("cluster-member-%s", state), fmt.Sprintf("/1.0/cluster/members/%s", name), map[string]interface{}{"address": address})
+	}
 
 	/* Setup some mounts (nice to have) */
 	if !d.os.MockMode {
@@ -917,6 +1005,13 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	// Record the host features which are missing as warnings.
+	if !d.os.AppArmorAvailable && os.Getenv("LXD_SECURITY_APPARMOR") != "false" {
+		warningRaise(d.State(), "", "apparmor", "unavailable", "", db.WarningSeverityHigh, "AppArmor support is unavailable, instances aren't confined by AppArmor")
+	} else {
+		warningResolve(d.State(), "", "apparmor", "unavailable", "")
+	}
+
 	// Cleanup leftover images.
 	pruneLeftoverImages(d)
 
@@ -938,6 +1033,12 @@ func (d *Daemon) init() error {
 	maasAPIKey := ""
 	maasMachine := ""
 
+	var socketGroups map[string]map[string]string
+
+	auditFile := ""
+	auditSyslog := false
+	auditWebhook := ""
+
 	err = d.db.Transaction(func(tx *db.NodeTx) error {
 		config, err := node.ConfigLoad(tx)
 		if err != nil {
@@ -945,6 +1046,9 @@ func (d *Daemon) init() error {
 		}
 
 		maasMachine = config.MAASMachine()
+		socketGroups = config.SocketGroups()
+		auditFile = config.AuditFile()
+		auditSyslog = config.AuditSyslog()
 		return nil
 	})
 	if err != nil {
@@ -965,12 +1069,29 @@ func (d *Daemon) init() error {
 		candidAPIURL, candidAPIKey, candidExpiry, candidDomains = config.CandidServer()
 		maasAPIURL, maasAPIKey = config.MAASController()
 		rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey = config.RBACServer()
+		auditWebhook = config.AuditWebhook()
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	err = d.refreshServerName()
+	if err != nil {
+		return err
+	}
+
+	err = d.setupSocketGroups(socketGroups)
+	if err != nil {
+		return err
+	}
+
+	// A broken audit sink shouldn't prevent the daemon from starting.
+	err = d.audit.Configure(auditFile, auditSyslog, auditWebhook)
+	if err != nil {
+		logger.Error("Failed to configure the audit log", log.Ctx{"err": err})
+	}
+
 	if rbacAPIURL != "" {
 		err = d.setupRBACServer(rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey)
 		if err != nil {
@@ -1013,6 +1134,33 @@ func (d *Daemon) init() error {
 			logger.Info("Started seccomp handler", log.Ctx{"path": shared.VarPath("seccomp.socket")})
 		}
 
+		// Setup the DNS server for the network zones
+		dnsAddress, err := node.DNSAddress(d.db)
+		if err != nil {
+			return errors.Wrap(err, "Failed to fetch DNS address")
+		}
+
+		d.dns = lxdDNS.NewServer(func(name string) (*lxdDNS.Zone, error) {
+			return network.ZoneContent(d.State(), name, func(networkName string, projectName string) ([]api.NetworkLease, error) {
+				return networkLeases(d, networkName, projectName, false)
+			})
+		})
+
+		if dnsAddress != "" {
+			err = d.dns.Start(dnsAddress)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Started DNS server", log.Ctx{"address": dnsAddress})
+		}
+
+		// Setup the BGP server, the prefixes of the networks started above are announced once it's up.
+		err = d.setupBGPServer()
+		if err != nil {
+			return err
+		}
+
 		// Read the trusted certificates
 		readSavedClientCAList(d)
 
@@ -1054,6 +1202,12 @@ func (d *Daemon) startClusterTasks() {
 	// Auto-sync images across the cluster (daily)
 	d.clusterTasks.Add(autoSyncImagesTask(d))
 
+	// Restart the instances of offline members elsewhere
+	d.clusterTasks.Add(clusterHealingTask(d))
+
+	// Stop local instances when cut off from the cluster
+	d.clusterTasks.Add(clusterHealingFenceTask(d))
+
 	// Start all background tasks
 	d.clusterTasks.Start()
 }
@@ -1087,6 +1241,9 @@ func (d *Daemon) Ready() error {
 		// Remove expired images (daily)
 		d.taskPruneImages = d.tasks.Add(pruneExpiredImagesTask(d))
 
+		// Remove expired image deltas (hourly)
+		d.tasks.Add(pruneExpiredImageDeltasTask(d))
+
 		// Auto-update images (every 6 hours, configurable)
 		d.taskAutoUpdate = d.tasks.Add(autoUpdateImagesTask(d))
 
@@ -1096,6 +1253,9 @@ func (d *Daemon) Ready() error {
 		// Remove expired container backups (hourly)
 		d.tasks.Add(pruneExpiredContainerBackupsTask(d))
 
+		// Take backup of instances (minutely check of configurable cron expression)
+		d.tasks.Add(autoCreateInstanceBackupsTask(d))
+
 		// Take snapshot of containers (minutely check of configurable cron expression)
 		d.tasks.Add(autoCreateContainerSnapshotsTask(d))
 
@@ -1107,8 +1267,20 @@ func (d *Daemon) Ready() error {
 
 		// Take snapshot of custom volumes (minutely check of configurable cron expression)
 		d.tasks.Add(autoCreateCustomVolumeSnapshotsTask(d))
+
+		// Scrub storage pools (minutely check of configurable cron expression)
+		d.tasks.Add(autoScrubStoragePoolsTask(d))
+
+		// Trim thin-provisioned storage pools (minutely check of configurable cron expression)
+		d.tasks.Add(autoTrimStoragePoolsTask(d))
+
+		// Health check network load balancer backends (every 10s)
+		d.tasks.Add(networkLoadBalancerHealthCheckTask(d))
 	}
 
+	// Write the queued last used dates (every 10s)
+	d.tasks.Add(flushWriteBehindTask(d))
+
 	// Start all background tasks
 	d.tasks.Start()
 
@@ -1198,6 +1370,16 @@ func (d *Daemon) Stop() error {
 			shouldUnmount = true
 		}
 
+		// Write the queued last used dates, unless the database can't be reached.
+		go func() {
+			ch <- d.cluster.FlushWriteBehind() == nil
+		}()
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			logger.Warnf("Give up writing queued last used dates")
+		}
+
 		logger.Infof("Closing the database")
 		err := d.cluster.Close()
 		// If we got io.EOF the network connection was interrupted and
@@ -1217,6 +1399,14 @@ func (d *Daemon) Stop() error {
 		trackError(d.gateway.Shutdown(), "Shutdown dqlite")
 	}
 
+	if d.dns != nil {
+		trackError(d.dns.Stop(), "Stop DNS server")
+	}
+
+	if d.bgp != nil {
+		trackError(d.bgp.Stop(), "Stop BGP server")
+	}
+
 	if d.endpoints != nil {
 		trackError(d.endpoints.Down(), "Shutdown endpoints")
 	}
@@ -1335,6 +1525,73 @@ func (d *Daemon) setupExternalAuthentication(authEndpoint string, authPubkey str
 }
 
 // Setup RBAC
+// setupBGPServer (re)configures the BGP server from the server configuration.
+func (d *Daemon) setupBGPServer() error {
+	var address, routerID, peersStr string
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		config, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		address = config.BGPAddress()
+		routerID = config.BGPRouterID()
+		peersStr = config.BGPPeers()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load BGP configuration")
+	}
+
+	var asn int64
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		asn = config.BGPASN()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load BGP configuration")
+	}
+
+	peers, err := bgp.ParsePeers(peersStr)
+	if err != nil {
+		return err
+	}
+
+	// Default to the listen address as router ID if it's a specific IPv4 address.
+	var routerIP net.IP
+	if routerID != "" {
+		routerIP = net.ParseIP(routerID)
+	} else if address != "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+
+		ip := net.ParseIP(strings.Trim(host, "[]"))
+		if ip != nil && ip.To4() != nil && !ip.IsUnspecified() {
+			routerIP = ip
+		} else if asn != 0 {
+			return fmt.Errorf("%q must be set when %q isn't a specific IPv4 address", "core.bgp_routerid", "core.bgp_address")
+		}
+	}
+
+	err = d.bgp.Configure(address, uint32(asn), routerIP, peers)
+	if err != nil {
+		return err
+	}
+
+	if address != "" && asn != 0 {
+		logger.Info("Started BGP server", log.Ctx{"address": address, "asn": asn, "routerID": routerIP, "peers": len(peers)})
+	}
+
+	return nil
+}
+
 func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int64, rbacAgentURL string, rbacAgentUsername string, rbacAgentPrivateKey string, rbacAgentPublicKey string) error {
 	if d.rbac != nil || rbacURL == "" || rbacAgentURL == "" || rbacAgentUsername == "" || rbacAgentPrivateKey == "" || rbacAgentPublicKey == "" {
 		return nil
@@ -1385,15 +1642,22 @@ func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int6
 }
 
 func (d *Daemon) userIsAdmin(r *http.Request) bool {
-	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
-		return true
+	// Local clients are admin unless they were only granted access through a restricted socket group.
+	if r.RemoteAddr == "@" {
+		return r.Context().Value("protocol") != "unix-group"
 	}
 
 	if r.Context().Value("protocol") == "cluster" {
 		return true
 	}
 
+	// TLS clients are admin unless their certificate is restricted to some projects.
 	if r.Context().Value("protocol") == "tls" {
+		_, restricted := d.clientRoles[r.Context().Value("username").(string)]
+		return !restricted
+	}
+
+	if d.externalAuth == nil || d.rbac == nil {
 		return true
 	}
 
@@ -1401,15 +1665,34 @@ func (d *Daemon) userIsAdmin(r *http.Request) bool {
 }
 
 func (d *Daemon) userHasPermission(r *http.Request, project string, permission string) bool {
-	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
-		return true
+	if r.RemoteAddr == "@" {
+		if r.Context().Value("protocol") != "unix-group" {
+			return true
+		}
+
+		roles, err := d.socketUserRoles(r)
+		if err != nil {
+			return false
+		}
+
+		return roles == nil || shared.StringInSlice(permission, rbac.ProjectRoles[roles[project]])
 	}
 
 	if r.Context().Value("protocol") == "cluster" {
 		return true
 	}
 
+	// Restricted TLS clients get the permissions of their role in the project, if any.
 	if r.Context().Value("protocol") == "tls" {
+		roles, restricted := d.clientRoles[r.Context().Value("username").(string)]
+		if !restricted {
+			return true
+		}
+
+		return shared.StringInSlice(permission, rbac.ProjectRoles[roles[project]])
+	}
+
+	if d.externalAuth == nil || d.rbac == nil {
 		return true
 	}
 
@@ -1516,6 +1799,33 @@ func (d *Daemon) hasNodeListChanged(heartbeatData *cluster.APIHeartbeat) bool {
 	return false
 }
 
+// ServerName returns the cached name of the local member.
+func (d *Daemon) ServerName() string {
+	d.serverNameLock.RLock()
+	defer d.serverNameLock.RUnlock()
+
+	return d.serverName
+}
+
+// refreshServerName reloads the name of the local member from the database.
+func (d *Daemon) refreshServerName() error {
+	var serverName string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		serverName, err = tx.GetLocalNodeName()
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get the name of the local member")
+	}
+
+	d.serverNameLock.Lock()
+	d.serverName = serverName
+	d.serverNameLock.Unlock()
+
+	return nil
+}
+
 // NodeRefreshTask is run each time a fresh node is generated.
 // This can be used to trigger actions when the node list changes.
 func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
@@ -1524,6 +1834,12 @@ func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
 		return
 	}
 
+	// Pick up renames of the local member done through other members.
+	err := d.refreshServerName()
+	if err != nil {
+		logger.Error("Failed to refresh the name of the local member", log.Ctx{"err": err})
+	}
+
 	// If the max version of the cluster has changed, check whether we need to upgrade.
 	if d.lastNodeList == nil || d.lastNodeList.Version.APIExtensions != heartbeatData.Version.APIExtensions || d.lastNodeList.Version.Schema != heartbeatData.Version.Schema {
 		err := cluster.MaybeUpdate(d.State())
@@ -1567,6 +1883,11 @@ func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
 				return
 			}
 		}
+
+		err := networkUpdateWireguardPeersTask(d.State(), heartbeatData)
+		if err != nil {
+			logger.Errorf("Error refreshing Wireguard peers: %v", err)
+		}
 	}
 
 	// Only update the node list if the task succeeded.
@@ -1612,3 +1933,16 @@ func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
 		}
 	}
 }
+
+// flushWriteBehindTask periodically writes the non-critical values, like last used dates, which are queued to
+// reduce the write load on the cluster database.
+func flushWriteBehindTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := d.cluster.FlushWriteBehind()
+		if err != nil {
+			logger.Warn("Failed to write queued database updates", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
@@ -4,9 +4,9 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,7 +20,7 @@ import (
 	"github.com/lxc/lxd/shared/cancel"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/logger"
-	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/version"
 
 	log "github.com/lxc/lxd/shared/log15"
@@ -75,6 +75,16 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			entry, _, err := remote.GetImageAliasType(imageType, fp)
 			if err == nil {
 				fp = entry.Target
+
+				// Pick the image matching the architecture of this server, if any.
+				for _, architecture := range d.os.Architectures {
+					architectureName, _ := osarch.ArchitectureName(architecture)
+					fingerprint, ok := entry.Architectures[architectureName]
+					if ok {
+						fp = fingerprint
+						break
+					}
+				}
 			}
 
 			// Expand partial fingerprints
@@ -246,6 +256,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		}
 
 		if meta["download_progress"] != progress.Text {
+			meta["progress"] = shared.ProgressMetadata("download", int64(progress.Percentage), progress.TransferredBytes, progress.TotalBytes, progress.Speed)
 			meta["download_progress"] = progress.Text
 			op.UpdateMetadata(meta)
 		}
@@ -312,6 +323,10 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			},
 		}
 
+		if protocol == "lxd" {
+			request.DeltaSources = d.imageDeltaSources(project, info)
+		}
+
 		if secret != "" {
 			resp, err = remote.GetPrivateImageFile(fp, secret, request)
 		} else {
@@ -348,35 +363,6 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			return nil, err
 		}
 
-		req, err := http.NewRequest("GET", server, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		req.Header.Set("User-Agent", version.UserAgent)
-
-		// Make the request
-		raw, doneCh, err := cancel.CancelableDownload(canceler, httpClient, req)
-		defer close(doneCh)
-		if err != nil {
-			return nil, err
-		}
-
-		if raw.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("Unable to fetch %s: %s", server, raw.Status)
-		}
-
-		// Progress handler
-		body := &ioprogress.ProgressReader{
-			ReadCloser: raw.Body,
-			Tracker: &ioprogress.ProgressTracker{
-				Length: raw.ContentLength,
-				Handler: func(percent int64, speed int64) {
-					progress(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
-				},
-			},
-		}
-
 		// Create the target files
 		f, err := os.Create(destName)
 		if err != nil {
@@ -384,21 +370,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		}
 		defer f.Close()
 
-		// Hashing
-		sha256 := sha256.New()
-
-		// Download the image
-		size, err := io.Copy(io.MultiWriter(f, sha256), body)
+		// Download and validate the image
+		size, err := shared.DownloadFileHash(httpClient, version.UserAgent, progress, canceler, "", server, fp, sha256.New(), f)
 		if err != nil {
 			return nil, err
 		}
 
-		// Validate hash
-		result := fmt.Sprintf("%x", sha256.Sum(nil))
-		if result != fp {
-			return nil, fmt.Errorf("Hash mismatch for %s: %s != %s", server, result, fp)
-		}
-
 		// Parse the image
 		imageMeta, imageType, err := getImageMetadata(destName)
 		if err != nil {
@@ -484,3 +461,55 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	logger.Info("Image downloaded", ctxMap)
 	return info, nil
 }
+
+// imageDeltaSources returns the fingerprints of the local images which most likely share the bulk of their root
+// filesystem with the given image, newest first. Those are offered to the source server as a base for a delta.
+func (d *Daemon) imageDeltaSources(project string, info *api.Image) []string {
+	fingerprints, err := d.cluster.GetImagesFingerprints(project, false)
+	if err != nil {
+		return nil
+	}
+
+	candidates := []*api.Image{}
+	for _, fingerprint := range fingerprints {
+		if fingerprint == info.Fingerprint || !shared.PathExists(shared.VarPath("images", fingerprint+".rootfs")) {
+			continue
+		}
+
+		_, image, err := d.cluster.GetImage(project, fingerprint, false)
+		if err != nil {
+			continue
+		}
+
+		if image.Type != info.Type || image.Architecture != info.Architecture {
+			continue
+		}
+
+		// Images of a different distribution or release have little in common.
+		if image.Properties["os"] == "" || image.Properties["release"] == "" {
+			continue
+		}
+
+		if image.Properties["os"] != info.Properties["os"] || image.Properties["release"] != info.Properties["release"] || image.Properties["variant"] != info.Properties["variant"] {
+			continue
+		}
+
+		candidates = append(candidates, image)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].CreatedAt.After(candidates[j].CreatedAt)
+	})
+
+	sources := []string{}
+	for i, image := range candidates {
+		// The source server only considers the most recent images.
+		if i == imageDeltaMaxCandidates {
+			break
+		}
+
+		sources = append(sources, image.Fingerprint)
+	}
+
+	return sources
+}
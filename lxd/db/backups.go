@@ -22,6 +22,9 @@ type InstanceBackup struct {
 	InstanceOnly         bool
 	OptimizedStorage     bool
 	CompressionAlgorithm string
+	DeltaFrom            string
+	Target               string
+	BandwidthLimit       string
 }
 
 // Returns the ID of the instance backup with the given name.
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Time to wait for more writes to join a batch before committing it, and the
+// maximum number of writes committed in a single batch.
+const (
+	writeBatchWindow = 20 * time.Millisecond
+	writeBatchMax    = 64
+)
+
+// writeBatch groups small independent writes issued concurrently, such as the
+// power state updates of an instance start or stop storm, into a single
+// transaction. This reduces the write contention on the cluster database while
+// keeping the writes synchronous for the callers.
+type writeBatch struct {
+	mu      sync.Mutex
+	pending []*batchedWrite
+}
+
+type batchedWrite struct {
+	f    func(*ClusterTx) error
+	done chan error
+}
+
+// batchTransaction runs f as part of a batch of writes and returns its error.
+func (c *Cluster) batchTransaction(f func(*ClusterTx) error) error {
+	write := &batchedWrite{f: f, done: make(chan error, 1)}
+
+	c.batch.mu.Lock()
+	c.batch.pending = append(c.batch.pending, write)
+	switch len(c.batch.pending) {
+	case 1:
+		time.AfterFunc(writeBatchWindow, c.commitBatch)
+	case writeBatchMax:
+		go c.commitBatch()
+	}
+	c.batch.mu.Unlock()
+
+	return <-write.done
+}
+
+// commitBatch commits the pending writes in a single transaction. If it fails,
+// each write is retried in its own transaction so that a single failing write
+// doesn't fail the others.
+func (c *Cluster) commitBatch() {
+	c.batch.mu.Lock()
+	writes := c.batch.pending
+	c.batch.pending = nil
+	c.batch.mu.Unlock()
+
+	if len(writes) == 0 {
+		return
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		for _, write := range writes {
+			err := write.f(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == nil || len(writes) == 1 {
+		for _, write := range writes {
+			write.done <- err
+		}
+
+		return
+	}
+
+	for _, write := range writes {
+		write.done <- c.Transaction(write.f)
+	}
+}
+
+// writeBehind holds non-critical values, like last used dates, until they're
+// flushed to the database. Only the most recent value of each row is kept.
+type writeBehind struct {
+	mu        sync.Mutex
+	instances map[int]time.Time
+	images    map[string]time.Time
+}
+
+// QueueInstanceLastUsedDate records the last used date of the instance with
+// the given ID, to be written by the next FlushWriteBehind call.
+func (c *Cluster) QueueInstanceLastUsedDate(id int, date time.Time) {
+	c.behind.mu.Lock()
+	defer c.behind.mu.Unlock()
+
+	if c.behind.instances == nil {
+		c.behind.instances = map[int]time.Time{}
+	}
+
+	c.behind.instances[id] = date
+}
+
+// QueueImageLastUseDate records the last use date of the image with the given
+// fingerprint, to be written by the next FlushWriteBehind call.
+func (c *Cluster) QueueImageLastUseDate(fingerprint string, date time.Time) {
+	c.behind.mu.Lock()
+	defer c.behind.mu.Unlock()
+
+	if c.behind.images == nil {
+		c.behind.images = map[string]time.Time{}
+	}
+
+	c.behind.images[fingerprint] = date
+}
+
+// FlushWriteBehind writes the queued non-critical values in a single
+// transaction. They're queued again if it fails, unless newer values have
+// been queued in the meantime.
+func (c *Cluster) FlushWriteBehind() error {
+	c.behind.mu.Lock()
+	instances := c.behind.instances
+	images := c.behind.images
+	c.behind.instances = nil
+	c.behind.images = nil
+	c.behind.mu.Unlock()
+
+	if len(instances) == 0 && len(images) == 0 {
+		return nil
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		for id, date := range instances {
+			err := tx.UpdateInstanceLastUsedDate(id, date)
+			if err != nil {
+				return err
+			}
+		}
+
+		for fingerprint, date := range images {
+			_, err := tx.tx.Exec("UPDATE images SET last_use_date=? WHERE fingerprint=?", date, fingerprint)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		c.behind.mu.Lock()
+		defer c.behind.mu.Unlock()
+
+		if c.behind.instances == nil {
+			c.behind.instances = map[int]time.Time{}
+		}
+
+		for id, date := range instances {
+			_, ok := c.behind.instances[id]
+			if !ok {
+				c.behind.instances[id] = date
+			}
+		}
+
+		if c.behind.images == nil {
+			c.behind.images = map[string]time.Time{}
+		}
+
+		for fingerprint, date := range images {
+			_, ok := c.behind.images[fingerprint]
+			if !ok {
+				c.behind.images[fingerprint] = date
+			}
+		}
+
+		return errors.Wrap(err, "Failed to flush last used dates")
+	}
+
+	return nil
+}
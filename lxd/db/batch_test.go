@@ -0,0 +1,92 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// Concurrent power state updates are all applied.
+func TestUpdateInstancePowerState_Concurrent(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		for i := 1; i <= 10; i++ {
+			_, err := tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (?, 1, ?, 1, 0, 1)
+`, i, fmt.Sprintf("c%d", i))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			assert.NoError(t, cluster.UpdateInstancePowerState(id, "RUNNING"))
+		}(i)
+	}
+	wg.Wait()
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		var count int
+		err := tx.Tx().QueryRow(`
+SELECT count(*) FROM instances_config WHERE key='volatile.last_state.power' AND value='RUNNING'
+`).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, 10, count)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// Queued last used dates are written when flushed, keeping the most recent one.
+func TestFlushWriteBehind(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'c1', 1, 0, 1)
+`)
+		return err
+	})
+	require.NoError(t, err)
+
+	lastUsed := func() time.Time {
+		var date time.Time
+		err := cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.Tx().QueryRow("SELECT last_use_date FROM instances WHERE id=1").Scan(&date)
+		})
+		require.NoError(t, err)
+		return date
+	}
+
+	date := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	cluster.QueueInstanceLastUsedDate(1, date.Add(-time.Hour))
+	cluster.QueueInstanceLastUsedDate(1, date)
+
+	require.NoError(t, cluster.FlushWriteBehind())
+	assert.Equal(t, date, lastUsed().UTC())
+
+	// Nothing left to flush.
+	require.NoError(t, cluster.FlushWriteBehind())
+}
@@ -22,6 +22,30 @@ package db
 //go:generate mapper method -p db -e certificate Delete
 //go:generate mapper method -p db -e certificate Rename
 
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Certificate types.
+const (
+	CertificateTypeClient  = 1
+	CertificateTypeMetrics = 2
+)
+
+// CertificateAPITypeToDBType converts an API certificate type to its database value.
+func CertificateAPITypeToDBType(certType string) (int, error) {
+	switch certType {
+	case "client":
+		return CertificateTypeClient, nil
+	case "metrics":
+		return CertificateTypeMetrics, nil
+	}
+
+	return -1, fmt.Errorf("Unknown certificate type %q", certType)
+}
+
 // Certificate is here to pass the certificates content
 // from the database around
 type Certificate struct {
@@ -30,6 +54,19 @@ type Certificate struct {
 	Type        int
 	Name        string
 	Certificate string
+	Restricted  bool
+}
+
+// ToAPIType returns the API equivalent of the certificate type.
+func (cert Certificate) ToAPIType() string {
+	switch cert.Type {
+	case CertificateTypeClient:
+		return "client"
+	case CertificateTypeMetrics:
+		return "metrics"
+	}
+
+	return "unknown"
 }
 
 // CertificateFilter can be used to filter results yielded by GetCertInfos
@@ -75,3 +112,122 @@ func (c *Cluster) RenameCertificate(fingerprint string, name string) error {
 	})
 	return err
 }
+
+// UpdateCertificateRestricted sets whether the certificate with the given
+// fingerprint is restricted to its projects.
+func (c *ClusterTx) UpdateCertificateRestricted(fingerprint string, restricted bool) error {
+	result, err := c.tx.Exec("UPDATE certificates SET restricted=? WHERE fingerprint=?", restricted, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// UpdateCertificateType sets the type of the certificate with the given
+// fingerprint.
+func (c *ClusterTx) UpdateCertificateType(fingerprint string, certType int) error {
+	result, err := c.tx.Exec("UPDATE certificates SET type=? WHERE fingerprint=?", certType, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// GetCertificatesProjects returns the project roles of all the certificates
+// which are restricted to some projects, indexed by fingerprint and then by
+// project name.
+func (c *ClusterTx) GetCertificatesProjects() (map[string]map[string]string, error) {
+	stmt := `
+SELECT certificates.fingerprint, projects.name, certificates_projects.role
+  FROM certificates_projects
+  JOIN certificates ON certificates.id = certificates_projects.certificate_id
+  JOIN projects ON projects.id = certificates_projects.project_id
+`
+	rows, err := c.tx.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string]map[string]string{}
+	for rows.Next() {
+		var fingerprint, project, role string
+		err := rows.Scan(&fingerprint, &project, &role)
+		if err != nil {
+			return nil, err
+		}
+
+		if result[fingerprint] == nil {
+			result[fingerprint] = map[string]string{}
+		}
+
+		result[fingerprint][project] = role
+	}
+
+	return result, rows.Err()
+}
+
+// GetCertificateProjects returns the roles of the certificate with the given
+// fingerprint, indexed by project name. An empty map means the certificate
+// isn't restricted to any project.
+func (c *ClusterTx) GetCertificateProjects(fingerprint string) (map[string]string, error) {
+	projects, err := c.GetCertificatesProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	result := projects[fingerprint]
+	if result == nil {
+		result = map[string]string{}
+	}
+
+	return result, nil
+}
+
+// UpdateCertificateProjects replaces the project roles of the certificate with
+// the given fingerprint.
+func (c *ClusterTx) UpdateCertificateProjects(fingerprint string, projects map[string]string) error {
+	id, err := c.GetCertificateID(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM certificates_projects WHERE certificate_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for project, role := range projects {
+		projectID, err := c.GetProjectID(project)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get ID of project %q", project)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO certificates_projects (certificate_id, project_id, role) VALUES (?, ?, ?)", id, projectID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
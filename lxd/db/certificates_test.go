@@ -22,3 +22,88 @@ func TestGetCertificate(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, cert.Fingerprint, "foobar")
 }
+
+func TestUpdateCertificateProjects(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateCertificate(db.Certificate{Fingerprint: "foobar"})
+	require.NoError(t, err)
+
+	projects, err := tx.GetCertificateProjects("foobar")
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+
+	err = tx.UpdateCertificateProjects("foobar", map[string]string{"default": "viewer"})
+	require.NoError(t, err)
+
+	projects, err = tx.GetCertificateProjects("foobar")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"default": "viewer"}, projects)
+
+	all, err := tx.GetCertificatesProjects()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"foobar": {"default": "viewer"}}, all)
+
+	err = tx.UpdateCertificateProjects("foobar", nil)
+	require.NoError(t, err)
+
+	projects, err = tx.GetCertificateProjects("foobar")
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+
+	err = tx.UpdateCertificateProjects("foobar", map[string]string{"missing": "viewer"})
+	assert.Error(t, err)
+}
+
+func TestUpdateCertificateRestricted(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateCertificate(db.Certificate{Fingerprint: "foobar", Restricted: true})
+	require.NoError(t, err)
+
+	cert, err := tx.GetCertificate("foobar")
+	require.NoError(t, err)
+	assert.True(t, cert.Restricted)
+
+	err = tx.UpdateCertificateRestricted("foobar", false)
+	require.NoError(t, err)
+
+	cert, err = tx.GetCertificate("foobar")
+	require.NoError(t, err)
+	assert.False(t, cert.Restricted)
+
+	err = tx.UpdateCertificateRestricted("missing", true)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+func TestUpdateCertificateType(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateCertificate(db.Certificate{Fingerprint: "foobar", Type: db.CertificateTypeClient})
+	require.NoError(t, err)
+
+	err = tx.UpdateCertificateType("foobar", db.CertificateTypeMetrics)
+	require.NoError(t, err)
+
+	cert, err := tx.GetCertificate("foobar")
+	require.NoError(t, err)
+	assert.Equal(t, db.CertificateTypeMetrics, cert.Type)
+
+	err = tx.UpdateCertificateType("missing", db.CertificateTypeClient)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+func TestCertificateAPITypeToDBType(t *testing.T) {
+	for _, certType := range []string{"client", "metrics"} {
+		dbType, err := db.CertificateAPITypeToDBType(certType)
+		require.NoError(t, err)
+		assert.Equal(t, certType, db.Certificate{Type: dbType}.ToAPIType())
+	}
+
+	_, err := db.CertificateAPITypeToDBType("server")
+	assert.Error(t, err)
+	assert.Equal(t, "unknown", db.Certificate{Type: 3}.ToAPIType())
+}
@@ -70,6 +70,465 @@ var updates = map[int]schema.Update{
 	31: updateFromV30,
 	32: updateFromV31,
 	33: updateFromV32,
+	34: updateFromV33,
+	35: updateFromV34,
+	36: updateFromV35,
+	37: updateFromV36,
+	38: updateFromV37,
+	39: updateFromV38,
+	40: updateFromV39,
+	41: updateFromV40,
+	42: updateFromV41,
+	43: updateFromV42,
+	44: updateFromV43,
+	45: updateFromV44,
+	46: updateFromV45,
+	47: updateFromV46,
+	48: updateFromV47,
+	49: updateFromV48,
+	50: updateFromV49,
+	51: updateFromV50,
+	52: updateFromV51,
+}
+
+// Make network names unique per project rather than globally. As SQLite can't drop constraints, the table is
+// recreated, saving and restoring the rows of the tables referencing it as they're deleted in cascade.
+func updateFromV51(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE new_networks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    project_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    state INTEGER NOT NULL DEFAULT 0,
+    type INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (project_id, name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+INSERT INTO new_networks (id, project_id, name, description, state, type)
+    SELECT id, project_id, name, description, state, type FROM networks;
+
+CREATE TABLE networks_config_copy AS SELECT * FROM networks_config;
+CREATE TABLE networks_nodes_copy AS SELECT * FROM networks_nodes;
+CREATE TABLE networks_forwards_copy AS SELECT * FROM networks_forwards;
+CREATE TABLE networks_forwards_config_copy AS SELECT * FROM networks_forwards_config;
+CREATE TABLE networks_load_balancers_copy AS SELECT * FROM networks_load_balancers;
+CREATE TABLE networks_load_balancers_config_copy AS SELECT * FROM networks_load_balancers_config;
+CREATE TABLE networks_leases_copy AS SELECT * FROM networks_leases;
+CREATE TABLE networks_wireguard_peers_copy AS SELECT * FROM networks_wireguard_peers;
+
+DROP TABLE networks;
+ALTER TABLE new_networks RENAME TO networks;
+
+INSERT INTO networks_config SELECT * FROM networks_config_copy;
+INSERT INTO networks_nodes SELECT * FROM networks_nodes_copy;
+INSERT INTO networks_forwards SELECT * FROM networks_forwards_copy;
+INSERT INTO networks_forwards_config SELECT * FROM networks_forwards_config_copy;
+INSERT INTO networks_load_balancers SELECT * FROM networks_load_balancers_copy;
+INSERT INTO networks_load_balancers_config SELECT * FROM networks_load_balancers_config_copy;
+INSERT INTO networks_leases SELECT * FROM networks_leases_copy;
+INSERT INTO networks_wireguard_peers SELECT * FROM networks_wireguard_peers_copy;
+
+DROP TABLE networks_config_copy;
+DROP TABLE networks_nodes_copy;
+DROP TABLE networks_forwards_copy;
+DROP TABLE networks_forwards_config_copy;
+DROP TABLE networks_load_balancers_copy;
+DROP TABLE networks_load_balancers_config_copy;
+DROP TABLE networks_leases_copy;
+DROP TABLE networks_wireguard_peers_copy;
+`
+	_, err := tx.Exec(stmt)
+	if err != nil {
+		return errors.Wrap(err, "Failed to make network names unique per project")
+	}
+
+	return nil
+}
+
+// Add the uid/gid ranges allocated to isolated containers.
+func updateFromV50(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE instances_idmaps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    instance_id INTEGER NOT NULL,
+    hostid INTEGER NOT NULL,
+    maprange INTEGER NOT NULL,
+    UNIQUE (instance_id),
+    FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmt)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add instances_idmaps table")
+	}
+
+	return nil
+}
+
+// Add parent profiles, whose config and devices profiles inherit.
+func updateFromV49(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE profiles_parents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    profile_id INTEGER NOT NULL,
+    parent_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    UNIQUE (profile_id, parent_id),
+    FOREIGN KEY (profile_id) REFERENCES profiles (id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES profiles (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmt)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add profiles_parents table")
+	}
+
+	return nil
+}
+
+// Add per-architecture targets to image aliases.
+func updateFromV48(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE images_aliases_architectures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    image_alias_id INTEGER NOT NULL,
+    image_id INTEGER NOT NULL,
+    UNIQUE (image_alias_id, image_id),
+    FOREIGN KEY (image_alias_id) REFERENCES images_aliases (id) ON DELETE CASCADE,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmt)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add images_aliases_architectures table")
+	}
+
+	return nil
+}
+
+// Add base image references for layered images.
+func updateFromV47(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE images ADD COLUMN base_image TEXT NOT NULL DEFAULT '';")
+	if err != nil {
+		return errors.Wrap(err, "Failed to add base_image column to images")
+	}
+
+	return nil
+}
+
+// Add image pinning and per-alias update sources.
+func updateFromV46(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE images ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE images_aliases_source (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    image_alias_id INTEGER NOT NULL,
+    server TEXT NOT NULL,
+    protocol INTEGER NOT NULL,
+    certificate TEXT NOT NULL,
+    alias TEXT NOT NULL,
+    auto_update INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (image_alias_id),
+    FOREIGN KEY (image_alias_id) REFERENCES images_aliases (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add image pinning and alias sources")
+	}
+
+	return nil
+}
+
+// Add warnings.
+func updateFromV45(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE warnings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid TEXT NOT NULL,
+    node_id INTEGER,
+    project_id INTEGER,
+    module TEXT NOT NULL,
+    type TEXT NOT NULL,
+    entity_url TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    status TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    first_seen_date DATETIME NOT NULL,
+    last_seen_date DATETIME NOT NULL,
+    last_message TEXT NOT NULL,
+    UNIQUE (uuid),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create warnings table")
+	}
+
+	return nil
+}
+
+// Add restricted flag to certificates. Certificates which already have project roles are restricted.
+func updateFromV44(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE certificates ADD COLUMN restricted INTEGER NOT NULL DEFAULT 0;
+UPDATE certificates SET restricted = 1 WHERE id IN (SELECT certificate_id FROM certificates_projects);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add restricted column to certificates")
+	}
+
+	return nil
+}
+
+// Add project roles of client certificates.
+func updateFromV43(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE certificates_projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    certificate_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    UNIQUE (certificate_id, project_id),
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create certificates projects table")
+	}
+
+	return nil
+}
+
+// Add user configuration of cluster members.
+func updateFromV42(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE nodes_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    node_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (node_id, key),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create cluster members configuration table")
+	}
+
+	return nil
+}
+
+// Add state column to nodes table, used to track evacuated members.
+func updateFromV41(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE nodes ADD COLUMN state INTEGER NOT NULL DEFAULT 0;")
+	return err
+}
+
+// Add cluster groups.
+func updateFromV40(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE cluster_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    UNIQUE (name)
+);
+CREATE TABLE nodes_cluster_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    node_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    UNIQUE (node_id, group_id),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES cluster_groups (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create cluster groups tables")
+	}
+
+	return nil
+}
+
+// Add project ownership of networks, networks created before belong to the default project.
+func updateFromV39(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE networks ADD COLUMN project_id INTEGER NOT NULL DEFAULT 1;")
+	if err != nil {
+		return errors.Wrap(err, "Failed to add project_id column to networks table")
+	}
+
+	return nil
+}
+
+// Add Wireguard mesh peers of bridge networks.
+func updateFromV38(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_wireguard_peers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,
+    UNIQUE (network_id, node_id),
+    FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE,
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network Wireguard peers table")
+	}
+
+	return nil
+}
+
+// Add network lease reservations.
+func updateFromV37(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_leases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    hostname TEXT NOT NULL,
+    hwaddr TEXT NOT NULL,
+    address TEXT NOT NULL,
+    UNIQUE (network_id, address),
+    FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network lease reservations table")
+	}
+
+	return nil
+}
+
+// Add network zones.
+func updateFromV36(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_zones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    project_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    UNIQUE (name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE TABLE networks_zones_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_zone_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (network_zone_id, key),
+    FOREIGN KEY (network_zone_id) REFERENCES networks_zones (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network zone tables")
+	}
+
+	return nil
+}
+
+// Add network load balancers.
+func updateFromV35(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_load_balancers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL,
+    listen_address TEXT NOT NULL,
+    description TEXT NOT NULL,
+    backends TEXT NOT NULL,
+    ports TEXT NOT NULL,
+    UNIQUE (network_id, node_id, listen_address),
+    FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE,
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE
+);
+CREATE TABLE networks_load_balancers_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_load_balancer_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (network_load_balancer_id, key),
+    FOREIGN KEY (network_load_balancer_id) REFERENCES networks_load_balancers (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network load balancer tables")
+	}
+
+	return nil
+}
+
+// Add network forwards.
+func updateFromV34(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_forwards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL,
+    listen_address TEXT NOT NULL,
+    description TEXT NOT NULL,
+    ports TEXT NOT NULL,
+    UNIQUE (network_id, node_id, listen_address),
+    FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE,
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE
+);
+CREATE TABLE networks_forwards_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_forward_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (network_forward_id, key),
+    FOREIGN KEY (network_forward_id) REFERENCES networks_forwards (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network forward tables")
+	}
+
+	return nil
+}
+
+// Add network ACLs.
+func updateFromV33(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE network_acls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    ingress TEXT NOT NULL,
+    egress TEXT NOT NULL,
+    UNIQUE (name)
+);
+CREATE TABLE network_acls_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_acl_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (network_acl_id, key),
+    FOREIGN KEY (network_acl_id) REFERENCES network_acls (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network ACL tables")
+	}
+
+	return nil
 }
 
 // Add type field to networks.
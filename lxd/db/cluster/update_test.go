@@ -717,3 +717,35 @@ func TestUpdateFromV26_WithVolumes(t *testing.T) {
 
 	assert.Equal(t, ids[0], 2)
 }
+
+func TestUpdateFromV51(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(52, func(db *sql.DB) {
+		_, err := db.Exec("INSERT INTO projects (id, name, description) VALUES (2, 'p1', '')")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO networks (id, name, description, state, type, project_id) VALUES (1, 'foo', '', 1, 1, 1)")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO networks_config (network_id, node_id, key, value) VALUES (1, NULL, 'bridge.mtu', '1400')")
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The config of the network survived the recreation of the table.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	config, err := query.SelectConfig(tx, "networks_config", "network_id=1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"bridge.mtu": "1400"}, config)
+	require.NoError(t, tx.Rollback())
+
+	// Network names are only unique within a project.
+	_, err = db.Exec("INSERT INTO networks (project_id, name, description, state, type) VALUES (2, 'foo', '', 1, 1)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks (project_id, name, description, state, type) VALUES (1, 'foo', '', 1, 1)")
+	require.Error(t, err)
+}
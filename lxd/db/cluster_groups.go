@@ -0,0 +1,136 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetClusterGroupNames returns the names of all cluster groups.
+func (c *ClusterTx) GetClusterGroupNames() ([]string, error) {
+	return query.SelectStrings(c.tx, "SELECT name FROM cluster_groups ORDER BY name")
+}
+
+// GetClusterGroup returns the cluster group with the given name along with its ID.
+func (c *ClusterTx) GetClusterGroup(name string) (int64, *api.ClusterGroup, error) {
+	group := api.ClusterGroup{
+		Name: name,
+	}
+
+	var id int64
+	err := c.tx.QueryRow("SELECT id, description FROM cluster_groups WHERE name=?", name).Scan(&id, &group.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	group.Members, err = c.GetClusterGroupNodes(name)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &group, nil
+}
+
+// GetClusterGroupNodes returns the names of the nodes which are members of the cluster group with the given name.
+func (c *ClusterTx) GetClusterGroupNodes(name string) ([]string, error) {
+	stmt := `
+SELECT nodes.name FROM nodes
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.node_id = nodes.id
+  JOIN cluster_groups ON cluster_groups.id = nodes_cluster_groups.group_id
+WHERE cluster_groups.name = ?
+ORDER BY nodes.name
+`
+	return query.SelectStrings(c.tx, stmt, name)
+}
+
+// GetNodeClusterGroups returns the names of the cluster groups the node with the given ID is a member of.
+func (c *ClusterTx) GetNodeClusterGroups(nodeID int64) ([]string, error) {
+	stmt := `
+SELECT cluster_groups.name FROM cluster_groups
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.group_id = cluster_groups.id
+WHERE nodes_cluster_groups.node_id = ?
+ORDER BY cluster_groups.name
+`
+	return query.SelectStrings(c.tx, stmt, nodeID)
+}
+
+// CreateClusterGroup creates a new cluster group with the given members.
+func (c *ClusterTx) CreateClusterGroup(info api.ClusterGroupsPost) (int64, error) {
+	count, err := query.Count(c.tx, "cluster_groups", "name=?", info.Name)
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to check existing cluster groups")
+	}
+
+	if count != 0 {
+		return -1, ErrAlreadyDefined
+	}
+
+	result, err := c.tx.Exec("INSERT INTO cluster_groups (name, description) VALUES (?, ?)", info.Name, info.Description)
+	if err != nil {
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.clusterGroupNodesAdd(id, info.Members)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// UpdateClusterGroup updates the description and members of the cluster group with the given ID.
+func (c *ClusterTx) UpdateClusterGroup(id int64, info api.ClusterGroupPut) error {
+	_, err := c.tx.Exec("UPDATE cluster_groups SET description=? WHERE id=?", info.Description, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM nodes_cluster_groups WHERE group_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	return c.clusterGroupNodesAdd(id, info.Members)
+}
+
+// DeleteClusterGroup deletes the cluster group with the given ID.
+func (c *ClusterTx) DeleteClusterGroup(id int64) error {
+	_, err := query.DeleteObject(c.tx, "cluster_groups", id)
+	return err
+}
+
+// clusterGroupNodesAdd adds the nodes with the given names to the cluster group with the given ID.
+func (c *ClusterTx) clusterGroupNodesAdd(id int64, members []string) error {
+	for _, member := range members {
+		node, err := c.GetNodeByName(member)
+		if err != nil {
+			if err == ErrNoSuchObject {
+				return fmt.Errorf("No cluster member called '%s'", member)
+			}
+
+			return err
+		}
+
+		_, err = c.tx.Exec("INSERT INTO nodes_cluster_groups (node_id, group_id) VALUES (?, ?)", node.ID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestClusterGroups(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	id, err := tx.CreateClusterGroup(api.ClusterGroupsPost{
+		Name: "ssd",
+		ClusterGroupPut: api.ClusterGroupPut{
+			Description: "Members with SSD storage",
+			Members:     []string{"buzz"},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.CreateClusterGroup(api.ClusterGroupsPost{Name: "ssd"})
+	assert.Equal(t, db.ErrAlreadyDefined, err)
+
+	_, group, err := tx.GetClusterGroup("ssd")
+	require.NoError(t, err)
+	assert.Equal(t, "Members with SSD storage", group.Description)
+	assert.Equal(t, []string{"buzz"}, group.Members)
+
+	err = tx.UpdateClusterGroup(id, api.ClusterGroupPut{Members: []string{"buzz", "none"}})
+	require.NoError(t, err)
+
+	groups, err := tx.GetNodeClusterGroups(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ssd"}, groups)
+
+	err = tx.UpdateClusterGroup(id, api.ClusterGroupPut{Members: []string{"rusp"}})
+	assert.EqualError(t, err, "No cluster member called 'rusp'")
+
+	err = tx.DeleteClusterGroup(id)
+	require.NoError(t, err)
+
+	_, _, err = tx.GetClusterGroup("ssd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
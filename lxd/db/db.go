@@ -136,6 +136,8 @@ type Cluster struct {
 	mu      sync.RWMutex
 	stmts   map[int]*sql.Stmt // Prepared statements by code.
 	closing bool              // True when daemon is shutting down, prevents retries
+	batch   writeBatch        // Concurrent small writes committed together
+	behind  writeBehind       // Non-critical writes flushed periodically
 }
 
 // OpenCluster creates a new Cluster object for interacting with the dqlite
@@ -5,6 +5,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -48,6 +49,8 @@ type Image struct {
 	Cached       bool
 	LastUseDate  time.Time
 	AutoUpdate   bool
+	Pinned       bool
+	BaseImage    string
 }
 
 // ImageFilter can be used to filter results yielded by GetImages.
@@ -165,12 +168,19 @@ func (c *ClusterTx) imageFill(id int, image *api.Image, create, expire, used, up
 	image.UploadedAt = *upload
 
 	// Get the properties
-	properties, err := query.SelectConfig(c.tx, "images_properties", "image_id=?", id)
+	properties, err := query.SelectConfig(c.tx, "images_properties", "image_id=? AND type=0", id)
 	if err != nil {
 		return err
 	}
 	image.Properties = properties
 
+	// Get the annotations
+	annotations, err := query.SelectConfig(c.tx, "images_properties", "image_id=? AND type=1", id)
+	if err != nil {
+		return err
+	}
+	image.Annotations = annotations
+
 	// Get the aliases
 	aliases := []api.ImageAlias{}
 	dest := func(i int) []interface{} {
@@ -267,6 +277,7 @@ SELECT fingerprint
 type ExpiredImage struct {
 	Fingerprint string
 	ProjectName string
+	Size        int64
 }
 
 // GetExpiredImages returns the names and project name of all images that have expired since the given time.
@@ -300,6 +311,7 @@ func (c *Cluster) GetExpiredImages(expiry int64) ([]ExpiredImage, error) {
 		result := ExpiredImage{
 			Fingerprint: r.Fingerprint,
 			ProjectName: r.Project,
+			Size:        r.Size,
 		}
 
 		results = append(results, result)
@@ -308,6 +320,81 @@ func (c *Cluster) GetExpiredImages(expiry int64) ([]ExpiredImage, error) {
 	return results, nil
 }
 
+// GetImagesExceedingCacheSize returns the least recently used cached images which have to be removed for the
+// total size of the cached images to fit in maxSize, and for the size of the cached images of each project to
+// fit in its limit from projectMaxSizes. A limit of zero disables it. Pinned images and the images which layered
+// images are based on are never returned, but still account for the size of the cache.
+func (c *Cluster) GetImagesExceedingCacheSize(maxSize int64, projectMaxSizes map[string]int64) ([]ExpiredImage, error) {
+	var images []Image
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		images, err = tx.GetImages(ImageFilter{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bases := map[string]bool{}
+	for _, r := range images {
+		if r.BaseImage != "" {
+			bases[r.BaseImage] = true
+		}
+	}
+
+	var totalSize int64
+	projectSizes := map[string]int64{}
+	candidates := []Image{}
+	for _, r := range images {
+		if !r.Cached {
+			continue
+		}
+
+		totalSize += r.Size
+		projectSizes[r.Project] += r.Size
+
+		if r.Pinned || bases[r.Fingerprint] {
+			continue
+		}
+
+		candidates = append(candidates, r)
+	}
+
+	// Least recently used first.
+	lastUse := func(r Image) time.Time {
+		if r.LastUseDate.IsZero() {
+			return r.UploadDate
+		}
+
+		return r.LastUseDate
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lastUse(candidates[i]).Before(lastUse(candidates[j]))
+	})
+
+	results := []ExpiredImage{}
+	for _, r := range candidates {
+		projectMaxSize := projectMaxSizes[r.Project]
+		overProject := projectMaxSize > 0 && projectSizes[r.Project] > projectMaxSize
+		overTotal := maxSize > 0 && totalSize > maxSize
+		if !overProject && !overTotal {
+			continue
+		}
+
+		totalSize -= r.Size
+		projectSizes[r.Project] -= r.Size
+
+		results = append(results, ExpiredImage{
+			Fingerprint: r.Fingerprint,
+			ProjectName: r.Project,
+			Size:        r.Size,
+		})
+	}
+
+	return results, nil
+}
+
 // CreateImageSource inserts a new image source.
 func (c *Cluster) CreateImageSource(id int, server string, protocol string, certificate string, alias string) error {
 	protocolInt := -1
@@ -424,6 +511,29 @@ func (c *Cluster) ImageExists(project string, fingerprint string) (bool, error)
 	return exists, nil
 }
 
+// ImageBaseExists returns whether the given base image exists in a project which also has the layered image
+// with the given fingerprint.
+func (c *Cluster) ImageBaseExists(fingerprint string, baseImage string) (bool, error) {
+	table := "images AS layer JOIN images AS base ON base.project_id = layer.project_id"
+	where := "layer.fingerprint = ? AND base.fingerprint = ?"
+
+	var exists bool
+	err := c.Transaction(func(tx *ClusterTx) error {
+		count, err := query.Count(tx.tx, table, where, fingerprint, baseImage)
+		if err != nil {
+			return err
+		}
+
+		exists = count > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
 // ImageIsReferencedByOtherProjects returns true if the image with the given
 // fingerprint is referenced by projects other than the given one.
 func (c *Cluster) ImageIsReferencedByOtherProjects(project string, fingerprint string) (bool, error) {
@@ -497,6 +607,8 @@ func (c *Cluster) GetImage(project, fingerprint string, public bool) (int, *api.
 		image.Cached = object.Cached
 		image.Public = object.Public
 		image.AutoUpdate = object.AutoUpdate
+		image.Pinned = object.Pinned
+		image.BaseImage = object.BaseImage
 
 		err = tx.imageFill(
 			object.ID, &image,
@@ -547,6 +659,8 @@ func (c *Cluster) GetImageFromAnyProject(fingerprint string) (int, *api.Image, e
 		image.Cached = object.Cached
 		image.Public = object.Public
 		image.AutoUpdate = object.AutoUpdate
+		image.Pinned = object.Pinned
+		image.BaseImage = object.BaseImage
 
 		err = tx.imageFill(
 			object.ID, &image,
@@ -683,7 +797,7 @@ SELECT images_aliases.name
 func (c *Cluster) GetImageAlias(project, name string, isTrustedClient bool) (int, api.ImageAliasesEntry, error) {
 	id := -1
 	entry := api.ImageAliasesEntry{}
-	q := `SELECT images_aliases.id, images.fingerprint, images.type, images_aliases.description
+	q := `SELECT images_aliases.id, images.fingerprint, images.type, images.architecture, images_aliases.description
 			 FROM images_aliases
 			 INNER JOIN images
 			 ON images_aliases.image_id=images.id
@@ -703,10 +817,10 @@ func (c *Cluster) GetImageAlias(project, name string, isTrustedClient bool) (int
 			project = "default"
 		}
 		var fingerprint, description string
-		var imageType int
+		var imageType, architecture int
 
 		arg1 := []interface{}{project, name}
-		arg2 := []interface{}{&id, &fingerprint, &imageType, &description}
+		arg2 := []interface{}{&id, &fingerprint, &imageType, &architecture, &description}
 		err = tx.tx.QueryRow(q, arg1...).Scan(arg2...)
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -721,6 +835,27 @@ func (c *Cluster) GetImageAlias(project, name string, isTrustedClient bool) (int
 		entry.Description = description
 		entry.Type = instancetype.Type(imageType).String()
 
+		source, autoUpdate, err := tx.getImageAliasSource(id)
+		if err != nil && err != ErrNoSuchObject {
+			return errors.Wrap(err, "Failed to fetch alias update source")
+		}
+
+		if err == nil {
+			entry.UpdateSource = &source
+			entry.AutoUpdate = autoUpdate
+		}
+
+		architectures, err := tx.getImageAliasArchitectures(id, isTrustedClient)
+		if err != nil {
+			return errors.Wrap(err, "Failed to fetch alias architectures")
+		}
+
+		if len(architectures) > 0 {
+			architectureName, _ := osarch.ArchitectureName(architecture)
+			architectures[architectureName] = fingerprint
+			entry.Architectures = architectures
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -730,6 +865,64 @@ func (c *Cluster) GetImageAlias(project, name string, isTrustedClient bool) (int
 	return id, entry, nil
 }
 
+// getImageAliasArchitectures returns the fingerprints of the images the alias with the given ID points to for
+// architectures other than the one of its target, keyed by architecture name.
+func (c *ClusterTx) getImageAliasArchitectures(aliasID int, isTrustedClient bool) (map[string]string, error) {
+	q := `
+SELECT images.architecture, images.fingerprint
+  FROM images_aliases_architectures
+  JOIN images ON images.id=images_aliases_architectures.image_id
+ WHERE images_aliases_architectures.image_alias_id=?`
+	if !isTrustedClient {
+		q = q + ` AND images.public=1`
+	}
+
+	rows, err := c.tx.Query(q, aliasID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	architectures := map[string]string{}
+	for rows.Next() {
+		var architecture int
+		var fingerprint string
+		err := rows.Scan(&architecture, &fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		architectureName, err := osarch.ArchitectureName(architecture)
+		if err != nil {
+			return nil, err
+		}
+
+		architectures[architectureName] = fingerprint
+	}
+
+	return architectures, rows.Err()
+}
+
+// UpdateImageAliasArchitectures sets the images the alias with the given ID points to for architectures other
+// than the one of its target.
+func (c *Cluster) UpdateImageAliasArchitectures(aliasID int, imageIDs []int) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM images_aliases_architectures WHERE image_alias_id=?", aliasID)
+		if err != nil {
+			return err
+		}
+
+		for _, imageID := range imageIDs {
+			_, err = tx.tx.Exec("INSERT INTO images_aliases_architectures (image_alias_id, image_id) VALUES (?, ?)", aliasID, imageID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // RenameImageAlias renames the alias with the given ID.
 func (c *Cluster) RenameImageAlias(id int, name string) error {
 	q := "UPDATE images_aliases SET name=? WHERE id=?"
@@ -770,6 +963,11 @@ func (c *Cluster) MoveImageAlias(source int, destination int) error {
 	q := "UPDATE images_aliases SET image_id=? WHERE image_id=?"
 	err := c.Transaction(func(tx *ClusterTx) error {
 		_, err := tx.tx.Exec(q, destination, source)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("UPDATE images_aliases_architectures SET image_id=? WHERE image_id=?", destination, source)
 		return err
 	})
 	return err
@@ -810,6 +1008,94 @@ func (c *Cluster) UpdateImageAlias(id int, imageID int, desc string) error {
 	return err
 }
 
+// getImageAliasSource returns the update source of the alias with the given ID and whether the alias is
+// automatically moved to the latest image of that source.
+func (c *ClusterTx) getImageAliasSource(aliasID int) (api.ImageSource, bool, error) {
+	q := `SELECT server, protocol, certificate, alias, auto_update FROM images_aliases_source WHERE image_alias_id=?`
+
+	var source api.ImageSource
+	var protocol int
+	var autoUpdate bool
+	err := c.tx.QueryRow(q, aliasID).Scan(&source.Server, &protocol, &source.Certificate, &source.Alias, &autoUpdate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return api.ImageSource{}, false, ErrNoSuchObject
+		}
+
+		return api.ImageSource{}, false, err
+	}
+
+	name, found := ImageSourceProtocol[protocol]
+	if !found {
+		return api.ImageSource{}, false, fmt.Errorf("Invalid protocol: %d", protocol)
+	}
+
+	source.Protocol = name
+
+	return source, autoUpdate, nil
+}
+
+// UpdateImageAliasSource sets the update source of the alias with the given ID, removing it if source is nil.
+func (c *Cluster) UpdateImageAliasSource(aliasID int, source *api.ImageSource, autoUpdate bool) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM images_aliases_source WHERE image_alias_id=?", aliasID)
+		if err != nil {
+			return err
+		}
+
+		if source == nil {
+			return nil
+		}
+
+		protocol := -1
+		for protoInt, protoString := range ImageSourceProtocol {
+			if protoString == source.Protocol {
+				protocol = protoInt
+			}
+		}
+
+		if protocol == -1 {
+			return fmt.Errorf("Invalid protocol: %s", source.Protocol)
+		}
+
+		_, err = tx.tx.Exec(`
+INSERT INTO images_aliases_source (image_alias_id, server, protocol, certificate, alias, auto_update)
+     VALUES (?, ?, ?, ?, ?, ?)
+`, aliasID, source.Server, protocol, source.Certificate, source.Alias, autoUpdate)
+		return err
+	})
+}
+
+// GetImageAliasesWithAutoUpdate returns the names of the aliases of the given project which are
+// automatically moved to the latest image of their update source.
+func (c *Cluster) GetImageAliasesWithAutoUpdate(project string) ([]string, error) {
+	var names []string
+	q := `
+SELECT images_aliases.name
+  FROM images_aliases
+  JOIN images_aliases_source ON images_aliases_source.image_alias_id=images_aliases.id
+  JOIN projects ON projects.id=images_aliases.project_id
+ WHERE projects.name=? AND images_aliases_source.auto_update=1
+`
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		enabled, err := tx.ProjectHasImages(project)
+		if err != nil {
+			return errors.Wrap(err, "Check if project has images")
+		}
+		if !enabled {
+			project = "default"
+		}
+		names, err = query.SelectStrings(tx.tx, q, project)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
 // CopyDefaultImageProfiles copies default profiles from id to new_id.
 func (c *Cluster) CopyDefaultImageProfiles(id int, newID int) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
@@ -834,6 +1120,66 @@ func (c *Cluster) CopyDefaultImageProfiles(id int, newID int) error {
 	return nil
 }
 
+// UpdateImagePinned sets whether the image with the given ID is pinned, pinned images being
+// left untouched by automatic updates.
+func (c *Cluster) UpdateImagePinned(id int, pinned bool) error {
+	stmt := `UPDATE images SET pinned=? WHERE id=?`
+	err := c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(stmt, pinned, id)
+		return err
+	})
+	return err
+}
+
+// UpdateImageBaseImage records the fingerprint of the image the layered image with the given ID
+// only contains the changes from.
+func (c *Cluster) UpdateImageBaseImage(id int, baseImage string) error {
+	stmt := `UPDATE images SET base_image=? WHERE id=?`
+	err := c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(stmt, baseImage, id)
+		return err
+	})
+	return err
+}
+
+// UpdateImageAnnotations replaces the annotations of the image with the given ID. Annotations are
+// stored alongside the image properties, with a type of 1.
+func (c *Cluster) UpdateImageAnnotations(id int, annotations map[string]string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`DELETE FROM images_properties WHERE image_id=? AND type=1`, id)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range annotations {
+			_, err = tx.tx.Exec(`INSERT INTO images_properties (image_id, type, key, value) VALUES (?, 1, ?, ?)`, id, key, value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetImagesWithBaseImage returns the fingerprints of the layered images, in any project, having
+// the image with the given fingerprint as their base.
+func (c *Cluster) GetImagesWithBaseImage(fingerprint string) ([]string, error) {
+	q := `SELECT DISTINCT fingerprint FROM images WHERE base_image=?`
+
+	var fingerprints []string
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		fingerprints, err = query.SelectStrings(tx.tx, q, fingerprint)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fingerprints, nil
+}
+
 // UpdateImageLastUseDate updates the last_use_date field of the image with the
 // given fingerprint.
 func (c *Cluster) UpdateImageLastUseDate(fingerprint string, date time.Time) error {
@@ -884,7 +1230,7 @@ func (c *Cluster) UpdateImage(id int, fname string, sz int64, public bool, autoU
 			return err
 		}
 
-		_, err = tx.tx.Exec(`DELETE FROM images_properties WHERE image_id=?`, id)
+		_, err = tx.tx.Exec(`DELETE FROM images_properties WHERE image_id=? AND type=0`, id)
 		if err != nil {
 			return err
 		}
@@ -16,43 +16,43 @@ import (
 var _ = api.ServerEnvironment{}
 
 var imageObjects = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByProject = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE project = ? ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByProjectAndPublic = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE project = ? AND images.public = ? ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByProjectAndFingerprint = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE project = ? AND images.fingerprint LIKE ? ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByProjectAndFingerprintAndPublic = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE project = ? AND images.fingerprint LIKE ? AND images.public = ? ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByFingerprint = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE images.fingerprint LIKE ? ORDER BY projects.id, images.fingerprint
 `)
 
 var imageObjectsByCached = cluster.RegisterStmt(`
-SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update
+SELECT images.id, projects.name AS project, images.fingerprint, images.type, images.filename, images.size, images.public, images.architecture, images.creation_date, images.expiry_date, images.upload_date, images.cached, images.last_use_date, images.auto_update, images.pinned, images.base_image
   FROM images JOIN projects ON images.project_id = projects.id
   WHERE images.cached = ? ORDER BY projects.id, images.fingerprint
 `)
@@ -138,6 +138,8 @@ func (c *ClusterTx) GetImages(filter ImageFilter) ([]Image, error) {
 			&objects[i].Cached,
 			&objects[i].LastUseDate,
 			&objects[i].AutoUpdate,
+			&objects[i].Pinned,
+			&objects[i].BaseImage,
 		}
 	}
 
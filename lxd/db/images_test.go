@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -58,3 +59,232 @@ func TestImageExists(t *testing.T) {
 
 	assert.True(t, exists)
 }
+
+func TestImageBaseExists(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		project := api.ProjectsPost{}
+		project.Name = "other"
+		project.Config = map[string]string{"features.images": "true"}
+		_, err := tx.CreateProject(project)
+		return err
+	})
+	require.NoError(t, err)
+
+	err = cluster.CreateImage(
+		"default", "base", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	err = cluster.CreateImage(
+		"other", "layer", "y.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	// The base image only exists in another project.
+	exists, err := cluster.ImageBaseExists("layer", "base")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = cluster.CreateImage(
+		"other", "base", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	exists, err = cluster.ImageBaseExists("layer", "base")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestImageAliasSource(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.CreateImage(
+		"default", "abc", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	imageID, _, err := cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+
+	err = cluster.CreateImageAlias("default", "focal", imageID, "")
+	require.NoError(t, err)
+
+	aliasID, alias, err := cluster.GetImageAlias("default", "focal", true)
+	require.NoError(t, err)
+	assert.Nil(t, alias.UpdateSource)
+
+	source := &api.ImageSource{
+		Server:   "https://images.linuxcontainers.org",
+		Protocol: "simplestreams",
+		Alias:    "ubuntu/20.04",
+	}
+
+	err = cluster.UpdateImageAliasSource(aliasID, source, true)
+	require.NoError(t, err)
+
+	_, alias, err = cluster.GetImageAlias("default", "focal", true)
+	require.NoError(t, err)
+	assert.Equal(t, source, alias.UpdateSource)
+	assert.True(t, alias.AutoUpdate)
+
+	names, err := cluster.GetImageAliasesWithAutoUpdate("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"focal"}, names)
+
+	err = cluster.UpdateImageAliasSource(aliasID, nil, false)
+	require.NoError(t, err)
+
+	names, err = cluster.GetImageAliasesWithAutoUpdate("default")
+	require.NoError(t, err)
+	assert.Len(t, names, 0)
+}
+
+func TestImageAliasArchitectures(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.CreateImage(
+		"default", "abc", "x.gz", 16, false, false, "x86_64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	err = cluster.CreateImage(
+		"default", "def", "y.gz", 16, false, false, "aarch64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	imageID, _, err := cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+
+	otherImageID, _, err := cluster.GetImage("default", "def", false)
+	require.NoError(t, err)
+
+	err = cluster.CreateImageAlias("default", "focal", imageID, "")
+	require.NoError(t, err)
+
+	aliasID, alias, err := cluster.GetImageAlias("default", "focal", true)
+	require.NoError(t, err)
+	assert.Nil(t, alias.Architectures)
+
+	err = cluster.UpdateImageAliasArchitectures(aliasID, []int{otherImageID})
+	require.NoError(t, err)
+
+	_, alias, err = cluster.GetImageAlias("default", "focal", true)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", alias.Target)
+	assert.Equal(t, map[string]string{"x86_64": "abc", "aarch64": "def"}, alias.Architectures)
+
+	err = cluster.UpdateImageAliasArchitectures(aliasID, nil)
+	require.NoError(t, err)
+
+	_, alias, err = cluster.GetImageAlias("default", "focal", true)
+	require.NoError(t, err)
+	assert.Nil(t, alias.Architectures)
+}
+
+func TestImageAnnotations(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.CreateImage(
+		"default", "abc", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{"os": "ubuntu"}, "container")
+	require.NoError(t, err)
+
+	id, image, err := cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+	assert.Len(t, image.Annotations, 0)
+
+	annotations := map[string]string{"status": "blocked", "os": "debian"}
+	err = cluster.UpdateImageAnnotations(id, annotations)
+	require.NoError(t, err)
+
+	_, image, err = cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+	assert.Equal(t, annotations, image.Annotations)
+	assert.Equal(t, map[string]string{"os": "ubuntu"}, image.Properties)
+
+	// Updating the properties leaves the annotations alone.
+	err = cluster.UpdateImage(id, image.Filename, image.Size, image.Public, image.AutoUpdate, image.Architecture, image.CreatedAt, image.ExpiresAt, map[string]string{}, "", nil)
+	require.NoError(t, err)
+
+	_, image, err = cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+	assert.Equal(t, annotations, image.Annotations)
+	assert.Len(t, image.Properties, 0)
+}
+
+func TestImageBaseImage(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.CreateImage(
+		"default", "abc", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	err = cluster.CreateImage(
+		"default", "def", "y.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	imageID, _, err := cluster.GetImage("default", "def", false)
+	require.NoError(t, err)
+
+	err = cluster.UpdateImageBaseImage(imageID, "abc")
+	require.NoError(t, err)
+
+	_, image, err := cluster.GetImage("default", "def", false)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", image.BaseImage)
+
+	fingerprints, err := cluster.GetImagesWithBaseImage("abc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"def"}, fingerprints)
+
+	fingerprints, err = cluster.GetImagesWithBaseImage("def")
+	require.NoError(t, err)
+	assert.Len(t, fingerprints, 0)
+}
+
+func TestGetImagesExceedingCacheSize(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i, fingerprint := range []string{"abc", "def", "ghi"} {
+		err := cluster.CreateImage(
+			"default", fingerprint, fingerprint+".gz", 10, false, false, "amd64", now, now, map[string]string{}, "container")
+		require.NoError(t, err)
+
+		err = cluster.InitImageLastUseDate(fingerprint)
+		require.NoError(t, err)
+
+		err = cluster.UpdateImageLastUseDate(fingerprint, now.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	// No limit.
+	images, err := cluster.GetImagesExceedingCacheSize(0, nil)
+	require.NoError(t, err)
+	assert.Len(t, images, 0)
+
+	// The least recently used images go first.
+	images, err = cluster.GetImagesExceedingCacheSize(15, nil)
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+	assert.Equal(t, "abc", images[0].Fingerprint)
+	assert.Equal(t, "def", images[1].Fingerprint)
+
+	// Project limits apply on top of the global one.
+	images, err = cluster.GetImagesExceedingCacheSize(25, map[string]int64{"default": 10})
+	require.NoError(t, err)
+	assert.Len(t, images, 2)
+
+	// Pinned images are kept.
+	imageID, _, err := cluster.GetImage("default", "abc", false)
+	require.NoError(t, err)
+
+	err = cluster.UpdateImagePinned(imageID, true)
+	require.NoError(t, err)
+
+	images, err = cluster.GetImagesExceedingCacheSize(25, nil)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, "def", images[0].Fingerprint)
+}
@@ -340,25 +340,23 @@ func (c *ClusterTx) instanceListExpanded() ([]Instance, error) {
 		projectHasProfiles[project.Name] = shared.IsTrue(project.Config["features.profiles"])
 	}
 
-	profiles, err := c.GetProfiles(ProfileFilter{})
+	profiles, err := c.GetProfilesWithParents(ProfileFilter{})
 	if err != nil {
 		return nil, errors.Wrap(err, "Load profiles")
 	}
 
 	// Index of all profiles by project and name.
-	profilesByProjectAndName := map[string]map[string]Profile{}
+	profilesByProjectAndName := map[string]map[string]api.Profile{}
 	for _, profile := range profiles {
 		profilesByName, ok := profilesByProjectAndName[profile.Project]
 		if !ok {
-			profilesByName = map[string]Profile{}
+			profilesByName = map[string]api.Profile{}
 			profilesByProjectAndName[profile.Project] = profilesByName
 		}
-		profilesByName[profile.Name] = profile
+		profilesByName[profile.Name] = *ProfileToAPI(&profile)
 	}
 
 	for i, instance := range instances {
-		profiles := make([]api.Profile, len(instance.Profiles))
-
 		profilesProject := instance.Project
 
 		// If the instance's project does not have the profiles feature
@@ -367,9 +365,9 @@ func (c *ClusterTx) instanceListExpanded() ([]Instance, error) {
 			profilesProject = "default"
 		}
 
-		for j, name := range instance.Profiles {
-			profile := profilesByProjectAndName[profilesProject][name]
-			profiles[j] = *ProfileToAPI(&profile)
+		profiles, err := ExpandProfiles(instance.Profiles, profilesByProjectAndName[profilesProject])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Load profiles of instance %q", instance.Name)
 		}
 
 		instances[i].Config = ExpandInstanceConfig(instance.Config, profiles)
@@ -511,6 +509,47 @@ func (c *ClusterTx) UpdateInstanceNode(project, oldName, newName, newNode string
 	return nil
 }
 
+// MoveInstanceToProject moves an instance to another project, optionally renaming it.
+//
+// Its snapshots follow it as they reference the instance by ID, and the
+// records of its storage volumes and their snapshots are moved along with it.
+// The volumes themselves must be renamed on storage by the caller.
+func (c *ClusterTx) MoveInstanceToProject(project, name, newProject, newName string) error {
+	projectID, err := c.GetProjectID(project)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get ID of project %q", project)
+	}
+
+	newProjectID, err := c.GetProjectID(newProject)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get ID of project %q", newProject)
+	}
+
+	stmt := "UPDATE instances SET project_id=?, name=? WHERE project_id=? AND name=?"
+	result, err := c.tx.Exec(stmt, newProjectID, newName, projectID, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance's project and name")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get rows affected by instance update")
+	}
+
+	if n != 1 {
+		return fmt.Errorf("Unexpected number of updated rows in instances table: %d", n)
+	}
+
+	// Shared pools like ceph have a record of the volume for each node.
+	stmt = "UPDATE storage_volumes SET project_id=?, name=? WHERE project_id=? AND name=? AND type IN (?, ?)"
+	_, err = c.tx.Exec(stmt, newProjectID, newName, projectID, name, StoragePoolVolumeTypeContainer, StoragePoolVolumeTypeVM)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance's volume project and name")
+	}
+
+	return nil
+}
+
 // GetLocalInstancesInProject retuurns all instances of the given type on the
 // local node within the given project.
 func (c *ClusterTx) GetLocalInstancesInProject(project string, instanceType instancetype.Type) ([]Instance, error) {
@@ -859,12 +898,11 @@ func (c *Cluster) ResetInstancesPowerState() error {
 }
 
 // UpdateInstancePowerState sets the the power state of the instance with the
-// given ID.
+// given ID. Concurrent updates are committed together.
 func (c *Cluster) UpdateInstancePowerState(id int, state string) error {
-	err := c.Transaction(func(tx *ClusterTx) error {
+	return c.batchTransaction(func(tx *ClusterTx) error {
 		return tx.UpdateInstancePowerState(id, state)
 	})
-	return err
 }
 
 // UpdateInstanceSnapshotCreationDate updates the creation_date field of the instance snapshot with ID.
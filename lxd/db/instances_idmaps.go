@@ -0,0 +1,103 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/pkg/errors"
+)
+
+// IdmapAllocation is a range of host uids and gids allocated to an isolated container.
+type IdmapAllocation struct {
+	Project  string
+	Instance string
+	HostID   int64
+	Maprange int64
+}
+
+// GetNodeIdmapAllocations returns the uid/gid ranges allocated to the containers of the local node.
+func (c *ClusterTx) GetNodeIdmapAllocations() ([]IdmapAllocation, error) {
+	allocations := []IdmapAllocation{}
+	dest := func(i int) []interface{} {
+		allocations = append(allocations, IdmapAllocation{})
+		return []interface{}{
+			&allocations[i].Project,
+			&allocations[i].Instance,
+			&allocations[i].HostID,
+			&allocations[i].Maprange,
+		}
+	}
+
+	q := `
+SELECT projects.name, instances.name, instances_idmaps.hostid, instances_idmaps.maprange
+  FROM instances_idmaps
+  JOIN instances ON instances.id = instances_idmaps.instance_id
+  JOIN projects ON projects.id = instances.project_id
+ WHERE instances.node_id = ?
+ ORDER BY instances_idmaps.hostid
+`
+	stmt, err := c.tx.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, c.nodeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch idmap allocations")
+	}
+
+	return allocations, nil
+}
+
+// GetInstanceIdmapAllocation returns the uid/gid range allocated to the given container, or nil if it has none.
+func (c *ClusterTx) GetInstanceIdmapAllocation(project string, name string) (*IdmapAllocation, error) {
+	id, err := c.GetInstanceID(project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	allocation := IdmapAllocation{Project: project, Instance: name}
+	err = c.tx.QueryRow("SELECT hostid, maprange FROM instances_idmaps WHERE instance_id = ?", id).Scan(&allocation.HostID, &allocation.Maprange)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch idmap allocation of instance %q", name)
+	}
+
+	return &allocation, nil
+}
+
+// UpdateInstanceIdmapAllocation records the uid/gid range allocated to the given container, replacing any
+// previous allocation.
+func (c *ClusterTx) UpdateInstanceIdmapAllocation(project string, name string, hostID int64, maprange int64) error {
+	id, err := c.GetInstanceID(project, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("INSERT OR REPLACE INTO instances_idmaps (instance_id, hostid, maprange) VALUES (?, ?, ?)", id, hostID, maprange)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to record idmap allocation of instance %q", name)
+	}
+
+	return nil
+}
+
+// DeleteInstanceIdmapAllocation releases the uid/gid range allocated to the given container, if any.
+func (c *ClusterTx) DeleteInstanceIdmapAllocation(project string, name string) error {
+	id, err := c.GetInstanceID(project, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM instances_idmaps WHERE instance_id = ?", id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to release idmap allocation of instance %q", name)
+	}
+
+	return nil
+}
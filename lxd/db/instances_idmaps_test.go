@@ -0,0 +1,57 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+)
+
+func TestInstanceIdmapAllocations(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	for _, name := range []string{"c1", "c2"} {
+		_, err := tx.CreateInstance(db.Instance{
+			Project:      "default",
+			Name:         name,
+			Node:         "none",
+			Type:         instancetype.Container,
+			Architecture: 1,
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tx.UpdateInstanceIdmapAllocation("default", "c1", 231072, 65536))
+	require.NoError(t, tx.UpdateInstanceIdmapAllocation("default", "c2", 165536, 65536))
+
+	// Updating an allocation replaces it.
+	require.NoError(t, tx.UpdateInstanceIdmapAllocation("default", "c1", 231072, 131072))
+
+	allocations, err := tx.GetNodeIdmapAllocations()
+	require.NoError(t, err)
+	assert.Equal(t, []db.IdmapAllocation{
+		{Project: "default", Instance: "c2", HostID: 165536, Maprange: 65536},
+		{Project: "default", Instance: "c1", HostID: 231072, Maprange: 131072},
+	}, allocations)
+
+	allocation, err := tx.GetInstanceIdmapAllocation("default", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, &db.IdmapAllocation{Project: "default", Instance: "c1", HostID: 231072, Maprange: 131072}, allocation)
+
+	require.NoError(t, tx.DeleteInstanceIdmapAllocation("default", "c2"))
+
+	allocation, err = tx.GetInstanceIdmapAllocation("default", "c2")
+	require.NoError(t, err)
+	assert.Nil(t, allocation)
+
+	allocations, err = tx.GetNodeIdmapAllocations()
+	require.NoError(t, err)
+	assert.Len(t, allocations, 1)
+	assert.Equal(t, "c1", allocations[0].Instance)
+}
@@ -381,6 +381,58 @@ func TestGetInstancePool(t *testing.T) {
 	assert.Equal(t, "default", poolName)
 }
 
+// An instance moves to another project along with its volume.
+func TestMoveInstanceToProject(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.CreateStoragePool("default", "", "dir", nil)
+	require.NoError(t, err)
+	_, err = cluster.CreateStoragePoolVolume("default", "c1", "", db.StoragePoolVolumeTypeContainer, poolID, nil, db.StoragePoolVolumeContentTypeFS)
+	require.NoError(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		project := api.ProjectsPost{}
+		project.Name = "other"
+		_, err := tx.CreateProject(project)
+		if err != nil {
+			return err
+		}
+
+		container := db.Instance{
+			Project: "default",
+			Name:    "c1",
+			Node:    "none",
+			Devices: map[string]map[string]string{
+				"root": {
+					"path": "/",
+					"pool": "default",
+					"type": "disk",
+				},
+			},
+		}
+		_, err = tx.CreateInstance(container)
+		if err != nil {
+			return err
+		}
+
+		return tx.MoveInstanceToProject("default", "c1", "other", "c2")
+	})
+	require.NoError(t, err)
+
+	_, err = cluster.GetInstanceID("default", "c1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, err = cluster.GetInstanceID("other", "c2")
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetLocalStoragePoolVolume("other", "c2", db.StoragePoolVolumeTypeContainer, poolID)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetLocalStoragePoolVolume("default", "c1", db.StoragePoolVolumeTypeContainer, poolID)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
 // All containers on a node are loaded in bulk.
 func TestGetLocalInstancesInProject(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
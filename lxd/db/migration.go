@@ -98,6 +98,7 @@ var preClusteringTablesRequiringProjectID = []string{
 	"containers",
 	"images",
 	"images_aliases",
+	"networks",
 	"profiles",
 	"storage_volumes",
 	"operations",
@@ -87,8 +87,8 @@ func TestImportPreClusteringData(t *testing.T) {
 	// networks
 	networks, err := cluster.GetNetworks()
 	require.NoError(t, err)
-	assert.Equal(t, []string{"lxcbr0"}, networks)
-	id, network, err := cluster.GetNetworkInAnyState("lxcbr0")
+	assert.Equal(t, map[string][]string{"default": {"lxcbr0"}}, networks)
+	id, network, err := cluster.GetNetworkInAnyState("default", "lxcbr0")
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), id)
 	assert.Equal(t, "true", network.Config["ipv4.nat"])
@@ -0,0 +1,189 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkACLs returns the names of existing network ACLs.
+func (c *Cluster) GetNetworkACLs() ([]string, error) {
+	var names []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		names, err = query.SelectStrings(tx.tx, "SELECT name FROM network_acls ORDER BY id")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// GetNetworkACL returns the network ACL with the given name.
+func (c *Cluster) GetNetworkACL(name string) (int64, *api.NetworkACL, error) {
+	var id int64 = -1
+	var ingressJSON, egressJSON string
+
+	acl := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{
+			Name: name,
+		},
+	}
+
+	q := "SELECT id, description, ingress, egress FROM network_acls WHERE name=?"
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &acl.Description, &ingressJSON, &egressJSON}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	acl.Ingress = []api.NetworkACLRule{}
+	err = json.Unmarshal([]byte(ingressJSON), &acl.Ingress)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed unmarshalling ingress rules: %v", err)
+	}
+
+	acl.Egress = []api.NetworkACLRule{}
+	err = json.Unmarshal([]byte(egressJSON), &acl.Egress)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed unmarshalling egress rules: %v", err)
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		acl.Config, err = query.SelectConfig(tx.tx, "network_acls_config", "network_acl_id=?", id)
+		return err
+	})
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed loading config: %v", err)
+	}
+
+	return id, &acl, nil
+}
+
+// CreateNetworkACL creates a new network ACL.
+func (c *Cluster) CreateNetworkACL(info *api.NetworkACLsPost) (int64, error) {
+	ingressJSON, egressJSON, err := networkACLRulesMarshal(&info.NetworkACLPut)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int64
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO network_acls (name, description, ingress, egress) VALUES (?, ?, ?, ?)", info.Name, info.Description, ingressJSON, egressJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkACLConfigAdd(tx.tx, id, info.Config)
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkACL updates the network ACL with the given ID.
+func (c *Cluster) UpdateNetworkACL(id int64, config *api.NetworkACLPut) error {
+	ingressJSON, egressJSON, err := networkACLRulesMarshal(config)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE network_acls SET description=?, ingress=?, egress=? WHERE id=?", config.Description, ingressJSON, egressJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM network_acls_config WHERE network_acl_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkACLConfigAdd(tx.tx, id, config.Config)
+	})
+}
+
+// RenameNetworkACL renames the network ACL with the given ID.
+func (c *Cluster) RenameNetworkACL(id int64, newName string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE network_acls SET name=? WHERE id=?", newName, id)
+		return err
+	})
+}
+
+// DeleteNetworkACL deletes the network ACL with the given ID.
+func (c *Cluster) DeleteNetworkACL(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := query.DeleteObject(tx.tx, "network_acls", id)
+		return err
+	})
+}
+
+// networkACLRulesMarshal returns the JSON encoded ingress and egress rules for storage.
+func networkACLRulesMarshal(config *api.NetworkACLPut) (string, string, error) {
+	ingress := config.Ingress
+	if ingress == nil {
+		ingress = []api.NetworkACLRule{}
+	}
+
+	egress := config.Egress
+	if egress == nil {
+		egress = []api.NetworkACLRule{}
+	}
+
+	ingressJSON, err := json.Marshal(ingress)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed marshalling ingress rules: %v", err)
+	}
+
+	egressJSON, err := json.Marshal(egress)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed marshalling egress rules: %v", err)
+	}
+
+	return string(ingressJSON), string(egressJSON), nil
+}
+
+// networkACLConfigAdd inserts the config of the network ACL with the given ID.
+func networkACLConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO network_acls_config (network_acl_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkACLs(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	info := &api.NetworkACLsPost{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers",
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", Protocol: "tcp", DestinationPort: "80,443", State: "enabled"},
+			},
+			Config: map[string]string{"user.owner": "ops"},
+		},
+	}
+
+	id, err := cluster.CreateNetworkACL(info)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	names, err := cluster.GetNetworkACLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, names)
+
+	gotID, acl, err := cluster.GetNetworkACL("web")
+	require.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, "Web servers", acl.Description)
+	assert.Equal(t, info.Ingress, acl.Ingress)
+	assert.Equal(t, []api.NetworkACLRule{}, acl.Egress)
+	assert.Equal(t, map[string]string{"user.owner": "ops"}, acl.Config)
+
+	put := acl.Writable()
+	put.Egress = []api.NetworkACLRule{{Action: "drop", Destination: "10.0.0.0/8", State: "enabled"}}
+	put.Config = map[string]string{}
+	err = cluster.UpdateNetworkACL(id, &put)
+	require.NoError(t, err)
+
+	err = cluster.RenameNetworkACL(id, "web2")
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkACL("web")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, acl, err = cluster.GetNetworkACL("web2")
+	require.NoError(t, err)
+	assert.Equal(t, put.Egress, acl.Egress)
+	assert.Equal(t, map[string]string{}, acl.Config)
+
+	err = cluster.DeleteNetworkACL(id)
+	require.NoError(t, err)
+
+	names, err = cluster.GetNetworkACLs()
+	require.NoError(t, err)
+	assert.Len(t, names, 0)
+}
@@ -0,0 +1,247 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkForwards returns the address forwards of the network with the given ID. If localOnly is true only
+// the forwards of the local cluster member are returned.
+func (c *Cluster) GetNetworkForwards(networkID int64, localOnly bool) ([]*api.NetworkForward, error) {
+	q := `
+SELECT networks_forwards.id, networks_forwards.listen_address, networks_forwards.description, networks_forwards.ports, nodes.name
+  FROM networks_forwards
+  JOIN nodes ON nodes.id = networks_forwards.node_id
+  WHERE networks_forwards.network_id = ?
+`
+	args := []interface{}{networkID}
+	if localOnly {
+		q += "  AND networks_forwards.node_id = ?\n"
+		args = append(args, c.nodeID)
+	}
+
+	q += "  ORDER BY networks_forwards.id"
+
+	var id int64
+	var listenAddress, description, portsJSON, location string
+	outfmt := []interface{}{id, listenAddress, description, portsJSON, location}
+	results, err := queryScan(c, q, args, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	forwards := make([]*api.NetworkForward, 0, len(results))
+	for _, r := range results {
+		forward := &api.NetworkForward{
+			ListenAddress: r[1].(string),
+			Location:      r[4].(string),
+		}
+		forward.Description = r[2].(string)
+
+		forward.Ports, err = networkForwardPortsUnmarshal(r[3].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		forward.Config, err = c.networkForwardConfig(r[0].(int64))
+		if err != nil {
+			return nil, err
+		}
+
+		forwards = append(forwards, forward)
+	}
+
+	return forwards, nil
+}
+
+// GetNetworkForward returns the address forward of the network with the given ID and listen address. Listen
+// addresses are unique across the cluster, the cluster member the forward is applied on is returned in Location.
+func (c *Cluster) GetNetworkForward(networkID int64, listenAddress string) (int64, *api.NetworkForward, error) {
+	var id int64 = -1
+	var portsJSON string
+
+	forward := api.NetworkForward{
+		ListenAddress: listenAddress,
+	}
+
+	q := `
+SELECT networks_forwards.id, networks_forwards.description, networks_forwards.ports, nodes.name
+  FROM networks_forwards
+  JOIN nodes ON nodes.id = networks_forwards.node_id
+  WHERE networks_forwards.network_id = ? AND networks_forwards.listen_address = ?
+`
+	arg1 := []interface{}{networkID, listenAddress}
+	arg2 := []interface{}{&id, &forward.Description, &portsJSON, &forward.Location}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	forward.Ports, err = networkForwardPortsUnmarshal(portsJSON)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	forward.Config, err = c.networkForwardConfig(id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &forward, nil
+}
+
+// GetNetworkForwardListenAddresses returns the listen addresses of all the address forwards in the cluster,
+// keyed by listen address with the name of the cluster member using it as value.
+func (c *Cluster) GetNetworkForwardListenAddresses() (map[string]string, error) {
+	q := `
+SELECT networks_forwards.listen_address, nodes.name
+  FROM networks_forwards
+  JOIN nodes ON nodes.id = networks_forwards.node_id
+`
+	var listenAddress, location string
+	results, err := queryScan(c, q, nil, []interface{}{listenAddress, location})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string]string, len(results))
+	for _, r := range results {
+		addresses[r[0].(string)] = r[1].(string)
+	}
+
+	return addresses, nil
+}
+
+// CreateNetworkForward creates a new address forward for the network with the given ID, applied on the local
+// cluster member.
+func (c *Cluster) CreateNetworkForward(networkID int64, info *api.NetworkForwardsPost) (int64, error) {
+	portsJSON, err := networkForwardPortsMarshal(info.Ports)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int64
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO networks_forwards (network_id, node_id, listen_address, description, ports) VALUES (?, ?, ?, ?, ?)", networkID, c.nodeID, info.ListenAddress, info.Description, portsJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkForwardConfigAdd(tx.tx, id, info.Config)
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkForward updates the address forward with the given ID.
+func (c *Cluster) UpdateNetworkForward(id int64, config *api.NetworkForwardPut) error {
+	portsJSON, err := networkForwardPortsMarshal(config.Ports)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_forwards SET description=?, ports=? WHERE id=?", config.Description, portsJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_forwards_config WHERE network_forward_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkForwardConfigAdd(tx.tx, id, config.Config)
+	})
+}
+
+// DeleteNetworkForward deletes the address forward with the given ID.
+func (c *Cluster) DeleteNetworkForward(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := query.DeleteObject(tx.tx, "networks_forwards", id)
+		return err
+	})
+}
+
+// networkForwardConfig returns the config of the address forward with the given ID.
+func (c *Cluster) networkForwardConfig(id int64) (map[string]string, error) {
+	var config map[string]string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		config, err = query.SelectConfig(tx.tx, "networks_forwards_config", "network_forward_id=?", id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading config: %v", err)
+	}
+
+	return config, nil
+}
+
+// networkForwardPortsMarshal returns the JSON encoded port specifications for storage.
+func networkForwardPortsMarshal(ports []api.NetworkForwardPort) (string, error) {
+	if ports == nil {
+		ports = []api.NetworkForwardPort{}
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return "", fmt.Errorf("Failed marshalling ports: %v", err)
+	}
+
+	return string(portsJSON), nil
+}
+
+// networkForwardPortsUnmarshal decodes the stored JSON port specifications.
+func networkForwardPortsUnmarshal(portsJSON string) ([]api.NetworkForwardPort, error) {
+	ports := []api.NetworkForwardPort{}
+	err := json.Unmarshal([]byte(portsJSON), &ports)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling ports: %v", err)
+	}
+
+	return ports, nil
+}
+
+// networkForwardConfigAdd inserts the config of the address forward with the given ID.
+func networkForwardConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_forwards_config (network_forward_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
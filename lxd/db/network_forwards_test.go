@@ -0,0 +1,70 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkForwards(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork("default", "lxdbr0", "", db.NetworkTypeBridge, nil)
+	require.NoError(t, err)
+
+	info := &api.NetworkForwardsPost{
+		ListenAddress: "192.0.2.1",
+		NetworkForwardPut: api.NetworkForwardPut{
+			Description: "Web",
+			Ports: []api.NetworkForwardPort{
+				{Protocol: "tcp", ListenPort: "80,443", TargetAddress: "10.0.0.2"},
+			},
+			Config: map[string]string{"user.owner": "ops"},
+		},
+	}
+
+	id, err := cluster.CreateNetworkForward(networkID, info)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	forwards, err := cluster.GetNetworkForwards(networkID, true)
+	require.NoError(t, err)
+	require.Len(t, forwards, 1)
+	assert.Equal(t, "192.0.2.1", forwards[0].ListenAddress)
+	assert.Equal(t, "none", forwards[0].Location)
+
+	addresses, err := cluster.GetNetworkForwardListenAddresses()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"192.0.2.1": "none"}, addresses)
+
+	gotID, forward, err := cluster.GetNetworkForward(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, "Web", forward.Description)
+	assert.Equal(t, info.Ports, forward.Ports)
+	assert.Equal(t, map[string]string{"user.owner": "ops"}, forward.Config)
+
+	put := forward.Writable()
+	put.Ports = nil
+	put.Config = map[string]string{"target_address": "10.0.0.3"}
+	err = cluster.UpdateNetworkForward(id, &put)
+	require.NoError(t, err)
+
+	_, forward, err = cluster.GetNetworkForward(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, []api.NetworkForwardPort{}, forward.Ports)
+	assert.Equal(t, put.Config, forward.Config)
+
+	err = cluster.DeleteNetworkForward(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkForward(networkID, "192.0.2.1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
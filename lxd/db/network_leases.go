@@ -0,0 +1,106 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkLeaseReservations returns the DHCP lease reservations of the network with the given ID, keyed by
+// the name of the project they belong to.
+func (c *Cluster) GetNetworkLeaseReservations(networkID int64) (map[string][]api.NetworkLease, error) {
+	q := `
+SELECT projects.name, networks_leases.hostname, networks_leases.hwaddr, networks_leases.address
+  FROM networks_leases
+  JOIN projects ON projects.id = networks_leases.project_id
+  WHERE networks_leases.network_id = ?
+  ORDER BY networks_leases.id
+`
+	var project, hostname, hwaddr, address string
+	outfmt := []interface{}{project, hostname, hwaddr, address}
+	results, err := queryScan(c, q, []interface{}{networkID}, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := map[string][]api.NetworkLease{}
+	for _, r := range results {
+		project := r[0].(string)
+		reservations[project] = append(reservations[project], api.NetworkLease{
+			Hostname: r[1].(string),
+			Hwaddr:   r[2].(string),
+			Address:  r[3].(string),
+			Type:     "reserved",
+		})
+	}
+
+	return reservations, nil
+}
+
+// GetNetworkLeaseReservation returns the DHCP lease reservation of the network with the given ID for the address,
+// along with the name of the project it belongs to.
+func (c *Cluster) GetNetworkLeaseReservation(networkID int64, address string) (int64, string, *api.NetworkLease, error) {
+	var id int64 = -1
+	var project string
+
+	lease := api.NetworkLease{
+		Address: address,
+		Type:    "reserved",
+	}
+
+	q := `
+SELECT networks_leases.id, projects.name, networks_leases.hostname, networks_leases.hwaddr
+  FROM networks_leases
+  JOIN projects ON projects.id = networks_leases.project_id
+  WHERE networks_leases.network_id = ? AND networks_leases.address = ?
+`
+	arg1 := []interface{}{networkID, address}
+	arg2 := []interface{}{&id, &project, &lease.Hostname, &lease.Hwaddr}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, "", nil, ErrNoSuchObject
+		}
+
+		return -1, "", nil, err
+	}
+
+	return id, project, &lease, nil
+}
+
+// CreateNetworkLeaseReservation reserves a DHCP lease on the network with the given ID for the given project.
+func (c *Cluster) CreateNetworkLeaseReservation(networkID int64, project string, info *api.NetworkLeasesPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		projectID, err := tx.GetProjectID(project)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.tx.Exec("INSERT INTO networks_leases (network_id, project_id, hostname, hwaddr, address) VALUES (?, ?, ?, ?, ?)", networkID, projectID, info.Hostname, info.Hwaddr, info.Address)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// DeleteNetworkLeaseReservation deletes the DHCP lease reservation with the given ID.
+func (c *Cluster) DeleteNetworkLeaseReservation(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := query.DeleteObject(tx.tx, "networks_leases", id)
+		return err
+	})
+}
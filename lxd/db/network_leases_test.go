@@ -0,0 +1,53 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkLeaseReservations(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork("default", "lxdbr0", "", db.NetworkTypeBridge, nil)
+	require.NoError(t, err)
+
+	info := &api.NetworkLeasesPost{
+		Hostname: "printer",
+		Hwaddr:   "00:16:3e:00:00:01",
+		Address:  "10.0.0.50",
+	}
+
+	id, err := cluster.CreateNetworkLeaseReservation(networkID, "default", info)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	// The address can only be reserved once per network.
+	_, err = cluster.CreateNetworkLeaseReservation(networkID, "default", info)
+	assert.Error(t, err)
+
+	reservations, err := cluster.GetNetworkLeaseReservations(networkID)
+	require.NoError(t, err)
+	require.Len(t, reservations["default"], 1)
+	assert.Equal(t, "printer", reservations["default"][0].Hostname)
+	assert.Equal(t, "reserved", reservations["default"][0].Type)
+
+	gotID, project, lease, err := cluster.GetNetworkLeaseReservation(networkID, "10.0.0.50")
+	require.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, "default", project)
+	assert.Equal(t, "00:16:3e:00:00:01", lease.Hwaddr)
+
+	err = cluster.DeleteNetworkLeaseReservation(id)
+	require.NoError(t, err)
+
+	_, _, _, err = cluster.GetNetworkLeaseReservation(networkID, "10.0.0.50")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -0,0 +1,265 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkLoadBalancers returns the load balancers of the network with the given ID. If localOnly is true only
+// the load balancers of the local cluster member are returned.
+func (c *Cluster) GetNetworkLoadBalancers(networkID int64, localOnly bool) ([]*api.NetworkLoadBalancer, error) {
+	q := `
+SELECT networks_load_balancers.id, networks_load_balancers.listen_address, networks_load_balancers.description, networks_load_balancers.backends, networks_load_balancers.ports, nodes.name
+  FROM networks_load_balancers
+  JOIN nodes ON nodes.id = networks_load_balancers.node_id
+  WHERE networks_load_balancers.network_id = ?
+`
+	args := []interface{}{networkID}
+	if localOnly {
+		q += "  AND networks_load_balancers.node_id = ?\n"
+		args = append(args, c.nodeID)
+	}
+
+	q += "  ORDER BY networks_load_balancers.id"
+
+	var id int64
+	var listenAddress, description, backendsJSON, portsJSON, location string
+	outfmt := []interface{}{id, listenAddress, description, backendsJSON, portsJSON, location}
+	results, err := queryScan(c, q, args, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	loadBalancers := make([]*api.NetworkLoadBalancer, 0, len(results))
+	for _, r := range results {
+		loadBalancer := &api.NetworkLoadBalancer{
+			ListenAddress: r[1].(string),
+			Location:      r[5].(string),
+		}
+		loadBalancer.Description = r[2].(string)
+
+		err = networkLoadBalancerUnmarshal(r[3].(string), r[4].(string), &loadBalancer.NetworkLoadBalancerPut)
+		if err != nil {
+			return nil, err
+		}
+
+		loadBalancer.Config, err = c.networkLoadBalancerConfig(r[0].(int64))
+		if err != nil {
+			return nil, err
+		}
+
+		loadBalancers = append(loadBalancers, loadBalancer)
+	}
+
+	return loadBalancers, nil
+}
+
+// GetNetworkLoadBalancer returns the load balancer of the network with the given ID and listen address. Listen
+// addresses are unique across the cluster, the cluster member the load balancer is applied on is returned in
+// Location.
+func (c *Cluster) GetNetworkLoadBalancer(networkID int64, listenAddress string) (int64, *api.NetworkLoadBalancer, error) {
+	var id int64 = -1
+	var backendsJSON, portsJSON string
+
+	loadBalancer := api.NetworkLoadBalancer{
+		ListenAddress: listenAddress,
+	}
+
+	q := `
+SELECT networks_load_balancers.id, networks_load_balancers.description, networks_load_balancers.backends, networks_load_balancers.ports, nodes.name
+  FROM networks_load_balancers
+  JOIN nodes ON nodes.id = networks_load_balancers.node_id
+  WHERE networks_load_balancers.network_id = ? AND networks_load_balancers.listen_address = ?
+`
+	arg1 := []interface{}{networkID, listenAddress}
+	arg2 := []interface{}{&id, &loadBalancer.Description, &backendsJSON, &portsJSON, &loadBalancer.Location}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	err = networkLoadBalancerUnmarshal(backendsJSON, portsJSON, &loadBalancer.NetworkLoadBalancerPut)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	loadBalancer.Config, err = c.networkLoadBalancerConfig(id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &loadBalancer, nil
+}
+
+// GetNetworkLoadBalancerListenAddresses returns the listen addresses of all the load balancers in the cluster,
+// keyed by listen address with the name of the cluster member using it as value.
+func (c *Cluster) GetNetworkLoadBalancerListenAddresses() (map[string]string, error) {
+	q := `
+SELECT networks_load_balancers.listen_address, nodes.name
+  FROM networks_load_balancers
+  JOIN nodes ON nodes.id = networks_load_balancers.node_id
+`
+	var listenAddress, location string
+	results, err := queryScan(c, q, nil, []interface{}{listenAddress, location})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string]string, len(results))
+	for _, r := range results {
+		addresses[r[0].(string)] = r[1].(string)
+	}
+
+	return addresses, nil
+}
+
+// CreateNetworkLoadBalancer creates a new load balancer for the network with the given ID, applied on the local
+// cluster member.
+func (c *Cluster) CreateNetworkLoadBalancer(networkID int64, info *api.NetworkLoadBalancersPost) (int64, error) {
+	backendsJSON, portsJSON, err := networkLoadBalancerMarshal(&info.NetworkLoadBalancerPut)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int64
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO networks_load_balancers (network_id, node_id, listen_address, description, backends, ports) VALUES (?, ?, ?, ?, ?, ?)", networkID, c.nodeID, info.ListenAddress, info.Description, backendsJSON, portsJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkLoadBalancerConfigAdd(tx.tx, id, info.Config)
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkLoadBalancer updates the load balancer with the given ID.
+func (c *Cluster) UpdateNetworkLoadBalancer(id int64, config *api.NetworkLoadBalancerPut) error {
+	backendsJSON, portsJSON, err := networkLoadBalancerMarshal(config)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_load_balancers SET description=?, backends=?, ports=? WHERE id=?", config.Description, backendsJSON, portsJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_load_balancers_config WHERE network_load_balancer_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkLoadBalancerConfigAdd(tx.tx, id, config.Config)
+	})
+}
+
+// DeleteNetworkLoadBalancer deletes the load balancer with the given ID.
+func (c *Cluster) DeleteNetworkLoadBalancer(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := query.DeleteObject(tx.tx, "networks_load_balancers", id)
+		return err
+	})
+}
+
+// networkLoadBalancerConfig returns the config of the load balancer with the given ID.
+func (c *Cluster) networkLoadBalancerConfig(id int64) (map[string]string, error) {
+	var config map[string]string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		config, err = query.SelectConfig(tx.tx, "networks_load_balancers_config", "network_load_balancer_id=?", id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading config: %v", err)
+	}
+
+	return config, nil
+}
+
+// networkLoadBalancerMarshal returns the JSON encoded backends and port specifications for storage.
+func networkLoadBalancerMarshal(config *api.NetworkLoadBalancerPut) (string, string, error) {
+	backends := config.Backends
+	if backends == nil {
+		backends = []api.NetworkLoadBalancerBackend{}
+	}
+
+	ports := config.Ports
+	if ports == nil {
+		ports = []api.NetworkLoadBalancerPort{}
+	}
+
+	backendsJSON, err := json.Marshal(backends)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed marshalling backends: %v", err)
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed marshalling ports: %v", err)
+	}
+
+	return string(backendsJSON), string(portsJSON), nil
+}
+
+// networkLoadBalancerUnmarshal decodes the stored JSON backends and port specifications.
+func networkLoadBalancerUnmarshal(backendsJSON string, portsJSON string, config *api.NetworkLoadBalancerPut) error {
+	config.Backends = []api.NetworkLoadBalancerBackend{}
+	err := json.Unmarshal([]byte(backendsJSON), &config.Backends)
+	if err != nil {
+		return fmt.Errorf("Failed unmarshalling backends: %v", err)
+	}
+
+	config.Ports = []api.NetworkLoadBalancerPort{}
+	err = json.Unmarshal([]byte(portsJSON), &config.Ports)
+	if err != nil {
+		return fmt.Errorf("Failed unmarshalling ports: %v", err)
+	}
+
+	return nil
+}
+
+// networkLoadBalancerConfigAdd inserts the config of the load balancer with the given ID.
+func networkLoadBalancerConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_load_balancers_config (network_load_balancer_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
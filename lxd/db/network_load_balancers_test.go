@@ -0,0 +1,74 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkLoadBalancers(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork("default", "lxdbr0", "", db.NetworkTypeBridge, nil)
+	require.NoError(t, err)
+
+	info := &api.NetworkLoadBalancersPost{
+		ListenAddress: "192.0.2.1",
+		NetworkLoadBalancerPut: api.NetworkLoadBalancerPut{
+			Description: "Web",
+			Backends: []api.NetworkLoadBalancerBackend{
+				{Name: "web1", TargetAddress: "10.0.0.2"},
+				{Name: "web2", TargetAddress: "10.0.0.3", TargetPort: "8080"},
+			},
+			Ports: []api.NetworkLoadBalancerPort{
+				{Protocol: "tcp", ListenPort: "80", TargetBackend: []string{"web1", "web2"}},
+			},
+			Config: map[string]string{"healthcheck": "true"},
+		},
+	}
+
+	id, err := cluster.CreateNetworkLoadBalancer(networkID, info)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	loadBalancers, err := cluster.GetNetworkLoadBalancers(networkID, true)
+	require.NoError(t, err)
+	require.Len(t, loadBalancers, 1)
+	assert.Equal(t, "192.0.2.1", loadBalancers[0].ListenAddress)
+	assert.Equal(t, info.Backends, loadBalancers[0].Backends)
+
+	addresses, err := cluster.GetNetworkLoadBalancerListenAddresses()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"192.0.2.1": "none"}, addresses)
+
+	gotID, loadBalancer, err := cluster.GetNetworkLoadBalancer(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, info.Ports, loadBalancer.Ports)
+	assert.Equal(t, map[string]string{"healthcheck": "true"}, loadBalancer.Config)
+
+	put := loadBalancer.Writable()
+	put.Backends = put.Backends[:1]
+	put.Ports[0].TargetBackend = []string{"web1"}
+	put.Config = nil
+	err = cluster.UpdateNetworkLoadBalancer(id, &put)
+	require.NoError(t, err)
+
+	_, loadBalancer, err = cluster.GetNetworkLoadBalancer(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Len(t, loadBalancer.Backends, 1)
+	assert.Equal(t, map[string]string{}, loadBalancer.Config)
+
+	err = cluster.DeleteNetworkLoadBalancer(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkLoadBalancer(networkID, "192.0.2.1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -0,0 +1,55 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// NetworkWireguardPeer is the Wireguard public key published by a cluster member for a network, along with the
+// cluster address of the member.
+type NetworkWireguardPeer struct {
+	NodeID      int64
+	NodeAddress string
+	PublicKey   string
+}
+
+// GetNetworkWireguardPeers returns the Wireguard peers published by the cluster members for the network with the
+// given ID.
+func (c *Cluster) GetNetworkWireguardPeers(networkID int64) ([]NetworkWireguardPeer, error) {
+	q := `
+SELECT nodes.id, nodes.address, networks_wireguard_peers.public_key
+  FROM networks_wireguard_peers
+  JOIN nodes ON nodes.id = networks_wireguard_peers.node_id
+  WHERE networks_wireguard_peers.network_id = ?
+  ORDER BY nodes.id
+`
+	var nodeID int64
+	var address, publicKey string
+	outfmt := []interface{}{nodeID, address, publicKey}
+	results, err := queryScan(c, q, []interface{}{networkID}, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]NetworkWireguardPeer, 0, len(results))
+	for _, r := range results {
+		peers = append(peers, NetworkWireguardPeer{
+			NodeID:      r[0].(int64),
+			NodeAddress: r[1].(string),
+			PublicKey:   r[2].(string),
+		})
+	}
+
+	return peers, nil
+}
+
+// UpsertNetworkWireguardPeer publishes the Wireguard public key of this member for the network with the given ID.
+func (c *Cluster) UpsertNetworkWireguardPeer(networkID int64, publicKey string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		columns := []string{"network_id", "node_id", "public_key"}
+		values := []interface{}{networkID, c.nodeID, publicKey}
+		_, err := query.UpsertObject(tx.tx, "networks_wireguard_peers", columns, values)
+		return err
+	})
+}
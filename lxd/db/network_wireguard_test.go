@@ -0,0 +1,45 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+func TestNetworkWireguardPeers(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork("default", "lxdbr0", "", db.NetworkTypeBridge, nil)
+	require.NoError(t, err)
+
+	peers, err := cluster.GetNetworkWireguardPeers(networkID)
+	require.NoError(t, err)
+	assert.Len(t, peers, 0)
+
+	err = cluster.UpsertNetworkWireguardPeer(networkID, "key1")
+	require.NoError(t, err)
+
+	// Publishing a new key replaces the existing one of the member.
+	err = cluster.UpsertNetworkWireguardPeer(networkID, "key2")
+	require.NoError(t, err)
+
+	peers, err = cluster.GetNetworkWireguardPeers(networkID)
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, cluster.GetNodeID(), peers[0].NodeID)
+	assert.Equal(t, "key2", peers[0].PublicKey)
+
+	// Peers are removed along with the network.
+	err = cluster.DeleteNetwork("default", "lxdbr0")
+	require.NoError(t, err)
+
+	peers, err = cluster.GetNetworkWireguardPeers(networkID)
+	require.NoError(t, err)
+	assert.Len(t, peers, 0)
+}
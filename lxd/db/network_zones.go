@@ -0,0 +1,162 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkZones returns the names of the network zones of the given project.
+func (c *Cluster) GetNetworkZones(project string) ([]string, error) {
+	q := `
+SELECT networks_zones.name
+  FROM networks_zones
+  JOIN projects ON projects.id = networks_zones.project_id
+  WHERE projects.name = ?
+  ORDER BY networks_zones.id
+`
+	var names []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		names, err = query.SelectStrings(tx.tx, q, project)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// GetNetworkZone returns the network zone with the given name along with the name of the project it belongs to.
+// Zone names are unique across all projects.
+func (c *Cluster) GetNetworkZone(name string) (int64, string, *api.NetworkZone, error) {
+	var id int64 = -1
+	var project string
+
+	zone := api.NetworkZone{
+		Name: name,
+	}
+
+	q := `
+SELECT networks_zones.id, projects.name, networks_zones.description
+  FROM networks_zones
+  JOIN projects ON projects.id = networks_zones.project_id
+  WHERE networks_zones.name = ?
+`
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &project, &zone.Description}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, "", nil, ErrNoSuchObject
+		}
+
+		return -1, "", nil, err
+	}
+
+	zone.Config, err = c.networkZoneConfig(id)
+	if err != nil {
+		return -1, "", nil, err
+	}
+
+	return id, project, &zone, nil
+}
+
+// CreateNetworkZone creates a new network zone in the given project.
+func (c *Cluster) CreateNetworkZone(project string, info *api.NetworkZonesPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		projectID, err := tx.GetProjectID(project)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.tx.Exec("INSERT INTO networks_zones (project_id, name, description) VALUES (?, ?, ?)", projectID, info.Name, info.Description)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkZoneConfigAdd(tx.tx, id, info.Config)
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkZone updates the network zone with the given ID.
+func (c *Cluster) UpdateNetworkZone(id int64, config *api.NetworkZonePut) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_zones SET description=? WHERE id=?", config.Description, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_zones_config WHERE network_zone_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkZoneConfigAdd(tx.tx, id, config.Config)
+	})
+}
+
+// DeleteNetworkZone deletes the network zone with the given ID.
+func (c *Cluster) DeleteNetworkZone(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := query.DeleteObject(tx.tx, "networks_zones", id)
+		return err
+	})
+}
+
+// networkZoneConfig returns the config of the network zone with the given ID.
+func (c *Cluster) networkZoneConfig(id int64) (map[string]string, error) {
+	var config map[string]string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		config, err = query.SelectConfig(tx.tx, "networks_zones_config", "network_zone_id=?", id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading config: %v", err)
+	}
+
+	return config, nil
+}
+
+// networkZoneConfigAdd inserts the config of the network zone with the given ID.
+func networkZoneConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_zones_config (network_zone_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
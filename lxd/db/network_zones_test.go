@@ -0,0 +1,56 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkZones(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	info := &api.NetworkZonesPost{
+		Name: "lxd.example.net",
+		NetworkZonePut: api.NetworkZonePut{
+			Description: "Instances",
+			Config:      map[string]string{"dns.nameservers": "ns1.example.net"},
+		},
+	}
+
+	id, err := cluster.CreateNetworkZone("default", info)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	names, err := cluster.GetNetworkZones("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lxd.example.net"}, names)
+
+	gotID, project, zone, err := cluster.GetNetworkZone("lxd.example.net")
+	require.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, "default", project)
+	assert.Equal(t, "Instances", zone.Description)
+	assert.Equal(t, map[string]string{"dns.nameservers": "ns1.example.net"}, zone.Config)
+
+	put := zone.Writable()
+	put.Config = map[string]string{"user.foo": "bar"}
+	err = cluster.UpdateNetworkZone(id, &put)
+	require.NoError(t, err)
+
+	_, _, zone, err = cluster.GetNetworkZone("lxd.example.net")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user.foo": "bar"}, zone.Config)
+
+	err = cluster.DeleteNetworkZone(id)
+	require.NoError(t, err)
+
+	_, _, _, err = cluster.GetNetworkZone("lxd.example.net")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -15,61 +15,94 @@ import (
 // GetNetworksLocalConfig returns a map associating each network name to its
 // node-specific config values on the local node (i.e. the ones where node_id
 // equals the ID of the local node).
+//
+// Networks are keyed by name only. Only networks backed by a host interface have node-specific config and
+// their names are unique across projects.
 func (c *ClusterTx) GetNetworksLocalConfig() (map[string]map[string]string, error) {
-	names, err := query.SelectStrings(c.tx, "SELECT name FROM networks")
+	networks, err := c.networkIDs("")
 	if err != nil {
 		return nil, err
 	}
-	networks := make(map[string]map[string]string, len(names))
-	for _, name := range names {
-		table := "networks_config JOIN networks ON networks.id=networks_config.network_id"
-		config, err := query.SelectConfig(
-			c.tx, table, "networks.name=? AND networks_config.node_id=?",
-			name, c.nodeID)
-		if err != nil {
-			return nil, err
+
+	configs := map[string]map[string]string{}
+	for _, ids := range networks {
+		for name, id := range ids {
+			config, err := query.SelectConfig(c.tx, "networks_config", "network_id=? AND node_id=?", id, c.nodeID)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(config) == 0 && configs[name] != nil {
+				continue
+			}
+
+			configs[name] = config
 		}
-		networks[name] = config
 	}
-	return networks, nil
+
+	return configs, nil
 }
 
-// GetNonPendingNetworkIDs returns a map associating each network name to its ID.
+// GetNonPendingNetworkIDs returns a map associating each project name to a map associating the name of each
+// network of the project to its ID.
 //
 // Pending networks are skipped.
-func (c *ClusterTx) GetNonPendingNetworkIDs() (map[string]int64, error) {
+func (c *ClusterTx) GetNonPendingNetworkIDs() (map[string]map[string]int64, error) {
+	return c.networkIDs("NOT networks.state=?", networkPending)
+}
+
+// Get the IDs of all networks matching the given WHERE filter (if given), grouped by project and name.
+func (c *ClusterTx) networkIDs(where string, args ...interface{}) (map[string]map[string]int64, error) {
 	networks := []struct {
-		id   int64
-		name string
+		id      int64
+		project string
+		name    string
 	}{}
 	dest := func(i int) []interface{} {
 		networks = append(networks, struct {
-			id   int64
-			name string
+			id      int64
+			project string
+			name    string
 		}{})
-		return []interface{}{&networks[i].id, &networks[i].name}
+		return []interface{}{&networks[i].id, &networks[i].project, &networks[i].name}
+	}
 
+	q := "SELECT networks.id, projects.name, networks.name FROM networks JOIN projects ON projects.id = networks.project_id"
+	if where != "" {
+		q += fmt.Sprintf(" WHERE %s", where)
 	}
-	stmt, err := c.tx.Prepare("SELECT id, name FROM networks WHERE NOT state=?")
+
+	stmt, err := c.tx.Prepare(q)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	err = query.SelectObjects(stmt, dest, networkPending)
+
+	err = query.SelectObjects(stmt, dest, args...)
 	if err != nil {
 		return nil, err
 	}
-	ids := map[string]int64{}
+
+	ids := map[string]map[string]int64{}
 	for _, network := range networks {
-		ids[network.name] = network.id
+		if ids[network.project] == nil {
+			ids[network.project] = map[string]int64{}
+		}
+
+		ids[network.project][network.name] = network.id
 	}
+
 	return ids, nil
 }
 
-// GetNetworkID returns the ID of the network with the given name.
-func (c *ClusterTx) GetNetworkID(name string) (int64, error) {
-	stmt := "SELECT id FROM networks WHERE name=?"
-	ids, err := query.SelectIntegers(c.tx, stmt, name)
+// GetNetworkID returns the ID of the network with the given name in the given project.
+func (c *ClusterTx) GetNetworkID(project string, name string) (int64, error) {
+	stmt := `
+SELECT networks.id FROM networks
+  JOIN projects ON projects.id = networks.project_id
+WHERE projects.name = ? AND networks.name = ?
+`
+	ids, err := query.SelectIntegers(c.tx, stmt, project, name)
 	if err != nil {
 		return -1, err
 	}
@@ -83,6 +116,68 @@ func (c *ClusterTx) GetNetworkID(name string) (int64, error) {
 	}
 }
 
+// GetNetworkProjects returns the names of the projects which have a network with the given name.
+func (c *ClusterTx) GetNetworkProjects(name string) ([]string, error) {
+	stmt := `
+SELECT projects.name FROM networks
+  JOIN projects ON projects.id = networks.project_id
+WHERE networks.name = ?
+ORDER BY projects.name
+`
+	return query.SelectStrings(c.tx, stmt, name)
+}
+
+// GetNetworkTypes returns a map associating the name of each project which has a network with the given name to
+// the type of that network.
+func (c *ClusterTx) GetNetworkTypes(name string) (map[string]NetworkType, error) {
+	stmt := `
+SELECT projects.name, networks.type FROM networks
+  JOIN projects ON projects.id = networks.project_id
+WHERE networks.name = ?
+`
+	rows, err := c.tx.Query(stmt, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := map[string]NetworkType{}
+	for rows.Next() {
+		var projectName string
+		var netType NetworkType
+
+		err := rows.Scan(&projectName, &netType)
+		if err != nil {
+			return nil, err
+		}
+
+		types[projectName] = netType
+	}
+
+	return types, rows.Err()
+}
+
+// GetProjectNetworksConfig returns a map associating the name of each network created in the given project to
+// its global config.
+func (c *ClusterTx) GetProjectNetworksConfig(project string) (map[string]map[string]string, error) {
+	networks, err := c.networkIDs("projects.name=?", project)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]map[string]string, len(networks[project]))
+	for name, id := range networks[project] {
+		config, err := query.SelectConfig(c.tx, "networks_config", "network_id=? AND node_id IS NULL", id)
+		if err != nil {
+			return nil, err
+		}
+
+		configs[name] = config
+	}
+
+	return configs, nil
+}
+
 // CreateNetworkConfig adds a new entry in the networks_config table
 func (c *ClusterTx) CreateNetworkConfig(networkID, nodeID int64, config map[string]string) error {
 	return networkConfigAdd(c.tx, networkID, nodeID, config)
@@ -147,9 +242,9 @@ WHERE networks.id = ? AND networks.state = ?
 	return configs, nil
 }
 
-// CreatePendingNetwork creates a new pending network on the node with
+// CreatePendingNetwork creates a new pending network in the given project on the node with
 // the given name.
-func (c *ClusterTx) CreatePendingNetwork(node, name string, netType NetworkType, conf map[string]string) error {
+func (c *ClusterTx) CreatePendingNetwork(node, project, name string, netType NetworkType, conf map[string]string) error {
 	// First check if a network with the given name exists, and, if
 	// so, that it's in the pending state.
 	network := struct {
@@ -165,12 +260,16 @@ func (c *ClusterTx) CreatePendingNetwork(node, name string, netType NetworkType,
 		}
 		return []interface{}{&network.id, &network.state}
 	}
-	stmt, err := c.tx.Prepare("SELECT id, state FROM networks WHERE name=?")
+	stmt, err := c.tx.Prepare(`
+SELECT networks.id, networks.state FROM networks
+  JOIN projects ON projects.id = networks.project_id
+WHERE projects.name = ? AND networks.name = ?
+`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	err = query.SelectObjects(stmt, dest, name)
+	err = query.SelectObjects(stmt, dest, project, name)
 	if err != nil {
 		return err
 	}
@@ -182,8 +281,13 @@ func (c *ClusterTx) CreatePendingNetwork(node, name string, netType NetworkType,
 	if networkID == 0 {
 		// No existing network with the given name was found, let's create
 		// one.
-		columns := []string{"name", "type"}
-		values := []interface{}{name, netType}
+		projectID, err := c.GetProjectID(project)
+		if err != nil {
+			return err
+		}
+
+		columns := []string{"project_id", "name", "type"}
+		values := []interface{}{projectID, name, netType}
 		networkID, err = query.UpsertObject(c.tx, "networks", columns, values)
 		if err != nil {
 			return err
@@ -227,18 +331,18 @@ func (c *ClusterTx) CreatePendingNetwork(node, name string, netType NetworkType,
 }
 
 // NetworkCreated sets the state of the given network to "Created".
-func (c *ClusterTx) NetworkCreated(name string) error {
-	return c.networkState(name, networkCreated)
+func (c *ClusterTx) NetworkCreated(project string, name string) error {
+	return c.networkState(project, name, networkCreated)
 }
 
 // NetworkErrored sets the state of the given network to "Errored".
-func (c *ClusterTx) NetworkErrored(name string) error {
-	return c.networkState(name, networkErrored)
+func (c *ClusterTx) NetworkErrored(project string, name string) error {
+	return c.networkState(project, name, networkErrored)
 }
 
-func (c *ClusterTx) networkState(name string, state int) error {
-	stmt := "UPDATE networks SET state=? WHERE name=?"
-	result, err := c.tx.Exec(stmt, state, name)
+func (c *ClusterTx) networkState(project string, name string, state int) error {
+	stmt := "UPDATE networks SET state=? WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND name=?"
+	result, err := c.tx.Exec(stmt, state, project, name)
 	if err != nil {
 		return err
 	}
@@ -252,20 +356,20 @@ func (c *ClusterTx) networkState(name string, state int) error {
 	return nil
 }
 
-// GetNetworks returns the names of existing networks.
-func (c *Cluster) GetNetworks() ([]string, error) {
+// GetNetworks returns a map associating each project name to the names of its existing networks.
+func (c *Cluster) GetNetworks() (map[string][]string, error) {
 	return c.networks("")
 }
 
-// GetNonPendingNetworks returns the names of all networks that are not
-// pending.
-func (c *Cluster) GetNonPendingNetworks() ([]string, error) {
-	return c.networks("NOT state=?", networkPending)
+// GetNonPendingNetworks returns a map associating each project name to the names of its networks that are
+// not pending.
+func (c *Cluster) GetNonPendingNetworks() (map[string][]string, error) {
+	return c.networks("NOT networks.state=?", networkPending)
 }
 
-// Get all networks matching the given WHERE filter (if given).
-func (c *Cluster) networks(where string, args ...interface{}) ([]string, error) {
-	q := "SELECT name FROM networks"
+// Get all networks matching the given WHERE filter (if given), grouped by project.
+func (c *Cluster) networks(where string, args ...interface{}) (map[string][]string, error) {
+	q := "SELECT projects.name, networks.name FROM networks JOIN projects ON projects.id = networks.project_id"
 	inargs := []interface{}{}
 
 	if where != "" {
@@ -275,16 +379,20 @@ func (c *Cluster) networks(where string, args ...interface{}) ([]string, error)
 		}
 	}
 
+	q += " ORDER BY projects.name, networks.name"
+
+	var project string
 	var name string
-	outfmt := []interface{}{name}
+	outfmt := []interface{}{project, name}
 	result, err := queryScan(c, q, inargs, outfmt)
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
 
-	response := []string{}
+	response := map[string][]string{}
 	for _, r := range result {
-		response = append(response, r[0].(string))
+		project := r[0].(string)
+		response[project] = append(response[project], r[1].(string))
 	}
 
 	return response, nil
@@ -302,36 +410,41 @@ type NetworkType int
 
 // Network types.
 const (
-	NetworkTypeBridge NetworkType = iota // Network type bridge.
+	NetworkTypeBridge   NetworkType = iota // Network type bridge.
+	NetworkTypeOVN                         // Network type ovn.
+	NetworkTypePhysical                    // Network type physical.
 )
 
-// GetNetwork returns the network with the given name.
+// GetNetwork returns the network with the given name in the given project.
 //
 // The network must be in the created stated, not pending.
-func (c *Cluster) GetNetwork(name string) (int64, *api.Network, error) {
-	return c.getNetwork(name, true)
+func (c *Cluster) GetNetwork(project string, name string) (int64, *api.Network, error) {
+	return c.getNetwork(project, name, true)
 }
 
-// GetNetworkInAnyState returns the network with the given name.
+// GetNetworkInAnyState returns the network with the given name in the given project.
 //
 // The network can be in any state.
-func (c *Cluster) GetNetworkInAnyState(name string) (int64, *api.Network, error) {
-	return c.getNetwork(name, false)
+func (c *Cluster) GetNetworkInAnyState(project string, name string) (int64, *api.Network, error) {
+	return c.getNetwork(project, name, false)
 }
 
-// Get the network with the given name. If onlyCreated is true, only return
+// Get the network with the given name in the given project. If onlyCreated is true, only return
 // networks in the created state.
-func (c *Cluster) getNetwork(name string, onlyCreated bool) (int64, *api.Network, error) {
+func (c *Cluster) getNetwork(project string, name string, onlyCreated bool) (int64, *api.Network, error) {
 	description := sql.NullString{}
 	id := int64(-1)
 	state := 0
 	var netType NetworkType
 
-	q := "SELECT id, description, state, type FROM networks WHERE name=?"
-	arg1 := []interface{}{name}
+	q := `
+SELECT networks.id, networks.description, networks.state, networks.type FROM networks
+  JOIN projects ON projects.id = networks.project_id
+WHERE projects.name = ? AND networks.name = ?`
+	arg1 := []interface{}{project, name}
 	arg2 := []interface{}{&id, &description, &state, &netType}
 	if onlyCreated {
-		q += " AND state=?"
+		q += " AND networks.state=?"
 		arg1 = append(arg1, networkCreated)
 	}
 	err := dbQueryRowScan(c, q, arg1, arg2)
@@ -369,6 +482,10 @@ func (c *Cluster) getNetwork(name string, onlyCreated bool) (int64, *api.Network
 	switch netType {
 	case NetworkTypeBridge:
 		network.Type = "bridge"
+	case NetworkTypeOVN:
+		network.Type = "ovn"
+	case NetworkTypePhysical:
+		network.Type = "physical"
 	default:
 		network.Type = "" // Unknown
 	}
@@ -491,11 +608,16 @@ func (c *Cluster) getNetworkConfig(id int64) (map[string]string, error) {
 	return config, nil
 }
 
-// CreateNetwork creates a new network.
-func (c *Cluster) CreateNetwork(name, description string, netType NetworkType, config map[string]string) (int64, error) {
+// CreateNetwork creates a new network in the given project.
+func (c *Cluster) CreateNetwork(project, name, description string, netType NetworkType, config map[string]string) (int64, error) {
 	var id int64
 	err := c.Transaction(func(tx *ClusterTx) error {
-		result, err := tx.tx.Exec("INSERT INTO networks (name, description, state, type) VALUES (?, ?, ?, ?)", name, description, networkCreated, netType)
+		projectID, err := tx.GetProjectID(project)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.tx.Exec("INSERT INTO networks (project_id, name, description, state, type) VALUES (?, ?, ?, ?, ?)", projectID, name, description, networkCreated, netType)
 		if err != nil {
 			return err
 		}
@@ -526,9 +648,9 @@ func (c *Cluster) CreateNetwork(name, description string, netType NetworkType, c
 	return id, err
 }
 
-// UpdateNetwork updates the network with the given name.
-func (c *Cluster) UpdateNetwork(name, description string, config map[string]string) error {
-	id, _, err := c.GetNetworkInAnyState(name)
+// UpdateNetwork updates the network with the given name in the given project.
+func (c *Cluster) UpdateNetwork(project, name, description string, config map[string]string) error {
+	id, _, err := c.GetNetworkInAnyState(project, name)
 	if err != nil {
 		return err
 	}
@@ -601,9 +723,9 @@ func clearNetworkConfig(tx *sql.Tx, networkID, nodeID int64) error {
 	return nil
 }
 
-// DeleteNetwork deletes the network with the given name.
-func (c *Cluster) DeleteNetwork(name string) error {
-	id, _, err := c.GetNetworkInAnyState(name)
+// DeleteNetwork deletes the network with the given name in the given project.
+func (c *Cluster) DeleteNetwork(project string, name string) error {
+	id, _, err := c.GetNetworkInAnyState(project, name)
 	if err != nil {
 		return err
 	}
@@ -616,9 +738,9 @@ func (c *Cluster) DeleteNetwork(name string) error {
 	return nil
 }
 
-// RenameNetwork renames a network.
-func (c *Cluster) RenameNetwork(oldName string, newName string) error {
-	id, _, err := c.GetNetworkInAnyState(oldName)
+// RenameNetwork renames a network in the given project.
+func (c *Cluster) RenameNetwork(project string, oldName string, newName string) error {
+	id, _, err := c.GetNetworkInAnyState(project, oldName)
 	if err != nil {
 		return err
 	}
@@ -634,4 +756,5 @@ func (c *Cluster) RenameNetwork(oldName string, newName string) error {
 // NodeSpecificNetworkConfig lists all network config keys which are node-specific.
 var NodeSpecificNetworkConfig = []string{
 	"bridge.external_interfaces",
+	"parent",
 }
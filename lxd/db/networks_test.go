@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,7 +16,7 @@ func TestGetNetworksLocalConfigs(t *testing.T) {
 	cluster, cleanup := db.NewTestCluster(t)
 	defer cleanup()
 
-	_, err := cluster.CreateNetwork("lxdbr0", "", db.NetworkTypeBridge, map[string]string{
+	_, err := cluster.CreateNetwork("default", "lxdbr0", "", db.NetworkTypeBridge, map[string]string{
 		"dns.mode":                   "none",
 		"bridge.external_interfaces": "vlan0",
 	})
@@ -45,15 +46,15 @@ func TestCreatePendingNetwork(t *testing.T) {
 	require.NoError(t, err)
 
 	config := map[string]string{"bridge.external_interfaces": "foo"}
-	err = tx.CreatePendingNetwork("buzz", "network1", db.NetworkTypeBridge, config)
+	err = tx.CreatePendingNetwork("buzz", "default", "network1", db.NetworkTypeBridge, config)
 	require.NoError(t, err)
 
-	networkID, err := tx.GetNetworkID("network1")
+	networkID, err := tx.GetNetworkID("default", "network1")
 	require.NoError(t, err)
 	assert.True(t, networkID > 0)
 
 	config = map[string]string{"bridge.external_interfaces": "bar"}
-	err = tx.CreatePendingNetwork("rusp", "network1", db.NetworkTypeBridge, config)
+	err = tx.CreatePendingNetwork("rusp", "default", "network1", db.NetworkTypeBridge, config)
 	require.NoError(t, err)
 
 	// The initial node (whose name is 'none' by default) is missing.
@@ -61,7 +62,7 @@ func TestCreatePendingNetwork(t *testing.T) {
 	require.EqualError(t, err, "Network not defined on nodes: none")
 
 	config = map[string]string{"bridge.external_interfaces": "egg"}
-	err = tx.CreatePendingNetwork("none", "network1", db.NetworkTypeBridge, config)
+	err = tx.CreatePendingNetwork("none", "default", "network1", db.NetworkTypeBridge, config)
 	require.NoError(t, err)
 
 	// Now the storage is defined on all nodes.
@@ -82,10 +83,10 @@ func TestNetworksCreatePending_AlreadyDefined(t *testing.T) {
 	_, err := tx.CreateNode("buzz", "1.2.3.4:666")
 	require.NoError(t, err)
 
-	err = tx.CreatePendingNetwork("buzz", "network1", db.NetworkTypeBridge, map[string]string{})
+	err = tx.CreatePendingNetwork("buzz", "default", "network1", db.NetworkTypeBridge, map[string]string{})
 	require.NoError(t, err)
 
-	err = tx.CreatePendingNetwork("buzz", "network1", db.NetworkTypeBridge, map[string]string{})
+	err = tx.CreatePendingNetwork("buzz", "default", "network1", db.NetworkTypeBridge, map[string]string{})
 	require.Equal(t, db.ErrAlreadyDefined, err)
 }
 
@@ -94,6 +95,43 @@ func TestNetworksCreatePending_NonExistingNode(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
 
-	err := tx.CreatePendingNetwork("buzz", "network1", db.NetworkTypeBridge, map[string]string{})
+	err := tx.CreatePendingNetwork("buzz", "default", "network1", db.NetworkTypeBridge, map[string]string{})
 	require.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Networks of different projects can share a name.
+func TestGetNetworkTypes(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		project := api.ProjectsPost{}
+		project.Name = "p1"
+		project.Config = map[string]string{"features.networks": "true"}
+		_, err := tx.CreateProject(project)
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = cluster.CreateNetwork("default", "net1", "", db.NetworkTypeBridge, nil)
+	require.NoError(t, err)
+
+	_, err = cluster.CreateNetwork("p1", "net1", "", db.NetworkTypeOVN, nil)
+	require.NoError(t, err)
+
+	_, err = cluster.CreateNetwork("p1", "net1", "", db.NetworkTypeOVN, nil)
+	require.Error(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		types, err := tx.GetNetworkTypes("net1")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]db.NetworkType{"default": db.NetworkTypeBridge, "p1": db.NetworkTypeOVN}, types)
+
+		projects, err := tx.GetNetworkProjects("net1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default", "p1"}, projects)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -25,12 +25,33 @@ type ClusterRole string
 // ClusterRoleDatabase represents the database role in a cluster.
 const ClusterRoleDatabase = ClusterRole("database")
 
+// ClusterRoleDatabaseStandBy represents the database stand-by role in a cluster.
+const ClusterRoleDatabaseStandBy = ClusterRole("database-standby")
+
+// ClusterRoleDatabaseLeader represents the database leader role in a cluster.
+const ClusterRoleDatabaseLeader = ClusterRole("database-leader")
+
+// ClusterRoleDatabasePinned marks a member which should always be a database voter.
+const ClusterRoleDatabasePinned = ClusterRole("database-pinned")
+
+// ClusterRoleDatabaseClient marks a member which should never hold a database role.
+const ClusterRoleDatabaseClient = ClusterRole("database-client")
+
 // ClusterRoles maps role ids into human-readable names.
 //
-// Note: the database role is currently stored directly in the raft
-// configuration which acts as single source of truth for it. This map should
+// Note: the database roles are currently stored directly in the raft
+// configuration which acts as single source of truth for them. This map should
 // only contain LXD-specific cluster roles.
-var ClusterRoles = map[int]ClusterRole{}
+var ClusterRoles = map[int]ClusterRole{
+	1: ClusterRoleDatabasePinned,
+	2: ClusterRoleDatabaseClient,
+}
+
+// Numeric values tracking the state of a cluster member.
+const (
+	ClusterMemberStateCreated   = 0
+	ClusterMemberStateEvacuated = 1
+)
 
 // NodeInfo holds information about a single LXD instance in a cluster.
 type NodeInfo struct {
@@ -43,6 +64,7 @@ type NodeInfo struct {
 	Heartbeat     time.Time // Timestamp of the last heartbeat
 	Roles         []string  // List of cluster roles
 	Architecture  int       // Node architecture
+	State         int       // Node state (created or evacuated)
 }
 
 // IsOffline returns true if the last successful heartbeat time of the node is
@@ -180,6 +202,25 @@ func (c *ClusterTx) NodeIsOutdated() (bool, error) {
 	return false, nil
 }
 
+// GetNodesAPIExtensionsCount returns the number of API extensions supported by
+// all the nodes of the cluster, that is the lowest API extensions count among
+// them.
+func (c *ClusterTx) GetNodesAPIExtensionsCount() (int, error) {
+	nodes, err := c.nodes(false /* not pending */, "")
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to fetch nodes")
+	}
+
+	count := -1
+	for _, node := range nodes {
+		if count == -1 || node.APIExtensions < count {
+			count = node.APIExtensions
+		}
+	}
+
+	return count, nil
+}
+
 // GetNodes returns all LXD nodes part of the cluster.
 //
 // If this LXD instance is not clustered, a list with a single node whose
@@ -277,6 +318,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 			&nodes[i].APIExtensions,
 			&nodes[i].Heartbeat,
 			&nodes[i].Architecture,
+			&nodes[i].State,
 		}
 	}
 	if pending {
@@ -286,7 +328,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 	}
 
 	// Get the node entries
-	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch FROM nodes WHERE pending=?"
+	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch, state FROM nodes WHERE pending=?"
 	if where != "" {
 		sql += fmt.Sprintf("AND %s ", where)
 	}
@@ -457,6 +499,50 @@ func (c *ClusterTx) UpdateNodeRoles(id int64, roles []ClusterRole) error {
 	return nil
 }
 
+// UpdateNodeDescription changes the description of a node.
+func (c *ClusterTx) UpdateNodeDescription(id int64, description string) error {
+	result, err := c.tx.Exec("UPDATE nodes SET description=? WHERE id=?", description, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("Query updated %d rows instead of 1", n)
+	}
+
+	return nil
+}
+
+// GetNodeConfig returns the user configuration of the node with the given ID.
+func (c *ClusterTx) GetNodeConfig(id int64) (map[string]string, error) {
+	return query.SelectConfig(c.tx, "nodes_config", "node_id=?", id)
+}
+
+// UpdateNodeConfig replaces the user configuration of the node with the given ID.
+func (c *ClusterTx) UpdateNodeConfig(id int64, config map[string]string) error {
+	_, err := c.tx.Exec("DELETE FROM nodes_config WHERE node_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range config {
+		if value == "" {
+			continue
+		}
+
+		_, err := c.tx.Exec("INSERT INTO nodes_config (node_id, key, value) VALUES (?, ?, ?)", id, key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // UpdateNodeFailureDomain changes the failure domain of a node.
 func (c *ClusterTx) UpdateNodeFailureDomain(id int64, domain string) error {
 	var domainID interface{}
@@ -756,22 +842,48 @@ func (c *ClusterTx) GetNodeOfflineThreshold() (time.Duration, error) {
 // GetNodeWithLeastInstances returns the name of the non-offline node with with
 // the least number of containers (either already created or being created with
 // an operation). If archs is not empty, then return only nodes with an
-// architecture in that list.
-func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
+// architecture in that list. If groups is not empty, then return only nodes
+// which are a member of one of those cluster groups.
+func (c *ClusterTx) GetNodeWithLeastInstances(archs []int, groups []string) (string, error) {
+	nodes, err := c.GetCandidateMembers(archs, groups)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+	containers := -1
+	for _, node := range nodes {
+		count, err := c.GetNodeInstancesCount(node.ID)
+		if err != nil {
+			return "", err
+		}
+
+		if containers == -1 || count < containers {
+			containers = count
+			name = node.Name
+		}
+	}
+	return name, nil
+}
+
+// GetCandidateMembers returns the non-offline and non-evacuated nodes which
+// instances can be placed on. If archs is not empty, then return only nodes with an
+// architecture in that list. If groups is not empty, then return only nodes
+// which are a member of one of those cluster groups.
+func (c *ClusterTx) GetCandidateMembers(archs []int, groups []string) ([]NodeInfo, error) {
 	threshold, err := c.GetNodeOfflineThreshold()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get offline threshold")
+		return nil, errors.Wrap(err, "failed to get offline threshold")
 	}
 
 	nodes, err := c.GetNodes()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get current nodes")
+		return nil, errors.Wrap(err, "failed to get current nodes")
 	}
 
-	name := ""
-	containers := -1
+	candidates := []NodeInfo{}
 	for _, node := range nodes {
-		if node.IsOffline(threshold) {
+		if node.IsOffline(threshold) || node.State == ClusterMemberStateEvacuated {
 			continue
 		}
 
@@ -779,7 +891,7 @@ func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
 			// Get personalities too.
 			personalities, err := osarch.ArchitecturePersonalities(node.Architecture)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 
 			supported := []int{node.Architecture}
@@ -797,26 +909,66 @@ func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
 			}
 		}
 
-		// Fetch the number of containers already created on this node.
-		created, err := query.Count(c.tx, "instances", "node_id=?", node.ID)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to get instances count")
-		}
+		if len(groups) > 0 {
+			nodeGroups, err := c.GetNodeClusterGroups(node.ID)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to get cluster groups")
+			}
 
-		// Fetch the number of containers currently being created on this node.
-		pending, err := query.Count(
-			c.tx, "operations", "node_id=? AND type=?", node.ID, OperationContainerCreate)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to get pending instances count")
-		}
+			match := false
+			for _, group := range nodeGroups {
+				if shared.StringInSlice(group, groups) {
+					match = true
+				}
+			}
 
-		count := created + pending
-		if containers == -1 || count < containers {
-			containers = count
-			name = node.Name
+			if !match {
+				continue
+			}
 		}
+
+		candidates = append(candidates, node)
 	}
-	return name, nil
+
+	return candidates, nil
+}
+
+// GetNodeInstancesCount returns the number of instances on the node with the
+// given ID, either already created or being created with an operation.
+func (c *ClusterTx) GetNodeInstancesCount(id int64) (int, error) {
+	// Fetch the number of containers already created on this node.
+	created, err := query.Count(c.tx, "instances", "node_id=?", id)
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to get instances count")
+	}
+
+	// Fetch the number of containers currently being created on this node.
+	pending, err := query.Count(
+		c.tx, "operations", "node_id=? AND type=?", id, OperationContainerCreate)
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to get pending instances count")
+	}
+
+	return created + pending, nil
+}
+
+// UpdateNodeState updates the state of the node with the given ID.
+func (c *ClusterTx) UpdateNodeState(id int64, state int) error {
+	result, err := c.tx.Exec("UPDATE nodes SET state=? WHERE id=?", state, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update node state")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get affected rows")
+	}
+
+	if n != 1 {
+		return fmt.Errorf("Expected exactly one row to be updated")
+	}
+
+	return nil
 }
 
 // SetNodeVersion updates the schema and API version of the node with the
@@ -8,6 +8,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/version"
 	"github.com/stretchr/testify/assert"
@@ -114,6 +115,25 @@ func TestNodeIsOutdated_OneNodeWithLowerVersion(t *testing.T) {
 	assert.False(t, outdated)
 }
 
+func TestGetNodesAPIExtensionsCount(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	count, err := tx.GetNodesAPIExtensionsCount()
+	require.NoError(t, err)
+	assert.Equal(t, len(version.APIExtensions), count)
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.SetNodeVersion(id, [2]int{cluster.SchemaVersion, len(version.APIExtensions) - 1})
+	require.NoError(t, err)
+
+	count, err = tx.GetNodesAPIExtensionsCount()
+	require.NoError(t, err)
+	assert.Equal(t, len(version.APIExtensions)-1, count)
+}
+
 func TestGetLocalNodeName(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
@@ -314,7 +334,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 `)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -338,7 +358,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	err = tx.SetNodeHeartbeat("0.0.0.0", time.Now().Add(-time.Minute))
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -358,7 +378,7 @@ INSERT INTO operations (id, uuid, node_id, type, project_id) VALUES (1, 'abc', 1
 `, db.OperationContainerCreate)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -387,11 +407,173 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	require.NoError(t, err)
 
 	// The local node is returned despite it has more containers.
-	name, err := tx.GetNodeWithLeastInstances([]int{localArch})
+	name, err := tx.GetNodeWithLeastInstances([]int{localArch}, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "none", name)
 }
 
+// If cluster groups are given, only nodes which are a member of one of them
+// are returned.
+func TestGetNodeWithLeastInstances_ClusterGroups(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.CreateClusterGroup(api.ClusterGroupsPost{
+		Name: "ssd",
+		ClusterGroupPut: api.ClusterGroupPut{
+			Members: []string{"none"},
+		},
+	})
+	require.NoError(t, err)
+
+	// Add a container to the default node (ID 1)
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	// The local node is returned despite it has more containers.
+	name, err := tx.GetNodeWithLeastInstances(nil, []string{"ssd"})
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+}
+
+func TestGetCandidateMembers(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.CreateClusterGroup(api.ClusterGroupsPost{
+		Name: "ssd",
+		ClusterGroupPut: api.ClusterGroupPut{
+			Members: []string{"buzz"},
+		},
+	})
+	require.NoError(t, err)
+
+	nodes, err := tx.GetCandidateMembers(nil, nil)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	nodes, err = tx.GetCandidateMembers(nil, []string{"ssd"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "buzz", nodes[0].Name)
+}
+
+func TestUpdateNodeState(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeState(id, db.ClusterMemberStateEvacuated)
+	require.NoError(t, err)
+
+	node, err := tx.GetNodeByName("buzz")
+	require.NoError(t, err)
+	assert.Equal(t, db.ClusterMemberStateEvacuated, node.State)
+
+	// Evacuated nodes aren't candidates for new instances.
+	nodes, err := tx.GetCandidateMembers(nil, nil)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "none", nodes[0].Name)
+}
+
+func TestUpdateNodeRoles(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeRoles(id, []db.ClusterRole{db.ClusterRoleDatabasePinned})
+	require.NoError(t, err)
+
+	node, err := tx.GetNodeByName("buzz")
+	require.NoError(t, err)
+	assert.Equal(t, []string{string(db.ClusterRoleDatabasePinned)}, node.Roles)
+
+	err = tx.UpdateNodeRoles(id, []db.ClusterRole{db.ClusterRoleDatabaseClient})
+	require.NoError(t, err)
+
+	node, err = tx.GetNodeByName("buzz")
+	require.NoError(t, err)
+	assert.Equal(t, []string{string(db.ClusterRoleDatabaseClient)}, node.Roles)
+
+	// The database role is tracked by raft, not stored.
+	err = tx.UpdateNodeRoles(id, []db.ClusterRole{db.ClusterRoleDatabase})
+	assert.EqualError(t, err, "Invalid cluster role 'database'")
+}
+
+func TestGetNodeInstancesCount(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	// Add a container to the default node (ID 1)
+	_, err := tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	// Add a pending container creation to the default node
+	_, err = tx.CreateOperation("", "abcd", db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	count, err := tx.GetNodeInstancesCount(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestUpdateNodeDescription(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeDescription(id, "Rack 1, slot 3")
+	require.NoError(t, err)
+
+	node, err := tx.GetNodeByName("buzz")
+	require.NoError(t, err)
+	assert.Equal(t, "Rack 1, slot 3", node.Description)
+}
+
+func TestUpdateNodeConfig(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	config, err := tx.GetNodeConfig(id)
+	require.NoError(t, err)
+	assert.Empty(t, config)
+
+	err = tx.UpdateNodeConfig(id, map[string]string{"user.rack": "a1", "user.gpu": "nvidia"})
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeConfig(id, map[string]string{"user.rack": "a2", "user.gpu": ""})
+	require.NoError(t, err)
+
+	config, err = tx.GetNodeConfig(id)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user.rack": "a2"}, config)
+
+	// Other nodes are unaffected.
+	config, err = tx.GetNodeConfig(1)
+	require.NoError(t, err)
+	assert.Empty(t, config)
+}
+
 func TestUpdateNodeFailureDomain(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
@@ -16,6 +16,7 @@ type Operation struct {
 	UUID        string        // User-visible identifier
 	NodeAddress string        // Address of the node the operation is running on
 	Type        OperationType // Type of the operation
+	Project     string        // Name of the project the operation belongs to, if any
 }
 
 // GetLocalOperations returns all operations associated with this node.
@@ -23,6 +24,12 @@ func (c *ClusterTx) GetLocalOperations() ([]Operation, error) {
 	return c.operations("node_id=?", c.nodeID)
 }
 
+// GetOperationsOfProject returns all operations in the cluster which belong to
+// the given project.
+func (c *ClusterTx) GetOperationsOfProject(project string) ([]Operation, error) {
+	return c.operations("projects.name=?", project)
+}
+
 // GetLocalOperationsUUIDs returns the UUIDs of all operations associated with this
 // node.
 func (c *ClusterTx) GetLocalOperationsUUIDs() ([]string, error) {
@@ -114,10 +121,13 @@ func (c *ClusterTx) operations(where string, args ...interface{}) ([]Operation,
 			&operations[i].UUID,
 			&operations[i].NodeAddress,
 			&operations[i].Type,
+			&operations[i].Project,
 		}
 	}
 	sql := `
-SELECT operations.id, uuid, nodes.address, type FROM operations JOIN nodes ON nodes.id = node_id `
+SELECT operations.id, uuid, nodes.address, type, COALESCE(projects.name, '') FROM operations
+  JOIN nodes ON nodes.id = node_id
+  LEFT OUTER JOIN projects ON projects.id = operations.project_id `
 	if where != "" {
 		sql += fmt.Sprintf("WHERE %s ", where)
 	}
@@ -28,6 +28,11 @@ func TestOperation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "default", operation.Project)
+
+	operations, err = tx.GetOperationsOfProject("default")
+	require.NoError(t, err)
+	assert.Len(t, operations, 1)
 
 	uuids, err := tx.GetLocalOperationsUUIDs()
 	require.NoError(t, err)
@@ -58,6 +63,11 @@ func TestOperationNoProject(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "", operation.Project)
+
+	operations, err = tx.GetOperationsOfProject("default")
+	require.NoError(t, err)
+	assert.Len(t, operations, 0)
 
 	uuids, err := tx.GetLocalOperationsUUIDs()
 	require.NoError(t, err)
@@ -56,6 +56,15 @@ const (
 	OperationBackupsExpire
 	OperationSnapshotsExpire
 	OperationCustomVolumeSnapshotsExpire
+	OperationBackupVerify
+	OperationClusterMemberEvacuate
+	OperationClusterMemberRestore
+	OperationClusterJoinToken
+	OperationClusterHeal
+	OperationCertificateAddToken
+	OperationInstancesStateUpdate
+	OperationProjectDelete
+	OperationInstanceRemap
 )
 
 // Description return a human-readable description of the operation type.
@@ -153,6 +162,24 @@ func (t OperationType) Description() string {
 		return "Cleaning up expired instance snapshots"
 	case OperationCustomVolumeSnapshotsExpire:
 		return "Cleaning up expired volume snapshots"
+	case OperationBackupVerify:
+		return "Verifying instance backup"
+	case OperationClusterMemberEvacuate:
+		return "Evacuating cluster member"
+	case OperationClusterMemberRestore:
+		return "Restoring cluster member"
+	case OperationClusterJoinToken:
+		return "Cluster join token"
+	case OperationClusterHeal:
+		return "Healing cluster"
+	case OperationCertificateAddToken:
+		return "Certificate add token"
+	case OperationInstancesStateUpdate:
+		return "Updating instances state"
+	case OperationProjectDelete:
+		return "Deleting project"
+	case OperationInstanceRemap:
+		return "Remapping instance filesystem"
 	default:
 		return "Executing operation"
 	}
@@ -169,6 +196,8 @@ func (t OperationType) Permission() string {
 		return "operate-containers"
 	case OperationBackupRemove:
 		return "operate-containers"
+	case OperationBackupVerify:
+		return "operate-containers"
 	case OperationConsoleShow:
 		return "operate-containers"
 	case OperationContainerFreeze:
@@ -181,6 +210,8 @@ func (t OperationType) Permission() string {
 		return "operate-containers"
 	case OperationContainerRestart:
 		return "operate-containers"
+	case OperationInstancesStateUpdate:
+		return "operate-containers"
 	case OperationCommandExec:
 		return "operate-containers"
 	case OperationSnapshotCreate:
@@ -208,6 +239,8 @@ func (t OperationType) Permission() string {
 		return "manage-containers"
 	case OperationSnapshotRestore:
 		return "manage-containers"
+	case OperationInstanceRemap:
+		return "manage-containers"
 
 	case OperationImageDownload:
 		return "manage-images"
@@ -5,6 +5,7 @@ package db
 import (
 	"fmt"
 
+	"github.com/lxc/lxd/lxd/db/query"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/pkg/errors"
@@ -62,6 +63,7 @@ type Profile struct {
 	Config      map[string]string
 	Devices     map[string]map[string]string
 	UsedBy      []string
+	Parents     []string `db:"ignore"`
 }
 
 // ProfileToAPI is a convenience to convert a Profile db struct into
@@ -74,6 +76,7 @@ func ProfileToAPI(profile *Profile) *api.Profile {
 	p.Description = profile.Description
 	p.Config = profile.Config
 	p.Devices = profile.Devices
+	p.Parents = profile.Parents
 
 	return p
 }
@@ -152,7 +155,7 @@ func (c *ClusterTx) getProfile(project, name string) (int64, *api.Profile, error
 		project = "default"
 	}
 
-	profile, err := c.GetProfile(project, name)
+	profile, err := c.GetProfileWithParents(project, name)
 	if err != nil {
 		return -1, nil, err
 	}
@@ -163,9 +166,21 @@ func (c *ClusterTx) getProfile(project, name string) (int64, *api.Profile, error
 	return id, result, nil
 }
 
-// GetProfiles returns the profiles with the given names in the given project.
+// GetProfiles returns the profiles with the given names in the given project, with the config and devices
+// of their parents applied.
 func (c *Cluster) GetProfiles(project string, names []string) ([]api.Profile, error) {
-	profiles := make([]api.Profile, len(names))
+	profiles, err := c.GetProjectProfiles(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExpandProfiles(names, profiles)
+}
+
+// GetProjectProfiles returns all the profiles usable in the given project, indexed by name. The config and
+// devices of their parents aren't applied.
+func (c *Cluster) GetProjectProfiles(project string) (map[string]api.Profile, error) {
+	profiles := map[string]api.Profile{}
 
 	err := c.Transaction(func(tx *ClusterTx) error {
 		enabled, err := tx.ProjectHasProfiles(project)
@@ -176,12 +191,13 @@ func (c *Cluster) GetProfiles(project string, names []string) ([]api.Profile, er
 			project = "default"
 		}
 
-		for i, name := range names {
-			profile, err := tx.GetProfile(project, name)
-			if err != nil {
-				return errors.Wrapf(err, "Load profile %q", name)
-			}
-			profiles[i] = *ProfileToAPI(profile)
+		dbProfiles, err := tx.GetProfilesWithParents(ProfileFilter{Project: project})
+		if err != nil {
+			return errors.Wrap(err, "Load profiles")
+		}
+
+		for _, profile := range dbProfiles {
+			profiles[profile.Name] = *ProfileToAPI(&profile)
 		}
 
 		return nil
@@ -193,6 +209,168 @@ func (c *Cluster) GetProfiles(project string, names []string) ([]api.Profile, er
 	return profiles, nil
 }
 
+// GetProfileWithParents is like GetProfile, but also loads the parents of the profile.
+func (c *ClusterTx) GetProfileWithParents(project string, name string) (*Profile, error) {
+	profile, err := c.GetProfile(project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Parents, err = query.SelectStrings(c.tx, `
+SELECT profiles.name FROM profiles_parents
+  JOIN profiles ON profiles.id=profiles_parents.parent_id
+ WHERE profiles_parents.profile_id=?
+ ORDER BY profiles_parents.position`, profile.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Load profile parents")
+	}
+
+	return profile, nil
+}
+
+// GetProfilesWithParents is like GetProfiles, but also loads the parents of each profile.
+func (c *ClusterTx) GetProfilesWithParents(filter ProfileFilter) ([]Profile, error) {
+	profiles, err := c.GetProfiles(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.tx.Query(`
+SELECT profiles_parents.profile_id, profiles.name FROM profiles_parents
+  JOIN profiles ON profiles.id=profiles_parents.parent_id
+ ORDER BY profiles_parents.profile_id, profiles_parents.position`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Load profile parents")
+	}
+	defer rows.Close()
+
+	parents := map[int][]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return nil, err
+		}
+
+		parents[id] = append(parents[id], name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range profiles {
+		profiles[i].Parents = parents[profiles[i].ID]
+	}
+
+	return profiles, nil
+}
+
+// UpdateProfileParents replaces the parents of the profile with the given name, which must all be profiles of
+// the same project.
+func (c *ClusterTx) UpdateProfileParents(project string, name string, parents []string) error {
+	id, err := c.GetProfileID(project, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM profiles_parents WHERE profile_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for i, parent := range parents {
+		parentID, err := c.GetProfileID(project, parent)
+		if err != nil {
+			return errors.Wrapf(err, "Load parent profile %q", parent)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO profiles_parents (profile_id, parent_id, position) VALUES (?, ?, ?)", id, parentID, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpandProfile returns the profile with the given name with the config and devices of its parents applied
+// underneath its own, along with the names of the profiles in the order they were applied. Parents are applied
+// in the order they're listed, each after its own parents, and only once when inherited multiple times.
+func ExpandProfile(name string, profiles map[string]api.Profile) (*api.Profile, []string, error) {
+	order := []string{}
+	applied := map[string]bool{}
+	expanding := map[string]bool{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if applied[name] {
+			return nil
+		}
+
+		if expanding[name] {
+			return fmt.Errorf("Profile %q inherits from itself", name)
+		}
+
+		profile, ok := profiles[name]
+		if !ok {
+			return fmt.Errorf("Profile %q doesn't exist", name)
+		}
+
+		expanding[name] = true
+		for _, parent := range profile.Parents {
+			err := visit(parent)
+			if err != nil {
+				return err
+			}
+		}
+		delete(expanding, name)
+
+		applied[name] = true
+		order = append(order, name)
+
+		return nil
+	}
+
+	err := visit(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expanded := profiles[name]
+	expanded.Config = map[string]string{}
+	expanded.Devices = map[string]map[string]string{}
+	for _, name := range order {
+		for k, v := range profiles[name].Config {
+			expanded.Config[k] = v
+		}
+
+		for k, v := range profiles[name].Devices {
+			expanded.Devices[k] = v
+		}
+	}
+
+	return &expanded, order, nil
+}
+
+// ExpandProfiles returns the profiles with the given names with the config and devices of their parents
+// applied, as done by ExpandProfile.
+func ExpandProfiles(names []string, profiles map[string]api.Profile) ([]api.Profile, error) {
+	expanded := make([]api.Profile, len(names))
+	for i, name := range names {
+		profile, _, err := ExpandProfile(name, profiles)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Load profile %q", name)
+		}
+
+		expanded[i] = *profile
+	}
+
+	return expanded, nil
+}
+
 // GetInstancesWithProfile gets the names of the instance associated with the
 // profile with the given name in the given project.
 func (c *Cluster) GetInstancesWithProfile(project, profile string) (map[string][]string, error) {
@@ -246,6 +424,7 @@ func (c *Cluster) RemoveUnreferencedProfiles() error {
 DELETE FROM profiles_config WHERE profile_id NOT IN (SELECT id FROM profiles);
 DELETE FROM profiles_devices WHERE profile_id NOT IN (SELECT id FROM profiles);
 DELETE FROM profiles_devices_config WHERE profile_device_id NOT IN (SELECT id FROM profiles_devices);
+DELETE FROM profiles_parents WHERE profile_id NOT IN (SELECT id FROM profiles) OR parent_id NOT IN (SELECT id FROM profiles);
 `
 	err := exec(c, stmt)
 	if err != nil {
@@ -0,0 +1,86 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileParents(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, name := range []string{"base", "net", "web"} {
+			_, err := tx.CreateProfile(db.Profile{Project: "default", Name: name})
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.UpdateProfileParents("default", "web", []string{"net", "base"})
+	})
+	require.NoError(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		profile, err := tx.GetProfileWithParents("default", "web")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"net", "base"}, profile.Parents)
+
+		err = tx.UpdateProfileParents("default", "web", []string{"missing"})
+		assert.Error(t, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestExpandProfile(t *testing.T) {
+	profiles := map[string]api.Profile{
+		"base": {
+			Name: "base",
+			ProfilePut: api.ProfilePut{
+				Config:  map[string]string{"limits.cpu": "1", "limits.memory": "1GiB"},
+				Devices: map[string]map[string]string{"root": {"type": "disk", "path": "/", "pool": "default"}},
+			},
+		},
+		"net": {
+			Name: "net",
+			ProfilePut: api.ProfilePut{
+				Config:  map[string]string{"limits.cpu": "2"},
+				Devices: map[string]map[string]string{"eth0": {"type": "nic", "network": "lxdbr0"}},
+				Parents: []string{"base"},
+			},
+		},
+		"web": {
+			Name: "web",
+			ProfilePut: api.ProfilePut{
+				Config:  map[string]string{"limits.memory": "2GiB"},
+				Devices: map[string]map[string]string{"root": {"type": "disk", "path": "/", "pool": "fast"}},
+				Parents: []string{"net", "base"},
+			},
+		},
+	}
+
+	expanded, order, err := db.ExpandProfile("web", profiles)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "net", "web"}, order)
+	assert.Equal(t, map[string]string{"limits.cpu": "2", "limits.memory": "2GiB"}, expanded.Config)
+	assert.Equal(t, "fast", expanded.Devices["root"]["pool"])
+	assert.Equal(t, "lxdbr0", expanded.Devices["eth0"]["network"])
+
+	// The profiles themselves are left untouched.
+	assert.Equal(t, map[string]string{"limits.memory": "2GiB"}, profiles["web"].Config)
+
+	base := profiles["base"]
+	base.Parents = []string{"web"}
+	profiles["base"] = base
+
+	_, _, err = db.ExpandProfile("web", profiles)
+	assert.EqualError(t, err, `Profile "web" inherits from itself`)
+}
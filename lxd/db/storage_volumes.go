@@ -366,6 +366,40 @@ func (c *Cluster) RenameStoragePoolVolume(project, oldVolumeName string, newVolu
 	return err
 }
 
+// MoveStoragePoolVolume moves the record of a volume to another storage pool and replaces its config. The
+// records of the volume's snapshots follow it as they reference the volume by ID. The volume itself must be
+// moved on storage by the caller.
+func (c *ClusterTx) MoveStoragePoolVolume(project, volumeName string, volumeType int, poolID, newPoolID int64, volumeConfig map[string]string) error {
+	// Shared pools like ceph have a record of the volume for each node.
+	volumeIDs, err := storageVolumeIDsGet(c.tx, project, volumeName, volumeType, poolID)
+	if err != nil {
+		return err
+	}
+
+	if len(volumeIDs) == 0 {
+		return ErrNoSuchObject
+	}
+
+	for _, volumeID := range volumeIDs {
+		_, err = c.tx.Exec("UPDATE storage_volumes SET storage_pool_id=? WHERE id=?", newPoolID, volumeID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to update volume's storage pool")
+		}
+
+		err = storageVolumeConfigClear(c.tx, volumeID, false)
+		if err != nil {
+			return err
+		}
+
+		err = storageVolumeConfigAdd(c.tx, volumeID, volumeConfig, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // This a convenience to replicate a certain volume change to all nodes if the
 // underlying driver is ceph.
 func storagePoolVolumeReplicateIfCeph(tx *sql.Tx, volumeID int64, project, volumeName string, volumeType int, poolID int64, f func(int64) error) error {
@@ -740,68 +774,98 @@ SELECT storage_volumes_snapshots.name FROM storage_volumes_snapshots
 	return max
 }
 
-// StorageVolumeIsAvailable checks that if a custom volume available for being attached.
-//
-// Always return true for non-Ceph volumes.
-//
-// For Ceph volumes, return true if the volume is either not attached to any
-// other container, or attached to containers on this node.
-func (c *Cluster) StorageVolumeIsAvailable(pool, volume string) (bool, error) {
-	isAvailable := false
+// StorageVolumeAttachment describes an instance disk device using a custom
+// storage volume.
+type StorageVolumeAttachment struct {
+	Project  string
+	Instance string
+	Device   string
+	Node     string
+	ReadOnly bool
+	Remote   bool // Whether the instance is located on another node.
+}
+
+// GetStorageVolumeAttachments returns all the instance disk devices that use
+// the given custom volume of the given project and pool, including devices
+// inherited from profiles.
+func (c *Cluster) GetStorageVolumeAttachments(project, pool, volume string) ([]StorageVolumeAttachment, error) {
+	var attachments []StorageVolumeAttachment
 
 	err := c.Transaction(func(tx *ClusterTx) error {
-		id, err := tx.GetStoragePoolID(pool)
-		if err != nil {
-			return errors.Wrapf(err, "Fetch storage pool ID for %q", pool)
-		}
+		var err error
+		attachments, err = tx.GetStorageVolumeAttachments(project, pool, volume)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		driver, err := tx.GetStoragePoolDriver(id)
-		if err != nil {
-			return errors.Wrapf(err, "Fetch storage pool driver for %q", pool)
-		}
+	return attachments, nil
+}
 
-		if driver != "ceph" {
-			isAvailable = true
-			return nil
-		}
+// GetStorageVolumeAttachments returns all the instance disk devices that use
+// the given custom volume of the given project and pool, including devices
+// inherited from profiles.
+func (c *ClusterTx) GetStorageVolumeAttachments(project, pool, volume string) ([]StorageVolumeAttachment, error) {
+	node, err := c.GetLocalNodeName()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch node name")
+	}
 
-		node, err := tx.GetLocalNodeName()
-		if err != nil {
-			return errors.Wrapf(err, "Fetch node name")
+	instances, err := c.instanceListExpanded()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch instances")
+	}
+
+	projects, err := c.GetProjects(ProjectFilter{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch projects")
+	}
+
+	// Map to check which projects have the storage volumes feature on.
+	projectHasVolumes := map[string]bool{}
+	for _, project := range projects {
+		projectHasVolumes[project.Name] = shared.IsTrue(project.Config["features.storage.volumes"])
+	}
+
+	attachments := []StorageVolumeAttachment{}
+	for _, instance := range instances {
+		// If the instance's project does not have the storage volumes feature
+		// enabled, its custom volumes are the ones of the default project.
+		volumeProject := instance.Project
+		if !projectHasVolumes[volumeProject] {
+			volumeProject = "default"
 		}
 
-		containers, err := tx.instanceListExpanded()
-		if err != nil {
-			return errors.Wrapf(err, "Fetch instances")
+		if volumeProject != project {
+			continue
 		}
 
-		for _, container := range containers {
-			for _, device := range container.Devices {
-				if device["type"] != "disk" {
-					continue
-				}
-				if device["pool"] != pool {
-					continue
-				}
-				if device["source"] != volume {
-					continue
-				}
-				if container.Node != node {
-					// This ceph volume is already attached
-					// to a container on a different node.
-					return nil
-				}
+		for name, device := range instance.Devices {
+			if device["type"] != "disk" {
+				continue
 			}
-		}
-		isAvailable = true
 
-		return nil
-	})
-	if err != nil {
-		return false, err
+			if device["pool"] != pool {
+				continue
+			}
+
+			if device["source"] != volume {
+				continue
+			}
+
+			attachments = append(attachments, StorageVolumeAttachment{
+				Project:  instance.Project,
+				Instance: instance.Name,
+				Device:   name,
+				Node:     instance.Node,
+				ReadOnly: shared.IsTrue(device["readonly"]),
+				Remote:   instance.Node != node,
+			})
+		}
 	}
 
-	return isAvailable, nil
+	return attachments, nil
 }
 
 // Updates the description of a storage volume.
@@ -4,8 +4,10 @@ package db_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,3 +57,75 @@ INSERT INTO storage_volumes(storage_pool_id, node_id, name, type, project_id) VA
 	_, err := tx.Tx().Exec(stmt, poolID, nodeID, name)
 	require.NoError(t, err)
 }
+
+// All disk devices using a custom volume are returned, flagging the ones on
+// other nodes.
+func TestGetStorageVolumeAttachments(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	nodeID1 := int64(1) // This is the default local node
+
+	nodeID2, err := tx.CreateNode("node2", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	addContainer(t, tx, nodeID1, "c1")
+	addContainer(t, tx, nodeID2, "c2")
+	addContainer(t, tx, nodeID2, "c3")
+
+	addContainerDevice(t, tx, "c1", "data", "disk", map[string]string{"pool": "pool1", "source": "vol1", "path": "/data"})
+	addContainerDevice(t, tx, "c2", "data", "disk", map[string]string{"pool": "pool1", "source": "vol1", "path": "/data", "readonly": "true"})
+	addContainerDevice(t, tx, "c3", "data", "disk", map[string]string{"pool": "pool1", "source": "vol2", "path": "/data"})
+
+	// A volume with the same name in a project with its own storage volumes.
+	projectID, err := tx.CreateProject(api.ProjectsPost{
+		Name:       "p1",
+		ProjectPut: api.ProjectPut{Config: map[string]string{"features.storage.volumes": "true"}},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.Tx().Exec("INSERT INTO instances(node_id, name, architecture, type, project_id) VALUES (?, 'c4', 1, 0, ?)", nodeID1, projectID)
+	require.NoError(t, err)
+
+	addContainerDevice(t, tx, "c4", "data", "disk", map[string]string{"pool": "pool1", "source": "vol1", "path": "/data"})
+
+	attachments, err := tx.GetStorageVolumeAttachments("default", "pool1", "vol1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.StorageVolumeAttachment{
+		{Project: "default", Instance: "c1", Device: "data", Node: "none", ReadOnly: false, Remote: false},
+		{Project: "default", Instance: "c2", Device: "data", Node: "node2", ReadOnly: true, Remote: true},
+	}, attachments)
+}
+
+// A volume moves to another pool along with its snapshots, and its config is replaced.
+func TestMoveStoragePoolVolume(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.CreateStoragePool("pool1", "", "dir", nil)
+	require.NoError(t, err)
+	newPoolID, err := cluster.CreateStoragePool("pool2", "", "lvm", nil)
+	require.NoError(t, err)
+
+	_, err = cluster.CreateStoragePoolVolume("default", "c1", "", db.StoragePoolVolumeTypeContainer, poolID, map[string]string{"size": "10GB"}, db.StoragePoolVolumeContentTypeFS)
+	require.NoError(t, err)
+	_, err = cluster.CreateStorageVolumeSnapshot("default", "c1/snap0", "", db.StoragePoolVolumeTypeContainer, poolID, nil, time.Time{})
+	require.NoError(t, err)
+
+	config := map[string]string{"size": "10GB", "block.filesystem": "ext4"}
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.MoveStoragePoolVolume("default", "c1", db.StoragePoolVolumeTypeContainer, poolID, newPoolID, config)
+	})
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetLocalStoragePoolVolume("default", "c1", db.StoragePoolVolumeTypeContainer, poolID)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, vol, err := cluster.GetLocalStoragePoolVolume("default", "c1", db.StoragePoolVolumeTypeContainer, newPoolID)
+	require.NoError(t, err)
+	assert.Equal(t, config, vol.Config)
+
+	_, _, err = cluster.GetLocalStoragePoolVolume("default", "c1/snap0", db.StoragePoolVolumeTypeContainer, newPoolID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,223 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Severities of warnings.
+const (
+	WarningSeverityLow      = "low"
+	WarningSeverityModerate = "moderate"
+	WarningSeverityHigh     = "high"
+)
+
+// Statuses of warnings.
+const (
+	WarningStatusNew          = "new"
+	WarningStatusAcknowledged = "acknowledged"
+	WarningStatusResolved     = "resolved"
+)
+
+// WarningStatuses lists the valid warning statuses.
+var WarningStatuses = []string{WarningStatusNew, WarningStatusAcknowledged, WarningStatusResolved}
+
+// GetWarnings returns all warnings in the cluster. If project is not empty,
+// only the warnings of that project are returned.
+func (c *ClusterTx) GetWarnings(project string) ([]api.Warning, error) {
+	if project != "" {
+		return c.warnings("projects.name=?", project)
+	}
+
+	return c.warnings("")
+}
+
+// GetWarning returns the warning with the given UUID.
+func (c *ClusterTx) GetWarning(uuid string) (*api.Warning, error) {
+	warnings, err := c.warnings("warnings.uuid=?", uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(warnings) {
+	case 0:
+		return nil, ErrNoSuchObject
+	case 1:
+		return &warnings[0], nil
+	default:
+		return nil, fmt.Errorf("More than one warning matches")
+	}
+}
+
+// UpsertWarningLocalNode records an occurrence of a warning on this node.
+//
+// Warnings are identified by their project, module, type and the URL of the
+// entity they are about, if any. If a matching
+// warning already exists, its count, last seen date and message are updated and
+// it is reopened if it was resolved, otherwise a new warning is created.
+func (c *ClusterTx) UpsertWarningLocalNode(project string, module string, typ string, entityURL string, severity string, message string) error {
+	projectID, err := c.warningProjectID(project)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	var id int64
+	var status string
+	stmt := "SELECT id, status FROM warnings WHERE node_id=? AND project_id IS ? AND module=? AND type=? AND entity_url=?"
+	err = c.tx.QueryRow(stmt, c.nodeID, projectID, module, typ, entityURL).Scan(&id, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "Failed to fetch existing warning")
+	}
+
+	if err == sql.ErrNoRows {
+		stmt := `
+INSERT INTO warnings (uuid, node_id, project_id, module, type, entity_url, severity, status, count, first_seen_date, last_seen_date, last_message)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?)
+`
+		_, err = c.tx.Exec(stmt, uuid.NewRandom().String(), c.nodeID, projectID, module, typ, entityURL, severity, WarningStatusNew, now, now, message)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create warning")
+		}
+
+		return nil
+	}
+
+	if status == WarningStatusResolved {
+		status = WarningStatusNew
+	}
+
+	stmt = "UPDATE warnings SET severity=?, status=?, count=count+1, last_seen_date=?, last_message=? WHERE id=?"
+	_, err = c.tx.Exec(stmt, severity, status, now, message, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update warning")
+	}
+
+	return nil
+}
+
+// ResolveWarningsLocalNode marks the warnings of this node with the given
+// project, module, type and entity URL as resolved.
+func (c *ClusterTx) ResolveWarningsLocalNode(project string, module string, typ string, entityURL string) error {
+	projectID, err := c.warningProjectID(project)
+	if err != nil {
+		return err
+	}
+
+	stmt := "UPDATE warnings SET status=? WHERE node_id=? AND project_id IS ? AND module=? AND type=? AND entity_url=? AND status!=?"
+	_, err = c.tx.Exec(stmt, WarningStatusResolved, c.nodeID, projectID, module, typ, entityURL, WarningStatusResolved)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve warnings")
+	}
+
+	return nil
+}
+
+// UpdateWarningStatus sets the status of the warning with the given UUID.
+func (c *ClusterTx) UpdateWarningStatus(uuid string, status string) error {
+	result, err := c.tx.Exec("UPDATE warnings SET status=? WHERE uuid=?", status, uuid)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// DeleteWarning deletes the warning with the given UUID.
+func (c *ClusterTx) DeleteWarning(uuid string) error {
+	result, err := c.tx.Exec("DELETE FROM warnings WHERE uuid=?", uuid)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// warningProjectID returns the ID of the given project, or nil if no project is given.
+func (c *ClusterTx) warningProjectID(project string) (interface{}, error) {
+	if project == "" {
+		return nil, nil
+	}
+
+	projectID, err := c.GetProjectID(project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch project ID")
+	}
+
+	return projectID, nil
+}
+
+// warnings returns all warnings in the cluster, filtered by the given clause.
+func (c *ClusterTx) warnings(where string, args ...interface{}) ([]api.Warning, error) {
+	warnings := []api.Warning{}
+	dest := func(i int) []interface{} {
+		warnings = append(warnings, api.Warning{})
+		return []interface{}{
+			&warnings[i].UUID,
+			&warnings[i].Location,
+			&warnings[i].Project,
+			&warnings[i].Module,
+			&warnings[i].Type,
+			&warnings[i].EntityURL,
+			&warnings[i].Severity,
+			&warnings[i].Status,
+			&warnings[i].Count,
+			&warnings[i].FirstSeenAt,
+			&warnings[i].LastSeenAt,
+			&warnings[i].LastMessage,
+		}
+	}
+
+	q := `
+SELECT warnings.uuid, COALESCE(nodes.name, ''), COALESCE(projects.name, ''), warnings.module, warnings.type,
+       warnings.entity_url, warnings.severity, warnings.status, warnings.count, warnings.first_seen_date, warnings.last_seen_date,
+       warnings.last_message
+  FROM warnings
+  LEFT OUTER JOIN nodes ON nodes.id = warnings.node_id
+  LEFT OUTER JOIN projects ON projects.id = warnings.project_id `
+	if where != "" {
+		q += fmt.Sprintf("WHERE %s ", where)
+	}
+	q += "ORDER BY warnings.last_seen_date DESC, warnings.id"
+
+	stmt, err := c.tx.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch warnings")
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,80 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+func TestWarnings(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	err := tx.UpsertWarningLocalNode("", "apparmor", "unavailable", "", db.WarningSeverityHigh, "Missing kernel support")
+	require.NoError(t, err)
+
+	err = tx.UpsertWarningLocalNode("default", "network", "startup", "/1.0/networks/lxdbr0", db.WarningSeverityHigh, "Failed to bring up network")
+	require.NoError(t, err)
+
+	err = tx.UpsertWarningLocalNode("", "apparmor", "unavailable", "", db.WarningSeverityHigh, "Missing apparmor_parser")
+	require.NoError(t, err)
+
+	warnings, err := tx.GetWarnings("")
+	require.NoError(t, err)
+	assert.Len(t, warnings, 2)
+
+	warnings, err = tx.GetWarnings("default")
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "network", warnings[0].Module)
+	assert.Equal(t, "/1.0/networks/lxdbr0", warnings[0].EntityURL)
+
+	warnings, err = tx.GetWarnings("")
+	require.NoError(t, err)
+
+	var uuid string
+	for _, warning := range warnings {
+		if warning.Module == "apparmor" {
+			uuid = warning.UUID
+		}
+	}
+
+	warning, err := tx.GetWarning(uuid)
+	require.NoError(t, err)
+	assert.Equal(t, 2, warning.Count)
+	assert.Equal(t, "Missing apparmor_parser", warning.LastMessage)
+	assert.Equal(t, db.WarningStatusNew, warning.Status)
+
+	err = tx.UpdateWarningStatus(uuid, db.WarningStatusAcknowledged)
+	require.NoError(t, err)
+
+	err = tx.ResolveWarningsLocalNode("", "apparmor", "unavailable", "")
+	require.NoError(t, err)
+
+	warning, err = tx.GetWarning(uuid)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusResolved, warning.Status)
+
+	// A new occurrence reopens a resolved warning.
+	err = tx.UpsertWarningLocalNode("", "apparmor", "unavailable", "", db.WarningSeverityHigh, "Missing apparmor_parser")
+	require.NoError(t, err)
+
+	warning, err = tx.GetWarning(uuid)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusNew, warning.Status)
+	assert.Equal(t, 3, warning.Count)
+
+	err = tx.DeleteWarning(uuid)
+	require.NoError(t, err)
+
+	_, err = tx.GetWarning(uuid)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.DeleteWarning(uuid)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
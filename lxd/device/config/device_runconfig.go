@@ -9,6 +9,9 @@ const MountOwnerShiftDynamic = "dynamic"
 // MountOwnerShiftStatic statically modify ownership.
 const MountOwnerShiftStatic = "static"
 
+// MountOptNoDiscard disables discard (TRIM) pass-through for a VM drive.
+const MountOptNoDiscard = "nodiscard"
+
 // RunConfigItem represents a single config item.
 type RunConfigItem struct {
 	Key   string
@@ -20,11 +20,16 @@ func (device Device) Clone() Device {
 }
 
 // NICType returns the derived NIC Type for a NIC device.
-// If the "network" property is specified then this implicitly (at least for now) means the nictype is "bridged".
-// Otherwise the "nictype" property is returned. If the device type is not a NIC then an empty string is returned.
+// If the "network" property is specified then this implicitly means the nictype is "bridged", unless the
+// "nictype" property is set to "ovn" to connect to an OVN network. Otherwise the "nictype" property is returned.
+// If the device type is not a NIC then an empty string is returned.
 func (device Device) NICType() string {
 	if device["type"] == "nic" {
 		if device["network"] != "" {
+			if device["nictype"] == "ovn" {
+				return "ovn"
+			}
+
 			return "bridged"
 		}
 
@@ -0,0 +1,60 @@
+package device
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Legacy ethtool commands to toggle offloads (from linux/ethtool.h).
+var ethtoolOffloadCmds = map[string]uint32{
+	"tso": 0x0000001f, // ETHTOOL_STSO
+	"gso": 0x00000024, // ETHTOOL_SGSO
+	"gro": 0x0000002c, // ETHTOOL_SGRO
+}
+
+type ethtoolReq struct {
+	name [16]byte
+	data uintptr
+}
+
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+// networkSetOffload enables or disables an offload ("tso", "gso" or "gro") on an interface.
+func networkSetOffload(devName string, offload string, enabled bool) error {
+	cmd, ok := ethtoolOffloadCmds[offload]
+	if !ok {
+		return fmt.Errorf("Unknown offload %q", offload)
+	}
+
+	ethtoolFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, unix.IPPROTO_IP)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open IPPROTO_IP socket")
+	}
+	defer unix.Close(ethtoolFd)
+
+	value := ethtoolValue{
+		cmd: cmd,
+	}
+
+	if enabled {
+		value.data = 1
+	}
+
+	req := ethtoolReq{
+		data: uintptr(unsafe.Pointer(&value)),
+	}
+	copy(req.name[:], []byte(devName))
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(ethtoolFd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return errors.Wrapf(unix.Errno(errno), "Failed to set %s offload on %q", offload, devName)
+	}
+
+	return nil
+}
@@ -1,11 +1,19 @@
 package device
 
 import (
+	"crypto/rand"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -160,3 +168,98 @@ func infinibandSetDevMAC(ibDev string, hwaddr string) error {
 
 	return fmt.Errorf("Invalid length")
 }
+
+// infinibandValidGUID validates an infiniband GUID, e.g. "00:16:3e:00:00:2a:f0:01".
+func infinibandValidGUID(value string) error {
+	regexGUID, err := regexp.Compile("^([0-9a-fA-F]{2}:){7}[0-9a-fA-F]{2}$")
+	if err != nil {
+		return err
+	}
+
+	if !regexGUID.MatchString(value) {
+		return fmt.Errorf("Invalid value, must be 8 bytes of hex separated by colons")
+	}
+
+	if value == "00:00:00:00:00:00:00:00" || strings.ToLower(value) == "ff:ff:ff:ff:ff:ff:ff:ff" {
+		return fmt.Errorf("Invalid value, the GUID is reserved")
+	}
+
+	return nil
+}
+
+// infinibandRandomGUID returns a random infiniband GUID using the same OUI as the MAC addresses generated by LXD.
+func infinibandRandomGUID() (string, error) {
+	buf := make([]byte, 5)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("00:16:3e:%02x:%02x:%02x:%02x:%02x", buf[0], buf[1], buf[2], buf[3], buf[4]), nil
+}
+
+// infinibandVFID returns the index of the virtual function of the parent device whose interface is vfName.
+func infinibandVFID(parent string, vfName string) (int, error) {
+	paths, err := filepath.Glob(fmt.Sprintf("/sys/class/net/%s/device/virtfn*/net/%s", parent, vfName))
+	if err != nil {
+		return -1, err
+	}
+
+	if len(paths) != 1 {
+		return -1, fmt.Errorf("Interface %q isn't a virtual function of %q", vfName, parent)
+	}
+
+	// Extract the index from the "virtfnN" path component.
+	virtfn := filepath.Base(filepath.Dir(filepath.Dir(paths[0])))
+	vfID, err := strconv.Atoi(strings.TrimPrefix(virtfn, "virtfn"))
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed parsing virtual function index of %q", vfName)
+	}
+
+	return vfID, nil
+}
+
+// infinibandSetVFGUID sets the node and port GUIDs of the virtual function vfID of the parent device. The VF is
+// unbound from its driver while the GUIDs are programmed, as the HCA only picks them up when the VF is probed,
+// and is always bound back so it isn't orphaned. The function waits for the VF interface vfName to re-appear.
+func infinibandSetVFGUID(parent string, vfID int, vfName string, guid string) error {
+	vfPCIDev, err := pciParseUeventFile(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/uevent", parent, vfID))
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = pciDeviceUnbind(vfPCIDev)
+	if err != nil {
+		return err
+	}
+
+	// However we return from this function, we must try to rebind the VF so its not orphaned.
+	revert.Add(func() { pciDeviceProbe(vfPCIDev) })
+
+	for _, guidType := range []string{"node_guid", "port_guid"} {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", parent, "vf", fmt.Sprintf("%d", vfID), guidType, guid)
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting the %s of VF %d", strings.Replace(guidType, "_", " ", -1), vfID)
+		}
+	}
+
+	err = pciDeviceProbe(vfPCIDev)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return networkInterfaceBindWait(vfName)
+}
+
+// infinibandHwaddrGUID returns the port GUID held in the last 8 bytes of a 20 byte infiniband hwaddr.
+func infinibandHwaddrGUID(hwaddr string) string {
+	if len(hwaddr) != 59 {
+		return ""
+	}
+
+	return hwaddr[36:]
+}
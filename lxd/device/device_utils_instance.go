@@ -2,6 +2,7 @@ package device
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
@@ -40,6 +41,10 @@ func instanceGetReservedDevices(s *state.State, m deviceConfig.Device) (map[stri
 			// host_name as reserved, as that device is using it as a SR-IOV VF.
 			if devConfig["type"] == m["type"] && parent == m["parent"] {
 				hostName := config[fmt.Sprintf("volatile.%s.host_name", devName)]
+				if devConfig.NICType() == "bond" {
+					// The VF of a bond NIC is used by its primary.
+					hostName = config[fmt.Sprintf("volatile.%s.primary.host_name", devName)]
+				}
 				if hostName != "" {
 					reservedDevices[hostName] = struct{}{}
 				}
@@ -50,6 +55,77 @@ func instanceGetReservedDevices(s *state.State, m deviceConfig.Device) (map[stri
 	return reservedDevices, nil
 }
 
+// gpuSlicesMutex used to coordinate the allocation of GPU slices across instances.
+var gpuSlicesMutex sync.Mutex
+
+// instanceGetReservedGPUSlices returns a map of the GPU slices (NVIDIA MIG instances and mediated devices) used by
+// the GPU devices of the other instances on the local node. The caller must hold gpuSlicesMutex until the slice
+// it picks is recorded in the volatile config of its device.
+func instanceGetReservedGPUSlices(s *state.State, inst instance.Instance) (map[string]struct{}, error) {
+	instances, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedSlices := map[string]struct{}{}
+	for _, other := range instances {
+		if other.Project() == inst.Project() && other.Name() == inst.Name() {
+			continue
+		}
+
+		config := other.ExpandedConfig()
+		for devName, devConfig := range other.ExpandedDevices() {
+			if devConfig["type"] != "gpu" {
+				continue
+			}
+
+			slice := config[fmt.Sprintf("volatile.%s.last_state.gpu.slice", devName)]
+			if slice != "" {
+				reservedSlices[slice] = struct{}{}
+			}
+		}
+	}
+
+	return reservedSlices, nil
+}
+
+// infinibandGUIDsMutex used to coordinate the allocation of infiniband GUIDs across instances.
+var infinibandGUIDsMutex sync.Mutex
+
+// instanceGetReservedInfinibandGUIDs returns a map of the infiniband GUIDs configured on or assigned to the
+// infiniband devices of the other instances on the local node, keyed by lower case GUID with the instance and
+// device using it as value. The caller must hold infinibandGUIDsMutex until the GUID it picks is recorded in the
+// volatile config of its device.
+func instanceGetReservedInfinibandGUIDs(s *state.State, inst instance.Instance) (map[string]string, error) {
+	instances, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedGUIDs := map[string]string{}
+	for _, other := range instances {
+		if other.Project() == inst.Project() && other.Name() == inst.Name() {
+			continue
+		}
+
+		config := other.ExpandedConfig()
+		for devName, devConfig := range other.ExpandedDevices() {
+			if devConfig["type"] != "infiniband" {
+				continue
+			}
+
+			user := fmt.Sprintf("%s/%s (project %s)", other.Name(), devName, other.Project())
+			for _, guid := range []string{devConfig["guid"], config[fmt.Sprintf("volatile.%s.guid", devName)]} {
+				if guid != "" {
+					reservedGUIDs[strings.ToLower(guid)] = user
+				}
+			}
+		}
+	}
+
+	return reservedGUIDs, nil
+}
+
 // instanceSupported is a helper function to check instance type is supported for validation.
 // Always returns true if supplied instance type is Any, to support profile validation.
 func instanceSupported(instType instancetype.Type, supportedTypes ...instancetype.Type) bool {
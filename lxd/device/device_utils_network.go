@@ -13,10 +13,12 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
@@ -231,7 +233,18 @@ func networkRandomDevName(prefix string) string {
 func networkCreateVethPair(hostName string, m deviceConfig.Device) (string, error) {
 	peerName := networkRandomDevName("veth")
 
-	_, err := shared.RunCommand("ip", "link", "add", "dev", hostName, "type", "veth", "peer", "name", peerName)
+	// Create the pair with the requested number of queues on both ends.
+	queues := []string{}
+	if m["queues"] != "" {
+		queues = []string{"numtxqueues", m["queues"], "numrxqueues", m["queues"]}
+	}
+
+	args := []string{"link", "add", "dev", hostName}
+	args = append(args, queues...)
+	args = append(args, "type", "veth", "peer", "name", peerName)
+	args = append(args, queues...)
+
+	_, err := shared.RunCommand("ip", args...)
 	if err != nil {
 		return "", fmt.Errorf("Failed to create the veth interfaces %s and %s: %v", hostName, peerName, err)
 	}
@@ -293,7 +306,14 @@ func networkCreateVethPair(hostName string, m deviceConfig.Device) (string, erro
 
 // networkCreateTap creates and configures a TAP device.
 func networkCreateTap(hostName string, m deviceConfig.Device) error {
-	_, err := shared.RunCommand("ip", "tuntap", "add", "name", hostName, "mode", "tap")
+	args := []string{"tuntap", "add", "name", hostName, "mode", "tap"}
+
+	// Multiple queues need a multi-queue TAP, QEMU opens one file descriptor per queue.
+	if m["queues"] != "" && m["queues"] != "1" {
+		args = append(args, "multi_queue")
+	}
+
+	_, err := shared.RunCommand("ip", args...)
 	if err != nil {
 		return errors.Wrapf(err, "Failed to create the tap interfaces %s", hostName)
 	}
@@ -357,6 +377,12 @@ func networkSetupHostVethDevice(device deviceConfig.Device, oldDevice deviceConf
 		return err
 	}
 
+	// Apply queue length and offload settings.
+	err = networkSetHostTuning(device)
+	if err != nil {
+		return err
+	}
+
 	// If oldDevice provided, remove old routes if any remain.
 	if oldDevice != nil {
 		// If not configured, copy the volatile host_name into old device to support live updates.
@@ -381,6 +407,31 @@ func networkSetupHostVethDevice(device deviceConfig.Device, oldDevice deviceConf
 	return nil
 }
 
+// networkSetHostTuning applies the txqueuelen and offload.* settings to the host side interface. Offloads which
+// aren't configured are left to the kernel defaults.
+func networkSetHostTuning(m deviceConfig.Device) error {
+	if m["txqueuelen"] != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", m["host_name"], "txqueuelen", m["txqueuelen"])
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting txqueuelen on %q", m["host_name"])
+		}
+	}
+
+	for _, offload := range []string{"gso", "tso", "gro"} {
+		value := m[fmt.Sprintf("offload.%s", offload)]
+		if value == "" {
+			continue
+		}
+
+		err := networkSetOffload(m["host_name"], offload, shared.IsTrue(value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // networkSetVethRoutes applies any static routes configured from the host to the container nic.
 func networkSetVethRoutes(m deviceConfig.Device) error {
 	// Decide whether the route should point to the veth parent or the bridge parent.
@@ -463,65 +514,121 @@ func networkRemoveVethRoutes(m deviceConfig.Device) {
 
 // networkSetVethLimits applies any network rate limits to the veth device specified in the config.
 func networkSetVethLimits(m deviceConfig.Device) error {
-	var err error
-
 	veth := m["host_name"]
 	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", veth)) {
 		return fmt.Errorf("Unknown or missing host side veth: %s", veth)
 	}
 
+	ingressInt, egressInt, err := networkParseLimits(m)
+	if err != nil {
+		return err
+	}
+
+	// The host side veth sends the traffic coming into the instance and receives the traffic leaving it.
+	err = networkSetDeviceLimits(veth, ingressInt, egressInt)
+	if err != nil {
+		return err
+	}
+
+	if m["limits.multicast"] != "" {
+		multicastInt, err := units.ParseBitSizeString(m["limits.multicast"])
+		if err != nil {
+			return err
+		}
+
+		err = networkSetDeviceMulticastLimit(veth, multicastInt, egressInt > 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkSetDeviceMulticastLimit polices the multicast and broadcast traffic received by the interface to rate
+// (in bit/s). It is added to the ingress qdisc, which is created unless hasIngress indicates it already exists.
+func networkSetDeviceMulticastLimit(devName string, rate int64, hasIngress bool) error {
+	if !hasIngress {
+		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", devName, "handle", "ffff:0", "ingress")
+		if err != nil {
+			return fmt.Errorf("Failed to create ingress tc qdisc: %s", out)
+		}
+	}
+
+	// Match on the group bit of the destination MAC address, ahead of any overall limit.
+	out, err := shared.RunCommand("tc", "filter", "add", "dev", devName, "parent", "ffff:0", "protocol", "all", "prio", "1", "u32", "match", "u8", "0x01", "0x01", "at", "-14", "police", "rate", fmt.Sprintf("%dbit", rate), "burst", "64k", "mtu", "64kb", "drop")
+	if err != nil {
+		return fmt.Errorf("Failed to create multicast tc filter: %s", out)
+	}
+
+	return nil
+}
+
+// networkParseLimits returns the ingress and egress limits (in bit/s) of the NIC config, 0 meaning unlimited.
+func networkParseLimits(m deviceConfig.Device) (int64, int64, error) {
+	var err error
+
+	ingress := m["limits.ingress"]
+	egress := m["limits.egress"]
+
 	// Apply max limit
 	if m["limits.max"] != "" {
-		m["limits.ingress"] = m["limits.max"]
-		m["limits.egress"] = m["limits.max"]
+		ingress = m["limits.max"]
+		egress = m["limits.max"]
 	}
 
 	// Parse the values
 	var ingressInt int64
-	if m["limits.ingress"] != "" {
-		ingressInt, err = units.ParseBitSizeString(m["limits.ingress"])
+	if ingress != "" {
+		ingressInt, err = units.ParseBitSizeString(ingress)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 	}
 
 	var egressInt int64
-	if m["limits.egress"] != "" {
-		egressInt, err = units.ParseBitSizeString(m["limits.egress"])
+	if egress != "" {
+		egressInt, err = units.ParseBitSizeString(egress)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 	}
 
+	return ingressInt, egressInt, nil
+}
+
+// networkSetDeviceLimits replaces the tc rate limits of the interface. The traffic sent by the interface is
+// shaped to sendRate and the traffic it receives is policed to receiveRate (in bit/s, 0 meaning unlimited).
+func networkSetDeviceLimits(devName string, sendRate int64, receiveRate int64) error {
 	// Clean any existing entry
-	shared.RunCommand("tc", "qdisc", "del", "dev", veth, "root")
-	shared.RunCommand("tc", "qdisc", "del", "dev", veth, "ingress")
+	shared.RunCommand("tc", "qdisc", "del", "dev", devName, "root")
+	shared.RunCommand("tc", "qdisc", "del", "dev", devName, "ingress")
 
 	// Apply new limits
-	if m["limits.ingress"] != "" {
-		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", veth, "root", "handle", "1:0", "htb", "default", "10")
+	if sendRate > 0 {
+		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", devName, "root", "handle", "1:0", "htb", "default", "10")
 		if err != nil {
 			return fmt.Errorf("Failed to create root tc qdisc: %s", out)
 		}
 
-		out, err = shared.RunCommand("tc", "class", "add", "dev", veth, "parent", "1:0", "classid", "1:10", "htb", "rate", fmt.Sprintf("%dbit", ingressInt))
+		out, err = shared.RunCommand("tc", "class", "add", "dev", devName, "parent", "1:0", "classid", "1:10", "htb", "rate", fmt.Sprintf("%dbit", sendRate))
 		if err != nil {
 			return fmt.Errorf("Failed to create limit tc class: %s", out)
 		}
 
-		out, err = shared.RunCommand("tc", "filter", "add", "dev", veth, "parent", "1:0", "protocol", "all", "u32", "match", "u32", "0", "0", "flowid", "1:1")
+		out, err = shared.RunCommand("tc", "filter", "add", "dev", devName, "parent", "1:0", "protocol", "all", "u32", "match", "u32", "0", "0", "flowid", "1:1")
 		if err != nil {
 			return fmt.Errorf("Failed to create tc filter: %s", out)
 		}
 	}
 
-	if m["limits.egress"] != "" {
-		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", veth, "handle", "ffff:0", "ingress")
+	if receiveRate > 0 {
+		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", devName, "handle", "ffff:0", "ingress")
 		if err != nil {
 			return fmt.Errorf("Failed to create ingress tc qdisc: %s", out)
 		}
 
-		out, err = shared.RunCommand("tc", "filter", "add", "dev", veth, "parent", "ffff:0", "protocol", "all", "u32", "match", "u32", "0", "0", "police", "rate", fmt.Sprintf("%dbit", egressInt), "burst", "1024k", "mtu", "64kb", "drop")
+		out, err = shared.RunCommand("tc", "filter", "add", "dev", devName, "parent", "ffff:0", "protocol", "all", "u32", "match", "u32", "0", "0", "police", "rate", fmt.Sprintf("%dbit", receiveRate), "burst", "1024k", "mtu", "64kb", "drop")
 		if err != nil {
 			return fmt.Errorf("Failed to create ingress tc qdisc: %s", out)
 		}
@@ -567,6 +674,20 @@ func networkValidVLAN(value string) error {
 	return nil
 }
 
+// networkValidQueues validates the number of queues of a NIC (the TUN driver supports up to 256).
+func networkValidQueues(value string) error {
+	queues, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Invalid number of queues: %s", value)
+	}
+
+	if queues < 1 || queues > 256 {
+		return fmt.Errorf("Out of range (1-256) number of queues: %s", value)
+	}
+
+	return nil
+}
+
 // networkValidVLANList validates a comma delimited list of VLAN IDs.
 func networkValidVLANList(value string) error {
 	for _, vlanID := range strings.Split(value, ",") {
@@ -618,3 +739,36 @@ func networkInterfaceBindWait(ifName string) error {
 
 	return fmt.Errorf("Bind of interface %q took too long", ifName)
 }
+
+// networkLoadByInstance loads the managed network with the given name as seen from the project of the instance, or
+// from the default project for profile devices.
+func networkLoadByInstance(state *state.State, inst instance.Instance, networkName string) (network.Network, error) {
+	projectName := project.Default
+	if inst != nil {
+		projectName = inst.Project()
+	}
+
+	return network.LoadVisibleByName(state, projectName, networkName)
+}
+
+// networkCheckProjectVisible checks that the managed network with the given name can be used from the project of
+// the instance.
+func networkCheckProjectVisible(state *state.State, inst instance.Instance, networkName string) error {
+	// Profile devices are only checked once applied to an instance.
+	if inst == nil {
+		return nil
+	}
+
+	return state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		visible, err := project.NetworkVisible(tx, inst.Project(), networkName)
+		if err != nil {
+			return err
+		}
+
+		if !visible {
+			return fmt.Errorf("Network %q isn't available in project %q", networkName, inst.Project())
+		}
+
+		return nil
+	})
+}
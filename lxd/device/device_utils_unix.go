@@ -553,3 +553,102 @@ func unixValidOctalFileMode(value string) error {
 
 	return nil
 }
+
+// unixUdevMatch is a single udev match expression of a unix-char or unix-block device.
+type unixUdevMatch struct {
+	Key       string // Name of the udev property, or of the sysfs attribute if Attribute is true.
+	Attribute bool   // Whether the expression matches a sysfs attribute.
+	Value     string // Shell pattern the value must match.
+	Negate    bool   // Whether the value must not match the pattern.
+}
+
+// unixParseUdevMatch parses a comma separated list of udev match expressions of the form "KEY=VALUE" or
+// "KEY!=VALUE", where KEY is either a udev property name or "ATTR{name}" for a sysfs attribute of the device and
+// VALUE is a shell pattern, e.g. "ID_VENDOR_ID=0403,ATTR{serial}=FT*".
+func unixParseUdevMatch(value string) ([]unixUdevMatch, error) {
+	matches := []unixUdevMatch{}
+	for _, expr := range strings.Split(value, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+
+		fields := strings.SplitN(expr, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid udev match expression %q", expr)
+		}
+
+		match := unixUdevMatch{Key: fields[0], Value: fields[1]}
+		if strings.HasSuffix(match.Key, "!") {
+			match.Key = strings.TrimSuffix(match.Key, "!")
+			match.Negate = true
+		}
+
+		if strings.HasPrefix(match.Key, "ATTR{") && strings.HasSuffix(match.Key, "}") {
+			match.Key = strings.TrimSuffix(strings.TrimPrefix(match.Key, "ATTR{"), "}")
+			match.Attribute = true
+		}
+
+		if match.Key == "" || strings.ContainsAny(match.Key, "{}/") {
+			return nil, fmt.Errorf("Invalid udev match expression %q", expr)
+		}
+
+		_, err := filepath.Match(match.Value, "")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern in udev match expression %q: %v", expr, err)
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// unixValidUdevMatch validates a list of udev match expressions.
+func unixValidUdevMatch(value string) error {
+	_, err := unixParseUdevMatch(value)
+	return err
+}
+
+// unixUdevMatches indicates whether a udev device with the given subsystem, properties and sysfs attributes
+// (looked up with the attr function) matches the udev.subsystem and udev.match settings of the device config.
+func unixUdevMatches(m deviceConfig.Device, subsystem string, properties map[string]string, attr func(name string) string) bool {
+	// Block devices can only be passed as unix-block devices and other devices as unix-char devices.
+	if (subsystem == "block") != (m["type"] == "unix-block") {
+		return false
+	}
+
+	if m["udev.subsystem"] != "" && m["udev.subsystem"] != subsystem {
+		return false
+	}
+
+	matches, err := unixParseUdevMatch(m["udev.match"])
+	if err != nil {
+		return false
+	}
+
+	for _, match := range matches {
+		value := properties[match.Key]
+		if match.Attribute {
+			value = attr(match.Key)
+		}
+
+		ok, _ := filepath.Match(match.Value, value)
+		if ok == match.Negate {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unixUdevSysAttr returns the value of a sysfs attribute of the device at the given udev DEVPATH, or an empty
+// string if it can't be read.
+func unixUdevSysAttr(devPath string, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join("/sys", devPath, name))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
@@ -25,6 +25,7 @@ import (
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/subprocess"
@@ -91,6 +92,8 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		"ceph.user_name":    shared.IsAny,
 		"boot.priority":     shared.IsUint32,
 		"path":              shared.IsAny,
+		"ephemeral":         shared.IsBool,
+		"discard":           shared.IsBool,
 	}
 
 	err := d.config.Validate(rules)
@@ -102,7 +105,27 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf(`Cannot use both "required" and deprecated "optional" properties at the same time`)
 	}
 
-	if d.config["source"] == "" && d.config["path"] != "/" {
+	isEphemeral := shared.IsTrue(d.config["ephemeral"])
+	if isEphemeral {
+		if d.config["path"] == "/" {
+			return fmt.Errorf("Root disk entry cannot be ephemeral")
+		}
+
+		if d.config["source"] != "" {
+			return fmt.Errorf(`Ephemeral disk entry may not have a "source" property set`)
+		}
+
+		if d.config["pool"] == "" || d.config["size"] == "" {
+			return fmt.Errorf(`Ephemeral disk entry must have both "pool" and "size" properties set`)
+		}
+
+		_, err := units.ParseByteSizeString(d.config["size"])
+		if err != nil {
+			return errors.Wrapf(err, "Invalid size %q", d.config["size"])
+		}
+	}
+
+	if d.config["source"] == "" && d.config["path"] != "/" && !isEphemeral {
 		return fmt.Errorf(`Disk entry is missing the required "source" property`)
 	}
 
@@ -114,8 +137,8 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf(`Root disk entry must have a "pool" property set`)
 	}
 
-	if d.config["size"] != "" && d.config["path"] != "/" {
-		return fmt.Errorf("Only the root disk may have a size quota")
+	if d.config["size"] != "" && d.config["path"] != "/" && !isEphemeral {
+		return fmt.Errorf("Only the root disk and ephemeral disks may have a size quota")
 	}
 
 	if d.config["recursive"] != "" && (d.config["path"] == "/" || !shared.IsDir(shared.HostPath(d.config["source"]))) {
@@ -169,21 +192,8 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 			return fmt.Errorf("The %q storage pool doesn't exist", d.config["pool"])
 		}
 
-		// Only check storate volume is available if we are validating an instance device and not a profile
-		// device (check for instancetype.Any), and we have least one expanded device (this is so we only
-		// do this expensive check after devices have been expanded).
-		if instConf.Type() != instancetype.Any && len(instConf.ExpandedDevices()) > 0 && d.config["source"] != "" && d.config["path"] != "/" {
-			isAvailable, err := d.state.Cluster.StorageVolumeIsAvailable(d.config["pool"], d.config["source"])
-			if err != nil {
-				return fmt.Errorf("Check if volume is available: %v", err)
-			}
-			if !isAvailable {
-				return fmt.Errorf("Storage volume %q is already attached to an instance on a different node", d.config["source"])
-			}
-		}
-
-		// Block volumes may only be attached to VMs.
-		if d.inst != nil && d.config["path"] != "/" {
+		// Ephemeral volumes are only created when the device is started, so there is nothing to check yet.
+		if d.inst != nil && d.config["path"] != "/" && !isEphemeral {
 			storageProjectName, err := project.StorageVolumeProject(d.state.Cluster, d.inst.Project(), db.StoragePoolVolumeTypeCustom)
 			if err != nil {
 				return err
@@ -194,6 +204,17 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 				return err
 			}
 
+			// Only check storage volume is available if we are validating an instance device and not a
+			// profile device (check for instancetype.Any), and we have least one expanded device (this
+			// is so we only do this expensive check after devices have been expanded).
+			if instConf.Type() != instancetype.Any && len(instConf.ExpandedDevices()) > 0 {
+				err = d.validateVolumeAttachments(storageProjectName, volume)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Block volumes may only be attached to VMs.
 			contentType, err := storagePools.VolumeContentTypeNameToContentType(volume.ContentType)
 			if err != nil {
 				return err
@@ -208,6 +229,48 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 	return nil
 }
 
+// validateVolumeAttachments checks that the custom volume can be attached to the instance given the
+// attachments it already has on other cluster members.
+//
+// Ceph RBD volumes can only be mapped on multiple members at the same time if all attachments are
+// read-only, or if the volume is marked as holding a cluster-aware filesystem using security.shared.
+// CephFS volumes are cluster-aware filesystems and volumes on local pools only exist on one member,
+// so no check is needed for those.
+func (d *disk) validateVolumeAttachments(projectName string, volume *api.StorageVolume) error {
+	_, pool, err := d.state.Cluster.GetStoragePool(d.config["pool"])
+	if err != nil {
+		return err
+	}
+
+	if pool.Driver != "ceph" || shared.IsTrue(volume.Config["security.shared"]) {
+		return nil
+	}
+
+	attachments, err := d.state.Cluster.GetStorageVolumeAttachments(projectName, d.config["pool"], d.config["source"])
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get attachments of storage volume %q", d.config["source"])
+	}
+
+	readOnly := shared.IsTrue(d.config["readonly"])
+	for _, attachment := range attachments {
+		if !attachment.Remote {
+			continue
+		}
+
+		if attachment.Project == d.inst.Project() && attachment.Instance == d.inst.Name() {
+			continue
+		}
+
+		if readOnly && attachment.ReadOnly {
+			continue
+		}
+
+		return fmt.Errorf("Storage volume %q is already attached to instance %q on cluster member %q, shared attachments must either all be read-only or the volume must have security.shared enabled", d.config["source"], attachment.Instance, attachment.Node)
+	}
+
+	return nil
+}
+
 // getDevicePath returns the absolute path on the host for this instance and supplied device config.
 func (d *disk) getDevicePath(devName string, devConfig deviceConfig.Device) string {
 	relativeDestPath := strings.TrimPrefix(devConfig["path"], "/")
@@ -217,8 +280,8 @@ func (d *disk) getDevicePath(devName string, devConfig deviceConfig.Device) stri
 
 // validateEnvironment checks the runtime environment for correctness.
 func (d *disk) validateEnvironment() error {
-	if shared.IsTrue(d.config["shift"]) && !d.state.OS.Shiftfs {
-		return fmt.Errorf("shiftfs is required by disk entry but isn't supported on system")
+	if shared.IsTrue(d.config["shift"]) && !d.state.OS.Shiftfs && !d.state.OS.IdmappedMounts {
+		return fmt.Errorf("idmapped mounts or shiftfs are required by disk entry but aren't supported on system")
 	}
 
 	if d.inst.Type() != instancetype.VM && d.config["source"] == diskSourceCloudInit {
@@ -241,11 +304,127 @@ func (d *disk) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	revert := revert.New()
+	defer revert.Fail()
+
+	if shared.IsTrue(d.config["ephemeral"]) {
+		err = d.createEphemeralVolume()
+		if err != nil {
+			return nil, err
+		}
+
+		revert.Add(func() { d.deleteEphemeralVolume() })
+
+		// Point the rest of the start logic at the newly created volume.
+		d.config = d.config.Clone()
+		d.config["source"] = d.ephemeralVolumeName()
+	}
+
+	var runConf *deviceConfig.RunConfig
 	if d.inst.Type() == instancetype.VM {
-		return d.startVM()
+		runConf, err = d.startVM()
+	} else {
+		runConf, err = d.startContainer()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	revert.Success()
+	return runConf, nil
+}
+
+// ephemeralVolumeName returns the name of the custom volume backing an ephemeral disk device.
+func (d *disk) ephemeralVolumeName() string {
+	return fmt.Sprintf("ephemeral_%s_%s", d.inst.Name(), d.name)
+}
+
+// ephemeralVolumeOwner returns the value of the volatile.ephemeral.owner key recorded on the custom volume backing
+// an ephemeral disk device. It identifies the instance the volume was created for, so that a volume which merely
+// has the same name is never reused or deleted.
+func (d *disk) ephemeralVolumeOwner() string {
+	return fmt.Sprintf("%s/%s/%d/%d", d.inst.Project(), d.inst.Name(), d.inst.ID(), d.inst.CreationDate().UnixNano())
+}
+
+// createEphemeralVolume creates the custom volume backing an ephemeral disk device, replacing any volume
+// left behind by this instance if it wasn't stopped cleanly.
+func (d *disk) createEphemeralVolume() error {
+	pool, err := storagePools.GetPoolByName(d.state, d.config["pool"])
+	if err != nil {
+		return err
+	}
+
+	storageProjectName, err := project.StorageVolumeProject(d.state.Cluster, d.inst.Project(), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	volName := d.ephemeralVolumeName()
+
+	_, vol, err := d.state.Cluster.GetLocalStoragePoolVolume(storageProjectName, volName, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err == nil {
+		if vol.Config["volatile.ephemeral.owner"] != d.ephemeralVolumeOwner() {
+			return fmt.Errorf("Custom volume %q already exists on storage pool %q and wasn't created for this device", volName, pool.Name())
+		}
+
+		err = d.deleteEphemeralVolume()
+		if err != nil {
+			return err
+		}
+	} else if err != db.ErrNoSuchObject {
+		return errors.Wrapf(err, "Failed to check for existing ephemeral volume %q", volName)
+	}
+
+	config := map[string]string{
+		"size":                     d.config["size"],
+		"volatile.ephemeral.owner": d.ephemeralVolumeOwner(),
+	}
+
+	desc := fmt.Sprintf("Ephemeral volume for device %q of instance %q", d.name, d.inst.Name())
+	err = pool.CreateCustomVolume(storageProjectName, volName, desc, config, storageDrivers.ContentTypeFS, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create ephemeral volume %q on storage pool %q", volName, pool.Name())
+	}
+
+	return nil
+}
+
+// deleteEphemeralVolume unmounts and deletes the custom volume backing an ephemeral disk device, provided it was
+// created for this device.
+func (d *disk) deleteEphemeralVolume() error {
+	pool, err := storagePools.GetPoolByName(d.state, d.config["pool"])
+	if err != nil {
+		return err
+	}
+
+	storageProjectName, err := project.StorageVolumeProject(d.state.Cluster, d.inst.Project(), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	volName := d.ephemeralVolumeName()
+
+	_, vol, err := d.state.Cluster.GetLocalStoragePoolVolume(storageProjectName, volName, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load ephemeral volume %q", volName)
+	}
+
+	if vol.Config["volatile.ephemeral.owner"] != d.ephemeralVolumeOwner() {
+		return fmt.Errorf("Refusing to delete custom volume %q on storage pool %q as it wasn't created for this device", volName, pool.Name())
+	}
+
+	_, err = pool.UnmountCustomVolume(storageProjectName, volName, nil)
+	if err != nil {
+		return err
+	}
+
+	err = pool.DeleteCustomVolume(storageProjectName, volName, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to delete ephemeral volume %q on storage pool %q", volName, pool.Name())
 	}
 
-	return d.startContainer()
+	return nil
 }
 
 // startContainer starts the disk device for a container instance.
@@ -317,6 +496,32 @@ func (d *disk) startContainer() (*deviceConfig.RunConfig, error) {
 			isFile = !shared.IsDir(srcPath) && !IsBlockdev(srcPath)
 		}
 
+		options := []string{}
+		if isReadOnly {
+			options = append(options, "ro")
+		}
+
+		if isRecursive {
+			options = append(options, "rbind")
+		} else {
+			options = append(options, "bind")
+		}
+
+		if d.config["propagation"] != "" {
+			options = append(options, d.config["propagation"])
+		}
+
+		if isFile {
+			options = append(options, "create=file")
+		} else {
+			options = append(options, "create=dir")
+		}
+
+		sourceDevPath, err := d.createDevice()
+		if err != nil {
+			return nil, err
+		}
+
 		ownerShift := deviceConfig.MountOwnerShiftNone
 		if shared.IsTrue(d.config["shift"]) {
 			ownerShift = deviceConfig.MountOwnerShiftDynamic
@@ -343,35 +548,12 @@ func (d *disk) startContainer() (*deviceConfig.RunConfig, error) {
 
 			if shared.IsTrue(volume.Config["security.shifted"]) {
 				ownerShift = "dynamic"
+			} else if d.volumeIdmapped(volume, sourceDevPath) {
+				// The volume isn't shifted on disk, see storagePoolVolumeAttachShift.
+				ownerShift = "dynamic"
 			}
 		}
 
-		options := []string{}
-		if isReadOnly {
-			options = append(options, "ro")
-		}
-
-		if isRecursive {
-			options = append(options, "rbind")
-		} else {
-			options = append(options, "bind")
-		}
-
-		if d.config["propagation"] != "" {
-			options = append(options, d.config["propagation"])
-		}
-
-		if isFile {
-			options = append(options, "create=file")
-		} else {
-			options = append(options, "create=dir")
-		}
-
-		sourceDevPath, err := d.createDevice()
-		if err != nil {
-			return nil, err
-		}
-
 		if sourceDevPath != "" {
 			// Instruct LXD to perform the mount.
 			runConf.Mounts = append(runConf.Mounts, deviceConfig.MountEntryItem{
@@ -396,11 +578,18 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 	runConf := deviceConfig.RunConfig{}
 	isRequired := d.isRequired(d.config)
 
+	// Discard requests from the guest are passed through to the backing storage unless disabled.
+	driveOpts := []string{}
+	if d.config["discard"] != "" && !shared.IsTrue(d.config["discard"]) {
+		driveOpts = append(driveOpts, deviceConfig.MountOptNoDiscard)
+	}
+
 	if shared.IsRootDiskDevice(d.config) {
 		runConf.Mounts = []deviceConfig.MountEntryItem{
 			{
 				TargetPath: d.config["path"], // Indicator used that this is the root device.
 				DevName:    d.name,
+				Opts:       driveOpts,
 			},
 		}
 
@@ -444,6 +633,7 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 				{
 					DevPath: fmt.Sprintf("rbd:%s/%s:%s", optEscaper.Replace(poolName), optEscaper.Replace(volumeName), strings.Join(opts, ":")),
 					DevName: d.name,
+					Opts:    driveOpts,
 				},
 			}
 		} else {
@@ -482,6 +672,7 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 			mount := deviceConfig.MountEntryItem{
 				DevPath: srcPath,
 				DevName: d.name,
+				Opts:    driveOpts,
 			}
 
 			// If the source being added is a directory, then we will be using 9p directory sharing to mount
@@ -940,6 +1131,20 @@ func (d *disk) createDevice() (string, error) {
 	return devPath, nil
 }
 
+// volumeIdmapped returns whether the ownership of the custom volume mounted at path is shifted with an idmapped
+// mount rather than on disk. This requires the kernel and the volume's filesystem to support idmapped mounts.
+func (d *disk) volumeIdmapped(volume *api.StorageVolume, path string) bool {
+	if path == "" || d.inst.Type() != instancetype.Container || d.inst.IsPrivileged() || !d.state.OS.IdmappedMounts {
+		return false
+	}
+
+	if volume.ContentType != db.StoragePoolVolumeContentTypeNameFS || shared.IsTrue(volume.Config["security.unmapped"]) {
+		return false
+	}
+
+	return idmap.CanIdmapMount(path)
+}
+
 func (d *disk) storagePoolVolumeAttachShift(projectName, poolName, volumeName string, volumeType int, remapPath string) error {
 	// Load the DB records.
 	poolID, pool, err := d.state.Cluster.GetStoragePool(poolName)
@@ -970,9 +1175,12 @@ func (d *disk) storagePoolVolumeAttachShift(projectName, poolName, volumeName st
 		}
 	}
 
+	// Volumes which are idmapped when mounted are kept unshifted on disk, like shifted ones.
+	shifted := shared.IsTrue(poolVolumePut.Config["security.shifted"]) || d.volumeIdmapped(volume, remapPath)
+
 	var nextIdmap *idmap.IdmapSet
 	nextJSONMap := "[]"
-	if !shared.IsTrue(poolVolumePut.Config["security.shifted"]) {
+	if !shifted {
 		c := d.inst.(instance.Container)
 		// Get the container's idmap.
 		if c.IsRunning() {
@@ -996,7 +1204,7 @@ func (d *disk) storagePoolVolumeAttachShift(projectName, poolName, volumeName st
 	if !nextIdmap.Equals(lastIdmap) {
 		logger.Debugf("Shifting storage volume")
 
-		if !shared.IsTrue(poolVolumePut.Config["security.shifted"]) {
+		if !shifted {
 			volumeUsedBy, err := storagePools.VolumeUsedByInstancesGet(d.state, projectName, poolName, volumeName)
 			if err != nil {
 				return err
@@ -1112,8 +1320,12 @@ func (d *disk) Stop() (*deviceConfig.RunConfig, error) {
 	relativeDestPath := strings.TrimPrefix(d.config["path"], "/")
 	devPath := d.getDevicePath(d.name, d.config)
 
-	// The disk device doesn't exist do nothing.
+	// The disk device doesn't exist do nothing, except throwing away an ephemeral volume which must not leak.
 	if !shared.PathExists(devPath) {
+		if shared.IsTrue(d.config["ephemeral"]) {
+			return &runConf, nil
+		}
+
 		return nil, nil
 	}
 
@@ -1154,6 +1366,11 @@ func (d *disk) stopVM() (*deviceConfig.RunConfig, error) {
 
 // postStop is run after the device is removed from the instance.
 func (d *disk) postStop() error {
+	// Ephemeral volumes are thrown away when the device is stopped.
+	if shared.IsTrue(d.config["ephemeral"]) {
+		return d.deleteEphemeralVolume()
+	}
+
 	// Check if pool-specific action should be taken to unmount custom volume disks.
 	if d.config["pool"] != "" && d.config["path"] != "/" {
 		pool, err := storagePools.GetPoolByName(d.state, d.config["pool"])
@@ -1259,7 +1476,7 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 
 		// Set the source path
 		source := d.getDevicePath(devName, dev)
-		if dev["source"] == "" {
+		if dev["source"] == "" && !shared.IsTrue(dev["ephemeral"]) {
 			source = d.inst.RootfsPath()
 		}
 
@@ -17,6 +17,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 )
 
 const gpuDRIDevPath = "/dev/dri"
@@ -39,6 +40,9 @@ func (d *gpu) validateConfig(instConf instance.ConfigReader) error {
 	}
 
 	rules := map[string]func(string) error{
+		"gputype": func(value string) error {
+			return shared.IsOneOf(value, []string{"physical", "mig", "mdev"})
+		},
 		"vendorid":  shared.IsDeviceID,
 		"productid": shared.IsDeviceID,
 		"id":        shared.IsAny,
@@ -46,6 +50,10 @@ func (d *gpu) validateConfig(instConf instance.ConfigReader) error {
 		"uid":       unixValidUserID,
 		"gid":       unixValidUserID,
 		"mode":      unixValidOctalFileMode,
+		"mig.uuid":  shared.IsAny,
+		"mig.gi":    shared.IsUint32,
+		"mig.ci":    shared.IsUint32,
+		"mdev":      shared.IsAny,
 	}
 
 	err := d.config.Validate(rules)
@@ -77,6 +85,45 @@ func (d *gpu) validateConfig(instConf instance.ConfigReader) error {
 		}
 	}
 
+	switch d.gpuType() {
+	case "mig":
+		if instConf.Type() == instancetype.VM {
+			return fmt.Errorf("MIG GPUs are only supported for containers")
+		}
+
+		if d.config["mig.uuid"] != "" && (d.config["mig.gi"] != "" || d.config["mig.ci"] != "") {
+			return fmt.Errorf(`Cannot use "mig.gi" or "mig.ci" when "mig.uuid" is set`)
+		}
+
+		if d.config["mig.uuid"] == "" && (d.config["mig.gi"] == "" || d.config["mig.ci"] == "") {
+			return fmt.Errorf(`Either "mig.uuid" or both "mig.gi" and "mig.ci" must be set for MIG GPUs`)
+		}
+
+		if d.config["mdev"] != "" {
+			return fmt.Errorf(`Cannot use "mdev" when "gputype" is "mig"`)
+		}
+	case "mdev":
+		if instConf.Type() == instancetype.Container {
+			return fmt.Errorf("Mediated device GPUs are only supported for virtual-machines")
+		}
+
+		if d.config["mdev"] == "" {
+			return fmt.Errorf(`The "mdev" property must be set for mediated device GPUs`)
+		}
+
+		for _, field := range []string{"mig.uuid", "mig.gi", "mig.ci"} {
+			if d.config[field] != "" {
+				return fmt.Errorf(`Cannot use %q when "gputype" is "mdev"`, field)
+			}
+		}
+	default:
+		for _, field := range []string{"mig.uuid", "mig.gi", "mig.ci", "mdev"} {
+			if d.config[field] != "" {
+				return fmt.Errorf(`Cannot use %q when "gputype" is "physical"`, field)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -86,9 +133,44 @@ func (d *gpu) validateEnvironment() error {
 		return fmt.Errorf("Invalid PCI address (no device found): %s", d.config["pci"])
 	}
 
+	if d.gpuType() == "mig" && !shared.IsTrue(d.inst.ExpandedConfig()["nvidia.runtime"]) {
+		return fmt.Errorf(`MIG GPUs require "nvidia.runtime" to be enabled`)
+	}
+
 	return nil
 }
 
+// gpuType returns the type of GPU device, defaulting to a physical GPU.
+func (d *gpu) gpuType() string {
+	if d.config["gputype"] == "" {
+		return "physical"
+	}
+
+	return d.config["gputype"]
+}
+
+// cardMatches returns whether the GPU card matches the vendorid, pci, productid and DRM ID settings (if specified).
+func (d *gpu) cardMatches(card api.ResourcesGPUCard) bool {
+	if (d.config["vendorid"] != "" && card.VendorID != d.config["vendorid"]) ||
+		(d.config["pci"] != "" && card.PCIAddress != d.config["pci"]) ||
+		(d.config["productid"] != "" && card.ProductID != d.config["productid"]) ||
+		(d.config["id"] != "" && (card.DRM == nil || fmt.Sprintf("%d", card.DRM.ID) != d.config["id"])) {
+		return false
+	}
+
+	return true
+}
+
+// CanHotPlug returns whether the device can be managed whilst the instance is running. GPU slices are only
+// passed to the instance when it starts.
+func (d *gpu) CanHotPlug() (bool, []string) {
+	if d.gpuType() != "physical" {
+		return false, []string{}
+	}
+
+	return true, []string{}
+}
+
 // Start is run when the device is added to the container.
 func (d *gpu) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -96,6 +178,13 @@ func (d *gpu) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	switch d.gpuType() {
+	case "mig":
+		return d.startMIG()
+	case "mdev":
+		return d.startMdev()
+	}
+
 	if d.inst.Type() == instancetype.VM {
 		return d.startVM()
 	}
@@ -348,10 +437,19 @@ func (d *gpu) postStop() error {
 	defer d.volatileSet(map[string]string{
 		"last_state.pci.slot.name": "",
 		"last_state.pci.driver":    "",
+		"last_state.gpu.slice":     "",
 	})
 
 	v := d.volatileGet()
 
+	// Remove the mediated device, releasing its slice.
+	if d.gpuType() == "mdev" && v["last_state.gpu.slice"] != "" {
+		err := gpuMdevRemove(v["last_state.gpu.slice"])
+		if err != nil {
+			return err
+		}
+	}
+
 	if d.inst.Type() == instancetype.Container {
 		// Remove host files for this device.
 		err := unixDeviceDeleteFiles(d.state, d.inst.DevicesPath(), "unix", d.name, "")
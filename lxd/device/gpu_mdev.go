@@ -0,0 +1,112 @@
+package device
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/resources"
+	"github.com/lxc/lxd/shared"
+)
+
+// startMdev creates a mediated device of the requested type on a matching GPU with a free slice of that type,
+// and passes it to the virtual-machine. The mediated device of a previous start is re-used if it's still around.
+func (d *gpu) startMdev() (*deviceConfig.RunConfig, error) {
+	runConf := deviceConfig.RunConfig{}
+	gpus, err := resources.GetGPU()
+	if err != nil {
+		return nil, err
+	}
+
+	mdevType := d.config["mdev"]
+
+	gpuSlicesMutex.Lock()
+	defer gpuSlicesMutex.Unlock()
+
+	reservedSlices, err := instanceGetReservedGPUSlices(d.state, d.inst)
+	if err != nil {
+		return nil, err
+	}
+
+	slice := d.volatileGet()["last_state.gpu.slice"]
+	_, reserved := reservedSlices[slice]
+	if slice != "" && (reserved || !shared.PathExists(filepath.Join("/sys/bus/mdev/devices", slice))) {
+		slice = ""
+	}
+
+	var pciAddress string
+	if slice == "" {
+		for _, gpu := range gpus.Cards {
+			if !d.cardMatches(gpu) {
+				continue
+			}
+
+			mdev, ok := gpu.Mdev[mdevType]
+			if ok && mdev.Available > 0 {
+				pciAddress = gpu.PCIAddress
+				break
+			}
+		}
+
+		if pciAddress == "" {
+			return nil, fmt.Errorf("No GPU with a free mediated device slice of type %q found", mdevType)
+		}
+
+		// Create the mediated device.
+		slice = uuid.New()
+		createPath := filepath.Join("/sys/bus/pci/devices", pciAddress, "mdev_supported_types", mdevType, "create")
+		err = ioutil.WriteFile(createPath, []byte(slice), 0200)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to create mediated device of type %q on GPU %q", mdevType, pciAddress)
+		}
+	}
+
+	err = d.volatileSet(map[string]string{"last_state.gpu.slice": slice})
+	if err != nil {
+		gpuMdevRemove(slice)
+		return nil, err
+	}
+
+	runConf.GPUDevice = append(runConf.GPUDevice,
+		[]deviceConfig.RunConfigItem{
+			{Key: "devName", Value: d.name},
+			{Key: "vgpu", Value: slice},
+		}...)
+
+	return &runConf, nil
+}
+
+// Remove is run when the device is removed from the instance or the instance is deleted. It removes any
+// mediated device left behind by the instance.
+func (d *gpu) Remove() error {
+	v := d.volatileGet()
+	if d.gpuType() != "mdev" || v["last_state.gpu.slice"] == "" {
+		return nil
+	}
+
+	err := gpuMdevRemove(v["last_state.gpu.slice"])
+	if err != nil {
+		return err
+	}
+
+	return d.volatileSet(map[string]string{"last_state.gpu.slice": ""})
+}
+
+// gpuMdevRemove removes the mediated device with the given UUID, if it still exists.
+func gpuMdevRemove(mdevUUID string) error {
+	removePath := filepath.Join("/sys/bus/mdev/devices", mdevUUID, "remove")
+	if !shared.PathExists(removePath) {
+		return nil
+	}
+
+	err := ioutil.WriteFile(removePath, []byte("1"), 0200)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to remove mediated device %q", mdevUUID)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package device
+
+import (
+	"fmt"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/resources"
+)
+
+// startMIG finds the requested NVIDIA MIG instance and passes it to the container through the NVIDIA runtime.
+// Each MIG instance can only be used by a single running instance.
+func (d *gpu) startMIG() (*deviceConfig.RunConfig, error) {
+	runConf := deviceConfig.RunConfig{}
+	gpus, err := resources.GetGPU()
+	if err != nil {
+		return nil, err
+	}
+
+	var gpuUUID string
+	for _, gpu := range gpus.Cards {
+		if !d.cardMatches(gpu) || gpu.Nvidia == nil || gpu.Nvidia.UUID == "" {
+			continue
+		}
+
+		if gpuUUID != "" {
+			return nil, fmt.Errorf("MIG GPUs cannot match multiple GPUs per device")
+		}
+
+		gpuUUID = gpu.Nvidia.UUID
+	}
+
+	if gpuUUID == "" {
+		return nil, fmt.Errorf("Failed to detect requested NVIDIA GPU device")
+	}
+
+	// Identify the MIG instance either by its own UUID, or by its GPU and compute instance IDs on the card.
+	var slice string
+	if d.config["mig.uuid"] != "" {
+		slice = fmt.Sprintf("MIG-%s", d.config["mig.uuid"])
+	} else {
+		slice = fmt.Sprintf("MIG-%s/%s/%s", gpuUUID, d.config["mig.gi"], d.config["mig.ci"])
+	}
+
+	gpuSlicesMutex.Lock()
+	defer gpuSlicesMutex.Unlock()
+
+	reservedSlices, err := instanceGetReservedGPUSlices(d.state, d.inst)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := reservedSlices[slice]
+	if ok {
+		return nil, fmt.Errorf("MIG instance %q is already in use by another instance", slice)
+	}
+
+	err = d.volatileSet(map[string]string{"last_state.gpu.slice": slice})
+	if err != nil {
+		return nil, err
+	}
+
+	runConf.GPUDevice = append(runConf.GPUDevice, deviceConfig.RunConfigItem{Key: "NVIDIA_VISIBLE_DEVICES", Value: slice})
+
+	return &runConf, nil
+}
@@ -2,11 +2,16 @@ package device
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/resources"
+	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
@@ -26,6 +31,7 @@ func (d *infinibandSRIOV) validateConfig(instConf instance.ConfigReader) error {
 		"name",
 		"mtu",
 		"hwaddr",
+		"guid",
 	}
 
 	rules := nicValidationRules(requiredFields, optionalFields)
@@ -36,6 +42,13 @@ func (d *infinibandSRIOV) validateConfig(instConf instance.ConfigReader) error {
 
 		return infinibandValidMAC(value)
 	}
+	rules["guid"] = func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		return infinibandValidGUID(value)
+	}
 
 	err := d.config.Validate(rules)
 	if err != nil {
@@ -109,6 +122,30 @@ func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Program the GUID of the instance onto the VF, recording the previous one for restoration.
+	vfID, err := infinibandVFID(d.config["parent"], saveData["host_name"])
+	if err != nil {
+		return nil, err
+	}
+
+	guid, err := d.allocateGUID()
+	if err != nil {
+		return nil, err
+	}
+
+	saveData["last_state.vf.id"] = fmt.Sprintf("%d", vfID)
+	saveData["last_state.vf.guid"] = infinibandHwaddrGUID(saveData["last_state.hwaddr"])
+
+	err = infinibandSetVFGUID(d.config["parent"], vfID, saveData["host_name"], guid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set the GUID of VF %d", vfID)
+	}
+
+	revert.Add(func() { d.restoreVFGUID(saveData) })
+
 	// Set the MAC address.
 	if d.config["hwaddr"] != "" {
 		err := infinibandSetDevMAC(saveData["host_name"], d.config["hwaddr"])
@@ -138,6 +175,8 @@ func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	revert.Success()
+
 	runConf.NetworkInterface = []deviceConfig.RunConfigItem{
 		{Key: "name", Value: d.config["name"]},
 		{Key: "type", Value: "phys"},
@@ -148,6 +187,92 @@ func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
 	return &runConf, nil
 }
 
+// allocateGUID returns the GUID of the device, which is either the configured one or the one previously assigned
+// to the device, generating a new one if needed. The GUID is checked against the ones used by the other
+// infiniband devices on the local node and recorded in the volatile config of the device.
+func (d *infinibandSRIOV) allocateGUID() (string, error) {
+	infinibandGUIDsMutex.Lock()
+	defer infinibandGUIDsMutex.Unlock()
+
+	reservedGUIDs, err := instanceGetReservedInfinibandGUIDs(d.state, d.inst)
+	if err != nil {
+		return "", err
+	}
+
+	// Also reserve the GUIDs used by the other infiniband devices of the instance.
+	config := d.inst.ExpandedConfig()
+	for devName, devConfig := range d.inst.ExpandedDevices() {
+		if devName == d.name || devConfig["type"] != "infiniband" {
+			continue
+		}
+
+		for _, guid := range []string{devConfig["guid"], config[fmt.Sprintf("volatile.%s.guid", devName)]} {
+			if guid != "" {
+				reservedGUIDs[strings.ToLower(guid)] = fmt.Sprintf("%s/%s (project %s)", d.inst.Name(), devName, d.inst.Project())
+			}
+		}
+	}
+
+	guid := d.config["guid"]
+	if guid != "" {
+		user, reserved := reservedGUIDs[strings.ToLower(guid)]
+		if reserved {
+			return "", fmt.Errorf("GUID %q is already used by %s", guid, user)
+		}
+	} else {
+		// Keep the previously assigned GUID unless another device got it meanwhile.
+		guid = d.volatileGet()["guid"]
+		_, reserved := reservedGUIDs[strings.ToLower(guid)]
+		if guid == "" || reserved {
+			guid = ""
+			for i := 0; i < 10; i++ {
+				newGUID, err := infinibandRandomGUID()
+				if err != nil {
+					return "", err
+				}
+
+				_, reserved := reservedGUIDs[newGUID]
+				if !reserved {
+					guid = newGUID
+					break
+				}
+			}
+
+			if guid == "" {
+				return "", fmt.Errorf("Failed to generate a unique GUID")
+			}
+		}
+	}
+
+	err = d.volatileSet(map[string]string{"guid": guid})
+	if err != nil {
+		return "", err
+	}
+
+	return guid, nil
+}
+
+// restoreVFGUID restores the GUID the VF had before being used by the device, using the volatile data stored in
+// Start().
+func (d *infinibandSRIOV) restoreVFGUID(volatile map[string]string) error {
+	// Nothing to do if we don't know the VF or its original GUID.
+	if volatile["host_name"] == "" || volatile["last_state.vf.id"] == "" || volatile["last_state.vf.guid"] == "" {
+		return nil
+	}
+
+	vfID, err := strconv.Atoi(volatile["last_state.vf.id"])
+	if err != nil {
+		return err
+	}
+
+	err = infinibandSetVFGUID(d.config["parent"], vfID, volatile["host_name"], volatile["last_state.vf.guid"])
+	if err != nil {
+		return errors.Wrapf(err, "Failed to restore the GUID of VF %d", vfID)
+	}
+
+	return nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *infinibandSRIOV) Stop() (*deviceConfig.RunConfig, error) {
 	v := d.volatileGet()
@@ -167,9 +292,11 @@ func (d *infinibandSRIOV) Stop() (*deviceConfig.RunConfig, error) {
 // postStop is run after the device is removed from the instance.
 func (d *infinibandSRIOV) postStop() error {
 	defer d.volatileSet(map[string]string{
-		"host_name":         "",
-		"last_state.hwaddr": "",
-		"last_state.mtu":    "",
+		"host_name":          "",
+		"last_state.hwaddr":  "",
+		"last_state.mtu":     "",
+		"last_state.vf.id":   "",
+		"last_state.vf.guid": "",
 	})
 
 	// Remove infiniband host files for this device.
@@ -178,8 +305,15 @@ func (d *infinibandSRIOV) postStop() error {
 		return fmt.Errorf("Failed to delete files for device '%s': %v", d.name, err)
 	}
 
-	// Restore hwaddr and mtu.
 	v := d.volatileGet()
+
+	// Restore the GUID first as rebinding the VF resets its hwaddr and mtu.
+	err = d.restoreVFGUID(v)
+	if err != nil {
+		return err
+	}
+
+	// Restore hwaddr and mtu.
 	if v["host_name"] != "" {
 		err := networkRestorePhysicalNic(v["host_name"], v)
 		if err != nil {
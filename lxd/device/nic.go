@@ -14,6 +14,8 @@ var nicTypes = map[string]func() device{
 	"routed":   func() device { return &nicRouted{} },
 	"macvlan":  func() device { return &nicMACVLAN{} },
 	"sriov":    func() device { return &nicSRIOV{} },
+	"ovn":      func() device { return &nicOVN{} },
+	"bond":     func() device { return &nicBond{} },
 }
 
 // nicLoadByType returns a NIC device instantiated with supplied config.
@@ -39,9 +41,13 @@ func nicValidationRules(requiredFields []string, optionalFields []string) map[st
 		"limits.ingress":          shared.IsAny,
 		"limits.egress":           shared.IsAny,
 		"limits.max":              shared.IsAny,
+		"limits.multicast":        shared.IsAny,
+		"multicast.flood":         shared.IsBool,
+		"multicast.fast_leave":    shared.IsBool,
 		"security.mac_filtering":  shared.IsAny,
 		"security.ipv4_filtering": shared.IsAny,
 		"security.ipv6_filtering": shared.IsAny,
+		"security.acls":           shared.IsAny,
 		"maas.subnet.ipv4":        shared.IsAny,
 		"maas.subnet.ipv6":        shared.IsAny,
 		"ipv4.address":            shared.IsNetworkAddressV4,
@@ -55,6 +61,11 @@ func nicValidationRules(requiredFields []string, optionalFields []string) map[st
 		"ipv6.host_address":       shared.IsNetworkAddressV6,
 		"ipv4.host_table":         shared.IsUint32,
 		"ipv6.host_table":         shared.IsUint32,
+		"queues":                  networkValidQueues,
+		"txqueuelen":              shared.IsUint32,
+		"offload.gso":             shared.IsBool,
+		"offload.tso":             shared.IsBool,
+		"offload.gro":             shared.IsBool,
 	}
 
 	validators := map[string]func(value string) error{}
@@ -0,0 +1,227 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+)
+
+// nicBond presents a SR-IOV virtual function and a virtio NIC connected to a bridge to a VM as a failover pair.
+// The guest uses the VF (primary) when it is plugged and transparently falls back to the virtio NIC (standby)
+// when it isn't, such as while the VF is unplugged for migration.
+type nicBond struct {
+	deviceCommon
+
+	primary device
+	standby device
+}
+
+// init stores the device config and sets up the primary and standby sub-devices, each storing its volatile
+// config under its own prefix.
+func (d *nicBond) init(inst instance.Instance, state *state.State, name string, conf deviceConfig.Device, volatileGet VolatileGetter, volatileSet VolatileSetter) {
+	d.deviceCommon.init(inst, state, name, conf, volatileGet, volatileSet)
+
+	primaryConfig := deviceConfig.Device{
+		"type":    "nic",
+		"nictype": "sriov",
+		"parent":  conf["parent"],
+	}
+
+	standbyConfig := deviceConfig.Device{
+		"type": "nic",
+	}
+
+	if conf["standby.network"] != "" {
+		standbyConfig["network"] = conf["standby.network"]
+	} else {
+		standbyConfig["nictype"] = "bridged"
+		standbyConfig["parent"] = conf["standby.parent"]
+	}
+
+	// Both NICs must share the MAC address for the guest to pair them.
+	for _, key := range []string{"hwaddr", "vlan"} {
+		if conf[key] != "" {
+			primaryConfig[key] = conf[key]
+			standbyConfig[key] = conf[key]
+		}
+	}
+
+	if conf["queues"] != "" {
+		standbyConfig["queues"] = conf["queues"]
+	}
+
+	d.primary = &nicSRIOV{}
+	primaryGet, primarySet := d.subVolatile("primary")
+	d.primary.init(inst, state, name, primaryConfig, primaryGet, primarySet)
+
+	d.standby = &nicBridged{}
+	standbyGet, standbySet := d.subVolatile("standby")
+	d.standby.init(inst, state, name, standbyConfig, standbyGet, standbySet)
+}
+
+// subVolatile returns volatile getter and setter functions for a sub-device, prefixing its keys.
+func (d *nicBond) subVolatile(prefix string) (VolatileGetter, VolatileSetter) {
+	if d.volatileGet == nil || d.volatileSet == nil {
+		return nil, nil
+	}
+
+	volatileGet := func() map[string]string {
+		volatile := map[string]string{}
+		for k, v := range d.volatileGet() {
+			if strings.HasPrefix(k, prefix+".") {
+				volatile[strings.TrimPrefix(k, prefix+".")] = v
+			}
+		}
+
+		return volatile
+	}
+
+	volatileSet := func(save map[string]string) error {
+		volatileSave := make(map[string]string, len(save))
+		for k, v := range save {
+			volatileSave[fmt.Sprintf("%s.%s", prefix, k)] = v
+		}
+
+		return d.volatileSet(volatileSave)
+	}
+
+	return volatileGet, volatileSet
+}
+
+// validateConfig checks the supplied config for correctness.
+func (d *nicBond) validateConfig(instConf instance.ConfigReader) error {
+	if !instanceSupported(instConf.Type(), instancetype.VM) {
+		return ErrUnsupportedDevType
+	}
+
+	requiredFields := []string{"parent"}
+	optionalFields := []string{
+		"name",
+		"hwaddr",
+		"vlan",
+		"queues",
+		"maas.subnet.ipv4",
+		"maas.subnet.ipv6",
+		"boot.priority",
+	}
+
+	if d.config["standby.parent"] == "" && d.config["standby.network"] == "" {
+		return fmt.Errorf("One of %q or %q must be set", "standby.parent", "standby.network")
+	}
+
+	if d.config["standby.parent"] != "" && d.config["standby.network"] != "" {
+		return fmt.Errorf("Cannot use %q property in conjunction with %q property", "standby.parent", "standby.network")
+	}
+
+	rules := nicValidationRules(requiredFields, optionalFields)
+	rules["standby.parent"] = shared.IsAny
+	rules["standby.network"] = shared.IsAny
+
+	err := d.config.Validate(rules)
+	if err != nil {
+		return err
+	}
+
+	err = d.primary.validateConfig(instConf)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid primary NIC")
+	}
+
+	err = d.standby.validateConfig(instConf)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid standby NIC")
+	}
+
+	return nil
+}
+
+// CanHotPlug returns whether the device can be managed whilst the instance is running. The failover pair is
+// set up when the VM starts.
+func (d *nicBond) CanHotPlug() (bool, []string) {
+	return false, []string{}
+}
+
+// Add is run when a device is added to an instance whether or not the instance is running.
+func (d *nicBond) Add() error {
+	return d.standby.Add()
+}
+
+// Start is run when the instance is starting up.
+func (d *nicBond) Start() (*deviceConfig.RunConfig, error) {
+	revert := revert.New()
+	defer revert.Fail()
+
+	primaryConf, err := d.primary.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to start primary NIC")
+	}
+
+	revert.Add(func() { d.stopSubDevice(d.primary) })
+
+	standbyConf, err := d.standby.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to start standby NIC")
+	}
+
+	var pciSlotName string
+	for _, item := range primaryConf.NetworkInterface {
+		if item.Key == "pciSlotName" {
+			pciSlotName = item.Value
+		}
+	}
+
+	// The standby virtio NIC is the one the guest sees at boot, the VF is paired with it.
+	runConf := deviceConfig.RunConfig{}
+	runConf.NetworkInterface = append(standbyConf.NetworkInterface, deviceConfig.RunConfigItem{Key: "failoverPCISlotName", Value: pciSlotName})
+
+	revert.Success()
+	return &runConf, nil
+}
+
+// Stop is run when the device is removed from the instance.
+func (d *nicBond) Stop() (*deviceConfig.RunConfig, error) {
+	runConf := deviceConfig.RunConfig{}
+
+	for _, sub := range []device{d.standby, d.primary} {
+		subConf, err := sub.Stop()
+		if err != nil {
+			return nil, err
+		}
+
+		if subConf != nil {
+			runConf.PostHooks = append(runConf.PostHooks, subConf.PostHooks...)
+		}
+	}
+
+	return &runConf, nil
+}
+
+// Remove is run when the device is removed from the instance or the instance is deleted.
+func (d *nicBond) Remove() error {
+	err := d.standby.Remove()
+	if err != nil {
+		return err
+	}
+
+	return d.primary.Remove()
+}
+
+// stopSubDevice stops a started sub-device and runs its post stop hooks, used when reverting a failed start.
+func (d *nicBond) stopSubDevice(sub device) {
+	runConf, err := sub.Stop()
+	if err != nil || runConf == nil {
+		return
+	}
+
+	for _, hook := range runConf.PostHooks {
+		hook()
+	}
+}
@@ -60,10 +60,19 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"security.mac_filtering",
 		"security.ipv4_filtering",
 		"security.ipv6_filtering",
+		"security.acls",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
 		"vlan",
+		"queues",
+		"txqueuelen",
+		"offload.gso",
+		"offload.tso",
+		"offload.gro",
+		"limits.multicast",
+		"multicast.flood",
+		"multicast.fast_leave",
 	}
 
 	// Check that if network proeperty is set that conflicting keys are not present.
@@ -78,10 +87,20 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		}
 
 		// If network property is specified, lookup network settings and apply them to the device's config.
-		n, err := network.LoadByName(d.state, d.config["network"])
+		err := networkCheckProjectVisible(d.state, d.inst, d.config["network"])
+		if err != nil {
+			return err
+		}
+
+		n, err := networkLoadByInstance(d.state, d.inst, d.config["network"])
 		if err != nil {
 			return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
 		}
+
+		if n.Type() != "bridge" {
+			return fmt.Errorf("Specified network must be of type bridge")
+		}
+
 		netConfig := n.Config()
 
 		if d.config["ipv4.address"] != "" {
@@ -147,6 +166,7 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 	}
 
 	rules := nicValidationRules(requiredFields, optionalFields)
+	rules["security.acls"] = network.ValidACLs(d.state)
 
 	// Add bridge specific vlan validation.
 	rules["vlan"] = func(value string) error {
@@ -204,7 +224,7 @@ func (d *nicBridged) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicBridged) CanHotPlug() (bool, []string) {
-	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering"}
+	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "security.acls", "txqueuelen", "offload.gso", "offload.tso", "offload.gro", "limits.multicast", "multicast.flood", "multicast.fast_leave"}
 }
 
 // Add is run when a device is added to an instance whether or not the instance is running.
@@ -273,6 +293,13 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 	}
 	revert.Add(func() { d.removeFilters(d.config) })
 
+	// Apply network ACLs to the host-side interface.
+	err = d.setupACLs(saveData["host_name"])
+	if err != nil {
+		return nil, err
+	}
+	revert.Add(func() { d.state.Firewall.InstanceClearACLRules(d.inst.Project(), d.inst.Name(), d.name) })
+
 	// Attach host side veth interface to bridge.
 	err = network.AttachInterface(d.config["parent"], saveData["host_name"])
 	if err != nil {
@@ -286,9 +313,14 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
-	// Detech bridge type and setup VLAN settings on bridge port.
+	// Detech bridge type and setup VLAN and multicast settings on bridge port.
 	if network.IsNativeBridge(d.config["parent"]) {
 		err = d.setupNativeBridgePortVLANs(saveData["host_name"])
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.setupNativeBridgePortMulticast(saveData["host_name"])
 	} else {
 		err = d.setupOVSBridgePortVLANs(saveData["host_name"])
 	}
@@ -315,6 +347,7 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 			[]deviceConfig.RunConfigItem{
 				{Key: "devName", Value: d.name},
 				{Key: "hwaddr", Value: d.config["hwaddr"]},
+				{Key: "queues", Value: d.config["queues"]},
 			}...)
 	}
 
@@ -357,6 +390,18 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 		if err != nil {
 			return err
 		}
+
+		err = d.setupACLs(v["host_name"])
+		if err != nil {
+			return err
+		}
+
+		if network.IsNativeBridge(d.config["parent"]) {
+			err = d.setupNativeBridgePortMulticast(v["host_name"])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Rebuild dnsmasq entry if needed and reload.
@@ -424,6 +469,11 @@ func (d *nicBridged) postStop() error {
 	networkRemoveVethRoutes(d.config)
 	d.removeFilters(d.config)
 
+	err := d.state.Firewall.InstanceClearACLRules(d.inst.Project(), d.inst.Name(), d.name)
+	if err != nil {
+		logger.Errorf("Failed to remove network ACL rules for %q: %v", d.name, err)
+	}
+
 	return nil
 }
 
@@ -445,7 +495,7 @@ func (d *nicBridged) Remove() error {
 		}
 
 		// Reload dnsmasq to apply new settings if dnsmasq is running.
-		if shared.PathExists(shared.VarPath("networks", d.config["parent"], "dnsmasq.pid")) {
+		if network.DHCPServerRunning(d.config["parent"]) {
 			err = dnsmasq.Kill(d.config["parent"], true)
 			if err != nil {
 				return err
@@ -460,19 +510,19 @@ func (d *nicBridged) Remove() error {
 // and reloads dnsmasq.
 func (d *nicBridged) rebuildDnsmasqEntry() error {
 	// Rebuild dnsmasq config if a bridged device has changed and parent is a managed network.
-	if !shared.PathExists(shared.VarPath("networks", d.config["parent"], "dnsmasq.pid")) {
+	if !network.DHCPServerRunning(d.config["parent"]) {
 		return nil
 	}
 
 	dnsmasq.ConfigMutex.Lock()
 	defer dnsmasq.ConfigMutex.Unlock()
 
-	_, dbInfo, err := d.state.Cluster.GetNetwork(d.config["parent"])
+	n, err := networkLoadByInstance(d.state, d.inst, d.config["parent"])
 	if err != nil {
 		return err
 	}
 
-	netConfig := dbInfo.Config
+	netConfig := n.Config()
 	ipv4Address := d.config["ipv4.address"]
 	ipv6Address := d.config["ipv6.address"]
 
@@ -525,6 +575,17 @@ func (d *nicBridged) setupHostFilters(oldConfig deviceConfig.Device) error {
 	return nil
 }
 
+// setupACLs applies the network ACLs in security.acls to the host-side interface, removing any previously applied
+// rules. ACLs applied to the parent network itself are handled by the network.
+func (d *nicBridged) setupACLs(hostName string) error {
+	aclNames := network.ACLNames(d.config["security.acls"])
+	if len(aclNames) > 0 && !network.IsNativeBridge(d.config["parent"]) {
+		return fmt.Errorf("Network ACLs can only be used with native bridges")
+	}
+
+	return network.InstanceDeviceACLsApply(d.state, d.inst.Project(), d.inst.Name(), d.name, hostName, aclNames)
+}
+
 // removeFilters removes any network level filters defined for the instance.
 func (d *nicBridged) removeFilters(m deviceConfig.Device) {
 	if m["hwaddr"] == "" {
@@ -615,7 +676,7 @@ func (d *nicBridged) setFilters() (err error) {
 
 	// Check if the parent is managed and load config. If parent is unmanaged continue anyway.
 	var IPv4, IPv6 net.IP
-	n, err := network.LoadByName(d.state, d.config["parent"])
+	n, err := networkLoadByInstance(d.state, d.inst, d.config["parent"])
 	if err != nil && err != db.ErrNoSuchObject {
 		return err
 	}
@@ -749,7 +810,7 @@ func (d *nicBridged) allocateFilterIPs(n network.Network) (net.IP, net.IP, error
 	}
 
 	// If parent is a DHCP enabled managed network and either IPv4 or IPv6 assigned is different than what is in dnsmasq config, rebuild config.
-	if shared.PathExists(shared.VarPath("networks", d.config["parent"], "dnsmasq.pid")) &&
+	if network.DHCPServerRunning(d.config["parent"]) &&
 		((IPv4 != nil && bytes.Compare(curIPv4.IP, IPv4.To4()) != 0) || (IPv6 != nil && bytes.Compare(curIPv6.IP, IPv6.To16()) != 0)) {
 		var IPv4Str, IPv6Str string
 
@@ -1272,6 +1333,29 @@ func (d *nicBridged) setupNativeBridgePortVLANs(hostName string) error {
 	return nil
 }
 
+// setupNativeBridgePortMulticast configures the multicast flags of the bridge port on the native bridge.
+// Disabling multicast.flood stops unregistered multicast traffic from being flooded to the instance, which then
+// only receives the groups it joined, and multicast.fast_leave removes the port from a group as soon as the
+// instance leaves it.
+func (d *nicBridged) setupNativeBridgePortMulticast(hostName string) error {
+	flood := "on"
+	if d.config["multicast.flood"] != "" && !shared.IsTrue(d.config["multicast.flood"]) {
+		flood = "off"
+	}
+
+	fastLeave := "off"
+	if shared.IsTrue(d.config["multicast.fast_leave"]) {
+		fastLeave = "on"
+	}
+
+	_, err := shared.RunCommand("bridge", "link", "set", "dev", hostName, "mcast_flood", flood, "fastleave", fastLeave)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting multicast flags on bridge port %q", hostName)
+	}
+
+	return nil
+}
+
 // setupOVSBridgePortVLANs configures the bridge port with the specified VLAN settings on the openvswitch bridge.
 func (d *nicBridged) setupOVSBridgePortVLANs(hostName string) error {
 	// Set port on bridge to specified untagged PVID.
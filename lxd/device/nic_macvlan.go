@@ -3,6 +3,8 @@ package device
 import (
 	"fmt"
 
+	"github.com/pkg/errors"
+
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
@@ -27,6 +29,9 @@ func (d *nicMACVLAN) validateConfig(instConf instance.ConfigReader) error {
 		"mtu",
 		"hwaddr",
 		"vlan",
+		"limits.ingress",
+		"limits.egress",
+		"limits.max",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
@@ -52,6 +57,18 @@ func (d *nicMACVLAN) validateEnvironment() error {
 	return nil
 }
 
+// CanHotPlug returns whether the device can be managed whilst the instance is running, it also
+// returns a list of fields that can be updated without triggering a device remove & add.
+func (d *nicMACVLAN) CanHotPlug() (bool, []string) {
+	// The macvtap interface of a VM stays on the host so its limits can be updated live, whereas the
+	// macvlan interface of a container is moved into the instance and needs to be recreated.
+	if d.inst.Type() == instancetype.VM {
+		return true, []string{"limits.ingress", "limits.egress", "limits.max"}
+	}
+
+	return true, []string{}
+}
+
 // Start is run when the device is added to a running instance or instance is starting up.
 func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -121,6 +138,13 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 		}
 	}
 
+	// Apply the limits. The interface sends the traffic leaving the instance and receives the traffic coming
+	// into it, and the tc configuration follows it when moved into a container.
+	err = d.setupLimits(saveData["host_name"])
+	if err != nil {
+		return nil, err
+	}
+
 	if d.inst.Type() == instancetype.VM {
 		// Bring the interface up on host side.
 		_, err := shared.RunCommand("ip", "link", "set", "dev", saveData["host_name"], "up")
@@ -154,6 +178,36 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 	return &runConf, nil
 }
 
+// Update applies configuration changes to a started device.
+func (d *nicMACVLAN) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
+	// Only the interface of a VM stays on the host.
+	if !isRunning || d.inst.Type() != instancetype.VM {
+		return nil
+	}
+
+	v := d.volatileGet()
+	if v["host_name"] == "" {
+		return nil
+	}
+
+	return d.setupLimits(v["host_name"])
+}
+
+// setupLimits applies the limits to the macvlan or macvtap interface.
+func (d *nicMACVLAN) setupLimits(devName string) error {
+	ingressInt, egressInt, err := networkParseLimits(d.config)
+	if err != nil {
+		return err
+	}
+
+	err = networkSetDeviceLimits(devName, egressInt, ingressInt)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to apply limits on %q", devName)
+	}
+
+	return nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *nicMACVLAN) Stop() (*deviceConfig.RunConfig, error) {
 	v := d.volatileGet()
@@ -0,0 +1,300 @@
+package device
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/openvswitch"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+)
+
+// ovnIntegrationBridge is the OVS bridge that ovn-controller connects instance ports to OVN with.
+const ovnIntegrationBridge = "br-int"
+
+// ovnNet defines an interface for accessing instance specific functions on OVN network.
+type ovnNet interface {
+	InstanceDevicePortAdd(instanceID int, deviceName string, mac net.HardwareAddr, ips []net.IP) (openvswitch.OVNSwitchPort, error)
+	InstanceDevicePortDelete(instanceID int, deviceName string) error
+	InstanceDevicePortACLsSet(instanceID int, deviceName string, aclNames []string) error
+}
+
+type nicOVN struct {
+	deviceCommon
+
+	network ovnNet // Populated in validateConfig().
+}
+
+// validateConfig checks the supplied config for correctness.
+func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
+	if !instanceSupported(instConf.Type(), instancetype.Container, instancetype.VM) {
+		return ErrUnsupportedDevType
+	}
+
+	requiredFields := []string{
+		"network",
+	}
+
+	optionalFields := []string{
+		"name",
+		"hwaddr",
+		"host_name",
+		"mtu",
+		"ipv4.address",
+		"ipv6.address",
+		"security.acls",
+		"boot.priority",
+	}
+
+	err := networkCheckProjectVisible(d.state, d.inst, d.config["network"])
+	if err != nil {
+		return err
+	}
+
+	// Lookup network settings and apply them to the device's config.
+	n, err := networkLoadByInstance(d.state, d.inst, d.config["network"])
+	if err != nil {
+		return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
+	}
+
+	if n.Type() != "ovn" {
+		return fmt.Errorf("Specified network must be of type ovn")
+	}
+
+	ovnNet, ok := n.(ovnNet)
+	if !ok {
+		return fmt.Errorf("Network is not ovnNet interface type")
+	}
+
+	d.network = ovnNet // Stored loaded network for use by other functions.
+	netConfig := n.Config()
+
+	if d.config["ipv4.address"] != "" {
+		_, subnet, err := net.ParseCIDR(netConfig["ipv4.address"])
+		if err != nil {
+			return fmt.Errorf("Cannot specify %q when %q is disabled on network %q", "ipv4.address", "ipv4.address", d.config["network"])
+		}
+
+		// Check the static IP supplied is valid for the linked network.
+		if !subnet.Contains(net.ParseIP(d.config["ipv4.address"])) {
+			return fmt.Errorf("Device IP address %q not within network %q subnet", d.config["ipv4.address"], d.config["network"])
+		}
+	}
+
+	if d.config["ipv6.address"] != "" {
+		// Static IPv6 addresses require stateful DHCPv6 (otherwise SLAAC determines the address).
+		if !shared.IsTrue(netConfig["ipv6.dhcp.stateful"]) {
+			return fmt.Errorf("Cannot specify %q when %q is disabled on network %q", "ipv6.address", "ipv6.dhcp.stateful", d.config["network"])
+		}
+
+		_, subnet, err := net.ParseCIDR(netConfig["ipv6.address"])
+		if err != nil {
+			return fmt.Errorf("Cannot specify %q when %q is disabled on network %q", "ipv6.address", "ipv6.address", d.config["network"])
+		}
+
+		// Check the static IP supplied is valid for the linked network.
+		if !subnet.Contains(net.ParseIP(d.config["ipv6.address"])) {
+			return fmt.Errorf("Device IP address %q not within network %q subnet", d.config["ipv6.address"], d.config["network"])
+		}
+	}
+
+	// Apply network level config options to device config before validation.
+	mtu := fmt.Sprintf("%d", 1442)
+	if netConfig["bridge.mtu"] != "" {
+		mtu = netConfig["bridge.mtu"]
+	}
+
+	d.config["mtu"] = mtu
+
+	rules := nicValidationRules(requiredFields, optionalFields)
+	rules["security.acls"] = network.ValidACLs(d.state)
+
+	err = d.config.Validate(rules)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateEnvironment checks the runtime environment for correctness.
+func (d *nicOVN) validateEnvironment() error {
+	if d.inst.Type() == instancetype.Container && d.config["name"] == "" {
+		return fmt.Errorf("Requires name property to start")
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", ovnIntegrationBridge)) {
+		return fmt.Errorf("OVS integration bridge device %q doesn't exist", ovnIntegrationBridge)
+	}
+
+	return nil
+}
+
+// CanHotPlug returns whether the device can be managed whilst the instance is running, it also
+// returns a list of fields that can be updated without triggering a device remove & add.
+func (d *nicOVN) CanHotPlug() (bool, []string) {
+	return true, []string{}
+}
+
+// Start is run when the device is added to a running instance or instance is starting up.
+func (d *nicOVN) Start() (*deviceConfig.RunConfig, error) {
+	err := d.validateEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	saveData := make(map[string]string)
+	saveData["host_name"] = d.config["host_name"]
+
+	var peerName string
+
+	// Create veth pair and configure the peer end with custom hwaddr and mtu if supplied.
+	if d.inst.Type() == instancetype.Container {
+		if saveData["host_name"] == "" {
+			saveData["host_name"] = networkRandomDevName("veth")
+		}
+		peerName, err = networkCreateVethPair(saveData["host_name"], d.config)
+	} else if d.inst.Type() == instancetype.VM {
+		if saveData["host_name"] == "" {
+			saveData["host_name"] = networkRandomDevName("tap")
+		}
+		peerName = saveData["host_name"] // VMs use the host_name to link to the TAP FD.
+		err = networkCreateTap(saveData["host_name"], d.config)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	revert.Add(func() { NetworkRemoveInterface(saveData["host_name"]) })
+
+	// Disable IPv6 on host-side veth interface (prevents host-side interface getting link-local address)
+	// which isn't needed because the host-side interface is connected to a bridge.
+	err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/disable_ipv6", saveData["host_name"]), "1")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	mac, err := net.ParseMAC(d.config["hwaddr"])
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []net.IP{}
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		if d.config[key] != "" {
+			ips = append(ips, net.ParseIP(d.config[key]))
+		}
+	}
+
+	// Add new OVN logical switch port for instance.
+	logicalPortName, err := d.network.InstanceDevicePortAdd(d.inst.ID(), d.name, mac, ips)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed adding OVN port")
+	}
+
+	revert.Add(func() { d.network.InstanceDevicePortDelete(d.inst.ID(), d.name) })
+
+	// Apply the network's and the device's ACLs to the logical port.
+	err = d.network.InstanceDevicePortACLsSet(d.inst.ID(), d.name, network.ACLNames(d.config["security.acls"]))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed applying network ACLs to OVN port")
+	}
+
+	// Attach host side veth interface to the integration bridge and associate it to the logical port.
+	ovs := openvswitch.NewOVS()
+	err = ovs.BridgePortAdd(ovnIntegrationBridge, saveData["host_name"], true)
+	if err != nil {
+		return nil, err
+	}
+
+	revert.Add(func() { ovs.BridgePortDelete(ovnIntegrationBridge, saveData["host_name"]) })
+
+	err = ovs.InterfaceAssociateOVNSwitchPort(saveData["host_name"], logicalPortName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempt to disable router advertisement acceptance.
+	err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_ra", saveData["host_name"]), "0")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err = d.volatileSet(saveData)
+	if err != nil {
+		return nil, err
+	}
+
+	runConf := deviceConfig.RunConfig{}
+	runConf.NetworkInterface = []deviceConfig.RunConfigItem{
+		{Key: "name", Value: d.config["name"]},
+		{Key: "type", Value: "phys"},
+		{Key: "flags", Value: "up"},
+		{Key: "link", Value: peerName},
+	}
+
+	if d.inst.Type() == instancetype.VM {
+		runConf.NetworkInterface = append(runConf.NetworkInterface,
+			[]deviceConfig.RunConfigItem{
+				{Key: "devName", Value: d.name},
+				{Key: "hwaddr", Value: d.config["hwaddr"]},
+			}...)
+	}
+
+	revert.Success()
+	return &runConf, nil
+}
+
+// Stop is run when the device is removed from the instance.
+func (d *nicOVN) Stop() (*deviceConfig.RunConfig, error) {
+	runConf := deviceConfig.RunConfig{
+		PostHooks: []func() error{d.postStop},
+	}
+
+	return &runConf, nil
+}
+
+// postStop is run after the device is removed from the instance.
+func (d *nicOVN) postStop() error {
+	defer d.volatileSet(map[string]string{
+		"host_name": "",
+	})
+
+	v := d.volatileGet()
+
+	if d.config["host_name"] == "" {
+		d.config["host_name"] = v["host_name"]
+	}
+
+	err := d.network.InstanceDevicePortDelete(d.inst.ID(), d.name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing OVN port")
+	}
+
+	if d.config["host_name"] != "" && shared.PathExists(fmt.Sprintf("/sys/class/net/%s", d.config["host_name"])) {
+		err = openvswitch.NewOVS().BridgePortDelete(ovnIntegrationBridge, d.config["host_name"])
+		if err != nil {
+			return err
+		}
+
+		// Removing host-side end of veth pair will delete the peer end too.
+		err = NetworkRemoveInterface(d.config["host_name"])
+		if err != nil {
+			return fmt.Errorf("Failed to remove interface %s: %s", d.config["host_name"], err)
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package device
 
 import (
 	"fmt"
+	"net"
+	"strings"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
@@ -29,7 +31,15 @@ func (d *nicP2P) validateConfig(instConf instance.ConfigReader) error {
 		"limits.max",
 		"ipv4.routes",
 		"ipv6.routes",
+		"security.mac_filtering",
+		"security.ipv4_filtering",
+		"security.ipv6_filtering",
 		"boot.priority",
+		"queues",
+		"txqueuelen",
+		"offload.gso",
+		"offload.tso",
+		"offload.gro",
 	}
 	err := d.config.Validate(nicValidationRules([]string{}, optionalFields))
 	if err != nil {
@@ -51,7 +61,7 @@ func (d *nicP2P) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicP2P) CanHotPlug() (bool, []string) {
-	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes"}
+	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "txqueuelen", "offload.gso", "offload.tso", "offload.gro"}
 }
 
 // Start is run when the device is added to a running instance or instance is starting up.
@@ -91,6 +101,13 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	// Apply MAC and IP filtering to the host-side interface.
+	err = d.setupHostFilters(saveData["host_name"], nil)
+	if err != nil {
+		NetworkRemoveInterface(saveData["host_name"])
+		return nil, err
+	}
+
 	err = d.volatileSet(saveData)
 	if err != nil {
 		return nil, err
@@ -109,6 +126,7 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 			[]deviceConfig.RunConfigItem{
 				{Key: "devName", Value: d.name},
 				{Key: "hwaddr", Value: d.config["hwaddr"]},
+				{Key: "queues", Value: d.config["queues"]},
 			}...)
 	}
 
@@ -136,9 +154,78 @@ func (d *nicP2P) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		return err
 	}
 
+	err = d.setupHostFilters(v["host_name"], oldConfig)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// filteringEnabled returns whether any MAC or IP filtering is enabled in the device config.
+func (d *nicP2P) filteringEnabled(m deviceConfig.Device) bool {
+	return shared.IsTrue(m["security.mac_filtering"]) || shared.IsTrue(m["security.ipv4_filtering"]) || shared.IsTrue(m["security.ipv6_filtering"])
+}
+
+// setupHostFilters applies the MAC and IP filtering to the host-side interface, replacing the filters of the
+// old config if supplied. The instance may only use its own MAC address and source addresses from its routes.
+func (d *nicP2P) setupHostFilters(hostName string, oldConfig deviceConfig.Device) error {
+	if oldConfig != nil && d.filteringEnabled(oldConfig) {
+		err := d.state.Firewall.InstanceClearNICFilter(d.inst.Project(), d.inst.Name(), d.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !d.filteringEnabled(d.config) {
+		return nil
+	}
+
+	// MAC filtering is always applied alongside IP filtering, as with bridged NICs.
+	if d.config["hwaddr"] == "" {
+		return fmt.Errorf("Failed to setup network filters for %q: hwaddr not defined", d.name)
+	}
+
+	var IPv4Nets, IPv6Nets []*net.IPNet
+	var err error
+
+	if shared.IsTrue(d.config["security.ipv4_filtering"]) {
+		IPv4Nets, err = nicP2PRoutes(d.config["ipv4.routes"])
+		if err != nil {
+			return err
+		}
+	}
+
+	if shared.IsTrue(d.config["security.ipv6_filtering"]) {
+		IPv6Nets, err = nicP2PRoutes(d.config["ipv6.routes"])
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.state.Firewall.InstanceSetupNICFilter(d.inst.Project(), d.inst.Name(), d.name, hostName, d.config["hwaddr"], IPv4Nets, IPv6Nets)
+}
+
+// nicP2PRoutes parses a comma delimited list of routes. It always returns a non-nil list.
+func nicP2PRoutes(value string) ([]*net.IPNet, error) {
+	routes := []*net.IPNet{}
+	for _, route := range strings.Split(value, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(route)
+		if err != nil {
+			return nil, err
+		}
+
+		routes = append(routes, subnet)
+	}
+
+	return routes, nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *nicP2P) Stop() (*deviceConfig.RunConfig, error) {
 	runConf := deviceConfig.RunConfig{
@@ -160,6 +247,13 @@ func (d *nicP2P) postStop() error {
 		d.config["host_name"] = v["host_name"]
 	}
 
+	if d.filteringEnabled(d.config) {
+		err := d.state.Firewall.InstanceClearNICFilter(d.inst.Project(), d.inst.Name(), d.name)
+		if err != nil {
+			return err
+		}
+	}
+
 	if d.config["host_name"] != "" && shared.PathExists(fmt.Sprintf("/sys/class/net/%s", d.config["host_name"])) {
 		// Removing host-side end of veth pair will delete the peer end too.
 		err := NetworkRemoveInterface(d.config["host_name"])
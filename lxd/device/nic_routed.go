@@ -2,6 +2,7 @@ package device
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
 
@@ -23,7 +24,7 @@ type nicRouted struct {
 }
 
 func (d *nicRouted) CanHotPlug() (bool, []string) {
-	return false, []string{"limits.ingress", "limits.egress", "limits.max"}
+	return false, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.address", "ipv6.address"}
 }
 
 // validateConfig checks the supplied config for correctness.
@@ -295,11 +296,12 @@ func (d *nicRouted) setupParentSysctls(parentName string) error {
 	return nil
 }
 
-// Update returns an error as most devices do not support live updates without being restarted.
+// Update applies configuration changes to a started device.
 func (d *nicRouted) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
+	oldConfig := oldDevices[d.name]
 	v := d.volatileGet()
 
-	// If instance is running, apply host side limits.
+	// If instance is running, apply host side limits and address changes.
 	if isRunning {
 		err := d.validateEnvironment()
 		if err != nil {
@@ -312,6 +314,169 @@ func (d *nicRouted) Update(oldDevices deviceConfig.Devices, isRunning bool) erro
 		if err != nil {
 			return err
 		}
+
+		err = d.updateAddresses(oldConfig, v["host_name"])
+		if err != nil {
+			return err
+		}
+
+		err = d.setupBGP()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateAddresses adds and removes the instance addresses that changed since the old config, along with their
+// host routes and proxy neighbour entries. The gateways are only setup at start, so the first address of a family
+// can't be added and the last one can't be removed whilst running.
+func (d *nicRouted) updateAddresses(oldConfig deviceConfig.Device, hostName string) error {
+	parentName := ""
+	if d.config["parent"] != "" {
+		parentName = network.GetHostDevice(d.config["parent"], d.config["vlan"])
+	}
+
+	for _, family := range []struct {
+		key          string
+		hostTableKey string
+		flag         string
+		prefixLen    int
+	}{{"ipv4.address", "ipv4.host_table", "-4", 32}, {"ipv6.address", "ipv6.host_table", "-6", 128}} {
+		oldAddresses := nicRoutedAddresses(oldConfig[family.key])
+		newAddresses := nicRoutedAddresses(d.config[family.key])
+
+		if (len(oldAddresses) == 0) != (len(newAddresses) == 0) {
+			return fmt.Errorf("Adding the first or removing the last address of %q requires restarting the instance", family.key)
+		}
+
+		hostTable := d.config[family.hostTableKey]
+
+		for _, addr := range oldAddresses {
+			if shared.StringInSlice(addr, newAddresses) {
+				continue
+			}
+
+			cidr := fmt.Sprintf("%s/%d", addr, family.prefixLen)
+
+			err := d.instanceAddress("del", cidr)
+			if err != nil {
+				return err
+			}
+
+			if parentName != "" {
+				_, err = shared.RunCommand("ip", family.flag, "neigh", "del", "proxy", addr, "dev", parentName)
+				if err != nil {
+					return err
+				}
+			}
+
+			if oldConfig[family.hostTableKey] != "" {
+				_, err = shared.RunCommand("ip", family.flag, "route", "del", "table", oldConfig[family.hostTableKey], cidr, "dev", hostName)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = shared.RunCommand("ip", family.flag, "route", "del", cidr, "dev", hostName)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, addr := range newAddresses {
+			if shared.StringInSlice(addr, oldAddresses) {
+				continue
+			}
+
+			cidr := fmt.Sprintf("%s/%d", addr, family.prefixLen)
+
+			_, err := shared.RunCommand("ip", family.flag, "route", "add", cidr, "dev", hostName)
+			if err != nil {
+				return err
+			}
+
+			if hostTable != "" {
+				_, err = shared.RunCommand("ip", family.flag, "route", "add", "table", hostTable, cidr, "dev", hostName)
+				if err != nil {
+					return err
+				}
+			}
+
+			if parentName != "" {
+				_, err = shared.RunCommand("ip", family.flag, "neigh", "add", "proxy", addr, "dev", parentName)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = d.instanceAddress("add", cidr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// nicRoutedAddresses returns the addresses of a comma separated address list.
+func nicRoutedAddresses(value string) []string {
+	addresses := []string{}
+	for _, addr := range strings.Split(value, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	return addresses
+}
+
+// instanceAddress adds or removes an address on the interface inside the instance.
+func (d *nicRouted) instanceAddress(action string, cidr string) error {
+	_, err := shared.RunCommand(
+		d.state.OS.ExecPath,
+		"forknet",
+		"address",
+		"--",
+		fmt.Sprintf("/proc/%d/ns/net", d.inst.InitPID()),
+		d.config["name"],
+		action,
+		cidr,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to %s address %q inside the instance", action, cidr)
+	}
+
+	return nil
+}
+
+// bgpOwner returns the owner name of the prefixes announced for the device.
+func (d *nicRouted) bgpOwner() string {
+	return fmt.Sprintf("instance_%d_%s", d.inst.ID(), d.name)
+}
+
+// setupBGP announces the instance addresses of the device with the server as next-hop.
+func (d *nicRouted) setupBGP() error {
+	err := d.state.BGP.RemovePrefixByOwner(d.bgpOwner())
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		for _, addr := range nicRoutedAddresses(d.config[key]) {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return fmt.Errorf("Invalid address %q", addr)
+			}
+
+			err = d.state.BGP.AddPrefix(network.IPToNet(ip), nil, d.bgpOwner())
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -395,6 +560,12 @@ func (d *nicRouted) postStart() error {
 				}
 			}
 		}
+
+		// Announce the instance addresses upstream.
+		err = d.setupBGP()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -429,12 +600,31 @@ func (d *nicRouted) postStop() error {
 		}
 	}
 
+	// Remove the proxy neighbour entries of the addresses added whilst running, liblxc only knows about the
+	// ones configured at start. Entries already removed by liblxc are ignored.
+	if d.config["parent"] != "" {
+		parentName := network.GetHostDevice(d.config["parent"], d.config["vlan"])
+		for _, addr := range nicRoutedAddresses(d.config["ipv4.address"]) {
+			shared.RunCommand("ip", "-4", "neigh", "del", "proxy", addr, "dev", parentName)
+		}
+
+		for _, addr := range nicRoutedAddresses(d.config["ipv6.address"]) {
+			shared.RunCommand("ip", "-6", "neigh", "del", "proxy", addr, "dev", parentName)
+		}
+	}
+
 	// Remove reverse path filters.
 	err := d.state.Firewall.InstanceClearRPFilter(d.inst.Project(), d.inst.Name(), d.name)
 	if err != nil {
 		errs = append(errs, err)
 	}
 
+	// Withdraw the instance addresses.
+	err = d.state.BGP.RemovePrefixByOwner(d.bgpOwner())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("%v", errs)
 	}
@@ -20,6 +20,9 @@ import (
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
 )
 
 type nicSRIOV struct {
@@ -37,7 +40,11 @@ func (d *nicSRIOV) validateConfig(instConf instance.ConfigReader) error {
 		"name",
 		"hwaddr",
 		"vlan",
+		"vlan.qos",
 		"security.mac_filtering",
+		"security.trusted",
+		"limits.egress",
+		"limits.egress.min",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
@@ -48,7 +55,50 @@ func (d *nicSRIOV) validateConfig(instConf instance.ConfigReader) error {
 		optionalFields = append(optionalFields, "mtu")
 	}
 
-	err := d.config.Validate(nicValidationRules(requiredFields, optionalFields))
+	// The VF rate limit only applies to the traffic leaving the instance.
+	if d.config["limits.ingress"] != "" || d.config["limits.max"] != "" {
+		return fmt.Errorf("SR-IOV devices only support limiting egress traffic (limits.egress)")
+	}
+
+	rules := nicValidationRules(requiredFields, optionalFields)
+	rules["vlan.qos"] = func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if d.config["vlan"] == "" {
+			return fmt.Errorf("VLAN QoS requires a VLAN to be set")
+		}
+
+		qos, err := strconv.Atoi(value)
+		if err != nil || qos < 0 || qos > 7 {
+			return fmt.Errorf("Invalid VLAN QoS priority (0-7): %s", value)
+		}
+
+		return nil
+	}
+
+	rules["limits.egress.min"] = func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		minRate, err := units.ParseBitSizeString(value)
+		if err != nil {
+			return err
+		}
+
+		if d.config["limits.egress"] != "" {
+			maxRate, err := units.ParseBitSizeString(d.config["limits.egress"])
+			if err == nil && minRate > maxRate {
+				return fmt.Errorf("The minimum egress rate cannot be higher than limits.egress")
+			}
+		}
+
+		return nil
+	}
+
+	err := d.config.Validate(rules)
 	if err != nil {
 		return err
 	}
@@ -69,6 +119,12 @@ func (d *nicSRIOV) validateEnvironment() error {
 	return nil
 }
 
+// CanHotPlug returns whether the device can be managed whilst the instance is running, it also
+// returns a list of fields that can be updated without triggering a device remove & add.
+func (d *nicSRIOV) CanHotPlug() (bool, []string) {
+	return true, []string{"limits.egress", "limits.egress.min", "security.trusted"}
+}
+
 // Start is run when the device is added to a running instance or instance is starting up.
 func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -91,11 +147,20 @@ func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
-	vfDev, vfID, err := d.findFreeVirtualFunction(reservedDevices)
+	reservedVFs, err := d.reservedVirtualFunctions()
 	if err != nil {
 		return nil, err
 	}
 
+	vfDev, vfID, err := d.findFreeVirtualFunction(reservedDevices, reservedVFs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the VF reserved for this device across restarts, it is only released when the device is removed.
+	saveData["vf.parent"] = d.config["parent"]
+	saveData["vf.id"] = fmt.Sprintf("%d", vfID)
+
 	vfPCIDev, err := d.setupSriovParent(vfDev, vfID, saveData)
 	if err != nil {
 		return nil, err
@@ -149,6 +214,143 @@ func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 	return &runConf, nil
 }
 
+// Update applies configuration changes to a started device.
+func (d *nicSRIOV) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
+	if !isRunning {
+		return nil
+	}
+
+	v := d.volatileGet()
+	if v["last_state.vf.id"] == "" {
+		return nil
+	}
+
+	err := d.setupVFRate(v["last_state.vf.id"], true)
+	if err != nil {
+		return err
+	}
+
+	return d.setupVFTrust(v["last_state.vf.id"], nil)
+}
+
+// setupVFRate applies the egress limits of the device as the max and min TX rates of the VF. When reset is false
+// the rates are only changed if a limit is set, so that parents not supporting VF rates can still be used.
+func (d *nicSRIOV) setupVFRate(vfID string, reset bool) error {
+	if d.config["limits.egress"] == "" && d.config["limits.egress.min"] == "" && !reset {
+		return nil
+	}
+
+	// VF rates are set in Mbit/s, round up so that small limits don't turn into no limit.
+	rate := func(key string) (string, error) {
+		if d.config[key] == "" {
+			return "0", nil
+		}
+
+		rateInt, err := units.ParseBitSizeString(d.config[key])
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d", (rateInt+999999)/1000000), nil
+	}
+
+	maxRate, err := rate("limits.egress")
+	if err != nil {
+		return err
+	}
+
+	minRate, err := rate("limits.egress.min")
+	if err != nil {
+		return err
+	}
+
+	// Both rates are set at once as some drivers validate them against each other.
+	_, err = shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", vfID, "max_tx_rate", maxRate, "min_tx_rate", minRate)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to set the TX rates of VF %q", vfID)
+	}
+
+	return nil
+}
+
+// setupVFTrust applies security.trusted to the VF, allowing it to change its MAC address and use promiscuous
+// mode. If volatile is supplied, the original trust setting is recorded into it for restoration.
+func (d *nicSRIOV) setupVFTrust(vfID string, volatile map[string]string) error {
+	if d.config["security.trusted"] == "" {
+		return nil
+	}
+
+	if volatile != nil {
+		vfInfo, err := d.networkGetVirtFuncInfo(d.config["parent"], d.vfIDInt(vfID))
+		if err != nil {
+			return err
+		}
+
+		volatile["last_state.vf.trust"] = fmt.Sprintf("%t", vfInfo.Trust)
+	}
+
+	mode := "off"
+	if shared.IsTrue(d.config["security.trusted"]) {
+		mode = "on"
+	}
+
+	_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", vfID, "trust", mode)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to set the trust mode of VF %q", vfID)
+	}
+
+	return nil
+}
+
+// vfIDInt converts a VF ID stored in volatile config into an integer, returning -1 if invalid.
+func (d *nicSRIOV) vfIDInt(vfID string) int {
+	id, err := strconv.Atoi(vfID)
+	if err != nil {
+		return -1
+	}
+
+	return id
+}
+
+// reservedVirtualFunctions returns the VF IDs of the parent reserved by the devices of other instances.
+func (d *nicSRIOV) reservedVirtualFunctions() (map[int]struct{}, error) {
+	instances, err := instance.LoadNodeAll(d.state, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedVFs := map[int]struct{}{}
+	for _, inst := range instances {
+		if inst.Project() == d.inst.Project() && inst.Name() == d.inst.Name() {
+			continue
+		}
+
+		config := inst.ExpandedConfig()
+		for devName, devConfig := range inst.ExpandedDevices() {
+			if devConfig["type"] != "nic" || !shared.StringInSlice(devConfig.NICType(), []string{"sriov", "bond"}) {
+				continue
+			}
+
+			// The VF of a bond NIC is reserved by its primary.
+			prefix := fmt.Sprintf("volatile.%s.", devName)
+			if devConfig.NICType() == "bond" {
+				prefix += "primary."
+			}
+
+			if config[prefix+"vf.parent"] != d.config["parent"] {
+				continue
+			}
+
+			vfID := d.vfIDInt(config[prefix+"vf.id"])
+			if vfID >= 0 {
+				reservedVFs[vfID] = struct{}{}
+			}
+		}
+	}
+
+	return reservedVFs, nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *nicSRIOV) Stop() (*deviceConfig.RunConfig, error) {
 	v := d.volatileGet()
@@ -173,6 +375,7 @@ func (d *nicSRIOV) postStop() error {
 		"last_state.vf.hwaddr":     "",
 		"last_state.vf.vlan":       "",
 		"last_state.vf.spoofcheck": "",
+		"last_state.vf.trust":      "",
 		"last_state.pci.driver":    "",
 	})
 
@@ -186,9 +389,11 @@ func (d *nicSRIOV) postStop() error {
 	return nil
 }
 
-// findFreeVirtualFunction looks on the specified parent device for an unused virtual function.
-// Returns the name of the interface and virtual function index ID if found, error if not.
-func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{}) (string, int, error) {
+// findFreeVirtualFunction looks on the specified parent device for an unused virtual function, preferring the
+// VF reserved by the device, then VFs which aren't reserved by other instances. VFs reserved by stopped instances
+// are only used once all the other VFs are in use. Returns the name of the interface and virtual function index
+// ID if found, error if not.
+func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{}, reservedVFs map[int]struct{}) (string, int, error) {
 	sriovNumVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", d.config["parent"])
 	sriovTotalVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_totalvfs", d.config["parent"])
 
@@ -236,68 +441,102 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 		return "", 0, err
 	}
 
-	// Check if any VFs are already enabled.
-	nicName := ""
-	vfID := 0
-	for i := 0; i < sriovNum; i++ {
-		if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)) {
-			continue
+	// freeVF returns the interface name of the VF if it isn't in use.
+	freeVF := func(vfID int) (string, error) {
+		vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], vfID)
+		if !shared.PathExists(vfListPath) {
+			return "", nil
 		}
 
-		// Check if VF is already in use.
-		empty, err := shared.PathIsEmpty(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i))
+		// VFs without an interface are in use (moved into a container or bound to vfio-pci).
+		empty, err := shared.PathIsEmpty(vfListPath)
 		if err != nil {
-			return "", 0, err
+			return "", err
 		}
+
 		if empty {
-			continue
+			return "", nil
 		}
 
-		vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)
-		nicName, err = d.getFreeVFInterface(reservedDevices, vfListPath, pfDevID, pfDevPort)
-		if err != nil {
-			return "", 0, err
-		}
+		return d.getFreeVFInterface(reservedDevices, vfListPath, pfDevID, pfDevPort)
+	}
 
-		// Found a free VF.
-		if nicName != "" {
-			vfID = i
-			break
+	// Try the VF reserved by the device first.
+	v := d.volatileGet()
+	if v["vf.parent"] == d.config["parent"] && v["vf.id"] != "" {
+		vfID := d.vfIDInt(v["vf.id"])
+		if vfID >= 0 && vfID < sriovNum {
+			nicName, err := freeVF(vfID)
+			if err != nil {
+				return "", 0, err
+			}
+
+			if nicName != "" {
+				return nicName, vfID, nil
+			}
 		}
 	}
 
-	if nicName == "" {
-		if sriovNum == sriovTotal {
-			return "", 0, fmt.Errorf("All virtual functions of sriov device '%s' seem to be in use", d.config["parent"])
+	// Then any free VF, leaving the ones reserved by other instances until last.
+	var fallbackName string
+	fallbackID := -1
+	scanVFs := func(first int, last int) (string, int, error) {
+		for i := first; i < last; i++ {
+			nicName, err := freeVF(i)
+			if err != nil {
+				return "", -1, err
+			}
+
+			if nicName == "" {
+				continue
+			}
+
+			_, reserved := reservedVFs[i]
+			if !reserved {
+				return nicName, i, nil
+			}
+
+			if fallbackID < 0 {
+				fallbackName = nicName
+				fallbackID = i
+			}
 		}
 
+		return "", -1, nil
+	}
+
+	nicName, vfID, err := scanVFs(0, sriovNum)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if vfID >= 0 {
+		return nicName, vfID, nil
+	}
+
+	if sriovNum < sriovTotal {
 		// Bump the number of VFs to the maximum.
 		err := ioutil.WriteFile(sriovNumVFs, []byte(sriovTotalVfsStr), 0644)
 		if err != nil {
 			return "", 0, err
 		}
 
-		// Use next free VF index.
-		for i := sriovNum + 1; i < sriovTotal; i++ {
-			vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)
-			nicName, err = d.getFreeVFInterface(reservedDevices, vfListPath, pfDevID, pfDevPort)
-			if err != nil {
-				return "", 0, err
-			}
+		nicName, vfID, err := scanVFs(sriovNum, sriovTotal)
+		if err != nil {
+			return "", 0, err
+		}
 
-			// Found a free VF.
-			if nicName != "" {
-				vfID = i
-				break
-			}
+		if vfID >= 0 {
+			return nicName, vfID, nil
 		}
 	}
 
-	if nicName == "" {
-		return "", 0, fmt.Errorf("All virtual functions on parent device are already in use")
+	if fallbackID >= 0 {
+		logger.Warn("Using a virtual function reserved by a stopped instance", log.Ctx{"parent": d.config["parent"], "vf": fallbackID, "instance": d.inst.Name(), "device": d.name})
+		return fallbackName, fallbackID, nil
 	}
 
-	return nicName, vfID, nil
+	return "", 0, fmt.Errorf("All %d virtual functions of SR-IOV parent %q are in use", sriovTotal, d.config["parent"])
 }
 
 // getFreeVFInterface checks the contents of the VF directory to find a free VF interface name that
@@ -385,12 +624,23 @@ func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[stri
 
 	// Setup VF VLAN if specified.
 	if d.config["vlan"] != "" {
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "vlan", d.config["vlan"])
+		args := []string{"link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "vlan", d.config["vlan"]}
+		if d.config["vlan.qos"] != "" {
+			args = append(args, "qos", d.config["vlan.qos"])
+		}
+
+		_, err := shared.RunCommand("ip", args...)
 		if err != nil {
 			return vfPCIDev, err
 		}
 	}
 
+	// Setup VF trust mode if specified.
+	err = d.setupVFTrust(volatile["last_state.vf.id"], volatile)
+	if err != nil {
+		return vfPCIDev, err
+	}
+
 	// Setup VF MAC spoofing protection if specified.
 	// The ordering of this section is very important, as Intel cards require a very specific
 	// order of setup to allow LXD to set custom MACs when using spoof check mode.
@@ -440,6 +690,12 @@ func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[stri
 		}
 	}
 
+	// Setup VF rate limit if specified.
+	err = d.setupVFRate(volatile["last_state.vf.id"], false)
+	if err != nil {
+		return vfPCIDev, err
+	}
+
 	if d.inst.Type() == instancetype.Container {
 		// Bind VF device onto the host so that the settings will take effect.
 		err = pciDeviceProbe(vfPCIDev)
@@ -477,6 +733,7 @@ type VirtFuncInfo struct {
 	MAC        string           `json:"mac"` // Deprecated
 	VLANs      []map[string]int `json:"vlan_list"`
 	SpoofCheck bool             `json:"spoofchk"`
+	Trust      bool             `json:"trust"`
 }
 
 // networkGetVirtFuncInfo returns info about an SR-IOV virtual function from the ip tool.
@@ -684,6 +941,27 @@ func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 		}
 	}
 
+	// Reset VF rate limits if specified.
+	if d.config["limits.egress"] != "" || d.config["limits.egress.min"] != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "max_tx_rate", "0", "min_tx_rate", "0")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reset VF trust mode if recorded.
+	if volatile["last_state.vf.trust"] != "" {
+		mode := "off"
+		if shared.IsTrue(volatile["last_state.vf.trust"]) {
+			mode = "on"
+		}
+
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "trust", mode)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Reset VF MAC specified if specified.
 	if volatile["last_state.vf.hwaddr"] != "" {
 		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "mac", volatile["last_state.vf.hwaddr"])
@@ -3,6 +3,7 @@ package device
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -22,6 +23,7 @@ import (
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -192,6 +194,14 @@ func (d *proxy) Start() (*deviceConfig.RunConfig, error) {
 			pidPath := filepath.Join(d.inst.DevicesPath(), devFileName)
 			logFileName := fmt.Sprintf("proxy.%s.log", d.name)
 			logPath := filepath.Join(d.inst.LogPath(), logFileName)
+			statsPath := proxyStatsPath(d.inst, d.name)
+
+			// The proxy process replaces its stats file, so it needs to own the directory holding it as
+			// it may not run as root.
+			err = d.setupProxyStatsDir(filepath.Dir(statsPath), proxyValues.securityUID, proxyValues.securityGID)
+			if err != nil {
+				return err
+			}
 
 			_, err = shared.RunCommandInheritFds(
 				proxyValues.inheritFds,
@@ -206,6 +216,7 @@ func (d *proxy) Start() (*deviceConfig.RunConfig, error) {
 				proxyValues.connectAddr,
 				logPath,
 				pidPath,
+				statsPath,
 				proxyValues.listenAddrGID,
 				proxyValues.listenAddrUID,
 				proxyValues.listenAddrMode,
@@ -294,9 +305,77 @@ func (d *proxy) Stop() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	os.RemoveAll(filepath.Dir(proxyStatsPath(d.inst, d.name)))
+
 	return nil, nil
 }
 
+// proxyStatsPath returns the path of the file the proxy process writes its counters to.
+func proxyStatsPath(inst instance.Instance, devName string) string {
+	return filepath.Join(inst.DevicesPath(), fmt.Sprintf("proxy.%s.stats", devName), "stats.json")
+}
+
+// setupProxyStatsDir creates the directory of the stats file of the proxy process, owned by the given user and
+// group (root if empty).
+func (d *proxy) setupProxyStatsDir(path string, securityUID string, securityGID string) error {
+	var err error
+
+	uid := 0
+	if securityUID != "" {
+		uid, err = strconv.Atoi(securityUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	gid := 0
+	if securityGID != "" {
+		gid, err = strconv.Atoi(securityGID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(path, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create proxy stats directory %q", path)
+	}
+
+	err = os.Chown(path, uid, gid)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to set owner of proxy stats directory %q", path)
+	}
+
+	return nil
+}
+
+// ProxyStats returns the connection counters of a running proxy device, or nil if it has no proxy process
+// (such as in NAT mode).
+func ProxyStats(inst instance.Instance, devName string) (*api.InstanceStateProxy, error) {
+	statsPath := proxyStatsPath(inst, devName)
+	content, err := ioutil.ReadFile(statsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		// The proxy process hasn't written its first counters yet.
+		if shared.PathExists(filepath.Dir(statsPath)) {
+			return &api.InstanceStateProxy{}, nil
+		}
+
+		return nil, nil
+	}
+
+	stats := api.InstanceStateProxy{}
+	err = json.Unmarshal(content, &stats)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse proxy stats")
+	}
+
+	return &stats, nil
+}
+
 func (d *proxy) setupNAT() error {
 	listenAddr, err := ProxyParseAddr(d.config["listen"])
 	if err != nil {
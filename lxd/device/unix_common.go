@@ -39,6 +39,12 @@ func (d *unixCommon) isRequired() bool {
 	return false
 }
 
+// isUdevMatched indicates whether the device config selects host devices using udev match rules rather than a
+// path.
+func (d *unixCommon) isUdevMatched() bool {
+	return d.config["udev.subsystem"] != "" || d.config["udev.match"] != ""
+}
+
 // validateConfig checks the supplied config for correctness.
 func (d *unixCommon) validateConfig(instConf instance.ConfigReader) error {
 	if !instanceSupported(instConf.Type(), instancetype.Container) {
@@ -54,6 +60,9 @@ func (d *unixCommon) validateConfig(instConf instance.ConfigReader) error {
 		"gid":      unixValidUserID,
 		"mode":     unixValidOctalFileMode,
 		"required": shared.IsBool,
+
+		"udev.subsystem": shared.IsAny,
+		"udev.match":     unixValidUdevMatch,
 	}
 
 	err := d.config.Validate(rules)
@@ -61,6 +70,14 @@ func (d *unixCommon) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	if d.isUdevMatched() {
+		if d.config["source"] != "" || d.config["major"] != "" || d.config["minor"] != "" {
+			return fmt.Errorf("Unix devices using udev match rules can't set the \"source\", \"major\" or \"minor\" properties")
+		}
+
+		return nil
+	}
+
 	if d.config["source"] == "" && d.config["path"] == "" {
 		return fmt.Errorf("Unix device entry is missing the required \"source\" or \"path\" property")
 	}
@@ -75,6 +92,11 @@ func (d *unixCommon) Register() error {
 		return nil
 	}
 
+	if d.isUdevMatched() {
+		d.registerUdev()
+		return nil
+	}
+
 	// Extract variables needed to run the event hook so that the reference to this device
 	// struct is not needed to be kept in memory.
 	devicesPath := d.inst.DevicesPath()
@@ -156,6 +178,16 @@ func (d *unixCommon) Register() error {
 func (d *unixCommon) Start() (*deviceConfig.RunConfig, error) {
 	runConf := deviceConfig.RunConfig{}
 	runConf.PostHooks = []func() error{d.Register}
+
+	if d.isUdevMatched() {
+		err := d.startUdev(&runConf)
+		if err != nil {
+			return nil, err
+		}
+
+		return &runConf, nil
+	}
+
 	srcPath := unixDeviceSourcePath(d.config)
 
 	// If device file already exists on system, proceed to add it whether its required or not.
@@ -195,6 +227,8 @@ func (d *unixCommon) Stop() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	unixHotplugUnregisterHandler(d.inst, d.name)
+
 	runConf := deviceConfig.RunConfig{
 		PostHooks: []func() error{d.postStop},
 	}
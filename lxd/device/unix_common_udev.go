@@ -0,0 +1,159 @@
+// +build linux,cgo
+
+package device
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	udev "github.com/farjump/go-libudev"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/state"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+)
+
+// unixUdevDestPath returns the path inside the instance of a host device matched by udev rules, which is the
+// "path" property of the device config if set or the path of the device on the host otherwise.
+func unixUdevDestPath(m deviceConfig.Device, devname string) string {
+	if m["path"] != "" {
+		return m["path"]
+	}
+
+	return devname
+}
+
+// unixUdevDeviceSetup creates the instance device file of a host device matched by udev rules.
+func unixUdevDeviceSetup(s *state.State, devicesPath string, deviceName string, m deviceConfig.Device, subsystem string, major uint32, minor uint32, devname string, runConf *deviceConfig.RunConfig) error {
+	// The host device is used as source so its file mode is used when no mode is configured.
+	configCopy := deviceConfig.Device{}
+	for k, v := range m {
+		configCopy[k] = v
+	}
+
+	configCopy["source"] = devname
+	destPath := unixUdevDestPath(m, devname)
+
+	if subsystem == "block" {
+		return unixDeviceSetupBlockNum(s, devicesPath, "unix", deviceName, configCopy, major, minor, destPath, false, runConf)
+	}
+
+	return unixDeviceSetupCharNum(s, devicesPath, "unix", deviceName, configCopy, major, minor, destPath, false, runConf)
+}
+
+// startUdev sets up the host devices currently matching the udev rules of the device. Only the first matching
+// device is passed to the instance when the device config has a path, as they would all use the same path.
+func (d *unixCommon) startUdev(runConf *deviceConfig.RunConfig) error {
+	u := udev.Udev{}
+	e := u.NewEnumerate()
+
+	if d.config["udev.subsystem"] != "" {
+		e.AddMatchSubsystem(d.config["udev.subsystem"])
+	}
+
+	e.AddMatchIsInitialized()
+	devices, err := e.Devices()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, device := range devices {
+		if device.Devnode() == "" {
+			continue
+		}
+
+		if !unixUdevMatches(d.config, device.Subsystem(), device.Properties(), device.SysattrValue) {
+			continue
+		}
+
+		devnum := device.Devnum()
+		err := unixUdevDeviceSetup(d.state, d.inst.DevicesPath(), d.name, d.config, device.Subsystem(), uint32(devnum.Major()), uint32(devnum.Minor()), device.Devnode(), runConf)
+		if err != nil {
+			return err
+		}
+
+		found = true
+		if d.config["path"] != "" {
+			break
+		}
+	}
+
+	if !found && d.isRequired() {
+		return fmt.Errorf("No device matching the udev rules found and the device is required")
+	}
+
+	return nil
+}
+
+// registerUdev registers a handler for udev events adding and removing host devices matching the udev rules of
+// the device to the instance.
+func (d *unixCommon) registerUdev() {
+	// Extract variables needed to run the event hook so that the reference to this device
+	// struct is not needed to be kept in memory.
+	devicesPath := d.inst.DevicesPath()
+	devConfig := d.config
+	deviceName := d.name
+	state := d.state
+
+	// Handler for when a udev event occurs.
+	f := func(e UnixHotplugEvent) (*deviceConfig.RunConfig, error) {
+		// Derive the host side path for the instance device file.
+		ourPrefix := deviceJoinPath("unix", deviceName)
+		relativeDestPath := strings.TrimPrefix(unixUdevDestPath(devConfig, e.Path), "/")
+		devPath := filepath.Join(devicesPath, storageDrivers.PathNameEncode(deviceJoinPath(ourPrefix, relativeDestPath)))
+
+		runConf := deviceConfig.RunConfig{}
+
+		if e.Action == "add" {
+			attr := func(name string) string {
+				return unixUdevSysAttr(e.Properties["DEVPATH"], name)
+			}
+
+			if !unixUdevMatches(devConfig, e.Subsystem, e.Properties, attr) {
+				return nil, nil
+			}
+
+			// Skip if host side instance device file already exists.
+			if shared.PathExists(devPath) {
+				return nil, nil
+			}
+
+			err := unixUdevDeviceSetup(state, devicesPath, deviceName, devConfig, e.Subsystem, e.Major, e.Minor, e.Path, &runConf)
+			if err != nil {
+				return nil, err
+			}
+		} else if e.Action == "remove" {
+			// Skip unless the host side instance device file is the one of the removed device.
+			_, major, minor, err := unixDeviceAttributes(devPath)
+			if err != nil || major != e.Major || minor != e.Minor {
+				return nil, nil
+			}
+
+			err = unixDeviceRemove(devicesPath, "unix", deviceName, relativeDestPath, &runConf)
+			if err != nil {
+				return nil, err
+			}
+
+			// Add a post hook function to remove the specific unix device file after unmount.
+			runConf.PostHooks = []func() error{func() error {
+				err := unixDeviceDeleteFiles(state, devicesPath, "unix", deviceName, relativeDestPath)
+				if err != nil {
+					return fmt.Errorf("Failed to delete files for device '%s': %v", deviceName, err)
+				}
+
+				return nil
+			}}
+		} else {
+			return nil, nil
+		}
+
+		runConf.Uevents = append(runConf.Uevents, e.UeventParts)
+
+		return &runConf, nil
+	}
+
+	unixHotplugRegisterHandler(d.inst, d.name, f)
+}
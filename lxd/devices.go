@@ -262,13 +262,12 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 					continue
 				}
 
+				// Devices without vendor and product IDs can still be matched by unix-char and
+				// unix-block devices using udev match rules.
 				vendor := ""
 				product := ""
 				if action == "add" {
-					vendor, product, ok = ueventParseVendorProduct(props, subsystem, devname)
-					if !ok {
-						continue
-					}
+					vendor, product, _ = ueventParseVendorProduct(props, subsystem, devname)
 				}
 
 				zeroPad := func(s string, l int) string {
@@ -298,6 +297,7 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 					devname,
 					ueventParts[:len(ueventParts)-1],
 					ueventLen,
+					props,
 				)
 				if err != nil {
 					logger.Error("Error reading unix device", log.Ctx{"err": err, "path": props["PHYSDEVPATH"]})
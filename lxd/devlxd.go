@@ -18,6 +18,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/lxc/lxd/lxd/daemon"
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/project"
@@ -92,6 +93,11 @@ var devlxdImageExport = devLxdHandler{"/1.0/images/{fingerprint}/export", func(d
 	// Use by security checks to distinguish devlxd vs lxd APIs
 	r.RemoteAddr = "@devlxd"
 
+	// Look the image up in the project of the instance
+	query := r.URL.Query()
+	query.Set("project", c.Project())
+	r.URL.RawQuery = query.Encode()
+
 	resp := imageExport(d, r)
 	err := resp.Render(w)
 	if err != nil {
@@ -106,6 +112,22 @@ var devlxdMetadataGet = devLxdHandler{"/1.0/meta-data", func(d *Daemon, c instan
 	return okResponse(fmt.Sprintf("#cloud-config\ninstance-id: %s\nlocal-hostname: %s\n%s", c.Name(), c.Name(), value), "raw")
 }}
 
+var devlxdDevicesGet = devLxdHandler{"/1.0/devices", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	return okResponse(c.ExpandedDevices().CloneNative(), "json")
+}}
+
+// devlxdCloudInitGet returns a handler serving the raw value of the given cloud-init config key.
+func devlxdCloudInitGet(path string, key string) devLxdHandler {
+	return devLxdHandler{path, func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+		value, ok := c.ExpandedConfig()[key]
+		if !ok {
+			return &devLxdResponse{"not found", http.StatusNotFound, "raw"}
+		}
+
+		return okResponse(value, "raw")
+	}}
+}
+
 var devlxdEventsGet = devLxdHandler{"/1.0/events", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
 	typeStr := r.FormValue("type")
 	if typeStr == "" {
@@ -118,7 +140,7 @@ var devlxdEventsGet = devLxdHandler{"/1.0/events", func(d *Daemon, c instance.In
 	}
 	defer conn.Close() // This ensures the go routine below is ended when this function ends.
 
-	listener, err := d.devlxdEvents.AddListener(strconv.Itoa(c.ID()), conn, strings.Split(typeStr, ","), "", false)
+	listener, err := d.devlxdEvents.AddListener(strconv.Itoa(c.ID()), conn, strings.Split(typeStr, ","), "", false, false, events.Filter{})
 	if err != nil {
 		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
 	}
@@ -161,6 +183,10 @@ var handlers = []devLxdHandler{
 	devlxdConfigGet,
 	devlxdConfigKeyGet,
 	devlxdMetadataGet,
+	devlxdCloudInitGet("/1.0/user-data", "user.user-data"),
+	devlxdCloudInitGet("/1.0/vendor-data", "user.vendor-data"),
+	devlxdCloudInitGet("/1.0/network-config", "user.network-config"),
+	devlxdDevicesGet,
 	devlxdEventsGet,
 	devlxdImageExport,
 }
@@ -0,0 +1,314 @@
+package dhcpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// DHCPv4 options not defined by gopacket.
+const (
+	dhcpv4OptRapidCommit  = layers.DHCPOpt(80)
+	dhcpv4OptDomainSearch = layers.DHCPOpt(119)
+)
+
+// serveDHCPv4 answers the DHCPv4 requests received on the connection until it's closed.
+func (s *Server) serveDHCPv4(conn net.PacketConn) {
+	packet := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			if s.readError("DHCPv4", err) {
+				return
+			}
+
+			continue
+		}
+
+		req := layers.DHCPv4{}
+		err = req.DecodeFromBytes(packet[:n], gopacket.NilDecodeFeedback)
+		if err != nil || req.Operation != layers.DHCPOpRequest || len(req.ClientHWAddr) != 6 {
+			continue
+		}
+
+		reply := s.handleDHCPv4(&req)
+		if reply == nil {
+			continue
+		}
+
+		buf := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{
+			ComputeChecksums: true,
+			FixLengths:       true,
+		}
+
+		err = gopacket.SerializeLayers(buf, opts, reply)
+		if err != nil {
+			s.logger.Error("Failed encoding DHCPv4 reply", log.Ctx{"err": err})
+			continue
+		}
+
+		// Clients without an address yet can only receive broadcasts.
+		dst := &net.UDPAddr{IP: net.IPv4bcast, Port: 68}
+		if req.ClientIP != nil && !req.ClientIP.IsUnspecified() && dhcpv4MessageType(reply) != layers.DHCPMsgTypeNak {
+			dst.IP = req.ClientIP
+		}
+
+		_, err = conn.WriteTo(buf.Bytes(), dst)
+		if err != nil {
+			s.logger.Error("Failed sending DHCPv4 reply", log.Ctx{"err": err, "client": req.ClientHWAddr.String()})
+		}
+	}
+}
+
+// handleDHCPv4 returns the reply to the request, if any.
+func (s *Server) handleDHCPv4(req *layers.DHCPv4) *layers.DHCPv4 {
+	hwaddr := req.ClientHWAddr
+	sameClient := func(l *Lease) bool {
+		return bytes.Equal(l.Hwaddr, hwaddr)
+	}
+
+	hosts, err := s.loadHosts()
+	if err != nil {
+		s.logger.Error("Failed loading static host entries", log.Ctx{"err": err})
+		return nil
+	}
+
+	switch dhcpv4MessageType(req) {
+	case layers.DHCPMsgTypeDiscover:
+		ip := s.dhcpv4Address(hwaddr, dhcpv4Option(req, layers.DHCPOptRequestIP), hosts, sameClient)
+		if ip == nil {
+			s.logger.Warn("No DHCPv4 address available", log.Ctx{"client": hwaddr.String()})
+			return nil
+		}
+
+		// Skip the offer if the client supports rapid commit.
+		if dhcpv4Option(req, dhcpv4OptRapidCommit) != nil {
+			s.grantDHCPv4Lease(req, ip, hosts)
+			reply := s.dhcpv4Reply(req, layers.DHCPMsgTypeAck, ip)
+			reply.Options = append(reply.Options, layers.NewDHCPOption(dhcpv4OptRapidCommit, nil))
+			return reply
+		}
+
+		return s.dhcpv4Reply(req, layers.DHCPMsgTypeOffer, ip)
+	case layers.DHCPMsgTypeRequest:
+		// Ignore the requests accepting the offer of another server.
+		serverID := dhcpv4Option(req, layers.DHCPOptServerID)
+		if serverID != nil && !net.IP(serverID).Equal(s.config.IPv4Address) {
+			return nil
+		}
+
+		ip := net.IP(dhcpv4Option(req, layers.DHCPOptRequestIP))
+		if len(ip) != net.IPv4len {
+			ip = req.ClientIP.To4()
+		}
+
+		if ip == nil || ip.IsUnspecified() || !s.dhcpv4Allowed(ip, hwaddr, hosts, sameClient) {
+			return s.dhcpv4Reply(req, layers.DHCPMsgTypeNak, nil)
+		}
+
+		s.grantDHCPv4Lease(req, ip, hosts)
+		return s.dhcpv4Reply(req, layers.DHCPMsgTypeAck, ip)
+	case layers.DHCPMsgTypeRelease:
+		s.releaseLeases(func(l *Lease) bool {
+			return sameClient(l) && l.Address.Equal(req.ClientIP)
+		})
+	case layers.DHCPMsgTypeDecline:
+		ip := net.IP(dhcpv4Option(req, layers.DHCPOptRequestIP))
+		s.releaseLeases(func(l *Lease) bool {
+			return sameClient(l) && l.Address.Equal(ip)
+		})
+	case layers.DHCPMsgTypeInform:
+		return s.dhcpv4Reply(req, layers.DHCPMsgTypeAck, nil)
+	}
+
+	return nil
+}
+
+// dhcpv4Address returns the address to offer to the client, preferring its static address, then its current
+// lease and then the address it asked for.
+func (s *Server) dhcpv4Address(hwaddr net.HardwareAddr, requested net.IP, hosts []host, sameClient func(l *Lease) bool) net.IP {
+	entry := findHost(hosts, hwaddr)
+	if entry != nil && entry.ipv4 != nil {
+		return entry.ipv4
+	}
+
+	lease := s.findLease(func(l *Lease) bool {
+		return l.isIPv4() && sameClient(l)
+	})
+
+	if lease != nil && s.addressAvailable(lease.Address, s.config.IPv4Ranges, hosts, hwaddr, sameClient) {
+		return lease.Address
+	}
+
+	if len(requested) == net.IPv4len && s.addressAvailable(requested, s.config.IPv4Ranges, hosts, hwaddr, sameClient) {
+		return requested
+	}
+
+	return s.allocateAddress(s.config.IPv4Ranges, hosts, hwaddr, sameClient)
+}
+
+// dhcpv4Allowed returns whether the client may use the address it requested.
+func (s *Server) dhcpv4Allowed(ip net.IP, hwaddr net.HardwareAddr, hosts []host, sameClient func(l *Lease) bool) bool {
+	entry := findHost(hosts, hwaddr)
+	if entry != nil && entry.ipv4 != nil {
+		return entry.ipv4.Equal(ip)
+	}
+
+	return s.addressAvailable(ip, s.config.IPv4Ranges, hosts, hwaddr, sameClient)
+}
+
+// grantDHCPv4Lease records the lease of the address to the client.
+func (s *Server) grantDHCPv4Lease(req *layers.DHCPv4, ip net.IP, hosts []host) {
+	lease := Lease{
+		Hwaddr:   req.ClientHWAddr,
+		Address:  ip.To4(),
+		Hostname: string(dhcpv4Option(req, layers.DHCPOptHostname)),
+		Expiry:   time.Now().Add(s.config.IPv4Expiry),
+		ClientID: dhcpv4Option(req, layers.DHCPOptClientID),
+	}
+
+	// The name of the static host entry takes precedence over the one provided by the client.
+	entry := findHost(hosts, req.ClientHWAddr)
+	if entry != nil && entry.name != "" {
+		lease.Hostname = entry.name
+	}
+
+	s.grantLease(lease, func(l *Lease) bool {
+		return bytes.Equal(l.Hwaddr, req.ClientHWAddr)
+	})
+}
+
+// dhcpv4Reply builds a reply of the given type to the request, leasing the address if not nil.
+func (s *Server) dhcpv4Reply(req *layers.DHCPv4, msgType layers.DHCPMsgType, ip net.IP) *layers.DHCPv4 {
+	reply := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: req.HardwareType,
+		HardwareLen:  req.HardwareLen,
+		Xid:          req.Xid,
+		Flags:        req.Flags,
+		ClientIP:     net.IPv4zero,
+		YourClientIP: net.IPv4zero,
+		NextServerIP: net.IPv4zero,
+		RelayAgentIP: net.IPv4zero,
+		ClientHWAddr: req.ClientHWAddr,
+	}
+
+	if msgType == layers.DHCPMsgTypeAck && req.ClientIP != nil {
+		reply.ClientIP = req.ClientIP
+	}
+
+	if ip != nil {
+		reply.YourClientIP = ip.To4()
+	}
+
+	reply.Options = append(reply.Options,
+		layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(msgType)}),
+		layers.NewDHCPOption(layers.DHCPOptServerID, s.config.IPv4Address.To4()),
+	)
+
+	if msgType == layers.DHCPMsgTypeNak {
+		return reply
+	}
+
+	if ip != nil {
+		expiry := uint32(s.config.IPv4Expiry / time.Second)
+		reply.Options = append(reply.Options,
+			layers.NewDHCPOption(layers.DHCPOptLeaseTime, uint32Bytes(expiry)),
+			layers.NewDHCPOption(layers.DHCPOptT1, uint32Bytes(expiry/2)),
+			layers.NewDHCPOption(layers.DHCPOptT2, uint32Bytes(expiry/8*7)),
+		)
+	}
+
+	subnet := s.config.IPv4Subnet
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = subnet.IP.To4()[i] | ^subnet.Mask[len(subnet.Mask)-net.IPv4len+i]
+	}
+
+	gateway := s.config.IPv4Gateway
+	if gateway == nil {
+		gateway = s.config.IPv4Address
+	}
+
+	reply.Options = append(reply.Options,
+		layers.NewDHCPOption(layers.DHCPOptSubnetMask, []byte(subnet.Mask[len(subnet.Mask)-net.IPv4len:])),
+		layers.NewDHCPOption(layers.DHCPOptBroadcastAddr, broadcast),
+		layers.NewDHCPOption(layers.DHCPOptRouter, gateway.To4()),
+		layers.NewDHCPOption(layers.DHCPOptDNS, s.config.IPv4Address.To4()),
+	)
+
+	if s.config.DNSDomain != "" {
+		reply.Options = append(reply.Options, layers.NewDHCPOption(layers.DHCPOptDomainName, []byte(s.config.DNSDomain)))
+	}
+
+	if s.config.MTU > 0 && s.config.MTU != 1500 {
+		mtu := make([]byte, 2)
+		binary.BigEndian.PutUint16(mtu, uint16(s.config.MTU))
+		reply.Options = append(reply.Options, layers.NewDHCPOption(layers.DHCPOptInterfaceMTU, mtu))
+	}
+
+	if len(s.config.DNSSearch) > 0 {
+		reply.Options = append(reply.Options, layers.NewDHCPOption(dhcpv4OptDomainSearch, encodeDomainList(s.config.DNSSearch)))
+	}
+
+	return reply
+}
+
+// dhcpv4MessageType returns the DHCP message type of the packet.
+func dhcpv4MessageType(msg *layers.DHCPv4) layers.DHCPMsgType {
+	value := dhcpv4Option(msg, layers.DHCPOptMessageType)
+	if len(value) != 1 {
+		return layers.DHCPMsgTypeUnspecified
+	}
+
+	return layers.DHCPMsgType(value[0])
+}
+
+// dhcpv4Option returns the value of the option, or nil if not present.
+func dhcpv4Option(msg *layers.DHCPv4, code layers.DHCPOpt) []byte {
+	for _, opt := range msg.Options {
+		if opt.Type == code {
+			if opt.Data == nil {
+				return []byte{}
+			}
+
+			return opt.Data
+		}
+	}
+
+	return nil
+}
+
+// uint32Bytes returns the big endian encoding of the value.
+func uint32Bytes(value uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, value)
+	return b
+}
+
+// encodeDomainList encodes the domains in the DNS wire format used by the DHCP search list options.
+func encodeDomainList(domains []string) []byte {
+	var buf bytes.Buffer
+	for _, domain := range domains {
+		for _, label := range strings.Split(strings.Trim(domain, "."), ".") {
+			if label == "" || len(label) > 63 {
+				continue
+			}
+
+			buf.WriteByte(byte(len(label)))
+			buf.WriteString(label)
+		}
+
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes()
+}
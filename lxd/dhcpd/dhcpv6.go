@@ -0,0 +1,319 @@
+package dhcpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// DHCPv6 options and status codes not defined by gopacket.
+const (
+	dhcpv6OptDNSServers = layers.DHCPv6Opt(23)
+	dhcpv6OptDomainList = layers.DHCPv6Opt(24)
+	dhcpv6OptClientFQDN = layers.DHCPv6Opt(39)
+
+	dhcpv6StatusSuccess      = 0
+	dhcpv6StatusNoAddrsAvail = 2
+	dhcpv6StatusNotOnLink    = 4
+)
+
+// identityAssociation represents an IA_NA option of a request.
+type identityAssociation struct {
+	iaid      uint32
+	requested net.IP
+}
+
+// serveDHCPv6 answers the DHCPv6 requests received on the connection until it's closed.
+func (s *Server) serveDHCPv6(conn net.PacketConn) {
+	packet := make([]byte, 1500)
+	for {
+		n, src, err := conn.ReadFrom(packet)
+		if err != nil {
+			if s.readError("DHCPv6", err) {
+				return
+			}
+
+			continue
+		}
+
+		req := layers.DHCPv6{}
+		err = req.DecodeFromBytes(packet[:n], gopacket.NilDecodeFeedback)
+		if err != nil {
+			continue
+		}
+
+		reply := s.handleDHCPv6(&req)
+		if reply == nil {
+			continue
+		}
+
+		buf := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{
+			ComputeChecksums: true,
+			FixLengths:       true,
+		}
+
+		err = gopacket.SerializeLayers(buf, opts, reply)
+		if err != nil {
+			s.logger.Error("Failed encoding DHCPv6 reply", log.Ctx{"err": err})
+			continue
+		}
+
+		_, err = conn.WriteTo(buf.Bytes(), src)
+		if err != nil {
+			s.logger.Error("Failed sending DHCPv6 reply", log.Ctx{"err": err, "client": src.String()})
+		}
+	}
+}
+
+// handleDHCPv6 returns the reply to the request, if any.
+func (s *Server) handleDHCPv6(req *layers.DHCPv6) *layers.DHCPv6 {
+	duid := dhcpv6Option(req, layers.DHCPv6OptClientID)
+	serverID := dhcpv6Option(req, layers.DHCPv6OptServerID)
+
+	switch req.MsgType {
+	case layers.DHCPv6MsgTypeSolicit, layers.DHCPv6MsgTypeRebind, layers.DHCPv6MsgTypeConfirm:
+		// Must be sent to all servers.
+		if duid == nil || serverID != nil {
+			return nil
+		}
+	case layers.DHCPv6MsgTypeRequest, layers.DHCPv6MsgTypeRenew, layers.DHCPv6MsgTypeRelease, layers.DHCPv6MsgTypeDecline:
+		// Must be sent to this server.
+		if duid == nil || !bytes.Equal(serverID, s.duid) {
+			return nil
+		}
+	case layers.DHCPv6MsgTypeInformationRequest:
+		if serverID != nil && !bytes.Equal(serverID, s.duid) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	// Without stateful DHCPv6, only information requests are answered.
+	if !s.config.IPv6Stateful && req.MsgType != layers.DHCPv6MsgTypeInformationRequest {
+		return nil
+	}
+
+	hosts, err := s.loadHosts()
+	if err != nil {
+		s.logger.Error("Failed loading static host entries", log.Ctx{"err": err})
+		return nil
+	}
+
+	replyType := layers.DHCPv6MsgTypeReply
+	if req.MsgType == layers.DHCPv6MsgTypeSolicit && dhcpv6Option(req, layers.DHCPv6OptRapidCommit) == nil {
+		replyType = layers.DHCPv6MsgTypeAdverstise
+	}
+
+	reply := &layers.DHCPv6{
+		MsgType:       replyType,
+		TransactionID: req.TransactionID,
+	}
+
+	reply.Options = append(reply.Options, layers.NewDHCPv6Option(layers.DHCPv6OptServerID, s.duid))
+	if duid != nil {
+		reply.Options = append(reply.Options, layers.NewDHCPv6Option(layers.DHCPv6OptClientID, duid))
+	}
+
+	hwaddr := duidHwaddr(duid)
+
+	switch req.MsgType {
+	case layers.DHCPv6MsgTypeSolicit, layers.DHCPv6MsgTypeRequest, layers.DHCPv6MsgTypeRenew, layers.DHCPv6MsgTypeRebind:
+		commit := replyType == layers.DHCPv6MsgTypeReply
+		if req.MsgType == layers.DHCPv6MsgTypeSolicit && commit {
+			reply.Options = append(reply.Options, layers.NewDHCPv6Option(layers.DHCPv6OptRapidCommit, nil))
+		}
+
+		for _, ia := range dhcpv6IdentityAssociations(req) {
+			ia := ia
+			sameClient := func(l *Lease) bool {
+				return !l.isIPv4() && bytes.Equal(l.ClientID, duid) && l.IAID == ia.iaid
+			}
+
+			ip := s.dhcpv6Address(hwaddr, ia.requested, hosts, sameClient)
+			if ip == nil {
+				s.logger.Warn("No DHCPv6 address available", log.Ctx{"client": formatHex(duid)})
+				reply.Options = append(reply.Options, layers.NewDHCPv6Option(layers.DHCPv6OptIANA, s.dhcpv6IANA(ia.iaid, nil)))
+				continue
+			}
+
+			if commit {
+				lease := Lease{
+					Hwaddr:   hwaddr,
+					Address:  ip,
+					Hostname: dhcpv6Hostname(req),
+					Expiry:   time.Now().Add(s.config.IPv6Expiry),
+					ClientID: duid,
+					IAID:     ia.iaid,
+				}
+
+				// The name of the static host entry takes precedence over the one provided by the client.
+				entry := findHost(hosts, hwaddr)
+				if entry != nil && entry.name != "" {
+					lease.Hostname = entry.name
+				}
+
+				s.grantLease(lease, sameClient)
+			}
+
+			reply.Options = append(reply.Options, layers.NewDHCPv6Option(layers.DHCPv6OptIANA, s.dhcpv6IANA(ia.iaid, ip)))
+		}
+	case layers.DHCPv6MsgTypeConfirm:
+		status := uint16(dhcpv6StatusSuccess)
+		for _, ia := range dhcpv6IdentityAssociations(req) {
+			if ia.requested != nil && !s.config.IPv6Subnet.Contains(ia.requested) {
+				status = dhcpv6StatusNotOnLink
+			}
+		}
+
+		reply.Options = append(reply.Options, dhcpv6Status(status))
+	case layers.DHCPv6MsgTypeRelease, layers.DHCPv6MsgTypeDecline:
+		for _, ia := range dhcpv6IdentityAssociations(req) {
+			s.releaseLeases(func(l *Lease) bool {
+				return !l.isIPv4() && bytes.Equal(l.ClientID, duid) && l.IAID == ia.iaid
+			})
+		}
+
+		reply.Options = append(reply.Options, dhcpv6Status(dhcpv6StatusSuccess))
+	}
+
+	reply.Options = append(reply.Options, layers.NewDHCPv6Option(dhcpv6OptDNSServers, s.config.IPv6Address.To16()))
+
+	domains := s.config.DNSSearch
+	if len(domains) == 0 && s.config.DNSDomain != "" {
+		domains = []string{s.config.DNSDomain}
+	}
+
+	if len(domains) > 0 {
+		reply.Options = append(reply.Options, layers.NewDHCPv6Option(dhcpv6OptDomainList, encodeDomainList(domains)))
+	}
+
+	return reply
+}
+
+// dhcpv6Address returns the address to lease to the client, preferring its static address, then its current
+// lease and then the address it asked for.
+func (s *Server) dhcpv6Address(hwaddr net.HardwareAddr, requested net.IP, hosts []host, sameClient func(l *Lease) bool) net.IP {
+	entry := findHost(hosts, hwaddr)
+	if entry != nil && entry.ipv6 != nil {
+		return entry.ipv6
+	}
+
+	lease := s.findLease(sameClient)
+	if lease != nil && s.addressAvailable(lease.Address, s.config.IPv6Ranges, hosts, hwaddr, sameClient) {
+		return lease.Address
+	}
+
+	if requested != nil && s.addressAvailable(requested, s.config.IPv6Ranges, hosts, hwaddr, sameClient) {
+		return requested
+	}
+
+	return s.allocateAddress(s.config.IPv6Ranges, hosts, hwaddr, sameClient)
+}
+
+// dhcpv6IANA builds an IA_NA option leasing the address, or reporting that no address is available if nil.
+func (s *Server) dhcpv6IANA(iaid uint32, ip net.IP) []byte {
+	expiry := uint32(s.config.IPv6Expiry / time.Second)
+
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data[0:4], iaid) // Identity Association Identifier
+
+	if ip == nil {
+		status := dhcpv6Status(dhcpv6StatusNoAddrsAvail)
+		sub := make([]byte, 4)
+		binary.BigEndian.PutUint16(sub[0:2], uint16(layers.DHCPv6OptStatusCode))
+		binary.BigEndian.PutUint16(sub[2:4], uint16(len(status.Data)))
+		return append(append(data, sub...), status.Data...)
+	}
+
+	binary.BigEndian.PutUint32(data[4:8], expiry/2)    // T1
+	binary.BigEndian.PutUint32(data[8:12], expiry/5*4) // T2
+
+	iaAddr := make([]byte, 28)
+	binary.BigEndian.PutUint16(iaAddr[0:2], uint16(layers.DHCPv6OptIAAddr)) // Sub-Option type
+	binary.BigEndian.PutUint16(iaAddr[2:4], 24)                             // Length (fixed at 24 bytes)
+	copy(iaAddr[4:20], ip.To16())                                           // Address
+	binary.BigEndian.PutUint32(iaAddr[20:24], expiry)                       // Preferred lifetime
+	binary.BigEndian.PutUint32(iaAddr[24:28], expiry)                       // Valid lifetime
+
+	return append(data, iaAddr...)
+}
+
+// dhcpv6IdentityAssociations returns the IA_NA options of the request along with their first requested
+// address.
+func dhcpv6IdentityAssociations(msg *layers.DHCPv6) []identityAssociation {
+	ias := []identityAssociation{}
+	for _, opt := range msg.Options {
+		if opt.Code != layers.DHCPv6OptIANA || len(opt.Data) < 12 {
+			continue
+		}
+
+		ia := identityAssociation{iaid: binary.BigEndian.Uint32(opt.Data[0:4])}
+
+		// Walk the IA_NA sub-options.
+		data := opt.Data[12:]
+		for len(data) >= 4 {
+			code := binary.BigEndian.Uint16(data[0:2])
+			length := int(binary.BigEndian.Uint16(data[2:4]))
+			if len(data) < 4+length {
+				break
+			}
+
+			value := data[4 : 4+length]
+			data = data[4+length:]
+
+			if code == uint16(layers.DHCPv6OptIAAddr) && len(value) >= 16 && ia.requested == nil {
+				ia.requested = net.IP(append([]byte{}, value[0:16]...))
+			}
+		}
+
+		ias = append(ias, ia)
+	}
+
+	return ias
+}
+
+// dhcpv6Hostname returns the first label of the client FQDN option, if any.
+func dhcpv6Hostname(msg *layers.DHCPv6) string {
+	value := dhcpv6Option(msg, dhcpv6OptClientFQDN)
+	if len(value) < 2 {
+		return ""
+	}
+
+	// Skip the flags.
+	length := int(value[1])
+	if length == 0 || len(value) < 2+length {
+		return ""
+	}
+
+	return string(value[2 : 2+length])
+}
+
+// dhcpv6Status builds a status code option.
+func dhcpv6Status(code uint16) layers.DHCPv6Option {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, code)
+	return layers.NewDHCPv6Option(layers.DHCPv6OptStatusCode, data)
+}
+
+// dhcpv6Option returns the value of the option, or nil if not present.
+func dhcpv6Option(msg *layers.DHCPv6, code layers.DHCPv6Opt) []byte {
+	for _, opt := range msg.Options {
+		if opt.Code == code {
+			if opt.Data == nil {
+				return []byte{}
+			}
+
+			return opt.Data
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package dhcpd
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// dnsTTL is the TTL of the local records, kept short as they follow the leases.
+const dnsTTL = 60
+
+// startDNS starts the DNS server listening over UDP and TCP on the address of the interface.
+func (s *Server) startDNS(address string, iface *net.Interface) error {
+	handler := dnsHandler{server: s}
+
+	udpConn, err := listenUDP("udp", address, iface, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to bind UDP address %q", address)
+	}
+
+	s.conns = append(s.conns, udpConn)
+
+	tcpListener, err := listenTCP(address, iface)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to bind TCP address %q", address)
+	}
+
+	s.conns = append(s.conns, tcpListener)
+
+	for _, server := range []*dns.Server{{PacketConn: udpConn, Handler: handler}, {Listener: tcpListener, Handler: handler}} {
+		server := server
+		s.dnsServers = append(s.dnsServers, server)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			err := server.ActivateAndServe()
+			if err != nil && !s.stopped() {
+				s.logger.Error("Failed to run DNS server", log.Ctx{"address": address, "err": err})
+			}
+		}()
+	}
+
+	return nil
+}
+
+type dnsHandler struct {
+	server *Server
+}
+
+// ServeDNS answers the queries for the local names and addresses, and forwards the others to the upstream
+// servers of the host.
+func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	msg := dns.Msg{}
+	msg.SetReply(r)
+
+	// We only support single questions.
+	if len(r.Question) != 1 {
+		msg.SetRcode(r, dns.RcodeFormatError)
+		d.write(w, &msg)
+		return
+	}
+
+	question := r.Question[0]
+	name := strings.ToLower(question.Name)
+
+	records, local := d.server.localRecords(name)
+	if !local {
+		d.forward(w, r)
+		return
+	}
+
+	msg.Authoritative = true
+	for _, record := range records {
+		if question.Qtype == dns.TypeANY || record.Header().Rrtype == question.Qtype {
+			msg.Answer = append(msg.Answer, record)
+		}
+	}
+
+	if len(records) == 0 {
+		msg.Rcode = dns.RcodeNameError
+	}
+
+	d.write(w, &msg)
+}
+
+// forward relays the query to the upstream servers of the host in order, until one of them answers.
+func (d dnsHandler) forward(w dns.ResponseWriter, r *dns.Msg) {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err == nil {
+		client := dns.Client{Net: w.RemoteAddr().Network(), Timeout: 2 * time.Second}
+		for _, server := range config.Servers {
+			resp, _, err := client.Exchange(r, net.JoinHostPort(server, config.Port))
+			if err != nil {
+				continue
+			}
+
+			d.write(w, resp)
+			return
+		}
+	}
+
+	msg := dns.Msg{}
+	msg.SetRcode(r, dns.RcodeServerFailure)
+	d.write(w, &msg)
+}
+
+// write sends the response to the client.
+func (d dnsHandler) write(w dns.ResponseWriter, msg *dns.Msg) {
+	err := w.WriteMsg(msg)
+	if err != nil {
+		d.server.logger.Error("Failed sending DNS response", log.Ctx{"err": err})
+	}
+}
+
+// localRecords returns the records of the name if it's part of the local domain or the reverse zone of one of
+// the subnets, along with whether it is.
+func (s *Server) localRecords(name string) ([]dns.RR, bool) {
+	if s.config.DNSDomain == "" {
+		return nil, false
+	}
+
+	names, err := s.localNames()
+	if err != nil {
+		s.logger.Error("Failed loading local DNS names", log.Ctx{"err": err})
+		return nil, false
+	}
+
+	domain := dns.Fqdn(strings.ToLower(s.config.DNSDomain))
+	if name == domain || strings.HasSuffix(name, "."+domain) {
+		records := []dns.RR{}
+		for _, ip := range names[strings.TrimSuffix(name, "."+domain)] {
+			if ip.To4() != nil {
+				records = append(records, &dns.A{Hdr: dnsHeader(name, dns.TypeA), A: ip.To4()})
+			} else {
+				records = append(records, &dns.AAAA{Hdr: dnsHeader(name, dns.TypeAAAA), AAAA: ip})
+			}
+		}
+
+		return records, true
+	}
+
+	ip := reverseAddress(name)
+	if ip == nil {
+		return nil, false
+	}
+
+	if (s.config.IPv4Subnet == nil || !s.config.IPv4Subnet.Contains(ip)) && (s.config.IPv6Subnet == nil || !s.config.IPv6Subnet.Contains(ip)) {
+		return nil, false
+	}
+
+	records := []dns.RR{}
+	for hostname, addresses := range names {
+		for _, address := range addresses {
+			if address.Equal(ip) {
+				records = append(records, &dns.PTR{Hdr: dnsHeader(name, dns.TypePTR), Ptr: hostname + "." + domain})
+			}
+		}
+	}
+
+	return records, true
+}
+
+// localNames returns the addresses of the local names, from the static host entries and, if enabled, the
+// hostnames provided by the clients.
+func (s *Server) localNames() (map[string][]net.IP, error) {
+	hosts, err := s.loadHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string][]net.IP{}
+	add := func(name string, ip net.IP) {
+		if name == "" || ip == nil {
+			return
+		}
+
+		name = strings.ToLower(name)
+		for _, existing := range names[name] {
+			if existing.Equal(ip) {
+				return
+			}
+		}
+
+		names[name] = append(names[name], ip)
+	}
+
+	s.leasesMu.Lock()
+	defer s.leasesMu.Unlock()
+
+	now := time.Now()
+	for _, entry := range hosts {
+		add(entry.name, entry.ipv4)
+		add(entry.name, entry.ipv6)
+
+		for _, lease := range s.leases {
+			if lease.Expiry.After(now) && lease.Hwaddr != nil && entry.hasHwaddr(lease.Hwaddr) {
+				add(entry.name, lease.Address)
+			}
+		}
+	}
+
+	if s.config.DNSDynamic {
+		for _, lease := range s.leases {
+			if lease.Expiry.After(now) {
+				add(lease.Hostname, lease.Address)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// dnsHeader returns the header of a local record.
+func dnsHeader(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: dnsTTL}
+}
+
+// reverseAddress returns the address of a reverse lookup name, or nil if not one.
+func reverseAddress(name string) net.IP {
+	name = strings.TrimSuffix(name, ".")
+
+	if strings.HasSuffix(name, ".in-addr.arpa") {
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(labels) != 4 {
+			return nil
+		}
+
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+
+		return net.ParseIP(strings.Join(labels, ".")).To4()
+	}
+
+	if strings.HasSuffix(name, ".ip6.arpa") {
+		nibbles := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(nibbles) != 32 {
+			return nil
+		}
+
+		var address strings.Builder
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			address.WriteString(nibbles[i])
+			if i > 0 && i%4 == 0 {
+				address.WriteString(":")
+			}
+		}
+
+		return net.ParseIP(address.String())
+	}
+
+	return nil
+}
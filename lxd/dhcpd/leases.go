@@ -0,0 +1,443 @@
+package dhcpd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// Lease represents an address leased to a client.
+type Lease struct {
+	Hwaddr   net.HardwareAddr // MAC address of the client (only known for IPv6 when part of its DUID).
+	Address  net.IP
+	Hostname string
+	Expiry   time.Time
+	ClientID []byte // DHCPv4 client identifier or DHCPv6 DUID.
+	IAID     uint32 // DHCPv6 identity association.
+}
+
+// isIPv4 returns whether the lease is a DHCPv4 lease.
+func (l *Lease) isIPv4() bool {
+	return l.Address.To4() != nil
+}
+
+// host represents a static host entry.
+type host struct {
+	hwaddrs []net.HardwareAddr
+	ipv4    net.IP
+	ipv6    net.IP
+	name    string
+}
+
+// hasHwaddr returns whether the entry applies to the MAC address.
+func (h *host) hasHwaddr(hwaddr net.HardwareAddr) bool {
+	for _, entry := range h.hwaddrs {
+		if bytes.Equal(entry, hwaddr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadHosts reads the static host entries. Each file holds a single dnsmasq dhcp-host line made of MAC
+// addresses, an optional IPv4 address, an optional bracketed IPv6 address and an optional name.
+func (s *Server) loadHosts() ([]host, error) {
+	files, err := ioutil.ReadDir(s.config.HostsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	hosts := []host{}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(s.config.HostsPath, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		entry := host{}
+		for _, field := range strings.Split(strings.TrimSpace(string(content)), ",") {
+			hwaddr, err := net.ParseMAC(field)
+			if err == nil {
+				entry.hwaddrs = append(entry.hwaddrs, hwaddr)
+				continue
+			}
+
+			if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+				entry.ipv6 = net.ParseIP(field[1 : len(field)-1])
+				continue
+			}
+
+			ip := net.ParseIP(field)
+			if ip != nil && ip.To4() != nil {
+				entry.ipv4 = ip.To4()
+				continue
+			}
+
+			if field != "" {
+				entry.name = field
+			}
+		}
+
+		hosts = append(hosts, entry)
+	}
+
+	return hosts, nil
+}
+
+// findHost returns the static host entry of the MAC address, if any.
+func findHost(hosts []host, hwaddr net.HardwareAddr) *host {
+	if hwaddr == nil {
+		return nil
+	}
+
+	for i := range hosts {
+		if hosts[i].hasHwaddr(hwaddr) {
+			return &hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// loadLeases reads the leases file left by a previous run, dropping the expired leases.
+func (s *Server) loadLeases() error {
+	file, err := os.Open(s.config.LeasesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 5 {
+			continue
+		}
+
+		expiry, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || time.Unix(expiry, 0).Before(now) {
+			continue
+		}
+
+		lease := &Lease{
+			Address: net.ParseIP(fields[2]),
+			Expiry:  time.Unix(expiry, 0),
+		}
+
+		if lease.Address == nil {
+			continue
+		}
+
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+
+		if fields[4] != "*" {
+			lease.ClientID = parseHex(fields[4])
+		}
+
+		if lease.isIPv4() {
+			lease.Address = lease.Address.To4()
+			lease.Hwaddr, err = net.ParseMAC(fields[1])
+			if err != nil {
+				continue
+			}
+		} else {
+			iaid, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				continue
+			}
+
+			lease.IAID = uint32(iaid)
+			lease.Hwaddr = duidHwaddr(lease.ClientID)
+		}
+
+		s.leases = append(s.leases, lease)
+	}
+
+	return scanner.Err()
+}
+
+// writeLeases saves the leases in the dnsmasq leases file format, the IPv6 leases following the server DUID.
+// Must be called with leasesMu held.
+func (s *Server) writeLeases() error {
+	var buf bytes.Buffer
+	for _, lease := range s.leases {
+		if lease.isIPv4() {
+			fmt.Fprintf(&buf, "%d %s %s %s %s\n", lease.Expiry.Unix(), lease.Hwaddr, lease.Address, orStar(lease.Hostname), orStar(formatHex(lease.ClientID)))
+		}
+	}
+
+	if s.config.IPv6Stateful {
+		fmt.Fprintf(&buf, "duid %s\n", formatHex(s.duid))
+		for _, lease := range s.leases {
+			if !lease.isIPv4() {
+				fmt.Fprintf(&buf, "%d %d %s %s %s\n", lease.Expiry.Unix(), lease.IAID, lease.Address, orStar(lease.Hostname), formatHex(lease.ClientID))
+			}
+		}
+	}
+
+	// Replace the file atomically as it's read by other parts of LXD.
+	tmpPath := s.config.LeasesPath + ".tmp"
+	err := ioutil.WriteFile(tmpPath, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, s.config.LeasesPath)
+}
+
+// updateLeases applies the change to the leases under lock, saves them if changed and notifies the granted and
+// released leases.
+func (s *Server) updateLeases(change func() (granted []Lease, released []Lease, changed bool)) {
+	s.leasesMu.Lock()
+	granted, released, changed := change()
+	if changed {
+		err := s.writeLeases()
+		if err != nil {
+			s.logger.Error("Failed writing leases", log.Ctx{"err": err})
+		}
+	}
+	s.leasesMu.Unlock()
+
+	if s.config.OnLease == nil {
+		return
+	}
+
+	for _, lease := range granted {
+		s.config.OnLease(lease, false)
+	}
+
+	for _, lease := range released {
+		s.config.OnLease(lease, true)
+	}
+}
+
+// grantLease records the lease of the address to the client matched by the function, replacing its previous
+// leases of the same family and any stale lease of the address.
+func (s *Server) grantLease(lease Lease, sameClient func(l *Lease) bool) {
+	s.updateLeases(func() ([]Lease, []Lease, bool) {
+		granted := []Lease{}
+		released := []Lease{}
+		renewal := false
+
+		leases := make([]*Lease, 0, len(s.leases)+1)
+		for _, l := range s.leases {
+			if l.isIPv4() != lease.isIPv4() || (!sameClient(l) && !l.Address.Equal(lease.Address)) {
+				leases = append(leases, l)
+				continue
+			}
+
+			if l.Address.Equal(lease.Address) && sameClient(l) {
+				renewal = true
+				continue
+			}
+
+			released = append(released, *l)
+		}
+
+		// Only notify new leases, not renewals.
+		if !renewal {
+			granted = append(granted, lease)
+		}
+
+		s.leases = append(leases, &lease)
+
+		return granted, released, true
+	})
+}
+
+// releaseLeases removes the leases matched by the function.
+func (s *Server) releaseLeases(match func(l *Lease) bool) {
+	s.updateLeases(func() ([]Lease, []Lease, bool) {
+		released := []Lease{}
+
+		leases := make([]*Lease, 0, len(s.leases))
+		for _, l := range s.leases {
+			if match(l) {
+				released = append(released, *l)
+				continue
+			}
+
+			leases = append(leases, l)
+		}
+
+		s.leases = leases
+
+		return nil, released, len(released) > 0
+	})
+}
+
+// findLease returns a copy of the first lease matched by the function.
+func (s *Server) findLease(match func(l *Lease) bool) *Lease {
+	s.leasesMu.Lock()
+	defer s.leasesMu.Unlock()
+
+	for _, l := range s.leases {
+		if match(l) {
+			lease := *l
+			return &lease
+		}
+	}
+
+	return nil
+}
+
+// expireLeases periodically removes the expired leases.
+func (s *Server) expireLeases() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		s.releaseLeases(func(l *Lease) bool {
+			return l.Expiry.Before(now)
+		})
+	}
+}
+
+// addressAvailable returns whether the address can be leased to the client matched by the function, that is
+// it's part of the ranges, isn't used by the server and isn't statically or dynamically assigned to another
+// client.
+func (s *Server) addressAvailable(ip net.IP, ranges []Range, hosts []host, hwaddr net.HardwareAddr, sameClient func(l *Lease) bool) bool {
+	if ip == nil || !inRanges(ip, ranges) {
+		return false
+	}
+
+	for _, reserved := range []net.IP{s.config.IPv4Address, s.config.IPv4Gateway, s.config.IPv6Address} {
+		if reserved != nil && reserved.Equal(ip) {
+			return false
+		}
+	}
+
+	for _, entry := range hosts {
+		if (ip.Equal(entry.ipv4) || ip.Equal(entry.ipv6)) && (hwaddr == nil || !entry.hasHwaddr(hwaddr)) {
+			return false
+		}
+	}
+
+	lease := s.findLease(func(l *Lease) bool {
+		return l.Address.Equal(ip) && !sameClient(l) && l.Expiry.After(time.Now())
+	})
+
+	return lease == nil
+}
+
+// allocateAddress returns the first available address of the ranges, or nil if they are exhausted.
+func (s *Server) allocateAddress(ranges []Range, hosts []host, hwaddr net.HardwareAddr, sameClient func(l *Lease) bool) net.IP {
+	for _, r := range ranges {
+		for ip := r.Start; compareIP(ip, r.End) <= 0; ip = nextIP(ip) {
+			if s.addressAvailable(ip, ranges, hosts, hwaddr, sameClient) {
+				return ip
+			}
+		}
+	}
+
+	return nil
+}
+
+// compareIP compares two addresses of the same family.
+func compareIP(a net.IP, b net.IP) int {
+	if a.To4() != nil && b.To4() != nil {
+		return bytes.Compare(a.To4(), b.To4())
+	}
+
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// nextIP returns the address following the given one.
+func nextIP(ip net.IP) net.IP {
+	size := len(ip)
+	if ip.To4() != nil {
+		ip = ip.To4()
+		size = net.IPv4len
+	}
+
+	n := big.NewInt(0).SetBytes(ip)
+	n.Add(n, big.NewInt(1))
+
+	next := make(net.IP, size)
+	b := n.Bytes()
+	if len(b) > size {
+		// Overflowed, return an address past any range end.
+		for i := range next {
+			next[i] = 0xff
+		}
+
+		return append(next, 0xff)
+	}
+
+	copy(next[size-len(b):], b)
+	return next
+}
+
+// duidHwaddr returns the MAC address from a DUID-LLT or DUID-LL of an Ethernet client, or nil.
+func duidHwaddr(duid []byte) net.HardwareAddr {
+	if len(duid) == 14 && duid[1] == 1 && duid[3] == 1 {
+		return net.HardwareAddr(duid[8:14])
+	}
+
+	if len(duid) == 10 && duid[1] == 3 && duid[3] == 1 {
+		return net.HardwareAddr(duid[4:10])
+	}
+
+	return nil
+}
+
+// formatHex formats the bytes as colon separated hexadecimal, like dnsmasq does for DUIDs and client IDs.
+func formatHex(b []byte) string {
+	parts := make([]string, 0, len(b))
+	for _, c := range b {
+		parts = append(parts, fmt.Sprintf("%02x", c))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// parseHex parses colon separated hexadecimal.
+func parseHex(s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, ":", "", -1))
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// orStar returns the value, or "*" if empty.
+func orStar(value string) string {
+	if value == "" {
+		return "*"
+	}
+
+	return value
+}
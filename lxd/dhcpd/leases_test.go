@@ -0,0 +1,102 @@
+package dhcpd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+func TestLeasesRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-dhcpd-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := Config{
+		LeasesPath:   filepath.Join(dir, "dnsmasq.leases"),
+		IPv6Stateful: true,
+	}
+
+	hwaddr, _ := net.ParseMAC("00:16:3e:11:22:33")
+	duid := []byte{0, 3, 0, 1, 0, 0x16, 0x3e, 0x11, 0x22, 0x33}
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	s := NewServer(config, logger.Log)
+	s.duid = []byte{0, 3, 0, 1, 0, 0x16, 0x3e, 0, 0, 1}
+	s.leases = []*Lease{
+		{Hwaddr: hwaddr, Address: net.ParseIP("10.0.0.2").To4(), Hostname: "c1", Expiry: expiry},
+		{Hwaddr: hwaddr, Address: net.ParseIP("fd42::2"), Expiry: expiry, ClientID: duid, IAID: 42},
+		{Hwaddr: hwaddr, Address: net.ParseIP("10.0.0.3").To4(), Expiry: time.Now().Add(-time.Hour)},
+	}
+
+	err = s.writeLeases()
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(config.LeasesPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), " 00:16:3e:11:22:33 10.0.0.2 c1 *\n")
+	assert.Contains(t, string(content), "duid 00:03:00:01:00:16:3e:00:00:01\n")
+	assert.Contains(t, string(content), " 42 fd42::2 * 00:03:00:01:00:16:3e:11:22:33\n")
+
+	// Expired leases are dropped on load.
+	loaded := NewServer(config, logger.Log)
+	err = loaded.loadLeases()
+	require.NoError(t, err)
+	require.Len(t, loaded.leases, 2)
+	assert.Equal(t, "10.0.0.2", loaded.leases[0].Address.String())
+	assert.Equal(t, hwaddr, loaded.leases[0].Hwaddr)
+	assert.Equal(t, expiry.Unix(), loaded.leases[0].Expiry.Unix())
+	assert.Equal(t, uint32(42), loaded.leases[1].IAID)
+	assert.Equal(t, duid, loaded.leases[1].ClientID)
+	assert.Equal(t, hwaddr, loaded.leases[1].Hwaddr)
+}
+
+func TestLoadHosts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-dhcpd-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "c1"), []byte("00:16:3e:11:22:33,10.0.0.10,[fd42::10],c1\n"), 0644)
+	require.NoError(t, err)
+
+	s := NewServer(Config{HostsPath: dir}, logger.Log)
+	hosts, err := s.loadHosts()
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+
+	hwaddr, _ := net.ParseMAC("00:16:3e:11:22:33")
+	entry := findHost(hosts, hwaddr)
+	require.NotNil(t, entry)
+	assert.Equal(t, "10.0.0.10", entry.ipv4.String())
+	assert.Equal(t, "fd42::10", entry.ipv6.String())
+	assert.Equal(t, "c1", entry.name)
+}
+
+func TestAllocateAddress(t *testing.T) {
+	s := NewServer(Config{IPv4Address: net.ParseIP("10.0.0.1")}, logger.Log)
+	other, _ := net.ParseMAC("00:16:3e:00:00:01")
+	s.leases = []*Lease{{Hwaddr: other, Address: net.ParseIP("10.0.0.2").To4(), Expiry: time.Now().Add(time.Hour)}}
+
+	hwaddr, _ := net.ParseMAC("00:16:3e:00:00:02")
+	hosts := []host{{hwaddrs: []net.HardwareAddr{other}, ipv4: net.ParseIP("10.0.0.3").To4()}}
+	ranges := []Range{{Start: net.ParseIP("10.0.0.1"), End: net.ParseIP("10.0.0.5")}}
+	sameClient := func(l *Lease) bool { return l.Hwaddr.String() == hwaddr.String() }
+
+	ip := s.allocateAddress(ranges, hosts, hwaddr, sameClient)
+	assert.Equal(t, "10.0.0.4", ip.String())
+
+	assert.False(t, s.addressAvailable(net.ParseIP("10.0.0.6"), ranges, hosts, hwaddr, sameClient))
+}
+
+func TestReverseAddress(t *testing.T) {
+	assert.Equal(t, "10.0.0.2", reverseAddress("2.0.0.10.in-addr.arpa.").String())
+	assert.Equal(t, "fd42::2", reverseAddress("2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.2.4.d.f.ip6.arpa.").String())
+	assert.Nil(t, reverseAddress("c1.lxd."))
+}
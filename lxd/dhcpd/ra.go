@@ -0,0 +1,136 @@
+package dhcpd
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// ICMPv6 message types of router discovery (RFC 4861).
+const (
+	icmpv6RouterSolicitation  = 133
+	icmpv6RouterAdvertisement = 134
+)
+
+// Router advertisement timings, with a few quick initial advertisements for clients starting with the network.
+const (
+	raInitialCount    = 3
+	raInitialInterval = 4 * time.Second
+	raInterval        = 200 * time.Second
+	raMinDelay        = 3 * time.Second
+	raRouterLifetime  = 1800
+)
+
+// serveRouterSolicitations triggers a router advertisement for each router solicitation received on the
+// connection until it's closed.
+func (s *Server) serveRouterSolicitations(conn net.PacketConn) {
+	packet := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			if s.readError("ICMPv6", err) {
+				return
+			}
+
+			continue
+		}
+
+		if n < 1 || packet[0] != icmpv6RouterSolicitation {
+			continue
+		}
+
+		select {
+		case s.solicit <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// sendRouterAdvertisements periodically advertises the subnet, and whenever a router solicitation is received.
+func (s *Server) sendRouterAdvertisements(conn net.PacketConn, iface *net.Interface) {
+	defer s.wg.Done()
+
+	dst := &net.IPAddr{IP: net.IPv6linklocalallnodes, Zone: iface.Name}
+	var last time.Time
+
+	for count := 0; ; count++ {
+		interval := raInterval
+		if count < raInitialCount {
+			interval = raInitialInterval
+		}
+
+		if time.Since(last) >= raMinDelay {
+			_, err := conn.WriteTo(s.routerAdvertisement(), dst)
+			if err != nil && !s.stopped() {
+				s.logger.Error("Failed sending router advertisement", log.Ctx{"err": err})
+			}
+
+			last = time.Now()
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.solicit:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// routerAdvertisement builds the router advertisement of the subnet. The checksum is filled by the kernel.
+func (s *Server) routerAdvertisement() []byte {
+	ra := make([]byte, 16)
+	ra[0] = icmpv6RouterAdvertisement
+	ra[4] = 64 // Current hop limit
+
+	// Managed address configuration and other configuration flags.
+	if s.config.IPv6Stateful {
+		ra[5] |= 0x80
+	}
+
+	if s.config.IPv6DHCP {
+		ra[5] |= 0x40
+	}
+
+	binary.BigEndian.PutUint16(ra[6:8], raRouterLifetime)
+
+	// Source link-layer address.
+	ra = append(ra, 1, 1)
+	ra = append(ra, s.hwaddr...)
+
+	if s.config.MTU > 0 {
+		mtu := make([]byte, 8)
+		mtu[0] = 5 // Type
+		mtu[1] = 1 // Length (in units of 8 bytes)
+		binary.BigEndian.PutUint32(mtu[4:8], s.config.MTU)
+		ra = append(ra, mtu...)
+	}
+
+	// Prefix information, only allowing autoconfiguration on /64 subnets without stateful DHCPv6.
+	ones, _ := s.config.IPv6Subnet.Mask.Size()
+	prefix := make([]byte, 32)
+	prefix[0] = 3 // Type
+	prefix[1] = 4 // Length (in units of 8 bytes)
+	prefix[2] = byte(ones)
+	prefix[3] = 0x80 // On-link
+	if ones == 64 && !s.config.IPv6Stateful {
+		prefix[3] |= 0x40 // Autonomous address configuration
+	}
+
+	binary.BigEndian.PutUint32(prefix[4:8], 86400)  // Valid lifetime
+	binary.BigEndian.PutUint32(prefix[8:12], 14400) // Preferred lifetime
+	copy(prefix[16:32], s.config.IPv6Subnet.IP.To16())
+	ra = append(ra, prefix...)
+
+	// Recursive DNS server (RFC 8106).
+	rdnss := make([]byte, 24)
+	rdnss[0] = 25 // Type
+	rdnss[1] = 3  // Length (in units of 8 bytes)
+	binary.BigEndian.PutUint32(rdnss[4:8], raRouterLifetime)
+	copy(rdnss[8:24], s.config.IPv6Address.To16())
+	ra = append(ra, rdnss...)
+
+	return ra
+}
@@ -0,0 +1,223 @@
+// Package dhcpd implements the built-in DHCPv4, DHCPv6, router advertisement and DNS server of managed bridges.
+//
+// The server keeps the static host entries and the leases in the same formats as dnsmasq, so that the rest of
+// LXD can inspect and release them regardless of the server in use.
+package dhcpd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Range represents a range of addresses handed out by the server.
+type Range struct {
+	Start net.IP
+	End   net.IP
+}
+
+// Config represents the configuration of the server.
+type Config struct {
+	Interface  string // Name of the interface to serve.
+	LeasesPath string // Path of the leases file.
+	HostsPath  string // Path of the directory of static host entries.
+	MTU        uint32 // MTU advertised to the clients (0 to not advertise any).
+
+	DNSDomain  string   // Domain of the local names (empty to disable them).
+	DNSDynamic bool     // Whether the hostnames provided by the clients are registered.
+	DNSSearch  []string // Search domains advertised to the clients.
+
+	IPv4Address net.IP
+	IPv4Subnet  *net.IPNet
+	IPv4Gateway net.IP // Gateway advertised instead of the server address if set.
+	IPv4DHCP    bool
+	IPv4Ranges  []Range
+	IPv4Expiry  time.Duration
+
+	IPv6Address  net.IP
+	IPv6Subnet   *net.IPNet
+	IPv6DHCP     bool // Whether DHCPv6 is answered at all (stateless unless IPv6Stateful).
+	IPv6Stateful bool
+	IPv6Ranges   []Range
+	IPv6Expiry   time.Duration
+
+	// OnLease is called when a lease is granted, or when it's released or expires.
+	OnLease func(lease Lease, released bool)
+}
+
+// Server represents a running server.
+type Server struct {
+	config Config
+	logger logger.Logger
+	hwaddr net.HardwareAddr
+	duid   []byte
+
+	leases   []*Lease
+	leasesMu sync.Mutex
+
+	conns      []io.Closer
+	dnsServers []*dns.Server
+	solicit    chan struct{}
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewServer returns a new server instance for the given configuration.
+func NewServer(config Config, l logger.Logger) *Server {
+	return &Server{
+		config:  config,
+		logger:  l,
+		solicit: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts serving the interface.
+func (s *Server) Start() error {
+	iface, err := net.InterfaceByName(s.config.Interface)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading interface %q", s.config.Interface)
+	}
+
+	// Use a DUID-LL based on the interface's MAC address.
+	s.hwaddr = iface.HardwareAddr
+	s.duid = append([]byte{0, 3, 0, 1}, iface.HardwareAddr...)
+
+	err = s.loadLeases()
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading leases from %q", s.config.LeasesPath)
+	}
+
+	err = s.start(iface)
+	if err != nil {
+		s.Stop()
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.expireLeases()
+
+	return nil
+}
+
+func (s *Server) start(iface *net.Interface) error {
+	if s.config.IPv4Address != nil && s.config.IPv4DHCP {
+		conn, err := listenUDP("udp4", "0.0.0.0:67", iface, nil)
+		if err != nil {
+			return errors.Wrap(err, "Failed listening for DHCPv4 requests")
+		}
+
+		s.serve(conn, s.serveDHCPv4)
+	}
+
+	if s.config.IPv6Address != nil {
+		conn, err := listenICMPv6(iface)
+		if err != nil {
+			return errors.Wrap(err, "Failed listening for router solicitations")
+		}
+
+		s.serve(conn, s.serveRouterSolicitations)
+
+		s.wg.Add(1)
+		go s.sendRouterAdvertisements(conn, iface)
+
+		if s.config.IPv6DHCP {
+			conn, err := listenUDP("udp6", "[::]:547", iface, net.ParseIP("ff02::1:2"))
+			if err != nil {
+				return errors.Wrap(err, "Failed listening for DHCPv6 requests")
+			}
+
+			s.serve(conn, s.serveDHCPv6)
+		}
+	}
+
+	for _, address := range []net.IP{s.config.IPv4Address, s.config.IPv6Address} {
+		if address == nil {
+			continue
+		}
+
+		err := s.startDNS(net.JoinHostPort(address.String(), "53"), iface)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serve records the connection to be closed on stop and runs the handler loop for it.
+func (s *Server) serve(conn net.PacketConn, handler func(conn net.PacketConn)) {
+	s.conns = append(s.conns, conn)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		handler(conn)
+	}()
+}
+
+// Stop stops the server and waits for all its handlers to exit.
+func (s *Server) Stop() {
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+
+	close(s.stop)
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+
+	for _, server := range s.dnsServers {
+		err := server.Shutdown()
+		if err != nil {
+			s.logger.Warn("Failed stopping DNS server", log.Ctx{"err": err})
+		}
+	}
+
+	s.wg.Wait()
+}
+
+// stopped returns whether the server has been asked to stop.
+func (s *Server) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// readError logs a read error unless the server is stopping, and returns whether the handler should exit.
+func (s *Server) readError(proto string, err error) bool {
+	if s.stopped() {
+		return true
+	}
+
+	s.logger.Error(fmt.Sprintf("Failed reading %s packet", proto), log.Ctx{"err": err})
+
+	// Don't spin on a broken socket.
+	time.Sleep(time.Second)
+	return false
+}
+
+// inRanges returns whether the address is part of one of the ranges.
+func inRanges(ip net.IP, ranges []Range) bool {
+	for _, r := range ranges {
+		if compareIP(ip, r.Start) >= 0 && compareIP(ip, r.End) <= 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package dhcpd
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// control returns a socket control function binding the socket to the interface before it's bound to its
+// address, so that each network can have its own server on the same ports.
+func control(iface *net.Interface, setup func(fd int) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		cerr := c.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+			if err != nil {
+				return
+			}
+
+			err = unix.BindToDevice(int(fd), iface.Name)
+			if err != nil {
+				return
+			}
+
+			// Allow binding to addresses still going through duplicate address detection.
+			err = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_FREEBIND, 1)
+			if err != nil {
+				return
+			}
+
+			if setup != nil {
+				err = setup(int(fd))
+			}
+		})
+		if cerr != nil {
+			return cerr
+		}
+
+		return err
+	}
+}
+
+// listenUDP listens on the UDP address of the interface, optionally joining the IPv6 multicast group.
+func listenUDP(network string, address string, iface *net.Interface, group net.IP) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: control(iface, func(fd int) error {
+			if network == "udp4" {
+				return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_BROADCAST, 1)
+			}
+
+			if group != nil {
+				mreq := &unix.IPv6Mreq{Interface: uint32(iface.Index)}
+				copy(mreq.Multiaddr[:], group.To16())
+				return unix.SetsockoptIPv6Mreq(fd, unix.IPPROTO_IPV6, unix.IPV6_JOIN_GROUP, mreq)
+			}
+
+			return nil
+		}),
+	}
+
+	conn, err := lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
+
+// listenICMPv6 opens a raw ICMPv6 socket on the interface, with the hop limit required by neighbor discovery.
+func listenICMPv6(iface *net.Interface) (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: control(iface, func(fd int) error {
+			for _, opt := range []int{unix.IPV6_MULTICAST_HOPS, unix.IPV6_UNICAST_HOPS} {
+				err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, opt, 255)
+				if err != nil {
+					return err
+				}
+			}
+
+			return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, iface.Index)
+		}),
+	}
+
+	return lc.ListenPacket(context.Background(), "ip6:ipv6-icmp", "::")
+}
+
+// listenTCP listens on the TCP address of the interface.
+func listenTCP(address string, iface *net.Interface) (net.Listener, error) {
+	lc := net.ListenConfig{Control: control(iface, nil)}
+	return lc.Listen(context.Background(), "tcp", address)
+}
@@ -0,0 +1,144 @@
+package dns
+
+import (
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+type dnsHandler struct {
+	server *Server
+}
+
+// ServeDNS answers the queries for the zones the server is authoritative for, including full zone transfers
+// (AXFR) for the peers of the zone.
+func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	msg := dns.Msg{}
+	msg.SetReply(r)
+
+	// We only support single questions.
+	if len(r.Question) != 1 {
+		msg.SetRcode(r, dns.RcodeFormatError)
+		d.write(w, &msg)
+		return
+	}
+
+	question := r.Question[0]
+
+	zone, err := d.findZone(question.Name)
+	if err != nil {
+		logger.Errorf("Failed loading DNS zone for %q: %v", question.Name, err)
+		msg.SetRcode(r, dns.RcodeServerFailure)
+		d.write(w, &msg)
+		return
+	}
+
+	// Refuse queries for names outside of our zones.
+	if zone == nil {
+		msg.SetRcode(r, dns.RcodeRefused)
+		d.write(w, &msg)
+		return
+	}
+
+	if question.Qtype == dns.TypeAXFR {
+		if !zone.allowTransfer(w.RemoteAddr()) {
+			msg.SetRcode(r, dns.RcodeRefused)
+			d.write(w, &msg)
+			return
+		}
+
+		// The transfer starts and ends with the SOA record.
+		records := make([]dns.RR, 0, len(zone.Records)+1)
+		records = append(records, zone.Records...)
+		records = append(records, zone.Records[0])
+
+		ch := make(chan *dns.Envelope)
+		tr := new(dns.Transfer)
+		go func() {
+			ch <- &dns.Envelope{RR: records}
+			close(ch)
+		}()
+
+		err = tr.Out(w, r, ch)
+		if err != nil {
+			logger.Errorf("Failed transferring DNS zone %q: %v", zone.Name, err)
+		}
+
+		w.Hijack()
+		return
+	}
+
+	msg.Authoritative = true
+
+	nameFound := false
+	for _, record := range zone.Records {
+		if !strings.EqualFold(record.Header().Name, question.Name) {
+			continue
+		}
+
+		nameFound = true
+		if question.Qtype == dns.TypeANY || record.Header().Rrtype == question.Qtype {
+			msg.Answer = append(msg.Answer, record)
+		}
+	}
+
+	// Negative answers include the SOA record for caching.
+	if len(msg.Answer) < 1 {
+		if !nameFound {
+			msg.Rcode = dns.RcodeNameError
+		}
+
+		msg.Ns = append(msg.Ns, zone.Records[0])
+	}
+
+	d.write(w, &msg)
+}
+
+// write sends the response to the client.
+func (d dnsHandler) write(w dns.ResponseWriter, msg *dns.Msg) {
+	err := w.WriteMsg(msg)
+	if err != nil {
+		logger.Errorf("Failed sending DNS response: %v", err)
+	}
+}
+
+// findZone returns the closest enclosing zone of the name, or nil if the server isn't authoritative for it.
+func (d dnsHandler) findZone(name string) (*Zone, error) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	for i := range labels {
+		zone, err := d.server.zoneRetriever(strings.Join(labels[i:], "."))
+		if err != nil {
+			return nil, err
+		}
+
+		if zone != nil && len(zone.Records) > 0 {
+			return zone, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// allowTransfer returns whether the remote address is allowed to transfer the zone.
+func (z *Zone) allowTransfer(remote net.Addr) bool {
+	host, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, peer := range z.Peers {
+		if peer.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,144 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Zone represents a DNS zone served by the server.
+type Zone struct {
+	Name    string   // Name of the zone, without the trailing dot.
+	Records []dns.RR // Records of the zone, starting with the SOA record.
+	Peers   []net.IP // Addresses allowed to transfer (AXFR) the zone.
+}
+
+// ZoneRetriever returns the zone with the given name, or nil if the server isn't authoritative for it.
+type ZoneRetriever func(name string) (*Zone, error)
+
+// Server represents the built-in DNS server.
+type Server struct {
+	tcpDNS *dns.Server
+	udpDNS *dns.Server
+
+	zoneRetriever ZoneRetriever
+
+	mu sync.Mutex
+}
+
+// NewServer returns a new server instance using the given zone retriever.
+func NewServer(zoneRetriever ZoneRetriever) *Server {
+	return &Server{zoneRetriever: zoneRetriever}
+}
+
+// Start starts the DNS server listening on the given address (port 53 by default).
+func (s *Server) Start(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.start(address)
+}
+
+func (s *Server) start(address string) error {
+	// Set default port if required.
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "53")
+	}
+
+	// Setup the handler.
+	handler := dnsHandler{server: s}
+
+	// Spawn the DNS server over TCP.
+	tcpListener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to bind TCP address %q", address)
+	}
+
+	s.tcpDNS = &dns.Server{Listener: tcpListener, Handler: handler}
+
+	// Spawn the DNS server over UDP.
+	udpListener, err := net.ListenPacket("udp", address)
+	if err != nil {
+		tcpListener.Close()
+		s.tcpDNS = nil
+		return errors.Wrapf(err, "Failed to bind UDP address %q", address)
+	}
+
+	s.udpDNS = &dns.Server{PacketConn: udpListener, Handler: handler}
+
+	go func() {
+		err := s.tcpDNS.ActivateAndServe()
+		if err != nil {
+			logger.Errorf("Failed to run DNS server over TCP: %v", err)
+		}
+	}()
+
+	go func() {
+		err := s.udpDNS.ActivateAndServe()
+		if err != nil {
+			logger.Errorf("Failed to run DNS server over UDP: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the DNS server.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop()
+}
+
+func (s *Server) stop() error {
+	errs := []error{}
+
+	if s.tcpDNS != nil {
+		err := s.tcpDNS.Shutdown()
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		s.tcpDNS = nil
+	}
+
+	if s.udpDNS != nil {
+		err := s.udpDNS.Shutdown()
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		s.udpDNS = nil
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to stop DNS server: %v", errs)
+	}
+
+	return nil
+}
+
+// Reconfigure restarts the DNS server on the new address, or stops it if the address is empty.
+func (s *Server) Reconfigure(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.stop()
+	if err != nil {
+		return err
+	}
+
+	if address == "" {
+		return nil
+	}
+
+	return s.start(address)
+}
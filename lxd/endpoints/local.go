@@ -4,6 +4,7 @@ package endpoints
 
 import (
 	"net"
+	"os"
 )
 
 // Create a new net.Listener bound to the unix socket of the local endpoint.
@@ -48,3 +49,23 @@ func localSetAccess(path string, group string) error {
 
 	return nil
 }
+
+// LocalUpdateAccess changes the file mode of the local endpoint unix socket. If
+// public is true, any user of the system can connect to it, authorization being
+// then performed by the HTTP server using the socket ucred struct.
+func (e *Endpoints) LocalUpdateAccess(public bool) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	listener, ok := e.listeners[local]
+	if !ok {
+		return nil
+	}
+
+	mode := os.FileMode(0660)
+	if public {
+		mode = 0666
+	}
+
+	return socketUnixSetPermissions(listener.Addr().String(), mode)
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
 )
 
 var eventsCmd = APIEndpoint{
 	Path: "events",
 
-	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowProjectPermission("containers", "view")},
 }
 
 type eventsServe struct {
@@ -37,6 +42,40 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 		typeStr = "logging,operation,lifecycle"
 	}
 
+	// Events which aren't tied to a project, like logging events, are only sent to administrators.
+	isAdmin := d.userIsAdmin(r)
+	types := []string{}
+	for _, entry := range strings.Split(typeStr, ",") {
+		if entry == "logging" && !isAdmin {
+			continue
+		}
+
+		types = append(types, entry)
+	}
+
+	// Parse the filter, before upgrading the connection so that errors can be reported.
+	filter := events.Filter{Level: r.FormValue("level")}
+	if filter.Level != "" {
+		_, err := log.LvlFromString(filter.Level)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid level %q", filter.Level)).Render(w)
+		}
+	}
+
+	actionStr := r.FormValue("action")
+	if actionStr != "" {
+		filter.Actions = strings.Split(actionStr, ",")
+	}
+
+	since := r.FormValue("since")
+	if since != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since %q", since)).Render(w)
+		}
+	}
+
 	// Upgrade the connection to websocket
 	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -59,7 +98,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
 	// local events.
-	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r))
+	listener, err := d.events.AddListener(project, c, types, serverName, isClusterNotification(r), !isAdmin, filter)
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,18 +13,81 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 )
 
+// historySize is the number of past events kept to be replayed to reconnecting listeners.
+const historySize = 1024
+
 // Server represents an instance of an event server.
 type Server struct {
 	debug   bool
 	verbose bool
 
 	listeners map[string]*Listener
+	history   []historyEntry
 	lock      sync.Mutex
 }
 
+// historyEntry is a past event along with how it was broadcast.
+type historyEntry struct {
+	group     string
+	event     api.Event
+	isForward bool
+}
+
+// Filter restricts the events sent to a listener beyond their type.
+type Filter struct {
+	// Minimum level of the logging events (dbug, info, warn, eror or crit).
+	Level string
+
+	// Actions of the lifecycle events, optionally ending with a "*" wildcard.
+	Actions []string
+
+	// When set, the past events which happened after it are replayed to the listener first.
+	Since time.Time
+}
+
+// match returns whether the event passes the filter.
+func (f Filter) match(event api.Event) bool {
+	if event.Type == "logging" && f.Level != "" {
+		logEntry := api.EventLogging{}
+		err := json.Unmarshal(event.Metadata, &logEntry)
+		if err != nil {
+			return false
+		}
+
+		minLevel, err := log.LvlFromString(f.Level)
+		if err != nil {
+			return false
+		}
+
+		level, err := log.LvlFromString(logEntry.Level)
+		if err != nil || level > minLevel {
+			return false
+		}
+	}
+
+	if event.Type == "lifecycle" && len(f.Actions) > 0 {
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil {
+			return false
+		}
+
+		for _, action := range f.Actions {
+			if action == lifecycle.Action || (strings.HasSuffix(action, "*") && strings.HasPrefix(lifecycle.Action, strings.TrimSuffix(action, "*"))) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
 // NewServer returns a new event server.
 func NewServer(debug bool, verbose bool) *Server {
 	server := &Server{
@@ -35,27 +99,51 @@ func NewServer(debug bool, verbose bool) *Server {
 	return server
 }
 
-// AddListener creates and returns a new event listener.
-func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+// AddListener creates and returns a new event listener. If groupOnly is true, the listener doesn't get events which
+// aren't tied to a group. The past events requested by the filter are sent before any new event.
+func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool, groupOnly bool, filter Filter) (*Listener, error) {
 	listener := &Listener{
 		group:        group,
+		groupOnly:    groupOnly,
 		connection:   connection,
 		messageTypes: messageTypes,
+		filter:       filter,
 		location:     location,
 		noForward:    noForward,
 		active:       make(chan bool, 1),
 		id:           uuid.NewRandom().String(),
 	}
 
+	// Hold new events back until the past ones have been sent.
+	listener.lock.Lock()
+	defer listener.lock.Unlock()
+
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
 	if s.listeners[listener.id] != nil {
+		s.lock.Unlock()
 		return nil, fmt.Errorf("A listener with id '%s' already exists", listener.id)
 	}
 
 	s.listeners[listener.id] = listener
 
+	history := []historyEntry{}
+	if !filter.Since.IsZero() {
+		for _, entry := range s.history {
+			if entry.event.Timestamp.After(filter.Since) && listener.match(entry.group, entry.event, entry.isForward) {
+				history = append(history, entry)
+			}
+		}
+	}
+
+	s.lock.Unlock()
+
+	for _, entry := range history {
+		if !s.send(listener, entry.group, entry.event) {
+			break
+		}
+	}
+
 	return listener, nil
 }
 
@@ -103,7 +191,8 @@ func (s *Server) Forward(id int64, event api.Event) {
 		}
 	}
 
-	err := s.broadcast("", event, true)
+	// Events from other nodes are only delivered to the listeners of their project.
+	err := s.broadcast(event.Project, event, true)
 	if err != nil {
 		logger.Warnf("Failed to forward event from node %d: %v", id, err)
 	}
@@ -111,17 +200,16 @@ func (s *Server) Forward(id int64, event api.Event) {
 
 func (s *Server) broadcast(group string, event api.Event, isForward bool) error {
 	s.lock.Lock()
-	listeners := s.listeners
-	for _, listener := range listeners {
-		if group != "" && listener.group != "*" && group != listener.group {
-			continue
-		}
 
-		if isForward && listener.noForward {
-			continue
-		}
+	// Keep the event around for listeners reconnecting later.
+	s.history = append(s.history, historyEntry{group: group, event: event, isForward: isForward})
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
 
-		if !shared.StringInSlice(event.Type, listener.messageTypes) {
+	listeners := s.listeners
+	for _, listener := range listeners {
+		if !listener.match(group, event, isForward) {
 			continue
 		}
 
@@ -135,36 +223,7 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 			listener.lock.Lock()
 			defer listener.lock.Unlock()
 
-			// Make sure we're not done already
-			if listener.done {
-				return
-			}
-
-			// Set the Location to the expected serverName
-			if event.Location == "" {
-				eventCopy := api.Event{}
-				err := shared.DeepCopy(&event, &eventCopy)
-				if err != nil {
-					return
-				}
-				eventCopy.Location = listener.location
-
-				event = eventCopy
-			}
-
-			err := listener.connection.WriteJSON(event)
-			if err != nil {
-				// Remove the listener from the list
-				s.lock.Lock()
-				delete(s.listeners, listener.id)
-				s.lock.Unlock()
-
-				// Disconnect the listener
-				listener.connection.Close()
-				listener.active <- false
-				listener.done = true
-				logger.Debugf("Disconnected event listener: %s", listener.id)
-			}
+			s.send(listener, group, event)
 		}(listener, event)
 	}
 	s.lock.Unlock()
@@ -172,11 +231,62 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 	return nil
 }
 
+// send sends an event to a listener, disconnecting it on failure. It returns false if the listener is done.
+// The caller must hold the listener lock.
+func (s *Server) send(listener *Listener, group string, event api.Event) bool {
+	// Make sure we're not done already
+	if listener.done {
+		return false
+	}
+
+	// Set the Location to the expected serverName
+	if event.Location == "" {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return true
+		}
+		eventCopy.Location = listener.location
+
+		event = eventCopy
+	}
+
+	// Let listeners of all projects know which project the event belongs to
+	if event.Project == "" && group != "" && listener.group == "*" {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return true
+		}
+		eventCopy.Project = group
+
+		event = eventCopy
+	}
+
+	err := listener.connection.WriteJSON(event)
+	if err != nil {
+		// Remove the listener from the list
+		s.lock.Lock()
+		delete(s.listeners, listener.id)
+		s.lock.Unlock()
+
+		// Disconnect the listener
+		listener.connection.Close()
+		listener.active <- false
+		listener.done = true
+		logger.Debugf("Disconnected event listener: %s", listener.id)
+		return false
+	}
+
+	return true
+}
+
 // Listener describes an event listener.
 type Listener struct {
 	group        string
 	connection   *websocket.Conn
 	messageTypes []string
+	filter       Filter
 	active       chan bool
 	id           string
 	lock         sync.Mutex
@@ -187,6 +297,30 @@ type Listener struct {
 	// nodes. It only used by listeners created internally by LXD nodes
 	// connecting to other LXD nodes to get their local events only.
 	noForward bool
+
+	// If true, this listener only gets the events of its group.
+	groupOnly bool
+}
+
+// match returns whether the listener should get an event broadcast to the given group.
+func (e *Listener) match(group string, event api.Event, isForward bool) bool {
+	if group != "" && e.group != "*" && group != e.group {
+		return false
+	}
+
+	if group == "" && e.groupOnly {
+		return false
+	}
+
+	if isForward && e.noForward {
+		return false
+	}
+
+	if !shared.StringInSlice(event.Type, e.messageTypes) {
+		return false
+	}
+
+	return e.filter.match(event)
 }
 
 // MessageTypes returns a list of message types the listener will be notified of.
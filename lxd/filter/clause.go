@@ -7,6 +7,9 @@ import (
 	"github.com/lxc/lxd/shared"
 )
 
+// operators lists the supported comparison operators.
+var operators = []string{"eq", "ne", "gt", "ge", "lt", "le"}
+
 // Clause is a single filter clause in a filter string.
 type Clause struct {
 	PrevLogical string
@@ -45,6 +48,9 @@ func Parse(s string) ([]Clause, error) {
 			return nil, fmt.Errorf("clause has no operator")
 		}
 		clause.Operator = parts[index]
+		if !shared.StringInSlice(clause.Operator, operators) {
+			return nil, fmt.Errorf("invalid operator %q", clause.Operator)
+		}
 
 		index++
 		if index == len(parts) {
@@ -14,6 +14,7 @@ func TestParse_Error(t *testing.T) {
 		"foo":                    "clause has no operator",
 		"not foo":                "clause has no operator",
 		"foo eq":                 "clause has no value",
+		"foo xx bar":             "invalid operator \"xx\"",
 		"foo eq \"bar":           "unterminated quote",
 		"foo eq bar and":         "unterminated compound clause",
 		"foo eq \"bar egg\" and": "unterminated compound clause",
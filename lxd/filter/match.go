@@ -1,16 +1,18 @@
 package filter
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
 // Match returns true if the given object matches the given filter.
 func Match(obj interface{}, clauses []Clause) bool {
 	match := true
 
 	for _, clause := range clauses {
 		value := ValueOf(obj, clause.Field)
-		clauseMatch := value == clause.Value
-
-		if clause.Operator == "ne" {
-			clauseMatch = !clauseMatch
-		}
+		clauseMatch := compare(value, clause.Value, clause.Operator)
 
 		// Finish out logic
 		if clause.Not {
@@ -29,3 +31,62 @@ func Match(obj interface{}, clauses []Clause) bool {
 
 	return match
 }
+
+// compare compares the value of a field with the value of a clause using the given operator. Values are compared
+// as numbers or timestamps when both sides can be parsed as such, and as strings otherwise.
+func compare(value interface{}, clauseValue string, operator string) bool {
+	if value == nil {
+		return operator == "ne"
+	}
+
+	var cmp int
+
+	t, isTime := value.(time.Time)
+	clauseTime, err := time.Parse(time.RFC3339, clauseValue)
+	if isTime && err == nil {
+		switch {
+		case t.Before(clauseTime):
+			cmp = -1
+		case t.After(clauseTime):
+			cmp = 1
+		}
+	} else {
+		str := fmt.Sprintf("%v", value)
+		if isTime {
+			str = t.Format(time.RFC3339)
+		}
+
+		number, err := strconv.ParseFloat(str, 64)
+		clauseNumber, clauseErr := strconv.ParseFloat(clauseValue, 64)
+		if err == nil && clauseErr == nil {
+			switch {
+			case number < clauseNumber:
+				cmp = -1
+			case number > clauseNumber:
+				cmp = 1
+			}
+		} else {
+			switch {
+			case str < clauseValue:
+				cmp = -1
+			case str > clauseValue:
+				cmp = 1
+			}
+		}
+	}
+
+	switch operator {
+	case "ne":
+		return cmp != 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+
+	return cmp == 0
+}
@@ -40,6 +40,13 @@ func TestMatch_Instance(t *testing.T) {
 		"config.image.os eq BusyBox and expanded_devices.root.path eq /": true,
 		"name eq c2 or status eq Running":                                true,
 		"name eq c2 or name eq c3":                                       false,
+		"stateful eq false":                                              true,
+		"status ne Stopped":                                              true,
+		"created_at gt 2020-01-01T00:00:00Z":                             true,
+		"created_at le 2020-01-01T00:00:00Z":                             false,
+		"name lt c2":                                                     true,
+		"config.missing eq foo":                                          false,
+		"config.missing ne foo":                                          true,
 	}
 	for s := range cases {
 		t.Run(s, func(t *testing.T) {
@@ -52,6 +59,33 @@ func TestMatch_Instance(t *testing.T) {
 
 }
 
+func TestMatch_StorageVolume(t *testing.T) {
+	volume := api.StorageVolume{
+		StorageVolumePut: api.StorageVolumePut{
+			Config: map[string]string{
+				"size": "10737418240",
+			},
+		},
+		Name:        "vol1",
+		Type:        "custom",
+		ContentType: "filesystem",
+	}
+	cases := map[string]interface{}{
+		"type eq custom and content_type eq filesystem": true,
+		"config.size gt 1073741824":                     true,
+		"config.size ge 10737418240":                    true,
+		"config.size lt 9":                              false,
+	}
+	for s := range cases {
+		t.Run(s, func(t *testing.T) {
+			f, err := filter.Parse(s)
+			require.NoError(t, err)
+			match := filter.Match(volume, f)
+			assert.Equal(t, cases[s], match)
+		})
+	}
+}
+
 func TestMatch_Image(t *testing.T) {
 	image := api.Image{
 		ImagePut: api.ImagePut{
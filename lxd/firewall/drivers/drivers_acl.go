@@ -0,0 +1,63 @@
+package drivers
+
+import (
+	"net"
+)
+
+// ACLRule represents a single network ACL rule to be applied by the firewall.
+// Rules are evaluated in order and the first matching rule decides the fate of the traffic, traffic not
+// matching any rule is rejected.
+type ACLRule struct {
+	Action          string       // One of "allow", "drop" or "reject".
+	Source          []*net.IPNet // Source subnets, any source if empty.
+	Destination     []*net.IPNet // Destination subnets, any destination if empty.
+	Protocol        string       // One of "tcp", "udp", "icmp4" or "icmp6", any protocol if empty.
+	SourcePort      []string     // Source ports or port ranges (start-end) for tcp and udp.
+	DestinationPort []string     // Destination ports or port ranges (start-end) for tcp and udp.
+	ICMPType        string       // ICMP type for icmp4 and icmp6.
+	ICMPCode        string       // ICMP code for icmp4 and icmp6.
+}
+
+// Families returns the IP families (4 or 6) the rule needs to be rendered for, or a single 0 entry if the rule
+// doesn't depend on the IP family. An empty list means the rule can never match.
+func (r *ACLRule) Families() []uint {
+	families := []uint{4, 6}
+
+	switch r.Protocol {
+	case "icmp4":
+		families = []uint{4}
+	case "icmp6":
+		families = []uint{6}
+	default:
+		if len(r.Source) < 1 && len(r.Destination) < 1 {
+			return []uint{0}
+		}
+	}
+
+	result := []uint{}
+	for _, family := range families {
+		if len(r.Source) > 0 && len(r.Subnets(r.Source, family)) < 1 {
+			continue
+		}
+
+		if len(r.Destination) > 0 && len(r.Subnets(r.Destination, family)) < 1 {
+			continue
+		}
+
+		result = append(result, family)
+	}
+
+	return result
+}
+
+// Subnets returns the subnets from the list that belong to the specified IP family.
+func (r *ACLRule) Subnets(subnets []*net.IPNet, family uint) []string {
+	result := []string{}
+	for _, subnet := range subnets {
+		if (subnet.IP.To4() != nil) == (family == 4) {
+			result = append(result, subnet.String())
+		}
+	}
+
+	return result
+}
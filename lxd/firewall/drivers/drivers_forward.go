@@ -0,0 +1,77 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+)
+
+// AddressForward represents a NAT address forward to be applied by the firewall.
+type AddressForward struct {
+	ListenAddress net.IP
+	Protocol      string   // One of "tcp" or "udp", all traffic is forwarded if empty.
+	ListenPorts   []uint64 // Ports to forward (required if Protocol is set).
+	TargetAddress net.IP
+	TargetPorts   []uint64 // Either empty (keep the listen port), a single port or one port per listen port.
+}
+
+// forwardPortMap maps a range of listen ports to a target port (0 to keep the listen port).
+type forwardPortMap struct {
+	listenStart uint64
+	listenEnd   uint64
+	target      uint64
+}
+
+// listenPorts returns the listen port range in the specified format (using the separator between start and end).
+func (m forwardPortMap) listenPorts(sep string) string {
+	if m.listenStart == m.listenEnd {
+		return fmt.Sprintf("%d", m.listenStart)
+	}
+
+	return fmt.Sprintf("%d%s%d", m.listenStart, sep, m.listenEnd)
+}
+
+// targetPorts returns the ports the traffic arrives on at the target in the specified format.
+func (m forwardPortMap) targetPorts(sep string) string {
+	if m.target > 0 {
+		return fmt.Sprintf("%d", m.target)
+	}
+
+	return m.listenPorts(sep)
+}
+
+// portMaps returns the listen port ranges of the forward along with the target port each of them maps to.
+// Consecutive listen ports which keep their port number are collapsed into a single range.
+func (f *AddressForward) portMaps() []forwardPortMap {
+	maps := []forwardPortMap{}
+	for i, port := range f.ListenPorts {
+		target := uint64(0)
+		if len(f.TargetPorts) == 1 {
+			target = f.TargetPorts[0]
+		} else if len(f.TargetPorts) > i && f.TargetPorts[i] != port {
+			target = f.TargetPorts[i]
+		}
+
+		last := len(maps) - 1
+		if last >= 0 && maps[last].target == target && maps[last].listenEnd+1 == port && (target == 0 || len(f.TargetPorts) == 1) {
+			maps[last].listenEnd = port
+			continue
+		}
+
+		maps = append(maps, forwardPortMap{listenStart: port, listenEnd: port, target: target})
+	}
+
+	return maps
+}
+
+// targetDest returns the DNAT destination for the target address and port (0 to keep the listen port).
+func (f *AddressForward) targetDest(port uint64) string {
+	if port == 0 {
+		return f.TargetAddress.String()
+	}
+
+	if f.TargetAddress.To4() == nil {
+		return fmt.Sprintf("[%s]:%d", f.TargetAddress.String(), port)
+	}
+
+	return fmt.Sprintf("%s:%d", f.TargetAddress.String(), port)
+}
@@ -0,0 +1,48 @@
+package drivers
+
+import (
+	"net"
+)
+
+// LoadBalancer represents a NAT load balancer to be applied by the firewall.
+// New connections to the listen ports are distributed evenly across the targets.
+type LoadBalancer struct {
+	ListenAddress net.IP
+	Protocol      string   // One of "tcp" or "udp".
+	ListenPorts   []uint64 // Ports to load balance.
+	Targets       []LoadBalancerTarget
+}
+
+// LoadBalancerTarget represents a single target of a load balancer.
+type LoadBalancerTarget struct {
+	Address net.IP
+	Port    uint64 // Target port, 0 to keep the listen port.
+}
+
+// targetForward returns an address forward equivalent to the load balancer for the target at the given index.
+// This allows reusing the port mapping logic of address forwards.
+func (lb *LoadBalancer) targetForward(i int) *AddressForward {
+	forward := &AddressForward{
+		ListenAddress: lb.ListenAddress,
+		Protocol:      lb.Protocol,
+		ListenPorts:   lb.ListenPorts,
+		TargetAddress: lb.Targets[i].Address,
+	}
+
+	if lb.Targets[i].Port > 0 {
+		forward.TargetPorts = []uint64{lb.Targets[i].Port}
+	}
+
+	return forward
+}
+
+// targetEvery returns how many of the connections still unmatched by the previous targets should go to the
+// target at the given index for the connections to be evenly distributed, or 0 for the last target which gets
+// all the remaining ones.
+func (lb *LoadBalancer) targetEvery(i int) int {
+	if i >= len(lb.Targets)-1 {
+		return 0
+	}
+
+	return len(lb.Targets) - i
+}
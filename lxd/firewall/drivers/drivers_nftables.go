@@ -47,15 +47,13 @@ func (d Nftables) Compat() (bool, error) {
 	}
 
 	// We require a 5.x kernel to avoid weird conflicts with xtables.
-	if len(uname.Release) > 1 {
-		verInt, err := strconv.Atoi(uname.Release[0:1])
-		if err != nil {
-			return false, errors.Wrapf(err, "Failed parsing kernel version")
-		}
+	kernelMajor, err := strconv.Atoi(strings.SplitN(uname.Release, ".", 2)[0])
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed parsing kernel version")
+	}
 
-		if verInt < 5 {
-			return false, fmt.Errorf("Kernel version does not meet minimum requirement of 5")
-		}
+	if kernelMajor < 5 {
+		return false, fmt.Errorf("Kernel version does not meet minimum requirement of 5")
 	}
 
 	// Check if nftables nft command exists, if not use xtables.
@@ -159,7 +157,7 @@ func (d Nftables) nftParseRuleset() ([]nftGenericItem, error) {
 	return items, nil
 }
 
-// GetVersion returns the version of dnsmasq.
+// hostVersion returns the version of nft.
 func (d Nftables) hostVersion() (*version.DottedVersion, error) {
 	output, err := shared.RunCommandCLocale("nft", "--version")
 	if err != nil {
@@ -342,6 +340,304 @@ func (d Nftables) InstanceClearBridgeFilter(projectName string, instanceName str
 	return nil
 }
 
+// NetworkApplyACLRules applies the ACL rules to the traffic of all instances connected to the network.
+func (d Nftables) NetworkApplyACLRules(networkName string, ingress []ACLRule, egress []ACLRule) error {
+	err := d.NetworkClearACLRules(networkName)
+	if err != nil {
+		return err
+	}
+
+	err = d.applyACLRules(networkName, fmt.Sprintf("ibrname %q", networkName), fmt.Sprintf("obrname %q", networkName), ingress, egress)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding ACL rules for network %q", networkName)
+	}
+
+	return nil
+}
+
+// NetworkClearACLRules removes the ACL rules applied to the network.
+func (d Nftables) NetworkClearACLRules(networkName string) error {
+	err := d.removeChains([]string{"bridge"}, networkName, "aclprert", "aclfwd", "aclout", "aclegress", "aclingress")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing ACL rules for network %q", networkName)
+	}
+
+	return nil
+}
+
+// InstanceSetupACLRules applies the ACL rules to the traffic of the instance device's host interface.
+func (d Nftables) InstanceSetupACLRules(projectName string, instanceName string, deviceName string, hostName string, ingress []ACLRule, egress []ACLRule) error {
+	err := d.InstanceClearACLRules(projectName, instanceName, deviceName)
+	if err != nil {
+		return err
+	}
+
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+	err = d.applyACLRules(deviceLabel, fmt.Sprintf("iifname %q", hostName), fmt.Sprintf("oifname %q", hostName), ingress, egress)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding ACL rules for instance device %q", deviceLabel)
+	}
+
+	return nil
+}
+
+// InstanceClearACLRules removes the ACL rules applied to the instance device.
+func (d Nftables) InstanceClearACLRules(projectName string, instanceName string, deviceName string) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+	err := d.removeChains([]string{"bridge"}, deviceLabel, "aclprert", "aclfwd", "aclout", "aclegress", "aclingress")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing ACL rules for instance device %q", deviceLabel)
+	}
+
+	return nil
+}
+
+// applyACLRules adds the ACL chains for the label. The fromMatch and toMatch expressions select the traffic
+// leaving and arriving at the instances the rules apply to respectively.
+func (d Nftables) applyACLRules(label string, fromMatch string, toMatch string, ingress []ACLRule, egress []ACLRule) error {
+	// Egress traffic is filtered as soon as it enters the bridge, where rejecting is possible. Ingress traffic
+	// can only be filtered in the forward and output hooks, where the bridge family can't reject, so ingress
+	// reject rules drop the traffic instead.
+	egressRules := []string{}
+	for _, rule := range egress {
+		egressRules = append(egressRules, d.aclRuleRender(&rule, rule.Action)...)
+	}
+
+	ingressRules := []string{}
+	for _, rule := range ingress {
+		action := rule.Action
+		if action == "reject" {
+			action = "drop"
+		}
+
+		ingressRules = append(ingressRules, d.aclRuleRender(&rule, action)...)
+	}
+
+	tplFields := map[string]interface{}{
+		"namespace":      nftablesNamespace,
+		"chainSeparator": nftablesChainSeparator,
+		"family":         "bridge",
+		"label":          label,
+		"from":           fromMatch,
+		"to":             toMatch,
+		"egressRules":    egressRules,
+		"ingressRules":   ingressRules,
+	}
+
+	return d.applyNftConfig(nftablesACL, tplFields)
+}
+
+// aclRuleRender returns the nftables rules for an ACL rule (one per IP family it applies to).
+func (d Nftables) aclRuleRender(rule *ACLRule, action string) []string {
+	if action == "allow" {
+		action = "accept"
+	}
+
+	rendered := []string{}
+	for _, family := range rule.Families() {
+		parts := []string{}
+
+		ipFamily := "ip"
+		if family == 6 {
+			ipFamily = "ip6"
+		}
+
+		if len(rule.Source) > 0 {
+			parts = append(parts, fmt.Sprintf("%s saddr {%s}", ipFamily, strings.Join(rule.Subnets(rule.Source, family), ", ")))
+		}
+
+		if len(rule.Destination) > 0 {
+			parts = append(parts, fmt.Sprintf("%s daddr {%s}", ipFamily, strings.Join(rule.Subnets(rule.Destination, family), ", ")))
+		}
+
+		switch rule.Protocol {
+		case "tcp", "udp":
+			if len(rule.SourcePort) > 0 {
+				parts = append(parts, fmt.Sprintf("%s sport {%s}", rule.Protocol, strings.Join(rule.SourcePort, ", ")))
+			}
+
+			if len(rule.DestinationPort) > 0 {
+				parts = append(parts, fmt.Sprintf("%s dport {%s}", rule.Protocol, strings.Join(rule.DestinationPort, ", ")))
+			}
+
+			if len(rule.SourcePort) < 1 && len(rule.DestinationPort) < 1 {
+				parts = append(parts, fmt.Sprintf("meta l4proto %s", rule.Protocol))
+			}
+		case "icmp4", "icmp6":
+			icmpProto := "icmp"
+			l4Proto := "icmp"
+			if rule.Protocol == "icmp6" {
+				icmpProto = "icmpv6"
+				l4Proto = "ipv6-icmp"
+			}
+
+			if rule.ICMPType != "" {
+				parts = append(parts, fmt.Sprintf("%s type %s", icmpProto, rule.ICMPType))
+			}
+
+			if rule.ICMPCode != "" {
+				parts = append(parts, fmt.Sprintf("%s code %s", icmpProto, rule.ICMPCode))
+			}
+
+			if rule.ICMPType == "" && rule.ICMPCode == "" {
+				parts = append(parts, fmt.Sprintf("meta l4proto %s", l4Proto))
+			}
+		}
+
+		parts = append(parts, action)
+		rendered = append(rendered, strings.Join(parts, " "))
+	}
+
+	return rendered
+}
+
+// NetworkApplyForwards creates the DNAT rules for the address forwards of the network, replacing any existing
+// ones. The forwards are applied in order, so port specific forwards must come first.
+func (d Nftables) NetworkApplyForwards(networkName string, forwards []AddressForward) error {
+	err := d.NetworkClearForwards(networkName)
+	if err != nil {
+		return err
+	}
+
+	rules := map[string][]map[string]interface{}{}
+	for _, forward := range forwards {
+		family := "ip"
+		if forward.ListenAddress.To4() == nil {
+			family = "ip6"
+		}
+
+		if forward.Protocol == "" {
+			rules[family] = append(rules[family], map[string]interface{}{
+				"family":     family,
+				"listenHost": forward.ListenAddress.String(),
+				"targetHost": forward.TargetAddress.String(),
+				"targetDest": forward.targetDest(0),
+			})
+
+			continue
+		}
+
+		for _, portMap := range forward.portMaps() {
+			rules[family] = append(rules[family], map[string]interface{}{
+				"family":      family,
+				"protocol":    forward.Protocol,
+				"listenHost":  forward.ListenAddress.String(),
+				"listenPorts": portMap.listenPorts("-"),
+				"targetHost":  forward.TargetAddress.String(),
+				"targetPorts": portMap.targetPorts("-"),
+				"targetDest":  forward.targetDest(portMap.target),
+			})
+		}
+	}
+
+	for _, family := range []string{"ip", "ip6"} {
+		if len(rules[family]) < 1 {
+			continue
+		}
+
+		tplFields := map[string]interface{}{
+			"namespace":      nftablesNamespace,
+			"chainSeparator": nftablesChainSeparator,
+			"family":         family,
+			"networkName":    networkName,
+			"rules":          rules[family],
+		}
+
+		err = d.applyNftConfig(nftablesNetForwards, tplFields)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding address forward rules for network %q (%s)", networkName, family)
+		}
+	}
+
+	return nil
+}
+
+// NetworkClearForwards removes the DNAT rules for the address forwards of the network.
+func (d Nftables) NetworkClearForwards(networkName string) error {
+	err := d.removeChains([]string{"ip", "ip6"}, networkName, "fwdprert", "fwdout", "fwdpstrt")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing address forward rules for network %q", networkName)
+	}
+
+	return nil
+}
+
+// NetworkApplyLoadBalancers creates the DNAT rules for the load balancers of the network, replacing any existing
+// ones.
+func (d Nftables) NetworkApplyLoadBalancers(networkName string, loadBalancers []LoadBalancer) error {
+	err := d.NetworkClearLoadBalancers(networkName)
+	if err != nil {
+		return err
+	}
+
+	rules := map[string][]map[string]interface{}{}
+	for _, lb := range loadBalancers {
+		if len(lb.Targets) < 1 {
+			continue
+		}
+
+		family := "ip"
+		if lb.ListenAddress.To4() == nil {
+			family = "ip6"
+		}
+
+		// All targets share the same listen ports, so the port maps of each target line up.
+		targetPortMaps := make([][]forwardPortMap, 0, len(lb.Targets))
+		for i := range lb.Targets {
+			targetPortMaps = append(targetPortMaps, lb.targetForward(i).portMaps())
+		}
+
+		for j := range targetPortMaps[0] {
+			for i := range lb.Targets {
+				forward := lb.targetForward(i)
+				portMap := targetPortMaps[i][j]
+
+				rules[family] = append(rules[family], map[string]interface{}{
+					"family":      family,
+					"protocol":    lb.Protocol,
+					"listenHost":  lb.ListenAddress.String(),
+					"listenPorts": portMap.listenPorts("-"),
+					"every":       lb.targetEvery(i),
+					"targetHost":  forward.TargetAddress.String(),
+					"targetPorts": portMap.targetPorts("-"),
+					"targetDest":  forward.targetDest(portMap.target),
+				})
+			}
+		}
+	}
+
+	for _, family := range []string{"ip", "ip6"} {
+		if len(rules[family]) < 1 {
+			continue
+		}
+
+		tplFields := map[string]interface{}{
+			"namespace":      nftablesNamespace,
+			"chainSeparator": nftablesChainSeparator,
+			"family":         family,
+			"networkName":    networkName,
+			"rules":          rules[family],
+		}
+
+		err = d.applyNftConfig(nftablesNetLoadBalancers, tplFields)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding load balancer rules for network %q (%s)", networkName, family)
+		}
+	}
+
+	return nil
+}
+
+// NetworkClearLoadBalancers removes the DNAT rules for the load balancers of the network.
+func (d Nftables) NetworkClearLoadBalancers(networkName string) error {
+	err := d.removeChains([]string{"ip", "ip6"}, networkName, "lbprert", "lbout", "lbpstrt")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing load balancer rules for network %q", networkName)
+	}
+
+	return nil
+}
+
 // InstanceSetupProxyNAT creates DNAT rules for proxy devices.
 func (d Nftables) InstanceSetupProxyNAT(projectName string, instanceName string, deviceName string, listen, connect *deviceConfig.ProxyAddress) error {
 	connectAddrCount := len(connect.Addr)
@@ -476,12 +772,24 @@ func (d Nftables) removeChains(families []string, chainSuffix string, chains ...
 	}
 
 	for _, family := range families {
+		// Flush all the chains before deleting any of them, as a chain cannot be deleted whilst another
+		// chain still has a rule jumping to it.
+		items := []nftGenericItem{}
 		for _, item := range ruleset {
 			if item.Type == "chain" && item.Family == family && item.Table == nftablesNamespace && shared.StringInSlice(item.Name, fullChains) {
-				_, err = shared.RunCommand("nft", "flush", "chain", family, nftablesNamespace, item.Name, ";", "delete", "chain", family, nftablesNamespace, item.Name)
+				_, err = shared.RunCommand("nft", "flush", "chain", family, nftablesNamespace, item.Name)
 				if err != nil {
-					return errors.Wrapf(err, "Failed deleting nftables chain %q (%s)", item.Name, family)
+					return errors.Wrapf(err, "Failed flushing nftables chain %q (%s)", item.Name, family)
 				}
+
+				items = append(items, item)
+			}
+		}
+
+		for _, item := range items {
+			_, err = shared.RunCommand("nft", "delete", "chain", family, nftablesNamespace, item.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed deleting nftables chain %q (%s)", item.Name, family)
 			}
 		}
 	}
@@ -516,6 +824,66 @@ func (d Nftables) InstanceSetupRPFilter(projectName string, instanceName string,
 	return nil
 }
 
+// InstanceSetupNICFilter applies MAC and source address filtering to the traffic of an instance device arriving
+// on the host interface. An empty hwAddr disables MAC filtering. A nil list of subnets disables filtering for the
+// IP version, an empty one drops all of its traffic.
+func (d Nftables) InstanceSetupNICFilter(projectName string, instanceName string, deviceName string, hostName string, hwAddr string, IPv4Nets []*net.IPNet, IPv6Nets []*net.IPNet) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+
+	for _, family := range []string{"ip", "ip6"} {
+		tplFields := map[string]interface{}{
+			"namespace":      nftablesNamespace,
+			"chainSeparator": nftablesChainSeparator,
+			"family":         family,
+			"deviceLabel":    deviceLabel,
+			"hostName":       hostName,
+			"hwAddr":         hwAddr,
+		}
+
+		nets := IPv4Nets
+		if family == "ip6" {
+			nets = IPv6Nets
+		}
+
+		if nets != nil {
+			subnets := []string{}
+			for _, subnet := range nets {
+				subnets = append(subnets, subnet.String())
+			}
+
+			if family == "ip6" {
+				// Link-local addresses are needed for neighbour discovery.
+				subnets = append(subnets, "fe80::/10")
+				tplFields["dropRA"] = true
+			}
+
+			if len(subnets) > 0 {
+				tplFields["nets"] = strings.Join(subnets, ", ")
+			} else {
+				tplFields["filterAll"] = true
+			}
+		}
+
+		err := d.applyNftConfig(nftablesInstanceNICFilter, tplFields)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding NIC filter rules for instance device %q (%s)", deviceLabel, family)
+		}
+	}
+
+	return nil
+}
+
+// InstanceClearNICFilter removes the MAC and source address filtering of an instance device.
+func (d Nftables) InstanceClearNICFilter(projectName string, instanceName string, deviceName string) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+	err := d.removeChains([]string{"ip", "ip6"}, deviceLabel, "nicfilter")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing NIC filter rules for instance device %q", deviceLabel)
+	}
+
+	return nil
+}
+
 // InstanceClearRPFilter removes reverse path filtering for the specified instance device on the host interface.
 func (d Nftables) InstanceClearRPFilter(projectName string, instanceName string, deviceName string) error {
 	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
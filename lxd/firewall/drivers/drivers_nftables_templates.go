@@ -76,6 +76,52 @@ chain pstrt{{.chainSeparator}}{{.deviceLabel}} {
 }
 `))
 
+var nftablesNetForwards = template.Must(template.New("nftablesNetForwards").Parse(`
+chain fwdprert{{.chainSeparator}}{{.networkName}} {
+	type nat hook prerouting priority -100; policy accept;
+	{{- range .rules}}
+	{{.family}} daddr {{.listenHost}}{{if .protocol}} {{.protocol}} dport {{.listenPorts}}{{end}} dnat to {{.targetDest}}
+	{{- end}}
+}
+
+chain fwdout{{.chainSeparator}}{{.networkName}} {
+	type nat hook output priority -100; policy accept;
+	{{- range .rules}}
+	{{.family}} daddr {{.listenHost}}{{if .protocol}} {{.protocol}} dport {{.listenPorts}}{{end}} dnat to {{.targetDest}}
+	{{- end}}
+}
+
+chain fwdpstrt{{.chainSeparator}}{{.networkName}} {
+	type nat hook postrouting priority 100; policy accept;
+	{{- range .rules}}
+	{{.family}} saddr {{.targetHost}} {{.family}} daddr {{.targetHost}}{{if .protocol}} {{.protocol}} dport {{.targetPorts}}{{end}} masquerade
+	{{- end}}
+}
+`))
+
+var nftablesNetLoadBalancers = template.Must(template.New("nftablesNetLoadBalancers").Parse(`
+chain lbprert{{.chainSeparator}}{{.networkName}} {
+	type nat hook prerouting priority -100; policy accept;
+	{{- range .rules}}
+	{{.family}} daddr {{.listenHost}} {{.protocol}} dport {{.listenPorts}}{{if .every}} numgen inc mod {{.every}} == 0{{end}} dnat to {{.targetDest}}
+	{{- end}}
+}
+
+chain lbout{{.chainSeparator}}{{.networkName}} {
+	type nat hook output priority -100; policy accept;
+	{{- range .rules}}
+	{{.family}} daddr {{.listenHost}} {{.protocol}} dport {{.listenPorts}}{{if .every}} numgen inc mod {{.every}} == 0{{end}} dnat to {{.targetDest}}
+	{{- end}}
+}
+
+chain lbpstrt{{.chainSeparator}}{{.networkName}} {
+	type nat hook postrouting priority 100; policy accept;
+	{{- range .rules}}
+	{{.family}} saddr {{.targetHost}} {{.family}} daddr {{.targetHost}} {{.protocol}} dport {{.targetPorts}} masquerade
+	{{- end}}
+}
+`))
+
 // nftablesInstanceBridgeFilter defines the rules needed for MAC, IPv4 and IPv6 bridge security filtering.
 // To prevent instances from using IPs that are different from their assigned IPs we use ARP and NDP filtering
 // to prevent neighbour advertisements that are not allowed. However in order for DHCPv4 & DHCPv6 to work back to
@@ -148,3 +194,70 @@ chain prert{{.chainSeparator}}{{.deviceLabel}} {
 	iif "{{.hostName}}" fib saddr . iif oif missing drop
 }
 `))
+
+// nftablesInstanceNICFilter defines the rules for MAC and IP source filtering on a routed host interface, such
+// as the host side of a p2p NIC. Traffic that doesn't come from the instance's MAC address or from one of the
+// allowed source subnets is dropped, as are IPv6 router advertisements when filtering IPv6.
+var nftablesInstanceNICFilter = template.Must(template.New("nftablesInstanceNICFilter").Parse(`
+chain nicfilter{{.chainSeparator}}{{.deviceLabel}} {
+	type filter hook prerouting priority -300; policy accept;
+	{{if .hwAddr -}}
+	iifname "{{.hostName}}" ether saddr != {{.hwAddr}} drop
+	{{- end}}
+	{{if .filterAll -}}
+	iifname "{{.hostName}}" drop
+	{{- end}}
+	{{if .nets -}}
+	iifname "{{.hostName}}" {{.family}} saddr != { {{.nets}} } drop
+	{{- end}}
+	{{if .dropRA -}}
+	iifname "{{.hostName}}" icmpv6 type 134 drop
+	{{- end}}
+}
+`))
+
+// nftablesACL defines the chains needed to apply network ACL rules in the bridge family. Established traffic,
+// ARP, neighbour discovery, DHCP and DNS are always allowed, any other traffic not matching a rule is rejected.
+// The aclegress and aclingress chains are defined first as the hook chains jump to them.
+var nftablesACL = template.Must(template.New("nftablesACL").Parse(`
+chain aclegress{{.chainSeparator}}{{.label}} {
+	{{- range .egressRules}}
+	{{.}}
+	{{- end}}
+	reject
+}
+
+chain aclingress{{.chainSeparator}}{{.label}} {
+	{{- range .ingressRules}}
+	{{.}}
+	{{- end}}
+	drop
+}
+
+chain aclprert{{.chainSeparator}}{{.label}} {
+	type filter hook prerouting priority 0; policy accept;
+	{{.from}} ct state established,related accept
+	{{.from}} ether type arp accept
+	{{.from}} icmpv6 type {nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert} accept
+	{{.from}} udp dport {53, 67, 547} accept
+	{{.from}} tcp dport 53 accept
+	{{.from}} jump aclegress{{.chainSeparator}}{{.label}}
+}
+
+chain aclfwd{{.chainSeparator}}{{.label}} {
+	type filter hook forward priority 0; policy accept;
+	{{.to}} ct state established,related accept
+	{{.to}} ether type arp accept
+	{{.to}} icmpv6 type {nd-neighbor-solicit, nd-neighbor-advert} accept
+	{{.to}} jump aclingress{{.chainSeparator}}{{.label}}
+}
+
+chain aclout{{.chainSeparator}}{{.label}} {
+	type filter hook output priority 0; policy accept;
+	{{.to}} ct state established,related accept
+	{{.to}} ether type arp accept
+	{{.to}} icmpv6 type {nd-router-advert, nd-neighbor-solicit, nd-neighbor-advert} accept
+	{{.to}} udp sport {67, 547} accept
+	{{.to}} jump aclingress{{.chainSeparator}}{{.label}}
+}
+`))
@@ -348,6 +348,166 @@ func (d Xtables) InstanceClearBridgeFilter(projectName string, instanceName stri
 	return nil
 }
 
+// NetworkApplyForwards creates the DNAT rules for the address forwards of the network, replacing any existing
+// ones. The forwards are applied in order, so port specific forwards must come first.
+func (d Xtables) NetworkApplyForwards(networkName string, forwards []AddressForward) error {
+	err := d.NetworkClearForwards(networkName)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+	revert.Add(func() { d.NetworkClearForwards(networkName) })
+
+	comment := fmt.Sprintf("%s forward", d.networkIPTablesComment(networkName))
+
+	// Rules are prepended to get ahead of any existing rules, so add them in reverse order.
+	for i := len(forwards) - 1; i >= 0; i-- {
+		forward := forwards[i]
+
+		ipVersion := uint(4)
+		if forward.ListenAddress.To4() == nil {
+			ipVersion = 6
+		}
+
+		listenHost := forward.ListenAddress.String()
+		targetHost := forward.TargetAddress.String()
+
+		if forward.Protocol == "" {
+			for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+				err = d.iptablesPrepend(ipVersion, comment, "nat", chain, "--destination", listenHost, "-j", "DNAT", "--to-destination", forward.targetDest(0))
+				if err != nil {
+					return err
+				}
+			}
+
+			// Hairpin NAT for the target reaching itself through the listen address.
+			err = d.iptablesPrepend(ipVersion, comment, "nat", "POSTROUTING", "--source", targetHost, "--destination", targetHost, "-j", "MASQUERADE")
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		portMaps := forward.portMaps()
+		for j := len(portMaps) - 1; j >= 0; j-- {
+			portMap := portMaps[j]
+
+			for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+				err = d.iptablesPrepend(ipVersion, comment, "nat", chain, "-p", forward.Protocol, "--destination", listenHost, "--dport", portMap.listenPorts(":"), "-j", "DNAT", "--to-destination", forward.targetDest(portMap.target))
+				if err != nil {
+					return err
+				}
+			}
+
+			// Hairpin NAT for the target reaching itself through the listen address.
+			err = d.iptablesPrepend(ipVersion, comment, "nat", "POSTROUTING", "-p", forward.Protocol, "--source", targetHost, "--destination", targetHost, "--dport", portMap.targetPorts(":"), "-j", "MASQUERADE")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// NetworkClearForwards removes the DNAT rules for the address forwards of the network.
+func (d Xtables) NetworkClearForwards(networkName string) error {
+	comment := fmt.Sprintf("%s forward", d.networkIPTablesComment(networkName))
+	errs := []error{}
+	for _, ipVersion := range []uint{4, 6} {
+		err := d.iptablesClear(ipVersion, comment, "nat")
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to remove address forward rules for network %q: %v", networkName, errs)
+	}
+
+	return nil
+}
+
+// NetworkApplyLoadBalancers creates the DNAT rules for the load balancers of the network, replacing any existing
+// ones.
+func (d Xtables) NetworkApplyLoadBalancers(networkName string, loadBalancers []LoadBalancer) error {
+	err := d.NetworkClearLoadBalancers(networkName)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+	revert.Add(func() { d.NetworkClearLoadBalancers(networkName) })
+
+	comment := fmt.Sprintf("%s load-balancer", d.networkIPTablesComment(networkName))
+
+	// Rules are prepended to get ahead of any existing rules, so add them in reverse order.
+	for _, lb := range loadBalancers {
+		ipVersion := uint(4)
+		if lb.ListenAddress.To4() == nil {
+			ipVersion = 6
+		}
+
+		listenHost := lb.ListenAddress.String()
+
+		for i := len(lb.Targets) - 1; i >= 0; i-- {
+			forward := lb.targetForward(i)
+			targetHost := forward.TargetAddress.String()
+
+			args := []string{}
+			every := lb.targetEvery(i)
+			if every > 0 {
+				args = append(args, "-m", "statistic", "--mode", "nth", "--every", fmt.Sprintf("%d", every), "--packet", "0")
+			}
+
+			for _, portMap := range forward.portMaps() {
+				for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+					ruleArgs := []string{"-p", lb.Protocol, "--destination", listenHost, "--dport", portMap.listenPorts(":")}
+					ruleArgs = append(ruleArgs, args...)
+					ruleArgs = append(ruleArgs, "-j", "DNAT", "--to-destination", forward.targetDest(portMap.target))
+
+					err = d.iptablesPrepend(ipVersion, comment, "nat", chain, ruleArgs...)
+					if err != nil {
+						return err
+					}
+				}
+
+				// Hairpin NAT for the target reaching itself through the listen address.
+				err = d.iptablesPrepend(ipVersion, comment, "nat", "POSTROUTING", "-p", lb.Protocol, "--source", targetHost, "--destination", targetHost, "--dport", portMap.targetPorts(":"), "-j", "MASQUERADE")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// NetworkClearLoadBalancers removes the DNAT rules for the load balancers of the network.
+func (d Xtables) NetworkClearLoadBalancers(networkName string) error {
+	comment := fmt.Sprintf("%s load-balancer", d.networkIPTablesComment(networkName))
+	errs := []error{}
+	for _, ipVersion := range []uint{4, 6} {
+		err := d.iptablesClear(ipVersion, comment, "nat")
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to remove load balancer rules for network %q: %v", networkName, errs)
+	}
+
+	return nil
+}
+
 // InstanceSetupProxyNAT creates DNAT rules for proxy devices.
 func (d Xtables) InstanceSetupProxyNAT(projectName string, instanceName string, deviceName string, listen *deviceConfig.ProxyAddress, connect *deviceConfig.ProxyAddress) error {
 	connectAddrCount := len(connect.Addr)
@@ -740,3 +900,97 @@ func (d Xtables) InstanceClearRPFilter(projectName string, instanceName string,
 
 	return nil
 }
+
+// InstanceSetupNICFilter applies MAC and source address filtering to the traffic of an instance device arriving
+// on the host interface. An empty hwAddr disables MAC filtering. A nil list of subnets disables filtering for the
+// IP version, an empty one drops all of its traffic.
+func (d Xtables) InstanceSetupNICFilter(projectName string, instanceName string, deviceName string, hostName string, hwAddr string, IPv4Nets []*net.IPNet, IPv6Nets []*net.IPNet) error {
+	comment := fmt.Sprintf("%s nicfilter", d.instanceDeviceIPTablesComment(projectName, instanceName, deviceName))
+
+	for _, ipVersion := range []uint{4, 6} {
+		nets := IPv4Nets
+		if ipVersion == 6 {
+			nets = IPv6Nets
+		}
+
+		// Rules are prepended, so they are added in reverse order of evaluation.
+		if nets != nil {
+			err := d.iptablesPrepend(ipVersion, comment, "raw", "PREROUTING", "-i", hostName, "-j", "DROP")
+			if err != nil {
+				return err
+			}
+
+			if ipVersion == 6 {
+				// Link-local addresses are needed for neighbour discovery.
+				err = d.iptablesPrepend(ipVersion, comment, "raw", "PREROUTING", "-i", hostName, "-s", "fe80::/10", "-j", "ACCEPT")
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, subnet := range nets {
+				err = d.iptablesPrepend(ipVersion, comment, "raw", "PREROUTING", "-i", hostName, "-s", subnet.String(), "-j", "ACCEPT")
+				if err != nil {
+					return err
+				}
+			}
+
+			if ipVersion == 6 {
+				err = d.iptablesPrepend(ipVersion, comment, "raw", "PREROUTING", "-i", hostName, "-p", "icmpv6", "--icmpv6-type", "router-advertisement", "-j", "DROP")
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if hwAddr != "" {
+			err := d.iptablesPrepend(ipVersion, comment, "raw", "PREROUTING", "-i", hostName, "-m", "mac", "!", "--mac-source", hwAddr, "-j", "DROP")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// InstanceClearNICFilter removes the MAC and source address filtering of an instance device.
+func (d Xtables) InstanceClearNICFilter(projectName string, instanceName string, deviceName string) error {
+	comment := fmt.Sprintf("%s nicfilter", d.instanceDeviceIPTablesComment(projectName, instanceName, deviceName))
+	errs := []error{}
+	err := d.iptablesClear(4, comment, "raw")
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = d.iptablesClear(6, comment, "raw")
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to remove NIC filter rules for %q: %v", deviceName, errs)
+	}
+
+	return nil
+}
+
+// NetworkApplyACLRules is not supported by the xtables driver.
+func (d Xtables) NetworkApplyACLRules(networkName string, ingress []ACLRule, egress []ACLRule) error {
+	return fmt.Errorf("Network ACLs are not supported by the xtables firewall driver")
+}
+
+// NetworkClearACLRules is a no-op as ACL rules cannot be applied by the xtables driver.
+func (d Xtables) NetworkClearACLRules(networkName string) error {
+	return nil
+}
+
+// InstanceSetupACLRules is not supported by the xtables driver.
+func (d Xtables) InstanceSetupACLRules(projectName string, instanceName string, deviceName string, hostName string, ingress []ACLRule, egress []ACLRule) error {
+	return fmt.Errorf("Network ACLs are not supported by the xtables firewall driver")
+}
+
+// InstanceClearACLRules is a no-op as ACL rules cannot be applied by the xtables driver.
+func (d Xtables) InstanceClearACLRules(projectName string, instanceName string, deviceName string) error {
+	return nil
+}
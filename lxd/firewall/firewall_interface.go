@@ -4,6 +4,7 @@ import (
 	"net"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/firewall/drivers"
 )
 
 // Firewall represents an LXD firewall.
@@ -16,6 +17,12 @@ type Firewall interface {
 	NetworkSetupDHCPDNSAccess(networkName string, ipVersion uint) error
 	NetworkSetupDHCPv4Checksum(networkName string) error
 	NetworkClear(networkName string, ipVersion uint) error
+	NetworkApplyACLRules(networkName string, ingress []drivers.ACLRule, egress []drivers.ACLRule) error
+	NetworkClearACLRules(networkName string) error
+	NetworkApplyForwards(networkName string, forwards []drivers.AddressForward) error
+	NetworkClearForwards(networkName string) error
+	NetworkApplyLoadBalancers(networkName string, loadBalancers []drivers.LoadBalancer) error
+	NetworkClearLoadBalancers(networkName string) error
 
 	InstanceSetupBridgeFilter(projectName string, instanceName string, deviceName string, parentName string, hostName string, hwAddr string, IPv4 net.IP, IPv6 net.IP) error
 	InstanceClearBridgeFilter(projectName string, instanceName string, deviceName string, parentName string, hostName string, hwAddr string, IPv4 net.IP, IPv6 net.IP) error
@@ -25,4 +32,10 @@ type Firewall interface {
 
 	InstanceSetupRPFilter(projectName string, instanceName string, deviceName string, hostName string) error
 	InstanceClearRPFilter(projectName string, instanceName string, deviceName string) error
+
+	InstanceSetupNICFilter(projectName string, instanceName string, deviceName string, hostName string, hwAddr string, IPv4Nets []*net.IPNet, IPv6Nets []*net.IPNet) error
+	InstanceClearNICFilter(projectName string, instanceName string, deviceName string) error
+
+	InstanceSetupACLRules(projectName string, instanceName string, deviceName string, hostName string, ingress []drivers.ACLRule, egress []drivers.ACLRule) error
+	InstanceClearACLRules(projectName string, instanceName string, deviceName string) error
 }
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,9 +17,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -32,7 +35,6 @@ import (
 	"github.com/lxc/lxd/lxd/filter"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
-	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
@@ -46,6 +48,7 @@ import (
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/logging"
 	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/version"
 )
 
@@ -212,6 +215,15 @@ func imgPostInstanceInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *ope
 
 	info.Type = c.Type().String()
 
+	// Layered images only contain the changes from the image the instance was created from.
+	var base *api.Image
+	if req.Diff {
+		base, err = imagePublishDiffBase(d, project, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Build the actual image file
 	imageFile, err := ioutil.TempFile(builddir, "lxd_build_image_")
 	if err != nil {
@@ -236,24 +248,19 @@ func imgPostInstanceInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *ope
 
 	// Track progress creating image.
 	metadata := make(map[string]interface{})
-	imageProgressWriter := &ioprogress.ProgressWriter{
-		Tracker: &ioprogress.ProgressTracker{
-			Handler: func(value, speed int64) {
-				percent := int64(0)
-				processed := int64(0)
-
-				if totalSize > 0 {
-					percent = value
-					processed = totalSize * (percent / 100.0)
-				} else {
-					processed = value
-				}
+	imageProgressTracker := &ioprogress.ProgressTracker{Length: totalSize}
+	imageProgressTracker.Handler = func(value, speed int64) {
+		percent := int64(0)
+		if totalSize > 0 {
+			percent = value
+		}
 
-				shared.SetProgressMetadata(metadata, "create_image_from_container_pack", "Image pack", percent, processed, speed)
-				op.UpdateMetadata(metadata)
-			},
-			Length: totalSize,
-		},
+		shared.SetProgressMetadata(metadata, "create_image_from_container_pack", "Image pack", percent, imageProgressTracker.Transferred(), totalSize, speed)
+		op.UpdateMetadata(metadata)
+	}
+
+	imageProgressWriter := &ioprogress.ProgressWriter{
+		Tracker: imageProgressTracker,
 	}
 
 	sha256 := sha256.New()
@@ -294,7 +301,11 @@ func imgPostInstanceInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *ope
 
 	// Export instance to writer.
 	var meta api.ImageMetadata
-	meta, err = c.Export(writer, req.Properties)
+	if base != nil {
+		meta, err = imageExportDiff(d, c, base, builddir, writer, req.Properties)
+	} else {
+		meta, err = c.Export(writer, req.Properties)
+	}
 
 	// Clean up file handles.
 	// When compression is used, Close on imageProgressWriter/tarWriter is required for compressFile/gzip to
@@ -346,6 +357,20 @@ func imgPostInstanceInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *ope
 		return nil, err
 	}
 
+	if base != nil {
+		id, _, err := d.cluster.GetImage(c.Project(), info.Fingerprint, false)
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.cluster.UpdateImageBaseImage(id, base.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		info.BaseImage = base.Fingerprint
+	}
+
 	return &info, nil
 }
 
@@ -474,6 +499,16 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 	sha256 := sha256.New()
 	var size int64
 
+	// Virtual-machine images may get converted on import, except when synchronizing them between cluster members
+	// which requires the fingerprints to match.
+	var convertFormat, convertCompress string
+	if !isClusterNotification(r) {
+		convertFormat, convertCompress, err = imageImportConversion(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if ctype == "multipart/form-data" {
 		// Create a temporary file for the image tarball
 		imageTarf, err := ioutil.TempFile(builddir, "lxd_tar_")
@@ -552,6 +587,14 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			return nil, err
 		}
 
+		if info.Type == instancetype.VM.String() && (convertFormat != "" || convertCompress != "") {
+			info.Fingerprint, info.Size, err = imageConvert(d, imageTarf.Name(), rootfsTarf.Name(), convertFormat, convertCompress, builddir)
+			if err != nil {
+				logger.Error("Failed to convert the image", log.Ctx{"err": err})
+				return nil, err
+			}
+		}
+
 		imgfname := shared.VarPath("images", info.Fingerprint)
 		err = shared.FileMove(imageTarf.Name(), imgfname)
 		if err != nil {
@@ -600,6 +643,14 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		}
 		info.Type = imageType
 
+		if info.Type == instancetype.VM.String() && (convertFormat != "" || convertCompress != "") {
+			info.Fingerprint, info.Size, err = imageConvert(d, post.Name(), "", convertFormat, convertCompress, builddir)
+			if err != nil {
+				logger.Error("Failed to convert the image", log.Ctx{"err": err})
+				return nil, err
+			}
+		}
+
 		imgfname := shared.VarPath("images", info.Fingerprint)
 		err = shared.FileMove(post.Name(), imgfname)
 		if err != nil {
@@ -637,6 +688,20 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		}
 	}
 
+	// Layered images can only be imported alongside their base image.
+	if imageMeta.BaseImage != "" {
+		_, base, err := d.cluster.GetImage(project, imageMeta.BaseImage, false)
+		if err != nil && err != db.ErrNoSuchObject {
+			return nil, err
+		}
+
+		if err == db.ErrNoSuchObject || base.Fingerprint != imageMeta.BaseImage {
+			return nil, fmt.Errorf("Base image %q of layered image isn't available", imageMeta.BaseImage)
+		}
+
+		info.BaseImage = imageMeta.BaseImage
+	}
+
 	// Check if the image already exists
 	exists, err := d.cluster.ImageExists(project, info.Fingerprint)
 	if err != nil {
@@ -665,6 +730,18 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		if err != nil {
 			return nil, err
 		}
+
+		if info.BaseImage != "" {
+			id, _, err := d.cluster.GetImage(project, info.Fingerprint, false)
+			if err != nil {
+				return nil, err
+			}
+
+			err = d.cluster.UpdateImageBaseImage(id, info.BaseImage)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &info, nil
@@ -777,7 +854,7 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 		return createTokenResponse(d, project, req.Source.Fingerprint, metadata)
 	}
 
-	if !imageUpload && !shared.StringInSlice(req.Source.Type, []string{"container", "instance", "virtual-machine", "snapshot", "image", "url"}) {
+	if !imageUpload && !shared.StringInSlice(req.Source.Type, []string{"container", "instance", "virtual-machine", "snapshot", "image", "url", "oci"}) {
 		cleanup(builddir, post)
 		return response.InternalError(fmt.Errorf("Invalid images JSON"))
 	}
@@ -819,6 +896,9 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			} else if req.Source.Type == "url" {
 				/* Processing image copy from URL */
 				info, err = imgPostURLInfo(d, req, op, project)
+			} else if req.Source.Type == "oci" {
+				/* Processing image import from an OCI registry */
+				info, err = imgPostOCIInfo(d, req, op, builddir, project)
 			} else {
 				/* Processing image creation from container */
 				imagePublishLock.Lock()
@@ -1005,12 +1085,15 @@ func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
 	return &result, imageType, nil
 }
 
-func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses []filter.Clause) (interface{}, error) {
+func doImagesGet(d *Daemon, r *http.Request, recursion bool, project string, public bool, clauses []filter.Clause) (interface{}, error) {
 	results, err := d.cluster.GetImagesFingerprints(project, public)
 	if err != nil {
 		return []string{}, err
 	}
 
+	// Sort the fingerprints so that pages are stable.
+	sort.Strings(results)
+
 	resultString := []string{}
 	resultMap := []*api.Image{}
 
@@ -1039,10 +1122,21 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 				resultString = append(resultString, url)
 			}
 		}
-		return resultString, nil
+
+		start, end, err := util.Paginate(r, len(resultString))
+		if err != nil {
+			return nil, err
+		}
+
+		return resultString[start:end], nil
+	}
+
+	start, end, err := util.Paginate(r, len(resultMap))
+	if err != nil {
+		return nil, err
 	}
 
-	return resultMap, nil
+	return resultMap[start:end], nil
 }
 
 func imagesGet(d *Daemon, r *http.Request) response.Response {
@@ -1059,7 +1153,12 @@ func imagesGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	result, err := doImagesGet(d, util.IsRecursionRequest(r), project, public, clauses)
+	_, _, err := util.Paginate(r, 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	result, err := doImagesGet(d, r, util.IsRecursionRequest(r), project, public, clauses)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1145,7 +1244,25 @@ func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string) e
 			continue
 		}
 
-		if !info.AutoUpdate {
+		if !info.AutoUpdate || info.Pinned {
+			continue
+		}
+
+		// Only one of the members holding the image updates it, the others get the new image through
+		// the image replication.
+		holders, err := d.cluster.GetNodesWithImage(fingerprint)
+		if err != nil {
+			logger.Error("Error getting members with image", log.Ctx{"err": err, "fp": fingerprint, "project": project})
+			continue
+		}
+
+		local, err := clusterTaskIsLocal(d, "images.auto_update:"+fingerprint, holders)
+		if err != nil {
+			logger.Error("Error checking image update assignment", log.Ctx{"err": err, "fp": fingerprint, "project": project})
+			continue
+		}
+
+		if !local {
 			continue
 		}
 
@@ -1164,9 +1281,88 @@ func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string) e
 		}
 	}
 
+	aliases, err := d.cluster.GetImageAliasesWithAutoUpdate(project)
+	if err != nil {
+		return errors.Wrap(err, "Unable to retrieve the list of image aliases")
+	}
+
+	for _, name := range aliases {
+		local, err := clusterTaskIsLocal(d, fmt.Sprintf("images.auto_update:%s/%s", project, name), nil)
+		if err != nil {
+			logger.Error("Error checking image alias update assignment", log.Ctx{"err": err, "alias": name, "project": project})
+			continue
+		}
+
+		if !local {
+			continue
+		}
+
+		ch := make(chan struct{})
+		go func(name string) {
+			err := autoUpdateImageAlias(d, project, name)
+			if err != nil {
+				logger.Error("Failed to update image alias", log.Ctx{"err": err, "alias": name, "project": project})
+			}
+
+			ch <- struct{}{}
+		}(name)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+		}
+	}
+
 	return nil
 }
 
+// autoUpdateImageAlias points the alias at the latest image of its update source. The image it previously
+// pointed at is deleted once no other alias refers to it, unless it's pinned.
+func autoUpdateImageAlias(d *Daemon, project string, name string) error {
+	aliasID, alias, err := d.cluster.GetImageAlias(project, name, true)
+	if err != nil {
+		return err
+	}
+
+	source := alias.UpdateSource
+	if source == nil {
+		return nil
+	}
+
+	logger.Debug("Processing image alias", log.Ctx{"alias": name, "server": source.Server, "protocol": source.Protocol, "source": source.Alias})
+
+	newInfo, err := d.ImageDownload(nil, source.Server, source.Protocol, source.Certificate, "", source.Alias, alias.Type, false, false, "", false, project)
+	if err != nil {
+		return errors.Wrap(err, "Failed to download the image")
+	}
+
+	if newInfo.Fingerprint == alias.Target {
+		logger.Debug("Already up to date", log.Ctx{"alias": name, "fp": alias.Target})
+		return nil
+	}
+
+	newID, _, err := d.cluster.GetImage(project, newInfo.Fingerprint, false)
+	if err != nil {
+		return err
+	}
+
+	_, oldInfo, err := d.cluster.GetImage(project, alias.Target, false)
+	if err != nil {
+		return err
+	}
+
+	err = d.cluster.UpdateImageAlias(aliasID, newID, alias.Description)
+	if err != nil {
+		return errors.Wrap(err, "Failed to move the alias")
+	}
+
+	if oldInfo.Pinned || len(oldInfo.Aliases) > 1 {
+		return nil
+	}
+
+	return doImageDelete(d, project, oldInfo.Fingerprint, false)
+}
+
 // Update a single image.  The operation can be nil, if no progress tracking is needed.
 // Returns whether the image has been updated.
 func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Image, project string) error {
@@ -1276,6 +1472,13 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		return nil
 	}
 
+	// Keep the previous image if layered images are based on it.
+	layered, err := d.cluster.GetImagesWithBaseImage(fingerprint)
+	if err != nil || len(layered) > 0 {
+		setRefreshResult(true)
+		return nil
+	}
+
 	// Remove main image file.
 	fname := filepath.Join(d.os.VarDir, "images", fingerprint)
 	if shared.PathExists(fname) {
@@ -1325,30 +1528,24 @@ func pruneExpiredImagesTask(d *Daemon) (task.Func, task.Schedule) {
 
 	// Skip the first run, and instead run an initial pruning synchronously
 	// before we start updating images later on in the start up process.
-	expiry, err := cluster.ConfigGetInt64(d.cluster, "images.remote_cache_expiry")
+	interval, err := pruneExpiredImagesInterval(d)
 	if err != nil {
 		logger.Error("Unable to fetch cluster configuration", log.Ctx{"err": err})
-	} else if expiry > 0 {
+	} else if interval > 0 {
 		f(context.Background())
 	}
 
 	first := true
 	schedule := func() (time.Duration, error) {
-		interval := 24 * time.Hour
-		if first {
-			first = false
-			return interval, task.ErrSkip
-		}
-
-		expiry, err := cluster.ConfigGetInt64(d.cluster, "images.remote_cache_expiry")
+		interval, err := pruneExpiredImagesInterval(d)
 		if err != nil {
 			logger.Error("Unable to fetch cluster configuration", log.Ctx{"err": err})
-			return interval, nil
+			return 24 * time.Hour, nil
 		}
 
-		// Check if we're supposed to prune at all
-		if expiry <= 0 {
-			interval = 0
+		if first {
+			first = false
+			return interval, task.ErrSkip
 		}
 
 		return interval, nil
@@ -1357,6 +1554,27 @@ func pruneExpiredImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	return f, schedule
 }
 
+// pruneExpiredImagesInterval returns the interval at which the cached images are pruned, or 0 if neither an
+// expiry nor a maximum cache size is configured.
+func pruneExpiredImagesInterval(d *Daemon) (time.Duration, error) {
+	var config *cluster.Config
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		config, err = cluster.ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Check if we're supposed to prune at all. Project limits only apply on top of the global ones.
+	if config.RemoteCacheExpiry() <= 0 && config.RemoteCacheMaxSize() <= 0 {
+		return 0, nil
+	}
+
+	return config.RemoteCachePruneInterval(), nil
+}
+
 func pruneLeftoverImages(d *Daemon) {
 	opRun := func(op *operations.Operation) error {
 		// Get all images
@@ -1407,19 +1625,72 @@ func pruneLeftoverImages(d *Daemon) {
 	logger.Infof("Done pruning leftover image files")
 }
 
+// Number of cached images and bytes removed by the pruning task since the daemon started, as reported in the
+// metrics.
+var imagesPrunedCount int64
+var imagesPrunedBytes int64
+
 func pruneExpiredImages(ctx context.Context, d *Daemon) error {
-	expiry, err := cluster.ConfigGetInt64(d.cluster, "images.remote_cache_expiry")
+	var config *cluster.Config
+	projectMaxSizes := map[string]int64{}
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		config, err = cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		projects, err := tx.GetProjects(db.ProjectFilter{})
+		if err != nil {
+			return err
+		}
+
+		for _, project := range projects {
+			value := project.Config["images.remote_cache_max_size"]
+			if value == "" {
+				continue
+			}
+
+			size, err := units.ParseByteSizeString(value)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid images.remote_cache_max_size of project %q", project.Name)
+			}
+
+			projectMaxSizes[project.Name] = size
+		}
+
+		return nil
+	})
 	if err != nil {
 		return errors.Wrap(err, "Unable to fetch cluster configuration")
 	}
 
 	// Get the list of expired images.
-	images, err := d.cluster.GetExpiredImages(expiry)
+	images := []db.ExpiredImage{}
+	if config.RemoteCacheExpiry() > 0 {
+		images, err = d.cluster.GetExpiredImages(config.RemoteCacheExpiry())
+		if err != nil {
+			return errors.Wrap(err, "Unable to retrieve the list of expired images")
+		}
+	}
+
+	err = pruneCachedImages(ctx, d, images)
+	if err != nil {
+		return err
+	}
+
+	// Then evict the least recently used images until the cache fits in its limits.
+	images, err = d.cluster.GetImagesExceedingCacheSize(config.RemoteCacheMaxSize(), projectMaxSizes)
 	if err != nil {
-		return errors.Wrap(err, "Unable to retrieve the list of expired images")
+		return errors.Wrap(err, "Unable to retrieve the list of images exceeding the cache size")
 	}
 
-	// Delete them
+	return pruneCachedImages(ctx, d, images)
+}
+
+// pruneCachedImages removes the given cached images from the storage pools, the images directory and the
+// database.
+func pruneCachedImages(ctx context.Context, d *Daemon, images []db.ExpiredImage) error {
 	for _, img := range images {
 		// At each iteration we check if we got cancelled in the
 		// meantime. It is safe to abort here since anything not
@@ -1430,6 +1701,12 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 		default:
 		}
 
+		// Keep the images which layered images are based on.
+		layered, err := d.cluster.GetImagesWithBaseImage(img.Fingerprint)
+		if err != nil || len(layered) > 0 {
+			continue
+		}
+
 		// Get the IDs of all storage pools on which a storage volume
 		// for the requested image currently exists.
 		poolIDs, err := d.cluster.GetPoolsWithImage(img.Fingerprint)
@@ -1477,6 +1754,9 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 		if err = d.cluster.DeleteImage(imgID); err != nil {
 			return errors.Wrapf(err, "Error deleting image %q from database", img.Fingerprint)
 		}
+
+		atomic.AddInt64(&imagesPrunedCount, 1)
+		atomic.AddInt64(&imagesPrunedBytes, img.Size)
 	}
 
 	return nil
@@ -1496,98 +1776,114 @@ func imageDelete(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
 	do := func(op *operations.Operation) error {
-		// Use the fingerprint we received in a LIKE query and use the full
-		// fingerprint we receive from the database in all further queries.
-		imgID, imgInfo, err := d.cluster.GetImage(project, fingerprint, false)
-		if err != nil {
-			return err
-		}
-
-		if !isClusterNotification(r) {
-			// Check if the image being deleted is actually still
-			// referenced by other projects. In that case we don't want to
-			// physically delete it just yet, but just to remove the
-			// relevant database entry.
-			referenced, err := d.cluster.ImageIsReferencedByOtherProjects(project, imgInfo.Fingerprint)
-			if err != nil {
-				return err
-			}
+		return doImageDelete(d, project, fingerprint, isClusterNotification(r))
+	}
 
-			if referenced {
-				err := d.cluster.DeleteImage(imgID)
-				if err != nil {
-					return errors.Wrap(err, "Error deleting image info from the database")
-				}
+	resources := map[string][]string{}
+	resources["images"] = []string{fingerprint}
 
-				return nil
-			}
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDelete, resources, nil, do, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
 
-			// Notify the other nodes about the removed image so they can remove it from disk too.
-			notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
-			if err != nil {
-				return err
-			}
+	return operations.OperationResponse(op)
+}
 
-			err = notifier(func(client lxd.InstanceServer) error {
-				op, err := client.UseProject(project).DeleteImage(imgInfo.Fingerprint)
-				if err != nil {
-					return errors.Wrap(err, "Failed to request to delete image from peer node")
-				}
+// doImageDelete deletes the image with the given fingerprint from the project. The image files are only removed
+// once the image isn't referenced by any other project.
+func doImageDelete(d *Daemon, project string, fingerprint string, clusterNotification bool) error {
+	// Use the fingerprint we received in a LIKE query and use the full
+	// fingerprint we receive from the database in all further queries.
+	imgID, imgInfo, err := d.cluster.GetImage(project, fingerprint, false)
+	if err != nil {
+		return err
+	}
 
-				err = op.Wait()
-				if err != nil {
-					return errors.Wrap(err, "Failed to delete image from peer node")
-				}
+	if !clusterNotification {
+		// Check if the image being deleted is actually still
+		// referenced by other projects. In that case we don't want to
+		// physically delete it just yet, but just to remove the
+		// relevant database entry.
+		referenced, err := d.cluster.ImageIsReferencedByOtherProjects(project, imgInfo.Fingerprint)
+		if err != nil {
+			return err
+		}
 
-				return nil
-			})
+		if referenced {
+			err := d.cluster.DeleteImage(imgID)
 			if err != nil {
-				return err
+				return errors.Wrap(err, "Error deleting image info from the database")
 			}
+
+			return nil
 		}
 
-		// Delete the pool volumes.
-		poolIDs, err := d.cluster.GetPoolsWithImage(imgInfo.Fingerprint)
+		// Layered images can't be used without their base image.
+		layered, err := d.cluster.GetImagesWithBaseImage(imgInfo.Fingerprint)
 		if err != nil {
 			return err
 		}
 
-		pools, err := d.cluster.GetPoolNamesFromIDs(poolIDs)
+		if len(layered) > 0 {
+			return fmt.Errorf("Image is the base of layered images: %s", strings.Join(layered, ", "))
+		}
+
+		// Notify the other nodes about the removed image so they can remove it from disk too.
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
 		if err != nil {
 			return err
 		}
 
-		for _, pool := range pools {
-			err := doDeleteImageFromPool(d.State(), imgInfo.Fingerprint, pool)
+		err = notifier(func(client lxd.InstanceServer) error {
+			op, err := client.UseProject(project).DeleteImage(imgInfo.Fingerprint)
 			if err != nil {
-				return err
+				return errors.Wrap(err, "Failed to request to delete image from peer node")
 			}
-		}
 
-		// Remove the database entry.
-		if !isClusterNotification(r) {
-			err = d.cluster.DeleteImage(imgID)
+			err = op.Wait()
 			if err != nil {
-				return errors.Wrap(err, "Error deleting image info from the database")
+				return errors.Wrap(err, "Failed to delete image from peer node")
 			}
-		}
-
-		// Remove main image file from disk.
-		imageDeleteFromDisk(imgInfo.Fingerprint)
 
-		return nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	resources := map[string][]string{}
-	resources["images"] = []string{fingerprint}
+	// Delete the pool volumes.
+	poolIDs, err := d.cluster.GetPoolsWithImage(imgInfo.Fingerprint)
+	if err != nil {
+		return err
+	}
 
-	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDelete, resources, nil, do, nil, nil)
+	pools, err := d.cluster.GetPoolNamesFromIDs(poolIDs)
 	if err != nil {
-		return response.InternalError(err)
+		return err
 	}
 
-	return operations.OperationResponse(op)
-}
+	for _, pool := range pools {
+		err := doDeleteImageFromPool(d.State(), imgInfo.Fingerprint, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the database entry.
+	if !clusterNotification {
+		err = d.cluster.DeleteImage(imgID)
+		if err != nil {
+			return errors.Wrap(err, "Error deleting image info from the database")
+		}
+	}
+
+	// Remove main image file from disk.
+	imageDeleteFromDisk(imgInfo.Fingerprint)
+
+	return nil
+}
 
 // Helper to delete an image file from the local images directory.
 func imageDeleteFromDisk(fingerprint string) {
@@ -1608,6 +1904,16 @@ func imageDeleteFromDisk(fingerprint string) {
 			logger.Errorf("Error deleting image file %s: %s", fname, err)
 		}
 	}
+
+	// Remove the deltas from and against the image.
+	deltas, _ := filepath.Glob(shared.VarPath("images", fingerprint) + ".rootfs.delta-*")
+	others, _ := filepath.Glob(shared.VarPath("images", "*.rootfs.delta-"+fingerprint))
+	for _, fname := range append(deltas, others...) {
+		err := os.Remove(fname)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Errorf("Error deleting image delta %s: %s", fname, err)
+		}
+	}
 }
 
 func doImageGet(db *db.Cluster, project, fingerprint string, public bool) (*api.Image, response.Response) {
@@ -1665,7 +1971,7 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 		return response.NotFound(fmt.Errorf("Image '%s' not found", info.Fingerprint))
 	}
 
-	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties}
+	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties, info.Annotations}
 	return response.SyncResponseETag(true, info, etag)
 }
 
@@ -1679,7 +1985,7 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate ETag
-	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties}
+	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties, info.Annotations}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
 		return response.PreconditionFailed(err)
@@ -1690,6 +1996,11 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = imageValidateAnnotations(req.Annotations)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Get ExpiresAt
 	if !req.ExpiresAt.IsZero() {
 		info.ExpiresAt = req.ExpiresAt
@@ -1715,6 +2026,18 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.UpdateImageAnnotations(id, req.Annotations)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if req.Pinned != info.Pinned {
+		err = d.cluster.UpdateImagePinned(id, req.Pinned)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1728,7 +2051,7 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate ETag
-	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties}
+	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties, info.Annotations}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
 		return response.PreconditionFailed(err)
@@ -1777,14 +2100,77 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 		info.Properties = properties
 	}
 
+	// Get Annotations, an empty value removing the annotation
+	_, ok = reqRaw["annotations"]
+	if ok {
+		annotations := map[string]string{}
+		for k, v := range info.Annotations {
+			annotations[k] = v
+		}
+
+		for k, v := range req.Annotations {
+			if v == "" {
+				delete(annotations, k)
+				continue
+			}
+
+			annotations[k] = v
+		}
+
+		err = imageValidateAnnotations(annotations)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		info.Annotations = annotations
+	}
+
 	err = d.cluster.UpdateImage(id, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, "", nil)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.UpdateImageAnnotations(id, info.Annotations)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Get Pinned
+	pinned, err := reqRaw.GetBool("pinned")
+	if err == nil && pinned != info.Pinned {
+		err = d.cluster.UpdateImagePinned(id, pinned)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
+// imageValidateAnnotations checks the annotations of an image, the "status" annotation being restricted to the
+// values LXD acts upon.
+func imageValidateAnnotations(annotations map[string]string) error {
+	status := annotations["status"]
+	if !shared.StringInSlice(status, []string{"", "deprecated", "blocked"}) {
+		return fmt.Errorf("Invalid image status %q, must be one of \"deprecated\" or \"blocked\"", status)
+	}
+
+	return nil
+}
+
+// imageCheckStatus refuses images annotated as blocked and warns about deprecated ones before instances get
+// created from them.
+func imageCheckStatus(info *api.Image) error {
+	switch info.Annotations["status"] {
+	case "blocked":
+		return fmt.Errorf("Image %q is blocked", info.Fingerprint)
+	case "deprecated":
+		logger.Warn("Creating instance from deprecated image", log.Ctx{"fingerprint": info.Fingerprint})
+	}
+
+	return nil
+}
+
 func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	req := api.ImageAliasesPost{}
@@ -1796,8 +2182,13 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("name and target are required"))
 	}
 
+	err := imageAliasValidateSource(req.ImageAliasesEntryPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// This is just to see if the alias name already exists.
-	_, _, err := d.cluster.GetImageAlias(project, req.Name, true)
+	_, _, err = d.cluster.GetImageAlias(project, req.Name, true)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.InternalError(err)
@@ -1811,11 +2202,33 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	architectureIDs, resp := imageAliasArchitectures(d, project, req.ImageAliasesEntryPut)
+	if resp != nil {
+		return resp
+	}
+
 	err = d.cluster.CreateImageAlias(project, req.Name, id, req.Description)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if req.UpdateSource != nil || len(architectureIDs) > 0 {
+		aliasID, _, err := d.cluster.GetImageAlias(project, req.Name, true)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = d.cluster.UpdateImageAliasSource(aliasID, req.UpdateSource, req.AutoUpdate)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = d.cluster.UpdateImageAliasArchitectures(aliasID, architectureIDs)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/images/aliases/%s", version.APIVersion, req.Name))
 }
 
@@ -1903,16 +2316,36 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("The target field is required"))
 	}
 
+	err = imageAliasValidateSource(req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	imageId, _, err := d.cluster.GetImage(project, req.Target, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	architectureIDs, resp := imageAliasArchitectures(d, project, req)
+	if resp != nil {
+		return resp
+	}
+
 	err = d.cluster.UpdateImageAlias(id, imageId, req.Description)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.UpdateImageAliasSource(id, req.UpdateSource, req.AutoUpdate)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.UpdateImageAliasArchitectures(id, architectureIDs)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1931,8 +2364,13 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		return response.PreconditionFailed(err)
 	}
 
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
 	req := shared.Jmap{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&req); err != nil {
 		return response.BadRequest(err)
 	}
 
@@ -1943,6 +2381,13 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 			return response.BadRequest(err)
 		}
 
+		// The previous target is replaced for its architecture too.
+		for architecture, fingerprint := range alias.Architectures {
+			if fingerprint == alias.Target {
+				delete(alias.Architectures, architecture)
+			}
+		}
+
 		alias.Target = target
 	}
 
@@ -1956,19 +2401,127 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		alias.Description = description
 	}
 
+	_, ok = req["auto_update"]
+	if ok {
+		autoUpdate, err := req.GetBool("auto_update")
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		alias.AutoUpdate = autoUpdate
+	}
+
+	_, ok = req["update_source"]
+	if ok {
+		put := api.ImageAliasesEntryPut{}
+		if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&put); err != nil {
+			return response.BadRequest(err)
+		}
+
+		alias.UpdateSource = put.UpdateSource
+	}
+
+	_, ok = req["architectures"]
+	if ok {
+		put := api.ImageAliasesEntryPut{}
+		if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&put); err != nil {
+			return response.BadRequest(err)
+		}
+
+		alias.Architectures = put.Architectures
+	}
+
+	err = imageAliasValidateSource(alias.ImageAliasesEntryPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	imageId, _, err := d.cluster.GetImage(project, alias.Target, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	architectureIDs, resp := imageAliasArchitectures(d, project, alias.ImageAliasesEntryPut)
+	if resp != nil {
+		return resp
+	}
+
 	err = d.cluster.UpdateImageAlias(id, imageId, alias.Description)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.UpdateImageAliasSource(id, alias.UpdateSource, alias.AutoUpdate)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.UpdateImageAliasArchitectures(id, architectureIDs)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
+// imageAliasArchitectures returns the IDs of the images an alias points to for architectures other than the one
+// of its target, as listed in its architectures field.
+func imageAliasArchitectures(d *Daemon, project string, req api.ImageAliasesEntryPut) ([]int, response.Response) {
+	_, target, err := d.cluster.GetImage(project, req.Target, false)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	ids := []int{}
+	for architecture, fingerprint := range req.Architectures {
+		id, img, err := d.cluster.GetImage(project, fingerprint, false)
+		if err != nil {
+			return nil, response.SmartError(errors.Wrapf(err, "Failed to load image %q", fingerprint))
+		}
+
+		if img.Architecture != architecture {
+			return nil, response.BadRequest(fmt.Errorf("Image %q is for architecture %q rather than %q", img.Fingerprint, img.Architecture, architecture))
+		}
+
+		if img.Type != target.Type {
+			return nil, response.BadRequest(fmt.Errorf("Image %q isn't of the same type as the alias target", img.Fingerprint))
+		}
+
+		if architecture == target.Architecture {
+			if img.Fingerprint != target.Fingerprint {
+				return nil, response.BadRequest(fmt.Errorf("The image for architecture %q must be the alias target", architecture))
+			}
+
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// imageAliasValidateSource checks the update settings of an alias.
+func imageAliasValidateSource(req api.ImageAliasesEntryPut) error {
+	if req.UpdateSource == nil {
+		if req.AutoUpdate {
+			return fmt.Errorf("Automatic updates require an update source")
+		}
+
+		return nil
+	}
+
+	if req.UpdateSource.Server == "" || req.UpdateSource.Alias == "" {
+		return fmt.Errorf("The update source requires a server and an alias")
+	}
+
+	if !shared.StringInSlice(req.UpdateSource.Protocol, []string{"lxd", "simplestreams"}) {
+		return fmt.Errorf("Invalid update source protocol %q", req.UpdateSource.Protocol)
+	}
+
+	return nil
+}
+
 func imageAliasPost(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	name := mux.Vars(r)["name"]
@@ -2003,6 +2556,7 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 
 	public := d.checkTrustedClient(r) != nil || allowProjectPermission("images", "view")(d, r) != response.EmptySyncResponse
 	secret := r.FormValue("secret")
+	generateDelta := false
 
 	var imgInfo *api.Image
 	var err error
@@ -2013,7 +2567,7 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		if !imgInfo.Public && !imgInfo.Cached {
+		if imgInfo.Fingerprint != fingerprint || (!imgInfo.Public && !imgInfo.Cached) {
 			return response.NotFound(fmt.Errorf("Image '%s' not found", fingerprint))
 		}
 	} else {
@@ -2026,6 +2580,10 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		if !imgInfo.Public && public && !valid {
 			return response.NotFound(fmt.Errorf("Image '%s' not found", imgInfo.Fingerprint))
 		}
+
+		// Deltas are expensive to generate, so only trusted clients and servers the image was
+		// explicitly exported to get new ones generated.
+		generateDelta = !public || valid
 	}
 
 	// Check if the image is only available on another node.
@@ -2047,6 +2605,25 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
 	rootfsPath := imagePath + ".rootfs"
 
+	// Images can be converted between the unified and split formats as they're sent.
+	format := r.FormValue("format")
+	if !shared.StringInSlice(format, []string{"", "unified", "split"}) {
+		return response.BadRequest(fmt.Errorf("Invalid image format %q", format))
+	}
+
+	if format == "unified" && shared.PathExists(rootfsPath) {
+		return imageExportUnified(imgInfo, imagePath, rootfsPath)
+	}
+
+	if format == "split" && !shared.PathExists(rootfsPath) {
+		// Layered images rely on being unified tarballs to be recognized.
+		if imgInfo.BaseImage != "" {
+			return response.BadRequest(fmt.Errorf("Layered images can't be exported as split images"))
+		}
+
+		return imageExportSplit(imgInfo, imagePath)
+	}
+
 	_, ext, _, err := shared.DetectCompression(imagePath)
 	if err != nil {
 		ext = ""
@@ -2076,7 +2653,21 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		files[1].Path = rootfsPath
 		files[1].Filename = filename
 
-		return response.FileResponse(r, files, nil, false)
+		// Send a delta against one of the images the client already has, if any.
+		var headers map[string]string
+		deltaFrom := r.FormValue("delta_from")
+		if deltaFrom != "" {
+			deltaPath, base, err := imageExportDelta(d, project, imgInfo, strings.Split(deltaFrom, ","), public, generateDelta)
+			if err != nil {
+				logger.Warn("Failed to generate image delta", log.Ctx{"fingerprint": imgInfo.Fingerprint, "err": err})
+			} else if deltaPath != "" {
+				files[1].Identifier = "rootfs.delta"
+				files[1].Path = deltaPath
+				headers = map[string]string{"X-LXD-Delta-Base": base}
+			}
+		}
+
+		return response.FileResponse(r, files, headers, false)
 	}
 
 	files := make([]response.FileResponseEntry, 1)
@@ -2087,6 +2678,141 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	return response.FileResponse(r, files, nil, false)
 }
 
+// imageDeltaMaxCandidates is the number of candidate base images considered for a delta, to bound the work
+// a single export request can cause.
+const imageDeltaMaxCandidates = 1
+
+// imageDeltaExpiry is how long a generated delta is kept after it was last sent.
+const imageDeltaExpiry = 24 * time.Hour
+
+// imageExportDelta returns the path to a delta of the root filesystem of the given image against the first of
+// the candidate images found in the store, along with the fingerprint of that image. Deltas are generated on
+// first use if generate is true and kept alongside the image until they expire. An empty path is returned when
+// no usable delta can be provided.
+func imageExportDelta(d *Daemon, project string, info *api.Image, candidates []string, public bool, generate bool) (string, string, error) {
+	_, err := exec.LookPath("xdelta3")
+	if err != nil {
+		return "", "", nil
+	}
+
+	if len(candidates) > imageDeltaMaxCandidates {
+		candidates = candidates[:imageDeltaMaxCandidates]
+	}
+
+	rootfsPath := shared.VarPath("images", info.Fingerprint+".rootfs")
+	rootfsInfo, err := os.Stat(rootfsPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, candidate := range candidates {
+		// Only accept full fingerprints of other images of the same type.
+		if len(candidate) != 64 || candidate == info.Fingerprint {
+			continue
+		}
+
+		_, err := hex.DecodeString(candidate)
+		if err != nil {
+			continue
+		}
+
+		_, baseInfo, err := d.cluster.GetImage(project, candidate, public)
+		if err != nil || baseInfo.Type != info.Type {
+			continue
+		}
+
+		basePath := shared.VarPath("images", candidate+".rootfs")
+		if !shared.PathExists(basePath) {
+			continue
+		}
+
+		deltaPath := fmt.Sprintf("%s.delta-%s", rootfsPath, candidate)
+		if !shared.PathExists(deltaPath) {
+			if !generate {
+				continue
+			}
+
+			// Generate into a temporary file so concurrent exports never see a partial delta.
+			tmpPath := fmt.Sprintf("%s.tmp-%d", deltaPath, time.Now().UnixNano())
+			_, err = shared.RunCommand("xdelta3", "-e", "-f", "-s", basePath, rootfsPath, tmpPath)
+			if err != nil {
+				os.Remove(tmpPath)
+				return "", "", err
+			}
+
+			err = os.Rename(tmpPath, deltaPath)
+			if err != nil {
+				os.Remove(tmpPath)
+				return "", "", err
+			}
+		}
+
+		// A delta is only worth sending if it's smaller than the full root filesystem.
+		deltaInfo, err := os.Stat(deltaPath)
+		if err != nil {
+			return "", "", err
+		}
+
+		if deltaInfo.Size() >= rootfsInfo.Size() {
+			continue
+		}
+
+		// Record the use of the delta to keep it from expiring.
+		now := time.Now()
+		err = os.Chtimes(deltaPath, now, now)
+		if err != nil {
+			return "", "", err
+		}
+
+		return deltaPath, candidate, nil
+	}
+
+	return "", "", nil
+}
+
+func pruneExpiredImageDeltasTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := pruneExpiredImageDeltas(time.Now())
+		if err != nil {
+			logger.Warn("Failed to prune expired image deltas", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Hour)
+}
+
+// pruneExpiredImageDeltas removes the image deltas which haven't been sent for imageDeltaExpiry.
+func pruneExpiredImageDeltas(now time.Time) error {
+	deltas, err := filepath.Glob(shared.VarPath("images", "*.rootfs.delta-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, deltaPath := range deltas {
+		deltaInfo, err := os.Stat(deltaPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return err
+		}
+
+		if now.Sub(deltaInfo.ModTime()) < imageDeltaExpiry {
+			continue
+		}
+
+		err = os.Remove(deltaPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		logger.Debugf("Removed expired image delta: %s", filepath.Base(deltaPath))
+	}
+
+	return nil
+}
+
 func imageExportPost(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	fingerprint := mux.Vars(r)["fingerprint"]
@@ -2277,6 +3003,10 @@ func imageRefresh(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if imageInfo.Pinned {
+		return response.BadRequest(fmt.Errorf("Pinned images can't be refreshed"))
+	}
+
 	// Begin background operation
 	run := func(op *operations.Operation) error {
 		return autoUpdateImage(d, op, imageId, imageInfo, project)
@@ -2293,21 +3023,15 @@ func imageRefresh(d *Daemon, r *http.Request) response.Response {
 func autoSyncImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		// In order to only have one task operation executed per image when syncing the images
-		// across the cluster, only leader node can launch the task, no others.
-		localAddress, err := node.ClusterAddress(d.db)
+		// across the cluster, only the member the task is assigned to launches it.
+		local, err := clusterTaskIsLocal(d, "images.sync", nil)
 		if err != nil {
-			logger.Errorf("Failed to get current node address: %v", err)
+			logger.Error("Failed to check image synchronization task assignment", log.Ctx{"err": err})
 			return
 		}
 
-		leader, err := d.gateway.LeaderAddress()
-		if err != nil {
-			logger.Errorf("Failed to get leader node address: %v", err)
-			return
-		}
-
-		if localAddress != leader {
-			logger.Debug("Skipping image synchronization task since we're not leader")
+		if !local {
+			logger.Debug("Skipping image synchronization task since it's assigned to another member")
 			return
 		}
 
@@ -2334,7 +3058,93 @@ func autoSyncImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	return f, task.Daily()
 }
 
+// autoSyncImages makes sure that all images in the cluster exist on at least
+// cluster.images_minimal_replica members. The local images are replicated
+// directly while the other members are asked to replicate the images which
+// only they have. It's meant to run on a single member.
 func autoSyncImages(ctx context.Context, d *Daemon) error {
+	err := syncLocalImages(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	desiredSyncNodeCount, err := imagesMinimalReplica(d)
+	if err != nil {
+		return err
+	}
+
+	var members []db.NodeInfo
+	var localAddress string
+	var offlineThreshold time.Duration
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		members, err = tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		localAddress, err = tx.GetLocalNodeAddress()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.GetNodeOfflineThreshold()
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load cluster members")
+	}
+
+	for _, member := range members {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if member.Address == localAddress || member.IsOffline(offlineThreshold) {
+			continue
+		}
+
+		imageProjectInfo, err := d.cluster.GetImagesOnNode(member.ID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to query image fingerprints of member %q", member.Name)
+		}
+
+		// Only bother the member if it has images which need more copies.
+		needed := false
+		for fingerprint := range imageProjectInfo {
+			addresses, err := d.cluster.GetNodesWithImage(fingerprint)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get nodes for the image synchronization")
+			}
+
+			if int64(len(addresses)) < desiredSyncNodeCount {
+				needed = true
+				break
+			}
+		}
+
+		if !needed {
+			continue
+		}
+
+		client, err := cluster.Connect(member.Address, d.endpoints.NetworkCert(), true)
+		if err != nil {
+			logger.Error("Failed to connect to cluster member for image synchronization", log.Ctx{"member": member.Name, "err": err})
+			continue
+		}
+
+		_, _, err = client.RawQuery("POST", "/internal/cluster/image-sync", nil, "")
+		if err != nil {
+			logger.Error("Failed to synchronize images of cluster member", log.Ctx{"member": member.Name, "err": err})
+		}
+	}
+
+	return nil
+}
+
+// syncLocalImages replicates the images of the local member to other members
+// which don't have them yet, up to cluster.images_minimal_replica copies.
+func syncLocalImages(ctx context.Context, d *Daemon) error {
 	// Check how many images the current node owns and automatically sync all
 	// available images to other nodes which don't have yet.
 	imageProjectInfo, err := d.cluster.GetImagesOnLocalNode()
@@ -2362,7 +3172,8 @@ func autoSyncImages(ctx context.Context, d *Daemon) error {
 	return nil
 }
 
-func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error {
+// imagesMinimalReplica returns the number of cluster members each image should be on.
+func imagesMinimalReplica(d *Daemon) (int64, error) {
 	var desiredSyncNodeCount int64
 
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
@@ -2384,6 +3195,15 @@ func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error
 
 		return nil
 	})
+	if err != nil {
+		return -1, err
+	}
+
+	return desiredSyncNodeCount, nil
+}
+
+func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error {
+	desiredSyncNodeCount, err := imagesMinimalReplica(d)
 	if err != nil {
 		return err
 	}
@@ -2470,6 +3290,22 @@ func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error
 	return op.Wait()
 }
 
+var internalClusterImageSyncCmd = APIEndpoint{
+	Path: "cluster/image-sync",
+
+	Post: APIEndpointAction{Handler: internalClusterImageSync},
+}
+
+// internalClusterImageSync replicates the local images to other members, as requested by the leader.
+func internalClusterImageSync(d *Daemon, r *http.Request) response.Response {
+	err := syncLocalImages(r.Context(), d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 func createTokenResponse(d *Daemon, project, fingerprint string, metadata shared.Jmap) response.Response {
 	secret, err := shared.RandomCryptoString()
 	if err != nil {
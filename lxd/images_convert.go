@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/instancewriter"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// imageImportConversion returns the disk format and compression algorithm uploaded virtual-machine images are
+// converted to, empty values meaning the uploaded image is kept as is.
+func imageImportConversion(d *Daemon) (string, string, error) {
+	format, err := cluster.ConfigGetString(d.cluster, "images.import_vm_format")
+	if err != nil {
+		return "", "", err
+	}
+
+	compress, err := cluster.ConfigGetString(d.cluster, "images.import_compression")
+	if err != nil {
+		return "", "", err
+	}
+
+	return format, compress, nil
+}
+
+// imageConvert converts the root disk of a virtual-machine image to the given format and re-packs its tarball
+// with the given compression algorithm, replacing the image files in place. The rootfs file is empty for unified
+// tarballs. The new fingerprint and size of the image are returned.
+func imageConvert(d *Daemon, imageFile string, rootfsFile string, format string, compress string, builddir string) (string, int64, error) {
+	tempDir, err := ioutil.TempDir(builddir, "lxd_image_convert_")
+	if err != nil {
+		return "", -1, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The metadata tarball of split images only needs re-packing to change its compression.
+	if rootfsFile == "" || compress != "" {
+		err = shared.Unpack(imageFile, tempDir, false, d.os.RunningInUserNS, nil)
+		if err != nil {
+			return "", -1, errors.Wrap(err, "Failed unpacking image")
+		}
+	}
+
+	diskFile := rootfsFile
+	if diskFile == "" {
+		diskFile = filepath.Join(tempDir, "rootfs.img")
+	}
+
+	if format != "" {
+		err = imageConvertDisk(diskFile, format)
+		if err != nil {
+			return "", -1, err
+		}
+	}
+
+	if rootfsFile == "" || compress != "" {
+		// Conversions of unified tarballs without a compression algorithm use the default one of new images.
+		if compress == "" {
+			compress, err = cluster.ConfigGetString(d.cluster, "images.compression_algorithm")
+			if err != nil {
+				return "", -1, err
+			}
+		}
+
+		err = imageConvertPack(tempDir, imageFile, compress)
+		if err != nil {
+			return "", -1, err
+		}
+	}
+
+	// Compute the fingerprint and size of the converted image.
+	sha256 := sha256.New()
+	var size int64
+	for _, path := range []string{imageFile, rootfsFile} {
+		if path == "" {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return "", -1, err
+		}
+
+		n, err := io.Copy(sha256, f)
+		f.Close()
+		if err != nil {
+			return "", -1, err
+		}
+
+		size += n
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum(nil)), size, nil
+}
+
+// imageConvertDisk converts the given disk image file in place to the given format, leaving it alone if it
+// already uses that format.
+func imageConvertDisk(path string, format string) error {
+	imgJSON, err := shared.RunCommand("qemu-img", "info", "--output=json", path)
+	if err != nil {
+		return errors.Wrapf(err, "Failed reading image info %q", path)
+	}
+
+	imgInfo := struct {
+		Format string `json:"format"`
+	}{}
+
+	err = json.Unmarshal([]byte(imgJSON), &imgInfo)
+	if err != nil {
+		return err
+	}
+
+	if !shared.StringInSlice(imgInfo.Format, []string{"qcow2", "raw"}) {
+		return fmt.Errorf("Unexpected image format %q", imgInfo.Format)
+	}
+
+	if imgInfo.Format == format {
+		return nil
+	}
+
+	logger.Debugf("Converting %s image %q to %s", imgInfo.Format, path, format)
+	_, err = shared.RunCommand("qemu-img", "convert", "-f", imgInfo.Format, "-O", format, path, path+".convert")
+	if err != nil {
+		os.Remove(path + ".convert")
+		return errors.Wrapf(err, "Failed converting image to %s", format)
+	}
+
+	return os.Rename(path+".convert", path)
+}
+
+// imageConvertPack writes the content of the given directory to the given image tarball, compressed with the
+// given algorithm.
+func imageConvertPack(dir string, imageFile string, compress string) error {
+	f, err := os.Create(imageFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tarReader, tarWriter := io.Pipe()
+	compressErr := make(chan error, 1)
+	go func() {
+		var err error
+		if compress != "none" {
+			err = compressFile(compress, tarReader, f)
+		} else {
+			_, err = io.Copy(f, tarReader)
+		}
+
+		// End the tarball creation if the compression failed.
+		tarReader.CloseWithError(err)
+		compressErr <- err
+	}()
+
+	tw := instancewriter.NewInstanceTarWriter(tarWriter, nil)
+	offset := len(dir) + 1
+
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == dir {
+			return nil
+		}
+
+		return tw.WriteFile(path[offset:], path, fi, false)
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+
+	tarWriter.CloseWithError(err)
+	if err != nil {
+		<-compressErr
+		return err
+	}
+
+	err = <-compressErr
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
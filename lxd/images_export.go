@@ -0,0 +1,225 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// imageExportCopyEntry writes the given tarball entry to tw, renaming it as well as the target of hard links
+// with the rename function.
+func imageExportCopyEntry(tw *tar.Writer, name string, hdr *tar.Header, r io.Reader, rename func(name string) string) error {
+	hdr.Name = rename(name)
+	if hdr.Typeflag == tar.TypeDir {
+		hdr.Name += "/"
+	}
+
+	if hdr.Typeflag == tar.TypeLink {
+		hdr.Linkname = rename(strings.TrimPrefix(strings.TrimPrefix(hdr.Linkname, "./"), "/"))
+	}
+
+	// Let the writer pick a format able to hold the new names.
+	hdr.Format = tar.FormatUnknown
+
+	err := tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, r)
+	return err
+}
+
+// imageExportUnified returns a response streaming the given split image as an uncompressed unified tarball.
+func imageExportUnified(info *api.Image, imagePath string, rootfsPath string) response.Response {
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		// The converted image doesn't match the fingerprint, so the hash of what's sent is computed
+		// beforehand for the client to check it.
+		hash := sha256.New()
+		err := imageExportWriteUnified(info, imagePath, rootfsPath, hash)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline;filename=%s.tar", info.Fingerprint))
+		w.Header().Set("X-LXD-Export-Format", "unified")
+		w.Header().Set("X-LXD-Export-SHA256", fmt.Sprintf("%x", hash.Sum(nil)))
+		w.WriteHeader(http.StatusOK)
+
+		return imageExportWriteUnified(info, imagePath, rootfsPath, w)
+	})
+}
+
+// imageExportWriteUnified writes the given split image to w as an uncompressed unified tarball.
+func imageExportWriteUnified(info *api.Image, imagePath string, rootfsPath string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	err := imageLayerWalk(imagePath, "", func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == "" {
+			return nil
+		}
+
+		return imageExportCopyEntry(tw, name, hdr, r, func(name string) string { return name })
+	})
+	if err != nil {
+		return err
+	}
+
+	if info.Type == instancetype.VM.String() {
+		f, err := os.Open(rootfsPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     "rootfs.img",
+			Mode:     0600,
+			Size:     fi.Size(),
+			ModTime:  fi.ModTime(),
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(tw, f)
+		if err != nil {
+			return err
+		}
+	} else {
+		rename := func(name string) string {
+			return strings.TrimSuffix("rootfs/"+name, "/")
+		}
+
+		err = imageLayerWalk(rootfsPath, "", func(name string, hdr *tar.Header, r io.Reader) error {
+			return imageExportCopyEntry(tw, name, hdr, r, rename)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// imageExportSplit returns a response streaming the given unified image as an uncompressed metadata tarball
+// followed by the root filesystem, as a tarball for containers or a disk image for virtual-machines.
+func imageExportSplit(info *api.Image, imagePath string) response.Response {
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		// The converted image doesn't match the fingerprint, so the hash of the content of both parts is
+		// computed beforehand for the client to check it.
+		hash := sha256.New()
+		err := imageExportWriteSplit(info, imagePath, func(fieldname string, filename string) (io.Writer, error) {
+			return hash, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", mw.FormDataContentType())
+		w.Header().Set("X-LXD-Export-Format", "split")
+		w.Header().Set("X-LXD-Export-SHA256", fmt.Sprintf("%x", hash.Sum(nil)))
+		w.WriteHeader(http.StatusOK)
+
+		err = imageExportWriteSplit(info, imagePath, mw.CreateFormFile)
+		if err != nil {
+			return err
+		}
+
+		return mw.Close()
+	})
+}
+
+// imageExportWriteSplit writes the given unified image as an uncompressed metadata tarball followed by the root
+// filesystem, each to the writer returned by createPart for it.
+func imageExportWriteSplit(info *api.Image, imagePath string, createPart func(fieldname string, filename string) (io.Writer, error)) error {
+	isRootfs := func(name string) bool {
+		return name == "rootfs" || name == "rootfs.img" || strings.HasPrefix(name, "rootfs/")
+	}
+
+	// Write the metadata tarball.
+	fw, err := createPart("metadata", fmt.Sprintf("meta-%s.tar", info.Fingerprint))
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(fw)
+	err = imageLayerWalk(imagePath, "", func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == "" || isRootfs(name) {
+			return nil
+		}
+
+		return imageExportCopyEntry(tw, name, hdr, r, func(name string) string { return name })
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	// Write the root filesystem.
+	if info.Type == instancetype.VM.String() {
+		fw, err = createPart("rootfs.img", fmt.Sprintf("%s.img", info.Fingerprint))
+		if err != nil {
+			return err
+		}
+
+		return imageLayerWalk(imagePath, "", func(name string, hdr *tar.Header, r io.Reader) error {
+			if name != "rootfs.img" {
+				return nil
+			}
+
+			_, err := io.Copy(fw, r)
+			return err
+		})
+	}
+
+	fw, err = createPart("rootfs", fmt.Sprintf("%s.tar", info.Fingerprint))
+	if err != nil {
+		return err
+	}
+
+	rename := func(name string) string {
+		if name == "rootfs" {
+			return "."
+		}
+
+		return strings.TrimPrefix(name, "rootfs/")
+	}
+
+	tw = tar.NewWriter(fw)
+	err = imageLayerWalk(imagePath, "", func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == "rootfs.img" || !isRootfs(name) {
+			return nil
+		}
+
+		return imageExportCopyEntry(tw, name, hdr, r, rename)
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
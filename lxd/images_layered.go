@@ -0,0 +1,413 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// imageLayerEntry describes a root filesystem entry of an image, as used to compare it against an instance.
+type imageLayerEntry struct {
+	typeflag byte
+	mode     int64
+	uid      int
+	gid      int
+	size     int64
+	linkname string
+	devmajor int64
+	devminor int64
+	sha256   string
+}
+
+// imageLayerEntryFromTar returns the description of the given tarball entry, hashing its content.
+func imageLayerEntryFromTar(hdr *tar.Header, r io.Reader) (imageLayerEntry, error) {
+	entry := imageLayerEntry{
+		typeflag: hdr.Typeflag,
+		mode:     hdr.Mode,
+		uid:      hdr.Uid,
+		gid:      hdr.Gid,
+		size:     hdr.Size,
+		linkname: hdr.Linkname,
+		devmajor: hdr.Devmajor,
+		devminor: hdr.Devminor,
+	}
+
+	if hdr.Typeflag == tar.TypeReg {
+		hash := sha256.New()
+		_, err := io.Copy(hash, r)
+		if err != nil {
+			return entry, err
+		}
+
+		entry.sha256 = fmt.Sprintf("%x", hash.Sum(nil))
+	}
+
+	return entry, nil
+}
+
+// imageLayerWalk calls fn for each entry of the given image file, with names relative to the root of the
+// image. Entries of a separate root filesystem file are prefixed with "rootfs/".
+func imageLayerWalk(path string, prefix string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, ext, unpacker, err := shared.DetectCompressionFile(f)
+	if err != nil {
+		return err
+	}
+
+	if ext == ".squashfs" {
+		// sqfs2tar can only read from a file
+		unpacker = append(unpacker, path)
+	}
+
+	tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), f, unpacker)
+	if err != nil {
+		return err
+	}
+	defer cancelFunc()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(hdr.Name, "/"), "./"), "/")
+		if name == "." {
+			name = ""
+		}
+
+		if prefix != "" {
+			name = strings.TrimSuffix(prefix+name, "/")
+		}
+
+		err = fn(name, hdr, tr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// imageLayerIndex returns the description of all the root filesystem entries of the image with the given
+// fingerprint, taking the images it's layered on into account.
+func imageLayerIndex(d *Daemon, fingerprint string) (map[string]imageLayerEntry, error) {
+	_, image, err := d.cluster.GetImageFromAnyProject(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]imageLayerEntry{}
+	if image.BaseImage != "" {
+		index, err = imageLayerIndex(d, image.BaseImage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	imagePath := shared.VarPath("images", fingerprint)
+	rootfsPath := imagePath + ".rootfs"
+
+	var whiteouts []string
+	addEntry := func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == "whiteouts" {
+			content, err := ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+
+			whiteouts = strings.Split(strings.TrimSpace(string(content)), "\n")
+			return nil
+		}
+
+		if name != "rootfs" && !strings.HasPrefix(name, "rootfs/") {
+			return nil
+		}
+
+		entry, err := imageLayerEntryFromTar(hdr, r)
+		if err != nil {
+			return err
+		}
+
+		index[name] = entry
+		return nil
+	}
+
+	if shared.PathExists(rootfsPath) {
+		err = imageLayerWalk(rootfsPath, "rootfs/", addEntry)
+	} else {
+		err = imageLayerWalk(imagePath, "", addEntry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, whiteout := range whiteouts {
+		if whiteout == "" {
+			continue
+		}
+
+		name := "rootfs/" + whiteout
+		for path := range index {
+			if path == name || strings.HasPrefix(path, name+"/") {
+				delete(index, path)
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// imagePublishDiffBase returns the image the given instance was created from, which a layered image published
+// from the instance only contains the changes from.
+func imagePublishDiffBase(d *Daemon, project string, inst instance.Instance) (*api.Image, error) {
+	if inst.Type() != instancetype.Container {
+		return nil, fmt.Errorf("Layered images can only be published from containers")
+	}
+
+	fingerprint := inst.LocalConfig()["volatile.base_image"]
+	if fingerprint == "" {
+		return nil, fmt.Errorf("Instance %q wasn't created from an image", inst.Name())
+	}
+
+	_, base, err := d.cluster.GetImage(project, fingerprint, false)
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return nil, fmt.Errorf("Base image %q of instance %q doesn't exist anymore", fingerprint, inst.Name())
+		}
+
+		return nil, err
+	}
+
+	if base.Type != instancetype.Container.String() {
+		return nil, fmt.Errorf("Layered images can only be based on container images")
+	}
+
+	if !shared.PathExists(shared.VarPath("images", base.Fingerprint)) {
+		return nil, fmt.Errorf("Base image %q isn't available on this server", base.Fingerprint)
+	}
+
+	return base, nil
+}
+
+// imageExportDiff exports the given instance as a layered image on top of the given base image, writing the
+// resulting tarball to w. The tarball only contains the root filesystem entries which differ from the base
+// image, along with a "whiteouts" file listing the paths removed from it.
+func imageExportDiff(d *Daemon, inst instance.Instance, base *api.Image, builddir string, w io.Writer, properties map[string]string) (api.ImageMetadata, error) {
+	// Export the full instance first.
+	exportFile, err := ioutil.TempFile(builddir, "lxd_build_export_")
+	if err != nil {
+		return api.ImageMetadata{}, err
+	}
+	defer os.Remove(exportFile.Name())
+	defer exportFile.Close()
+
+	meta, err := inst.Export(exportFile, properties)
+	if err != nil {
+		return meta, err
+	}
+
+	meta.BaseImage = base.Fingerprint
+
+	index, err := imageLayerIndex(d, base.Fingerprint)
+	if err != nil {
+		return meta, err
+	}
+
+	_, err = exportFile.Seek(0, 0)
+	if err != nil {
+		return meta, err
+	}
+
+	tw := tar.NewWriter(w)
+	seen := map[string]bool{}
+	tr := tar.NewReader(exportFile)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return meta, err
+		}
+
+		name := strings.TrimPrefix(strings.TrimSuffix(hdr.Name, "/"), "./")
+
+		// Record the base image in the metadata.
+		if name == "metadata.yaml" {
+			data, err := yaml.Marshal(&meta)
+			if err != nil {
+				return meta, err
+			}
+
+			hdr.Size = int64(len(data))
+			err = tw.WriteHeader(hdr)
+			if err != nil {
+				return meta, err
+			}
+
+			_, err = tw.Write(data)
+			if err != nil {
+				return meta, err
+			}
+
+			continue
+		}
+
+		if name != "rootfs" && !strings.HasPrefix(name, "rootfs/") {
+			err = tw.WriteHeader(hdr)
+			if err != nil {
+				return meta, err
+			}
+
+			_, err = io.Copy(tw, tr)
+			if err != nil {
+				return meta, err
+			}
+
+			continue
+		}
+
+		seen[name] = true
+
+		baseEntry, ok := index[name]
+		if ok && hdr.Typeflag == tar.TypeReg && baseEntry.typeflag == tar.TypeReg && baseEntry.size == hdr.Size {
+			err = imageExportDiffFile(tw, hdr, tr, baseEntry, builddir)
+			if err != nil {
+				return meta, err
+			}
+
+			continue
+		}
+
+		if ok && hdr.Typeflag != tar.TypeReg {
+			entry, err := imageLayerEntryFromTar(hdr, nil)
+			if err != nil {
+				return meta, err
+			}
+
+			if entry == baseEntry {
+				continue
+			}
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return meta, err
+		}
+
+		_, err = io.Copy(tw, tr)
+		if err != nil {
+			return meta, err
+		}
+	}
+
+	// List the paths removed from the base image, skipping those within removed directories.
+	removed := []string{}
+	for name := range index {
+		if !seen[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	whiteouts := bytes.Buffer{}
+	last := ""
+	for _, name := range removed {
+		if last != "" && strings.HasPrefix(name, last+"/") {
+			continue
+		}
+
+		last = name
+		fmt.Fprintf(&whiteouts, "%s\n", strings.TrimPrefix(name, "rootfs/"))
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:     "whiteouts",
+		Mode:     0600,
+		Size:     int64(whiteouts.Len()),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return meta, err
+	}
+
+	_, err = tw.Write(whiteouts.Bytes())
+	if err != nil {
+		return meta, err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return meta, err
+	}
+
+	return meta, nil
+}
+
+// imageExportDiffFile writes the given regular file to the layered image tarball if its content differs from
+// the one of the same size in the base image.
+func imageExportDiffFile(tw *tar.Writer, hdr *tar.Header, r io.Reader, baseEntry imageLayerEntry, builddir string) error {
+	// The file is read twice, to compare it and then to copy it if needed.
+	buf, err := ioutil.TempFile(builddir, "lxd_build_entry_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(buf.Name())
+	defer buf.Close()
+
+	_, err = io.Copy(buf, r)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	entry, err := imageLayerEntryFromTar(hdr, buf)
+	if err != nil {
+		return err
+	}
+
+	if entry == baseEntry {
+		return nil
+	}
+
+	_, err = buf.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, buf)
+	return err
+}
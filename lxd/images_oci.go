@@ -0,0 +1,405 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/instancewriter"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// ociArchitectures maps the LXD architectures to the names used by OCI registries.
+var ociArchitectures = map[int]string{
+	osarch.ARCH_32BIT_INTEL_X86:             "386",
+	osarch.ARCH_64BIT_INTEL_X86:             "amd64",
+	osarch.ARCH_32BIT_ARMV7_LITTLE_ENDIAN:   "arm",
+	osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN:   "arm64",
+	osarch.ARCH_64BIT_POWERPC_LITTLE_ENDIAN: "ppc64le",
+	osarch.ARCH_64BIT_S390_BIG_ENDIAN:       "s390x",
+	osarch.ARCH_64BIT_RISCV_LITTLE_ENDIAN:   "riscv64",
+}
+
+// ociRuntimeConfig is the part of the runtime configuration generated by umoci which describes how to run the
+// image.
+type ociRuntimeConfig struct {
+	Process struct {
+		Args []string `json:"args"`
+		Env  []string `json:"env"`
+		Cwd  string   `json:"cwd"`
+		User struct {
+			UID uint32 `json:"uid"`
+			GID uint32 `json:"gid"`
+		} `json:"user"`
+	} `json:"process"`
+}
+
+// imgPostOCIInfo imports an image from an OCI registry as a container image, using skopeo to pull it and umoci
+// to flatten its layers into a root filesystem.
+func imgPostOCIInfo(d *Daemon, req api.ImagesPost, op *operations.Operation, builddir string, project string) (*api.Image, error) {
+	reference := req.Source.URL
+	if reference == "" {
+		return nil, fmt.Errorf("Missing image reference")
+	}
+
+	if !strings.HasPrefix(reference, "docker://") {
+		reference = fmt.Sprintf("docker://%s", reference)
+	}
+
+	entrypoint := req.Source.Entrypoint
+	if entrypoint == "" {
+		entrypoint = "wrapper"
+	}
+
+	if !shared.StringInSlice(entrypoint, []string{"wrapper", "config"}) {
+		return nil, fmt.Errorf("Invalid entrypoint mode %q", entrypoint)
+	}
+
+	for _, tool := range []string{"skopeo", "umoci"} {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return nil, fmt.Errorf("The %q tool is required to import OCI images", tool)
+		}
+	}
+
+	architecture, err := osarch.ArchitectureName(d.os.Architectures[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ociArchitecture, ok := ociArchitectures[d.os.Architectures[0]]
+	if !ok {
+		return nil, fmt.Errorf("Architecture %q isn't supported for OCI images", architecture)
+	}
+
+	tmpDir, err := ioutil.TempDir(builddir, "lxd_oci_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Pull the image and flatten its layers.
+	ociDir := filepath.Join(tmpDir, "oci")
+	bundleDir := filepath.Join(tmpDir, "bundle")
+
+	op.UpdateMetadata(map[string]interface{}{"download_progress": "Pulling image"})
+	_, err = shared.RunCommand("skopeo", "--override-arch", ociArchitecture, "copy", reference, fmt.Sprintf("oci:%s:lxd", ociDir))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pull OCI image %q: %v", reference, err)
+	}
+
+	op.UpdateMetadata(map[string]interface{}{"download_progress": "Unpacking image"})
+	_, err = shared.RunCommand("umoci", "unpack", "--image", fmt.Sprintf("%s:lxd", ociDir), bundleDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unpack OCI image %q: %v", reference, err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(bundleDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	config := ociRuntimeConfig{}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Process.Args) == 0 {
+		return nil, fmt.Errorf("OCI image %q doesn't define an entrypoint", reference)
+	}
+
+	rootfsDir := filepath.Join(bundleDir, "rootfs")
+	properties := map[string]string{
+		"description":   fmt.Sprintf("OCI image %s", strings.TrimPrefix(reference, "docker://")),
+		"oci.reference": reference,
+	}
+
+	if entrypoint == "wrapper" {
+		err = ociWriteInitWrapper(rootfsDir, config)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		properties["oci.entrypoint"] = shellquote.Join(config.Process.Args...)
+		if config.Process.Cwd != "" && config.Process.Cwd != "/" {
+			properties["oci.cwd"] = config.Process.Cwd
+		}
+
+		if config.Process.User.UID != 0 {
+			properties["oci.uid"] = fmt.Sprintf("%d", config.Process.User.UID)
+		}
+
+		if config.Process.User.GID != 0 {
+			properties["oci.gid"] = fmt.Sprintf("%d", config.Process.User.GID)
+		}
+
+		for _, env := range config.Process.Env {
+			fields := strings.SplitN(env, "=", 2)
+			if len(fields) == 2 {
+				properties[fmt.Sprintf("oci.environment.%s", fields[0])] = fields[1]
+			}
+		}
+	}
+
+	// LXC needs the mount points of the API filesystems.
+	for _, path := range []string{"/dev", "/proc", "/sys"} {
+		target, err := ociResolvePath(rootfsDir, path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.MkdirAll(target, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Allow overriding or adding properties
+	for k, v := range req.Properties {
+		properties[k] = v
+	}
+
+	// Generate the metadata.
+	info := api.Image{}
+	info.Filename = req.Filename
+	info.Public = req.Public
+	info.Architecture = architecture
+	info.Type = instancetype.Container.String()
+	info.CreatedAt = time.Now().UTC()
+	info.Properties = properties
+
+	meta := api.ImageMetadata{
+		Architecture: architecture,
+		CreationDate: info.CreatedAt.Unix(),
+		Properties:   properties,
+	}
+
+	data, err := yaml.Marshal(&meta)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(bundleDir, "metadata.yaml"), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the unified image tarball.
+	op.UpdateMetadata(map[string]interface{}{"download_progress": "Packing image"})
+	imageFile, err := ioutil.TempFile(builddir, "lxd_build_image_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(imageFile.Name())
+	defer imageFile.Close()
+
+	compress, err := cluster.ConfigGetString(d.cluster, "images.compression_algorithm")
+	if err != nil {
+		return nil, err
+	}
+
+	sha256 := sha256.New()
+	writer := io.MultiWriter(imageFile, sha256)
+	tarReader, tarWriter := io.Pipe()
+	compressErr := make(chan error, 1)
+	go func() {
+		var err error
+		if compress != "none" {
+			err = compressFile(compress, tarReader, writer)
+		} else {
+			_, err = io.Copy(writer, tarReader)
+		}
+
+		// End the tarball creation if the compression failed.
+		tarReader.CloseWithError(err)
+		compressErr <- err
+	}()
+
+	err = ociWriteTarball(bundleDir, tarWriter)
+	tarWriter.CloseWithError(err)
+	if err != nil {
+		<-compressErr
+		return nil, err
+	}
+
+	err = <-compressErr
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := imageFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info.Size = fi.Size()
+	info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
+
+	_, _, err = d.cluster.GetImage(project, info.Fingerprint, false)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return nil, err
+		}
+
+		return &info, fmt.Errorf("The image already exists: %s", info.Fingerprint)
+	}
+
+	err = shared.FileMove(imageFile.Name(), shared.VarPath("images", info.Fingerprint))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the database entry
+	err = d.cluster.CreateImage(project, info.Fingerprint, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// ociWriteInitWrapper installs a /sbin/init script in the root filesystem which sets up the environment of the
+// OCI image and then runs its entrypoint, as LXD containers always start /sbin/init.
+func ociWriteInitWrapper(rootfsDir string, config ociRuntimeConfig) error {
+	shell, err := ociResolvePath(rootfsDir, "/bin/sh")
+	if err != nil {
+		return err
+	}
+
+	if !shared.PathExists(shell) {
+		return fmt.Errorf("The image doesn't contain /bin/sh to run the init wrapper, use the \"config\" entrypoint mode instead")
+	}
+
+	script := []string{
+		"#!/bin/sh",
+		"# Generated by LXD to run the entrypoint of the OCI image.",
+	}
+
+	for _, env := range config.Process.Env {
+		fields := strings.SplitN(env, "=", 2)
+		if len(fields) == 2 {
+			script = append(script, fmt.Sprintf("export %s=%s", fields[0], shellquote.Join(fields[1])))
+		}
+	}
+
+	if config.Process.Cwd != "" {
+		script = append(script, fmt.Sprintf("cd %s || exit 1", shellquote.Join(config.Process.Cwd)))
+	}
+
+	script = append(script, fmt.Sprintf("exec %s", shellquote.Join(config.Process.Args...)), "")
+
+	// Replace /sbin/init itself rather than whatever it may link to.
+	sbinDir, err := ociResolvePath(rootfsDir, "/sbin")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(sbinDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	initPath := filepath.Join(sbinDir, "init")
+	err = os.Remove(initPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return ioutil.WriteFile(initPath, []byte(strings.Join(script, "\n")), 0755)
+}
+
+// ociResolvePath returns the host path of the given absolute path of the root filesystem, following its symlinks
+// as if the root filesystem was the root directory so that they can't point outside of it.
+func ociResolvePath(rootfsDir string, path string) (string, error) {
+	current := "/"
+	links := 0
+	remaining := strings.Split(strings.Trim(path, "/"), "/")
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+
+		if part == "" || part == "." {
+			continue
+		}
+
+		if part == ".." {
+			current = filepath.Dir(current)
+			continue
+		}
+
+		next := filepath.Join(current, part)
+		fi, err := os.Lstat(filepath.Join(rootfsDir, next))
+		if err != nil {
+			if os.IsNotExist(err) {
+				current = next
+				continue
+			}
+
+			return "", err
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("Too many levels of symbolic links in %q", path)
+		}
+
+		target, err := os.Readlink(filepath.Join(rootfsDir, next))
+		if err != nil {
+			return "", err
+		}
+
+		if strings.HasPrefix(target, "/") {
+			current = "/"
+		}
+
+		remaining = append(strings.Split(strings.Trim(target, "/"), "/"), remaining...)
+	}
+
+	return filepath.Join(rootfsDir, current), nil
+}
+
+// ociWriteTarball writes the metadata and root filesystem of the given bundle as a unified image tarball.
+func ociWriteTarball(bundleDir string, w io.Writer) error {
+	tarWriter := instancewriter.NewInstanceTarWriter(w, nil)
+	offset := len(bundleDir) + 1
+
+	writeToTar := func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return tarWriter.WriteFile(path[offset:], path, fi, false)
+	}
+
+	for _, name := range []string{"metadata.yaml", "rootfs"} {
+		err := filepath.Walk(filepath.Join(bundleDir, name), writeToTar)
+		if err != nil {
+			tarWriter.Close()
+			return err
+		}
+	}
+
+	return tarWriter.Close()
+}
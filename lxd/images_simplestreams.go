@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// simpleStreamsFile is a file of an image as exposed through the simplestreams index.
+type simpleStreamsFile struct {
+	path   string
+	name   string
+	ftype  string
+	size   int64
+	sha256 string
+}
+
+// Image files are immutable, so their hashes are only ever computed once.
+var simpleStreamsFilesCache = map[string][]simpleStreamsFile{}
+var simpleStreamsFilesLock sync.Mutex
+
+// simpleStreamsHandlers returns the handlers serving the public images of the default project as a simplestreams
+// image server, when enabled through images.simplestreams.
+func simpleStreamsHandlers(d *Daemon) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/streams/v1/index.json":       simpleStreamsHandler(d, simpleStreamsIndex),
+		"/streams/v1/images.json":      simpleStreamsHandler(d, simpleStreamsImages),
+		"/images/{fingerprint}/{file}": simpleStreamsHandler(d, simpleStreamsImageFile),
+	}
+}
+
+// simpleStreamsHandler wraps a simplestreams handler so that it's only reachable when the index is enabled.
+func simpleStreamsHandler(d *Daemon, handler func(d *Daemon, w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enabled := false
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			enabled = config.ImagesSimpleStreams()
+			return nil
+		})
+		if err != nil {
+			response.SmartError(err).Render(w)
+			return
+		}
+
+		if !enabled || r.Method != "GET" {
+			response.NotFound(nil).Render(w)
+			return
+		}
+
+		err = handler(d, w, r)
+		if err != nil {
+			logger.Error("Failed to serve simplestreams request", log.Ctx{"url": r.URL, "err": err})
+			response.SmartError(err).Render(w)
+		}
+	}
+}
+
+func simpleStreamsIndex(d *Daemon, w http.ResponseWriter, r *http.Request) error {
+	products, err := simpleStreamsProducts(d)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range products.Products {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stream := simplestreams.Stream{
+		Format:  "index:1.0",
+		Updated: products.Updated,
+		Index: map[string]simplestreams.StreamIndex{
+			"images": {
+				DataType: "image-downloads",
+				Path:     "streams/v1/images.json",
+				Format:   "products:1.0",
+				Updated:  products.Updated,
+				Products: names,
+			},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(stream)
+}
+
+func simpleStreamsImages(d *Daemon, w http.ResponseWriter, r *http.Request) error {
+	products, err := simpleStreamsProducts(d)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(products)
+}
+
+func simpleStreamsImageFile(d *Daemon, w http.ResponseWriter, r *http.Request) error {
+	fingerprint := mux.Vars(r)["fingerprint"]
+	name := mux.Vars(r)["file"]
+
+	// Only public images of the default project are exposed, and require an exact match.
+	_, image, err := d.cluster.GetImage(project.Default, fingerprint, true)
+	if err != nil {
+		return err
+	}
+
+	if image.Fingerprint != fingerprint {
+		return response.NotFound(fmt.Errorf("Image '%s' not found", fingerprint)).Render(w)
+	}
+
+	files, err := simpleStreamsImageFiles(image)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.name != name {
+			continue
+		}
+
+		entry := response.FileResponseEntry{
+			Identifier: file.name,
+			Path:       file.path,
+			Filename:   file.name,
+		}
+
+		return response.FileResponse(r, []response.FileResponseEntry{entry}, nil, false).Render(w)
+	}
+
+	return response.NotFound(fmt.Errorf("File '%s' not found", name)).Render(w)
+}
+
+// simpleStreamsProducts generates the simplestreams products out of the public images of the default project
+// which are stored on this server. Images sharing their distribution, release, variant, architecture and type
+// are grouped as versions of a single product.
+func simpleStreamsProducts(d *Daemon) (*simplestreams.Products, error) {
+	fingerprints, err := d.cluster.GetImagesFingerprints(project.Default, true)
+	if err != nil {
+		return nil, err
+	}
+
+	products := map[string]simplestreams.Product{}
+	for _, fingerprint := range fingerprints {
+		if !shared.PathExists(shared.VarPath("images", fingerprint)) {
+			continue
+		}
+
+		_, image, err := d.cluster.GetImage(project.Default, fingerprint, true)
+		if err != nil {
+			return nil, err
+		}
+
+		files, err := simpleStreamsImageFiles(image)
+		if err != nil {
+			logger.Warn("Skipping image in simplestreams index", log.Ctx{"fingerprint": fingerprint, "err": err})
+			continue
+		}
+
+		// Group the images which are versions of the same product.
+		osName := image.Properties["os"]
+		release := image.Properties["release"]
+		name := image.Fingerprint
+		if osName != "" && release != "" {
+			variant := image.Properties["variant"]
+			if variant == "" {
+				variant = "default"
+			}
+
+			name = strings.Join([]string{osName, release, variant, image.Architecture, image.Type}, ":")
+		}
+
+		product, ok := products[name]
+		if !ok {
+			product = simplestreams.Product{
+				Architecture:    image.Architecture,
+				OperatingSystem: osName,
+				Release:         release,
+				ReleaseTitle:    release,
+				Version:         image.Properties["version"],
+				Versions:        map[string]simplestreams.ProductVersion{},
+			}
+		}
+
+		for _, alias := range image.Aliases {
+			aliases := []string{}
+			if product.Aliases != "" {
+				aliases = strings.Split(product.Aliases, ",")
+			}
+
+			if !shared.StringInSlice(alias.Name, aliases) {
+				product.Aliases = strings.Join(append(aliases, alias.Name), ",")
+			}
+		}
+
+		// Version names must start with the creation date of the image.
+		createdAt := image.CreatedAt
+		if createdAt.Unix() <= 0 {
+			createdAt = image.UploadedAt
+		}
+
+		versionName := createdAt.UTC().Format("20060102_1504")
+		_, ok = product.Versions[versionName]
+		if ok {
+			versionName = fmt.Sprintf("%s_%s", versionName, image.Fingerprint[0:12])
+		}
+
+		version := simplestreams.ProductVersion{
+			Items: map[string]simplestreams.ProductVersionItem{},
+			Label: image.Properties["label"],
+		}
+
+		for _, file := range files {
+			item := simplestreams.ProductVersionItem{
+				FileType:   file.ftype,
+				Path:       fmt.Sprintf("images/%s/%s", image.Fingerprint, file.name),
+				HashSha256: file.sha256,
+				Size:       file.size,
+			}
+
+			// The combined hash of the metadata and root filesystem is the image fingerprint.
+			if len(files) > 1 && file.ftype == "lxd.tar.xz" {
+				switch files[1].ftype {
+				case "squashfs":
+					item.LXDHashSha256SquashFs = image.Fingerprint
+				case "disk-kvm.img":
+					item.LXDHashSha256DiskKvmImg = image.Fingerprint
+				default:
+					item.LXDHashSha256RootXz = image.Fingerprint
+				}
+			}
+
+			version.Items[file.ftype] = item
+		}
+
+		product.Versions[versionName] = version
+		products[name] = product
+	}
+
+	return &simplestreams.Products{
+		ContentID: "images",
+		DataType:  "image-downloads",
+		Format:    "products:1.0",
+		Products:  products,
+		Updated:   time.Now().UTC().Format(time.RFC1123Z),
+	}, nil
+}
+
+// simpleStreamsImageFiles returns the files of the given image, the metadata coming first.
+func simpleStreamsImageFiles(image *api.Image) ([]simpleStreamsFile, error) {
+	simpleStreamsFilesLock.Lock()
+	defer simpleStreamsFilesLock.Unlock()
+
+	files, ok := simpleStreamsFilesCache[image.Fingerprint]
+	if ok {
+		return files, nil
+	}
+
+	imagePath := shared.VarPath("images", image.Fingerprint)
+	rootfsPath := imagePath + ".rootfs"
+
+	_, ext, _, err := shared.DetectCompression(imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !shared.PathExists(rootfsPath) {
+		files = []simpleStreamsFile{{path: imagePath, name: "lxd_combined" + ext, ftype: "lxd_combined.tar.gz"}}
+	} else {
+		files = []simpleStreamsFile{{path: imagePath, name: "lxd" + ext, ftype: "lxd.tar.xz"}}
+
+		rootfs := simpleStreamsFile{path: rootfsPath}
+		if image.Type == "virtual-machine" {
+			rootfs.name = "disk.qcow2"
+			rootfs.ftype = "disk-kvm.img"
+		} else {
+			_, ext, _, err = shared.DetectCompression(rootfsPath)
+			if err != nil {
+				return nil, err
+			}
+
+			if ext == ".squashfs" {
+				rootfs.name = "rootfs.squashfs"
+				rootfs.ftype = "squashfs"
+			} else {
+				rootfs.name = "rootfs" + ext
+				rootfs.ftype = "root.tar.xz"
+			}
+		}
+
+		files = append(files, rootfs)
+	}
+
+	for i := range files {
+		f, err := os.Open(files[i].path)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files[i].size = size
+		files[i].sha256 = fmt.Sprintf("%x", hash.Sum(nil))
+	}
+
+	simpleStreamsFilesCache[image.Fingerprint] = files
+
+	return files, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
@@ -71,6 +72,35 @@ func instanceCreateAsEmpty(d *Daemon, args db.InstanceArgs) (instance.Instance,
 	return inst, nil
 }
 
+// instanceImageEnsureLocal imports the image with the given fingerprint from another node if it isn't
+// available locally.
+func instanceImageEnsureLocal(d *Daemon, project string, fingerprint string) error {
+	nodeAddress, err := d.cluster.LocateImage(fingerprint)
+	if err != nil {
+		return errors.Wrapf(err, "Locate image %s in the cluster", fingerprint)
+	}
+
+	if nodeAddress == "" {
+		return nil
+	}
+
+	// The image is available from another node, let's try to import it.
+	logger.Debugf("Transferring image %s from node %s", fingerprint, nodeAddress)
+	client, err := cluster.Connect(nodeAddress, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	client = client.UseProject(project)
+
+	err = imageImportFromNode(filepath.Join(d.os.VarDir, "images"), client, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	return d.cluster.AddImageToLocalNode(project, fingerprint)
+}
+
 // instanceCreateFromImage creates an instance from a rootfs image.
 func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *operations.Operation) (instance.Instance, error) {
 	s := d.State()
@@ -97,29 +127,25 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 	}
 
 	// Check if the image is available locally or it's on another node.
-	nodeAddress, err := s.Cluster.LocateImage(hash)
+	err = instanceImageEnsureLocal(d, args.Project, hash)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Locate image %s in the cluster", hash)
+		return nil, err
 	}
-	if nodeAddress != "" {
-		// The image is available from another node, let's try to import it.
-		logger.Debugf("Transferring image %s from node %s", hash, nodeAddress)
-		client, err := cluster.Connect(nodeAddress, d.endpoints.NetworkCert(), false)
-		if err != nil {
-			return nil, err
-		}
 
-		client = client.UseProject(args.Project)
-
-		err = imageImportFromNode(filepath.Join(d.os.VarDir, "images"), client, hash)
+	// Layered images also require the images they're based on.
+	baseImage := img.BaseImage
+	for baseImage != "" {
+		err = instanceImageEnsureLocal(d, args.Project, baseImage)
 		if err != nil {
 			return nil, err
 		}
 
-		err = d.cluster.AddImageToLocalNode(args.Project, hash)
+		_, base, err := s.Cluster.GetImageFromAnyProject(baseImage)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrapf(err, "Fetch base image %s from database", baseImage)
 		}
+
+		baseImage = base.BaseImage
 	}
 
 	// Set the "image.*" keys.
@@ -129,6 +155,21 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		}
 	}
 
+	// Run the entrypoint of imported OCI images, unless overridden.
+	for k, v := range img.Properties {
+		key := k
+		if strings.HasPrefix(k, "oci.environment.") {
+			key = fmt.Sprintf("environment.%s", strings.TrimPrefix(k, "oci.environment."))
+		} else if !shared.StringInSlice(k, []string{"oci.entrypoint", "oci.cwd", "oci.uid", "oci.gid"}) {
+			continue
+		}
+
+		_, ok := args.Config[key]
+		if !ok {
+			args.Config[key] = v
+		}
+	}
+
 	// Set the BaseImage field (regardless of previous value).
 	args.BaseImage = hash
 
@@ -147,10 +188,7 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		inst.Delete()
 	}()
 
-	err = s.Cluster.UpdateImageLastUseDate(hash, time.Now().UTC())
-	if err != nil {
-		return nil, fmt.Errorf("Error updating image last use date: %s", err)
-	}
+	s.Cluster.QueueImageLastUseDate(hash, time.Now().UTC())
 
 	pool, err := storagePools.GetPoolByInstance(d.State(), inst)
 	if err != nil {
@@ -432,6 +470,36 @@ func instanceCreateAsSnapshot(s *state.State, args db.InstanceArgs, sourceInstan
 	return inst, nil
 }
 
+// instanceArchitectureEmulated returns whether the architecture of a new container is emulated through the
+// linux.binfmt_misc.emulate key of its config or of its profiles.
+func instanceArchitectureEmulated(s *state.State, args db.InstanceArgs) bool {
+	if args.Type != instancetype.Container {
+		return false
+	}
+
+	value, ok := args.Config["linux.binfmt_misc.emulate"]
+	if !ok {
+		for _, name := range args.Profiles {
+			_, profile, err := s.Cluster.GetProfile(args.Project, name)
+			if err != nil {
+				continue
+			}
+
+			profileValue, ok := profile.Config["linux.binfmt_misc.emulate"]
+			if ok {
+				value = profileValue
+			}
+		}
+	}
+
+	archs, err := util.BinfmtArchitectures(value)
+	if err != nil {
+		return false
+	}
+
+	return shared.IntInSlice(args.Architecture, archs)
+}
+
 // instanceCreateInternal creates an instance record and storage volume record in the database.
 func instanceCreateInternal(s *state.State, args db.InstanceArgs) (instance.Instance, error) {
 	// Set default values.
@@ -487,7 +555,7 @@ func instanceCreateInternal(s *state.State, args db.InstanceArgs) (instance.Inst
 		return nil, err
 	}
 
-	if !shared.IntInSlice(args.Architecture, s.OS.Architectures) {
+	if !shared.IntInSlice(args.Architecture, s.OS.Architectures) && !instanceArchitectureEmulated(s, args) {
 		return nil, fmt.Errorf("Requested architecture isn't supported by this host")
 	}
 
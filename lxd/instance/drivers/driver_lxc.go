@@ -40,6 +40,7 @@ import (
 	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/seccomp"
 	"github.com/lxc/lxd/lxd/state"
@@ -262,6 +263,7 @@ func lxcCreate(s *state.State, args db.InstanceArgs) (instance.Instance, error)
 	if !c.IsPrivileged() {
 		idmap, base, err = findIdmap(
 			s,
+			args.Project,
 			args.Name,
 			c.expandedConfig["security.idmap.isolated"],
 			c.expandedConfig["security.idmap.base"],
@@ -483,11 +485,12 @@ func idmapSize(state *state.State, isolatedStr string, size string) (int64, erro
 		if isolated {
 			idMapSize = 65536
 		} else {
-			if len(state.OS.IdmapSet.Idmap) != 2 {
-				return 0, fmt.Errorf("bad initial idmap: %v", state.OS.IdmapSet)
+			hostIdmap := state.OS.IdmapSet()
+			if len(hostIdmap.Idmap) != 2 {
+				return 0, fmt.Errorf("bad initial idmap: %v", hostIdmap)
 			}
 
-			idMapSize = state.OS.IdmapSet.Idmap[0].Maprange
+			idMapSize = hostIdmap.Idmap[0].Maprange
 		}
 	} else {
 		size, err := strconv.ParseInt(size, 10, 64)
@@ -501,9 +504,67 @@ func idmapSize(state *state.State, isolatedStr string, size string) (int64, erro
 	return idMapSize, nil
 }
 
+// idmapLock serializes the idmap allocations and the updates of the host idmap.
 var idmapLock sync.Mutex
 
-func findIdmap(state *state.State, cName string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
+// idmapRecordAllocation records the uid/gid range allocated to an isolated container in the database.
+func idmapRecordAllocation(state *state.State, projectName string, cName string, offset int64, size int64) error {
+	return state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateInstanceIdmapAllocation(projectName, cName, offset, size)
+	})
+}
+
+// idmapReleaseAllocation releases the uid/gid range allocated to a container which isn't isolated anymore.
+func idmapReleaseAllocation(state *state.State, projectName string, cName string) error {
+	return state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.DeleteInstanceIdmapAllocation(projectName, cName)
+	})
+}
+
+// idmapRestoreAllocation puts back the uid/gid range allocated to a container before a failed update, releasing
+// its allocation if it had none.
+func idmapRestoreAllocation(state *state.State, projectName string, cName string, allocation *db.IdmapAllocation) error {
+	idmapLock.Lock()
+	defer idmapLock.Unlock()
+
+	if allocation == nil {
+		return idmapReleaseAllocation(state, projectName, cName)
+	}
+
+	return idmapRecordAllocation(state, projectName, cName, allocation.HostID, allocation.Maprange)
+}
+
+// idmapGrowHostRange reloads the uid/gid range delegated to LXD and adopts it if it extends the current one, so
+// that isolated containers can use newly delegated ids without restarting LXD. Returns whether the range grew.
+// The caller must hold idmapLock.
+func idmapGrowHostRange(state *state.State) bool {
+	current := state.OS.IdmapSet()
+	next, err := idmap.DefaultIdmapSet("", "")
+	if err != nil || current == nil || len(next.Idmap) != len(current.Idmap) || next.Usable() != nil {
+		return false
+	}
+
+	grew := false
+	for i, entry := range next.Idmap {
+		old := current.Idmap[i]
+		if entry.Isuid != old.Isuid || entry.Isgid != old.Isgid || entry.Nsid != old.Nsid || entry.Hostid != old.Hostid || entry.Maprange < old.Maprange {
+			return false
+		}
+
+		if entry.Maprange > old.Maprange {
+			grew = true
+		}
+	}
+
+	if grew {
+		logger.Info("Extending the uid/gid range available to isolated containers", log.Ctx{"hostid": next.Idmap[0].Hostid, "maprange": next.Idmap[0].Maprange})
+		state.OS.SetIdmapSet(next)
+	}
+
+	return grew
+}
+
+func findIdmap(state *state.State, projectName string, cName string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
 	isolated := false
 	if shared.IsTrue(isolatedStr) {
 		isolated = true
@@ -514,9 +575,14 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return nil, 0, err
 	}
 
+	// Hold the lock while allocating, as the host idmap may grow in the meantime.
+	idmapLock.Lock()
+	defer idmapLock.Unlock()
+
 	if !isolated {
-		newIdmapset := idmap.IdmapSet{Idmap: make([]idmap.IdmapEntry, len(state.OS.IdmapSet.Idmap))}
-		copy(newIdmapset.Idmap, state.OS.IdmapSet.Idmap)
+		hostIdmap := state.OS.IdmapSet()
+		newIdmapset := idmap.IdmapSet{Idmap: make([]idmap.IdmapEntry, len(hostIdmap.Idmap))}
+		copy(newIdmapset.Idmap, hostIdmap.Idmap)
 
 		for _, ent := range rawMaps {
 			err := newIdmapset.AddSafe(ent)
@@ -525,6 +591,11 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			}
 		}
 
+		err = idmapReleaseAllocation(state, projectName, cName)
+		if err != nil {
+			return nil, 0, err
+		}
+
 		return &newIdmapset, 0, nil
 	}
 
@@ -560,100 +631,76 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			return nil, 0, err
 		}
 
+		err = idmapRecordAllocation(state, projectName, cName, offset, size)
+		if err != nil {
+			return nil, 0, err
+		}
+
 		return set, offset, nil
 	}
 
-	idmapLock.Lock()
-	defer idmapLock.Unlock()
-
-	cts, err := instance.LoadNodeAll(state, instancetype.Container)
+	var allocations []db.IdmapAllocation
+	err = state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		allocations, err = tx.GetNodeIdmapAllocations()
+		return err
+	})
 	if err != nil {
 		return nil, 0, err
 	}
 
-	offset := state.OS.IdmapSet.Idmap[0].Hostid + 65536
-
 	mapentries := idmap.ByHostid{}
-	for _, container := range cts {
-		if container.Type() != instancetype.Container {
-			continue
-		}
-
-		name := container.Name()
-
+	allocated := int64(0)
+	for _, allocation := range allocations {
 		/* Don't change our map Just Because. */
-		if name == cName {
-			continue
-		}
-
-		if container.IsPrivileged() {
-			continue
-		}
-
-		if !shared.IsTrue(container.ExpandedConfig()["security.idmap.isolated"]) {
+		if allocation.Project == projectName && allocation.Instance == cName {
 			continue
 		}
 
-		cBase := int64(0)
-		if container.ExpandedConfig()["volatile.idmap.base"] != "" {
-			cBase, err = strconv.ParseInt(container.ExpandedConfig()["volatile.idmap.base"], 10, 64)
-			if err != nil {
-				return nil, 0, err
-			}
-		}
-
-		cSize, err := idmapSize(state, container.ExpandedConfig()["security.idmap.isolated"], container.ExpandedConfig()["security.idmap.size"])
-		if err != nil {
-			return nil, 0, err
-		}
-
-		mapentries = append(mapentries, &idmap.IdmapEntry{Hostid: int64(cBase), Maprange: cSize})
+		mapentries = append(mapentries, &idmap.IdmapEntry{Hostid: allocation.HostID, Maprange: allocation.Maprange})
+		allocated += allocation.Maprange
 	}
 
 	sort.Sort(mapentries)
 
-	for i := range mapentries {
-		if i == 0 {
-			if mapentries[0].Hostid < offset+size {
-				offset = mapentries[0].Hostid + mapentries[0].Maprange
-				continue
+	// Look for the first gap between the allocated ranges big enough for the container. The first 65536 ids
+	// of the host range are left to the containers which aren't isolated.
+	findOffset := func() (int64, bool) {
+		hostMap := state.OS.IdmapSet().Idmap[0]
+		offset := hostMap.Hostid + 65536
+		for _, entry := range mapentries {
+			if offset+size <= entry.Hostid {
+				break
 			}
 
-			set, err := mkIdmap(offset, size)
-			if err != nil && err == idmap.ErrHostIdIsSubId {
-				return nil, 0, err
+			if entry.Hostid+entry.Maprange > offset {
+				offset = entry.Hostid + entry.Maprange
 			}
-
-			return set, offset, nil
 		}
 
-		if mapentries[i-1].Hostid+mapentries[i-1].Maprange > offset {
-			offset = mapentries[i-1].Hostid + mapentries[i-1].Maprange
-			continue
-		}
+		return offset, offset+size <= hostMap.Hostid+hostMap.Maprange
+	}
 
-		offset = mapentries[i-1].Hostid + mapentries[i-1].Maprange
-		if offset+size < mapentries[i].Hostid {
-			set, err := mkIdmap(offset, size)
-			if err != nil && err == idmap.ErrHostIdIsSubId {
-				return nil, 0, err
-			}
+	offset, found := findOffset()
+	if !found && idmapGrowHostRange(state) {
+		offset, found = findOffset()
+	}
 
-			return set, offset, nil
-		}
-		offset = mapentries[i].Hostid + mapentries[i].Maprange
+	if !found {
+		available := state.OS.IdmapSet().Idmap[0].Maprange - 65536
+		return nil, 0, fmt.Errorf("Not enough uid/gid available for the container: %d ids are needed but %d of the %d ids available to isolated containers are allocated to %d other containers", size, allocated, available, len(mapentries))
 	}
 
-	if offset+size < state.OS.IdmapSet.Idmap[0].Hostid+state.OS.IdmapSet.Idmap[0].Maprange {
-		set, err := mkIdmap(offset, size)
-		if err != nil && err == idmap.ErrHostIdIsSubId {
-			return nil, 0, err
-		}
+	set, err := mkIdmap(offset, size)
+	if err != nil && err == idmap.ErrHostIdIsSubId {
+		return nil, 0, err
+	}
 
-		return set, offset, nil
+	err = idmapRecordAllocation(state, projectName, cName, offset, size)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return nil, 0, fmt.Errorf("Not enough uid/gid available for the container")
+	return set, offset, nil
 }
 
 func (c *lxc) init() error {
@@ -1031,6 +1078,26 @@ func (c *lxc) initLXC(config bool) error {
 		}
 	}
 
+	// Setup the entrypoint of imported OCI images
+	if c.expandedConfig["oci.entrypoint"] != "" {
+		for _, key := range []string{"entrypoint", "cwd", "uid", "gid"} {
+			value := c.expandedConfig[fmt.Sprintf("oci.%s", key)]
+			if value == "" {
+				continue
+			}
+
+			lxcKey := fmt.Sprintf("lxc.init.%s", key)
+			if key == "entrypoint" {
+				lxcKey = "lxc.init.cmd"
+			}
+
+			err = lxcSetConfigItem(cc, lxcKey, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// Setup environment
 	for k, v := range c.expandedConfig {
 		if strings.HasPrefix(k, "environment.") {
@@ -1681,13 +1748,18 @@ func (c *lxc) deviceHandleMounts(mounts []deviceConfig.MountEntryItem) error {
 				}
 			}
 
-			shiftfs := false
+			// Prefer idmapped mounts over shiftfs for dynamic owner shifting, if the filesystem supports them.
+			idmapType := idmap.IdmapStorageNone
 			if mount.OwnerShift == deviceConfig.MountOwnerShiftDynamic {
-				shiftfs = true
+				if c.state.OS.IdmappedMounts && !c.IsPrivileged() && idmap.CanIdmapMount(mount.DevPath) {
+					idmapType = idmap.IdmapStorageIdmapped
+				} else {
+					idmapType = idmap.IdmapStorageShiftfs
+				}
 			}
 
 			// Mount it into the container.
-			err := c.insertMount(mount.DevPath, mount.TargetPath, mount.FSType, flags, shiftfs)
+			err := c.insertMount(mount.DevPath, mount.TargetPath, mount.FSType, flags, idmapType)
 			if err != nil {
 				return fmt.Errorf("Failed to add mount for device inside container: %s", err)
 			}
@@ -1897,6 +1969,170 @@ func (c *lxc) expandDevices(profiles []api.Profile) error {
 }
 
 // Start functions
+var remapLocksMu sync.Mutex
+var remapLocks = map[string]*sync.Mutex{}
+
+// remapLock returns the lock serializing the filesystem remaps of the given container.
+func remapLock(projectName string, cName string) *sync.Mutex {
+	remapLocksMu.Lock()
+	defer remapLocksMu.Unlock()
+
+	key := project.Instance(projectName, cName)
+	lock, ok := remapLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		remapLocks[key] = lock
+	}
+
+	return lock
+}
+
+// remapRootfs shifts the ownership of the files of the mounted container filesystem from the idmap recorded on
+// disk to nextIdmap, reporting the number of files processed as progress of the container operation.
+func (c *lxc) remapRootfs(nextIdmap *idmap.IdmapSet) error {
+	lock := remapLock(c.Project(), c.Name())
+	lock.Lock()
+	defer lock.Unlock()
+
+	// A background remap may have completed while waiting for the lock, so use the idmap currently recorded
+	// in the database rather than the one this container was loaded with.
+	inst, err := instance.LoadByProjectAndName(c.state, c.Project(), c.Name())
+	if err != nil {
+		return err
+	}
+
+	diskIdmap, err := inst.(instance.Container).DiskIdmap()
+	if err != nil {
+		return errors.Wrap(err, "Set last ID map")
+	}
+
+	if nextIdmap.Equals(diskIdmap) {
+		jsonDiskIdmap, ok := inst.LocalConfig()["volatile.last_state.idmap"]
+		if ok {
+			c.localConfig["volatile.last_state.idmap"] = jsonDiskIdmap
+		}
+
+		return nil
+	}
+
+	storageType, err := c.getStorageType()
+	if err != nil {
+		return errors.Wrap(err, "Storage type")
+	}
+
+	files := 0
+	withProgress := func(skipper func(dir string, absPath string, fi os.FileInfo) bool) func(dir string, absPath string, fi os.FileInfo) bool {
+		return func(dir string, absPath string, fi os.FileInfo) bool {
+			files++
+			if files%1000 == 0 {
+				c.updateProgress(fmt.Sprintf("Remapping container filesystem: %d files", files))
+			}
+
+			return skipper != nil && skipper(dir, absPath, fi)
+		}
+	}
+
+	if diskIdmap != nil {
+		if storageType == "zfs" {
+			err = diskIdmap.UnshiftRootfs(c.RootfsPath(), withProgress(storageDrivers.ShiftZFSSkipper))
+		} else if storageType == "btrfs" {
+			err = storageDrivers.UnshiftBtrfsRootfs(c.RootfsPath(), diskIdmap)
+		} else {
+			err = diskIdmap.UnshiftRootfs(c.RootfsPath(), withProgress(nil))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if nextIdmap != nil && !c.state.OS.Shiftfs {
+		if storageType == "zfs" {
+			err = nextIdmap.ShiftRootfs(c.RootfsPath(), withProgress(storageDrivers.ShiftZFSSkipper))
+		} else if storageType == "btrfs" {
+			err = storageDrivers.ShiftBtrfsRootfs(c.RootfsPath(), nextIdmap)
+		} else {
+			err = nextIdmap.ShiftRootfs(c.RootfsPath(), withProgress(nil))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	jsonDiskIdmap := "[]"
+	if nextIdmap != nil && !c.state.OS.Shiftfs {
+		idmapBytes, err := json.Marshal(nextIdmap.Idmap)
+		if err != nil {
+			return err
+		}
+		jsonDiskIdmap = string(idmapBytes)
+	}
+
+	err = c.VolatileSet(map[string]string{"volatile.last_state.idmap": jsonDiskIdmap})
+	if err != nil {
+		return errors.Wrapf(err, "Set volatile.last_state.idmap config key on container %q (id %d)", c.name, c.id)
+	}
+
+	return nil
+}
+
+// remapRootfsBackground remaps the filesystem of a stopped container to its next idmap in a background
+// operation, so that the next start of the container doesn't have to wait for it.
+func (c *lxc) remapRootfsBackground() error {
+	resources := map[string][]string{}
+	resources["instances"] = []string{c.Name()}
+	resources["containers"] = resources["instances"]
+
+	run := func(op *operations.Operation) error {
+		inst, err := instance.LoadByProjectAndName(c.state, c.Project(), c.Name())
+		if err != nil {
+			return err
+		}
+
+		ct := inst.(*lxc)
+		ct.SetOperation(op)
+
+		// Shifting protected containers is left to the start of the container, which reports the error.
+		if shared.IsTrue(ct.expandedConfig["security.protection.shift"]) {
+			return nil
+		}
+
+		nextIdmap, err := ct.NextIdmap()
+		if err != nil {
+			return errors.Wrap(err, "Set ID map")
+		}
+
+		diskIdmap, err := ct.DiskIdmap()
+		if err != nil {
+			return errors.Wrap(err, "Set last ID map")
+		}
+
+		if nextIdmap.Equals(diskIdmap) || (diskIdmap == nil && ct.state.OS.Shiftfs) {
+			return nil
+		}
+
+		ourStart, err := ct.mount()
+		if err != nil {
+			return errors.Wrap(err, "Storage start")
+		}
+
+		if ourStart {
+			defer ct.unmount()
+		}
+
+		ct.updateProgress("Remapping container filesystem")
+		defer ct.updateProgress("")
+
+		return ct.remapRootfs(nextIdmap)
+	}
+
+	op, err := operations.OperationCreate(c.state, c.Project(), operations.OperationClassTask, db.OperationInstanceRemap, resources, nil, run, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return op.Start()
+}
+
 func (c *lxc) startCommon() (string, []func() error, error) {
 	var ourStart bool
 
@@ -1916,9 +2152,9 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 		return "", postStartHooks, fmt.Errorf("The container is already running")
 	}
 
-	// Load any required kernel modules
+	// Load any required kernel modules, unless they're loaded on demand through syscall interception
 	kernelModules := c.expandedConfig["linux.kernel_modules"]
-	if kernelModules != "" {
+	if kernelModules != "" && c.expandedConfig["linux.kernel_modules.load"] != "ondemand" {
 		for _, module := range strings.Split(kernelModules, ",") {
 			module = strings.TrimPrefix(module, " ")
 			err := util.LoadModule(module)
@@ -1928,6 +2164,49 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 		}
 	}
 
+	// Register binfmt_misc handlers inside the container for the emulated architectures
+	emulatedArchs, err := util.BinfmtArchitectures(c.expandedConfig["linux.binfmt_misc.emulate"])
+	if err != nil {
+		return "", postStartHooks, err
+	}
+
+	if !shared.IntInSlice(c.architecture, c.state.OS.Architectures) && !shared.IntInSlice(c.architecture, emulatedArchs) {
+		archName, _ := osarch.ArchitectureName(c.architecture)
+		return "", postStartHooks, fmt.Errorf("Architecture %q isn't supported by this host, it can be emulated through linux.binfmt_misc.emulate", archName)
+	}
+
+	if len(emulatedArchs) > 0 {
+		// Privileged containers share the binfmt_misc instance of the host.
+		if c.IsPrivileged() {
+			return "", postStartHooks, fmt.Errorf("linux.binfmt_misc.emulate is only supported for unprivileged containers")
+		}
+
+		err = util.LoadModule("binfmt_misc")
+		if err != nil {
+			return "", postStartHooks, fmt.Errorf("Failed to load kernel module 'binfmt_misc': %s", err)
+		}
+
+		handlers := []string{}
+		for _, arch := range emulatedArchs {
+			interpreter, err := util.BinfmtInterpreter(arch)
+			if err != nil {
+				return "", postStartHooks, err
+			}
+
+			archName, err := osarch.ArchitectureName(arch)
+			if err != nil {
+				return "", postStartHooks, err
+			}
+
+			handlers = append(handlers, fmt.Sprintf("%s=%s", archName, interpreter))
+		}
+
+		err = lxcSetConfigItem(c.c, "lxc.hook.mount", fmt.Sprintf("%s forkbinfmt %s", c.state.OS.ExecPath, strings.Join(handlers, " ")))
+		if err != nil {
+			return "", postStartHooks, errors.Wrap(err, "Failed to setup binfmt_misc handlers")
+		}
+	}
+
 	/* Deal with idmap changes */
 	nextIdmap, err := c.NextIdmap()
 	if err != nil {
@@ -1952,55 +2231,12 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 			return "", postStartHooks, errors.Wrap(err, "Storage start")
 		}
 
-		storageType, err := c.getStorageType()
+		err = c.remapRootfs(nextIdmap)
 		if err != nil {
-			return "", postStartHooks, errors.Wrap(err, "Storage type")
-		}
-
-		if diskIdmap != nil {
-			if storageType == "zfs" {
-				err = diskIdmap.UnshiftRootfs(c.RootfsPath(), storageDrivers.ShiftZFSSkipper)
-			} else if storageType == "btrfs" {
-				err = storageDrivers.UnshiftBtrfsRootfs(c.RootfsPath(), diskIdmap)
-			} else {
-				err = diskIdmap.UnshiftRootfs(c.RootfsPath(), nil)
-			}
-			if err != nil {
-				if ourStart {
-					c.unmount()
-				}
-				return "", postStartHooks, err
-			}
-		}
-
-		if nextIdmap != nil && !c.state.OS.Shiftfs {
-			if storageType == "zfs" {
-				err = nextIdmap.ShiftRootfs(c.RootfsPath(), storageDrivers.ShiftZFSSkipper)
-			} else if storageType == "btrfs" {
-				err = storageDrivers.ShiftBtrfsRootfs(c.RootfsPath(), nextIdmap)
-			} else {
-				err = nextIdmap.ShiftRootfs(c.RootfsPath(), nil)
-			}
-			if err != nil {
-				if ourStart {
-					c.unmount()
-				}
-				return "", postStartHooks, err
-			}
-		}
-
-		jsonDiskIdmap := "[]"
-		if nextIdmap != nil && !c.state.OS.Shiftfs {
-			idmapBytes, err := json.Marshal(nextIdmap.Idmap)
-			if err != nil {
-				return "", postStartHooks, err
+			if ourStart {
+				c.unmount()
 			}
-			jsonDiskIdmap = string(idmapBytes)
-		}
-
-		err = c.VolatileSet(map[string]string{"volatile.last_state.idmap": jsonDiskIdmap})
-		if err != nil {
-			return "", postStartHooks, errors.Wrapf(err, "Set volatile.last_state.idmap config key on container %q (id %d)", c.name, c.id)
+			return "", postStartHooks, err
 		}
 
 		c.updateProgress("")
@@ -2050,6 +2286,7 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 
 	// Create the devices
 	nicID := -1
+	nvidiaDevices := []string{}
 
 	// Setup devices in sorted order, this ensures that device mounts are added in path order.
 	for _, d := range c.expandedDevices.Sorted() {
@@ -2142,9 +2379,13 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 					return "", postStartHooks, errors.Wrapf(fmt.Errorf("liblxc 3.0 is required for mount propagation configuration"), "Failed to setup device mount '%s'", dev.Name)
 				}
 
-				if mount.OwnerShift == deviceConfig.MountOwnerShiftDynamic && !c.IsPrivileged() {
+				if mount.OwnerShift == deviceConfig.MountOwnerShiftDynamic && !c.IsPrivileged() && c.state.OS.IdmappedMounts && idmap.CanIdmapMount(mount.DevPath) {
+					// Have LXC idmap the mount with the container's idmap, shiftfs is used for the
+					// filesystems which don't support idmapped mounts.
+					mount.Opts = append(mount.Opts, "idmap=container")
+				} else if mount.OwnerShift == deviceConfig.MountOwnerShiftDynamic && !c.IsPrivileged() {
 					if !c.state.OS.Shiftfs {
-						return "", postStartHooks, errors.Wrapf(fmt.Errorf("shiftfs is required but isn't supported on system"), "Failed to setup device mount '%s'", dev.Name)
+						return "", postStartHooks, errors.Wrapf(fmt.Errorf("idmapped mounts or shiftfs are required but aren't supported on system"), "Failed to setup device mount '%s'", dev.Name)
 					}
 
 					err = lxcSetConfigItem(c.c, "lxc.hook.pre-start", fmt.Sprintf("/bin/mount -t shiftfs -o mark,passthrough=3 %s %s", mount.DevPath, mount.DevPath))
@@ -2189,12 +2430,27 @@ func (c *lxc) startCommon() (string, []func() error, error) {
 			}
 		}
 
+		// Collect the GPUs passed through the NVIDIA runtime.
+		for _, gpuItem := range runConf.GPUDevice {
+			if gpuItem.Key == "NVIDIA_VISIBLE_DEVICES" {
+				nvidiaDevices = append(nvidiaDevices, gpuItem.Value)
+			}
+		}
+
 		// Add any post start hooks.
 		if len(runConf.PostHooks) > 0 {
 			postStartHooks = append(postStartHooks, runConf.PostHooks...)
 		}
 	}
 
+	// Expose the GPUs passed through the NVIDIA runtime, overriding the default of none set by initLXC.
+	if len(nvidiaDevices) > 0 {
+		err = lxcSetConfigItem(c.c, "lxc.environment", fmt.Sprintf("NVIDIA_VISIBLE_DEVICES=%s", strings.Join(nvidiaDevices, ",")))
+		if err != nil {
+			return "", postStartHooks, errors.Wrap(err, "Failed to setup NVIDIA devices")
+		}
+	}
+
 	// Rotate the log file
 	logfile := c.LogFilePath()
 	if shared.PathExists(logfile) {
@@ -2529,26 +2785,15 @@ func (c *lxc) onStart(_ map[string]string) error {
 		}(c)
 	}
 
-	// Database updates
-	err = c.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
-		// Record current state
-		err = tx.UpdateInstancePowerState(c.id, "RUNNING")
-		if err != nil {
-			return errors.Wrap(err, "Error updating container state")
-		}
-
-		// Update time container last started time
-		err = tx.UpdateInstanceLastUsedDate(c.id, time.Now().UTC())
-		if err != nil {
-			return errors.Wrap(err, "Error updating last used")
-		}
-
-		return nil
-	})
+	// Record current state, concurrent starts are committed together.
+	err = c.state.Cluster.UpdateInstancePowerState(c.id, "RUNNING")
 	if err != nil {
-		return err
+		return errors.Wrap(err, "Error updating container state")
 	}
 
+	// Update time container last started time, it's written in the background.
+	c.state.Cluster.QueueInstanceLastUsedDate(c.id, time.Now().UTC())
+
 	return nil
 }
 
@@ -3080,7 +3325,7 @@ func (c *lxc) Render(options ...func(response interface{}) error) (interface{},
 	}
 
 	// Prepare the ETag
-	etag := []interface{}{c.architecture, c.localConfig, c.localDevices, c.ephemeral, c.profiles}
+	etag := []interface{}{c.architecture, c.localConfig, c.localDevices, c.ephemeral, c.profiles, c.description}
 
 	// FIXME: Render shouldn't directly access the go-lxc struct
 	cState, err := c.getLxcState()
@@ -3197,6 +3442,8 @@ func (c *lxc) RenderState() (*api.InstanceState, error) {
 		status.Network = c.networkState()
 		status.Pid = int64(pid)
 		status.Processes = c.processesState()
+		status.Proxies = c.proxiesState()
+		status.GPU = c.gpuState()
 	}
 	status.Disk = c.diskState()
 
@@ -3892,6 +4139,9 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 
 	oldExpiryDate := c.expiryDate
 
+	// Set when the idmap allocation of the container is changed, to put it back on failure.
+	var undoIdmapAllocation func()
+
 	// Define a function which reverts everything.  Defer this function
 	// so that it doesn't need to be explicitly called in every failing
 	// return path.  Track whether or not we want to undo the changes
@@ -3908,6 +4158,9 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 			c.localDevices = oldLocalDevices
 			c.profiles = oldProfiles
 			c.expiryDate = oldExpiryDate
+			if undoIdmapAllocation != nil {
+				undoIdmapAllocation()
+			}
 			if c.c != nil {
 				c.c.Release()
 				c.c = nil
@@ -4016,13 +4269,33 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 		}
 	}
 
+	idmapChanged := false
 	if shared.StringInSlice("security.idmap.isolated", changedConfig) || shared.StringInSlice("security.idmap.base", changedConfig) || shared.StringInSlice("security.idmap.size", changedConfig) || shared.StringInSlice("raw.idmap", changedConfig) || shared.StringInSlice("security.privileged", changedConfig) {
+		idmapChanged = true
+
+		var oldAllocation *db.IdmapAllocation
+		err = c.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			oldAllocation, err = tx.GetInstanceIdmapAllocation(c.Project(), c.Name())
+			return err
+		})
+		if err != nil {
+			return errors.Wrap(err, "Failed to get ID map allocation")
+		}
+
+		undoIdmapAllocation = func() {
+			err := idmapRestoreAllocation(c.state, c.Project(), c.Name(), oldAllocation)
+			if err != nil {
+				logger.Error("Failed to restore ID map allocation", log.Ctx{"project": c.Project(), "instance": c.Name(), "err": err})
+			}
+		}
+
 		var idmap *idmap.IdmapSet
 		base := int64(0)
 		if !c.IsPrivileged() {
 			// update the idmap
 			idmap, base, err = findIdmap(
 				c.state,
+				c.Project(),
 				c.Name(),
 				c.expandedConfig["security.idmap.isolated"],
 				c.expandedConfig["security.idmap.base"],
@@ -4032,6 +4305,11 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 			if err != nil {
 				return errors.Wrap(err, "Failed to get ID map")
 			}
+		} else {
+			err = idmapReleaseAllocation(c.state, c.Project(), c.Name())
+			if err != nil {
+				return err
+			}
 		}
 
 		var jsonIdmap string
@@ -4088,7 +4366,7 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 				}
 			} else if key == "security.devlxd" {
 				if value == "" || shared.IsTrue(value) {
-					err = c.insertMount(shared.VarPath("devlxd"), "/dev/lxd", "none", unix.MS_BIND, false)
+					err = c.insertMount(shared.VarPath("devlxd"), "/dev/lxd", "none", unix.MS_BIND, idmap.IdmapStorageNone)
 					if err != nil {
 						return err
 					}
@@ -4103,7 +4381,7 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 						return err
 					}
 				}
-			} else if key == "linux.kernel_modules" && value != "" {
+			} else if key == "linux.kernel_modules" && value != "" && c.expandedConfig["linux.kernel_modules.load"] != "ondemand" {
 				for _, module := range strings.Split(value, ",") {
 					module = strings.TrimPrefix(module, " ")
 					err := util.LoadModule(module)
@@ -4476,6 +4754,14 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 	// Success, update the closure to mark that the changes should be kept.
 	undoChanges = false
 
+	// Remap the filesystem of a stopped container to its new idmap ahead of its next start.
+	if idmapChanged && !c.IsSnapshot() && !c.IsRunning() {
+		err = c.remapRootfsBackground()
+		if err != nil {
+			logger.Warn("Failed to start filesystem remap", log.Ctx{"project": c.Project(), "instance": c.Name(), "err": err})
+		}
+	}
+
 	var endpoint string
 
 	if c.IsSnapshot() {
@@ -5822,6 +6108,30 @@ func (c *lxc) memoryState() api.InstanceStateMemory {
 	return memory
 }
 
+func (c *lxc) proxiesState() map[string]api.InstanceStateProxy {
+	proxies := map[string]api.InstanceStateProxy{}
+
+	for _, dev := range c.expandedDevices.Sorted() {
+		if dev.Config["type"] != "proxy" {
+			continue
+		}
+
+		stats, err := device.ProxyStats(c, dev.Name)
+		if err != nil {
+			logger.Error("Error getting proxy stats", log.Ctx{"project": c.Project(), "instance": c.Name(), "device": dev.Name, "err": err})
+			continue
+		}
+
+		if stats == nil {
+			continue
+		}
+
+		proxies[dev.Name] = *stats
+	}
+
+	return proxies
+}
+
 func (c *lxc) networkState() map[string]api.InstanceStateNetwork {
 	result := map[string]api.InstanceStateNetwork{}
 
@@ -5915,6 +6225,16 @@ func (c *lxc) processesState() int64 {
 		return valueInt
 	}
 
+	return int64(len(c.processes()))
+}
+
+// processes returns the PIDs of the processes of the container.
+func (c *lxc) processes() []int64 {
+	pid := c.InitPID()
+	if pid == -1 {
+		return []int64{}
+	}
+
 	pids := []int64{int64(pid)}
 
 	// Go through the pid list, adding new pids at the end so we go through them all
@@ -5935,7 +6255,31 @@ func (c *lxc) processesState() int64 {
 		}
 	}
 
-	return int64(len(pids))
+	return pids
+}
+
+// gpuState returns the usage of the GPU cards by the processes of the container, keyed by the PCI address of
+// the cards. Containers without GPU devices can't use any GPU, so they aren't inspected.
+func (c *lxc) gpuState() map[string]api.InstanceStateGPU {
+	hasGPU := false
+	for _, dev := range c.expandedDevices {
+		if dev["type"] == "gpu" {
+			hasGPU = true
+			break
+		}
+	}
+
+	if !hasGPU {
+		return map[string]api.InstanceStateGPU{}
+	}
+
+	gpus, err := resources.GetGPUUsage(c.processes())
+	if err != nil {
+		logger.Error("Error getting GPU usage", log.Ctx{"project": c.Project(), "instance": c.Name(), "err": err})
+		return map[string]api.InstanceStateGPU{}
+	}
+
+	return gpus
 }
 
 // getStoragePool returns the current storage pool handle. To avoid a DB lookup each time this
@@ -6023,7 +6367,7 @@ func (c *lxc) unmount() (bool, error) {
 }
 
 // Mount handling
-func (c *lxc) insertMountLXD(source, target, fstype string, flags int, mntnsPID int, shiftfs bool) error {
+func (c *lxc) insertMountLXD(source, target, fstype string, flags int, mntnsPID int, idmapType idmap.IdmapStorageType) error {
 	pid := mntnsPID
 	if pid <= 0 {
 		// Get the init PID
@@ -6060,7 +6404,16 @@ func (c *lxc) insertMountLXD(source, target, fstype string, flags int, mntnsPID
 	}
 	defer unix.Unmount(tmpMount, unix.MNT_DETACH)
 
+	// Setup host side idmapped mount as needed
+	if idmapType == idmap.IdmapStorageIdmapped {
+		err = idmap.IdmapMount(tmpMount, fmt.Sprintf("/proc/%d/ns/user", pid))
+		if err != nil {
+			return fmt.Errorf("Failed to setup idmapped mount: %s", err)
+		}
+	}
+
 	// Setup host side shiftfs as needed
+	shiftfs := idmapType == idmap.IdmapStorageShiftfs
 	if shiftfs {
 		err = unix.Mount(tmpMount, tmpMount, "shiftfs", 0, "mark,passthrough=3")
 		if err != nil {
@@ -6126,12 +6479,12 @@ func (c *lxc) insertMountLXC(source, target, fstype string, flags int) error {
 	return nil
 }
 
-func (c *lxc) insertMount(source, target, fstype string, flags int, shiftfs bool) error {
-	if c.state.OS.LXCFeatures["mount_injection_file"] && !shiftfs {
+func (c *lxc) insertMount(source, target, fstype string, flags int, idmapType idmap.IdmapStorageType) error {
+	if c.state.OS.LXCFeatures["mount_injection_file"] && idmapType == idmap.IdmapStorageNone {
 		return c.insertMountLXC(source, target, fstype, flags)
 	}
 
-	return c.insertMountLXD(source, target, fstype, flags, -1, shiftfs)
+	return c.insertMountLXD(source, target, fstype, flags, -1, idmapType)
 }
 
 func (c *lxc) removeMount(mount string) error {
@@ -6239,7 +6592,7 @@ func (c *lxc) InsertSeccompUnixDevice(prefix string, m deviceConfig.Device, pid
 
 	// Bind-mount it into the container
 	defer os.Remove(devPath)
-	return c.insertMountLXD(devPath, tgtPath, "none", unix.MS_BIND, pid, false)
+	return c.insertMountLXD(devPath, tgtPath, "none", unix.MS_BIND, pid, idmap.IdmapStorageNone)
 }
 
 func (c *lxc) removeUnixDevices() error {
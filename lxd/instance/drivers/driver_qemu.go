@@ -932,31 +932,17 @@ func (vm *qemu) Start(stateful bool) error {
 		return err
 	}
 
-	// Database updates
-	err = vm.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
-		// Record current state
-		err = tx.UpdateInstancePowerState(vm.id, "RUNNING")
-		if err != nil {
-			err = errors.Wrap(err, "Error updating instance state")
-			op.Done(err)
-			return err
-		}
-
-		// Update time instance last started time
-		err = tx.UpdateInstanceLastUsedDate(vm.id, time.Now().UTC())
-		if err != nil {
-			err = errors.Wrap(err, "Error updating instance last used")
-			op.Done(err)
-			return err
-		}
-
-		return nil
-	})
+	// Record current state, concurrent starts are committed together.
+	err = vm.state.Cluster.UpdateInstancePowerState(vm.id, "RUNNING")
 	if err != nil {
+		err = errors.Wrap(err, "Error updating instance state")
 		op.Done(err)
 		return err
 	}
 
+	// Update time instance last started time, it's written in the background.
+	vm.state.Cluster.QueueInstanceLastUsedDate(vm.id, time.Now().UTC())
+
 	revert.Success()
 	vm.state.Events.SendLifecycle(vm.project, "virtual-machine-started", fmt.Sprintf("/1.0/virtual-machines/%s", vm.name), nil)
 	return nil
@@ -1184,6 +1170,10 @@ func (vm *qemu) spicePath() string {
 	return filepath.Join(vm.LogPath(), "qemu.spice")
 }
 
+func (vm *qemu) vncPath() string {
+	return filepath.Join(vm.LogPath(), "qemu.vnc")
+}
+
 // generateConfigShare generates the config share directory that will be exported to the VM via
 // a 9P share. Due to the unknown size of templates inside the images this directory is created
 // inside the VM's config volume so that it can be restricted by quota.
@@ -1586,6 +1576,7 @@ func (vm *qemu) generateQemuConfigFile(busName string, devConfs []*deviceConfig.
 	err := qemuBase.Execute(sb, map[string]interface{}{
 		"architecture": vm.architectureName,
 		"spicePath":    vm.spicePath(),
+		"vncPath":      vm.vncPath(),
 	})
 	if err != nil {
 		return "", err
@@ -1935,6 +1926,7 @@ func (vm *qemu) addRootDriveConfig(sb *strings.Builder, bootIndexes map[string]i
 	driveConf := deviceConfig.MountEntryItem{
 		DevName: rootDriveConf.DevName,
 		DevPath: rootDrivePath,
+		Opts:    rootDriveConf.Opts,
 	}
 
 	// If the storage pool is on ZFS and backed by a loop file and we can't use DirectIO, then resort to
@@ -2038,13 +2030,14 @@ func (vm *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int,
 		"bootIndex": bootIndexes[driveConf.DevName],
 		"cacheMode": cacheMode,
 		"aioMode":   aioMode,
+		"discard":   !shared.StringInSlice(deviceConfig.MountOptNoDiscard, driveConf.Opts),
 		"shared":    driveConf.TargetPath != "/" && !strings.HasPrefix(driveConf.DevPath, "rbd:"),
 	})
 }
 
 // addNetDevConfig adds the qemu config required for adding a network device.
 func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes map[string]int, nicConfig []deviceConfig.RunConfigItem, fdFiles *[]string) error {
-	var devName, nicName, devHwaddr, pciSlotName string
+	var devName, nicName, devHwaddr, pciSlotName, failoverPCISlotName, queues string
 	for _, nicItem := range nicConfig {
 		if nicItem.Key == "devName" {
 			devName = nicItem.Value
@@ -2054,6 +2047,10 @@ func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes m
 			devHwaddr = nicItem.Value
 		} else if nicItem.Key == "pciSlotName" {
 			pciSlotName = nicItem.Value
+		} else if nicItem.Key == "queues" {
+			queues = nicItem.Value
+		} else if nicItem.Key == "failoverPCISlotName" {
+			failoverPCISlotName = nicItem.Value
 		}
 	}
 
@@ -2084,7 +2081,19 @@ func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes m
 	} else if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/tun_flags", nicName)) {
 		// Detect TAP (via TUN driver) device.
 		tplFields["ifName"] = nicName
+		tplFields["failover"] = failoverPCISlotName != ""
 		tpl = qemuNetDevTapTun
+
+		// Multi-queue virtio-net needs an MSI-X vector per TX and RX queue, plus config and control.
+		if queues != "" && queues != "1" {
+			queueCount, err := strconv.Atoi(queues)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid number of queues %q", queues)
+			}
+
+			tplFields["queues"] = queueCount
+			tplFields["vectors"] = 2*queueCount + 2
+		}
 	} else if pciSlotName != "" {
 		// Detect physical passthrough device.
 		tplFields["pciSlotName"] = pciSlotName
@@ -2095,22 +2104,61 @@ func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes m
 	tplFields["devBus"] = devBus
 	tplFields["devAddr"] = devAddr
 	tplFields["multifunction"] = multi
-	if tpl != nil {
-		return tpl.Execute(sb, tplFields)
+	if tpl == nil {
+		return fmt.Errorf("Unrecognised device type")
+	}
+
+	err := tpl.Execute(sb, tplFields)
+	if err != nil {
+		return err
+	}
+
+	// Add the VF paired with the virtio NIC of a failover pair, it must be on its own hotpluggable port.
+	if failoverPCISlotName != "" {
+		if bus.name != "pcie" {
+			return fmt.Errorf("NIC failover requires a PCIe bus")
+		}
+
+		devBus, devAddr, multi := bus.allocate(busFunctionGroupNone)
+		return qemuNetDevFailoverPrimary.Execute(sb, map[string]interface{}{
+			"devName":       devName,
+			"devBus":        devBus,
+			"devAddr":       devAddr,
+			"multifunction": multi,
+			"pciSlotName":   failoverPCISlotName,
+		})
 	}
 
-	return fmt.Errorf("Unrecognised device type")
+	return nil
 }
 
 // addGPUDevConfig adds the qemu config required for adding a GPU device.
 func (vm *qemu) addGPUDevConfig(sb *strings.Builder, bus *qemuBus, gpuConfig []deviceConfig.RunConfigItem) error {
-	var devName, pciSlotName string
+	var devName, pciSlotName, vgpu string
 	for _, gpuItem := range gpuConfig {
 		if gpuItem.Key == "devName" {
 			devName = gpuItem.Value
 		} else if gpuItem.Key == "pciSlotName" {
 			pciSlotName = gpuItem.Value
+		} else if gpuItem.Key == "vgpu" {
+			vgpu = gpuItem.Value
+		}
+	}
+
+	// Add mediated device to qemu config.
+	if vgpu != "" {
+		devBus, devAddr, multi := bus.allocate(fmt.Sprintf("lxd_%s", devName))
+		tplFields := map[string]interface{}{
+			"bus":           bus.name,
+			"devBus":        devBus,
+			"devAddr":       devAddr,
+			"multifunction": multi,
+
+			"devName": devName,
+			"vgpu":    vgpu,
 		}
+
+		return qemuGPUDevMdev.Execute(sb, tplFields)
 	}
 
 	// Pass-through VGA mode if enabled on the host device and architecture is x86_64.
@@ -3760,6 +3808,8 @@ func (vm *qemu) Console(protocol string) (*os.File, chan error, error) {
 		return vm.console()
 	case instance.ConsoleTypeVGA:
 		return vm.vga()
+	case instance.ConsoleTypeVNC:
+		return vm.vnc()
 	default:
 		return nil, nil, fmt.Errorf("Unknown protocol %q", protocol)
 	}
@@ -3806,10 +3856,19 @@ func (vm *qemu) console() (*os.File, chan error, error) {
 }
 
 func (vm *qemu) vga() (*os.File, chan error, error) {
-	// Open the spice socket
-	conn, err := net.Dial("unix", vm.spicePath())
+	return vm.graphicalConsole("SPICE", vm.spicePath())
+}
+
+func (vm *qemu) vnc() (*os.File, chan error, error) {
+	return vm.graphicalConsole("VNC", vm.vncPath())
+}
+
+// graphicalConsole connects to the given graphical console socket.
+func (vm *qemu) graphicalConsole(protocol string, path string) (*os.File, chan error, error) {
+	// Open the socket
+	conn, err := net.Dial("unix", path)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "Connect to SPICE socket %q", vm.spicePath())
+		return nil, nil, errors.Wrapf(err, "Connect to %s socket %q", protocol, path)
 	}
 
 	file, err := (conn.(*net.UnixConn)).File()
@@ -3927,7 +3986,7 @@ func (vm *qemu) Render(options ...func(response interface{}) error) (interface{}
 	}
 
 	// Prepare the ETag
-	etag := []interface{}{vm.architecture, vm.localConfig, vm.localDevices, vm.ephemeral, vm.profiles}
+	etag := []interface{}{vm.architecture, vm.localConfig, vm.localDevices, vm.ephemeral, vm.profiles, vm.description}
 
 	instState := api.Instance{
 		ExpandedConfig:  vm.expandedConfig,
@@ -4080,6 +4139,11 @@ func (vm *qemu) RenderState() (*api.InstanceState, error) {
 						BytesSent:       hostCounters.BytesReceived,
 						PacketsReceived: hostCounters.PacketsSent,
 						PacketsSent:     hostCounters.PacketsReceived,
+
+						ErrorsReceived:         hostCounters.ErrorsSent,
+						ErrorsSent:             hostCounters.ErrorsReceived,
+						PacketsDroppedInbound:  hostCounters.PacketsDroppedOutbound,
+						PacketsDroppedOutbound: hostCounters.PacketsDroppedInbound,
 					},
 					Hwaddr:   m["hwaddr"],
 					HostName: m["host_name"],
@@ -4827,9 +4891,10 @@ func (vm *qemu) writeInstanceData() error {
 	}
 
 	out, err := json.Marshal(struct {
-		Name   string            `json:"name"`
-		Config map[string]string `json:"config,omitempty"`
-	}{vm.Name(), userConfig})
+		Name    string                       `json:"name"`
+		Config  map[string]string            `json:"config,omitempty"`
+		Devices map[string]map[string]string `json:"devices,omitempty"`
+	}{vm.Name(), userConfig, vm.ExpandedDevices().CloneNative()})
 	if err != nil {
 		return err
 	}
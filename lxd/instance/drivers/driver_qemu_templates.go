@@ -50,6 +50,9 @@ backend = "pty"
 unix = "on"
 addr = "{{.spicePath}}"
 disable-ticketing = "on"
+
+[vnc "qemu_vnc"]
+vnc = "unix:{{.vncPath}}"
 `))
 
 var qemuMemory = template.Must(template.New("qemuMemory").Parse(`
@@ -399,7 +402,7 @@ format = "raw"
 if = "none"
 cache = "{{.cacheMode}}"
 aio = "{{.aioMode}}"
-discard = "on"
+discard = "{{if .discard}}on{{else}}ignore{{end}}"
 {{if .shared -}}
 file.locking = "off"
 {{- end }}
@@ -431,6 +434,13 @@ driver = "virtio-net-ccw"
 netdev = "lxd_{{.devName}}"
 mac = "{{.devHwaddr}}"
 bootindex = "{{.bootIndex}}"
+{{if .queues -}}
+mq = "on"
+vectors = "{{.vectors}}"
+{{- end }}
+{{if .failover -}}
+failover = "on"
+{{- end }}
 {{if .multifunction -}}
 multifunction = "on"
 {{- end }}
@@ -445,6 +455,9 @@ vhost = "on"
 ifname = "{{.ifName}}"
 script = "no"
 downscript = "no"
+{{if .queues -}}
+queues = "{{.queues}}"
+{{- end }}
 {{ template "qemuNetDevTapCommon" . -}}
 `))
 
@@ -477,6 +490,21 @@ multifunction = "on"
 {{- end }}
 `))
 
+// qemuNetDevFailoverPrimary is the VF paired with the virtio NIC of a failover pair, plugged by the guest
+// once its virtio driver acknowledges the standby feature.
+var qemuNetDevFailoverPrimary = template.Must(template.New("qemuNetDevFailoverPrimary").Parse(`
+# Network card failover primary ("{{.devName}}" device)
+[device "dev-lxd_{{.devName}}-primary"]
+driver = "vfio-pci"
+bus = "{{.devBus}}"
+addr = "{{.devAddr}}"
+host = "{{.pciSlotName}}"
+failover_pair_id = "dev-lxd_{{.devName}}"
+{{if .multifunction -}}
+multifunction = "on"
+{{- end }}
+`))
+
 // Devices use "lxd_" prefix indicating that this is a user named device.
 var qemuGPUDevPhysical = template.Must(template.New("qemuGPUDevPhysical").Parse(`
 # GPU card ("{{.devName}}" device)
@@ -497,3 +525,21 @@ x-vga = "on"
 multifunction = "on"
 {{- end }}
 `))
+
+// Devices use "lxd_" prefix indicating that this is a user named device.
+var qemuGPUDevMdev = template.Must(template.New("qemuGPUDevMdev").Parse(`
+# GPU mediated device ("{{.devName}}" device)
+[device "dev-lxd_{{.devName}}"]
+{{- if eq .bus "pci" "pcie"}}
+driver = "vfio-pci"
+bus = "{{.devBus}}"
+addr = "{{.devAddr}}"
+{{- end}}
+{{if eq .bus "ccw" -}}
+driver = "vfio-ccw"
+{{- end}}
+sysfsdev = "/sys/bus/mdev/devices/{{.vgpu}}"
+{{if .multifunction -}}
+multifunction = "on"
+{{- end }}
+`))
@@ -29,6 +29,7 @@ const HookStop = "onstop"
 const (
 	ConsoleTypeConsole = "console"
 	ConsoleTypeVGA     = "vga"
+	ConsoleTypeVNC     = "vnc"
 )
 
 // ConfigReader is used to read instance config.
@@ -78,7 +79,7 @@ type Instance interface {
 	FilePush(fileType string, srcpath string, dstpath string, uid int64, gid int64, mode int, write string) error
 	FileRemove(path string) error
 
-	// Console - Allocate and run a console tty or a spice Unix socket.
+	// Console - Allocate and run a console tty or a spice or VNC Unix socket.
 	Console(protocol string) (*os.File, chan error, error)
 	Exec(req api.InstanceExecPost, stdin *os.File, stdout *os.File, stderr *os.File) (Cmd, error)
 
@@ -177,7 +177,17 @@ func ValidConfig(sysOS *sys.OS, config map[string]string, profile bool, expanded
 		return err
 	}
 
-	if expanded && (config["security.privileged"] == "" || !shared.IsTrue(config["security.privileged"])) && sysOS.IdmapSet == nil {
+	_, err = util.BinfmtArchitectures(config["linux.binfmt_misc.emulate"])
+	if err != nil {
+		return err
+	}
+
+	// Backups uploaded to object storage are only ever deleted through their expiry.
+	if expanded && config["backups.target"] == "s3" && config["backups.schedule"] != "" && config["backups.expiry"] == "" {
+		return fmt.Errorf("backups.expiry must be set to schedule backups to S3")
+	}
+
+	if expanded && (config["security.privileged"] == "" || !shared.IsTrue(config["security.privileged"])) && sysOS.IdmapSet() == nil {
 		return fmt.Errorf("LXD doesn't have a uid/gid allocation. In this mode, only privileged containers are supported")
 	}
 
@@ -519,13 +529,18 @@ func LoadAllInternal(s *state.State, dbInstances []db.Instance) ([]Instance, err
 
 	// Get the profile data
 	for project, projectProfiles := range profiles {
+		names := make([]string, 0, len(projectProfiles))
 		for name := range projectProfiles {
-			_, profile, err := s.Cluster.GetProfile(project, name)
-			if err != nil {
-				return nil, err
-			}
+			names = append(names, name)
+		}
 
-			projectProfiles[name] = *profile
+		expanded, err := s.Cluster.GetProfiles(project, names)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, name := range names {
+			projectProfiles[name] = expanded[i]
 		}
 	}
 
@@ -693,6 +708,19 @@ func ResolveImage(s *state.State, project string, source api.InstanceSource) (st
 			return "", err
 		}
 
+		// Pick the image matching the architecture of this server, if any.
+		for _, architecture := range s.OS.Architectures {
+			architectureName, err := osarch.ArchitectureName(architecture)
+			if err != nil {
+				continue
+			}
+
+			fingerprint, ok := alias.Architectures[architectureName]
+			if ok {
+				return fingerprint, nil
+			}
+		}
+
 		return alias.Target, nil
 	}
 
@@ -792,6 +820,28 @@ func SuitableArchitectures(s *state.State, project string, req api.InstancesPost
 
 		// Handle local images.
 		if req.Source.Server == "" {
+			// Aliases may point to an image for each architecture.
+			if req.Source.Fingerprint == "" && req.Source.Alias != "" {
+				_, alias, err := s.Cluster.GetImageAlias(project, req.Source.Alias, true)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(alias.Architectures) > 0 {
+					architectures := []int{}
+					for architectureName := range alias.Architectures {
+						id, err := osarch.ArchitectureId(architectureName)
+						if err != nil {
+							return nil, err
+						}
+
+						architectures = append(architectures, id)
+					}
+
+					return architectures, nil
+				}
+			}
+
 			_, img, err := s.Cluster.GetImage(project, hash, false)
 			if err != nil {
 				return nil, err
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
@@ -159,6 +161,33 @@ func containerBackupsPost(d *Daemon, r *http.Request) response.Response {
 	fullName := name + shared.SnapshotDelimiter + req.Name
 	instanceOnly := req.InstanceOnly || req.ContainerOnly
 
+	if !shared.StringInSlice(req.Target, []string{"", "s3"}) {
+		return response.BadRequest(fmt.Errorf("Invalid backup target %q", req.Target))
+	}
+
+	if req.BandwidthLimit != "" {
+		err = shared.IsSize(req.BandwidthLimit)
+		if err != nil {
+			return response.BadRequest(errors.Wrapf(err, "Invalid bandwidth limit %q", req.BandwidthLimit))
+		}
+	}
+
+	// Incremental backups are made of the snapshots taken after the base snapshot.
+	if req.DeltaFrom != "" {
+		if !req.OptimizedStorage {
+			return response.BadRequest(fmt.Errorf("Incremental backups require optimized storage"))
+		}
+
+		if instanceOnly {
+			return response.BadRequest(fmt.Errorf("Incremental backups cannot be instance only"))
+		}
+
+		_, err = instance.LoadByProjectAndName(d.State(), project, name+shared.SnapshotDelimiter+req.DeltaFrom)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed loading base snapshot %q", req.DeltaFrom))
+		}
+	}
+
 	backup := func(op *operations.Operation) error {
 		args := db.InstanceBackup{
 			Name:                 fullName,
@@ -168,9 +197,12 @@ func containerBackupsPost(d *Daemon, r *http.Request) response.Response {
 			InstanceOnly:         instanceOnly,
 			OptimizedStorage:     req.OptimizedStorage,
 			CompressionAlgorithm: req.CompressionAlgorithm,
+			DeltaFrom:            req.DeltaFrom,
+			Target:               req.Target,
+			BandwidthLimit:       req.BandwidthLimit,
 		}
 
-		err := backupCreate(d.State(), args, inst)
+		err := backupCreate(d.State(), args, inst, op)
 		if err != nil {
 			return errors.Wrap(err, "Create backup")
 		}
@@ -189,6 +221,8 @@ func containerBackupsPost(d *Daemon, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
+	op.SetCancelable()
+
 	return operations.OperationResponse(op)
 }
 
@@ -356,3 +390,52 @@ func containerBackupExportGet(d *Daemon, r *http.Request) response.Response {
 
 	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
 }
+
+func containerBackupVerifyPost(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	proj := projectParam(r)
+	name := mux.Vars(r)["name"]
+	backupName := mux.Vars(r)["backupName"]
+
+	// Handle requests targeted to a container on a different node
+	resp, err := forwardedResponseIfInstanceIsRemote(d, r, proj, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	fullName := name + shared.SnapshotDelimiter + backupName
+	b, err := instance.BackupLoadByName(d.State(), proj, fullName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	verify := func(op *operations.Operation) error {
+		f, err := os.Open(shared.VarPath("backups", project.Instance(proj, b.Name())))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return backup.Verify(f)
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{name}
+	resources["containers"] = resources["instances"]
+	resources["backups"] = []string{backupName}
+
+	op, err := operations.OperationCreate(d.State(), proj, operations.OperationClassTask,
+		db.OperationBackupVerify, resources, nil, verify, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -20,6 +21,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -158,6 +160,19 @@ func (s *execWs) Do(op *operations.Operation) error {
 		stderr = ttys[2]
 	}
 
+	// Record the output on top of mirroring it, if requested.
+	var records []*os.File
+	var output shared.Jmap
+	if s.req.RecordOutput && !s.req.Interactive {
+		records = make([]*os.File, 3)
+		records[1], records[2], output, err = execRecordOutput(s.instance, op)
+		if err != nil {
+			return err
+		}
+		defer records[1].Close()
+		defer records[2].Close()
+	}
+
 	controlExit := make(chan struct{})
 	attachedChildIsDead := make(chan struct{})
 	var wgEOF sync.WaitGroup
@@ -189,6 +204,10 @@ func (s *execWs) Do(op *operations.Operation) error {
 		}
 
 		metadata := shared.Jmap{"return": cmdResult}
+		if output != nil {
+			metadata["output"] = output
+		}
+
 		err = op.UpdateMetadata(metadata)
 		if err != nil {
 			return err
@@ -322,7 +341,12 @@ func (s *execWs) Do(op *operations.Operation) error {
 					conn := s.conns[i]
 					s.connsLock.Unlock()
 
-					<-shared.WebsocketSendStream(conn, ptys[i], -1)
+					var r io.Reader = ptys[i]
+					if records != nil {
+						r = io.TeeReader(ptys[i], records[i])
+					}
+
+					<-shared.WebsocketSendStream(conn, r, -1)
 					ptys[i].Close()
 					wgEOF.Done()
 				}
@@ -430,6 +454,10 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 		post.Environment["LANG"] = "C.UTF-8"
 	}
 
+	if post.RecordOutput && post.WaitForWS && post.Interactive {
+		return response.BadRequest(fmt.Errorf("Output recording isn't supported for interactive commands"))
+	}
+
 	if post.WaitForWS {
 		ws := &execWs{}
 		ws.fds = map[int]string{}
@@ -484,18 +512,15 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 
 		if post.RecordOutput {
 			// Prepare stdout and stderr recording
-			stdout, err := os.OpenFile(filepath.Join(inst.LogPath(), fmt.Sprintf("exec_%s.stdout", op.ID())), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+			stdout, stderr, output, err := execRecordOutput(inst, op)
 			if err != nil {
 				return err
 			}
 			defer stdout.Close()
-
-			stderr, err := os.OpenFile(filepath.Join(inst.LogPath(), fmt.Sprintf("exec_%s.stderr", op.ID())), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-			if err != nil {
-				return err
-			}
 			defer stderr.Close()
 
+			metadata["output"] = output
+
 			// Run the command
 			cmd, err := inst.Exec(post, nil, stdout, stderr)
 			if err != nil {
@@ -507,12 +532,7 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 				return err
 			}
 
-			// Update metadata with the right URLs
 			metadata["return"] = exitCode
-			metadata["output"] = shared.Jmap{
-				"1": fmt.Sprintf("/%s/instances/%s/logs/%s", version.APIVersion, inst.Name(), filepath.Base(stdout.Name())),
-				"2": fmt.Sprintf("/%s/instances/%s/logs/%s", version.APIVersion, inst.Name(), filepath.Base(stderr.Name())),
-			}
 		} else {
 			cmd, err := inst.Exec(post, nil, nil, nil)
 			if err != nil {
@@ -548,3 +568,48 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 
 	return operations.OperationResponse(op)
 }
+
+// execRecordOutput creates the log files recording the stdout and stderr of the command run by the given
+// exec operation. The URLs of the files are added to the operation metadata straight away, so that the
+// output can be retrieved while the command is still running.
+func execRecordOutput(inst instance.Instance, op *operations.Operation) (*os.File, *os.File, shared.Jmap, error) {
+	stdout, err := os.OpenFile(filepath.Join(inst.LogPath(), fmt.Sprintf("exec_%s.stdout", op.ID())), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stderr, err := os.OpenFile(filepath.Join(inst.LogPath(), fmt.Sprintf("exec_%s.stderr", op.ID())), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		stdout.Close()
+		return nil, nil, nil, err
+	}
+
+	url := func(file *os.File) string {
+		u := fmt.Sprintf("/%s/instances/%s/logs/%s", version.APIVersion, inst.Name(), filepath.Base(file.Name()))
+		if inst.Project() != project.Default {
+			u += fmt.Sprintf("?project=%s", inst.Project())
+		}
+
+		return u
+	}
+
+	output := shared.Jmap{
+		"1": url(stdout),
+		"2": url(stderr),
+	}
+
+	metadata := op.Metadata()
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["output"] = output
+
+	err = op.UpdateMetadata(metadata)
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return nil, nil, nil, err
+	}
+
+	return stdout, stderr, output, nil
+}
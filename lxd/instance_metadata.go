@@ -15,6 +15,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
@@ -42,7 +43,6 @@ func containerMetadataGet(d *Daemon, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
-	metadataPath := filepath.Join(c.Path(), "metadata.yaml")
 
 	// Start the storage if needed
 	ourStart, err := c.StorageStart()
@@ -53,31 +53,38 @@ func containerMetadataGet(d *Daemon, r *http.Request) response.Response {
 		defer c.StorageStop()
 	}
 
+	metadata, err := instanceMetadataRead(c)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, metadata, metadata)
+}
+
+// instanceMetadataRead returns the content of the metadata.yaml file of the instance, which storage must be
+// started, or an empty result if missing.
+func instanceMetadataRead(c instance.Instance) (*api.ImageMetadata, error) {
+	metadataPath := filepath.Join(c.Path(), "metadata.yaml")
+
 	// If missing, just return empty result
 	if !shared.PathExists(metadataPath) {
-		return response.SyncResponse(true, api.ImageMetadata{})
+		return &api.ImageMetadata{}, nil
 	}
 
 	// Read the metadata
-	metadataFile, err := os.Open(metadataPath)
-	if err != nil {
-		return response.InternalError(err)
-	}
-	defer metadataFile.Close()
-
-	data, err := ioutil.ReadAll(metadataFile)
+	data, err := ioutil.ReadFile(metadataPath)
 	if err != nil {
-		return response.InternalError(err)
+		return nil, err
 	}
 
 	// Parse into the API struct
 	metadata := api.ImageMetadata{}
 	err = yaml.Unmarshal(data, &metadata)
 	if err != nil {
-		return response.SmartError(err)
+		return nil, err
 	}
 
-	return response.SyncResponse(true, metadata)
+	return &metadata, nil
 }
 
 func containerMetadataPut(d *Daemon, r *http.Request) response.Response {
@@ -114,6 +121,17 @@ func containerMetadataPut(d *Daemon, r *http.Request) response.Response {
 		defer c.StorageStop()
 	}
 
+	// Validate the ETag
+	current, err := instanceMetadataRead(c)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = util.EtagCheck(r, current)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
 	// Read the new metadata
 	metadata := api.ImageMetadata{}
 	if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil {
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// instancePlacementCandidate holds the usage of a cluster member considered for an instance placement.
+// Usage values are ratios between 0 and 1, or -1 when they couldn't be retrieved.
+type instancePlacementCandidate struct {
+	Name        string  `json:"name"`
+	Instances   int     `json:"instances"`
+	CPULoad     float64 `json:"cpu_load"`
+	MemoryUsage float64 `json:"memory_usage"`
+	DiskUsage   float64 `json:"disk_usage"`
+	Score       float64 `json:"score"`
+}
+
+// instancePlacementHookRequest is sent to the scheduler hook on its standard input.
+type instancePlacementHookRequest struct {
+	Project    string                       `json:"project"`
+	Request    *api.InstancesPost           `json:"request"`
+	Candidates []instancePlacementCandidate `json:"candidates"`
+}
+
+// instancePlacement picks the cluster member a new instance should be created on among the given candidates.
+//
+// Each candidate is scored using its instances count, CPU load, memory usage and the usage of the storage pool
+// of the instance's root disk, weighted by the cluster.scheduler.weight.* config keys. The candidate with the
+// lowest score is picked, unless a cluster.scheduler.hook executable is set in which case it's given the
+// scored candidates and prints the name of the member to use.
+func instancePlacement(d *Daemon, projectName string, req *api.InstancesPost, members []db.NodeInfo) (string, error) {
+	if len(members) == 0 {
+		return "", nil
+	}
+
+	var config *cluster.Config
+	var poolName string
+	candidates := make([]instancePlacementCandidate, len(members))
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		config, err = cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		for i, member := range members {
+			candidates[i].Name = member.Name
+			candidates[i].Instances, err = tx.GetNodeInstancesCount(member.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		poolName, err = instancePlacementRootPool(tx, projectName, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	weightInstances, weightCPU, weightMemory, weightDisk := config.SchedulerWeights()
+	hook := config.SchedulerHook()
+
+	// There's nothing to compare a single member to.
+	if len(candidates) == 1 && hook == "" {
+		return candidates[0].Name, nil
+	}
+
+	// Only query the members when their usage is taken into account.
+	if weightCPU > 0 || weightMemory > 0 || weightDisk > 0 || hook != "" {
+		for i := range candidates {
+			instancePlacementUsage(d, &candidates[i], poolName)
+		}
+	}
+
+	// Normalize each criterion against the busiest candidate so that the weights are comparable.
+	maxInstances, maxCPU, maxMemory, maxDisk := 0.0, 0.0, 0.0, 0.0
+	for _, candidate := range candidates {
+		maxInstances = instancePlacementMax(maxInstances, float64(candidate.Instances))
+		maxCPU = instancePlacementMax(maxCPU, candidate.CPULoad)
+		maxMemory = instancePlacementMax(maxMemory, candidate.MemoryUsage)
+		maxDisk = instancePlacementMax(maxDisk, candidate.DiskUsage)
+	}
+
+	best := 0
+	for i := range candidates {
+		candidate := &candidates[i]
+		candidate.Score = float64(weightInstances)*instancePlacementNormalize(float64(candidate.Instances), maxInstances) +
+			float64(weightCPU)*instancePlacementNormalize(candidate.CPULoad, maxCPU) +
+			float64(weightMemory)*instancePlacementNormalize(candidate.MemoryUsage, maxMemory) +
+			float64(weightDisk)*instancePlacementNormalize(candidate.DiskUsage, maxDisk)
+
+		if candidate.Score < candidates[best].Score {
+			best = i
+		}
+	}
+
+	if hook == "" {
+		return candidates[best].Name, nil
+	}
+
+	name, err := instancePlacementRunHook(hook, projectName, req, candidates)
+	if err != nil {
+		return "", err
+	}
+
+	// Let the hook defer to the built-in choice.
+	if name == "" {
+		return candidates[best].Name, nil
+	}
+
+	return name, nil
+}
+
+// instancePlacementRootPool returns the storage pool of the root disk of the instance being created, if any.
+func instancePlacementRootPool(tx *db.ClusterTx, projectName string, req *api.InstancesPost) (string, error) {
+	_, rootDev, err := shared.GetRootDiskDevice(req.Devices)
+	if err == nil {
+		return rootDev["pool"], nil
+	}
+
+	profileProject := projecthelpers.Default
+	enabled, err := tx.ProjectHasProfiles(projectName)
+	if err != nil {
+		return "", errors.Wrap(err, "Check if project has profiles")
+	}
+
+	if enabled {
+		profileProject = projectName
+	}
+
+	profiles := req.Profiles
+	if profiles == nil {
+		profiles = []string{"default"}
+	}
+
+	// The last profile with a root disk wins.
+	pool := ""
+	for _, name := range profiles {
+		profile, err := tx.GetProfile(profileProject, name)
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				continue
+			}
+
+			return "", errors.Wrapf(err, "Load profile %q", name)
+		}
+
+		_, rootDev, err := shared.GetRootDiskDevice(profile.Devices)
+		if err == nil {
+			pool = rootDev["pool"]
+		}
+	}
+
+	return pool, nil
+}
+
+// instancePlacementUsage fills the CPU load, memory usage and disk usage of the given candidate. Values which
+// can't be retrieved are set to -1.
+func instancePlacementUsage(d *Daemon, candidate *instancePlacementCandidate, poolName string) {
+	candidate.CPULoad = -1
+	candidate.MemoryUsage = -1
+	candidate.DiskUsage = -1
+
+	address, err := cluster.ResolveTarget(d.cluster, candidate.Name)
+	if err != nil {
+		logger.Warn("Failed to resolve cluster member address", log.Ctx{"member": candidate.Name, "err": err})
+		return
+	}
+
+	var res *api.Resources
+	var poolRes *api.ResourcesStoragePool
+
+	if address == "" {
+		res, err = resources.GetResources()
+		if err == nil && poolName != "" {
+			var pool storagePools.Pool
+			pool, err = storagePools.GetPoolByName(d.State(), poolName)
+			if err == nil {
+				poolRes, err = pool.GetResources()
+			}
+		}
+	} else {
+		var client lxd.InstanceServer
+		client, err = cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			logger.Warn("Failed to connect to cluster member", log.Ctx{"member": candidate.Name, "err": err})
+			return
+		}
+
+		res, err = client.GetServerResources()
+		if err == nil && poolName != "" {
+			poolRes, err = client.GetStoragePoolResources(poolName)
+		}
+	}
+
+	if err != nil {
+		logger.Warn("Failed to get cluster member resources", log.Ctx{"member": candidate.Name, "err": err})
+	}
+
+	if res != nil {
+		if len(res.CPU.LoadAverage) > 0 && res.CPU.Total > 0 {
+			candidate.CPULoad = res.CPU.LoadAverage[0] / float64(res.CPU.Total)
+		}
+
+		if res.Memory.Total > 0 {
+			candidate.MemoryUsage = float64(res.Memory.Used) / float64(res.Memory.Total)
+		}
+	}
+
+	if poolRes != nil && poolRes.Space.Total > 0 {
+		candidate.DiskUsage = float64(poolRes.Space.Used) / float64(poolRes.Space.Total)
+	}
+}
+
+// instancePlacementRunHook runs the scheduler hook and returns the name of the member it picked.
+func instancePlacementRunHook(hook string, projectName string, req *api.InstancesPost, candidates []instancePlacementCandidate) (string, error) {
+	data, err := json.Marshal(instancePlacementHookRequest{
+		Project:    projectName,
+		Request:    req,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	err = shared.RunCommandWithFds(bytes.NewReader(data), &stdout, hook)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to run scheduler hook")
+	}
+
+	name := strings.TrimSpace(stdout.String())
+	if name == "" {
+		return "", nil
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Name == name {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("Scheduler hook picked cluster member %q which isn't a suitable candidate", name)
+}
+
+// instancePlacementMax returns the largest of the two values.
+func instancePlacementMax(a float64, b float64) float64 {
+	if b > a {
+		return b
+	}
+
+	return a
+}
+
+// instancePlacementNormalize scales value to between 0 and 1 relative to max. Unknown (negative) values are
+// treated as the worst possible.
+func instancePlacementNormalize(value float64, max float64) float64 {
+	if value < 0 {
+		return 1
+	}
+
+	if max <= 0 {
+		return 0
+	}
+
+	return value / max
+}
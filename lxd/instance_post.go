@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 	"github.com/pborman/uuid"
@@ -14,12 +15,14 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
 	driver "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -206,11 +209,35 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		instanceOnly := req.InstanceOnly || req.ContainerOnly
+
+		// Moving the instance to another project on the same server.
+		if req.Project != "" && req.Project != project {
+			if req.Pool != "" {
+				return response.BadRequest(fmt.Errorf("Instances can't be moved to another project and storage pool at once"))
+			}
+
+			if !d.userHasPermission(r, req.Project, "manage-containers") {
+				return response.Forbidden(nil)
+			}
+
+			return instancePostProjectMigration(d, inst, req.Name, instanceOnly, req.Project)
+		}
+
+		// Moving the instance to another storage pool on the same server.
+		if req.Pool != "" {
+			return instancePostPoolMigration(d, inst, req.Name, instanceOnly, req.Pool)
+		}
+
 		ws, err := newMigrationSource(inst, stateful, instanceOnly)
 		if err != nil {
 			return response.InternalError(err)
 		}
 
+		err = ws.setBandwidthLimit(d.State(), req.BandwidthLimit)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		resources := map[string][]string{}
 		resources["instances"] = []string{name}
 		resources["containers"] = resources["instances"]
@@ -248,6 +275,12 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 		return operations.OperationResponse(op)
 	}
 
+	// Check that the new name complies with the naming policy of the project.
+	err = instanceNameAllowed(d, project, req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Check that the name isn't already in use.
 	id, _ := d.cluster.GetInstanceID(project, req.Name)
 	if id > 0 {
@@ -270,6 +303,298 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 	return operations.OperationResponse(op)
 }
 
+// Move an instance's root disk to another storage pool on the same server.
+func instancePostPoolMigration(d *Daemon, inst instance.Instance, newName string, instanceOnly bool, newPool string) response.Response {
+	if inst.IsRunning() {
+		return response.BadRequest(fmt.Errorf("Instance must be stopped to be moved to another storage pool"))
+	}
+
+	// Check that the target pool exists.
+	_, err := d.cluster.GetStoragePoolID(newPool)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed to get storage pool %q", newPool))
+	}
+
+	rootDevKey, rootDev, err := shared.GetRootDiskDevice(inst.ExpandedDevices().CloneNative())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if rootDev["pool"] == newPool {
+		return response.BadRequest(fmt.Errorf("Instance is already using storage pool %q", newPool))
+	}
+
+	if newName == "" {
+		newName = inst.Name()
+	}
+
+	if newName != inst.Name() {
+		err = instanceNameAllowed(d, inst.Project(), newName)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		id, _ := d.cluster.GetInstanceID(inst.Project(), newName)
+		if id > 0 {
+			return response.Conflict(fmt.Errorf("Name '%s' already in use", newName))
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		srcPool, err := driver.GetPoolByInstance(d.State(), inst)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load instance storage pool")
+		}
+
+		pool, err := driver.GetPoolByName(d.State(), newPool)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to load storage pool %q", newPool)
+		}
+
+		// Volumes of shared pools have a record for each node, those of local pools only for this one.
+		clustered, err := cluster.Enabled(d.db)
+		if err != nil {
+			return err
+		}
+
+		if clustered && srcPool.Driver().Info().Remote != pool.Driver().Info().Remote {
+			return fmt.Errorf("Instances can't be moved between local and remote storage pools in a cluster")
+		}
+
+		revert := revert.New()
+		defer revert.Fail()
+
+		// The instance is moved in place rather than copied, so that it keeps its ID, creation date
+		// and backups. Its volume and those of its snapshots move to the new pool with their records.
+		err = pool.MoveInstanceToPool(inst, srcPool, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to move instance volume to storage pool %q", newPool)
+		}
+
+		revert.Add(func() { srcPool.MoveInstanceToPool(inst, pool, op) })
+
+		// Override the root disk device locally so that it points to the new pool, even if it
+		// was previously inherited from a profile.
+		localDevices := inst.LocalDevices().CloneNative()
+		newRootDev := map[string]string{}
+		for k, v := range rootDev {
+			newRootDev[k] = v
+		}
+
+		newRootDev["pool"] = newPool
+		localDevices[rootDevKey] = newRootDev
+
+		args := db.InstanceArgs{
+			Architecture: inst.Architecture(),
+			Config:       inst.LocalConfig(),
+			Description:  inst.Description(),
+			Devices:      deviceConfig.NewDevices(localDevices),
+			Ephemeral:    inst.IsEphemeral(),
+			Profiles:     inst.Profiles(),
+			Project:      inst.Project(),
+			Type:         inst.Type(),
+			Snapshot:     inst.IsSnapshot(),
+		}
+
+		err = inst.Update(args, false)
+		if err != nil {
+			return errors.Wrap(err, "Failed to update root disk device")
+		}
+
+		if newName != inst.Name() {
+			err = inst.Rename(newName)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to rename instance to %q", newName)
+			}
+		}
+
+		revert.Success()
+
+		// Only drop the snapshots once the instance itself is safely in the new pool.
+		if instanceOnly {
+			snapshots, err := inst.Snapshots()
+			if err != nil {
+				return err
+			}
+
+			for i := len(snapshots) - 1; i >= 0; i-- {
+				err = snapshots[i].Delete()
+				if err != nil {
+					return errors.Wrapf(err, "Failed to delete snapshot %q", snapshots[i].Name())
+				}
+			}
+		}
+
+		err = pool.UpdateInstanceBackupFile(inst, op)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{inst.Name()}
+	resources["containers"] = resources["instances"]
+
+	op, err := operations.OperationCreate(d.State(), inst.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// Move an instance to another project on the same server. The instance's profiles are mapped onto the
+// profiles with the same names in the target project.
+func instancePostProjectMigration(d *Daemon, inst instance.Instance, newName string, instanceOnly bool, newProject string) response.Response {
+	if inst.IsRunning() {
+		return response.BadRequest(fmt.Errorf("Instance must be stopped to be moved to another project"))
+	}
+
+	if newName == "" {
+		newName = inst.Name()
+	}
+
+	// Check that the name isn't already in use in the target project.
+	id, _ := d.cluster.GetInstanceID(newProject, newName)
+	if id > 0 {
+		return response.Conflict(fmt.Errorf("Name '%s' already in use in project %q", newName, newProject))
+	}
+
+	err := instanceNameAllowed(d, newProject, newName)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Check that all the profiles of the instance exist in the target project.
+	profileNames, err := d.cluster.GetProfileNames(newProject)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed to get profiles of project %q", newProject))
+	}
+
+	for _, profile := range inst.Profiles() {
+		if !shared.StringInSlice(profile, profileNames) {
+			return response.BadRequest(fmt.Errorf("Profile %q doesn't exist in project %q", profile, newProject))
+		}
+	}
+
+	// Keep all config keys, including volatile ones, as this is a move.
+	localConfig := map[string]string{}
+	for k, v := range inst.LocalConfig() {
+		localConfig[k] = v
+	}
+
+	localDevices := inst.LocalDevices().CloneNative()
+
+	// Check the limits and restrictions of the target project.
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		req := api.InstancesPost{
+			Name: newName,
+			Type: api.InstanceType(inst.Type().String()),
+			InstancePut: api.InstancePut{
+				Config:   localConfig,
+				Devices:  localDevices,
+				Profiles: inst.Profiles(),
+			},
+		}
+
+		return project.AllowInstanceCreation(tx, newProject, req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Backups are stored under the project and name of the instance, and aren't moved.
+	backups, err := inst.Backups()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(backups) > 0 {
+		return response.BadRequest(fmt.Errorf("Instance backups must be deleted before moving the instance to another project"))
+	}
+
+	run := func(op *operations.Operation) error {
+		pool, err := driver.GetPoolByInstance(d.State(), inst)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load instance storage pool")
+		}
+
+		revert := revert.New()
+		defer revert.Fail()
+
+		// The instance is moved in place rather than copied, so that there's always exactly one copy of it.
+		// Its database records, including those of its snapshots and volumes, move in a single transaction.
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.MoveInstanceToProject(inst.Project(), inst.Name(), newProject, newName)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed to move instance to project %q", newProject)
+		}
+
+		revert.Add(func() {
+			d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				return tx.MoveInstanceToProject(newProject, newName, inst.Project(), inst.Name())
+			})
+		})
+
+		err = pool.MoveInstanceToProject(inst, newProject, newName, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to move instance volume to project %q", newProject)
+		}
+
+		// Keep the logs of the instance.
+		newLogPath := shared.LogPath(project.Instance(newProject, newName))
+		if shared.PathExists(inst.LogPath()) {
+			err = os.Rename(inst.LogPath(), newLogPath)
+			if err != nil {
+				return errors.Wrap(err, "Failed to move instance logs")
+			}
+		}
+
+		revert.Success()
+
+		newInst, err := instance.LoadByProjectAndName(d.State(), newProject, newName)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load moved instance")
+		}
+
+		// Only drop the snapshots once the instance itself is safely in the target project.
+		if instanceOnly {
+			snapshots, err := newInst.Snapshots()
+			if err != nil {
+				return err
+			}
+
+			for i := len(snapshots) - 1; i >= 0; i-- {
+				err = snapshots[i].Delete()
+				if err != nil {
+					return errors.Wrapf(err, "Failed to delete snapshot %q", snapshots[i].Name())
+				}
+			}
+		}
+
+		err = pool.UpdateInstanceBackupFile(newInst, op)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{inst.Name()}
+	resources["containers"] = resources["instances"]
+
+	op, err := operations.OperationCreate(d.State(), inst.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
 // Move a non-ceph container to another cluster node.
 func containerPostClusteringMigrate(d *Daemon, c instance.Instance, oldName, newName, newNode string) response.Response {
 	cert := d.endpoints.NetworkCert()
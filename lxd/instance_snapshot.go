@@ -306,12 +306,12 @@ func snapshotPut(d *Daemon, r *http.Request, sc instance.Instance, name string)
 }
 
 func snapshotGet(s *state.State, snapInst instance.Instance, name string) response.Response {
-	render, _, err := snapInst.Render(storagePools.RenderSnapshotUsage(s, snapInst))
+	render, etag, err := snapInst.Render(storagePools.RenderSnapshotUsage(s, snapInst))
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	return response.SyncResponse(true, render.(*api.InstanceSnapshot))
+	return response.SyncResponseETag(true, render.(*api.InstanceSnapshot), etag)
 }
 
 func snapshotPost(d *Daemon, r *http.Request, sc instance.Instance, containerName string) response.Response {
@@ -364,6 +364,11 @@ func snapshotPost(d *Daemon, r *http.Request, sc instance.Instance, containerNam
 			return response.SmartError(err)
 		}
 
+		err = ws.setBandwidthLimit(d.State(), "")
+		if err != nil {
+			return response.SmartError(err)
+		}
+
 		resources := map[string][]string{}
 		resources["containers"] = []string{containerName}
 
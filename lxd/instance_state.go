@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -39,6 +40,17 @@ func containerState(d *Daemon, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
+
+	// Wait for the status of the instance to change if requested.
+	if shared.IsTrue(r.FormValue("wait")) {
+		timeout, err := shared.AtoiEmptyDefault(r.FormValue("timeout"), -1)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		instanceStateWait(r.Context(), c, timeout)
+	}
+
 	state, err := c.RenderState()
 	if err != nil {
 		return response.InternalError(err)
@@ -47,6 +59,32 @@ func containerState(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, state)
 }
 
+// instanceStateWait waits for the status of the instance to change, for up to timeout seconds (indefinitely if
+// -1) or until the context is cancelled.
+func instanceStateWait(ctx context.Context, inst instance.Instance, timeout int) {
+	if timeout >= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	status := inst.State()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if inst.State() != status {
+				return
+			}
+		}
+	}
+}
+
 func containerStatePut(d *Daemon, r *http.Request) response.Response {
 	instanceType, err := urlInstanceTypeDetect(r)
 	if err != nil {
@@ -83,6 +121,25 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	opType, do, err := instanceStateAction(d, c, raw)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{name}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, opType, resources, nil, do, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceStateAction returns the operation type and the function performing the requested state
+// change on the given instance.
+func instanceStateAction(d *Daemon, c instance.Instance, raw api.InstanceStatePut) (db.OperationType, func(*operations.Operation) error, error) {
 	var opType db.OperationType
 	var do func(*operations.Operation) error
 	switch shared.InstanceAction(raw.Action) {
@@ -90,7 +147,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		opType = db.OperationContainerStart
 		do = func(op *operations.Operation) error {
 			c.SetOperation(op)
-			if err = c.Start(raw.Stateful); err != nil {
+			if err := c.Start(raw.Stateful); err != nil {
 				return err
 			}
 			return nil
@@ -110,7 +167,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		} else if raw.Timeout == 0 || raw.Force {
 			do = func(op *operations.Operation) error {
 				c.SetOperation(op)
-				err = c.Stop(false)
+				err := c.Stop(false)
 				if err != nil {
 					return err
 				}
@@ -127,7 +184,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 					}
 				}
 
-				err = c.Shutdown(time.Duration(raw.Timeout) * time.Second)
+				err := c.Shutdown(time.Duration(raw.Timeout) * time.Second)
 				if err != nil {
 					return err
 				}
@@ -168,7 +225,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 			}
 
 			if raw.Timeout == 0 || raw.Force {
-				err = c.Stop(false)
+				err := c.Stop(false)
 				if err != nil {
 					return err
 				}
@@ -177,13 +234,13 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 					return fmt.Errorf("Instance is not running")
 				}
 
-				err = c.Shutdown(time.Duration(raw.Timeout) * time.Second)
+				err := c.Shutdown(time.Duration(raw.Timeout) * time.Second)
 				if err != nil {
 					return err
 				}
 			}
 
-			err = c.Start(false)
+			err := c.Start(false)
 			if err != nil {
 				return err
 			}
@@ -192,7 +249,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		}
 	case shared.Freeze:
 		if !d.os.CGInfo.Supports(cgroup.Freezer, nil) {
-			return response.BadRequest(fmt.Errorf("This system doesn't support freezing instances"))
+			return opType, nil, fmt.Errorf("This system doesn't support freezing instances")
 		}
 
 		opType = db.OperationContainerFreeze
@@ -202,7 +259,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		}
 	case shared.Unfreeze:
 		if !d.os.CGInfo.Supports(cgroup.Freezer, nil) {
-			return response.BadRequest(fmt.Errorf("This system doesn't support unfreezing instances"))
+			return opType, nil, fmt.Errorf("This system doesn't support unfreezing instances")
 		}
 
 		opType = db.OperationContainerUnfreeze
@@ -211,16 +268,8 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 			return c.Unfreeze()
 		}
 	default:
-		return response.BadRequest(fmt.Errorf("unknown action %s", raw.Action))
+		return opType, nil, fmt.Errorf("unknown action %s", raw.Action)
 	}
 
-	resources := map[string][]string{}
-	resources["containers"] = []string{name}
-
-	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, opType, resources, nil, do, nil, nil)
-	if err != nil {
-		return response.InternalError(err)
-	}
-
-	return operations.OperationResponse(op)
+	return opType, do, nil
 }
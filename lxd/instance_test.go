@@ -99,7 +99,7 @@ func (suite *containerTestSuite) TestContainer_ProfilesOverwriteDefaultNic() {
 		Name: "testFoo",
 	}
 
-	_, err := suite.d.State().Cluster.CreateNetwork("unknownbr0", "", db.NetworkTypeBridge, nil)
+	_, err := suite.d.State().Cluster.CreateNetwork("default", "unknownbr0", "", db.NetworkTypeBridge, nil)
 	suite.Req.Nil(err)
 
 	c, err := instanceCreateInternal(suite.d.State(), args)
@@ -133,7 +133,7 @@ func (suite *containerTestSuite) TestContainer_LoadFromDB() {
 	}
 	state := suite.d.State()
 
-	_, err := state.Cluster.CreateNetwork("unknownbr0", "", db.NetworkTypeBridge, nil)
+	_, err := state.Cluster.CreateNetwork("default", "unknownbr0", "", db.NetworkTypeBridge, nil)
 	suite.Req.Nil(err)
 
 	// Create the container
@@ -257,7 +257,7 @@ func (suite *containerTestSuite) TestContainer_findIdmap_isolated() {
 	map2, err := c2.(instance.Container).NextIdmap()
 	suite.Req.Nil(err)
 
-	host := suite.d.os.IdmapSet.Idmap[0]
+	host := suite.d.os.IdmapSet().Idmap[0]
 
 	for i := 0; i < 2; i++ {
 		suite.Req.Equal(host.Hostid+65536, map1.Idmap[i].Hostid, "hostids don't match %d", i)
@@ -298,7 +298,7 @@ func (suite *containerTestSuite) TestContainer_findIdmap_mixed() {
 	map2, err := c2.(instance.Container).NextIdmap()
 	suite.Req.Nil(err)
 
-	host := suite.d.os.IdmapSet.Idmap[0]
+	host := suite.d.os.IdmapSet().Idmap[0]
 
 	for i := 0; i < 2; i++ {
 		suite.Req.Equal(host.Hostid, map1.Idmap[i].Hostid, "hostids don't match %d", i)
@@ -328,7 +328,7 @@ func (suite *containerTestSuite) TestContainer_findIdmap_raw() {
 	map1, err := c1.(instance.Container).NextIdmap()
 	suite.Req.Nil(err)
 
-	host := suite.d.os.IdmapSet.Idmap[0]
+	host := suite.d.os.IdmapSet().Idmap[0]
 
 	for _, i := range []int{0, 3} {
 		suite.Req.Equal(host.Hostid, map1.Idmap[i].Hostid, "hostids don't match")
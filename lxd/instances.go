@@ -27,6 +27,7 @@ var instancesCmd = APIEndpoint{
 
 	Get:  APIEndpointAction{Handler: containersGet, AccessHandler: allowProjectPermission("containers", "view")},
 	Post: APIEndpointAction{Handler: containersPost, AccessHandler: allowProjectPermission("containers", "manage-containers")},
+	Put:  APIEndpointAction{Handler: instancesPut, AccessHandler: allowProjectPermission("containers", "operate-containers")},
 }
 
 var instanceCmd = APIEndpoint{
@@ -181,6 +182,17 @@ var instanceBackupExportCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: containerBackupExportGet, AccessHandler: allowProjectPermission("containers", "view")},
 }
 
+var instanceBackupVerifyCmd = APIEndpoint{
+	Name: "instanceBackupVerify",
+	Path: "instances/{name}/backups/{backupName}/verify",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerBackupVerify", Path: "containers/{name}/backups/{backupName}/verify"},
+		{Name: "vmBackupVerify", Path: "virtual-machines/{name}/backups/{backupName}/verify"},
+	},
+
+	Post: APIEndpointAction{Handler: containerBackupVerifyPost, AccessHandler: allowProjectPermission("containers", "operate-containers")},
+}
+
 type containerAutostartList []instance.Instance
 
 func (slice containerAutostartList) Len() int {
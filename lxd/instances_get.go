@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
@@ -45,6 +46,11 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 }
 
 func containersGet(d *Daemon, r *http.Request) response.Response {
+	_, _, err := util.Paginate(r, 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	for i := 0; i < 100; i++ {
 		result, err := doContainersGet(d, r)
 		if err == nil {
@@ -192,7 +198,7 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 				defer wg.Done()
 				cert := d.endpoints.NetworkCert()
 
-				if recursion == 1 {
+				if recursion < 2 {
 					cs, err := doContainersGetFromNode(project, address, cert, instanceType)
 					if err != nil {
 						for _, name := range containers {
@@ -299,7 +305,16 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 				resultString = append(resultString, url)
 			}
 		}
-		return resultString, nil
+
+		// Sort the result list so that pages are stable.
+		sort.Strings(resultString)
+
+		start, end, err := util.Paginate(r, len(resultString))
+		if err != nil {
+			return nil, err
+		}
+
+		return resultString[start:end], nil
 	}
 
 	if recursion == 1 {
@@ -310,7 +325,13 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 		if clauses != nil {
 			resultList = instance.Filter(resultList, clauses)
 		}
-		return resultList, nil
+
+		start, end, err := util.Paginate(r, len(resultList))
+		if err != nil {
+			return nil, err
+		}
+
+		return resultList[start:end], nil
 	}
 
 	// Sort the result list by name.
@@ -321,7 +342,13 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	if clauses != nil {
 		resultFullList = instance.FilterFull(resultFullList, clauses)
 	}
-	return resultFullList, nil
+
+	start, end, err := util.Paginate(r, len(resultFullList))
+	if err != nil {
+		return nil, err
+	}
+
+	return resultFullList[start:end], nil
 }
 
 // Fetch information about the containers on the given remote node, using the
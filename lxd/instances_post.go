@@ -89,6 +89,11 @@ func createFromImage(d *Daemon, project string, req *api.InstancesPost) response
 			}
 		}
 
+		err = imageCheckStatus(info)
+		if err != nil {
+			return err
+		}
+
 		args.Architecture, err = osarch.ArchitectureId(info.Architecture)
 		if err != nil {
 			return err
@@ -312,6 +317,7 @@ func createFromMigration(d *Daemon, project string, req *api.InstancesPost) resp
 		Live:         req.Source.Live,
 		InstanceOnly: instanceOnly,
 		Refresh:      req.Source.Refresh,
+		Resumable:    req.Source.Resumable,
 	}
 
 	sink, err := newMigrationSink(&migrationArgs)
@@ -323,6 +329,13 @@ func createFromMigration(d *Daemon, project string, req *api.InstancesPost) resp
 		opRevert := true
 		defer func() {
 			if opRevert && !req.Source.Refresh && inst != nil {
+				// Keep the partially transferred instance so that the copy can be retried as a
+				// refresh, as long as some of its data made it to the storage pool.
+				if req.Source.Resumable && shared.PathExists(inst.Path()) {
+					logger.Warn("Keeping partially transferred instance", log.Ctx{"project": project, "instance": inst.Name()})
+					return
+				}
+
 				inst.Delete()
 			}
 		}()
@@ -536,7 +549,15 @@ func createFromCopy(d *Daemon, project string, req *api.InstancesPost) response.
 	return operations.OperationResponse(op)
 }
 
-func createFromBackup(d *Daemon, project string, data io.Reader, pool string) response.Response {
+// instanceNameAllowed returns an error if the given instance name doesn't comply with the naming policy of the
+// project.
+func instanceNameAllowed(d *Daemon, projectName string, name string) error {
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return projecthelpers.AllowInstanceName(tx, projectName, name)
+	})
+}
+
+func createFromBackup(d *Daemon, project string, data io.Reader, pool string, verify bool) response.Response {
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -595,11 +616,47 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 	}
 	bInfo.Project = project
 
+	// Incremental backups are applied on top of an existing stopped instance that has the base snapshot.
+	if bInfo.DeltaFrom != "" {
+		inst, err := instance.LoadByProjectAndName(d.State(), project, bInfo.Name)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed loading instance %q to apply incremental backup to", bInfo.Name))
+		}
+
+		if inst.IsRunning() {
+			return response.BadRequest(fmt.Errorf("Instance %q must be stopped to apply an incremental backup", bInfo.Name))
+		}
+
+		_, err = instance.LoadByProjectAndName(d.State(), project, bInfo.Name+shared.SnapshotDelimiter+bInfo.DeltaFrom)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed loading base snapshot %q", bInfo.DeltaFrom))
+		}
+
+		instPool, err := inst.StoragePool()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if pool != "" && pool != instPool {
+			return response.BadRequest(fmt.Errorf("Incremental backups must be applied on the instance's storage pool %q", instPool))
+		}
+
+		pool = instPool
+	}
+
 	// Override pool.
 	if pool != "" {
 		bInfo.Pool = pool
 	}
 
+	// New instances must comply with the naming policy of the project.
+	if bInfo.DeltaFrom == "" {
+		err = instanceNameAllowed(d, project, bInfo.Name)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	logger.Debug("Backup file info loaded", log.Ctx{
 		"type":      bInfo.Type,
 		"name":      bInfo.Name,
@@ -644,6 +701,17 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 		defer backupFile.Close()
 		defer runRevert.Fail()
 
+		// Check the content of the backup against its manifest before restoring anything.
+		if verify {
+			logger.Debug("Verifying backup file")
+			err := backup.Verify(backupFile)
+			if err != nil {
+				return err
+			}
+
+			backupFile.Seek(0, 0)
+		}
+
 		pool, err := storagePools.GetPoolByName(d.State(), bInfo.Pool)
 		if err != nil {
 			return err
@@ -692,8 +760,10 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 			return errors.Wrap(err, "Load instance")
 		}
 
-		// Clean up created instance if the post hook fails below.
-		runRevert.Add(func() { inst.Delete() })
+		// Clean up created instance if the post hook fails below (unless it existed before).
+		if bInfo.DeltaFrom == "" {
+			runRevert.Add(func() { inst.Delete() })
+		}
 
 		// Run the storage post hook to perform any final actions now that the instance has been created
 		// in the database (this normally includes unmounting volumes that were mounted).
@@ -727,7 +797,7 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 
 	// If we're getting binary content, process separately
 	if r.Header.Get("Content-Type") == "application/octet-stream" {
-		return createFromBackup(d, project, r.Body, r.Header.Get("X-LXD-pool"))
+		return createFromBackup(d, project, r.Body, r.Header.Get("X-LXD-pool"), shared.IsTrue(r.Header.Get("X-LXD-verify")))
 	}
 
 	// Parse the request
@@ -748,20 +818,96 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	targetNode := queryParam(r, "target")
-	if targetNode == "" {
-		// If no target node was specified, pick the node with the
-		// least number of containers. If there's just one node, or if
-		// the selected node is the local one, this is effectively a
-		// no-op, since GetNodeWithLeastInstances() will return an empty
-		// string.
+	if targetNode == "" || strings.HasPrefix(targetNode, "@") {
+		// If no target node was specified, let the scheduler pick the
+		// least busy member, within the cluster group if one was
+		// specified using "@<group>" or the groups the project is
+		// restricted to, and among the members with the given label if
+		// one was specified using "@user.<key>=<value>". If the
+		// selected member is the local one, this is effectively a
+		// no-op.
 		architectures, err := instance.SuitableArchitectures(d.State(), project, req)
 		if err != nil {
 			return response.BadRequest(err)
 		}
+
+		var candidates []db.NodeInfo
+		targetGroup := strings.TrimPrefix(targetNode, "@")
+		var targetLabel []string
+		if strings.Contains(targetGroup, "=") {
+			targetLabel = strings.SplitN(targetGroup, "=", 2)
+			targetGroup = ""
+
+			if !strings.HasPrefix(targetLabel[0], "user.") {
+				return response.BadRequest(fmt.Errorf("Only user.* labels of cluster members can be used as targets"))
+			}
+		}
+
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			var err error
-			targetNode, err = tx.GetNodeWithLeastInstances(architectures)
-			return err
+			groups, err := projecthelpers.GetRestrictedClusterGroups(tx, project)
+			if err != nil {
+				return err
+			}
+
+			if targetGroup != "" {
+				_, _, err = tx.GetClusterGroup(targetGroup)
+				if err != nil {
+					if err == db.ErrNoSuchObject {
+						return fmt.Errorf("No cluster group called '%s'", targetGroup)
+					}
+
+					return err
+				}
+
+				if groups != nil && !shared.StringInSlice(targetGroup, groups) {
+					return fmt.Errorf("Cluster group %s isn't allowed in project %s", targetGroup, project)
+				}
+
+				groups = []string{targetGroup}
+			}
+
+			candidates, err = tx.GetCandidateMembers(architectures, groups)
+			if err != nil {
+				return err
+			}
+
+			if targetLabel != nil {
+				labelled := []db.NodeInfo{}
+				for _, candidate := range candidates {
+					config, err := tx.GetNodeConfig(candidate.ID)
+					if err != nil {
+						return err
+					}
+
+					if config[targetLabel[0]] == targetLabel[1] {
+						labelled = append(labelled, candidate)
+					}
+				}
+
+				if len(labelled) == 0 {
+					return fmt.Errorf("No suitable cluster member available with %s=%s", targetLabel[0], targetLabel[1])
+				}
+
+				candidates = labelled
+			}
+
+			if len(candidates) == 0 && groups != nil {
+				return fmt.Errorf("No suitable cluster member available in the cluster groups %s", strings.Join(groups, ", "))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		targetNode, err = instancePlacement(d, project, &req, candidates)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	} else {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return projecthelpers.AllowClusterMember(tx, project, targetNode)
 		})
 		if err != nil {
 			return response.SmartError(err)
@@ -860,10 +1006,16 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 				return err
 			}
 
+			// Generated names get the prefix and suffix required by the project.
+			p, err := tx.GetProject(project)
+			if err != nil {
+				return err
+			}
+
 			i := 0
 			for {
 				i++
-				req.Name = strings.ToLower(petname.Generate(2, "-"))
+				req.Name = p.Config["instances.name.prefix"] + strings.ToLower(petname.Generate(2, "-")) + p.Config["instances.name.suffix"]
 				if !shared.StringInSlice(req.Name, names) {
 					break
 				}
@@ -875,7 +1027,8 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 
 			logger.Debugf("No name provided, creating %s", req.Name)
 		}
-		return nil
+
+		return projecthelpers.AllowInstanceName(tx, project, req.Name)
 	})
 	if err != nil {
 		return response.SmartError(err)
@@ -918,13 +1071,13 @@ func containerFindStoragePool(d *Daemon, project string, req *api.InstancesPost)
 
 	// If we don't have a valid pool yet, look through profiles
 	if storagePool == "" {
-		for _, pName := range req.Profiles {
-			_, p, err := d.cluster.GetProfile(project, pName)
-			if err != nil {
-				return "", "", "", nil, response.SmartError(err)
-			}
+		profiles, err := d.cluster.GetProfiles(project, req.Profiles)
+		if err != nil {
+			return "", "", "", nil, response.SmartError(err)
+		}
 
-			k, v, _ := shared.GetRootDiskDevice(p.Devices)
+		for i, pName := range req.Profiles {
+			k, v, _ := shared.GetRootDiskDevice(profiles[i].Devices)
 			if k != "" && v["pool"] != "" {
 				// Keep going as we want the last one in the profile chain
 				storagePool = v["pool"]
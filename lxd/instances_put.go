@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/filter"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// instancesPut changes the state of many instances at once.
+//
+// The instances are selected by name and/or through the filter query parameter, all the instances of
+// the project being selected otherwise. The state changes are performed as a single operation whose
+// "results" metadata maps each instance name to an empty string on success or the error message.
+func instancesPut(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+
+	req := api.InstancesPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.State == nil {
+		return response.BadRequest(fmt.Errorf("No state change requested"))
+	}
+
+	actions := []string{string(shared.Start), string(shared.Stop), string(shared.Restart), string(shared.Freeze), string(shared.Unfreeze)}
+	if !shared.StringInSlice(req.State.Action, actions) {
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.State.Action))
+	}
+
+	// Parse filter value
+	filterStr := r.FormValue("filter")
+	var clauses []filter.Clause
+	if filterStr != "" {
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
+	// Don't mess with instances while in setup mode
+	<-d.readyChan
+
+	// Get the list and location of all instances
+	var instances map[string][]string // Instances by node address
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		instances, err = tx.GetInstanceNamesByNodeAddress(project, instanceType)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// If this is an internal request from another cluster node, only act on
+	// the instances of this node.
+	if isClusterNotification(r) {
+		instances = map[string][]string{"": instances[""]}
+	}
+
+	// Restrict the selection to the requested instances
+	if len(req.Instances) > 0 {
+		found := map[string]bool{}
+		for address, names := range instances {
+			selected := []string{}
+			for _, name := range names {
+				if shared.StringInSlice(name, req.Instances) {
+					selected = append(selected, name)
+					found[name] = true
+				}
+			}
+
+			instances[address] = selected
+		}
+
+		for _, name := range req.Instances {
+			if !found[name] {
+				return response.NotFound(fmt.Errorf("Instance %q not found", name))
+			}
+		}
+	}
+
+	if clauses != nil {
+		instances, err = instancesPutFilter(d, project, instanceType, instances, clauses)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{}
+	for _, names := range instances {
+		resources["containers"] = append(resources["containers"], names...)
+	}
+
+	run := func(op *operations.Operation) error {
+		results := map[string]string{}
+		resultsMu := sync.Mutex{}
+		setResult := func(name string, err error) {
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+
+			results[name] = ""
+			if err != nil {
+				results[name] = err.Error()
+			}
+		}
+
+		wg := sync.WaitGroup{}
+		for address, names := range instances {
+			// Instances on unavailable nodes can't be acted on
+			if address == "0.0.0.0" {
+				for _, name := range names {
+					setResult(name, fmt.Errorf("Cluster member is unavailable"))
+				}
+
+				continue
+			}
+
+			// Instances on other nodes are handled by their node
+			if address != "" {
+				if len(names) == 0 {
+					continue
+				}
+
+				wg.Add(1)
+				go func(address string, names []string) {
+					defer wg.Done()
+					instancesPutOnNode(d, project, address, api.InstancesPut{State: req.State, Instances: names}, setResult)
+				}(address, names)
+
+				continue
+			}
+
+			for _, name := range names {
+				wg.Add(1)
+				go func(name string) {
+					defer wg.Done()
+					setResult(name, instanceStateChange(d, op, project, name, *req.State))
+				}(name)
+			}
+		}
+		wg.Wait()
+
+		err := op.UpdateMetadata(map[string]interface{}{"results": results})
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, result := range results {
+			if result != "" {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("Failed to %s %d out of %d instances", req.State.Action, failed, len(results))
+		}
+
+		return nil
+	}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationInstancesStateUpdate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceStateChange performs the requested state change on a local instance, as part of the given operation.
+func instanceStateChange(d *Daemon, op *operations.Operation, project string, name string, req api.InstanceStatePut) error {
+	inst, err := instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return err
+	}
+
+	_, do, err := instanceStateAction(d, inst, req)
+	if err != nil {
+		return err
+	}
+
+	return do(op)
+}
+
+// instancesPutOnNode forwards a bulk state change to the node with the given address and
+// reports the result for each of the instances through setResult.
+func instancesPutOnNode(d *Daemon, project string, address string, req api.InstancesPut, setResult func(string, error)) {
+	setAll := func(err error) {
+		for _, name := range req.Instances {
+			setResult(name, err)
+		}
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), true)
+	if err != nil {
+		setAll(errors.Wrapf(err, "Failed to connect to node %s", address))
+		return
+	}
+
+	client = client.UseProject(project)
+
+	op, err := client.UpdateInstances(req, "")
+	if err != nil {
+		setAll(err)
+		return
+	}
+
+	waitErr := op.Wait()
+
+	// Use the per-instance results of the remote operation, if any
+	results, _ := op.Get().Metadata["results"].(map[string]interface{})
+	for _, name := range req.Instances {
+		result, ok := results[name].(string)
+		if !ok {
+			if waitErr == nil {
+				waitErr = fmt.Errorf("No result reported by node %s", address)
+			}
+
+			setResult(name, waitErr)
+			continue
+		}
+
+		if result != "" {
+			setResult(name, fmt.Errorf("%s", result))
+			continue
+		}
+
+		setResult(name, nil)
+	}
+}
+
+// instancesPutFilter returns the instances matching the given filter clauses, by node address.
+func instancesPutFilter(d *Daemon, project string, instanceType instancetype.Type, instances map[string][]string, clauses []filter.Clause) (map[string][]string, error) {
+	result := map[string][]string{}
+
+	for address, names := range instances {
+		if len(names) == 0 || address == "0.0.0.0" {
+			continue
+		}
+
+		rendered := []*api.Instance{}
+		if address == "" {
+			for _, name := range names {
+				inst, err := instance.LoadByProjectAndName(d.State(), project, name)
+				if err != nil {
+					return nil, err
+				}
+
+				c, _, err := inst.Render()
+				if err != nil {
+					return nil, err
+				}
+
+				rendered = append(rendered, c.(*api.Instance))
+			}
+		} else {
+			cs, err := doContainersGetFromNode(project, address, d.endpoints.NetworkCert(), instanceType)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range cs {
+				if shared.StringInSlice(cs[i].Name, names) {
+					rendered = append(rendered, &cs[i])
+				}
+			}
+		}
+
+		for _, c := range instance.Filter(rendered, clauses) {
+			result[address] = append(result[address], c.Name)
+		}
+	}
+
+	return result, nil
+}
@@ -125,6 +125,10 @@ func main() {
 	forkDNSCmd := cmdForkDNS{global: &globalCmd}
 	app.AddCommand(forkDNSCmd.Command())
 
+	// forkbinfmt sub-command
+	forkbinfmtCmd := cmdForkbinfmt{global: &globalCmd}
+	app.AddCommand(forkbinfmtCmd.Command())
+
 	// forkexec sub-command
 	forkexecCmd := cmdForkexec{global: &globalCmd}
 	app.AddCommand(forkexecCmd.Command())
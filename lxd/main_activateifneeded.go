@@ -87,11 +87,13 @@ func (c *cmdActivateifneeded) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Load the idmap for unprivileged instances
-	d.os.IdmapSet, err = idmap.DefaultIdmapSet("", "")
+	idmapSet, err := idmap.DefaultIdmapSet("", "")
 	if err != nil {
 		return err
 	}
 
+	d.os.SetIdmapSet(idmapSet)
+
 	// Look for auto-started or previously started instances
 	path = d.os.GlobalDatabasePath()
 	if !shared.PathExists(path) {
@@ -156,6 +158,25 @@ func (c *cmdActivateifneeded) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Check for scheduled storage pool scrubs
+	poolNames, err := d.cluster.GetStoragePoolNames()
+	if err != nil && err != db.ErrNoSuchObject {
+		return err
+	}
+
+	for _, poolName := range poolNames {
+		_, pool, err := d.cluster.GetStoragePool(poolName)
+		if err != nil {
+			return err
+		}
+
+		if pool.Config["scrub.schedule"] != "" {
+			logger.Debugf("Daemon has scheduled storage pool scrubs, activating...")
+			_, err := lxd.ConnectLXDUnix("", nil)
+			return err
+		}
+	}
+
 	logger.Debugf("No need to start the daemon now")
 	return nil
 }
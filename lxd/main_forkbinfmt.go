@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+type cmdForkbinfmt struct {
+	global *cmdGlobal
+}
+
+func (c *cmdForkbinfmt) Command() *cobra.Command {
+	// Main subcommand
+	cmd := &cobra.Command{}
+	cmd.Use = "forkbinfmt <architecture>=<interpreter>..."
+	cmd.Short = "Register binfmt_misc handlers inside the container"
+	cmd.Long = `Description:
+  Register binfmt_misc handlers inside the container
+
+  This internal command is run as an LXC mount hook. It mounts a binfmt_misc
+  instance in the container and registers a handler running the binaries of
+  each given architecture through the given qemu-user interpreter.
+`
+	cmd.RunE = c.Run
+	cmd.Hidden = true
+
+	return cmd
+}
+
+func (c *cmdForkbinfmt) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	if len(args) == 0 {
+		cmd.Help()
+
+		return fmt.Errorf("Missing required arguments")
+	}
+
+	rootfs := os.Getenv("LXC_ROOTFS_MOUNT")
+	if rootfs == "" {
+		return fmt.Errorf("This must be run as an LXC mount hook")
+	}
+
+	// Mount a binfmt_misc instance in the container, the handlers registered in it are then only used by the
+	// processes of the container.
+	path := filepath.Join(rootfs, "proc", "sys", "fs", "binfmt_misc")
+	err := unix.Mount("binfmt_misc", path, "binfmt_misc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	if err != nil {
+		return fmt.Errorf("Failed to mount binfmt_misc in the container, the kernel must support binfmt_misc in user namespaces: %v", err)
+	}
+
+	for _, arg := range args {
+		fields := strings.SplitN(arg, "=", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("Invalid handler %q", arg)
+		}
+
+		arch, err := osarch.ArchitectureId(fields[0])
+		if err != nil {
+			return err
+		}
+
+		registration, err := util.BinfmtRegistration(arch, fields[1])
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filepath.Join(path, "register"), []byte(registration), 0)
+		if err != nil {
+			return fmt.Errorf("Failed to register binfmt_misc handler for %q: %v", fields[0], err)
+		}
+	}
+
+	return nil
+}
@@ -85,7 +85,8 @@ void forknet(void)
 		forkdonetinfo(ns_fd);
 	}
 
-	if (strcmp(command, "detach") == 0)
+	// The address subcommand also attaches through the netns file.
+	if (strcmp(command, "detach") == 0 || strcmp(command, "address") == 0)
 		forkdonetdetach(cur);
 }
 */
@@ -123,6 +124,13 @@ func (c *cmdForknet) Command() *cobra.Command {
 	cmdDetach.RunE = c.RunDetach
 	cmd.AddCommand(cmdDetach)
 
+	// address
+	cmdAddress := &cobra.Command{}
+	cmdAddress.Use = "address <netns file> <ifname> <add|del> <address>"
+	cmdAddress.Args = cobra.ExactArgs(4)
+	cmdAddress.RunE = c.RunAddress
+	cmd.AddCommand(cmdAddress)
+
 	return cmd
 }
 
@@ -174,3 +182,24 @@ func (c *cmdForknet) RunDetach(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func (c *cmdForknet) RunAddress(cmd *cobra.Command, args []string) error {
+	ifName := args[1]
+	action := args[2]
+	address := args[3]
+
+	if ifName == "" {
+		return fmt.Errorf("ifname argument is required")
+	}
+
+	if !shared.StringInSlice(action, []string{"add", "del"}) {
+		return fmt.Errorf("Invalid action %q", action)
+	}
+
+	_, err := shared.RunCommand("ip", "address", action, address, "dev", ifName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
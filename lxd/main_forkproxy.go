@@ -1,14 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/lxc/lxd/lxd/device"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/netutils"
 )
 
@@ -28,6 +32,7 @@ import (
 #endif
 #include <errno.h>
 #include <fcntl.h>
+#include <libgen.h>
 #include <stdbool.h>
 #include <stdint.h>
 #include <stdio.h>
@@ -53,6 +58,7 @@ int whoami = -ESRCH;
 #define FORKPROXY_CHILD 1
 #define FORKPROXY_PARENT 0
 #define FORKPROXY_UDS_SOCK_FD_NUM 200
+#define FORKPROXY_STATS_FD_NUM 201
 
 static int switch_uid_gid(uint32_t uid, uint32_t gid)
 {
@@ -82,11 +88,12 @@ again:
 void forkproxy(void)
 {
 	unsigned int needs_mntns = 0;
-	int connect_pid, connect_pidfd, listen_pid, listen_pidfd, log_fd;
+	int connect_pid, connect_pidfd, listen_pid, listen_pidfd, log_fd, stats_fd;
 	size_t unix_prefix_len = sizeof("unix:") - 1;
 	ssize_t ret;
 	pid_t pid;
-	char *connect_addr, *cur, *listen_addr, *log_path, *pid_path;
+	char *connect_addr, *cur, *listen_addr, *log_path, *pid_path, *stats_path;
+	__do_free char *stats_dir = NULL;
 	int sk_fds[2] = {-EBADF, -EBADF};
 	FILE *pid_file;
 
@@ -105,6 +112,7 @@ void forkproxy(void)
 	connect_addr = advance_arg(true);
 	log_path = advance_arg(true);
 	pid_path = advance_arg(true);
+	stats_path = advance_arg(true);
 
 	close(STDIN_FILENO);
 	log_fd = open(log_path, O_WRONLY | O_CREAT | O_CLOEXEC | O_TRUNC, 0600);
@@ -127,6 +135,20 @@ void forkproxy(void)
 		_exit(EXIT_FAILURE);
 	}
 
+	// The stats file gets replaced rather than written to, so keep its
+	// directory around for after the mount namespace has been switched.
+	stats_dir = strdup(stats_path);
+	if (!stats_dir)
+		_exit(EXIT_FAILURE);
+
+	stats_fd = open(dirname(stats_dir), O_PATH | O_DIRECTORY | O_CLOEXEC);
+	if (stats_fd < 0) {
+		fprintf(stderr,
+			"%s - Failed to open stats directory for proxy daemon\n",
+			strerror(errno));
+		_exit(EXIT_FAILURE);
+	}
+
 	if (strncmp(listen_addr, "udp:", sizeof("udp:") - 1) == 0 &&
 	    strncmp(connect_addr, "udp:", sizeof("udp:") - 1) != 0) {
 		    fprintf(stderr, "Error: Proxying from udp to non-udp protocol is not supported\n");
@@ -164,6 +186,7 @@ void forkproxy(void)
 		whoami = FORKPROXY_CHILD;
 
 		fclose(pid_file);
+		close(stats_fd);
 		ret = close(sk_fds[0]);
 		if (ret < 0)
 			fprintf(stderr, "%s - Failed to close fd %d\n",
@@ -251,6 +274,16 @@ void forkproxy(void)
 			fprintf(stderr, "%s - Failed to close fd %d\n",
 				strerror(errno), sk_fds[0]);
 
+		ret = dup3(stats_fd, FORKPROXY_STATS_FD_NUM, O_CLOEXEC);
+		if (ret < 0) {
+			fprintf(stderr,
+				"%s - Failed to duplicate fd %d to fd 201\n",
+				strerror(errno), stats_fd);
+			_exit(EXIT_FAILURE);
+		}
+
+		close(stats_fd);
+
 		// Usually we should wait for the child process somewhere here.
 		// But we cannot really do this. The listener file descriptors
 		// are retrieved in the go runtime but at that point we have
@@ -307,6 +340,7 @@ void forkproxy(void)
 import "C"
 
 const forkproxyUDSSockFDNum int = C.FORKPROXY_UDS_SOCK_FD_NUM
+const forkproxyStatsFDNum int = C.FORKPROXY_STATS_FD_NUM
 
 type cmdForkproxy struct {
 	global *cmdGlobal
@@ -323,10 +357,62 @@ type udpSession struct {
 	timerLock sync.Mutex
 }
 
+// Connection and traffic counters of the proxy, periodically written to the stats file for LXD to report.
+var proxyStats api.InstanceStateProxy
+
+// writeProxyStats writes the counters of the proxy to the stats file with the given name every few seconds. The
+// file is replaced through a rename so that it's never read partially written.
+func writeProxyStats(name string) {
+	for {
+		stats := api.InstanceStateProxy{
+			ConnectionsTotal:  atomic.LoadInt64(&proxyStats.ConnectionsTotal),
+			ConnectionsActive: atomic.LoadInt64(&proxyStats.ConnectionsActive),
+			BytesReceived:     atomic.LoadInt64(&proxyStats.BytesReceived),
+			BytesSent:         atomic.LoadInt64(&proxyStats.BytesSent),
+		}
+
+		err := writeProxyStatsFile(name, stats)
+		if err != nil {
+			fmt.Printf("Warning: Failed to write proxy stats: %v\n", err)
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// writeProxyStatsFile writes the given counters to a temporary file of the stats directory and renames it over
+// the stats file with the given name.
+func writeProxyStatsFile(name string, stats api.InstanceStateProxy) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	tmpName := "." + name + ".tmp"
+	fd, err := unix.Openat(forkproxyStatsFDNum, tmpName, unix.O_WRONLY|unix.O_CREAT|unix.O_TRUNC|unix.O_CLOEXEC, 0600)
+	if err != nil {
+		return err
+	}
+
+	f := os.NewFile(uintptr(fd), tmpName)
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return unix.Renameat(forkproxyStatsFDNum, tmpName, forkproxyStatsFDNum, name)
+}
+
 func (c *cmdForkproxy) Command() *cobra.Command {
 	// Main subcommand
 	cmd := &cobra.Command{}
-	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect address> <log path> <pid path> <listen gid> <listen uid> <listen mode> <security gid> <security uid>"
+	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect address> <log path> <pid path> <stats path> <listen gid> <listen uid> <listen mode> <security gid> <security uid> <proxy protocol>"
 	cmd.Short = "Setup network connection proxying"
 	cmd.Long = `Description:
   Setup network connection proxying
@@ -335,7 +421,7 @@ func (c *cmdForkproxy) Command() *cobra.Command {
   container, connecting one side to the host and the other to the
   container.
 `
-	cmd.Args = cobra.ExactArgs(14)
+	cmd.Args = cobra.ExactArgs(15)
 	cmd.RunE = c.Run
 	cmd.Hidden = true
 
@@ -432,13 +518,19 @@ func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *devic
 		}
 	}
 
-	if cAddr.ConnType == "unix" && lAddr.ConnType == "unix" {
-		// Handle OOB if both src and dst are using unix sockets
-		go unixRelay(srcConn, dstConn)
-	} else {
+	atomic.AddInt64(&proxyStats.ConnectionsTotal, 1)
+	atomic.AddInt64(&proxyStats.ConnectionsActive, 1)
 
-		go genericRelay(srcConn, dstConn, false)
-	}
+	go func() {
+		if cAddr.ConnType == "unix" && lAddr.ConnType == "unix" {
+			// Handle OOB if both src and dst are using unix sockets
+			unixRelay(srcConn, dstConn)
+		} else {
+			genericRelay(srcConn, dstConn, false)
+		}
+
+		atomic.AddInt64(&proxyStats.ConnectionsActive, -1)
+	}()
 
 	return nil
 }
@@ -456,7 +548,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Sanity checks
-	if len(args) != 14 {
+	if len(args) != 15 {
 		cmd.Help()
 
 		if len(args) == 0 {
@@ -526,16 +618,16 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 			var err error
 
 			listenAddrGID := -1
-			if args[8] != "" {
-				listenAddrGID, err = strconv.Atoi(args[8])
+			if args[9] != "" {
+				listenAddrGID, err = strconv.Atoi(args[9])
 				if err != nil {
 					return err
 				}
 			}
 
 			listenAddrUID := -1
-			if args[9] != "" {
-				listenAddrUID, err = strconv.Atoi(args[9])
+			if args[10] != "" {
+				listenAddrUID, err = strconv.Atoi(args[10])
 				if err != nil {
 					return err
 				}
@@ -549,8 +641,8 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 			}
 
 			var listenAddrMode os.FileMode
-			if args[10] != "" {
-				tmp, err := strconv.ParseUint(args[10], 8, 0)
+			if args[11] != "" {
+				tmp, err := strconv.ParseUint(args[11], 8, 0)
 				if err != nil {
 					return err
 				}
@@ -618,16 +710,16 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 
 	// Drop privilege if requested
 	gid := uint64(0)
-	if args[11] != "" {
-		gid, err = strconv.ParseUint(args[11], 10, 32)
+	if args[12] != "" {
+		gid, err = strconv.ParseUint(args[12], 10, 32)
 		if err != nil {
 			return err
 		}
 	}
 
 	uid := uint64(0)
-	if args[12] != "" {
-		uid, err = strconv.ParseUint(args[12], 10, 32)
+	if args[13] != "" {
+		uid, err = strconv.ParseUint(args[13], 10, 32)
 		if err != nil {
 			return err
 		}
@@ -688,6 +780,8 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	go writeProxyStats(filepath.Base(args[8]))
+
 	// This line is used by LXD to check forkproxy has started OK.
 	fmt.Println("Status: Started")
 
@@ -707,7 +801,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			err := listenerInstance(epFd, lAddr, cAddr, curFd, srcConn, args[13] == "true")
+			err := listenerInstance(epFd, lAddr, cAddr, curFd, srcConn, args[14] == "true")
 			if err != nil {
 				fmt.Printf("Warning: Failed to prepare new listener instance: %s\n", err)
 			}
@@ -718,7 +812,8 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func proxyCopy(dst net.Conn, src net.Conn) error {
+// proxyCopy copies data from src to dst, adding the number of bytes copied to counter.
+func proxyCopy(dst net.Conn, src net.Conn, counter *int64) error {
 	var err error
 
 	// Attempt casting to UDP connections
@@ -755,9 +850,13 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 					udpSessions[addr.String()] = us
 					udpSessionsLock.Unlock()
 
-					go proxyCopy(src, dc)
+					atomic.AddInt64(&proxyStats.ConnectionsTotal, 1)
+					atomic.AddInt64(&proxyStats.ConnectionsActive, 1)
+
+					go proxyCopy(src, dc, &proxyStats.BytesSent)
 					us.timer = time.AfterFunc(30*time.Minute, func() {
 						us.target.Close()
+						atomic.AddInt64(&proxyStats.ConnectionsActive, -1)
 
 						udpSessionsLock.Lock()
 						delete(udpSessions, addr.String())
@@ -812,6 +911,10 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 				nw, ew = dst.Write(buf[0:nr])
 			}
 
+			if nw > 0 {
+				atomic.AddInt64(counter, int64(nw))
+			}
+
 			// keep retrying on EAGAIN
 			errno, ok := shared.GetErrno(ew)
 			if ok && (errno == unix.EAGAIN) {
@@ -840,19 +943,19 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 }
 
 func genericRelay(dst net.Conn, src net.Conn, timeout bool) {
-	relayer := func(src net.Conn, dst net.Conn, ch chan error) {
-		ch <- proxyCopy(src, dst)
+	relayer := func(src net.Conn, dst net.Conn, ch chan error, counter *int64) {
+		ch <- proxyCopy(src, dst, counter)
 		close(ch)
 	}
 
 	chSend := make(chan error)
 	chRecv := make(chan error)
 
-	go relayer(src, dst, chRecv)
+	go relayer(src, dst, chRecv, &proxyStats.BytesReceived)
 
 	_, isUDP := dst.(*net.UDPConn)
 	if !isUDP {
-		go relayer(dst, src, chSend)
+		go relayer(dst, src, chSend, &proxyStats.BytesSent)
 	}
 
 	select {
@@ -877,7 +980,7 @@ func genericRelay(dst net.Conn, src net.Conn, timeout bool) {
 	<-chRecv
 }
 
-func unixRelayer(src *net.UnixConn, dst *net.UnixConn, ch chan error) {
+func unixRelayer(src *net.UnixConn, dst *net.UnixConn, ch chan error, counter *int64) {
 	dataBuf := make([]byte, 4096)
 	oobBuf := make([]byte, 4096)
 
@@ -923,6 +1026,8 @@ func unixRelayer(src *net.UnixConn, dst *net.UnixConn, ch chan error) {
 			return
 		}
 
+		atomic.AddInt64(counter, int64(tData))
+
 		if sData != tData || sOob != tOob {
 			ch <- fmt.Errorf("Lost oob data during transfer")
 			return
@@ -943,10 +1048,10 @@ func unixRelayer(src *net.UnixConn, dst *net.UnixConn, ch chan error) {
 
 func unixRelay(dst io.ReadWriteCloser, src io.ReadWriteCloser) {
 	chSend := make(chan error)
-	go unixRelayer(dst.(*net.UnixConn), src.(*net.UnixConn), chSend)
+	go unixRelayer(dst.(*net.UnixConn), src.(*net.UnixConn), chSend, &proxyStats.BytesReceived)
 
 	chRecv := make(chan error)
-	go unixRelayer(src.(*net.UnixConn), dst.(*net.UnixConn), chRecv)
+	go unixRelayer(src.(*net.UnixConn), dst.(*net.UnixConn), chRecv, &proxyStats.BytesSent)
 
 	select {
 	case errSnd := <-chSend:
@@ -136,7 +136,34 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		if cli.AskBool("Are you joining an existing cluster? (yes/no) [default=no]: ", "no") {
 			// Existing cluster
 			config.Cluster.ServerAddress = serverAddress
-			for {
+
+			// Join token
+			usingToken := false
+			if cli.AskBool("Do you have a join token? (yes/no) [default=no]: ", "no") {
+				for {
+					joinToken, err := clusterJoinTokenDecode(cli.AskString("Please provide join token: ", "", nil))
+					if err != nil {
+						fmt.Printf("Invalid join token: %v\n", err)
+						continue
+					}
+
+					if joinToken.ServerName != config.Cluster.ServerName {
+						fmt.Printf("Using the server name %q from the join token\n", joinToken.ServerName)
+						config.Cluster.ServerName = joinToken.ServerName
+					}
+
+					config.Cluster.ClusterAddress, config.Cluster.ClusterCertificate, err = clusterJoinTokenResolve(joinToken)
+					if err != nil {
+						return err
+					}
+
+					config.Cluster.ClusterPassword = joinToken.Secret
+					usingToken = true
+					break
+				}
+			}
+
+			for !usingToken {
 				// Cluster URL
 				clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
 				_, _, err := net.SplitHostPort(clusterAddress)
@@ -188,6 +215,11 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 				return errors.Wrap(err, "Failed to setup trust relationship with cluster")
 			}
 
+			// Join tokens are single-use and the trust relationship is now setup.
+			if usingToken {
+				config.Cluster.ClusterPassword = ""
+			}
+
 			// Client parameters to connect to the target cluster node.
 			args := &lxd.ConnectionArgs{
 				TLSClientCert: string(cert.PublicKey()),
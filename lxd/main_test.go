@@ -31,10 +31,10 @@ func mockStartDaemon() (*Daemon, error) {
 		return nil, err
 	}
 
-	d.os.IdmapSet = &idmap.IdmapSet{Idmap: []idmap.IdmapEntry{
+	d.os.SetIdmapSet(&idmap.IdmapSet{Idmap: []idmap.IdmapEntry{
 		{Isuid: true, Hostid: 100000, Nsid: 0, Maprange: 500000},
 		{Isgid: true, Hostid: 100000, Nsid: 0, Maprange: 500000},
-	}}
+	}})
 
 	return d, nil
 }
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricType represents the type of a metric.
+type MetricType string
+
+const (
+	// Counter is a metric which only ever goes up.
+	Counter MetricType = "counter"
+
+	// Gauge is a metric which can go up and down.
+	Gauge MetricType = "gauge"
+)
+
+// Sample is a single value of a metric along with its labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+type metric struct {
+	metricType MetricType
+	help       string
+	samples    []Sample
+}
+
+// MetricSet is a set of metrics rendered in the Prometheus text exposition format.
+type MetricSet struct {
+	metrics map[string]*metric
+	labels  map[string]string
+}
+
+// NewMetricSet returns a new metric set with labels which are added to all its samples.
+func NewMetricSet(labels map[string]string) *MetricSet {
+	return &MetricSet{
+		metrics: map[string]*metric{},
+		labels:  labels,
+	}
+}
+
+// Add adds a sample to the metric with the given name, creating the metric if needed.
+func (m *MetricSet) Add(name string, metricType MetricType, help string, value float64, labels map[string]string) {
+	entry, ok := m.metrics[name]
+	if !ok {
+		entry = &metric{metricType: metricType, help: help}
+		m.metrics[name] = entry
+	}
+
+	sampleLabels := make(map[string]string, len(m.labels)+len(labels))
+	for k, v := range m.labels {
+		sampleLabels[k] = v
+	}
+
+	for k, v := range labels {
+		sampleLabels[k] = v
+	}
+
+	entry.samples = append(entry.samples, Sample{Labels: sampleLabels, Value: value})
+}
+
+// String renders the metric set in the Prometheus text exposition format. Metrics are sorted by name and samples
+// by labels to keep the output stable between scrapes.
+func (m *MetricSet) String() string {
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		entry := m.metrics[name]
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, escape(entry.help, false))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, entry.metricType)
+
+		lines := make([]string, 0, len(entry.samples))
+		for _, sample := range entry.samples {
+			lines = append(lines, fmt.Sprintf("%s%s %s\n", name, renderLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64)))
+		}
+
+		sort.Strings(lines)
+		for _, line := range lines {
+			b.WriteString(line)
+		}
+	}
+
+	return b.String()
+}
+
+// renderLabels returns the labels sorted by name in the exposition format.
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", k, escape(labels[k], true)))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+// escape escapes backslashes and line feeds, along with double quotes in label values.
+func escape(value string, quotes bool) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	if quotes {
+		value = strings.Replace(value, `"`, `\"`, -1)
+	}
+
+	return value
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricSet(t *testing.T) {
+	m := NewMetricSet(map[string]string{"project": "default"})
+
+	m.Add("lxd_memory_usage_bytes", Gauge, "Memory usage", 1024, map[string]string{"name": "c1"})
+	m.Add("lxd_cpu_seconds_total", Counter, "CPU time", 2, map[string]string{"name": "c2"})
+	m.Add("lxd_cpu_seconds_total", Counter, "CPU time", 1.5, map[string]string{"name": "c1"})
+
+	expected := `# HELP lxd_cpu_seconds_total CPU time
+# TYPE lxd_cpu_seconds_total counter
+lxd_cpu_seconds_total{name="c1",project="default"} 1.5
+lxd_cpu_seconds_total{name="c2",project="default"} 2
+# HELP lxd_memory_usage_bytes Memory usage
+# TYPE lxd_memory_usage_bytes gauge
+lxd_memory_usage_bytes{name="c1",project="default"} 1024
+`
+
+	assert.Equal(t, expected, m.String())
+}
+
+func TestMetricSet_Escaping(t *testing.T) {
+	m := NewMetricSet(nil)
+
+	m.Add("lxd_goroutines", Gauge, "Number of goroutines", 10, nil)
+	m.Add("lxd_test", Gauge, "Help with a \\ and\na line feed", 1, map[string]string{"name": "a \"b\" \\c"})
+
+	expected := `# HELP lxd_goroutines Number of goroutines
+# TYPE lxd_goroutines gauge
+lxd_goroutines 10
+# HELP lxd_test Help with a \\ and\na line feed
+# TYPE lxd_test gauge
+lxd_test{name="a \"b\" \\c"} 1
+`
+
+	assert.Equal(t, expected, m.String())
+}
@@ -18,11 +18,15 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
 
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/idmap"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -44,6 +48,21 @@ type migrationFields struct {
 
 	// storage specific fields
 	volumeOnly bool
+
+	// transport specific fields
+	bandwidthLimit int64
+}
+
+// setBandwidthLimit sets the bandwidth limit of the migration transfer to the lowest of the requested
+// limit and the server wide migration.max_bandwidth.
+func (c *migrationFields) setBandwidthLimit(s *state.State, requested string) error {
+	max, err := cluster.ConfigGetString(s.Cluster, "migration.max_bandwidth")
+	if err != nil {
+		return err
+	}
+
+	c.bandwidthLimit, err = util.BandwidthLimit(requested, max)
+	return err
 }
 
 func (c *migrationFields) send(m proto.Message) error {
@@ -223,12 +242,7 @@ func (s *migrationSourceWs) ConnectTarget(certificate string, operation string,
 			return fmt.Errorf("Unknown secret provided: %s", name)
 		}
 
-		query := url.Values{"secret": []string{secret}}
-
-		// The URL is a https URL to the operation, mangle to be a wss URL to the secret
-		wsUrl := fmt.Sprintf("wss://%s/websocket?%s", strings.TrimPrefix(operation, "https://"), query.Encode())
-
-		wsConn, _, err := dialer.Dial(wsUrl, http.Header{})
+		wsConn, err := migrationDial(dialer, operation, secret)
 		if err != nil {
 			return err
 		}
@@ -254,6 +268,7 @@ type migrationSink struct {
 	allConnected chan bool
 	push         bool
 	refresh      bool
+	resumable    bool
 }
 
 type MigrationSinkArgs struct {
@@ -269,6 +284,7 @@ type MigrationSinkArgs struct {
 	Idmap        *idmap.IdmapSet
 	Live         bool
 	Refresh      bool
+	Resumable    bool
 	Snapshots    []*migration.Snapshot
 
 	// Storage specific fields
@@ -280,17 +296,46 @@ type MigrationSinkArgs struct {
 }
 
 func (c *migrationSink) connectWithSecret(secret string) (*websocket.Conn, error) {
+	return migrationDial(c.dialer, c.url, secret)
+}
+
+// migrationDial connects to a websocket of the migration operation at the given https URL.
+//
+// If the operation runs on another member of the remote cluster, the member in the URL is asked for the
+// address of the member running it, so that the data streams directly between the members involved rather
+// than through the one in the URL. If that member can't be reached, the member in the URL forwards the
+// connection instead.
+func migrationDial(dialer websocket.Dialer, operation string, secret string) (*websocket.Conn, error) {
 	query := url.Values{"secret": []string{secret}}
 
 	// The URL is a https URL to the operation, mangle to be a wss URL to the secret
-	wsUrl := fmt.Sprintf("wss://%s/websocket?%s", strings.TrimPrefix(c.url, "https://"), query.Encode())
+	wsUrl := fmt.Sprintf("wss://%s/websocket?%s", strings.TrimPrefix(operation, "https://"), query.Encode())
+
+	header := http.Header{}
+	header.Set("X-LXD-redirect", "true")
+
+	conn, resp, err := dialer.Dial(wsUrl, header)
+	if err == nil {
+		return conn, nil
+	}
+
+	if resp == nil || resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") == "" {
+		return nil, err
+	}
+
+	conn, _, err = dialer.Dial(resp.Header.Get("Location"), http.Header{})
+	if err == nil {
+		return conn, nil
+	}
+
+	logger.Debug("Failed to connect to the member running the migration, going through the one in the URL", log.Ctx{"url": resp.Header.Get("Location"), "err": err})
 
-	conn, _, err := c.dialer.Dial(wsUrl, http.Header{})
+	conn, _, err = dialer.Dial(wsUrl, http.Header{})
 	if err != nil {
 		return nil, err
 	}
 
-	return conn, err
+	return conn, nil
 }
 
 func (s *migrationSink) Metadata() interface{} {
@@ -492,7 +492,7 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 	volSourceArgs.MigrationType = migrationTypes[0]
 	volSourceArgs.Snapshots = sendSnapshotNames
 	volSourceArgs.TrackProgress = true
-	err = pool.MigrateInstance(s.instance, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
+	err = pool.MigrateInstance(s.instance, util.NewBandwidthLimitedReadWriteCloser(&shared.WebsocketIO{Conn: s.fsConn}, s.bandwidthLimit), volSourceArgs, migrateOp)
 	if err != nil {
 		return abort(err)
 	}
@@ -682,7 +682,7 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 		volSourceArgs.FinalSync = true
 		volSourceArgs.Snapshots = nil
 
-		err = pool.MigrateInstance(s.instance, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
+		err = pool.MigrateInstance(s.instance, util.NewBandwidthLimitedReadWriteCloser(&shared.WebsocketIO{Conn: s.fsConn}, s.bandwidthLimit), volSourceArgs, migrateOp)
 		if err != nil {
 			return abort(err)
 		}
@@ -712,12 +712,13 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 
 func newMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	sink := migrationSink{
-		src:     migrationFields{instance: args.Instance, instanceOnly: args.InstanceOnly},
-		dest:    migrationFields{instanceOnly: args.InstanceOnly},
-		url:     args.Url,
-		dialer:  args.Dialer,
-		push:    args.Push,
-		refresh: args.Refresh,
+		src:       migrationFields{instance: args.Instance, instanceOnly: args.InstanceOnly},
+		dest:      migrationFields{instanceOnly: args.InstanceOnly},
+		url:       args.Url,
+		dialer:    args.Dialer,
+		push:      args.Push,
+		refresh:   args.Refresh,
+		resumable: args.Resumable,
 	}
 
 	if sink.push {
@@ -869,6 +870,7 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 	// Translate the legacy MigrationSinkArgs to a VolumeTargetArgs suitable for use
 	// with the new storage layer.
 	myTarget = func(conn *websocket.Conn, op *operations.Operation, args MigrationSinkArgs) error {
+		createdSnapshots := []instance.Instance{}
 		volTargetArgs := migration.VolumeTargetArgs{
 			Name:          args.Instance.Name(),
 			MigrationType: respTypes[0],
@@ -876,6 +878,7 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 			TrackProgress: false,        // Do not use a progress tracker on receiver.
 			Live:          args.Live,    // Indicates we will get a final rootfs sync.
 			VolumeSize:    args.VolumeSize,
+			Resumable:     args.Resumable,
 		}
 
 		// At this point we have already figured out the parent container's root
@@ -916,15 +919,37 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 				_, err := instance.LoadByProjectAndName(state, args.Instance.Project(), snapArgs.Name)
 				if err != nil {
 					// Create the snapshot as it doesn't seem to exist.
-					_, err := instanceCreateInternal(state, snapArgs)
+					snapInst, err := instanceCreateInternal(state, snapArgs)
 					if err != nil {
 						return err
 					}
+
+					createdSnapshots = append(createdSnapshots, snapInst)
+				}
+			}
+		}
+
+		err := pool.CreateInstanceFromMigration(args.Instance, &shared.WebsocketIO{Conn: conn}, volTargetArgs, op)
+		if err != nil {
+			// Snapshots received in full are kept so that a refresh retrying the transfer skips them,
+			// drop the records of those that didn't make it so that they get sent again.
+			if args.Resumable {
+				for _, snapInst := range createdSnapshots {
+					if shared.PathExists(snapInst.Path()) {
+						continue
+					}
+
+					err := state.Cluster.DeleteInstance(snapInst.Project(), snapInst.Name())
+					if err != nil {
+						logger.Errorf("Failed to remove incomplete snapshot record %q: %v", snapInst.Name(), err)
+					}
 				}
 			}
+
+			return err
 		}
 
-		return pool.CreateInstanceFromMigration(args.Instance, &shared.WebsocketIO{Conn: conn}, volTargetArgs, op)
+		return nil
 	}
 
 	// Add CRIU info to response.
@@ -1043,6 +1068,7 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 				Idmap:         srcIdmap,
 				Live:          sendFinalFsDelta,
 				Refresh:       c.refresh,
+				Resumable:     c.resumable,
 				RsyncFeatures: rsyncFeatures,
 				Snapshots:     snapshots,
 				VolumeSize:    offerHeader.GetVolumeSize(),
@@ -11,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
@@ -133,7 +134,7 @@ func (s *migrationSourceWs) DoStorage(state *state.State, projectName string, po
 		ContentType:   vol.ContentType,
 	}
 
-	err = pool.MigrateCustomVolume(projectName, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
+	err = pool.MigrateCustomVolume(projectName, util.NewBandwidthLimitedReadWriteCloser(&shared.WebsocketIO{Conn: s.fsConn}, s.bandwidthLimit), volSourceArgs, migrateOp)
 	if err != nil {
 		go s.sendControl(err)
 		return err
@@ -3,9 +3,11 @@ package migration
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/cancel"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/units"
 )
@@ -41,6 +43,7 @@ type VolumeTargetArgs struct {
 	Live          bool
 	VolumeSize    int64
 	ContentType   string
+	Resumable     bool
 }
 
 // TypesToHeader converts one or more Types to a MigrationHeader. It uses the first type argument
@@ -205,6 +208,7 @@ func progressWrapperRender(op *operations.Operation, key string, description str
 	}
 
 	if meta[key] != progress {
+		meta["progress"] = shared.ProgressMetadata(strings.TrimSuffix(key, "_progress"), 0, progressInt, 0, speedInt)
 		meta[key] = progress
 		op.UpdateMetadata(meta)
 	}
@@ -228,7 +232,8 @@ func ProgressReader(op *operations.Operation, key string, description string) fu
 			},
 		}
 
-		return readPipe
+		// Abort the transfer if the operation gets cancelled.
+		return &cancelReadCloser{Reader: cancel.Reader{Reader: readPipe, Context: op.Context()}, Closer: readPipe}
 	}
 }
 
@@ -250,10 +255,23 @@ func ProgressWriter(op *operations.Operation, key string, description string) fu
 			},
 		}
 
-		return writePipe
+		// Abort the transfer if the operation gets cancelled.
+		return &cancelWriteCloser{Writer: cancel.Writer{Writer: writePipe, Context: op.Context()}, Closer: writePipe}
 	}
 }
 
+// cancelReadCloser is a ReadCloser whose reads fail once its context is cancelled.
+type cancelReadCloser struct {
+	cancel.Reader
+	io.Closer
+}
+
+// cancelWriteCloser is a WriteCloser whose writes fail once its context is cancelled.
+type cancelWriteCloser struct {
+	cancel.Writer
+	io.Closer
+}
+
 // ProgressTracker returns a migration I/O tracker
 func ProgressTracker(op *operations.Operation, key string, description string) *ioprogress.ProgressTracker {
 	progress := func(progressInt int64, speedInt int64) {
@@ -0,0 +1,529 @@
+package network
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// ValidACLName checks the supplied name is a valid network ACL name.
+func ValidACLName(value string) error {
+	if value == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if len(value) > 63 {
+		return fmt.Errorf("Name is too long (maximum 63 characters)")
+	}
+
+	match, _ := regexp.MatchString("^[a-zA-Z][-_a-zA-Z0-9]*$", value)
+	if !match {
+		return fmt.Errorf("Name must start with a letter and only contain letters, numbers, dashes and underscores")
+	}
+
+	return nil
+}
+
+// ValidateACL checks the supplied network ACL configuration and rules are valid.
+func ValidateACL(put *api.NetworkACLPut) error {
+	for k := range put.Config {
+		if !strings.HasPrefix(k, "user.") {
+			return fmt.Errorf("Invalid network ACL configuration key %q", k)
+		}
+	}
+
+	for direction, rules := range map[string][]api.NetworkACLRule{"ingress": put.Ingress, "egress": put.Egress} {
+		for i, rule := range rules {
+			_, err := aclRuleToFirewall(rule)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid %s rule %d", direction, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ACLNames returns the list of network ACL names from a comma separated "security.acls" value.
+func ACLNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || shared.StringInSlice(name, names) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ValidACLs returns a validator for a comma separated list of existing network ACL names.
+func ValidACLs(s *state.State) func(value string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		existing, err := s.Cluster.GetNetworkACLs()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range ACLNames(value) {
+			if !shared.StringInSlice(name, existing) {
+				return fmt.Errorf("Network ACL %q doesn't exist", name)
+			}
+		}
+
+		return nil
+	}
+}
+
+// ACLRules loads the named network ACLs and returns their combined enabled ingress and egress rules in order.
+func ACLRules(s *state.State, names []string) ([]firewallDrivers.ACLRule, []firewallDrivers.ACLRule, error) {
+	ingress := []firewallDrivers.ACLRule{}
+	egress := []firewallDrivers.ACLRule{}
+
+	for _, name := range names {
+		_, acl, err := s.Cluster.GetNetworkACL(name)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed loading network ACL %q", name)
+		}
+
+		for _, rule := range acl.Ingress {
+			if rule.State == "disabled" {
+				continue
+			}
+
+			fwRule, err := aclRuleToFirewall(rule)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "Invalid ingress rule in network ACL %q", name)
+			}
+
+			ingress = append(ingress, fwRule)
+		}
+
+		for _, rule := range acl.Egress {
+			if rule.State == "disabled" {
+				continue
+			}
+
+			fwRule, err := aclRuleToFirewall(rule)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "Invalid egress rule in network ACL %q", name)
+			}
+
+			egress = append(egress, fwRule)
+		}
+	}
+
+	return ingress, egress, nil
+}
+
+// ACLUsedBy returns the URLs of the networks, instances and profiles using the network ACL.
+func ACLUsedBy(s *state.State, aclName string) ([]string, error) {
+	usedBy := []string{}
+
+	networks, err := s.Cluster.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	for networkProject, networkNames := range networks {
+		for _, networkName := range networkNames {
+			_, network, err := s.Cluster.GetNetworkInAnyState(networkProject, networkName)
+			if err != nil {
+				return nil, err
+			}
+
+			if shared.StringInSlice(aclName, ACLNames(network.Config["security.acls"])) {
+				uri := fmt.Sprintf("/%s/networks/%s", version.APIVersion, networkName)
+				if networkProject != project.Default {
+					uri += fmt.Sprintf("?project=%s", networkProject)
+				}
+
+				usedBy = append(usedBy, uri)
+			}
+		}
+	}
+
+	insts, err := instance.LoadFromAllProjects(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inst := range insts {
+		if isACLInUseByDevices(inst.LocalDevices(), aclName) {
+			uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, inst.Name())
+			if inst.Project() != project.Default {
+				uri += fmt.Sprintf("?project=%s", inst.Project())
+			}
+
+			usedBy = append(usedBy, uri)
+		}
+	}
+
+	var profiles []db.Profile
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		profiles, err = tx.GetProfiles(db.ProfileFilter{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if isACLInUseByDevices(deviceConfig.NewDevices(db.ProfileToAPI(&profile).Devices), aclName) {
+			uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, profile.Name)
+			if profile.Project != project.Default {
+				uri += fmt.Sprintf("?project=%s", profile.Project)
+			}
+
+			usedBy = append(usedBy, uri)
+		}
+	}
+
+	return usedBy, nil
+}
+
+// isACLInUseByDevices returns true if any of the NIC devices use the network ACL.
+func isACLInUseByDevices(devices deviceConfig.Devices, aclName string) bool {
+	for _, d := range devices {
+		if d["type"] == "nic" && shared.StringInSlice(aclName, ACLNames(d["security.acls"])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ACLRefresh re-applies the rules of the network ACL to the networks and running instance devices on the local
+// node that use it. This needs to be run on every cluster member after the ACL has been modified.
+func ACLRefresh(s *state.State, aclName string) error {
+	networks, err := s.Cluster.GetNonPendingNetworks()
+	if err != nil {
+		return err
+	}
+
+	ovnNetworks := []string{}
+	for networkProject, networkNames := range networks {
+		for _, networkName := range networkNames {
+			n, err := LoadByName(s, networkProject, networkName)
+			if err != nil {
+				return err
+			}
+
+			if !shared.StringInSlice(aclName, ACLNames(n.Config()["security.acls"])) {
+				continue
+			}
+
+			switch n := n.(type) {
+			case *bridge:
+				if n.isRunning() {
+					err = n.setupACLs()
+					if err != nil {
+						return err
+					}
+				}
+			case *ovn:
+				// OVN networks apply their ACLs to each instance port.
+				ovnNetworks = append(ovnNetworks, networkName)
+			}
+		}
+	}
+
+	return aclRefreshInstanceDevices(s, func(dev deviceConfig.Device, aclNames []string) bool {
+		if shared.StringInSlice(aclName, aclNames) {
+			return true
+		}
+
+		return dev.NICType() == "ovn" && shared.StringInSlice(dev["network"], ovnNetworks)
+	})
+}
+
+// aclRefreshInstanceDevices re-applies the network ACLs of the NIC devices of the running instances on the local
+// node that are selected by the match function.
+func aclRefreshInstanceDevices(s *state.State, match func(dev deviceConfig.Device, aclNames []string) bool) error {
+	insts, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		for devName, dev := range inst.ExpandedDevices() {
+			if dev["type"] != "nic" {
+				continue
+			}
+
+			aclNames := ACLNames(dev["security.acls"])
+			if !match(dev, aclNames) {
+				continue
+			}
+
+			switch dev.NICType() {
+			case "bridged":
+				hostName := dev["host_name"]
+				if hostName == "" {
+					hostName = inst.LocalConfig()[fmt.Sprintf("volatile.%s.host_name", devName)]
+				}
+
+				if hostName == "" {
+					continue
+				}
+
+				err = InstanceDeviceACLsApply(s, inst.Project(), inst.Name(), devName, hostName, aclNames)
+			case "ovn":
+				var n Network
+				n, err = LoadVisibleByName(s, inst.Project(), dev["network"])
+				if err != nil {
+					break
+				}
+
+				ovnNet, ok := n.(*ovn)
+				if !ok {
+					continue
+				}
+
+				err = ovnNet.InstanceDevicePortACLsSet(inst.ID(), devName, aclNames)
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "Failed applying network ACLs to device %q of instance %q", devName, inst.Name())
+			}
+		}
+	}
+
+	return nil
+}
+
+// InstanceDeviceACLsApply applies the named network ACLs to the host interface of an instance device, removing
+// any previously applied rules. If no ACLs are specified then any existing rules are just removed.
+func InstanceDeviceACLsApply(s *state.State, projectName string, instanceName string, deviceName string, hostName string, aclNames []string) error {
+	if len(aclNames) < 1 {
+		return s.Firewall.InstanceClearACLRules(projectName, instanceName, deviceName)
+	}
+
+	ingress, egress, err := ACLRules(s, aclNames)
+	if err != nil {
+		return err
+	}
+
+	return s.Firewall.InstanceSetupACLRules(projectName, instanceName, deviceName, hostName, ingress, egress)
+}
+
+// aclRuleToFirewall validates a network ACL rule and converts it into a firewall rule.
+func aclRuleToFirewall(rule api.NetworkACLRule) (firewallDrivers.ACLRule, error) {
+	fwRule := firewallDrivers.ACLRule{
+		Action:   rule.Action,
+		Protocol: rule.Protocol,
+	}
+
+	if !shared.StringInSlice(rule.Action, []string{"allow", "drop", "reject"}) {
+		return fwRule, fmt.Errorf("Action must be one of allow, drop or reject")
+	}
+
+	if !shared.StringInSlice(rule.State, []string{"", "enabled", "disabled"}) {
+		return fwRule, fmt.Errorf("State must be one of enabled or disabled")
+	}
+
+	if !shared.StringInSlice(rule.Protocol, []string{"", "tcp", "udp", "icmp4", "icmp6"}) {
+		return fwRule, fmt.Errorf("Protocol must be one of tcp, udp, icmp4 or icmp6")
+	}
+
+	var err error
+
+	fwRule.Source, err = aclParseSubjects(rule.Source)
+	if err != nil {
+		return fwRule, errors.Wrapf(err, "Invalid source")
+	}
+
+	fwRule.Destination, err = aclParseSubjects(rule.Destination)
+	if err != nil {
+		return fwRule, errors.Wrapf(err, "Invalid destination")
+	}
+
+	if rule.SourcePort != "" || rule.DestinationPort != "" {
+		if !shared.StringInSlice(rule.Protocol, []string{"tcp", "udp"}) {
+			return fwRule, fmt.Errorf("Ports can only be specified with the tcp or udp protocols")
+		}
+
+		fwRule.SourcePort, err = aclParsePorts(rule.SourcePort)
+		if err != nil {
+			return fwRule, errors.Wrapf(err, "Invalid source port")
+		}
+
+		fwRule.DestinationPort, err = aclParsePorts(rule.DestinationPort)
+		if err != nil {
+			return fwRule, errors.Wrapf(err, "Invalid destination port")
+		}
+	}
+
+	if rule.ICMPType != "" || rule.ICMPCode != "" {
+		if !shared.StringInSlice(rule.Protocol, []string{"icmp4", "icmp6"}) {
+			return fwRule, fmt.Errorf("ICMP type and code can only be specified with the icmp4 or icmp6 protocols")
+		}
+
+		for _, value := range []string{rule.ICMPType, rule.ICMPCode} {
+			if value == "" {
+				continue
+			}
+
+			_, err := strconv.ParseUint(value, 10, 8)
+			if err != nil {
+				return fwRule, fmt.Errorf("Invalid ICMP type or code %q", value)
+			}
+		}
+
+		fwRule.ICMPType = rule.ICMPType
+		fwRule.ICMPCode = rule.ICMPCode
+	}
+
+	if len(fwRule.Families()) < 1 {
+		return fwRule, fmt.Errorf("Source, destination and protocol don't share a common IP family")
+	}
+
+	return fwRule, nil
+}
+
+// aclParseSubjects parses a comma separated list of IP addresses, CIDR subnets and IP ranges (start-end) into a
+// list of subnets.
+func aclParseSubjects(value string) ([]*net.IPNet, error) {
+	subnets := []*net.IPNet{}
+	if value == "" {
+		return subnets, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if strings.Contains(entry, "-") {
+			ipRanges, err := parseIPRanges(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			subnets = append(subnets, ipRangeSubnets(ipRanges[0])...)
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, subnet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid subnet %q", entry)
+			}
+
+			subnets = append(subnets, subnet)
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid IP address %q", entry)
+		}
+
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+
+		subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+
+	return subnets, nil
+}
+
+// aclParsePorts parses a comma separated list of ports and port ranges (start-end).
+func aclParsePorts(value string) ([]string, error) {
+	ports := []string{}
+	if value == "" {
+		return ports, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.SplitN(entry, "-", 2)
+
+		start, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid port %q", entry)
+		}
+
+		if len(parts) > 1 {
+			end, err := strconv.ParseUint(parts[1], 10, 16)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("Invalid port range %q", entry)
+			}
+		}
+
+		ports = append(ports, entry)
+	}
+
+	return ports, nil
+}
+
+// ipRangeSubnets returns the smallest list of subnets covering the IP range.
+func ipRangeSubnets(r *ipRange) []*net.IPNet {
+	start := r.Start.To16()
+	end := r.End.To16()
+	bits := 128
+	if r.Start.To4() != nil {
+		start = r.Start.To4()
+		end = r.End.To4()
+		bits = 32
+	}
+
+	cur := big.NewInt(0).SetBytes(start)
+	last := big.NewInt(0).SetBytes(end)
+	one := big.NewInt(1)
+
+	subnets := []*net.IPNet{}
+	for cur.Cmp(last) <= 0 {
+		// Grow the subnet whilst it stays aligned on the current address and doesn't go past the end.
+		prefix := bits
+		for prefix > 0 {
+			size := big.NewInt(0).Lsh(one, uint(bits-prefix+1))
+			if big.NewInt(0).Mod(cur, size).Sign() != 0 {
+				break
+			}
+
+			blockEnd := big.NewInt(0).Add(cur, size)
+			if blockEnd.Sub(blockEnd, one).Cmp(last) > 0 {
+				break
+			}
+
+			prefix--
+		}
+
+		ip := make(net.IP, len(start))
+		curBytes := cur.Bytes()
+		copy(ip[len(ip)-len(curBytes):], curBytes)
+
+		subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, bits)})
+		cur.Add(cur, big.NewInt(0).Lsh(one, uint(bits-prefix)))
+	}
+
+	return subnets
+}
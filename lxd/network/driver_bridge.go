@@ -18,6 +18,7 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/dnsmasq"
+	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/util"
@@ -57,6 +58,11 @@ func (n *bridge) fillConfig(req *api.NetworksPost) error {
 			req.Config["ipv4.nat"] = "true"
 		}
 
+		// Delegated networks get their IPv6 subnet from the upstream router once the prefix is acquired.
+		if req.Config["ipv6.address"] == "" && req.Config["ipv6.delegation.interface"] != "" {
+			req.Config["ipv6.address"] = "none"
+		}
+
 		if req.Config["ipv6.address"] == "" {
 			content, err := ioutil.ReadFile("/proc/sys/net/ipv6/conf/default/disable_ipv6")
 			if err == nil && string(content) == "0\n" {
@@ -98,6 +104,43 @@ func (n *bridge) Validate(config map[string]string) error {
 		"bridge.mode": func(value string) error {
 			return shared.IsOneOf(value, []string{"standard", "fan"})
 		},
+		"bridge.multicast.snooping": shared.IsBool,
+		"bridge.multicast.querier":  shared.IsBool,
+		"bridge.multicast.igmp_version": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return shared.IsOneOf(value, []string{"2", "3"})
+		},
+		"bridge.multicast.mld_version": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return shared.IsOneOf(value, []string{"1", "2"})
+		},
+		"bridge.vlan.default": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return validBridgeVLAN(value)
+		},
+		"bridge.vlan.tagged": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			for _, vlanID := range strings.Split(value, ",") {
+				err := validBridgeVLAN(strings.TrimSpace(vlanID))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
 
 		"fan.overlay_subnet": shared.IsNetworkV4,
 		"fan.underlay_subnet": func(value string) error {
@@ -130,6 +173,7 @@ func (n *bridge) Validate(config map[string]string) error {
 		"ipv4.dhcp.ranges":  shared.IsAny,
 		"ipv4.routes":       shared.IsNetworkV4List,
 		"ipv4.routing":      shared.IsBool,
+		"ipv4.ovn.ranges":   validIPRanges(4),
 
 		"ipv6.address": func(value string) error {
 			if shared.IsOneOf(value, []string{"none", "auto"}) == nil {
@@ -150,17 +194,45 @@ func (n *bridge) Validate(config map[string]string) error {
 		"ipv6.dhcp.ranges":   shared.IsAny,
 		"ipv6.routes":        shared.IsNetworkV6List,
 		"ipv6.routing":       shared.IsBool,
+		"ipv6.ovn.ranges":    validIPRanges(6),
+		"ipv6.delegation.interface": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return ValidNetworkName(value)
+		},
+		"ipv6.delegation.subnet": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return shared.IsUint32(value)
+		},
+
+		"dhcp.server": func(value string) error {
+			return shared.IsOneOf(value, []string{"dnsmasq", "builtin"})
+		},
 
 		"dns.domain": shared.IsAny,
 		"dns.search": shared.IsAny,
 		"dns.mode": func(value string) error {
 			return shared.IsOneOf(value, []string{"dynamic", "managed", "none"})
 		},
+		"dns.zone.forward":      ValidForwardZones(n.state),
+		"dns.zone.reverse.ipv4": ValidReverseZone(n.state, 4),
+		"dns.zone.reverse.ipv6": ValidReverseZone(n.state, 6),
 
 		"raw.dnsmasq": shared.IsAny,
 
 		"maas.subnet.ipv4": shared.IsAny,
 		"maas.subnet.ipv6": shared.IsAny,
+
+		"security.acls": ValidACLs(n.state),
+
+		"wireguard.mesh":   shared.IsBool,
+		"wireguard.port":   networkValidPort,
+		"wireguard.subnet": shared.IsNetworkV4,
 	}
 
 	// Add dynamic validation rules.
@@ -210,6 +282,75 @@ func (n *bridge) Validate(config map[string]string) error {
 
 	// Peform composite key checks after per-key validation.
 
+	// ACLs are applied with nftables bridge filtering which doesn't see traffic on Open vSwitch bridges.
+	if config["security.acls"] != "" && config["bridge.driver"] == "openvswitch" {
+		return fmt.Errorf("Network ACLs cannot be used with the openvswitch bridge driver")
+	}
+
+	// VLAN filtering is only configured on native bridges, Open vSwitch handles VLANs per port.
+	if (config["bridge.vlan.default"] != "" || config["bridge.vlan.tagged"] != "") && config["bridge.driver"] == "openvswitch" {
+		return fmt.Errorf("Bridge VLAN settings cannot be used with the openvswitch bridge driver")
+	}
+
+	// Multicast snooping is configured on native bridges, Open vSwitch has its own per-bridge settings.
+	for key := range config {
+		if strings.HasPrefix(key, "bridge.multicast.") && config["bridge.driver"] == "openvswitch" {
+			return fmt.Errorf("Bridge multicast settings cannot be used with the openvswitch bridge driver")
+		}
+	}
+
+	// Adding the untagged VLAN as a tagged membership would strip its untagged flag on the external interfaces.
+	defaultVLAN := config["bridge.vlan.default"]
+	if defaultVLAN == "" {
+		defaultVLAN = "1"
+	}
+
+	if shared.StringInSlice(defaultVLAN, bridgeVLANList(config["bridge.vlan.tagged"])) {
+		return fmt.Errorf("Tagged VLAN ID %q cannot be the same as the default untagged VLAN ID", defaultVLAN)
+	}
+
+	// The delegated prefix is specific to the uplink of each server, so it can't be shared across a cluster.
+	if config["ipv6.delegation.interface"] != "" {
+		if config["ipv6.address"] == "auto" {
+			return fmt.Errorf("%q cannot be set to %q when using prefix delegation", "ipv6.address", "auto")
+		}
+
+		clustered, err := cluster.Enabled(n.state.Node)
+		if err != nil {
+			return err
+		}
+
+		if clustered {
+			return fmt.Errorf("IPv6 prefix delegation cannot be used on clustered servers")
+		}
+	}
+
+	// The Wireguard mesh interfaces are named after the network and the mesh has its own addressing.
+	if shared.IsTrue(config["wireguard.mesh"]) {
+		if config["wireguard.subnet"] == "" {
+			return fmt.Errorf("%q must be set when using a Wireguard mesh", "wireguard.subnet")
+		}
+
+		if config["bridge.mode"] == "fan" {
+			return fmt.Errorf("Wireguard meshes cannot be used in 'fan' mode")
+		}
+
+		if len(n.name) > 11 {
+			return fmt.Errorf("Network name too long to use with a Wireguard mesh (must be 11 characters or less)")
+		}
+	}
+
+	// The built-in DHCP and DNS server doesn't take dnsmasq options nor forward to the fan's DNS forwarder.
+	if config["dhcp.server"] == "builtin" {
+		if config["raw.dnsmasq"] != "" {
+			return fmt.Errorf("%q cannot be used with the built-in DHCP and DNS server", "raw.dnsmasq")
+		}
+
+		if config["bridge.mode"] == "fan" {
+			return fmt.Errorf("The built-in DHCP and DNS server cannot be used in 'fan' mode")
+		}
+	}
+
 	// Validate network name when used in fan mode.
 	bridgeMode := config["bridge.mode"]
 	if bridgeMode == "fan" && len(n.name) > 11 {
@@ -408,6 +549,8 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 	mtu := ""
 	if n.config["bridge.mtu"] != "" {
 		mtu = n.config["bridge.mtu"]
+	} else if shared.IsTrue(n.config["wireguard.mesh"]) {
+		mtu = "1370"
 	} else if len(tunnels) > 0 {
 		mtu = "1400"
 	} else if n.config["bridge.mode"] == "fan" {
@@ -454,11 +597,21 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			n.logger.Warn(fmt.Sprintf("%v", err))
 		}
 
-		// Set the default PVID for new ports to 1.
-		err = BridgeVLANSetDefaultPVID(n.name, "1")
+		// Set the default PVID for new ports (and the bridge itself) to the untagged VLAN.
+		defaultPVID := n.config["bridge.vlan.default"]
+		if defaultPVID == "" {
+			defaultPVID = "1"
+		}
+
+		err = BridgeVLANSetDefaultPVID(n.name, defaultPVID)
 		if err != nil {
 			n.logger.Warn(fmt.Sprintf("%v", err))
 		}
+
+		err = n.setupMulticast()
+		if err != nil {
+			return err
+		}
 	}
 
 	// Bring it up
@@ -497,6 +650,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			if err != nil {
 				return err
 			}
+
+			// Let the tagged VLANs through the external interface so the bridge can trunk them.
+			if n.config["bridge.driver"] != "openvswitch" {
+				err = n.setupExternalInterfaceVLANs(entry, oldConfig)
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -574,23 +735,26 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		"--no-ping", // --no-ping is very important to prevent delays to lease file updates.
 		fmt.Sprintf("--interface=%s", n.name)}
 
-	dnsmasqVersion, err := dnsmasq.GetVersion()
-	if err != nil {
-		return err
-	}
+	// The built-in DHCP and DNS server doesn't need dnsmasq to be installed.
+	if !n.usesBuiltinDHCP() {
+		dnsmasqVersion, err := dnsmasq.GetVersion()
+		if err != nil {
+			return err
+		}
 
-	// --dhcp-rapid-commit option is only supported on >2.79
-	minVer, _ := version.NewDottedVersion("2.79")
-	if dnsmasqVersion.Compare(minVer) > 0 {
-		dnsmasqCmd = append(dnsmasqCmd, "--dhcp-rapid-commit")
-	}
+		// --dhcp-rapid-commit option is only supported on >2.79
+		minVer, _ := version.NewDottedVersion("2.79")
+		if dnsmasqVersion.Compare(minVer) > 0 {
+			dnsmasqCmd = append(dnsmasqCmd, "--dhcp-rapid-commit")
+		}
 
-	if !daemon.Debug {
-		// --quiet options are only supported on >2.67
-		minVer, _ := version.NewDottedVersion("2.67")
+		if !daemon.Debug {
+			// --quiet options are only supported on >2.67
+			minVer, _ := version.NewDottedVersion("2.67")
 
-		if err == nil && dnsmasqVersion.Compare(minVer) > 0 {
-			dnsmasqCmd = append(dnsmasqCmd, []string{"--quiet-dhcp", "--quiet-dhcp6", "--quiet-ra"}...)
+			if err == nil && dnsmasqVersion.Compare(minVer) > 0 {
+				dnsmasqCmd = append(dnsmasqCmd, []string{"--quiet-dhcp", "--quiet-dhcp6", "--quiet-ra"}...)
+			}
 		}
 	}
 
@@ -1100,6 +1264,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		if err != nil {
 			return err
 		}
+	}
+
+	// Configure the Wireguard mesh between cluster members.
+	if shared.IsTrue(n.config["wireguard.mesh"]) {
+		err = n.setupWireguardMesh(mtu)
+		if err != nil {
+			return err
+		}
 
 		_, err = shared.RunCommand("ip", "link", "set", "dev", n.name, "up")
 		if err != nil {
@@ -1108,6 +1280,8 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 	}
 
 	// Kill any existing dnsmasq and forkdns daemon for this network
+	stopBuiltinDHCP(n.name)
+
 	err = dnsmasq.Kill(n.name, false)
 	if err != nil {
 		return err
@@ -1118,8 +1292,36 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		return err
 	}
 
-	// Configure dnsmasq
-	if n.config["bridge.mode"] == "fan" || !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) || !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
+	// Configure the DHCP and DNS server
+	if n.usesBuiltinDHCP() && (!shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) || !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"})) {
+		// Remove the PID file of a previous dnsmasq.
+		pidPath := shared.VarPath("networks", n.name, "dnsmasq.pid")
+		if shared.PathExists(pidPath) {
+			err := os.Remove(pidPath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to remove old dnsmasq pid file '%s'", pidPath)
+			}
+		}
+
+		// Create DHCP hosts directory
+		if !shared.PathExists(shared.VarPath("networks", n.name, "dnsmasq.hosts")) {
+			err = os.MkdirAll(shared.VarPath("networks", n.name, "dnsmasq.hosts"), 0755)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = n.startBuiltinDHCP(mtu)
+		if err != nil {
+			return err
+		}
+
+		// Update the static leases
+		err = UpdateDNSMasqStatic(n.state, n.name)
+		if err != nil {
+			return err
+		}
+	} else if n.config["bridge.mode"] == "fan" || !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) || !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
 		// Setup the dnsmasq domain
 		dnsDomain := n.config["dns.domain"]
 		if dnsDomain == "" {
@@ -1232,11 +1434,407 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Apply network ACLs.
+	err = n.setupACLs()
+	if err != nil {
+		return err
+	}
+
+	// Apply address forwards.
+	err = n.setupForwards()
+	if err != nil {
+		return err
+	}
+
+	// Apply load balancers.
+	err = n.setupLoadBalancers()
+	if err != nil {
+		return err
+	}
+
+	// Announce the routed subnets of the network.
+	err = n.bgpSetupSubnets()
+	if err != nil {
+		return err
+	}
+
+	// Request (or stop requesting) the IPv6 prefix from the upstream router.
+	err = startPrefixDelegation(n.name, n.logger, n.config["ipv6.delegation.interface"], n.delegatedPrefixChanged)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// delegatedPrefixChanged updates ipv6.address with the configured subnet of a new delegated prefix, which
+// reconfigures the bridge address, router advertisements and DHCPv6. A nil prefix removes the IPv6 subnet.
+func (n *bridge) delegatedPrefixChanged(prefix *net.IPNet) {
+	// Reload the network as the config may have changed since the client was started.
+	network, err := LoadByName(n.state, n.project, n.name)
+	if err != nil {
+		n.logger.Error("Failed loading network for delegated prefix", log.Ctx{"err": err})
+		return
+	}
+
+	b, ok := network.(*bridge)
+	if !ok {
+		return
+	}
+
+	address := "none"
+	if prefix != nil {
+		index := uint64(0)
+		if b.config["ipv6.delegation.subnet"] != "" {
+			index, err = strconv.ParseUint(b.config["ipv6.delegation.subnet"], 10, 32)
+			if err != nil {
+				n.logger.Error("Invalid delegated subnet", log.Ctx{"err": err})
+				return
+			}
+		}
+
+		address, err = delegatedSubnet(prefix, index)
+		if err != nil {
+			n.logger.Error("Failed applying delegated prefix", log.Ctx{"err": err})
+			return
+		}
+	}
+
+	if b.config["ipv6.address"] == address {
+		return
+	}
+
+	newConfig := make(map[string]string, len(b.config))
+	for k, v := range b.config {
+		newConfig[k] = v
+	}
+
+	newConfig["ipv6.address"] = address
+
+	err = b.Update(api.NetworkPut{Description: b.description, Config: newConfig}, false)
+	if err != nil {
+		n.logger.Error("Failed applying delegated prefix", log.Ctx{"address": address, "err": err})
+	}
+}
+
+// setupACLs applies the network ACLs in security.acls to the traffic of the instances connected to the bridge.
+func (n *bridge) setupACLs() error {
+	aclNames := ACLNames(n.config["security.acls"])
+	if len(aclNames) < 1 {
+		return n.state.Firewall.NetworkClearACLRules(n.name)
+	}
+
+	ingress, egress, err := ACLRules(n.state, aclNames)
+	if err != nil {
+		return err
+	}
+
+	return n.state.Firewall.NetworkApplyACLRules(n.name, ingress, egress)
+}
+
+// setupForwards applies the address forwards of the local cluster member.
+func (n *bridge) setupForwards() error {
+	forwards, err := n.state.Cluster.GetNetworkForwards(n.id, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading address forwards")
+	}
+
+	err = n.bgpSetupForwards(forwards)
+	if err != nil {
+		return err
+	}
+
+	if len(forwards) < 1 {
+		return n.state.Firewall.NetworkClearForwards(n.name)
+	}
+
+	fwForwards := []firewallDrivers.AddressForward{}
+	for _, forward := range forwards {
+		rules, err := forwardValidate(net.ParseIP(forward.ListenAddress), &forward.NetworkForwardPut)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid address forward %q", forward.ListenAddress)
+		}
+
+		fwForwards = append(fwForwards, rules...)
+	}
+
+	return n.state.Firewall.NetworkApplyForwards(n.name, fwForwards)
+}
+
+// forwardCheckListenAddress checks the listen address can be used for an address forward on the network.
+func (n *bridge) forwardCheckListenAddress(listenAddress net.IP) error {
+	if listenAddress == nil {
+		return fmt.Errorf("Invalid listen address")
+	}
+
+	if listenAddress.IsUnspecified() || listenAddress.IsMulticast() || listenAddress.IsLoopback() {
+		return fmt.Errorf("Listen address %q must be a unicast address", listenAddress.String())
+	}
+
+	// Forwarding an address of the bridge's own subnets would hijack the instances' traffic.
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		if shared.StringInSlice(n.config[key], []string{"", "none"}) {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(n.config[key])
+		if err != nil {
+			continue
+		}
+
+		if subnet.Contains(listenAddress) {
+			return fmt.Errorf("Listen address %q is within the network's subnet %q", listenAddress.String(), subnet.String())
+		}
+	}
+
+	return nil
+}
+
+// ForwardCreate creates a new address forward applied on the local cluster member.
+func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost) error {
+	listenAddress := net.ParseIP(forward.ListenAddress)
+	err := n.forwardCheckListenAddress(listenAddress)
+	if err != nil {
+		return err
+	}
+
+	_, err = forwardValidate(listenAddress, &forward.NetworkForwardPut)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Store the canonical form of the listen address so it can be looked up reliably.
+	forward.ListenAddress = listenAddress.String()
+	id, err := n.state.Cluster.CreateNetworkForward(n.id, &forward)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		n.state.Cluster.DeleteNetworkForward(id)
+		n.setupForwards()
+	})
+
+	if n.isRunning() {
+		err = n.setupForwards()
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// ForwardUpdate updates the address forward with the given listen address.
+func (n *bridge) ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error {
+	id, curForward, err := n.state.Cluster.GetNetworkForward(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	_, err = forwardValidate(net.ParseIP(listenAddress), &newForward)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = n.state.Cluster.UpdateNetworkForward(id, &newForward)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		oldForward := curForward.Writable()
+		n.state.Cluster.UpdateNetworkForward(id, &oldForward)
+		n.setupForwards()
+	})
+
+	if n.isRunning() {
+		err = n.setupForwards()
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// ForwardDelete deletes the address forward with the given listen address.
+func (n *bridge) ForwardDelete(listenAddress string) error {
+	id, _, err := n.state.Cluster.GetNetworkForward(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Cluster.DeleteNetworkForward(id)
+	if err != nil {
+		return err
+	}
+
+	if n.isRunning() {
+		err = n.setupForwards()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setupLoadBalancers applies the load balancers of the local cluster member.
+func (n *bridge) setupLoadBalancers() error {
+	loadBalancers, err := n.state.Cluster.GetNetworkLoadBalancers(n.id, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading load balancers")
+	}
+
+	if len(loadBalancers) < 1 {
+		return n.state.Firewall.NetworkClearLoadBalancers(n.name)
+	}
+
+	fwLoadBalancers := []firewallDrivers.LoadBalancer{}
+	for _, lb := range loadBalancers {
+		rules, err := loadBalancerRules(n.name, net.ParseIP(lb.ListenAddress), &lb.NetworkLoadBalancerPut)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid load balancer %q", lb.ListenAddress)
+		}
+
+		fwLoadBalancers = append(fwLoadBalancers, rules...)
+	}
+
+	return n.state.Firewall.NetworkApplyLoadBalancers(n.name, fwLoadBalancers)
+}
+
+// LoadBalancerCreate creates a new load balancer applied on the local cluster member.
+func (n *bridge) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error {
+	listenAddress := net.ParseIP(loadBalancer.ListenAddress)
+	err := n.forwardCheckListenAddress(listenAddress)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = loadBalancerValidate(listenAddress, &loadBalancer.NetworkLoadBalancerPut)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Store the canonical form of the listen address so it can be looked up reliably.
+	loadBalancer.ListenAddress = listenAddress.String()
+	id, err := n.state.Cluster.CreateNetworkLoadBalancer(n.id, &loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		n.state.Cluster.DeleteNetworkLoadBalancer(id)
+		n.setupLoadBalancers()
+	})
+
+	if n.isRunning() {
+		err = n.setupLoadBalancers()
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
 	return nil
 }
 
+// LoadBalancerUpdate updates the load balancer with the given listen address.
+func (n *bridge) LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error {
+	id, curLoadBalancer, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = loadBalancerValidate(net.ParseIP(listenAddress), &newLoadBalancer)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = n.state.Cluster.UpdateNetworkLoadBalancer(id, &newLoadBalancer)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		oldLoadBalancer := curLoadBalancer.Writable()
+		n.state.Cluster.UpdateNetworkLoadBalancer(id, &oldLoadBalancer)
+		n.setupLoadBalancers()
+	})
+
+	// The backends may have changed, so start the health checks over.
+	loadBalancerHealthClear(n.name, listenAddress)
+
+	if n.isRunning() {
+		err = n.setupLoadBalancers()
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// LoadBalancerDelete deletes the load balancer with the given listen address.
+func (n *bridge) LoadBalancerDelete(listenAddress string) error {
+	id, _, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Cluster.DeleteNetworkLoadBalancer(id)
+	if err != nil {
+		return err
+	}
+
+	loadBalancerHealthClear(n.name, listenAddress)
+
+	if n.isRunning() {
+		err = n.setupLoadBalancers()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadBalancerState returns the health of the backends of the load balancer with the given listen address.
+func (n *bridge) LoadBalancerState(listenAddress string) (*api.NetworkLoadBalancerState, error) {
+	_, lb, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancerState(n.name, lb)
+}
+
 // Stop stops the network.
 func (n *bridge) Stop() error {
+	stopPrefixDelegation(n.name)
+
+	// Withdraw the prefixes of the network.
+	err := n.bgpClear()
+	if err != nil {
+		return err
+	}
+
 	if !n.isRunning() {
 		return nil
 	}
@@ -1255,6 +1853,21 @@ func (n *bridge) Stop() error {
 	}
 
 	// Cleanup firewall rules.
+	err = n.state.Firewall.NetworkClearACLRules(n.name)
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Firewall.NetworkClearForwards(n.name)
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Firewall.NetworkClearLoadBalancers(n.name)
+	if err != nil {
+		return err
+	}
+
 	if usesIPv4Firewall(n.config) {
 		err := n.state.Firewall.NetworkClear(n.name, 4)
 		if err != nil {
@@ -1270,7 +1883,9 @@ func (n *bridge) Stop() error {
 	}
 
 	// Kill any existing dnsmasq and forkdns daemon for this network
-	err := dnsmasq.Kill(n.name, false)
+	stopBuiltinDHCP(n.name)
+
+	err = dnsmasq.Kill(n.name, false)
 	if err != nil {
 		return err
 	}
@@ -1299,6 +1914,75 @@ func (n *bridge) Stop() error {
 	return nil
 }
 
+// setupExternalInterfaceVLANs adds the tagged VLAN memberships from bridge.vlan.tagged to an external interface
+// and removes those which were only present in the old config.
+func (n *bridge) setupExternalInterfaceVLANs(iface string, oldConfig map[string]string) error {
+	newVLANs := bridgeVLANList(n.config["bridge.vlan.tagged"])
+
+	for _, vlanID := range bridgeVLANList(oldConfig["bridge.vlan.tagged"]) {
+		if shared.StringInSlice(vlanID, newVLANs) {
+			continue
+		}
+
+		_, err := shared.RunCommand("bridge", "vlan", "del", "dev", iface, "vid", vlanID)
+		if err != nil {
+			n.logger.Warn("Failed removing VLAN from external interface", log.Ctx{"interface": iface, "vlan": vlanID, "err": err})
+		}
+	}
+
+	for _, vlanID := range newVLANs {
+		_, err := shared.RunCommand("bridge", "vlan", "add", "dev", iface, "vid", vlanID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding VLAN %s to external interface %q", vlanID, iface)
+		}
+	}
+
+	return nil
+}
+
+// setupMulticast applies the IGMP/MLD snooping settings of the native bridge. Unset keys are reset to the kernel
+// defaults so that removing a key from the config reverts its setting.
+func (n *bridge) setupMulticast() error {
+	settings := []struct {
+		key          string
+		option       string
+		defaultValue string
+	}{
+		{key: "bridge.multicast.snooping", option: "multicast_snooping", defaultValue: "1"},
+		{key: "bridge.multicast.querier", option: "multicast_querier", defaultValue: "0"},
+		{key: "bridge.multicast.igmp_version", option: "multicast_igmp_version", defaultValue: "2"},
+		{key: "bridge.multicast.mld_version", option: "multicast_mld_version", defaultValue: "1"},
+	}
+
+	for _, setting := range settings {
+		value := setting.defaultValue
+		if n.config[setting.key] != "" {
+			value = n.config[setting.key]
+
+			// Convert booleans to the sysfs representation.
+			if setting.key == "bridge.multicast.snooping" || setting.key == "bridge.multicast.querier" {
+				value = "0"
+				if shared.IsTrue(n.config[setting.key]) {
+					value = "1"
+				}
+			}
+		}
+
+		err := BridgeMulticastSet(n.name, setting.option, value)
+		if err != nil {
+			// Older kernels lack the IGMP/MLD version settings, only fail if they were requested.
+			if n.config[setting.key] == "" {
+				n.logger.Warn("Failed resetting bridge multicast setting", log.Ctx{"option": setting.option, "err": err})
+				continue
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Update updates the network. Accepts notification boolean indicating if this update request is coming from a
 // cluster notification, in which case do not update the database, just apply local changes needed.
 func (n *bridge) Update(newNetwork api.NetworkPut, clusterNotification bool) error {
@@ -1427,9 +2111,14 @@ func (n *bridge) spawnForkDNS(listenAddress string) error {
 	return nil
 }
 
-// HandleHeartbeat refreshes forkdns servers. Retrieves the IPv4 address of each cluster node (excluding ourselves)
-// for this network. It then updates the forkdns server list file if there are changes.
+// HandleHeartbeat refreshes the Wireguard mesh peers when the network uses one, otherwise it refreshes forkdns
+// servers. Retrieves the IPv4 address of each cluster node (excluding ourselves) for this network. It then updates
+// the forkdns server list file if there are changes.
 func (n *bridge) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
+	if shared.IsTrue(n.config["wireguard.mesh"]) {
+		return n.syncWireguardPeers()
+	}
+
 	addresses := []string{}
 	localAddress, err := node.HTTPSAddress(n.state.Node)
 	if err != nil {
@@ -1450,7 +2139,7 @@ func (n *bridge) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
 			return err
 		}
 
-		state, err := client.GetNetworkState(n.name)
+		state, err := client.UseProject(n.project).GetNetworkState(n.name)
 		if err != nil {
 			return err
 		}
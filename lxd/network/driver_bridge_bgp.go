@@ -0,0 +1,91 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// bgpOwner returns the owner name of the prefixes announced for the network.
+func (n *bridge) bgpOwner() string {
+	return fmt.Sprintf("network_%d", n.id)
+}
+
+// bgpForwardsOwner returns the owner name of the address forward prefixes announced for the network.
+func (n *bridge) bgpForwardsOwner() string {
+	return fmt.Sprintf("network_%d_forwards", n.id)
+}
+
+// bgpSetupSubnets announces the subnets and routes of the network which aren't NATed.
+func (n *bridge) bgpSetupSubnets() error {
+	err := n.state.BGP.RemovePrefixByOwner(n.bgpOwner())
+	if err != nil {
+		return err
+	}
+
+	for _, ipVersion := range []int{4, 6} {
+		keyPrefix := fmt.Sprintf("ipv%d", ipVersion)
+
+		address := n.config[fmt.Sprintf("%s.address", keyPrefix)]
+		if shared.StringInSlice(address, []string{"", "none"}) || shared.IsTrue(n.config[fmt.Sprintf("%s.nat", keyPrefix)]) {
+			continue
+		}
+
+		subnets := []string{address}
+		routes := n.config[fmt.Sprintf("%s.routes", keyPrefix)]
+		if routes != "" {
+			subnets = append(subnets, strings.Split(routes, ",")...)
+		}
+
+		for _, subnet := range subnets {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(subnet))
+			if err != nil {
+				return err
+			}
+
+			err = n.state.BGP.AddPrefix(*ipNet, nil, n.bgpOwner())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bgpSetupForwards announces the listen addresses of the address forwards of the network.
+func (n *bridge) bgpSetupForwards(forwards []*api.NetworkForward) error {
+	err := n.state.BGP.RemovePrefixByOwner(n.bgpForwardsOwner())
+	if err != nil {
+		return err
+	}
+
+	for _, forward := range forwards {
+		ip := net.ParseIP(forward.ListenAddress)
+		if ip == nil {
+			return fmt.Errorf("Invalid listen address %q", forward.ListenAddress)
+		}
+
+		err = n.state.BGP.AddPrefix(IPToNet(ip), nil, n.bgpForwardsOwner())
+		if err != nil {
+			return errors.Wrapf(err, "Failed announcing address forward %q", forward.ListenAddress)
+		}
+	}
+
+	return nil
+}
+
+// bgpClear withdraws all the prefixes announced for the network.
+func (n *bridge) bgpClear() error {
+	err := n.state.BGP.RemovePrefixByOwner(n.bgpOwner())
+	if err != nil {
+		return err
+	}
+
+	return n.state.BGP.RemovePrefixByOwner(n.bgpForwardsOwner())
+}
@@ -0,0 +1,194 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/dhcpd"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// builtinDHCPServers tracks the running built-in DHCP and DNS servers, keyed by network name.
+var builtinDHCPServers = map[string]*dhcpd.Server{}
+var builtinDHCPServersMu sync.Mutex
+
+// DHCPServerRunning returns whether a DHCP server (dnsmasq or the built-in one) runs for the network.
+func DHCPServerRunning(networkName string) bool {
+	builtinDHCPServersMu.Lock()
+	_, ok := builtinDHCPServers[networkName]
+	builtinDHCPServersMu.Unlock()
+
+	return ok || shared.PathExists(shared.VarPath("networks", networkName, "dnsmasq.pid"))
+}
+
+// stopBuiltinDHCP stops the built-in DHCP and DNS server of the network if running.
+func stopBuiltinDHCP(networkName string) {
+	builtinDHCPServersMu.Lock()
+	defer builtinDHCPServersMu.Unlock()
+
+	server, ok := builtinDHCPServers[networkName]
+	if !ok {
+		return
+	}
+
+	server.Stop()
+	delete(builtinDHCPServers, networkName)
+}
+
+// usesBuiltinDHCP returns whether the network uses the built-in DHCP and DNS server rather than dnsmasq.
+func (n *bridge) usesBuiltinDHCP() bool {
+	return n.config["dhcp.server"] == "builtin"
+}
+
+// startBuiltinDHCP starts the built-in DHCP and DNS server of the network with its current config.
+func (n *bridge) startBuiltinDHCP(mtu string) error {
+	stopBuiltinDHCP(n.name)
+
+	config := dhcpd.Config{
+		Interface:  n.name,
+		LeasesPath: shared.VarPath("networks", n.name, "dnsmasq.leases"),
+		HostsPath:  shared.VarPath("networks", n.name, "dnsmasq.hosts"),
+		OnLease:    n.builtinDHCPLeaseChanged,
+	}
+
+	mtuValue, err := strconv.ParseUint(mtu, 10, 32)
+	if err == nil {
+		config.MTU = uint32(mtuValue)
+	}
+
+	if n.config["dns.mode"] != "none" {
+		config.DNSDomain = n.config["dns.domain"]
+		if config.DNSDomain == "" {
+			config.DNSDomain = "lxd"
+		}
+
+		config.DNSDynamic = n.config["dns.mode"] == "dynamic"
+	}
+
+	for _, domain := range strings.Split(n.config["dns.search"], ",") {
+		domain = strings.TrimSpace(domain)
+		if domain != "" {
+			config.DNSSearch = append(config.DNSSearch, domain)
+		}
+	}
+
+	if !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) {
+		config.IPv4Address, config.IPv4Subnet, err = net.ParseCIDR(n.config["ipv4.address"])
+		if err != nil {
+			return err
+		}
+
+		config.IPv4Address = config.IPv4Address.To4()
+		config.IPv4DHCP = n.HasDHCPv4()
+		config.IPv4Gateway = net.ParseIP(n.config["ipv4.dhcp.gateway"])
+
+		for _, dhcpRange := range n.DHCPv4Ranges() {
+			config.IPv4Ranges = append(config.IPv4Ranges, dhcpd.Range{Start: dhcpRange.Start, End: dhcpRange.End})
+		}
+
+		if len(config.IPv4Ranges) == 0 {
+			config.IPv4Ranges = []dhcpd.Range{{Start: GetIP(config.IPv4Subnet, 2).To4(), End: GetIP(config.IPv4Subnet, -2).To4()}}
+		}
+
+		config.IPv4Expiry, err = parseDHCPExpiry(n.config["ipv4.dhcp.expiry"])
+		if err != nil {
+			return err
+		}
+	}
+
+	if !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
+		config.IPv6Address, config.IPv6Subnet, err = net.ParseCIDR(n.config["ipv6.address"])
+		if err != nil {
+			return err
+		}
+
+		config.IPv6DHCP = n.HasDHCPv6()
+		config.IPv6Stateful = config.IPv6DHCP && shared.IsTrue(n.config["ipv6.dhcp.stateful"])
+
+		for _, dhcpRange := range n.DHCPv6Ranges() {
+			config.IPv6Ranges = append(config.IPv6Ranges, dhcpd.Range{Start: dhcpRange.Start, End: dhcpRange.End})
+		}
+
+		if len(config.IPv6Ranges) == 0 {
+			config.IPv6Ranges = []dhcpd.Range{{Start: GetIP(config.IPv6Subnet, 2), End: GetIP(config.IPv6Subnet, -1)}}
+		}
+
+		config.IPv6Expiry, err = parseDHCPExpiry(n.config["ipv6.dhcp.expiry"])
+		if err != nil {
+			return err
+		}
+	}
+
+	server := dhcpd.NewServer(config, n.logger)
+	err = server.Start()
+	if err != nil {
+		return errors.Wrapf(err, "Failed starting built-in DHCP and DNS server")
+	}
+
+	builtinDHCPServersMu.Lock()
+	builtinDHCPServers[n.name] = server
+	builtinDHCPServersMu.Unlock()
+
+	return nil
+}
+
+// builtinDHCPLeaseChanged sends a lifecycle event when the built-in DHCP server grants or releases a lease.
+func (n *bridge) builtinDHCPLeaseChanged(lease dhcpd.Lease, released bool) {
+	action := "network-lease-created"
+	if released {
+		action = "network-lease-deleted"
+	}
+
+	context := map[string]interface{}{
+		"network":  n.name,
+		"address":  lease.Address.String(),
+		"hostname": lease.Hostname,
+	}
+
+	if lease.Hwaddr != nil {
+		context["hwaddr"] = lease.Hwaddr.String()
+	}
+
+	err := n.state.Events.SendLifecycle(project.Default, action, fmt.Sprintf("/1.0/networks/%s/leases", n.name), context)
+	if err != nil {
+		n.logger.Warn("Failed sending lease event", log.Ctx{"err": err})
+	}
+}
+
+// parseDHCPExpiry parses a DHCP lease expiry in the dnsmasq format, that is a number of seconds, minutes (m),
+// hours (h), days (d) or weeks (w), or "infinite". Defaults to one hour.
+func parseDHCPExpiry(value string) (time.Duration, error) {
+	if value == "" {
+		return time.Hour, nil
+	}
+
+	if value == "infinite" {
+		return time.Duration(math.MaxUint32) * time.Second, nil
+	}
+
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	unit := time.Second
+	number := value
+
+	multiplier, ok := units[value[len(value)-1]]
+	if ok {
+		unit = multiplier
+		number = value[:len(value)-1]
+	}
+
+	count, err := strconv.ParseUint(number, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid DHCP expiry %q", value)
+	}
+
+	return time.Duration(count) * unit, nil
+}
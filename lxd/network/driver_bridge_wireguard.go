@@ -0,0 +1,259 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// wireguardDefaultPort is the UDP port used by the Wireguard mesh when wireguard.port isn't set.
+const wireguardDefaultPort = "51820"
+
+// wireguardVXLANPort is the VXLAN port used inside the Wireguard mesh.
+const wireguardVXLANPort = "4789"
+
+// wireguardInterfaces returns the names of the Wireguard interface and of the VXLAN interface running over it.
+func (n *bridge) wireguardInterfaces() (string, string) {
+	return fmt.Sprintf("%s-wg", n.name), fmt.Sprintf("%s-wgx", n.name)
+}
+
+// wireguardPort returns the UDP port used by the Wireguard mesh.
+func (n *bridge) wireguardPort() string {
+	if n.config["wireguard.port"] != "" {
+		return n.config["wireguard.port"]
+	}
+
+	return wireguardDefaultPort
+}
+
+// wireguardAddress returns the address of a cluster member inside the Wireguard mesh, derived from its ID.
+func (n *bridge) wireguardAddress(nodeID int64) (net.IP, *net.IPNet, error) {
+	_, subnet, err := net.ParseCIDR(n.config["wireguard.subnet"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 63 && nodeID >= (int64(1)<<uint(bits-ones))-1 {
+		return nil, nil, fmt.Errorf("Wireguard subnet %q is too small for cluster member ID %d", n.config["wireguard.subnet"], nodeID)
+	}
+
+	return GetIP(subnet, nodeID), subnet, nil
+}
+
+// wireguardPublicKey returns the Wireguard public key of this member, generating its private key if needed.
+// The private key never leaves the member, only the public key is published in the cluster database.
+func (n *bridge) wireguardPublicKey() (string, string, error) {
+	keyPath := shared.VarPath("networks", n.name, "wireguard.key")
+
+	if !shared.PathExists(keyPath) {
+		privateKey, err := shared.RunCommand("wg", "genkey")
+		if err != nil {
+			return "", "", errors.Wrap(err, "Failed to generate Wireguard private key")
+		}
+
+		err = ioutil.WriteFile(keyPath, []byte(privateKey), 0600)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	f, err := os.Open(keyPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	var stdout bytes.Buffer
+	err = shared.RunCommandWithFds(f, &stdout, "wg", "pubkey")
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed to derive Wireguard public key")
+	}
+
+	return keyPath, strings.TrimSpace(stdout.String()), nil
+}
+
+// setupWireguardMesh creates the Wireguard interface of the mesh and the VXLAN interface which carries the bridge
+// traffic to the other cluster members over it, then publishes the public key of this member.
+func (n *bridge) setupWireguardMesh(mtu string) error {
+	_, err := exec.LookPath("wg")
+	if err != nil {
+		return fmt.Errorf("wireguard-tools is required for Wireguard meshes")
+	}
+
+	err = util.LoadModule("wireguard")
+	if err != nil {
+		return errors.Wrapf(err, "Error loading %q module", "wireguard")
+	}
+
+	keyPath, publicKey, err := n.wireguardPublicKey()
+	if err != nil {
+		return err
+	}
+
+	address, subnet, err := n.wireguardAddress(n.state.Cluster.GetNodeID())
+	if err != nil {
+		return err
+	}
+
+	// The Wireguard interface needs to fit the VXLAN encapsulated bridge frames.
+	mtuInt, err := strconv.ParseInt(mtu, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	wgName, vxlanName := n.wireguardInterfaces()
+
+	_, err = shared.RunCommand("ip", "link", "add", "dev", wgName, "type", "wireguard")
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("wg", "set", wgName, "private-key", keyPath, "listen-port", n.wireguardPort())
+	if err != nil {
+		return err
+	}
+
+	ones, _ := subnet.Mask.Size()
+	_, err = shared.RunCommand("ip", "-4", "addr", "add", fmt.Sprintf("%s/%d", address.String(), ones), "dev", wgName)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", wgName, "mtu", fmt.Sprintf("%d", mtuInt+50), "up")
+	if err != nil {
+		return err
+	}
+
+	// Use the network ID as VNI so that meshes of different networks don't clash.
+	_, err = shared.RunCommand("ip", "link", "add", "dev", vxlanName, "type", "vxlan", "id", fmt.Sprintf("%d", n.id), "local", address.String(), "dstport", wireguardVXLANPort, "dev", wgName)
+	if err != nil {
+		return err
+	}
+
+	err = AttachInterface(n.name, vxlanName)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", vxlanName, "mtu", mtu, "up")
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Cluster.UpsertNetworkWireguardPeer(n.id, publicKey)
+	if err != nil {
+		return errors.Wrap(err, "Failed to publish Wireguard public key")
+	}
+
+	return n.syncWireguardPeers()
+}
+
+// syncWireguardPeers configures the other cluster members which published a Wireguard public key for the network
+// as peers of the mesh, and removes the peers of members which are gone.
+func (n *bridge) syncWireguardPeers() error {
+	wgName, vxlanName := n.wireguardInterfaces()
+
+	// Nothing to do if the mesh isn't running on this member.
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", wgName)) {
+		return nil
+	}
+
+	peers, err := n.state.Cluster.GetNetworkWireguardPeers(n.id)
+	if err != nil {
+		return err
+	}
+
+	// Get the current peers and VXLAN destinations.
+	out, err := shared.RunCommand("wg", "show", wgName, "peers")
+	if err != nil {
+		return err
+	}
+
+	curKeys := strings.Fields(out)
+
+	out, err = shared.RunCommand("bridge", "fdb", "show", "dev", vxlanName)
+	if err != nil {
+		return err
+	}
+
+	curDsts := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "00:00:00:00:00:00" && fields[1] == "dst" {
+			curDsts = append(curDsts, fields[2])
+		}
+	}
+
+	localID := n.state.Cluster.GetNodeID()
+	keys := []string{}
+	dsts := []string{}
+	for _, peer := range peers {
+		if peer.NodeID == localID {
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(peer.NodeAddress)
+		if err != nil {
+			n.logger.Warn("Skipping Wireguard peer with invalid address", log.Ctx{"address": peer.NodeAddress, "err": err})
+			continue
+		}
+
+		address, _, err := n.wireguardAddress(peer.NodeID)
+		if err != nil {
+			return err
+		}
+
+		_, err = shared.RunCommand("wg", "set", wgName, "peer", peer.PublicKey, "endpoint", net.JoinHostPort(host, n.wireguardPort()), "allowed-ips", fmt.Sprintf("%s/32", address.String()), "persistent-keepalive", "25")
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, peer.PublicKey)
+
+		// Flood the broadcast and unknown unicast traffic to every member.
+		if !shared.StringInSlice(address.String(), curDsts) {
+			_, err = shared.RunCommand("bridge", "fdb", "append", "00:00:00:00:00:00", "dev", vxlanName, "dst", address.String())
+			if err != nil {
+				return err
+			}
+		}
+
+		dsts = append(dsts, address.String())
+	}
+
+	for _, key := range curKeys {
+		if shared.StringInSlice(key, keys) {
+			continue
+		}
+
+		_, err = shared.RunCommand("wg", "set", wgName, "peer", key, "remove")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, dst := range curDsts {
+		if shared.StringInSlice(dst, dsts) {
+			continue
+		}
+
+		_, err = shared.RunCommand("bridge", "fdb", "del", "00:00:00:00:00:00", "dev", vxlanName, "dst", dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
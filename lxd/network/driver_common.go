@@ -10,7 +10,9 @@ import (
 
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -30,6 +32,7 @@ type common struct {
 	logger      logger.Logger
 	state       *state.State
 	id          int64
+	project     string
 	name        string
 	netType     string
 	description string
@@ -37,9 +40,10 @@ type common struct {
 }
 
 // init initialise internal variables.
-func (n *common) init(state *state.State, id int64, name string, netType string, description string, config map[string]string) {
-	n.logger = logging.AddContext(logger.Log, log.Ctx{"driver": netType, "network": name})
+func (n *common) init(state *state.State, id int64, projectName string, name string, netType string, description string, config map[string]string) {
+	n.logger = logging.AddContext(logger.Log, log.Ctx{"driver": netType, "project": projectName, "network": name})
 	n.id = id
+	n.project = projectName
 	n.name = name
 	n.netType = netType
 	n.config = config
@@ -96,6 +100,16 @@ func (n *common) validate(config map[string]string, driverRules map[string]func(
 	return nil
 }
 
+// ID returns the network ID.
+func (n *common) ID() int64 {
+	return n.id
+}
+
+// Project returns the name of the project the network belongs to.
+func (n *common) Project() string {
+	return n.project
+}
+
 // Name returns the network name.
 func (n *common) Name() string {
 	return n.name
@@ -119,8 +133,30 @@ func (n *common) IsUsed() bool {
 		return true
 	}
 
+	// Networks with the same name in other projects aren't this one.
+	visible := map[string]bool{}
 	for _, inst := range insts {
-		if IsInUseByInstance(inst, n.name) {
+		_, ok := visible[inst.Project()]
+		if !ok {
+			err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+				networkProject, err := project.NetworkProject(tx, inst.Project(), n.name)
+				if err == db.ErrNoSuchObject {
+					return nil
+				}
+
+				if err != nil {
+					return err
+				}
+
+				visible[inst.Project()] = networkProject == n.project
+				return nil
+			})
+			if err != nil {
+				return true
+			}
+		}
+
+		if visible[inst.Project()] && IsInUseByInstance(inst, n.name) {
 			return true
 		}
 	}
@@ -206,14 +242,14 @@ func (n *common) update(applyNetwork api.NetworkPut, clusterNotification bool) e
 		}
 
 		err = notifier(func(client lxd.InstanceServer) error {
-			return client.UpdateNetwork(n.name, applyNetwork, "")
+			return client.UseProject(n.project).UpdateNetwork(n.name, applyNetwork, "")
 		})
 		if err != nil {
 			return err
 		}
 
 		// Update the database.
-		err = n.state.Cluster.UpdateNetwork(n.name, applyNetwork.Description, applyNetwork.Config)
+		err = n.state.Cluster.UpdateNetwork(n.project, n.name, applyNetwork.Description, applyNetwork.Config)
 		if err != nil {
 			return err
 		}
@@ -286,13 +322,13 @@ func (n *common) rename(newName string) error {
 	}
 
 	// Rename the database entry.
-	err := n.state.Cluster.RenameNetwork(n.name, newName)
+	err := n.state.Cluster.RenameNetwork(n.project, n.name, newName)
 	if err != nil {
 		return err
 	}
 
 	// Reinitialise internal name variable and logger context with new name.
-	n.init(n.state, n.id, newName, n.netType, n.description, n.config)
+	n.init(n.state, n.id, n.project, newName, n.netType, n.description, n.config)
 
 	return nil
 }
@@ -302,7 +338,7 @@ func (n *common) delete(clusterNotification bool) error {
 	// Only delete database record if not cluster notification.
 	if !clusterNotification {
 		// Remove the network from the database.
-		err := n.state.Cluster.DeleteNetwork(n.name)
+		err := n.state.Cluster.DeleteNetwork(n.project, n.name)
 		if err != nil {
 			return err
 		}
@@ -311,6 +347,41 @@ func (n *common) delete(clusterNotification bool) error {
 	return nil
 }
 
+// ForwardCreate returns ErrNotImplemented for drivers that don't support address forwards.
+func (n *common) ForwardCreate(forward api.NetworkForwardsPost) error {
+	return ErrNotImplemented
+}
+
+// ForwardUpdate returns ErrNotImplemented for drivers that don't support address forwards.
+func (n *common) ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error {
+	return ErrNotImplemented
+}
+
+// ForwardDelete returns ErrNotImplemented for drivers that don't support address forwards.
+func (n *common) ForwardDelete(listenAddress string) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerCreate returns ErrNotImplemented for drivers that don't support load balancers.
+func (n *common) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerUpdate returns ErrNotImplemented for drivers that don't support load balancers.
+func (n *common) LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerDelete returns ErrNotImplemented for drivers that don't support load balancers.
+func (n *common) LoadBalancerDelete(listenAddress string) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerState returns ErrNotImplemented for drivers that don't support load balancers.
+func (n *common) LoadBalancerState(listenAddress string) (*api.NetworkLoadBalancerState, error) {
+	return nil, ErrNotImplemented
+}
+
 // HandleHeartbeat is a no-op.
 func (n *common) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
 	return nil
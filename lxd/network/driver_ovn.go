@@ -0,0 +1,1254 @@
+package network
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/lxd/network/openvswitch"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// ovnChassisPriorityMax is the highest priority a chassis can have in an OVN HA chassis group.
+const ovnChassisPriorityMax = 32767
+
+// ovnGeneveTunnelMTU is the MTU that is safe to use when tunneling using geneve.
+const ovnGeneveTunnelMTU = 1442
+
+// ovnACLPriorityMax is the maximum priority of an OVN ACL.
+const ovnACLPriorityMax = 32767
+
+// ovnVolatileParentIPv4 is the config key storing the router's address on the parent network's IPv4 subnet.
+const ovnVolatileParentIPv4 = "volatile.network.ipv4.address"
+
+// ovnVolatileParentIPv6 is the config key storing the router's address on the parent network's IPv6 subnet.
+const ovnVolatileParentIPv6 = "volatile.network.ipv6.address"
+
+// ovnParentVars OVN object variables derived from parent network.
+type ovnParentVars struct {
+	// Router.
+	routerExtPortIPv4Net *net.IPNet
+	routerExtPortIPv6Net *net.IPNet
+	routerExtGwIPv4      net.IP
+	routerExtGwIPv6      net.IP
+
+	// External Switch.
+	extSwitchProviderName string
+
+	// DNS.
+	dnsIPv4 net.IP
+	dnsIPv6 net.IP
+}
+
+// ovnParentPortBridgeVars parent bridge port variables used for start/stop.
+type ovnParentPortBridgeVars struct {
+	ovsBridge string
+	parentEnd string
+	ovsEnd    string
+}
+
+// ovn represents a LXD OVN network.
+type ovn struct {
+	common
+}
+
+// fillConfig fills requested config with any default values.
+func (n *ovn) fillConfig(req *api.NetworksPost) error {
+	if req.Config["ipv4.address"] == "" {
+		req.Config["ipv4.address"] = "auto"
+	}
+
+	if req.Config["ipv6.address"] == "" {
+		content, err := ioutil.ReadFile("/proc/sys/net/ipv6/conf/default/disable_ipv6")
+		if err == nil && string(content) == "0\n" {
+			req.Config["ipv6.address"] = "auto"
+		}
+	}
+
+	return nil
+}
+
+// Validate network config.
+func (n *ovn) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		"network":       shared.IsNotEmpty,
+		"bridge.hwaddr": shared.IsAny,
+		"bridge.mtu":    shared.IsInt64,
+		"ipv4.address": func(value string) error {
+			if shared.IsOneOf(value, []string{"none", "auto"}) == nil {
+				return nil
+			}
+
+			return shared.IsNetworkAddressCIDRV4(value)
+		},
+		"ipv6.address": func(value string) error {
+			if shared.IsOneOf(value, []string{"none", "auto"}) == nil {
+				return nil
+			}
+
+			return shared.IsNetworkAddressCIDRV6(value)
+		},
+		"ipv4.nat":           shared.IsBool,
+		"ipv6.nat":           shared.IsBool,
+		"ipv6.dhcp.stateful": shared.IsBool,
+		"dns.domain":         shared.IsAny,
+		"dns.search":         shared.IsAny,
+		"security.acls":      ValidACLs(n.state),
+
+		// Volatile keys populated automatically as needed.
+		ovnVolatileParentIPv4: shared.IsNetworkAddressV4,
+		ovnVolatileParentIPv6: shared.IsNetworkAddressV6,
+	}
+
+	err := n.validate(config, rules)
+	if err != nil {
+		return err
+	}
+
+	// Peform composite key checks after per-key validation.
+	if config["bridge.mtu"] != "" {
+		mtu, err := strconv.ParseInt(config["bridge.mtu"], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid value for an integer: %s", config["bridge.mtu"])
+		}
+
+		if mtu > ovnGeneveTunnelMTU {
+			return fmt.Errorf("Maximum MTU for an OVN network is %d", ovnGeneveTunnelMTU)
+		}
+
+		if !shared.StringInSlice(config["ipv6.address"], []string{"", "none"}) && mtu < 1280 {
+			return fmt.Errorf("The minimum MTU for an IPv6 network is 1280")
+		}
+	}
+
+	return nil
+}
+
+// getClient initialises OVN client and returns it.
+func (n *ovn) getClient() (*openvswitch.OVN, error) {
+	client, err := openvswitch.NewOVN(n.state)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get OVN client")
+	}
+
+	return client, nil
+}
+
+// getParentNetwork loads the managed bridge or physical network providing external connectivity to this network.
+func (n *ovn) getParentNetwork() (Network, error) {
+	parentNet, err := LoadVisibleByName(n.state, n.project, n.config["network"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading parent network %q", n.config["network"])
+	}
+
+	if !shared.StringInSlice(parentNet.Type(), []string{"bridge", "physical"}) {
+		return nil, fmt.Errorf("Parent network %q must be of type bridge or physical", parentNet.Name())
+	}
+
+	return parentNet, nil
+}
+
+// parentAddressKey returns the config key of the parent network holding its gateway address and subnet for the
+// IP family. Bridge networks are the gateway themselves, physical networks describe the gateway of the uplink.
+func (n *ovn) parentAddressKey(parentNet Network, family int) string {
+	if parentNet.Type() == "physical" {
+		return fmt.Sprintf("ipv%d.gateway", family)
+	}
+
+	return fmt.Sprintf("ipv%d.address", family)
+}
+
+// natEnabled returns whether outbound NAT is used for the IP family, the default.
+func (n *ovn) natEnabled(family int) bool {
+	value := n.config[fmt.Sprintf("ipv%d.nat", family)]
+	return value == "" || shared.IsTrue(value)
+}
+
+// checkParentRoutes checks that the subnets of the families not using NAT are within the routes the physical
+// parent network allows, as the uplink must route them to the network.
+func (n *ovn) checkParentRoutes(parentNet Network) error {
+	if parentNet.Type() != "physical" {
+		return nil
+	}
+
+	for _, family := range []int{4, 6} {
+		addressKey := fmt.Sprintf("ipv%d.address", family)
+		routesKey := fmt.Sprintf("ipv%d.routes", family)
+
+		if shared.StringInSlice(n.config[addressKey], []string{"", "none"}) || n.natEnabled(family) {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(n.config[addressKey])
+		if err != nil {
+			return errors.Wrapf(err, "Invalid %q", addressKey)
+		}
+
+		allowed := false
+		for _, route := range strings.Split(parentNet.Config()[routesKey], ",") {
+			_, routeNet, err := net.ParseCIDR(strings.TrimSpace(route))
+			if err != nil {
+				continue
+			}
+
+			routeOnes, _ := routeNet.Mask.Size()
+			subnetOnes, _ := subnet.Mask.Size()
+			if routeNet.Contains(subnet.IP) && subnetOnes >= routeOnes {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("Subnet %q isn't within the %q of parent network %q", subnet.String(), routesKey, parentNet.Name())
+		}
+	}
+
+	return nil
+}
+
+// getParentPortBridgeVars returns the names of the OVS bridge and veth pair connecting the parent network to OVN.
+func (n *ovn) getParentPortBridgeVars(parentNet Network) *ovnParentPortBridgeVars {
+	ovsBridge := fmt.Sprintf("lxdovn%d", parentNet.ID())
+
+	return &ovnParentPortBridgeVars{
+		ovsBridge: ovsBridge,
+		parentEnd: fmt.Sprintf("%sa", ovsBridge),
+		ovsEnd:    fmt.Sprintf("%sb", ovsBridge),
+	}
+}
+
+// getRouterName returns the OVN logical router name to use.
+func (n *ovn) getRouterName() openvswitch.OVNRouter {
+	return openvswitch.OVNRouter(fmt.Sprintf("lxd-net%d-lr", n.id))
+}
+
+// getRouterExtPortName returns the OVN logical router external port name to use.
+func (n *ovn) getRouterExtPortName() openvswitch.OVNRouterPort {
+	return openvswitch.OVNRouterPort(fmt.Sprintf("%s-lrp-ext", n.getRouterName()))
+}
+
+// getRouterIntPortName returns the OVN logical router internal port name to use.
+func (n *ovn) getRouterIntPortName() openvswitch.OVNRouterPort {
+	return openvswitch.OVNRouterPort(fmt.Sprintf("%s-lrp-int", n.getRouterName()))
+}
+
+// getExtSwitchName returns the OVN logical external switch name.
+func (n *ovn) getExtSwitchName() openvswitch.OVNSwitch {
+	return openvswitch.OVNSwitch(fmt.Sprintf("lxd-net%d-ls-ext", n.id))
+}
+
+// getExtSwitchRouterPortName returns OVN logical external switch router port name.
+func (n *ovn) getExtSwitchRouterPortName() openvswitch.OVNSwitchPort {
+	return openvswitch.OVNSwitchPort(fmt.Sprintf("%s-lsp-router", n.getExtSwitchName()))
+}
+
+// getExtSwitchProviderPortName returns OVN logical external switch provider port name.
+func (n *ovn) getExtSwitchProviderPortName() openvswitch.OVNSwitchPort {
+	return openvswitch.OVNSwitchPort(fmt.Sprintf("%s-lsp-provider", n.getExtSwitchName()))
+}
+
+// getIntSwitchName returns the OVN logical internal switch name.
+func (n *ovn) getIntSwitchName() openvswitch.OVNSwitch {
+	return openvswitch.OVNSwitch(fmt.Sprintf("lxd-net%d-ls-int", n.id))
+}
+
+// getIntSwitchRouterPortName returns OVN logical internal switch router port name.
+func (n *ovn) getIntSwitchRouterPortName() openvswitch.OVNSwitchPort {
+	return openvswitch.OVNSwitchPort(fmt.Sprintf("%s-lsp-router", n.getIntSwitchName()))
+}
+
+// getIntSwitchInstancePortName returns OVN logical internal switch port name to use for an instance device.
+func (n *ovn) getIntSwitchInstancePortName(instanceID int, deviceName string) openvswitch.OVNSwitchPort {
+	return openvswitch.OVNSwitchPort(fmt.Sprintf("lxd-net%d-instance-%d-%s", n.id, instanceID, deviceName))
+}
+
+// getChassisGroupName returns OVN chassis group name to use.
+func (n *ovn) getChassisGroupName() openvswitch.OVNChassisGroup {
+	return openvswitch.OVNChassisGroup(fmt.Sprintf("lxd-net%d", n.id))
+}
+
+// getMTU returns the MTU to use for the network.
+func (n *ovn) getMTU() uint32 {
+	if n.config["bridge.mtu"] != "" {
+		mtu, err := strconv.ParseUint(n.config["bridge.mtu"], 10, 32)
+		if err == nil {
+			return uint32(mtu)
+		}
+	}
+
+	return ovnGeneveTunnelMTU
+}
+
+// getMAC returns a stable MAC address in the LXD range derived from the network ID and supplied suffix.
+func (n *ovn) getMAC(suffix string) net.HardwareAddr {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("lxd-net%d-%s", n.id, suffix)))
+	return net.HardwareAddr{0x00, 0x16, 0x3e, hash[0], hash[1], hash[2]}
+}
+
+// getRouterIntPortMAC returns the MAC address of the router's internal port.
+func (n *ovn) getRouterIntPortMAC() (net.HardwareAddr, error) {
+	if n.config["bridge.hwaddr"] != "" {
+		mac, err := net.ParseMAC(n.config["bridge.hwaddr"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid bridge.hwaddr")
+		}
+
+		return mac, nil
+	}
+
+	return n.getMAC("int"), nil
+}
+
+// getRouterIntPortNet parses the network's address for the given IP family (4 or 6), returning the router's
+// address and the subnet. Returns nil values if the family is disabled.
+func (n *ovn) getRouterIntPortNet(family int) (*net.IPNet, *net.IPNet, error) {
+	key := fmt.Sprintf("ipv%d.address", family)
+	if shared.StringInSlice(n.config[key], []string{"", "none"}) {
+		return nil, nil, nil
+	}
+
+	ip, subnet, err := net.ParseCIDR(n.config[key])
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Invalid %s", key)
+	}
+
+	return &net.IPNet{IP: ip, Mask: subnet.Mask}, subnet, nil
+}
+
+// getDNSSearchList returns the DNS search list to advertise to instances.
+func (n *ovn) getDNSSearchList() []string {
+	if n.config["dns.search"] != "" {
+		return strings.Split(n.config["dns.search"], ",")
+	}
+
+	return []string{n.getDomainName()}
+}
+
+// getDomainName returns the DNS domain of the network.
+func (n *ovn) getDomainName() string {
+	if n.config["dns.domain"] != "" {
+		return n.config["dns.domain"]
+	}
+
+	return "lxd"
+}
+
+// allocateParentPortIPs attempts to find a free IP in the parent network's OVN ranges for each enabled IP family
+// and then stores it in the ovnVolatileParentIPv4 and ovnVolatileParentIPv6 config keys of this network.
+func (n *ovn) allocateParentPortIPs(parentNet Network) error {
+	parentConfig := parentNet.Config()
+	allocated := false
+
+	for _, family := range []int{4, 6} {
+		volatileKey := ovnVolatileParentIPv4
+		if family == 6 {
+			volatileKey = ovnVolatileParentIPv6
+		}
+
+		addressKey := fmt.Sprintf("ipv%d.address", family)
+		parentAddressKey := n.parentAddressKey(parentNet, family)
+		rangesKey := fmt.Sprintf("ipv%d.ovn.ranges", family)
+
+		// Skip families that are disabled on either side or already allocated.
+		if n.config[volatileKey] != "" || shared.StringInSlice(n.config[addressKey], []string{"", "none"}) || shared.StringInSlice(parentConfig[parentAddressKey], []string{"", "none"}) {
+			continue
+		}
+
+		if parentConfig[rangesKey] == "" {
+			return fmt.Errorf("Missing required %q config key on parent network %q", rangesKey, parentNet.Name())
+		}
+
+		ipRanges, err := parseIPRanges(parentConfig[rangesKey])
+		if err != nil {
+			return errors.Wrapf(err, "Failed parsing %q on parent network %q", rangesKey, parentNet.Name())
+		}
+
+		usedIPs, err := n.parentUsedIPs(parentNet, volatileKey)
+		if err != nil {
+			return err
+		}
+
+		var freeIP net.IP
+		for _, ipRange := range ipRanges {
+			for ip := ipRange.Start; ipRange.ContainsIP(ip); ip = nextIP(ip) {
+				if !shared.StringInSlice(ip.String(), usedIPs) {
+					freeIP = ip
+					break
+				}
+			}
+
+			if freeIP != nil {
+				break
+			}
+		}
+
+		if freeIP == nil {
+			return fmt.Errorf("No free IPv%d addresses available in %q on parent network %q", family, rangesKey, parentNet.Name())
+		}
+
+		n.config[volatileKey] = freeIP.String()
+		allocated = true
+	}
+
+	if allocated {
+		err := n.state.Cluster.UpdateNetwork(n.project, n.name, n.description, n.config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed saving allocated parent network IPs")
+		}
+	}
+
+	return nil
+}
+
+// parentUsedIPs returns the addresses stored in volatileKey by the other OVN networks using the parent network.
+func (n *ovn) parentUsedIPs(parentNet Network, volatileKey string) ([]string, error) {
+	networks, err := n.state.Cluster.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	usedIPs := []string{}
+	for projectName, names := range networks {
+		for _, name := range names {
+			if projectName == n.project && name == n.name {
+				continue
+			}
+
+			otherNet, err := LoadByName(n.state, projectName, name)
+			if err != nil {
+				continue
+			}
+
+			otherConfig := otherNet.Config()
+			if otherNet.Type() != "ovn" || otherConfig["network"] != parentNet.Name() || otherConfig[volatileKey] == "" {
+				continue
+			}
+
+			usedIPs = append(usedIPs, net.ParseIP(otherConfig[volatileKey]).String())
+		}
+	}
+
+	return usedIPs, nil
+}
+
+// getParentVars returns the OVN object variables derived from the parent network.
+func (n *ovn) getParentVars(parentNet Network) (*ovnParentVars, error) {
+	parentConfig := parentNet.Config()
+	v := &ovnParentVars{
+		extSwitchProviderName: parentNet.Name(),
+	}
+
+	if n.config[ovnVolatileParentIPv4] != "" {
+		gwIP, subnet, err := net.ParseCIDR(parentConfig[n.parentAddressKey(parentNet, 4)])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid %s on parent network %q", n.parentAddressKey(parentNet, 4), parentNet.Name())
+		}
+
+		v.routerExtPortIPv4Net = &net.IPNet{IP: net.ParseIP(n.config[ovnVolatileParentIPv4]), Mask: subnet.Mask}
+		v.routerExtGwIPv4 = gwIP
+		v.dnsIPv4 = gwIP
+	}
+
+	if n.config[ovnVolatileParentIPv6] != "" {
+		gwIP, subnet, err := net.ParseCIDR(parentConfig[n.parentAddressKey(parentNet, 6)])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid %s on parent network %q", n.parentAddressKey(parentNet, 6), parentNet.Name())
+		}
+
+		v.routerExtPortIPv6Net = &net.IPNet{IP: net.ParseIP(n.config[ovnVolatileParentIPv6]), Mask: subnet.Mask}
+		v.routerExtGwIPv6 = gwIP
+		v.dnsIPv6 = gwIP
+	}
+
+	// Physical networks can point to nameservers other than their gateway.
+	if parentNet.Type() == "physical" {
+		for _, nameserver := range strings.Split(parentConfig["dns.nameservers"], ",") {
+			ip := net.ParseIP(strings.TrimSpace(nameserver))
+			if ip == nil {
+				continue
+			}
+
+			if ip.To4() != nil && v.routerExtPortIPv4Net != nil {
+				v.dnsIPv4 = ip
+			} else if ip.To4() == nil && v.routerExtPortIPv6Net != nil {
+				v.dnsIPv6 = ip
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// startParentPort connects the parent network to the OVN integration bridge on this node by way of a dedicated
+// OVS bridge, and maps that bridge to the parent network's provider name. Bridge networks are connected to the OVS
+// bridge using a veth pair, the interface of physical networks is added to it directly.
+func (n *ovn) startParentPort(parentNet Network) error {
+	ovs := openvswitch.NewOVS()
+	if !ovs.Installed() {
+		return fmt.Errorf("Open vSwitch isn't installed on this system")
+	}
+
+	parentDev := parentNet.Name()
+	if parentNet.Type() == "physical" {
+		parentDev = GetHostDevice(parentNet.Config()["parent"], parentNet.Config()["vlan"])
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", parentDev)) {
+		return fmt.Errorf("Parent network %q isn't running on this system", parentNet.Name())
+	}
+
+	vars := n.getParentPortBridgeVars(parentNet)
+
+	err := ovs.BridgeAdd(vars.ovsBridge, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create parent OVS bridge %q", vars.ovsBridge)
+	}
+
+	if parentNet.Type() == "physical" {
+		err = ovs.BridgePortAdd(vars.ovsBridge, parentDev, true)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to connect %q to parent OVS bridge %q", parentDev, vars.ovsBridge)
+		}
+
+		return n.mapParentBridge(ovs, parentNet, vars)
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", vars.parentEnd)) {
+		_, err = shared.RunCommand("ip", "link", "add", "dev", vars.parentEnd, "type", "veth", "peer", "name", vars.ovsEnd)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create the parent veth pair %q and %q", vars.parentEnd, vars.ovsEnd)
+		}
+	}
+
+	for _, iface := range []string{vars.parentEnd, vars.ovsEnd} {
+		_, err = shared.RunCommand("ip", "link", "set", "dev", iface, "up")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to bring up parent veth interface %q", iface)
+		}
+	}
+
+	err = AttachInterface(parentNet.Name(), vars.parentEnd)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to connect %q to parent network %q", vars.parentEnd, parentNet.Name())
+	}
+
+	err = ovs.BridgePortAdd(vars.ovsBridge, vars.ovsEnd, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to connect %q to parent OVS bridge %q", vars.ovsEnd, vars.ovsBridge)
+	}
+
+	return n.mapParentBridge(ovs, parentNet, vars)
+}
+
+// mapParentBridge maps the parent OVS bridge to the parent network's OVN provider name.
+func (n *ovn) mapParentBridge(ovs *openvswitch.OVS, parentNet Network, vars *ovnParentPortBridgeVars) error {
+	parent, err := n.getParentVars(parentNet)
+	if err != nil {
+		return err
+	}
+
+	err = ovs.OVNBridgeMappingAdd(vars.ovsBridge, parent.extSwitchProviderName)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to associate parent OVS bridge %q to OVN provider %q", vars.ovsBridge, parent.extSwitchProviderName)
+	}
+
+	return nil
+}
+
+// Start starts the network. This ensures the logical network exists and adds this node as a candidate gateway
+// for the network's external router port.
+func (n *ovn) Start() error {
+	// If we are in mock mode, just no-op.
+	if n.state.OS.MockMode {
+		return nil
+	}
+
+	parentNet, err := n.getParentNetwork()
+	if err != nil {
+		return err
+	}
+
+	err = n.setup(parentNet, false)
+	if err != nil {
+		return err
+	}
+
+	err = n.startParentPort(parentNet)
+	if err != nil {
+		return err
+	}
+
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	chassisID, err := openvswitch.NewOVS().ChassisID()
+	if err != nil {
+		return errors.Wrapf(err, "Failed getting OVS Chassis ID")
+	}
+
+	// Spread the gateway role amongst the cluster members by using a random priority.
+	err = client.ChassisGroupChassisAdd(n.getChassisGroupName(), chassisID, uint(rand.Intn(ovnChassisPriorityMax+1)))
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding OVS chassis %q to chassis group %q", chassisID, n.getChassisGroupName())
+	}
+
+	return nil
+}
+
+// setup creates or updates the logical router and switches making up the network. The objects are shared by
+// all cluster members so this is safe to run on each of them. When update is true, the router ports, NAT rules
+// and routes are recreated so that changes of address are applied.
+func (n *ovn) setup(parentNet Network, update bool) error {
+	n.logger.Debug("Setting up network")
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	err = n.checkParentRoutes(parentNet)
+	if err != nil {
+		return err
+	}
+
+	err = n.allocateParentPortIPs(parentNet)
+	if err != nil {
+		return errors.Wrapf(err, "Failed allocating parent port IPs on network %q", parentNet.Name())
+	}
+
+	parent, err := n.getParentVars(parentNet)
+	if err != nil {
+		return err
+	}
+
+	routerIntPortIPv4Net, intSubnetV4, err := n.getRouterIntPortNet(4)
+	if err != nil {
+		return err
+	}
+
+	routerIntPortIPv6Net, intSubnetV6, err := n.getRouterIntPortNet(6)
+	if err != nil {
+		return err
+	}
+
+	routerIntPortMAC, err := n.getRouterIntPortMAC()
+	if err != nil {
+		return err
+	}
+
+	routerExtPortMAC := n.getMAC("ext")
+
+	// Create chassis group.
+	err = client.ChassisGroupAdd(n.getChassisGroupName(), true)
+	if err != nil {
+		return err
+	}
+
+	if !update {
+		revert.Add(func() { client.ChassisGroupDelete(n.getChassisGroupName()) })
+	}
+
+	// Create logical router.
+	err = client.LogicalRouterAdd(n.getRouterName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding router")
+	}
+
+	if !update {
+		revert.Add(func() { client.LogicalRouterDelete(n.getRouterName()) })
+	}
+
+	// Remove the router's ports, NAT rules and routes so they can be recreated with the new settings.
+	if update {
+		for _, portName := range []openvswitch.OVNRouterPort{n.getRouterExtPortName(), n.getRouterIntPortName()} {
+			err = client.LogicalRouterPortDelete(portName)
+			if err != nil {
+				return errors.Wrapf(err, "Failed deleting router port %q", portName)
+			}
+		}
+
+		err = client.LogicalRouterNATDeleteAll(n.getRouterName())
+		if err != nil {
+			return errors.Wrapf(err, "Failed deleting router NAT rules")
+		}
+
+		err = client.LogicalRouterRouteDeleteAll(n.getRouterName())
+		if err != nil {
+			return errors.Wrapf(err, "Failed deleting router routes")
+		}
+	}
+
+	// Configure logical router, networks not using NAT are routed by the parent network.
+	if parent.routerExtPortIPv4Net != nil && intSubnetV4 != nil && n.natEnabled(4) {
+		err = client.LogicalRouterSNATAdd(n.getRouterName(), intSubnetV4, parent.routerExtPortIPv4Net.IP)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding router IPv4 SNAT rule")
+		}
+	}
+
+	if parent.routerExtPortIPv6Net != nil && intSubnetV6 != nil && n.natEnabled(6) {
+		err = client.LogicalRouterSNATAdd(n.getRouterName(), intSubnetV6, parent.routerExtPortIPv6Net.IP)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding router IPv6 SNAT rule")
+		}
+	}
+
+	if parent.routerExtGwIPv4 != nil {
+		err = client.LogicalRouterRouteAdd(n.getRouterName(), &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}, parent.routerExtGwIPv4)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding IPv4 default route")
+		}
+	}
+
+	if parent.routerExtGwIPv6 != nil {
+		err = client.LogicalRouterRouteAdd(n.getRouterName(), &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}, parent.routerExtGwIPv6)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding IPv6 default route")
+		}
+	}
+
+	// Create external logical switch and connect it to the router and the parent network.
+	err = client.LogicalSwitchAdd(n.getExtSwitchName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding external switch")
+	}
+
+	if !update {
+		revert.Add(func() { client.LogicalSwitchDelete(n.getExtSwitchName()) })
+	}
+
+	extRouterNets := []*net.IPNet{}
+	for _, ipNet := range []*net.IPNet{parent.routerExtPortIPv4Net, parent.routerExtPortIPv6Net} {
+		if ipNet != nil {
+			extRouterNets = append(extRouterNets, ipNet)
+		}
+	}
+
+	err = client.LogicalRouterPortAdd(n.getRouterName(), n.getRouterExtPortName(), routerExtPortMAC, extRouterNets...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding external router port")
+	}
+
+	err = client.LogicalRouterPortLinkChassisGroup(n.getRouterExtPortName(), n.getChassisGroupName())
+	if err != nil {
+		return errors.Wrapf(err, "Failed linking external router port to chassis group")
+	}
+
+	err = client.LogicalSwitchPortAdd(n.getExtSwitchName(), n.getExtSwitchRouterPortName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding external switch router port")
+	}
+
+	err = client.LogicalSwitchPortLinkRouter(n.getExtSwitchRouterPortName(), n.getRouterExtPortName())
+	if err != nil {
+		return errors.Wrapf(err, "Failed linking external router port to external switch port")
+	}
+
+	err = client.LogicalSwitchPortAdd(n.getExtSwitchName(), n.getExtSwitchProviderPortName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding external switch provider port")
+	}
+
+	err = client.LogicalSwitchPortLinkProviderNetwork(n.getExtSwitchProviderPortName(), parent.extSwitchProviderName)
+	if err != nil {
+		return errors.Wrapf(err, "Failed linking external switch provider port to external provider network")
+	}
+
+	// Create internal logical switch and connect it to the router.
+	err = client.LogicalSwitchAdd(n.getIntSwitchName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding internal switch")
+	}
+
+	if !update {
+		revert.Add(func() { client.LogicalSwitchDelete(n.getIntSwitchName()) })
+	}
+
+	intRouterNets := []*net.IPNet{}
+	for _, ipNet := range []*net.IPNet{routerIntPortIPv4Net, routerIntPortIPv6Net} {
+		if ipNet != nil {
+			intRouterNets = append(intRouterNets, ipNet)
+		}
+	}
+
+	err = client.LogicalRouterPortAdd(n.getRouterName(), n.getRouterIntPortName(), routerIntPortMAC, intRouterNets...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding internal router port")
+	}
+
+	err = client.LogicalSwitchPortAdd(n.getIntSwitchName(), n.getIntSwitchRouterPortName(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding internal switch router port")
+	}
+
+	err = client.LogicalSwitchPortLinkRouter(n.getIntSwitchRouterPortName(), n.getRouterIntPortName())
+	if err != nil {
+		return errors.Wrapf(err, "Failed linking internal router port to internal switch port")
+	}
+
+	// Configure dynamic IP allocation on the internal switch, excluding the router's own address.
+	ipAllocationOpts := &openvswitch.OVNIPAllocationOpts{
+		PrefixIPv4: intSubnetV4,
+		PrefixIPv6: intSubnetV6,
+	}
+
+	if routerIntPortIPv4Net != nil {
+		ipAllocationOpts.ExcludeIPv4 = []net.IP{routerIntPortIPv4Net.IP}
+	}
+
+	err = client.LogicalSwitchSetIPAllocation(n.getIntSwitchName(), ipAllocationOpts)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting IP allocation settings on internal switch")
+	}
+
+	// Configure distributed DHCPv4 and IPv6 router advertisements.
+	if routerIntPortIPv4Net != nil {
+		_, err = client.LogicalSwitchDHCPv4OptionsSet(n.getIntSwitchName(), intSubnetV4, &openvswitch.OVNDHCPv4Opts{
+			ServerID:           routerIntPortIPv4Net.IP,
+			ServerMAC:          routerIntPortMAC,
+			Router:             routerIntPortIPv4Net.IP,
+			RecursiveDNSServer: parent.dnsIPv4,
+			DomainName:         n.getDomainName(),
+			LeaseTime:          time.Hour,
+			MTU:                n.getMTU(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding DHCPv4 settings for internal switch")
+		}
+	}
+
+	if routerIntPortIPv6Net != nil {
+		_, err = client.LogicalSwitchDHCPv6OptionsSet(n.getIntSwitchName(), intSubnetV6, &openvswitch.OVNDHCPv6Opts{
+			ServerID:           routerIntPortMAC,
+			RecursiveDNSServer: parent.dnsIPv6,
+			DNSSearchList:      n.getDNSSearchList(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding DHCPv6 settings for internal switch")
+		}
+
+		// Use SLAAC along with stateless DHCPv6 for the DNS settings, unless stateful DHCPv6 is enabled.
+		addressMode := openvswitch.OVNIPv6AddressModeDHCPStateless
+		if shared.IsTrue(n.config["ipv6.dhcp.stateful"]) {
+			addressMode = openvswitch.OVNIPv6AddressModeDHCPStateful
+		}
+
+		err = client.LogicalRouterPortSetIPv6Advertisements(n.getRouterIntPortName(), &openvswitch.OVNIPv6RAOpts{
+			AddressMode:        addressMode,
+			SendPeriodic:       true,
+			DNSSearchList:      n.getDNSSearchList(),
+			RecursiveDNSServer: parent.dnsIPv6,
+			MTU:                n.getMTU(),
+
+			// Keep these low until we support DNS search domains via DHCPv4, as otherwise RA DNSSL
+			// won't take effect until advert after DHCPv4 has run on instance.
+			MinInterval: 30 * time.Second,
+			MaxInterval: time.Minute,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting internal router port IPv6 advertisement settings")
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// Stop stops the network. This removes this node from the candidate gateways of the network.
+func (n *ovn) Stop() error {
+	// If we are in mock mode, just no-op.
+	if n.state.OS.MockMode {
+		return nil
+	}
+
+	ovs := openvswitch.NewOVS()
+	if !ovs.Installed() {
+		return nil
+	}
+
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	chassisID, err := ovs.ChassisID()
+	if err != nil {
+		return errors.Wrapf(err, "Failed getting OVS Chassis ID")
+	}
+
+	err = client.ChassisGroupChassisDelete(n.getChassisGroupName(), chassisID)
+	if err != nil {
+		n.logger.Warn("Failed removing OVS chassis from chassis group", log.Ctx{"chassis": chassisID, "err": err})
+	}
+
+	return nil
+}
+
+// Delete deletes a network.
+func (n *ovn) Delete(clusterNotification bool) error {
+	n.logger.Debug("Delete", log.Ctx{"clusterNotification": clusterNotification})
+
+	err := n.Stop()
+	if err != nil {
+		return err
+	}
+
+	// The logical objects are shared by all cluster members, so only remove them once.
+	if !clusterNotification && !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		err = client.LogicalRouterDelete(n.getRouterName())
+		if err != nil {
+			return err
+		}
+
+		err = client.LogicalSwitchDelete(n.getExtSwitchName())
+		if err != nil {
+			return err
+		}
+
+		err = client.LogicalSwitchDelete(n.getIntSwitchName())
+		if err != nil {
+			return err
+		}
+
+		err = client.ChassisGroupDelete(n.getChassisGroupName())
+		if err != nil {
+			return err
+		}
+	}
+
+	return n.common.delete(clusterNotification)
+}
+
+// Rename renames a network.
+func (n *ovn) Rename(newName string) error {
+	n.logger.Debug("Rename", log.Ctx{"newName": newName})
+
+	// Sanity checks.
+	if n.IsUsed() {
+		return fmt.Errorf("The network is currently in use")
+	}
+
+	// The logical objects are named after the network ID, so only the common steps are needed.
+	err := n.common.rename(newName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Update updates the network. Accepts notification boolean indicating if this update request is coming from a
+// cluster notification, in which case do not update the database, just apply local changes needed.
+func (n *ovn) Update(newNetwork api.NetworkPut, clusterNotification bool) error {
+	n.logger.Debug("Update", log.Ctx{"clusterNotification": clusterNotification})
+
+	// Populate auto fields.
+	err := fillAuto(newNetwork.Config)
+	if err != nil {
+		return err
+	}
+
+	dbUpdateNeeeded, changedKeys, oldNetwork, err := n.common.configChanged(newNetwork)
+	if err != nil {
+		return err
+	}
+
+	if !dbUpdateNeeeded {
+		return nil // Nothing changed.
+	}
+
+	// Allocate new addresses on the new parent network.
+	parentChanged := shared.StringInSlice("network", changedKeys)
+	if parentChanged {
+		delete(newNetwork.Config, ovnVolatileParentIPv4)
+		delete(newNetwork.Config, ovnVolatileParentIPv6)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Define a function which reverts everything.
+	revert.Add(func() {
+		// Reset changes to all nodes and database.
+		n.common.update(oldNetwork, clusterNotification)
+
+		// Reset any change that was made to the logical network.
+		if !clusterNotification && !n.state.OS.MockMode {
+			parentNet, err := n.getParentNetwork()
+			if err == nil {
+				n.setup(parentNet, true)
+			}
+		}
+	})
+
+	// Apply changes to database.
+	err = n.common.update(newNetwork, clusterNotification)
+	if err != nil {
+		return err
+	}
+
+	if len(changedKeys) > 0 && !n.state.OS.MockMode {
+		parentNet, err := n.getParentNetwork()
+		if err != nil {
+			return err
+		}
+
+		// Each node needs to be connected to the new parent network.
+		if parentChanged {
+			err = n.startParentPort(parentNet)
+			if err != nil {
+				return err
+			}
+		}
+
+		// The logical objects are shared by all cluster members, so only update them once.
+		if !clusterNotification {
+			err = n.setup(parentNet, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Each node re-applies the ACLs to the ports of its own running instances.
+		if shared.StringInSlice("security.acls", changedKeys) {
+			err = aclRefreshInstanceDevices(n.state, func(dev deviceConfig.Device, aclNames []string) bool {
+				return dev.NICType() == "ovn" && dev["network"] == n.name
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// InstanceDevicePortAdd adds an instance device port to the internal logical switch and returns the port name.
+func (n *ovn) InstanceDevicePortAdd(instanceID int, deviceName string, mac net.HardwareAddr, ips []net.IP) (openvswitch.OVNSwitchPort, error) {
+	client, err := n.getClient()
+	if err != nil {
+		return "", err
+	}
+
+	portOpts := &openvswitch.OVNSwitchPortOpts{
+		MAC: mac,
+		IPs: ips,
+	}
+
+	for _, family := range []int{4, 6} {
+		_, subnet, err := n.getRouterIntPortNet(family)
+		if err != nil {
+			return "", err
+		}
+
+		if subnet == nil {
+			continue
+		}
+
+		dhcpOptsID, err := client.LogicalSwitchDHCPOptionsGet(n.getIntSwitchName(), subnet)
+		if err != nil {
+			return "", err
+		}
+
+		if family == 4 {
+			portOpts.DHCPv4OptsID = dhcpOptsID
+		} else {
+			portOpts.DHCPv6OptsID = dhcpOptsID
+		}
+	}
+
+	instancePortName := n.getIntSwitchInstancePortName(instanceID, deviceName)
+
+	err = client.LogicalSwitchPortAdd(n.getIntSwitchName(), instancePortName, true)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.LogicalSwitchPortSet(instancePortName, portOpts)
+	if err != nil {
+		client.LogicalSwitchPortDelete(instancePortName)
+		return "", err
+	}
+
+	return instancePortName, nil
+}
+
+// InstanceDevicePortDelete deletes an instance device port from the internal logical switch.
+func (n *ovn) InstanceDevicePortDelete(instanceID int, deviceName string) error {
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	instancePortName := n.getIntSwitchInstancePortName(instanceID, deviceName)
+
+	err = client.LogicalSwitchPortACLsDelete(n.getIntSwitchName(), instancePortName)
+	if err != nil {
+		return err
+	}
+
+	return client.LogicalSwitchPortDelete(instancePortName)
+}
+
+// InstanceDevicePortACLsSet applies the network's ACLs along with the instance device's own ACLs to the instance
+// device port. If neither the network nor the device have any ACLs then any existing ACLs are removed.
+func (n *ovn) InstanceDevicePortACLsSet(instanceID int, deviceName string, aclNames []string) error {
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	instancePortName := n.getIntSwitchInstancePortName(instanceID, deviceName)
+	aclNames = ACLNames(strings.Join(append([]string{n.config["security.acls"]}, aclNames...), ","))
+
+	if len(aclNames) < 1 {
+		return client.LogicalSwitchPortACLsDelete(n.getIntSwitchName(), instancePortName)
+	}
+
+	ingress, egress, err := ACLRules(n.state, aclNames)
+	if err != nil {
+		return err
+	}
+
+	return client.LogicalSwitchPortACLsSet(n.getIntSwitchName(), instancePortName, ovnACLs(instancePortName, ingress, egress))
+}
+
+// ovnACLs converts the ingress and egress ACL rules into OVN ACLs for the logical switch port.
+// The rules are given descending priorities so that the first matching rule applies. Established traffic,
+// ARP, neighbour discovery, DHCP and DNS are always allowed, any other traffic not matching a rule is rejected.
+func ovnACLs(portName openvswitch.OVNSwitchPort, ingress []firewallDrivers.ACLRule, egress []firewallDrivers.ACLRule) []openvswitch.OVNACL {
+	fromPort := fmt.Sprintf(`inport == "%s"`, portName)
+	toPort := fmt.Sprintf(`outport == "%s"`, portName)
+
+	acls := []openvswitch.OVNACL{
+		{Direction: "from-lport", Priority: ovnACLPriorityMax, Action: "allow", Match: fmt.Sprintf("%s && (arp || nd || nd_rs || udp.dst == {53, 67, 547} || tcp.dst == 53)", fromPort)},
+		{Direction: "to-lport", Priority: ovnACLPriorityMax, Action: "allow", Match: fmt.Sprintf("%s && (arp || nd || nd_ra || udp.src == {67, 547})", toPort)},
+		{Direction: "from-lport", Priority: 0, Action: "reject", Match: fromPort},
+		{Direction: "to-lport", Priority: 0, Action: "reject", Match: toPort},
+	}
+
+	for _, dir := range []struct {
+		direction string
+		portMatch string
+		rules     []firewallDrivers.ACLRule
+	}{
+		{direction: "to-lport", portMatch: toPort, rules: ingress},
+		{direction: "from-lport", portMatch: fromPort, rules: egress},
+	} {
+		priority := uint(ovnACLPriorityMax - 1)
+		for _, rule := range dir.rules {
+			action := rule.Action
+			if action == "allow" {
+				action = "allow-related" // Allow reply traffic.
+			}
+
+			for _, match := range ovnACLRuleMatches(&rule) {
+				if match != "" {
+					match = fmt.Sprintf("%s && %s", dir.portMatch, match)
+				} else {
+					match = dir.portMatch
+				}
+
+				acls = append(acls, openvswitch.OVNACL{Direction: dir.direction, Priority: priority, Action: action, Match: match})
+			}
+
+			if priority > 1 {
+				priority--
+			}
+		}
+	}
+
+	return acls
+}
+
+// ovnACLRuleMatches returns the OVN match expressions for an ACL rule (one per IP family it applies to).
+func ovnACLRuleMatches(rule *firewallDrivers.ACLRule) []string {
+	portsMatch := func(field string, ports []string) string {
+		parts := []string{}
+		for _, port := range ports {
+			portRange := strings.SplitN(port, "-", 2)
+			if len(portRange) > 1 {
+				parts = append(parts, fmt.Sprintf("(%s >= %s && %s <= %s)", field, portRange[0], field, portRange[1]))
+			} else {
+				parts = append(parts, fmt.Sprintf("%s == %s", field, port))
+			}
+		}
+
+		return fmt.Sprintf("(%s)", strings.Join(parts, " || "))
+	}
+
+	matches := []string{}
+	for _, family := range rule.Families() {
+		parts := []string{}
+
+		ipFamily := "ip"
+		if family == 4 {
+			ipFamily = "ip4"
+		} else if family == 6 {
+			ipFamily = "ip6"
+		}
+
+		if family > 0 {
+			parts = append(parts, ipFamily)
+		}
+
+		if len(rule.Source) > 0 {
+			parts = append(parts, fmt.Sprintf("%s.src == {%s}", ipFamily, strings.Join(rule.Subnets(rule.Source, family), ", ")))
+		}
+
+		if len(rule.Destination) > 0 {
+			parts = append(parts, fmt.Sprintf("%s.dst == {%s}", ipFamily, strings.Join(rule.Subnets(rule.Destination, family), ", ")))
+		}
+
+		switch rule.Protocol {
+		case "tcp", "udp":
+			parts = append(parts, rule.Protocol)
+
+			if len(rule.SourcePort) > 0 {
+				parts = append(parts, portsMatch(fmt.Sprintf("%s.src", rule.Protocol), rule.SourcePort))
+			}
+
+			if len(rule.DestinationPort) > 0 {
+				parts = append(parts, portsMatch(fmt.Sprintf("%s.dst", rule.Protocol), rule.DestinationPort))
+			}
+		case "icmp4", "icmp6":
+			parts = append(parts, rule.Protocol)
+
+			if rule.ICMPType != "" {
+				parts = append(parts, fmt.Sprintf("%s.type == %s", rule.Protocol, rule.ICMPType))
+			}
+
+			if rule.ICMPCode != "" {
+				parts = append(parts, fmt.Sprintf("%s.code == %s", rule.Protocol, rule.ICMPCode))
+			}
+		}
+
+		matches = append(matches, strings.Join(parts, " && "))
+	}
+
+	return matches
+}
@@ -0,0 +1,283 @@
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// physical represents a LXD physical network. It describes an uplink interface of the host (and the addressing
+// available on it) that other networks use for external connectivity, without their users needing to know the
+// host's interface details.
+type physical struct {
+	common
+}
+
+// Validate network config.
+func (n *physical) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		// The parent is a node-specific key, so it isn't set when defining the network across a cluster.
+		"parent": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return ValidNetworkName(value)
+		},
+		"mtu": shared.IsInt64,
+		"vlan": func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			return validBridgeVLAN(value)
+		},
+		"ipv4.gateway":    shared.IsNetworkAddressCIDRV4,
+		"ipv6.gateway":    shared.IsNetworkAddressCIDRV6,
+		"ipv4.ovn.ranges": validIPRanges(4),
+		"ipv6.ovn.ranges": validIPRanges(6),
+		"ipv4.routes":     shared.IsNetworkV4List,
+		"ipv6.routes":     shared.IsNetworkV6List,
+		"dns.nameservers": func(value string) error {
+			for _, nameserver := range strings.Split(value, ",") {
+				nameserver = strings.TrimSpace(nameserver)
+				if nameserver != "" && net.ParseIP(nameserver) == nil {
+					return fmt.Errorf("Invalid nameserver address %q", nameserver)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	err := n.validate(config, rules)
+	if err != nil {
+		return err
+	}
+
+	// Peform composite key checks after per-key validation.
+	for _, family := range []int{4, 6} {
+		rangesKey := fmt.Sprintf("ipv%d.ovn.ranges", family)
+		gatewayKey := fmt.Sprintf("ipv%d.gateway", family)
+
+		if config[rangesKey] == "" {
+			continue
+		}
+
+		if config[gatewayKey] == "" {
+			return fmt.Errorf("%q requires %q to be set", rangesKey, gatewayKey)
+		}
+
+		_, subnet, _ := net.ParseCIDR(config[gatewayKey])
+		ipRanges, _ := parseIPRanges(config[rangesKey])
+		for _, ipRange := range ipRanges {
+			if !subnet.Contains(ipRange.Start) || !subnet.Contains(ipRange.End) {
+				return fmt.Errorf("IP range \"%s-%s\" in %q isn't within the %q subnet", ipRange.Start, ipRange.End, rangesKey, gatewayKey)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hostDevice returns the name of the host interface of the uplink, the VLAN interface if a VLAN is set.
+func (n *physical) hostDevice() string {
+	return GetHostDevice(n.config["parent"], n.config["vlan"])
+}
+
+// vlanCreatedPath returns the path of the file recording that the VLAN interface was created by the network.
+func (n *physical) vlanCreatedPath() string {
+	return shared.VarPath("networks", n.name, "vlan.created")
+}
+
+// IsUsed returns whether the network is used as uplink by another network or by instances.
+func (n *physical) IsUsed() bool {
+	networks, err := n.state.Cluster.GetNetworks()
+	if err != nil {
+		return true
+	}
+
+	// Physical network names are unique across projects, so any OVN network referring to this name uses it.
+	for projectName, names := range networks {
+		for _, name := range names {
+			if projectName == n.project && name == n.name {
+				continue
+			}
+
+			otherNet, err := LoadByName(n.state, projectName, name)
+			if err != nil {
+				continue
+			}
+
+			if otherNet.Type() == "ovn" && otherNet.Config()["network"] == n.name {
+				return true
+			}
+		}
+	}
+
+	return n.common.IsUsed()
+}
+
+// Start starts the network, creating the VLAN interface on the parent if needed.
+func (n *physical) Start() error {
+	// If we are in mock mode, just no-op.
+	if n.state.OS.MockMode {
+		return nil
+	}
+
+	if n.config["parent"] == "" {
+		return fmt.Errorf("Missing required %q config key", "parent")
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", n.config["parent"])) {
+		return fmt.Errorf("Parent interface %q not found", n.config["parent"])
+	}
+
+	hostDev := n.hostDevice()
+
+	if n.config["vlan"] != "" && !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", hostDev)) {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", n.config["parent"], "up")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to bring up parent interface %q", n.config["parent"])
+		}
+
+		_, err = shared.RunCommand("ip", "link", "add", "link", n.config["parent"], "name", hostDev, "type", "vlan", "id", n.config["vlan"])
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create VLAN interface %q", hostDev)
+		}
+
+		// Attempt to disable IPv6 router advertisement acceptance.
+		util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_ra", hostDev), "0")
+
+		err = os.MkdirAll(shared.VarPath("networks", n.name), 0711)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(n.vlanCreatedPath(), []byte(hostDev), 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	if n.config["mtu"] != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", hostDev, "mtu", n.config["mtu"])
+		if err != nil {
+			return errors.Wrapf(err, "Failed to set the MTU of %q", hostDev)
+		}
+	}
+
+	_, err := shared.RunCommand("ip", "link", "set", "dev", hostDev, "up")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to bring up interface %q", hostDev)
+	}
+
+	return nil
+}
+
+// Stop stops the network, removing the VLAN interface if it was created by the network.
+func (n *physical) Stop() error {
+	// If we are in mock mode, just no-op.
+	if n.state.OS.MockMode {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(n.vlanCreatedPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	hostDev := strings.TrimSpace(string(content))
+	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s", hostDev)) {
+		_, err = shared.RunCommand("ip", "link", "delete", "dev", hostDev)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to remove VLAN interface %q", hostDev)
+		}
+	}
+
+	return os.Remove(n.vlanCreatedPath())
+}
+
+// Delete deletes a network.
+func (n *physical) Delete(clusterNotification bool) error {
+	n.logger.Debug("Delete", log.Ctx{"clusterNotification": clusterNotification})
+
+	err := n.Stop()
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(shared.VarPath("networks", n.name))
+	if err != nil {
+		return err
+	}
+
+	return n.common.delete(clusterNotification)
+}
+
+// Rename renames a network.
+func (n *physical) Rename(newName string) error {
+	n.logger.Debug("Rename", log.Ctx{"newName": newName})
+
+	// Sanity checks.
+	if n.IsUsed() {
+		return fmt.Errorf("The network is currently in use")
+	}
+
+	return n.common.rename(newName)
+}
+
+// Update updates the network. Accepts notification boolean indicating if this update request is coming from a
+// cluster notification, in which case do not update the database, just apply local changes needed.
+func (n *physical) Update(newNetwork api.NetworkPut, clusterNotification bool) error {
+	n.logger.Debug("Update", log.Ctx{"clusterNotification": clusterNotification})
+
+	dbUpdateNeeeded, changedKeys, oldNetwork, err := n.common.configChanged(newNetwork)
+	if err != nil {
+		return err
+	}
+
+	if !dbUpdateNeeeded {
+		return nil // Nothing changed.
+	}
+
+	// The interface can't be changed underneath the networks using it.
+	if shared.StringInSlice("parent", changedKeys) || shared.StringInSlice("vlan", changedKeys) {
+		if n.IsUsed() {
+			return fmt.Errorf("Cannot change the parent or VLAN of a network that is in use")
+		}
+
+		err = n.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply changes to database.
+	err = n.common.update(newNetwork, clusterNotification)
+	if err != nil {
+		n.common.update(oldNetwork, clusterNotification)
+		return err
+	}
+
+	err = n.Start()
+	if err != nil {
+		n.common.update(oldNetwork, clusterNotification)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// forwardValidate checks the address forward configuration is valid and converts it into the firewall forwards
+// to apply, with the port specific forwards first and the forward for the remaining traffic (if any) last.
+func forwardValidate(listenAddress net.IP, put *api.NetworkForwardPut) ([]firewallDrivers.AddressForward, error) {
+	if listenAddress == nil {
+		return nil, fmt.Errorf("Invalid listen address")
+	}
+
+	listenIsIP4 := listenAddress.To4() != nil
+
+	// checkTarget parses the target address and checks it is of the same family as the listen address.
+	checkTarget := func(value string) (net.IP, error) {
+		targetAddress := net.ParseIP(value)
+		if targetAddress == nil {
+			return nil, fmt.Errorf("Invalid target address %q", value)
+		}
+
+		if (targetAddress.To4() != nil) != listenIsIP4 {
+			return nil, fmt.Errorf("Target address %q is not of the same IP family as the listen address", value)
+		}
+
+		return targetAddress, nil
+	}
+
+	for k := range put.Config {
+		if k != "target_address" && !strings.HasPrefix(k, "user.") {
+			return nil, fmt.Errorf("Invalid network forward configuration key %q", k)
+		}
+	}
+
+	forwards := []firewallDrivers.AddressForward{}
+	usedPorts := map[string][]uint64{}
+
+	for i, portSpec := range put.Ports {
+		if !shared.StringInSlice(portSpec.Protocol, []string{"tcp", "udp"}) {
+			return nil, fmt.Errorf("Invalid protocol %q in port specification %d (must be tcp or udp)", portSpec.Protocol, i)
+		}
+
+		listenPorts, err := forwardParsePorts(portSpec.ListenPort)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid listen port in port specification %d", i)
+		}
+
+		if len(listenPorts) < 1 {
+			return nil, fmt.Errorf("Missing listen port in port specification %d", i)
+		}
+
+		for _, port := range listenPorts {
+			for _, usedPort := range usedPorts[portSpec.Protocol] {
+				if port == usedPort {
+					return nil, fmt.Errorf("Listen port %d (%s) is used by more than one port specification", port, portSpec.Protocol)
+				}
+			}
+		}
+
+		usedPorts[portSpec.Protocol] = append(usedPorts[portSpec.Protocol], listenPorts...)
+
+		targetPorts, err := forwardParsePorts(portSpec.TargetPort)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid target port in port specification %d", i)
+		}
+
+		if len(targetPorts) > 1 && len(targetPorts) != len(listenPorts) {
+			return nil, fmt.Errorf("Target ports in port specification %d must either be a single port or match the number of listen ports", i)
+		}
+
+		targetAddress, err := checkTarget(portSpec.TargetAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid port specification %d", i)
+		}
+
+		forwards = append(forwards, firewallDrivers.AddressForward{
+			ListenAddress: listenAddress,
+			Protocol:      portSpec.Protocol,
+			ListenPorts:   listenPorts,
+			TargetAddress: targetAddress,
+			TargetPorts:   targetPorts,
+		})
+	}
+
+	if put.Config["target_address"] != "" {
+		targetAddress, err := checkTarget(put.Config["target_address"])
+		if err != nil {
+			return nil, err
+		}
+
+		forwards = append(forwards, firewallDrivers.AddressForward{
+			ListenAddress: listenAddress,
+			TargetAddress: targetAddress,
+		})
+	}
+
+	return forwards, nil
+}
+
+// forwardParsePorts parses a comma separated list of ports and port ranges (start-end) into the list of ports.
+func forwardParsePorts(value string) ([]uint64, error) {
+	ports := []uint64{}
+	if value == "" {
+		return ports, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.SplitN(entry, "-", 2)
+
+		start, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("Invalid port %q", entry)
+		}
+
+		end := start
+		if len(parts) > 1 {
+			end, err = strconv.ParseUint(parts[1], 10, 16)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("Invalid port range %q", entry)
+			}
+		}
+
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, nil
+}
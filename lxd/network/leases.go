@@ -0,0 +1,297 @@
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// dnsmasqLease represents an entry of the dnsmasq leases file of a network.
+type dnsmasqLease struct {
+	Hwaddr   string
+	Address  string
+	Hostname string
+}
+
+// dnsmasqLeases parses the dnsmasq leases file of the network. Returns an empty list if the network doesn't have
+// a leases file.
+func dnsmasqLeases(networkName string) ([]dnsmasqLease, error) {
+	content, err := ioutil.ReadFile(shared.VarPath("networks", networkName, "dnsmasq.leases"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []dnsmasqLease{}, nil
+		}
+
+		return nil, err
+	}
+
+	leases := []dnsmasqLease{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		// Parse the MAC. IPv6 leases only have the IAID in the MAC field, the MAC is then taken from the end
+		// of the client DUID.
+		mac := GetMACSlice(fields[1])
+		macStr := strings.Join(mac, ":")
+
+		if len(macStr) < 17 && len(fields[4]) >= 17 {
+			macStr = fields[4][len(fields[4])-17:]
+		}
+
+		leases = append(leases, dnsmasqLease{
+			Hwaddr:   macStr,
+			Address:  fields[2],
+			Hostname: fields[3],
+		})
+	}
+
+	return leases, nil
+}
+
+// StaticLeases returns the static leases of the bridged NICs connected to the network of the instances in the
+// project, along with the MAC addresses of all those NICs.
+func StaticLeases(s *state.State, networkName string, projectName string) ([]api.NetworkLease, []string, error) {
+	leases := []api.NetworkLease{}
+	projectMacs := []string{}
+
+	instances, err := instance.LoadByProject(s, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, inst := range instances {
+		// Go through all its devices (including profiles).
+		for k, d := range inst.ExpandedDevices() {
+			// Skip uninteresting entries.
+			if d["type"] != "nic" || d.NICType() != "bridged" {
+				continue
+			}
+
+			// Temporarily populate parent from network setting if used.
+			if d["network"] != "" {
+				d["parent"] = d["network"]
+			}
+
+			if d["parent"] != networkName {
+				continue
+			}
+
+			// Fill in the hwaddr from volatile.
+			if d["hwaddr"] == "" {
+				d["hwaddr"] = inst.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", k)]
+			}
+
+			// Record the MAC.
+			if d["hwaddr"] != "" {
+				projectMacs = append(projectMacs, d["hwaddr"])
+			}
+
+			// Add the lease.
+			for _, key := range []string{"ipv4.address", "ipv6.address"} {
+				if d[key] == "" {
+					continue
+				}
+
+				leases = append(leases, api.NetworkLease{
+					Hostname: inst.Name(),
+					Address:  d[key],
+					Hwaddr:   d["hwaddr"],
+					Type:     "static",
+					Location: inst.Location(),
+				})
+			}
+		}
+	}
+
+	// Add the reservations of the project.
+	var networkProject string
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		networkProject, err = project.NetworkProject(tx, projectName, networkName)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	networkID, _, err := s.Cluster.GetNetwork(networkProject, networkName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reservations, err := s.Cluster.GetNetworkLeaseReservations(networkID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, lease := range reservations[projectName] {
+		if !shared.StringInSlice(lease.Hwaddr, projectMacs) {
+			projectMacs = append(projectMacs, lease.Hwaddr)
+		}
+
+		leases = append(leases, lease)
+	}
+
+	return leases, projectMacs, nil
+}
+
+// ValidateLeaseReservation checks the supplied DHCP lease reservation is valid for the network and doesn't
+// conflict with the existing reservations. The MAC address is normalised in place.
+func ValidateLeaseReservation(s *state.State, n Network, projectName string, req *api.NetworkLeasesPost) error {
+	err := shared.ValidHostname(req.Hostname)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid hostname %q", req.Hostname)
+	}
+
+	hwaddr, err := net.ParseMAC(req.Hwaddr)
+	if err != nil || len(hwaddr) != 6 {
+		return fmt.Errorf("Invalid MAC address %q", req.Hwaddr)
+	}
+
+	req.Hwaddr = hwaddr.String()
+
+	ip := net.ParseIP(req.Address)
+	if ip == nil {
+		return fmt.Errorf("Invalid address %q", req.Address)
+	}
+
+	req.Address = ip.String()
+
+	// The address must be part of the matching subnet of the network, without being the network's own.
+	key := "ipv4.address"
+	if ip.To4() == nil {
+		key = "ipv6.address"
+	}
+
+	gateway, subnet, err := net.ParseCIDR(n.Config()[key])
+	if err != nil || !subnet.Contains(ip) {
+		return fmt.Errorf("Address %q isn't part of the %s subnet of network %q", req.Address, key, n.Name())
+	}
+
+	if gateway.Equal(ip) {
+		return fmt.Errorf("Address %q is used by network %q", req.Address, n.Name())
+	}
+
+	// A reservation can't take the name of an instance of the project.
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		names, err := tx.GetInstanceNames(projectName)
+		if err != nil {
+			return err
+		}
+
+		if shared.StringInSlice(req.Hostname, names) {
+			return fmt.Errorf("Hostname %q is used by an instance", req.Hostname)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// A MAC address gets at most one reserved address per family, all under the same name and project.
+	reservations, err := s.Cluster.GetNetworkLeaseReservations(n.ID())
+	if err != nil {
+		return err
+	}
+
+	for reservationProject, leases := range reservations {
+		for _, lease := range leases {
+			if lease.Address == req.Address {
+				return fmt.Errorf("Address %q is already reserved", req.Address)
+			}
+
+			if lease.Hwaddr != req.Hwaddr {
+				continue
+			}
+
+			if reservationProject != projectName || lease.Hostname != req.Hostname {
+				return fmt.Errorf("MAC address %q is already reserved for %q", req.Hwaddr, lease.Hostname)
+			}
+
+			if (net.ParseIP(lease.Address).To4() == nil) == (ip.To4() == nil) {
+				return fmt.Errorf("MAC address %q already has reserved address %q", req.Hwaddr, lease.Address)
+			}
+		}
+	}
+
+	return nil
+}
+
+// leaseReservationEntries returns the dnsmasq host entries of the DHCP lease reservations of the network, as
+// hwaddr, project, hostname, IPv4 address and IPv6 address.
+func leaseReservationEntries(s *state.State, networkID int64) ([][]string, error) {
+	reservations, err := s.Cluster.GetNetworkLeaseReservations(networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := [][]string{}
+	for projectName, leases := range reservations {
+		byHwaddr := map[string][]string{}
+		for _, lease := range leases {
+			entry, ok := byHwaddr[lease.Hwaddr]
+			if !ok {
+				entry = []string{lease.Hwaddr, projectName, lease.Hostname, "", ""}
+				entries = append(entries, entry)
+			}
+
+			if net.ParseIP(lease.Address).To4() != nil {
+				entry[3] = lease.Address
+			} else {
+				entry[4] = lease.Address
+			}
+
+			byHwaddr[lease.Hwaddr] = entry
+		}
+	}
+
+	return entries, nil
+}
+
+// DynamicLeases returns the dynamic leases handed out on the network by the local member.
+func DynamicLeases(s *state.State, networkName string) ([]api.NetworkLease, error) {
+	entries, err := dnsmasqLeases(networkName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) < 1 {
+		return []api.NetworkLease{}, nil
+	}
+
+	var serverName string
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		serverName, err = tx.GetLocalNodeName()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]api.NetworkLease, 0, len(entries))
+	for _, entry := range entries {
+		leases = append(leases, api.NetworkLease{
+			Hostname: entry.Hostname,
+			Address:  entry.Address,
+			Hwaddr:   entry.Hwaddr,
+			Type:     "dynamic",
+			Location: serverName,
+		})
+	}
+
+	return leases, nil
+}
@@ -0,0 +1,433 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// loadBalancerConfigKeys holds the validators of the load balancer configuration keys.
+var loadBalancerConfigKeys = map[string]func(value string) error{
+	"healthcheck":               shared.IsBool,
+	"healthcheck.timeout":       loadBalancerIsPositiveInt,
+	"healthcheck.failure_count": loadBalancerIsPositiveInt,
+	"healthcheck.success_count": loadBalancerIsPositiveInt,
+}
+
+// loadBalancerPort is a validated port specification of a load balancer.
+type loadBalancerPort struct {
+	protocol    string
+	listenPorts []uint64
+	backends    []string
+}
+
+// loadBalancerBackendHealth tracks the result of the health checks of a load balancer backend.
+type loadBalancerBackendHealth struct {
+	offline   bool
+	checked   bool
+	successes int
+	failures  int
+}
+
+// loadBalancerHealth holds the health of the load balancer backends on the local member, keyed by
+// loadBalancerHealthKey.
+var loadBalancerHealth = map[string]*loadBalancerBackendHealth{}
+var loadBalancerHealthMu sync.Mutex
+
+// loadBalancerHealthKey returns the key of a backend in loadBalancerHealth.
+func loadBalancerHealthKey(networkName string, listenAddress string, backendName string) string {
+	return fmt.Sprintf("%s/%s/%s", networkName, listenAddress, backendName)
+}
+
+// loadBalancerIsPositiveInt checks the value is a positive integer.
+func loadBalancerIsPositiveInt(value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 {
+		return fmt.Errorf("Invalid value %q (must be a positive integer)", value)
+	}
+
+	return nil
+}
+
+// loadBalancerConfigInt returns the integer value of the configuration key, or the default if not set.
+func loadBalancerConfigInt(config map[string]string, key string, defaultValue int) int {
+	i, err := strconv.Atoi(config[key])
+	if err != nil {
+		return defaultValue
+	}
+
+	return i
+}
+
+// loadBalancerValidate checks the load balancer configuration is valid and returns its port specifications
+// along with the firewall target of each backend, keyed by backend name.
+func loadBalancerValidate(listenAddress net.IP, put *api.NetworkLoadBalancerPut) ([]loadBalancerPort, map[string]firewallDrivers.LoadBalancerTarget, error) {
+	if listenAddress == nil {
+		return nil, nil, fmt.Errorf("Invalid listen address")
+	}
+
+	listenIsIP4 := listenAddress.To4() != nil
+
+	for k, v := range put.Config {
+		if strings.HasPrefix(k, "user.") {
+			continue
+		}
+
+		validator, ok := loadBalancerConfigKeys[k]
+		if !ok {
+			return nil, nil, fmt.Errorf("Invalid network load balancer configuration key %q", k)
+		}
+
+		if v == "" {
+			continue
+		}
+
+		err := validator(v)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid value for network load balancer configuration key %q", k)
+		}
+	}
+
+	targets := make(map[string]firewallDrivers.LoadBalancerTarget, len(put.Backends))
+	for _, backend := range put.Backends {
+		err := ValidACLName(backend.Name)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid backend name %q", backend.Name)
+		}
+
+		_, ok := targets[backend.Name]
+		if ok {
+			return nil, nil, fmt.Errorf("Backend %q is defined more than once", backend.Name)
+		}
+
+		targetAddress := net.ParseIP(backend.TargetAddress)
+		if targetAddress == nil {
+			return nil, nil, fmt.Errorf("Invalid target address %q for backend %q", backend.TargetAddress, backend.Name)
+		}
+
+		if (targetAddress.To4() != nil) != listenIsIP4 {
+			return nil, nil, fmt.Errorf("Target address %q of backend %q is not of the same IP family as the listen address", backend.TargetAddress, backend.Name)
+		}
+
+		targetPorts, err := forwardParsePorts(backend.TargetPort)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid target port for backend %q", backend.Name)
+		}
+
+		if len(targetPorts) > 1 {
+			return nil, nil, fmt.Errorf("Target port of backend %q must be a single port", backend.Name)
+		}
+
+		target := firewallDrivers.LoadBalancerTarget{Address: targetAddress}
+		if len(targetPorts) > 0 {
+			target.Port = targetPorts[0]
+		}
+
+		targets[backend.Name] = target
+	}
+
+	ports := []loadBalancerPort{}
+	usedPorts := map[string][]uint64{}
+
+	for i, portSpec := range put.Ports {
+		if !shared.StringInSlice(portSpec.Protocol, []string{"tcp", "udp"}) {
+			return nil, nil, fmt.Errorf("Invalid protocol %q in port specification %d (must be tcp or udp)", portSpec.Protocol, i)
+		}
+
+		listenPorts, err := forwardParsePorts(portSpec.ListenPort)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid listen port in port specification %d", i)
+		}
+
+		if len(listenPorts) < 1 {
+			return nil, nil, fmt.Errorf("Missing listen port in port specification %d", i)
+		}
+
+		for _, port := range listenPorts {
+			for _, usedPort := range usedPorts[portSpec.Protocol] {
+				if port == usedPort {
+					return nil, nil, fmt.Errorf("Listen port %d (%s) is used by more than one port specification", port, portSpec.Protocol)
+				}
+			}
+		}
+
+		usedPorts[portSpec.Protocol] = append(usedPorts[portSpec.Protocol], listenPorts...)
+
+		if len(portSpec.TargetBackend) < 1 {
+			return nil, nil, fmt.Errorf("Missing target backend in port specification %d", i)
+		}
+
+		for _, backendName := range portSpec.TargetBackend {
+			_, ok := targets[backendName]
+			if !ok {
+				return nil, nil, fmt.Errorf("Unknown target backend %q in port specification %d", backendName, i)
+			}
+		}
+
+		ports = append(ports, loadBalancerPort{
+			protocol:    portSpec.Protocol,
+			listenPorts: listenPorts,
+			backends:    portSpec.TargetBackend,
+		})
+	}
+
+	return ports, targets, nil
+}
+
+// loadBalancerRules returns the firewall load balancers to apply for the load balancer configuration, only
+// including the backends that aren't known to be offline. Port specifications without any usable backend are
+// skipped so that connections to them are refused rather than sent to a failed backend.
+func loadBalancerRules(networkName string, listenAddress net.IP, put *api.NetworkLoadBalancerPut) ([]firewallDrivers.LoadBalancer, error) {
+	ports, targets, err := loadBalancerValidate(listenAddress, put)
+	if err != nil {
+		return nil, err
+	}
+
+	loadBalancerHealthMu.Lock()
+	defer loadBalancerHealthMu.Unlock()
+
+	rules := []firewallDrivers.LoadBalancer{}
+	for _, port := range ports {
+		rule := firewallDrivers.LoadBalancer{
+			ListenAddress: listenAddress,
+			Protocol:      port.protocol,
+			ListenPorts:   port.listenPorts,
+		}
+
+		for _, backendName := range port.backends {
+			health, ok := loadBalancerHealth[loadBalancerHealthKey(networkName, listenAddress.String(), backendName)]
+			if ok && health.offline {
+				continue
+			}
+
+			rule.Targets = append(rule.Targets, targets[backendName])
+		}
+
+		if len(rule.Targets) < 1 {
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// loadBalancerBackendCheckPort returns the port used to health check the backend, which is either its target
+// port or the first TCP listen port it receives traffic on. Returns 0 if the backend can't be checked.
+func loadBalancerBackendCheckPort(ports []loadBalancerPort, backendName string, target firewallDrivers.LoadBalancerTarget) uint64 {
+	for _, port := range ports {
+		if port.protocol != "tcp" || !shared.StringInSlice(backendName, port.backends) {
+			continue
+		}
+
+		if target.Port > 0 {
+			return target.Port
+		}
+
+		return port.listenPorts[0]
+	}
+
+	return 0
+}
+
+// loadBalancerState returns the health of the backends of the load balancer.
+func loadBalancerState(networkName string, lb *api.NetworkLoadBalancer) (*api.NetworkLoadBalancerState, error) {
+	state := api.NetworkLoadBalancerState{
+		BackendHealth: map[string]api.NetworkLoadBalancerStateBackendHealth{},
+	}
+
+	loadBalancerHealthMu.Lock()
+	defer loadBalancerHealthMu.Unlock()
+
+	for _, backend := range lb.Backends {
+		status := "unknown"
+		health, ok := loadBalancerHealth[loadBalancerHealthKey(networkName, lb.ListenAddress, backend.Name)]
+		if ok && health.checked {
+			status = "online"
+			if health.offline {
+				status = "offline"
+			}
+		}
+
+		state.BackendHealth[backend.Name] = api.NetworkLoadBalancerStateBackendHealth{
+			Address: backend.TargetAddress,
+			Status:  status,
+		}
+	}
+
+	return &state, nil
+}
+
+// loadBalancerHealthClear forgets the health of the backends of the load balancer.
+func loadBalancerHealthClear(networkName string, listenAddress string) {
+	loadBalancerHealthMu.Lock()
+	defer loadBalancerHealthMu.Unlock()
+
+	prefix := loadBalancerHealthKey(networkName, listenAddress, "")
+	for key := range loadBalancerHealth {
+		if strings.HasPrefix(key, prefix) {
+			delete(loadBalancerHealth, key)
+		}
+	}
+}
+
+// loadBalancerHealthCheck runs a health check against each backend of the load balancer that supports it and
+// returns whether the set of offline backends changed.
+func loadBalancerHealthCheck(networkName string, lb *api.NetworkLoadBalancer) (bool, error) {
+	if !shared.IsTrue(lb.Config["healthcheck"]) {
+		loadBalancerHealthMu.Lock()
+		defer loadBalancerHealthMu.Unlock()
+
+		// Without health checks all backends are considered usable.
+		changed := false
+		for _, backend := range lb.Backends {
+			key := loadBalancerHealthKey(networkName, lb.ListenAddress, backend.Name)
+			health, ok := loadBalancerHealth[key]
+			if ok {
+				changed = changed || health.offline
+				delete(loadBalancerHealth, key)
+			}
+		}
+
+		return changed, nil
+	}
+
+	ports, targets, err := loadBalancerValidate(net.ParseIP(lb.ListenAddress), &lb.NetworkLoadBalancerPut)
+	if err != nil {
+		return false, err
+	}
+
+	timeout := time.Duration(loadBalancerConfigInt(lb.Config, "healthcheck.timeout", 5)) * time.Second
+	failureCount := loadBalancerConfigInt(lb.Config, "healthcheck.failure_count", 3)
+	successCount := loadBalancerConfigInt(lb.Config, "healthcheck.success_count", 2)
+
+	// Run the checks in parallel so that a few unresponsive backends don't hold up the others.
+	results := make(map[string]bool, len(targets))
+	resultsMu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for backendName, target := range targets {
+		port := loadBalancerBackendCheckPort(ports, backendName, target)
+		if port == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(backendName string, address string) {
+			defer wg.Done()
+
+			conn, err := net.DialTimeout("tcp", address, timeout)
+			if err == nil {
+				conn.Close()
+			}
+
+			resultsMu.Lock()
+			results[backendName] = err == nil
+			resultsMu.Unlock()
+		}(backendName, net.JoinHostPort(target.Address.String(), fmt.Sprintf("%d", port)))
+	}
+
+	wg.Wait()
+
+	loadBalancerHealthMu.Lock()
+	defer loadBalancerHealthMu.Unlock()
+
+	changed := false
+	for backendName, success := range results {
+		key := loadBalancerHealthKey(networkName, lb.ListenAddress, backendName)
+		health, ok := loadBalancerHealth[key]
+		if !ok {
+			health = &loadBalancerBackendHealth{}
+			loadBalancerHealth[key] = health
+		}
+
+		if success {
+			health.successes++
+			health.failures = 0
+		} else {
+			health.failures++
+			health.successes = 0
+		}
+
+		// Backends are considered online until proven otherwise.
+		offline := health.offline
+		if health.offline && health.successes >= successCount {
+			offline = false
+		} else if !health.offline && health.failures >= failureCount {
+			offline = true
+		}
+
+		if offline != health.offline {
+			logger.Info("Network load balancer backend health changed", log.Ctx{"network": networkName, "listenAddress": lb.ListenAddress, "backend": backendName, "offline": offline})
+			health.offline = offline
+			changed = true
+		}
+
+		if !health.checked && (health.successes >= successCount || health.failures >= failureCount) {
+			health.checked = true
+		}
+	}
+
+	return changed, nil
+}
+
+// LoadBalancersHealthCheck runs the health checks of the load balancers of the running networks on the local
+// member, re-applying the load balancers of the networks whose backends changed health.
+func LoadBalancersHealthCheck(s *state.State) error {
+	networks, err := s.Cluster.GetNonPendingNetworks()
+	if err != nil {
+		return err
+	}
+
+	for networkProject, networkNames := range networks {
+		for _, networkName := range networkNames {
+			n, err := LoadByName(s, networkProject, networkName)
+			if err != nil {
+				return err
+			}
+
+			b, ok := n.(*bridge)
+			if !ok || !b.isRunning() {
+				continue
+			}
+
+			loadBalancers, err := s.Cluster.GetNetworkLoadBalancers(b.id, true)
+			if err != nil {
+				return errors.Wrapf(err, "Failed loading load balancers of network %q", networkName)
+			}
+
+			changed := false
+			for _, lb := range loadBalancers {
+				lbChanged, err := loadBalancerHealthCheck(networkName, lb)
+				if err != nil {
+					logger.Warn("Failed health checking network load balancer", log.Ctx{"network": networkName, "listenAddress": lb.ListenAddress, "err": err})
+					continue
+				}
+
+				changed = changed || lbChanged
+			}
+
+			if changed {
+				err = b.setupLoadBalancers()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -9,11 +9,13 @@ import (
 // Network represents a LXD network.
 type Network interface {
 	// Load.
-	init(state *state.State, id int64, name string, netType string, description string, config map[string]string)
+	init(state *state.State, id int64, projectName string, name string, netType string, description string, config map[string]string)
 	fillConfig(*api.NetworksPost) error
 
 	// Config.
 	Validate(config map[string]string) error
+	ID() int64
+	Project() string
 	Name() string
 	Type() string
 	Config() map[string]string
@@ -30,4 +32,15 @@ type Network interface {
 	Update(newNetwork api.NetworkPut, clusterNotification bool) error
 	HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error
 	Delete(clusterNotification bool) error
+
+	// Address forwards.
+	ForwardCreate(forward api.NetworkForwardsPost) error
+	ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error
+	ForwardDelete(listenAddress string) error
+
+	// Load balancers.
+	LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error
+	LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error
+	LoadBalancerDelete(listenAddress string) error
+	LoadBalancerState(listenAddress string) (*api.NetworkLoadBalancerState, error)
 }
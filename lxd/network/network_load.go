@@ -1,17 +1,22 @@
 package network
 
 import (
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
 var drivers = map[string]func() Network{
-	"bridge": func() Network { return &bridge{} },
+	"bridge":   func() Network { return &bridge{} },
+	"ovn":      func() Network { return &ovn{} },
+	"physical": func() Network { return &physical{} },
 }
 
-// LoadByName loads the network info from the database by name.
-func LoadByName(s *state.State, name string) (Network, error) {
-	id, netInfo, err := s.Cluster.GetNetwork(name)
+// LoadByName loads the network info from the database by project and name.
+func LoadByName(s *state.State, projectName string, name string) (Network, error) {
+	id, netInfo, err := s.Cluster.GetNetwork(projectName, name)
 	if err != nil {
 		return nil, err
 	}
@@ -22,11 +27,54 @@ func LoadByName(s *state.State, name string) (Network, error) {
 	}
 
 	n := driverFunc()
-	n.init(s, id, name, netInfo.Type, netInfo.Description, netInfo.Config)
+	n.init(s, id, projectName, name, netInfo.Type, netInfo.Description, netInfo.Config)
 
 	return n, nil
 }
 
+// LoadVisibleByName loads the network with the given name as seen from the specified project, which may be
+// owned by another project sharing its networks.
+func LoadVisibleByName(s *state.State, projectName string, name string) (Network, error) {
+	var networkProject string
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		networkProject, err = project.NetworkProject(tx, projectName, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadByName(s, networkProject, name)
+}
+
+// loadHostByName loads the bridge or physical network with the given name. The names of those networks are
+// unique across projects as they own the host interface of the same name.
+func loadHostByName(s *state.State, name string) (Network, error) {
+	var networkProjects []string
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		networkProjects, err = tx.GetNetworkProjects(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, networkProject := range networkProjects {
+		n, err := LoadByName(s, networkProject, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if shared.StringInSlice(n.Type(), []string{"bridge", "physical"}) {
+			return n, nil
+		}
+	}
+
+	return nil, db.ErrNoSuchObject
+}
+
 // Validate validates the supplied network configuration for the specified network type.
 func Validate(name string, netType string, config map[string]string) error {
 	driverFunc, ok := drivers[netType]
@@ -40,7 +88,7 @@ func Validate(name string, netType string, config map[string]string) error {
 	}
 
 	n := driverFunc()
-	n.init(nil, 0, name, netType, "", config)
+	n.init(nil, 0, "", name, netType, "", config)
 	return n.Validate(config)
 }
 
@@ -52,7 +100,7 @@ func FillConfig(req *api.NetworksPost) error {
 	}
 
 	n := driverFunc()
-	n.init(nil, 0, req.Name, req.Type, req.Description, req.Config)
+	n.init(nil, 0, "", req.Name, req.Type, req.Description, req.Config)
 
 	err := n.fillConfig(req)
 	if err != nil {
@@ -2,6 +2,7 @@ package network
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -90,10 +91,15 @@ func isInUseByDevices(devices deviceConfig.Devices, networkName string) bool {
 			continue
 		}
 
-		if !shared.StringInSlice(d.NICType(), []string{"bridged", "macvlan", "ipvlan", "physical", "sriov"}) {
+		if !shared.StringInSlice(d.NICType(), []string{"bridged", "macvlan", "ipvlan", "physical", "sriov", "ovn", "bond"}) {
 			continue
 		}
 
+		// The standby NIC of a bond NIC is connected to a bridge.
+		if d.NICType() == "bond" && (d["standby.network"] == networkName || d["standby.parent"] == networkName) {
+			return true
+		}
+
 		// Temporarily populate parent from network setting if used.
 		if d["network"] != "" {
 			d["parent"] = d["network"]
@@ -111,6 +117,15 @@ func isInUseByDevices(devices deviceConfig.Devices, networkName string) bool {
 	return false
 }
 
+// IPToNet converts an IP into a single host subnet (/32 for IPv4 and /128 for IPv6).
+func IPToNet(ip net.IP) net.IPNet {
+	if ip.To4() != nil {
+		return net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}
+	}
+
+	return net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(128, 128)}
+}
+
 // GetIP returns a net.IP representing the IP belonging to the subnet for the host number supplied.
 func GetIP(subnet *net.IPNet, host int64) net.IP {
 	// Convert IP to a big int.
@@ -283,11 +298,18 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 	// Get all the networks.
 	var networks []string
 	if networkName == "" {
-		var err error
-		networks, err = s.Cluster.GetNetworks()
+		projectNetworks, err := s.Cluster.GetNetworks()
 		if err != nil {
 			return err
 		}
+
+		for _, names := range projectNetworks {
+			for _, name := range names {
+				if !shared.StringInSlice(name, networks) {
+					networks = append(networks, name)
+				}
+			}
+		}
 	} else {
 		networks = []string{networkName}
 	}
@@ -354,11 +376,11 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 		entries, _ := entries[network]
 
 		// Skip networks we don't manage (or don't have DHCP enabled).
-		if !shared.PathExists(shared.VarPath("networks", network, "dnsmasq.pid")) {
+		if !DHCPServerRunning(network) {
 			continue
 		}
 
-		n, err := LoadByName(s, network)
+		n, err := loadHostByName(s, network)
 		if err != nil {
 			return err
 		}
@@ -426,6 +448,19 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			}
 		}
 
+		// Add the DHCP lease reservations.
+		reservations, err := leaseReservationEntries(s, n.ID())
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range reservations {
+			err := dnsmasq.UpdateStaticEntry(network, entry[1], entry[2], config, entry[0], entry[3], entry[4])
+			if err != nil {
+				return err
+			}
+		}
+
 		// Signal dnsmasq.
 		err = dnsmasq.Kill(network, true)
 		if err != nil {
@@ -754,42 +789,28 @@ func GetLeaseAddresses(s *state.State, networkName string, hwaddr string) ([]api
 	}
 
 	// Look for DHCP leases.
-	leaseFile := shared.VarPath("networks", networkName, "dnsmasq.leases")
-	if !shared.PathExists(leaseFile) {
-		return addresses, nil
-	}
-
-	dbInfo, err := LoadByName(s, networkName)
+	leases, err := dnsmasqLeases(networkName)
 	if err != nil {
 		return nil, err
 	}
 
-	content, err := ioutil.ReadFile(leaseFile)
+	if len(leases) < 1 {
+		return addresses, nil
+	}
+
+	dbInfo, err := loadHostByName(s, networkName)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, lease := range strings.Split(string(content), "\n") {
-		fields := strings.Fields(lease)
-		if len(fields) < 5 {
-			continue
-		}
-
-		// Parse the MAC
-		mac := GetMACSlice(fields[1])
-		macStr := strings.Join(mac, ":")
-
-		if len(macStr) < 17 && fields[4] != "" {
-			macStr = fields[4][len(fields[4])-17:]
-		}
-
-		if macStr != hwaddr {
+	for _, lease := range leases {
+		if lease.Hwaddr != hwaddr {
 			continue
 		}
 
 		// Parse the IP
 		addr := api.InstanceStateNetworkAddress{
-			Address: fields[2],
+			Address: lease.Address,
 			Scope:   "global",
 		}
 
@@ -918,3 +939,141 @@ func BridgeVLANSetDefaultPVID(interfaceName string, vlanID string) error {
 
 	return nil
 }
+
+// BridgeMulticastSet sets a multicast (IGMP/MLD snooping) option of a bridge interface, such as
+// multicast_snooping or multicast_querier.
+func BridgeMulticastSet(interfaceName string, option string, value string) error {
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/%s", interfaceName, option), []byte(value), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting bridge %s for %q", option, interfaceName)
+	}
+
+	return nil
+}
+
+// validBridgeVLAN validates a VLAN ID usable on a native Linux bridge (VLAN 0 isn't allowed).
+func validBridgeVLAN(value string) error {
+	vlanID, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Invalid VLAN ID: %s", value)
+	}
+
+	if vlanID < 1 || vlanID > 4094 {
+		return fmt.Errorf("Out of range (1-4094) VLAN ID: %s", value)
+	}
+
+	return nil
+}
+
+// bridgeVLANList returns the VLAN IDs of a comma delimited list.
+func bridgeVLANList(value string) []string {
+	vlanIDs := []string{}
+	for _, vlanID := range strings.Split(value, ",") {
+		vlanID = strings.TrimSpace(vlanID)
+		if vlanID != "" {
+			vlanIDs = append(vlanIDs, vlanID)
+		}
+	}
+
+	return vlanIDs
+}
+
+// ipRange represents a range of IPs from start to end.
+type ipRange struct {
+	Start net.IP
+	End   net.IP
+}
+
+// ContainsIP tests whether a supplied IP is within the range.
+func (r *ipRange) ContainsIP(ip net.IP) bool {
+	if r.Start.To4() != nil {
+		ip = ip.To4()
+		if ip == nil {
+			return false
+		}
+
+		return bytes.Compare(ip, r.Start.To4()) >= 0 && bytes.Compare(ip, r.End.To4()) <= 0
+	}
+
+	ip = ip.To16()
+	return ip.To4() == nil && bytes.Compare(ip, r.Start.To16()) >= 0 && bytes.Compare(ip, r.End.To16()) <= 0
+}
+
+// parseIPRanges parses a comma separated list of IP ranges in the format "start-end" and checks that both ends
+// of each range belong to the same family and that the start isn't after the end.
+func parseIPRanges(value string) ([]*ipRange, error) {
+	ipRanges := []*ipRange{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.SplitN(entry, "-", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("IP range %q must contain start and end IP addresses", entry)
+		}
+
+		startIP := net.ParseIP(parts[0])
+		endIP := net.ParseIP(parts[1])
+
+		if startIP == nil {
+			return nil, fmt.Errorf("Start IP %q is invalid", parts[0])
+		}
+
+		if endIP == nil {
+			return nil, fmt.Errorf("End IP %q is invalid", parts[1])
+		}
+
+		if (startIP.To4() == nil) != (endIP.To4() == nil) {
+			return nil, fmt.Errorf("IP range %q mixes IPv4 and IPv6 addresses", entry)
+		}
+
+		if bytes.Compare(startIP.To16(), endIP.To16()) > 0 {
+			return nil, fmt.Errorf("Start IP %q must be before or equal to end IP %q", parts[0], parts[1])
+		}
+
+		if startIP.To4() != nil {
+			startIP = startIP.To4()
+			endIP = endIP.To4()
+		}
+
+		ipRanges = append(ipRanges, &ipRange{Start: startIP, End: endIP})
+	}
+
+	return ipRanges, nil
+}
+
+// validIPRanges validates a comma separated list of IP ranges of the given family (4 or 6).
+func validIPRanges(family int) func(value string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		ipRanges, err := parseIPRanges(value)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range ipRanges {
+			if (family == 4) != (r.Start.To4() != nil) {
+				return fmt.Errorf("IP range \"%s-%s\" isn't IPv%d", r.Start, r.End, family)
+			}
+		}
+
+		return nil
+	}
+}
+
+// nextIP returns the IP following the supplied one.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	return next
+}
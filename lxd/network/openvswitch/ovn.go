@@ -0,0 +1,737 @@
+package openvswitch
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+)
+
+// OVNRouter OVN router name.
+type OVNRouter string
+
+// OVNRouterPort OVN router port name.
+type OVNRouterPort string
+
+// OVNSwitch OVN switch name.
+type OVNSwitch string
+
+// OVNSwitchPort OVN switch port name.
+type OVNSwitchPort string
+
+// OVNChassisGroup OVN HA chassis group name.
+type OVNChassisGroup string
+
+// OVNIPAllocationOpts defines IP allocation settings that can be applied to a logical switch.
+type OVNIPAllocationOpts struct {
+	PrefixIPv4  *net.IPNet
+	PrefixIPv6  *net.IPNet
+	ExcludeIPv4 []net.IP
+}
+
+// OVNIPv6AddressMode IPv6 router advertisement address mode.
+type OVNIPv6AddressMode string
+
+// OVNIPv6AddressModeSLAAC IPv6 SLAAC mode.
+const OVNIPv6AddressModeSLAAC OVNIPv6AddressMode = "slaac"
+
+// OVNIPv6AddressModeDHCPStateful IPv6 DHCPv6 stateful mode.
+const OVNIPv6AddressModeDHCPStateful OVNIPv6AddressMode = "dhcpv6_stateful"
+
+// OVNIPv6AddressModeDHCPStateless IPv6 DHCPv6 stateless mode.
+const OVNIPv6AddressModeDHCPStateless OVNIPv6AddressMode = "dhcpv6_stateless"
+
+// OVNIPv6RAOpts IPv6 router advertisements options that can be applied to a router.
+type OVNIPv6RAOpts struct {
+	SendPeriodic       bool
+	AddressMode        OVNIPv6AddressMode
+	MinInterval        time.Duration
+	MaxInterval        time.Duration
+	RecursiveDNSServer net.IP
+	DNSSearchList      []string
+	MTU                uint32
+}
+
+// OVNDHCPv4Opts IPv4 DHCP options that can be applied to a switch port.
+type OVNDHCPv4Opts struct {
+	ServerID           net.IP
+	ServerMAC          net.HardwareAddr
+	Router             net.IP
+	RecursiveDNSServer net.IP
+	DomainName         string
+	LeaseTime          time.Duration
+	MTU                uint32
+}
+
+// OVNDHCPv6Opts IPv6 DHCP option set that can be created (and then applied to a switch port by resulting ID).
+type OVNDHCPv6Opts struct {
+	ServerID           net.HardwareAddr
+	RecursiveDNSServer net.IP
+	DNSSearchList      []string
+}
+
+// OVNSwitchPortOpts options that can be applied to a switch port.
+type OVNSwitchPortOpts struct {
+	MAC          net.HardwareAddr // Optional, if nil will be set to dynamic.
+	IPs          []net.IP         // Optional, if empty IPs will be set to dynamic.
+	DHCPv4OptsID string           // Optional, if empty, no DHCPv4 enabled on port.
+	DHCPv6OptsID string           // Optional, if empty, no DHCPv6 enabled on port.
+}
+
+// OVNACL defines an ACL that can be applied to a logical switch for a particular logical switch port.
+type OVNACL struct {
+	Direction string // Either "from-lport" or "to-lport".
+	Priority  uint   // Between 0 and 32767, higher priority ACLs are evaluated first.
+	Match     string // OVN match expression.
+	Action    string // One of "allow", "allow-related", "drop" or "reject".
+}
+
+// ovnExtIDLXDSwitch is the external ID used to tag the DHCP option sets belonging to a LXD managed switch.
+const ovnExtIDLXDSwitch = "lxd_switch"
+
+// ovnExtIDLXDSwitchPort is the external ID used to tag the ACLs belonging to a LXD managed switch port.
+const ovnExtIDLXDSwitchPort = "lxd_switch_port"
+
+// NewOVN initialises new OVN wrapper using the server's configured northbound database connection.
+func NewOVN(s *state.State) (*OVN, error) {
+	nbConnection, err := cluster.ConfigGetString(s.Cluster, "network.ovn.northbound_connection")
+	if err != nil {
+		return nil, err
+	}
+
+	return &OVN{nbDBAddr: nbConnection}, nil
+}
+
+// OVN command wrapper.
+type OVN struct {
+	nbDBAddr string
+}
+
+// nbctl executes ovn-nbctl with arguments to connect to the northbound database.
+func (o *OVN) nbctl(args ...string) (string, error) {
+	return shared.RunCommand("ovn-nbctl", append([]string{"--timeout=10", "--db", o.nbDBAddr}, args...)...)
+}
+
+// LogicalRouterAdd adds a named logical router.
+func (o *OVN) LogicalRouterAdd(routerName OVNRouter, mayExist bool) error {
+	args := []string{}
+
+	if mayExist {
+		args = append(args, "--may-exist")
+	}
+
+	_, err := o.nbctl(append(args, "lr-add", string(routerName))...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterDelete deletes a named logical router.
+func (o *OVN) LogicalRouterDelete(routerName OVNRouter) error {
+	_, err := o.nbctl("--if-exists", "lr-del", string(routerName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterSNATAdd adds an SNAT rule to a logical router to translate packets from intNet to extIP.
+func (o *OVN) LogicalRouterSNATAdd(routerName OVNRouter, intNet *net.IPNet, extIP net.IP) error {
+	_, err := o.nbctl("--may-exist", "lr-nat-add", string(routerName), "snat", extIP.String(), intNet.String())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterNATDeleteAll removes all NAT rules from a logical router.
+func (o *OVN) LogicalRouterNATDeleteAll(routerName OVNRouter) error {
+	_, err := o.nbctl("--if-exists", "lr-nat-del", string(routerName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterRouteAdd adds a static route to the logical router.
+func (o *OVN) LogicalRouterRouteAdd(routerName OVNRouter, destination *net.IPNet, nextHop net.IP) error {
+	_, err := o.nbctl("--may-exist", "lr-route-add", string(routerName), destination.String(), nextHop.String())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterRouteDeleteAll removes all static routes from the logical router.
+func (o *OVN) LogicalRouterRouteDeleteAll(routerName OVNRouter) error {
+	_, err := o.nbctl("--if-exists", "lr-route-del", string(routerName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterPortAdd adds a named logical router port to a logical router.
+func (o *OVN) LogicalRouterPortAdd(routerName OVNRouter, portName OVNRouterPort, mac net.HardwareAddr, ipAddr ...*net.IPNet) error {
+	args := []string{"--may-exist", "lrp-add", string(routerName), string(portName), mac.String()}
+
+	for _, ipNet := range ipAddr {
+		args = append(args, ipNet.String())
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterPortDelete deletes a named logical router port from a logical router.
+func (o *OVN) LogicalRouterPortDelete(portName OVNRouterPort) error {
+	_, err := o.nbctl("--if-exists", "lrp-del", string(portName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterPortSetIPv6Advertisements sets the IPv6 router advertisement options on a router port.
+func (o *OVN) LogicalRouterPortSetIPv6Advertisements(portName OVNRouterPort, opts *OVNIPv6RAOpts) error {
+	args := []string{"set", "logical_router_port", string(portName),
+		fmt.Sprintf("ipv6_ra_configs:send_periodic=%t", opts.SendPeriodic),
+	}
+
+	var removeRAConfigKeys []string
+
+	if opts.AddressMode != "" {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:address_mode=%s", string(opts.AddressMode)))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "address_mode")
+	}
+
+	if opts.MaxInterval > 0 {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:max_interval=%d", opts.MaxInterval/time.Second))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "max_interval")
+	}
+
+	if opts.MinInterval > 0 {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:min_interval=%d", opts.MinInterval/time.Second))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "min_interval")
+	}
+
+	if opts.MTU > 0 {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:mtu=%d", opts.MTU))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "mtu")
+	}
+
+	if len(opts.DNSSearchList) > 0 {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:dnssl=%s", strings.Join(opts.DNSSearchList, ",")))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "dnssl")
+	}
+
+	if opts.RecursiveDNSServer != nil {
+		args = append(args, fmt.Sprintf("ipv6_ra_configs:rdnss=%s", opts.RecursiveDNSServer.String()))
+	} else {
+		removeRAConfigKeys = append(removeRAConfigKeys, "rdnss")
+	}
+
+	// Configure IPv6 Router Advertisements.
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	// Remove any unset keys.
+	if len(removeRAConfigKeys) > 0 {
+		args = []string{"remove", "logical_router_port", string(portName), "ipv6_ra_configs"}
+		args = append(args, removeRAConfigKeys...)
+
+		_, err = o.nbctl(args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogicalRouterPortLinkChassisGroup links a logical router port to a HA chassis group.
+func (o *OVN) LogicalRouterPortLinkChassisGroup(portName OVNRouterPort, haChassisGroupName OVNChassisGroup) error {
+	chassisGroupID, err := o.chassisGroupID(haChassisGroupName)
+	if err != nil {
+		return err
+	}
+
+	if chassisGroupID == "" {
+		return fmt.Errorf("Chassis group %q not found", haChassisGroupName)
+	}
+
+	_, err = o.nbctl("set", "logical_router_port", string(portName), fmt.Sprintf("ha_chassis_group=%s", chassisGroupID))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchAdd adds a named logical switch.
+func (o *OVN) LogicalSwitchAdd(switchName OVNSwitch, mayExist bool) error {
+	args := []string{}
+
+	if mayExist {
+		args = append(args, "--may-exist")
+	}
+
+	_, err := o.nbctl(append(args, "ls-add", string(switchName))...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchDelete deletes a named logical switch along with its DHCP option sets.
+func (o *OVN) LogicalSwitchDelete(switchName OVNSwitch) error {
+	_, err := o.nbctl("--if-exists", "ls-del", string(switchName))
+	if err != nil {
+		return err
+	}
+
+	err = o.logicalSwitchDHCPOptionsDelete(switchName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchSetIPAllocation sets the IP allocation config on the logical switch.
+func (o *OVN) LogicalSwitchSetIPAllocation(switchName OVNSwitch, opts *OVNIPAllocationOpts) error {
+	var removeOtherConfigKeys []string
+	args := []string{"set", "logical_switch", string(switchName)}
+
+	if opts.PrefixIPv4 != nil {
+		args = append(args, fmt.Sprintf("other_config:subnet=%s", opts.PrefixIPv4.String()))
+	} else {
+		removeOtherConfigKeys = append(removeOtherConfigKeys, "subnet")
+	}
+
+	if opts.PrefixIPv6 != nil {
+		args = append(args, fmt.Sprintf("other_config:ipv6_prefix=%s", opts.PrefixIPv6.IP.String()))
+	} else {
+		removeOtherConfigKeys = append(removeOtherConfigKeys, "ipv6_prefix")
+	}
+
+	if len(opts.ExcludeIPv4) > 0 {
+		excludeIPs := make([]string, 0, len(opts.ExcludeIPv4))
+		for _, ip := range opts.ExcludeIPv4 {
+			excludeIPs = append(excludeIPs, ip.String())
+		}
+
+		args = append(args, fmt.Sprintf("other_config:exclude_ips=%s", strings.Join(excludeIPs, " ")))
+	} else {
+		removeOtherConfigKeys = append(removeOtherConfigKeys, "exclude_ips")
+	}
+
+	// Only run command if at least one setting is specified.
+	if len(args) > 3 {
+		_, err := o.nbctl(args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove any unset keys.
+	if len(removeOtherConfigKeys) > 0 {
+		args = []string{"remove", "logical_switch", string(switchName), "other_config"}
+		args = append(args, removeOtherConfigKeys...)
+
+		_, err := o.nbctl(args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogicalSwitchDHCPOptionsGet returns the UUID of the DHCP option set for the given CIDR that belongs to the
+// logical switch, or an empty string if there isn't one.
+func (o *OVN) LogicalSwitchDHCPOptionsGet(switchName OVNSwitch, cidr *net.IPNet) (string, error) {
+	output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--columns=_uuid", "find", "dhcp_options",
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitch, switchName),
+		fmt.Sprintf(`cidr="%s"`, cidr.String()),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// logicalSwitchDHCPOptionsCreate creates a DHCP option set for the given CIDR belonging to the logical switch
+// (if it doesn't already exist) and returns its UUID.
+func (o *OVN) logicalSwitchDHCPOptionsCreate(switchName OVNSwitch, cidr *net.IPNet) (string, error) {
+	uuid, err := o.LogicalSwitchDHCPOptionsGet(switchName, cidr)
+	if err != nil {
+		return "", err
+	}
+
+	if uuid != "" {
+		return uuid, nil
+	}
+
+	uuid, err = o.nbctl("create", "dhcp_options",
+		fmt.Sprintf(`cidr="%s"`, cidr.String()),
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitch, switchName),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(uuid), nil
+}
+
+// logicalSwitchDHCPOptionsDelete deletes all DHCP option sets belonging to the logical switch.
+func (o *OVN) logicalSwitchDHCPOptionsDelete(switchName OVNSwitch) error {
+	output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--columns=_uuid", "find", "dhcp_options",
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitch, switchName),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, uuid := range strings.Fields(output) {
+		_, err = o.nbctl("dhcp-options-del", uuid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogicalSwitchDHCPv4OptionsSet creates or updates the DHCPv4 option set of the logical switch for the given
+// subnet and returns its UUID so that it can be applied to switch ports.
+func (o *OVN) LogicalSwitchDHCPv4OptionsSet(switchName OVNSwitch, subnet *net.IPNet, opts *OVNDHCPv4Opts) (string, error) {
+	uuid, err := o.logicalSwitchDHCPOptionsCreate(switchName, subnet)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"dhcp-options-set-options", uuid,
+		fmt.Sprintf("server_id=%s", opts.ServerID.String()),
+		fmt.Sprintf("server_mac=%s", opts.ServerMAC.String()),
+		fmt.Sprintf("lease_time=%d", opts.LeaseTime/time.Second),
+	}
+
+	if opts.Router != nil {
+		args = append(args, fmt.Sprintf("router=%s", opts.Router.String()))
+	}
+
+	if opts.RecursiveDNSServer != nil {
+		args = append(args, fmt.Sprintf("dns_server=%s", opts.RecursiveDNSServer.String()))
+	}
+
+	if opts.DomainName != "" {
+		// Special quoting to allow domain names.
+		args = append(args, fmt.Sprintf(`domain_name="%s"`, opts.DomainName))
+	}
+
+	if opts.MTU > 0 {
+		args = append(args, fmt.Sprintf("mtu=%d", opts.MTU))
+	}
+
+	_, err = o.nbctl(args...)
+	if err != nil {
+		return "", err
+	}
+
+	return uuid, nil
+}
+
+// LogicalSwitchDHCPv6OptionsSet creates or updates the DHCPv6 option set of the logical switch for the given
+// subnet and returns its UUID so that it can be applied to switch ports.
+func (o *OVN) LogicalSwitchDHCPv6OptionsSet(switchName OVNSwitch, subnet *net.IPNet, opts *OVNDHCPv6Opts) (string, error) {
+	uuid, err := o.logicalSwitchDHCPOptionsCreate(switchName, subnet)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"dhcp-options-set-options", uuid,
+		fmt.Sprintf("server_id=%s", opts.ServerID.String()),
+	}
+
+	if len(opts.DNSSearchList) > 0 {
+		// Special quoting to allow domain names.
+		args = append(args, fmt.Sprintf(`domain_search="%s"`, strings.Join(opts.DNSSearchList, ",")))
+	}
+
+	if opts.RecursiveDNSServer != nil {
+		args = append(args, fmt.Sprintf("dns_server=%s", opts.RecursiveDNSServer.String()))
+	}
+
+	_, err = o.nbctl(args...)
+	if err != nil {
+		return "", err
+	}
+
+	return uuid, nil
+}
+
+// LogicalSwitchPortAdd adds a named logical switch port to a logical switch.
+func (o *OVN) LogicalSwitchPortAdd(switchName OVNSwitch, portName OVNSwitchPort, mayExist bool) error {
+	args := []string{}
+
+	if mayExist {
+		args = append(args, "--may-exist")
+	}
+
+	_, err := o.nbctl(append(args, "lsp-add", string(switchName), string(portName))...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortSet applies the addresses and DHCP options to a logical switch port.
+func (o *OVN) LogicalSwitchPortSet(portName OVNSwitchPort, opts *OVNSwitchPortOpts) error {
+	addresses := "dynamic"
+	if opts.MAC != nil {
+		addresses = opts.MAC.String()
+
+		if len(opts.IPs) > 0 {
+			for _, ip := range opts.IPs {
+				addresses = fmt.Sprintf("%s %s", addresses, ip.String())
+			}
+		} else {
+			addresses = fmt.Sprintf("%s dynamic", addresses)
+		}
+	}
+
+	_, err := o.nbctl("lsp-set-addresses", string(portName), addresses)
+	if err != nil {
+		return err
+	}
+
+	if opts.DHCPv4OptsID != "" {
+		_, err = o.nbctl("lsp-set-dhcpv4-options", string(portName), opts.DHCPv4OptsID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.DHCPv6OptsID != "" {
+		_, err = o.nbctl("lsp-set-dhcpv6-options", string(portName), opts.DHCPv6OptsID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortDelete deletes a named logical switch port.
+func (o *OVN) LogicalSwitchPortDelete(portName OVNSwitchPort) error {
+	_, err := o.nbctl("--if-exists", "lsp-del", string(portName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortLinkRouter links a logical switch port to a logical router port.
+func (o *OVN) LogicalSwitchPortLinkRouter(switchPortName OVNSwitchPort, routerPortName OVNRouterPort) error {
+	// Connect logical router port to switch.
+	_, err := o.nbctl(
+		"lsp-set-type", string(switchPortName), "router", "--",
+		"lsp-set-addresses", string(switchPortName), "router", "--",
+		"lsp-set-options", string(switchPortName), fmt.Sprintf("router-port=%s", string(routerPortName)),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortLinkProviderNetwork links a logical switch port to a provider network.
+func (o *OVN) LogicalSwitchPortLinkProviderNetwork(switchPortName OVNSwitchPort, extNetworkName string) error {
+	// Forward any unknown MAC frames down this port.
+	_, err := o.nbctl(
+		"lsp-set-addresses", string(switchPortName), "unknown", "--",
+		"lsp-set-type", string(switchPortName), "localnet", "--",
+		"lsp-set-options", string(switchPortName), fmt.Sprintf("network_name=%s", extNetworkName),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortACLsSet replaces the ACLs applied on the logical switch for the logical switch port.
+func (o *OVN) LogicalSwitchPortACLsSet(switchName OVNSwitch, portName OVNSwitchPort, acls []OVNACL) error {
+	// Clear existing ACLs and add the new ones in the same transaction so traffic is never unfiltered.
+	args, err := o.logicalSwitchPortACLsDeleteArgs(switchName, portName)
+	if err != nil {
+		return err
+	}
+
+	for i, acl := range acls {
+		id := fmt.Sprintf("@acl%d", i)
+
+		if len(args) > 0 {
+			args = append(args, "--")
+		}
+
+		args = append(args, fmt.Sprintf("--id=%s", id), "create", "acl",
+			fmt.Sprintf("direction=%s", acl.Direction),
+			fmt.Sprintf("priority=%d", acl.Priority),
+			fmt.Sprintf("match=%s", strconv.Quote(acl.Match)),
+			fmt.Sprintf("action=%s", acl.Action),
+			fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitchPort, portName),
+			"--", "add", "logical_switch", string(switchName), "acls", id,
+		)
+	}
+
+	if len(args) < 1 {
+		return nil
+	}
+
+	_, err = o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalSwitchPortACLsDelete removes any ACLs applied on the logical switch for the logical switch port.
+func (o *OVN) LogicalSwitchPortACLsDelete(switchName OVNSwitch, portName OVNSwitchPort) error {
+	args, err := o.logicalSwitchPortACLsDeleteArgs(switchName, portName)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return nil
+	}
+
+	_, err = o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// logicalSwitchPortACLsDeleteArgs returns the arguments needed to remove the ACLs belonging to the logical
+// switch port from the logical switch (unreferenced ACL rows are then garbage collected by the database).
+func (o *OVN) logicalSwitchPortACLsDeleteArgs(switchName OVNSwitch, portName OVNSwitchPort) ([]string, error) {
+	output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--columns=_uuid", "find", "acl",
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitchPort, portName),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	uuids := strings.Fields(output)
+	if len(uuids) < 1 {
+		return []string{}, nil
+	}
+
+	return append([]string{"remove", "logical_switch", string(switchName), "acls"}, uuids...), nil
+}
+
+// chassisGroupID returns the UUID of the named HA chassis group, or an empty string if it doesn't exist.
+func (o *OVN) chassisGroupID(haChassisGroupName OVNChassisGroup) (string, error) {
+	output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--columns=_uuid", "find", "ha_chassis_group",
+		fmt.Sprintf("name=%s", string(haChassisGroupName)),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// ChassisGroupAdd adds a new HA chassis group.
+func (o *OVN) ChassisGroupAdd(haChassisGroupName OVNChassisGroup, mayExist bool) error {
+	if mayExist {
+		chassisGroupID, err := o.chassisGroupID(haChassisGroupName)
+		if err != nil {
+			return err
+		}
+
+		if chassisGroupID != "" {
+			return nil // Chassis group already exists, nothing to do.
+		}
+	}
+
+	_, err := o.nbctl("ha-chassis-group-add", string(haChassisGroupName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChassisGroupDelete deletes a HA chassis group (if it exists).
+func (o *OVN) ChassisGroupDelete(haChassisGroupName OVNChassisGroup) error {
+	chassisGroupID, err := o.chassisGroupID(haChassisGroupName)
+	if err != nil {
+		return err
+	}
+
+	if chassisGroupID == "" {
+		return nil // Chassis group doesn't exist, nothing to do.
+	}
+
+	_, err = o.nbctl("ha-chassis-group-del", string(haChassisGroupName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChassisGroupChassisAdd adds a chassis ID to an HA chassis group with the specified priority.
+func (o *OVN) ChassisGroupChassisAdd(haChassisGroupName OVNChassisGroup, chassisID string, priority uint) error {
+	_, err := o.nbctl("ha-chassis-group-add-chassis", string(haChassisGroupName), chassisID, fmt.Sprintf("%d", priority))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChassisGroupChassisDelete deletes a chassis ID from an HA chassis group.
+func (o *OVN) ChassisGroupChassisDelete(haChassisGroupName OVNChassisGroup, chassisID string) error {
+	_, err := o.nbctl("ha-chassis-group-remove-chassis", string(haChassisGroupName), chassisID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
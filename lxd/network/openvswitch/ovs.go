@@ -0,0 +1,220 @@
+package openvswitch
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// NewOVS initialises new OVS wrapper.
+func NewOVS() *OVS {
+	return &OVS{}
+}
+
+// OVS command wrapper.
+type OVS struct{}
+
+// Installed returns true if OVS tools are installed.
+func (o *OVS) Installed() bool {
+	_, err := exec.LookPath("ovs-vsctl")
+	return err == nil
+}
+
+// BridgeExists returns true if OVS bridge exists.
+func (o *OVS) BridgeExists(bridgeName string) (bool, error) {
+	_, err := shared.RunCommand("ovs-vsctl", "br-exists", bridgeName)
+	if err != nil {
+		runErr, ok := err.(shared.RunError)
+		if ok {
+			exitError, ok := runErr.Err.(*exec.ExitError)
+
+			// ovs-vsctl manpage says that br-exists exits with code 2 if bridge doesn't exist.
+			if ok && exitError.ExitCode() == 2 {
+				return false, nil
+			}
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BridgeAdd adds an OVS bridge.
+func (o *OVS) BridgeAdd(bridgeName string, mayExist bool) error {
+	args := []string{}
+
+	if mayExist {
+		args = append(args, "--may-exist")
+	}
+
+	args = append(args, "add-br", bridgeName)
+
+	_, err := shared.RunCommand("ovs-vsctl", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BridgeDelete deletes an OVS bridge.
+func (o *OVS) BridgeDelete(bridgeName string) error {
+	_, err := shared.RunCommand("ovs-vsctl", "del-br", bridgeName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BridgePortAdd adds a port to the bridge (if already attached does nothing).
+func (o *OVS) BridgePortAdd(bridgeName string, portName string, mayExist bool) error {
+	args := []string{}
+
+	if mayExist {
+		args = append(args, "--may-exist")
+	}
+
+	args = append(args, "add-port", bridgeName, portName)
+
+	_, err := shared.RunCommand("ovs-vsctl", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BridgePortDelete deletes a port from the bridge (if already detached does nothing).
+func (o *OVS) BridgePortDelete(bridgeName string, portName string) error {
+	_, err := shared.RunCommand("ovs-vsctl", "--if-exists", "del-port", bridgeName, portName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InterfaceAssociateOVNSwitchPort removes any existing OVS ports associated to the specified ovnSwitchPortName
+// and then associates the specified interfaceName to the OVN switch port.
+func (o *OVS) InterfaceAssociateOVNSwitchPort(interfaceName string, ovnSwitchPortName OVNSwitchPort) error {
+	// Clear existing ports that were formerly associated to ovnSwitchPortName.
+	existingPorts, err := shared.RunCommand("ovs-vsctl", "--format=csv", "--no-headings", "--data=bare", "--columns=name", "find", "interface", fmt.Sprintf("external-ids:iface-id=%s", string(ovnSwitchPortName)))
+	if err != nil {
+		return err
+	}
+
+	existingPorts = strings.TrimSpace(existingPorts)
+	if existingPorts != "" {
+		for _, port := range strings.Split(existingPorts, "\n") {
+			_, err = shared.RunCommand("ovs-vsctl", "del-port", port)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = shared.RunCommand("ovs-vsctl", "set", "interface", interfaceName, fmt.Sprintf("external_ids:iface-id=%s", string(ovnSwitchPortName)))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChassisID returns the local chassis ID.
+func (o *OVS) ChassisID() (string, error) {
+	// ovs-vsctl's get command doesn't support its --format flag, so we always get the output quoted.
+	// However ovs-vsctl's find and list commands don't support retrieving a single column's map field.
+	// And ovs-vsctl's JSON output is unfriendly towards statically typed languages as it mixes data types
+	// in a slice. So stick with "get" command and use Go's strconv.Unquote to return the actual values.
+	chassisID, err := shared.RunCommand("ovs-vsctl", "get", "open_vswitch", ".", "external_ids:system-id")
+	if err != nil {
+		return "", err
+	}
+
+	chassisID, err = strconv.Unquote(strings.TrimSpace(chassisID))
+	if err != nil {
+		return "", err
+	}
+
+	return chassisID, nil
+}
+
+// OVNBridgeMappings gets the current OVN bridge mappings.
+func (o *OVS) OVNBridgeMappings() ([]string, error) {
+	mappings, err := shared.RunCommand("ovs-vsctl", "--if-exists", "get", "open_vswitch", ".", "external-ids:ovn-bridge-mappings")
+	if err != nil {
+		return nil, err
+	}
+
+	mappings = strings.TrimSpace(mappings)
+	if mappings == "" {
+		return []string{}, nil
+	}
+
+	mappings, err = strconv.Unquote(mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(mappings, ","), nil
+}
+
+// OVNBridgeMappingAdd appends an OVN bridge mapping between an OVS bridge and the logical provider name.
+func (o *OVS) OVNBridgeMappingAdd(bridgeName string, providerName string) error {
+	mappings, err := o.OVNBridgeMappings()
+	if err != nil {
+		return err
+	}
+
+	newMapping := fmt.Sprintf("%s:%s", providerName, bridgeName)
+	if shared.StringInSlice(newMapping, mappings) {
+		return nil // Mapping is already present, nothing to do.
+	}
+
+	mappings = append(mappings, newMapping)
+
+	// Set new mapping string back into OVS database.
+	_, err = shared.RunCommand("ovs-vsctl", "set", "open_vswitch", ".", fmt.Sprintf("external-ids:ovn-bridge-mappings=%s", strings.Join(mappings, ",")))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// OVNBridgeMappingDelete deletes an OVN bridge mapping between an OVS bridge and the logical provider name.
+func (o *OVS) OVNBridgeMappingDelete(bridgeName string, providerName string) error {
+	mappings, err := o.OVNBridgeMappings()
+	if err != nil {
+		return err
+	}
+
+	oldMapping := fmt.Sprintf("%s:%s", providerName, bridgeName)
+	newMappings := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		if mapping != oldMapping {
+			newMappings = append(newMappings, mapping)
+		}
+	}
+
+	if len(newMappings) == len(mappings) {
+		return nil // Mapping isn't present, nothing to do.
+	}
+
+	if len(newMappings) < 1 {
+		_, err = shared.RunCommand("ovs-vsctl", "remove", "open_vswitch", ".", "external-ids", "ovn-bridge-mappings")
+	} else {
+		_, err = shared.RunCommand("ovs-vsctl", "set", "open_vswitch", ".", fmt.Sprintf("external-ids:ovn-bridge-mappings=%s", strings.Join(newMappings, ",")))
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
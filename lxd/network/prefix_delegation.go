@@ -0,0 +1,478 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/pkg/errors"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// DHCPv6 status code for a server without any prefix to delegate (RFC 3633).
+const dhcpv6StatusNoPrefixAvail = 6
+
+// Maximum time between retransmissions of a DHCPv6 message.
+const dhcpv6MaxRetransmit = 2 * time.Minute
+
+// prefixDelegations tracks the running prefix delegation clients, keyed by network name.
+var prefixDelegations = map[string]*prefixDelegation{}
+var prefixDelegationsMu sync.Mutex
+
+// delegatedPrefix represents a prefix leased from the upstream router.
+type delegatedPrefix struct {
+	prefix   *net.IPNet
+	serverID []byte
+	renew    time.Duration
+	expiry   time.Time
+}
+
+// prefixDelegation is a DHCPv6 client requesting a prefix delegation (RFC 3633) on an uplink interface.
+type prefixDelegation struct {
+	logger   logger.Logger
+	iface    string
+	duid     []byte
+	iaid     uint32
+	onChange func(prefix *net.IPNet)
+
+	conn *net.UDPConn
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startPrefixDelegation starts the DHCPv6 prefix delegation client for the network if configured and not
+// already running on the configured interface. Any client running on another interface is stopped.
+func startPrefixDelegation(networkName string, l logger.Logger, iface string, onChange func(prefix *net.IPNet)) error {
+	prefixDelegationsMu.Lock()
+	defer prefixDelegationsMu.Unlock()
+
+	pd, ok := prefixDelegations[networkName]
+	if ok {
+		if pd.iface == iface {
+			return nil
+		}
+
+		pd.close()
+		delete(prefixDelegations, networkName)
+	}
+
+	if iface == "" {
+		return nil
+	}
+
+	pd, err := newPrefixDelegation(networkName, l, iface, onChange)
+	if err != nil {
+		return err
+	}
+
+	prefixDelegations[networkName] = pd
+	go pd.run()
+
+	return nil
+}
+
+// stopPrefixDelegation stops the DHCPv6 prefix delegation client of the network if running.
+func stopPrefixDelegation(networkName string) {
+	prefixDelegationsMu.Lock()
+	defer prefixDelegationsMu.Unlock()
+
+	pd, ok := prefixDelegations[networkName]
+	if !ok {
+		return
+	}
+
+	pd.close()
+	delete(prefixDelegations, networkName)
+}
+
+func newPrefixDelegation(networkName string, l logger.Logger, ifaceName string, onChange func(prefix *net.IPNet)) (*prefixDelegation, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading prefix delegation interface %q", ifaceName)
+	}
+
+	// DHCPv6 servers only answer on-link clients, so the client must use the uplink's link-local address.
+	var linkLocal net.IP
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err == nil && ip.To4() == nil && ip.IsLinkLocalUnicast() {
+			linkLocal = ip
+			break
+		}
+	}
+
+	if linkLocal == nil {
+		return nil, fmt.Errorf("No IPv6 link-local address found on prefix delegation interface %q", ifaceName)
+	}
+
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: linkLocal, Port: 546, Zone: ifaceName})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed listening for DHCPv6 replies on %q", ifaceName)
+	}
+
+	// Use a DUID-LL based on the uplink's MAC address and an IAID unique to the network.
+	duid := []byte{0, 3, 0, 1}
+	duid = append(duid, iface.HardwareAddr...)
+
+	h := fnv.New32a()
+	h.Write([]byte(networkName))
+
+	pd := &prefixDelegation{
+		logger:   l,
+		iface:    ifaceName,
+		duid:     duid,
+		iaid:     h.Sum32(),
+		onChange: onChange,
+		conn:     conn,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	return pd, nil
+}
+
+// close stops the client and waits for it to exit.
+func (pd *prefixDelegation) close() {
+	close(pd.stop)
+	pd.conn.Close()
+	<-pd.done
+}
+
+// stopped returns whether the client has been asked to stop.
+func (pd *prefixDelegation) stopped() bool {
+	select {
+	case <-pd.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait sleeps for the given duration, returning false if the client was stopped in the meantime.
+func (pd *prefixDelegation) wait(d time.Duration) bool {
+	select {
+	case <-pd.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// run acquires a prefix and keeps renewing it until the client is stopped.
+func (pd *prefixDelegation) run() {
+	defer close(pd.done)
+
+	var current *net.IPNet
+	for !pd.stopped() {
+		lease, err := pd.acquire()
+		if err != nil {
+			if pd.stopped() {
+				return
+			}
+
+			pd.logger.Warn("Failed acquiring delegated prefix", log.Ctx{"interface": pd.iface, "err": err})
+			if !pd.wait(dhcpv6MaxRetransmit) {
+				return
+			}
+
+			continue
+		}
+
+		if current == nil || current.String() != lease.prefix.String() {
+			pd.logger.Info("Acquired delegated prefix", log.Ctx{"interface": pd.iface, "prefix": lease.prefix.String()})
+			current = lease.prefix
+			pd.onChange(current)
+		}
+
+		// Renew the lease until the server stops extending it.
+		for {
+			if !pd.wait(lease.renew) {
+				return
+			}
+
+			renewed, err := pd.renew(lease)
+			if err != nil {
+				if pd.stopped() {
+					return
+				}
+
+				if time.Now().Before(lease.expiry) {
+					pd.logger.Warn("Failed renewing delegated prefix", log.Ctx{"interface": pd.iface, "err": err})
+					lease.renew = lease.expiry.Sub(time.Now()) / 2
+					if lease.renew < time.Minute {
+						lease.renew = time.Minute
+					}
+
+					continue
+				}
+
+				pd.logger.Warn("Lost delegated prefix", log.Ctx{"interface": pd.iface, "prefix": current.String()})
+				current = nil
+				pd.onChange(nil)
+				break
+			}
+
+			lease = renewed
+			if current.String() != lease.prefix.String() {
+				pd.logger.Info("Delegated prefix changed", log.Ctx{"interface": pd.iface, "prefix": lease.prefix.String()})
+				current = lease.prefix
+				pd.onChange(current)
+			}
+		}
+	}
+}
+
+// acquire runs the Solicit/Advertise/Request/Reply exchange.
+func (pd *prefixDelegation) acquire() (*delegatedPrefix, error) {
+	advertise, err := pd.exchange(layers.DHCPv6MsgTypeSolicit, layers.DHCPv6MsgTypeAdverstise, pd.iaPD(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	offer, err := pd.parseLease(advertise)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := pd.exchange(layers.DHCPv6MsgTypeRequest, layers.DHCPv6MsgTypeReply, pd.iaPD(offer.prefix), layers.NewDHCPv6Option(layers.DHCPv6OptServerID, offer.serverID))
+	if err != nil {
+		return nil, err
+	}
+
+	return pd.parseLease(reply)
+}
+
+// renew extends the lease with the server which delegated the prefix.
+func (pd *prefixDelegation) renew(lease *delegatedPrefix) (*delegatedPrefix, error) {
+	reply, err := pd.exchange(layers.DHCPv6MsgTypeRenew, layers.DHCPv6MsgTypeReply, pd.iaPD(lease.prefix), layers.NewDHCPv6Option(layers.DHCPv6OptServerID, lease.serverID))
+	if err != nil {
+		return nil, err
+	}
+
+	return pd.parseLease(reply)
+}
+
+// iaPD builds an IA_PD option, optionally hinting the prefix to the server.
+func (pd *prefixDelegation) iaPD(prefix *net.IPNet) layers.DHCPv6Option {
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data[0:4], pd.iaid) // Identity Association Identifier
+	binary.BigEndian.PutUint32(data[4:8], 0)       // T1
+	binary.BigEndian.PutUint32(data[8:12], 0)      // T2
+
+	if prefix != nil {
+		ones, _ := prefix.Mask.Size()
+
+		iaPrefix := make([]byte, 29)
+		binary.BigEndian.PutUint16(iaPrefix[0:2], uint16(layers.DHCPv6OptIAPrefix)) // Sub-Option type
+		binary.BigEndian.PutUint16(iaPrefix[2:4], 25)                               // Length (fixed at 25 bytes)
+		iaPrefix[12] = byte(ones)                                                   // Prefix length
+		copy(iaPrefix[13:29], prefix.IP.To16())                                     // Prefix
+		data = append(data, iaPrefix...)
+	}
+
+	return layers.NewDHCPv6Option(layers.DHCPv6OptIAPD, data)
+}
+
+// exchange sends a message to all DHCPv6 servers and relay agents on the link and waits for the matching
+// response, retransmitting with an exponential backoff.
+func (pd *prefixDelegation) exchange(msgType layers.DHCPv6MsgType, replyType layers.DHCPv6MsgType, options ...layers.DHCPv6Option) (*layers.DHCPv6, error) {
+	xid := make([]byte, 3)
+	_, err := rand.Read(xid)
+	if err != nil {
+		return nil, err
+	}
+
+	dhcp := layers.DHCPv6{
+		MsgType:       msgType,
+		TransactionID: xid,
+	}
+
+	dhcp.Options = append(dhcp.Options,
+		layers.NewDHCPv6Option(layers.DHCPv6OptClientID, pd.duid),
+		layers.NewDHCPv6Option(layers.DHCPv6OptElapsedTime, []byte{0, 0}),
+	)
+	dhcp.Options = append(dhcp.Options, options...)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		ComputeChecksums: true,
+		FixLengths:       true,
+	}
+
+	err = gopacket.SerializeLayers(buf, opts, &dhcp)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := &net.UDPAddr{IP: net.ParseIP("ff02::1:2"), Port: 547, Zone: pd.iface}
+	timeout := time.Second
+	packet := make([]byte, 1500)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		_, err = pd.conn.WriteToUDP(buf.Bytes(), dst)
+		if err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(timeout + jitter(timeout/10))
+		pd.conn.SetReadDeadline(deadline)
+
+		for time.Now().Before(deadline) {
+			n, _, err := pd.conn.ReadFromUDP(packet)
+			if err != nil {
+				if pd.stopped() {
+					return nil, fmt.Errorf("Prefix delegation client stopped")
+				}
+
+				netErr, ok := err.(net.Error)
+				if ok && netErr.Timeout() {
+					break
+				}
+
+				return nil, err
+			}
+
+			reply := layers.DHCPv6{}
+			err = reply.DecodeFromBytes(packet[:n], gopacket.NilDecodeFeedback)
+			if err != nil || reply.MsgType != replyType || string(reply.TransactionID) != string(xid) {
+				continue
+			}
+
+			return &reply, nil
+		}
+
+		timeout *= 2
+		if timeout > dhcpv6MaxRetransmit {
+			timeout = dhcpv6MaxRetransmit
+		}
+	}
+
+	return nil, fmt.Errorf("No DHCPv6 server answered on %q", pd.iface)
+}
+
+// parseLease extracts the delegated prefix and its lifetimes from an Advertise or Reply message.
+func (pd *prefixDelegation) parseLease(msg *layers.DHCPv6) (*delegatedPrefix, error) {
+	lease := &delegatedPrefix{}
+
+	for _, opt := range msg.Options {
+		switch opt.Code {
+		case layers.DHCPv6OptServerID:
+			lease.serverID = opt.Data
+		case layers.DHCPv6OptStatusCode:
+			if len(opt.Data) >= 2 && binary.BigEndian.Uint16(opt.Data[0:2]) != 0 {
+				return nil, fmt.Errorf("DHCPv6 server error: %s", string(opt.Data[2:]))
+			}
+		case layers.DHCPv6OptIAPD:
+			if len(opt.Data) < 12 || binary.BigEndian.Uint32(opt.Data[0:4]) != pd.iaid {
+				continue
+			}
+
+			t1 := binary.BigEndian.Uint32(opt.Data[4:8])
+
+			// Walk the IA_PD sub-options.
+			data := opt.Data[12:]
+			for len(data) >= 4 {
+				code := binary.BigEndian.Uint16(data[0:2])
+				length := int(binary.BigEndian.Uint16(data[2:4]))
+				if len(data) < 4+length {
+					break
+				}
+
+				value := data[4 : 4+length]
+				data = data[4+length:]
+
+				switch code {
+				case uint16(layers.DHCPv6OptStatusCode):
+					if len(value) >= 2 && binary.BigEndian.Uint16(value[0:2]) == dhcpv6StatusNoPrefixAvail {
+						return nil, fmt.Errorf("No prefix available for delegation")
+					}
+				case uint16(layers.DHCPv6OptIAPrefix):
+					if len(value) < 25 {
+						continue
+					}
+
+					preferred := binary.BigEndian.Uint32(value[0:4])
+					valid := binary.BigEndian.Uint32(value[4:8])
+					if valid == 0 {
+						continue
+					}
+
+					prefix := &net.IPNet{
+						IP:   net.IP(append([]byte{}, value[9:25]...)),
+						Mask: net.CIDRMask(int(value[8]), 128),
+					}
+
+					lease.prefix = prefix
+					lease.expiry = time.Now().Add(time.Duration(valid) * time.Second)
+					lease.renew = time.Duration(t1) * time.Second
+					if lease.renew == 0 {
+						lease.renew = time.Duration(preferred) * time.Second / 2
+					}
+
+					if lease.renew < time.Minute {
+						lease.renew = time.Minute
+					}
+				}
+			}
+		}
+	}
+
+	if lease.prefix == nil {
+		return nil, fmt.Errorf("No delegated prefix in DHCPv6 response")
+	}
+
+	if lease.serverID == nil {
+		return nil, fmt.Errorf("No server identifier in DHCPv6 response")
+	}
+
+	return lease, nil
+}
+
+// jitter returns a random duration up to max.
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(n.Int64())
+}
+
+// delegatedSubnet returns the gateway address of the /64 with the given index within the delegated prefix.
+func delegatedSubnet(prefix *net.IPNet, index uint64) (string, error) {
+	ones, bits := prefix.Mask.Size()
+	if bits != 128 || ones > 64 {
+		return "", fmt.Errorf("Delegated prefix %q is smaller than a /64", prefix.String())
+	}
+
+	if ones < 64 && index >= uint64(1)<<uint(64-ones) {
+		return "", fmt.Errorf("Subnet %d doesn't fit in delegated prefix %q", index, prefix.String())
+	} else if ones == 64 && index > 0 {
+		return "", fmt.Errorf("Subnet %d doesn't fit in delegated prefix %q", index, prefix.String())
+	}
+
+	ip := make(net.IP, 16)
+	copy(ip, prefix.IP.To16())
+	binary.BigEndian.PutUint64(ip[0:8], binary.BigEndian.Uint64(ip[0:8])|index)
+	ip[15] = 1
+
+	return fmt.Sprintf("%s/64", ip.String()), nil
+}
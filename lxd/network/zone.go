@@ -0,0 +1,373 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/lxc/lxd/lxd/db"
+	lxdDNS "github.com/lxc/lxd/lxd/dns"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// zoneRecordTTL is the TTL of the instance records in the zones.
+const zoneRecordTTL = 300
+
+// zoneNetwork identifies a network using a zone, as network names are only unique within a project.
+type zoneNetwork struct {
+	project string
+	name    string
+}
+
+// ZoneLeasesFunc returns the leases of the instances of the project on the network.
+type ZoneLeasesFunc func(networkName string, projectName string) ([]api.NetworkLease, error)
+
+// ValidZoneName checks the supplied name is a valid network zone name.
+func ValidZoneName(value string) error {
+	if value == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.HasSuffix(value, ".") {
+		return fmt.Errorf("Name must not end with a dot")
+	}
+
+	if value != strings.ToLower(value) {
+		return fmt.Errorf("Name must be lowercase")
+	}
+
+	_, ok := dns.IsDomainName(value)
+	if !ok {
+		return fmt.Errorf("Name must be a valid DNS domain name")
+	}
+
+	return nil
+}
+
+// ValidateZone checks the supplied network zone configuration is valid.
+func ValidateZone(put *api.NetworkZonePut) error {
+	for k, v := range put.Config {
+		switch {
+		case k == "dns.nameservers":
+			for _, name := range ZoneNames(v) {
+				_, ok := dns.IsDomainName(name)
+				if !ok {
+					return fmt.Errorf("Invalid name server %q", name)
+				}
+			}
+		case strings.HasPrefix(k, "dns.peers."):
+			fields := strings.Split(k, ".")
+			if len(fields) != 4 || fields[2] == "" || fields[3] != "address" {
+				return fmt.Errorf("Invalid network zone configuration key %q", k)
+			}
+
+			if net.ParseIP(v) == nil {
+				return fmt.Errorf("Invalid address %q for peer %q", v, fields[2])
+			}
+		case strings.HasPrefix(k, "user."):
+		default:
+			return fmt.Errorf("Invalid network zone configuration key %q", k)
+		}
+	}
+
+	return nil
+}
+
+// ZoneNames returns the list of zone names from a comma separated value.
+func ZoneNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || shared.StringInSlice(name, names) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ValidForwardZones returns a validator for a comma separated list of existing network zones, with at most one
+// zone per project.
+func ValidForwardZones(s *state.State) func(value string) error {
+	return func(value string) error {
+		projects := map[string]string{}
+		for _, name := range ZoneNames(value) {
+			_, projectName, _, err := s.Cluster.GetNetworkZone(name)
+			if err == db.ErrNoSuchObject {
+				return fmt.Errorf("Network zone %q doesn't exist", name)
+			} else if err != nil {
+				return err
+			}
+
+			other, ok := projects[projectName]
+			if ok {
+				return fmt.Errorf("Network zones %q and %q both belong to project %q", other, name, projectName)
+			}
+
+			projects[projectName] = name
+		}
+
+		return nil
+	}
+}
+
+// ValidReverseZone returns a validator for an existing reverse network zone of the given IP family.
+func ValidReverseZone(s *state.State, family uint) func(value string) error {
+	suffix := ".in-addr.arpa"
+	if family == 6 {
+		suffix = ".ip6.arpa"
+	}
+
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if !strings.HasSuffix(value, suffix) {
+			return fmt.Errorf("Reverse zone name must end with %q", suffix)
+		}
+
+		_, _, _, err := s.Cluster.GetNetworkZone(value)
+		if err == db.ErrNoSuchObject {
+			return fmt.Errorf("Network zone %q doesn't exist", value)
+		}
+
+		return err
+	}
+}
+
+// zoneNetworks returns the config of the networks using the zone as forward zone and as reverse zone.
+func zoneNetworks(s *state.State, zoneName string) (map[zoneNetwork]map[string]string, map[zoneNetwork]map[string]string, error) {
+	forward := map[zoneNetwork]map[string]string{}
+	reverse := map[zoneNetwork]map[string]string{}
+
+	networks, err := s.Cluster.GetNetworks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for networkProject, networkNames := range networks {
+		for _, networkName := range networkNames {
+			_, network, err := s.Cluster.GetNetworkInAnyState(networkProject, networkName)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			key := zoneNetwork{project: networkProject, name: networkName}
+			if shared.StringInSlice(zoneName, ZoneNames(network.Config["dns.zone.forward"])) {
+				forward[key] = network.Config
+			}
+
+			if network.Config["dns.zone.reverse.ipv4"] == zoneName || network.Config["dns.zone.reverse.ipv6"] == zoneName {
+				reverse[key] = network.Config
+			}
+		}
+	}
+
+	return forward, reverse, nil
+}
+
+// zoneVisibleNetworks drops the networks which can't be used by the instances of the project.
+func zoneVisibleNetworks(s *state.State, projectName string, networks map[zoneNetwork]map[string]string) (map[zoneNetwork]map[string]string, error) {
+	visible := map[zoneNetwork]map[string]string{}
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		for key, config := range networks {
+			networkProject, err := project.NetworkProject(tx, projectName, key.name)
+			if err == db.ErrNoSuchObject {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if networkProject == key.project {
+				visible[key] = config
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+// ZoneUsedBy returns the URLs of the networks using the network zone.
+func ZoneUsedBy(s *state.State, zoneName string) ([]string, error) {
+	forward, reverse, err := zoneNetworks(s, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	usedBy := []string{}
+	for _, networks := range []map[zoneNetwork]map[string]string{forward, reverse} {
+		for key := range networks {
+			uri := fmt.Sprintf("/%s/networks/%s", version.APIVersion, key.name)
+			if key.project != project.Default {
+				uri += fmt.Sprintf("?project=%s", key.project)
+			}
+
+			if !shared.StringInSlice(uri, usedBy) {
+				usedBy = append(usedBy, uri)
+			}
+		}
+	}
+
+	return usedBy, nil
+}
+
+// ZoneContent returns the records of the network zone, or nil if the zone doesn't exist. The forward zones
+// contain the address records of the instances of the zone's project on the networks using the zone and the
+// reverse zones the matching pointer records.
+func ZoneContent(s *state.State, zoneName string, leasesFunc ZoneLeasesFunc) (*lxdDNS.Zone, error) {
+	_, projectName, zoneInfo, err := s.Cluster.GetNetworkZone(zoneName)
+	if err == db.ErrNoSuchObject {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	zone := &lxdDNS.Zone{Name: zoneName}
+	fqdn := dns.Fqdn(zoneName)
+
+	// Name servers.
+	nameservers := []string{}
+	for _, name := range ZoneNames(zoneInfo.Config["dns.nameservers"]) {
+		nameservers = append(nameservers, dns.Fqdn(name))
+	}
+
+	primary := "ns1." + fqdn
+	if len(nameservers) > 0 {
+		primary = nameservers[0]
+	}
+
+	// Peers allowed to transfer the zone.
+	for k, v := range zoneInfo.Config {
+		if strings.HasPrefix(k, "dns.peers.") && strings.HasSuffix(k, ".address") {
+			ip := net.ParseIP(v)
+			if ip != nil {
+				zone.Peers = append(zone.Peers, ip)
+			}
+		}
+	}
+
+	header := func(name string, rrtype uint16, ttl uint32) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+	}
+
+	zone.Records = append(zone.Records, &dns.SOA{
+		Hdr:     header(fqdn, dns.TypeSOA, 30),
+		Ns:      primary,
+		Mbox:    "hostmaster." + fqdn,
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 120,
+		Retry:   60,
+		Expire:  86400,
+		Minttl:  30,
+	})
+
+	for _, nameserver := range nameservers {
+		zone.Records = append(zone.Records, &dns.NS{Hdr: header(fqdn, dns.TypeNS, zoneRecordTTL), Ns: nameserver})
+	}
+
+	forward, reverse, err := zoneNetworks(s, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	forward, err = zoneVisibleNetworks(s, projectName, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	reverse, err = zoneVisibleNetworks(s, projectName, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	// addRecord adds the record to the zone unless it's already there.
+	seen := map[string]bool{}
+	addRecord := func(record dns.RR) {
+		key := record.String()
+		if seen[key] {
+			return
+		}
+
+		seen[key] = true
+		zone.Records = append(zone.Records, record)
+	}
+
+	for key := range forward {
+		leases, err := leasesFunc(key.name, projectName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lease := range leases {
+			ip := net.ParseIP(lease.Address)
+			if ip == nil || lease.Hostname == "" {
+				continue
+			}
+
+			name := dns.Fqdn(fmt.Sprintf("%s.%s", lease.Hostname, zoneName))
+			if ip.To4() != nil {
+				addRecord(&dns.A{Hdr: header(name, dns.TypeA, zoneRecordTTL), A: ip.To4()})
+			} else {
+				addRecord(&dns.AAAA{Hdr: header(name, dns.TypeAAAA, zoneRecordTTL), AAAA: ip})
+			}
+		}
+	}
+
+	for key, config := range reverse {
+		// Pointer records target the names in the network's forward zone of the same project.
+		forwardZone := ""
+		for _, name := range ZoneNames(config["dns.zone.forward"]) {
+			_, forwardProject, _, err := s.Cluster.GetNetworkZone(name)
+			if err != nil {
+				continue
+			}
+
+			if forwardProject == projectName {
+				forwardZone = name
+				break
+			}
+		}
+
+		if forwardZone == "" {
+			continue
+		}
+
+		leases, err := leasesFunc(key.name, projectName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lease := range leases {
+			ip := net.ParseIP(lease.Address)
+			if ip == nil || lease.Hostname == "" {
+				continue
+			}
+
+			name, err := dns.ReverseAddr(ip.String())
+			if err != nil || !strings.HasSuffix(name, fqdn) {
+				continue
+			}
+
+			addRecord(&dns.PTR{Hdr: header(name, dns.TypePTR, zoneRecordTTL), Ptr: dns.Fqdn(fmt.Sprintf("%s.%s", lease.Hostname, forwardZone))})
+		}
+	}
+
+	return zone, nil
+}
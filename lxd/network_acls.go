@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkACLsCmd = APIEndpoint{
+	Path: "network-acls",
+
+	Get:  APIEndpointAction{Handler: networkACLsGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: networkACLsPost},
+}
+
+var networkACLCmd = APIEndpoint{
+	Path: "network-acls/{name}",
+
+	Delete: APIEndpointAction{Handler: networkACLDelete},
+	Get:    APIEndpointAction{Handler: networkACLGet, AccessHandler: allowAuthenticated},
+	Patch:  APIEndpointAction{Handler: networkACLPatch},
+	Post:   APIEndpointAction{Handler: networkACLPost},
+	Put:    APIEndpointAction{Handler: networkACLPut},
+}
+
+// API endpoints
+func networkACLsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	names, err := d.cluster.GetNetworkACLs()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkACL{}
+	for _, name := range names {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, name))
+		} else {
+			acl, err := doNetworkACLGet(d, name)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *acl)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+func networkACLsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.NetworkACLsPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	err = network.ValidACLName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = network.ValidateACL(&req.NetworkACLPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.GetNetworkACL(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("Network ACL %q already exists", req.Name))
+	} else if err != db.ErrNoSuchObject {
+		return response.SmartError(err)
+	}
+
+	// Create the database entry. ACLs don't have any local state until assigned, so there is nothing to
+	// apply on the cluster members yet.
+	_, err = d.cluster.CreateNetworkACL(&req)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Error inserting %q into database: %v", req.Name, err))
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name))
+}
+
+func networkACLGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	acl, err := doNetworkACLGet(d, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{acl.Name, acl.Description, acl.Ingress, acl.Egress, acl.Config}
+
+	return response.SyncResponseETag(true, acl, etag)
+}
+
+func doNetworkACLGet(d *Daemon, name string) (*api.NetworkACL, error) {
+	_, acl, err := d.cluster.GetNetworkACL(name)
+	if err != nil {
+		return nil, err
+	}
+
+	acl.UsedBy, err = network.ACLUsedBy(d.State(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return acl, nil
+}
+
+func networkACLPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, acl, err := d.cluster.GetNetworkACL(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{acl.Name, acl.Description, acl.Ingress, acl.Egress, acl.Config}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkACLPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doNetworkACLUpdate(d, id, name, req, isClusterNotification(r))
+}
+
+func networkACLPatch(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, acl, err := d.cluster.GetNetworkACL(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{acl.Name, acl.Description, acl.Ingress, acl.Egress, acl.Config}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current ACL so that omitted fields are left untouched.
+	req := acl.Writable()
+	req.Config = nil
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Config stacking
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for k, v := range acl.Config {
+		_, ok := req.Config[k]
+		if !ok {
+			req.Config[k] = v
+		}
+	}
+
+	return doNetworkACLUpdate(d, id, name, req, isClusterNotification(r))
+}
+
+func doNetworkACLUpdate(d *Daemon, id int64, name string, req api.NetworkACLPut, clusterNotification bool) response.Response {
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err := network.ValidateACL(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Only the member serving the request updates the database and notifies the other members, which then
+	// just re-apply the rules locally.
+	if !clusterNotification {
+		err = d.cluster.UpdateNetworkACL(id, &req)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(client lxd.InstanceServer) error {
+			return client.UpdateNetworkACL(name, req, "")
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	err = network.ACLRefresh(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func networkACLPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	req := api.NetworkACLPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	id, _, err := d.cluster.GetNetworkACL(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Sanity checks
+	err = network.ValidACLName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.GetNetworkACL(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("Network ACL %q already exists", req.Name))
+	} else if err != db.ErrNoSuchObject {
+		return response.SmartError(err)
+	}
+
+	// References to ACLs are stored by name, so only unused ACLs can be renamed.
+	usedBy, err := network.ACLUsedBy(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return response.BadRequest(fmt.Errorf("Network ACL %q is currently in use", name))
+	}
+
+	err = d.cluster.RenameNetworkACL(id, req.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name))
+}
+
+func networkACLDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, _, err := d.cluster.GetNetworkACL(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	usedBy, err := network.ACLUsedBy(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return response.BadRequest(fmt.Errorf("Network ACL %q is currently in use", name))
+	}
+
+	err = d.cluster.DeleteNetworkACL(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkForwardsCmd = APIEndpoint{
+	Path: "networks/{name}/forwards",
+
+	Get:  APIEndpointAction{Handler: networkForwardsGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: networkForwardsPost},
+}
+
+var networkForwardCmd = APIEndpoint{
+	Path: "networks/{name}/forwards/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkForwardDelete},
+	Get:    APIEndpointAction{Handler: networkForwardGet, AccessHandler: allowAuthenticated},
+	Patch:  APIEndpointAction{Handler: networkForwardPatch},
+	Put:    APIEndpointAction{Handler: networkForwardPut},
+}
+
+// API endpoints
+func networkForwardsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	networkID, _, err := d.cluster.GetNetwork(networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	forwards, err := d.cluster.GetNetworkForwards(networkID, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		resultString := []string{}
+		for _, forward := range forwards {
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, name, forward.ListenAddress))
+		}
+
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, forwards)
+}
+
+func networkForwardsPost(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, the forward is applied on that member.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkForwardsPost{}
+
+	// Parse the request
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	listenAddress := net.ParseIP(req.ListenAddress)
+	if listenAddress == nil {
+		return response.BadRequest(fmt.Errorf("Invalid listen address %q", req.ListenAddress))
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// A listen address can only be used by a single forward or load balancer across the cluster, so that
+	// requests for it can be routed to the member applying it.
+	resp = networkCheckListenAddressUnused(d, listenAddress)
+	if resp != nil {
+		return resp
+	}
+
+	err = n.ForwardCreate(req)
+	if err == network.ErrNotImplemented {
+		return response.BadRequest(fmt.Errorf("Network type %q doesn't support address forwards", n.Type()))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, name, listenAddress.String()))
+}
+
+func networkForwardGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	forward, err := doNetworkForwardGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{forward.ListenAddress, forward.Description, forward.Config, forward.Ports}
+
+	return response.SyncResponseETag(true, forward, etag)
+}
+
+// doNetworkForwardGet returns the address forward of the network for the listen address.
+func doNetworkForwardGet(d *Daemon, projectName string, name string, listenAddress string) (*api.NetworkForward, error) {
+	networkID, _, err := d.cluster.GetNetwork(projectName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listen addresses are stored in their canonical form.
+	ip := net.ParseIP(listenAddress)
+	if ip == nil {
+		return nil, db.ErrNoSuchObject
+	}
+
+	_, forward, err := d.cluster.GetNetworkForward(networkID, ip.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return forward, nil
+}
+
+func networkForwardPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	forward, err := doNetworkForwardGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The forward can only be changed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, forward.Location)
+	if resp != nil {
+		return resp
+	}
+
+	// Validate the ETag
+	etag := []interface{}{forward.ListenAddress, forward.Description, forward.Config, forward.Ports}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkForwardPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doNetworkForwardUpdate(d, networkProject, name, forward.ListenAddress, req)
+}
+
+func networkForwardPatch(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	forward, err := doNetworkForwardGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The forward can only be changed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, forward.Location)
+	if resp != nil {
+		return resp
+	}
+
+	// Validate the ETag
+	etag := []interface{}{forward.ListenAddress, forward.Description, forward.Config, forward.Ports}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current forward so that omitted fields are left untouched.
+	req := forward.Writable()
+	req.Config = nil
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Config stacking
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for k, v := range forward.Config {
+		_, ok := req.Config[k]
+		if !ok {
+			req.Config[k] = v
+		}
+	}
+
+	return doNetworkForwardUpdate(d, networkProject, name, forward.ListenAddress, req)
+}
+
+func doNetworkForwardUpdate(d *Daemon, projectName string, name string, listenAddress string, req api.NetworkForwardPut) response.Response {
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.ForwardUpdate(listenAddress, req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func networkForwardDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	forward, err := doNetworkForwardGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The forward can only be removed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, forward.Location)
+	if resp != nil {
+		return resp
+	}
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.ForwardDelete(forward.ListenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkLoadBalancersCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers",
+
+	Get:  APIEndpointAction{Handler: networkLoadBalancersGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: networkLoadBalancersPost},
+}
+
+var networkLoadBalancerCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkLoadBalancerDelete},
+	Get:    APIEndpointAction{Handler: networkLoadBalancerGet, AccessHandler: allowAuthenticated},
+	Patch:  APIEndpointAction{Handler: networkLoadBalancerPatch},
+	Put:    APIEndpointAction{Handler: networkLoadBalancerPut},
+}
+
+var networkLoadBalancerStateCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers/{listenAddress}/state",
+
+	Get: APIEndpointAction{Handler: networkLoadBalancerStateGet, AccessHandler: allowAuthenticated},
+}
+
+// API endpoints
+func networkLoadBalancersGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	networkID, _, err := d.cluster.GetNetwork(networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	loadBalancers, err := d.cluster.GetNetworkLoadBalancers(networkID, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		resultString := []string{}
+		for _, lb := range loadBalancers {
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, name, lb.ListenAddress))
+		}
+
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, loadBalancers)
+}
+
+func networkLoadBalancersPost(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, the load balancer is applied on that member.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkLoadBalancersPost{}
+
+	// Parse the request
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	listenAddress := net.ParseIP(req.ListenAddress)
+	if listenAddress == nil {
+		return response.BadRequest(fmt.Errorf("Invalid listen address %q", req.ListenAddress))
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// A listen address can only be used by a single forward or load balancer across the cluster, so that
+	// requests for it can be routed to the member applying it.
+	resp = networkCheckListenAddressUnused(d, listenAddress)
+	if resp != nil {
+		return resp
+	}
+
+	err = n.LoadBalancerCreate(req)
+	if err == network.ErrNotImplemented {
+		return response.BadRequest(fmt.Errorf("Network type %q doesn't support load balancers", n.Type()))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, name, listenAddress.String()))
+}
+
+func networkLoadBalancerGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lb, err := doNetworkLoadBalancerGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{lb.ListenAddress, lb.Description, lb.Config, lb.Backends, lb.Ports}
+
+	return response.SyncResponseETag(true, lb, etag)
+}
+
+// doNetworkLoadBalancerGet returns the load balancer of the network for the listen address.
+func doNetworkLoadBalancerGet(d *Daemon, projectName string, name string, listenAddress string) (*api.NetworkLoadBalancer, error) {
+	networkID, _, err := d.cluster.GetNetwork(projectName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listen addresses are stored in their canonical form.
+	ip := net.ParseIP(listenAddress)
+	if ip == nil {
+		return nil, db.ErrNoSuchObject
+	}
+
+	_, lb, err := d.cluster.GetNetworkLoadBalancer(networkID, ip.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return lb, nil
+}
+
+func networkLoadBalancerStateGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lb, err := doNetworkLoadBalancerGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The backend health is only known to the member the load balancer is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, lb.Location)
+	if resp != nil {
+		return resp
+	}
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	state, err := n.LoadBalancerState(lb.ListenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, state)
+}
+
+func networkLoadBalancerPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lb, err := doNetworkLoadBalancerGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The load balancer can only be changed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, lb.Location)
+	if resp != nil {
+		return resp
+	}
+
+	// Validate the ETag
+	etag := []interface{}{lb.ListenAddress, lb.Description, lb.Config, lb.Backends, lb.Ports}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkLoadBalancerPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doNetworkLoadBalancerUpdate(d, networkProject, name, lb.ListenAddress, req)
+}
+
+func networkLoadBalancerPatch(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lb, err := doNetworkLoadBalancerGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The load balancer can only be changed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, lb.Location)
+	if resp != nil {
+		return resp
+	}
+
+	// Validate the ETag
+	etag := []interface{}{lb.ListenAddress, lb.Description, lb.Config, lb.Backends, lb.Ports}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current load balancer so that omitted fields are left untouched.
+	req := lb.Writable()
+	req.Config = nil
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Config stacking
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for k, v := range lb.Config {
+		_, ok := req.Config[k]
+		if !ok {
+			req.Config[k] = v
+		}
+	}
+
+	return doNetworkLoadBalancerUpdate(d, networkProject, name, lb.ListenAddress, req)
+}
+
+func doNetworkLoadBalancerUpdate(d *Daemon, projectName string, name string, listenAddress string, req api.NetworkLoadBalancerPut) response.Response {
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.LoadBalancerUpdate(listenAddress, req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func networkLoadBalancerDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lb, err := doNetworkLoadBalancerGet(d, networkProject, name, mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The load balancer can only be removed on the member it is applied on.
+	resp := forwardedResponseIfNetworkForwardIsRemote(d, r, lb.Location)
+	if resp != nil {
+		return resp
+	}
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.LoadBalancerDelete(lb.ListenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// networkCheckListenAddressUnused returns a Conflict response if the listen address is already used by an
+// address forward or a load balancer anywhere in the cluster, or nil if it is free.
+func networkCheckListenAddressUnused(d *Daemon, listenAddress net.IP) response.Response {
+	forwardAddresses, err := d.cluster.GetNetworkForwardListenAddresses()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	location, ok := forwardAddresses[listenAddress.String()]
+	if ok {
+		return response.Conflict(fmt.Errorf("Listen address %q is already used by a forward on cluster member %q", listenAddress.String(), location))
+	}
+
+	lbAddresses, err := d.cluster.GetNetworkLoadBalancerListenAddresses()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	location, ok = lbAddresses[listenAddress.String()]
+	if ok {
+		return response.Conflict(fmt.Errorf("Listen address %q is already used by a load balancer on cluster member %q", listenAddress.String(), location))
+	}
+
+	return nil
+}
+
+func networkLoadBalancerHealthCheckTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := network.LoadBalancersHealthCheck(d.State())
+		if err != nil {
+			logger.Error("Failed health checking network load balancers", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
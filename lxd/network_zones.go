@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkZonesCmd = APIEndpoint{
+	Path: "network-zones",
+
+	Get:  APIEndpointAction{Handler: networkZonesGet, AccessHandler: allowProjectPermission("networks", "view")},
+	Post: APIEndpointAction{Handler: networkZonesPost},
+}
+
+var networkZoneCmd = APIEndpoint{
+	Path: "network-zones/{name}",
+
+	Delete: APIEndpointAction{Handler: networkZoneDelete},
+	Get:    APIEndpointAction{Handler: networkZoneGet, AccessHandler: allowProjectPermission("networks", "view")},
+	Patch:  APIEndpointAction{Handler: networkZonePatch},
+	Put:    APIEndpointAction{Handler: networkZonePut},
+}
+
+// API endpoints
+func networkZonesGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+	projectName := projectParam(r)
+
+	names, err := d.cluster.GetNetworkZones(projectName)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkZone{}
+	for _, name := range names {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/network-zones/%s", version.APIVersion, name))
+		} else {
+			_, zone, err := doNetworkZoneGet(d, projectName, name)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *zone)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+func networkZonesPost(d *Daemon, r *http.Request) response.Response {
+	projectName := projectParam(r)
+	req := api.NetworkZonesPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	err = network.ValidZoneName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = network.ValidateZone(&req.NetworkZonePut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Zone names are unique across projects as the DNS server serves all of them.
+	_, _, _, err = d.cluster.GetNetworkZone(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("Network zone %q already exists", req.Name))
+	} else if err != db.ErrNoSuchObject {
+		return response.SmartError(err)
+	}
+
+	_, err = d.cluster.CreateNetworkZone(projectName, &req)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Error inserting %q into database: %v", req.Name, err))
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/network-zones/%s", version.APIVersion, req.Name))
+}
+
+func networkZoneGet(d *Daemon, r *http.Request) response.Response {
+	_, zone, err := doNetworkZoneGet(d, projectParam(r), mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{zone.Name, zone.Description, zone.Config}
+
+	return response.SyncResponseETag(true, zone, etag)
+}
+
+// doNetworkZoneGet returns the network zone with the given name if it belongs to the project.
+func doNetworkZoneGet(d *Daemon, projectName string, name string) (int64, *api.NetworkZone, error) {
+	id, zoneProject, zone, err := d.cluster.GetNetworkZone(name)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	if zoneProject != projectName {
+		return -1, nil, db.ErrNoSuchObject
+	}
+
+	zone.UsedBy, err = network.ZoneUsedBy(d.State(), name)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, zone, nil
+}
+
+func networkZonePut(d *Daemon, r *http.Request) response.Response {
+	id, zone, err := doNetworkZoneGet(d, projectParam(r), mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{zone.Name, zone.Description, zone.Config}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkZonePut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return doNetworkZoneUpdate(d, id, req)
+}
+
+func networkZonePatch(d *Daemon, r *http.Request) response.Response {
+	id, zone, err := doNetworkZoneGet(d, projectParam(r), mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{zone.Name, zone.Description, zone.Config}
+
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Start from the current zone so that omitted fields are left untouched.
+	req := zone.Writable()
+	req.Config = nil
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Config stacking
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for k, v := range zone.Config {
+		_, ok := req.Config[k]
+		if !ok {
+			req.Config[k] = v
+		}
+	}
+
+	return doNetworkZoneUpdate(d, id, req)
+}
+
+func doNetworkZoneUpdate(d *Daemon, id int64, req api.NetworkZonePut) response.Response {
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err := network.ValidateZone(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// The zone content is generated on each query, so there is nothing to refresh.
+	err = d.cluster.UpdateNetworkZone(id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func networkZoneDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, zone, err := doNetworkZoneGet(d, projectParam(r), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(zone.UsedBy) > 0 {
+		return response.BadRequest(fmt.Errorf("Network zone %q is currently in use", name))
+	}
+
+	err = d.cluster.DeleteNetworkZone(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
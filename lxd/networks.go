@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -52,7 +51,14 @@ var networkCmd = APIEndpoint{
 var networkLeasesCmd = APIEndpoint{
 	Path: "networks/{name}/leases",
 
-	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowAuthenticated},
+	Get:  APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowProjectPermission("networks", "view")},
+	Post: APIEndpointAction{Handler: networkLeasesPost},
+}
+
+var networkLeaseCmd = APIEndpoint{
+	Path: "networks/{name}/leases/{address}",
+
+	Delete: APIEndpointAction{Handler: networkLeaseDelete},
 }
 
 var networkStateCmd = APIEndpoint{
@@ -70,16 +76,42 @@ func networksGet(d *Daemon, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
+	// Only list the networks which can be seen from the project.
+	projectName := projectParam(r)
+	visibleIfs := []string{}
+	ifProjects := map[string]string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, iface := range ifs {
+			networkProject, err := networkProjectVisible(tx, projectName, iface)
+			if err == db.ErrNoSuchObject {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			visibleIfs = append(visibleIfs, iface)
+			ifProjects[iface] = networkProject
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resultString := []string{}
 	resultMap := []api.Network{}
-	for _, iface := range ifs {
+	for _, iface := range visibleIfs {
 		if !recursion {
 			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, iface))
 		} else {
-			net, err := doNetworkGet(d, iface)
+			net, err := doNetworkGet(d, ifProjects[iface], iface)
 			if err != nil {
 				continue
 			}
+			net.UsedBy = filterUsedBy(d, r, net.UsedBy)
 			resultMap = append(resultMap, net)
 		}
 	}
@@ -95,6 +127,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	networkCreateLock.Lock()
 	defer networkCreateLock.Unlock()
 
+	projectName := projectParam(r)
 	req := api.NetworksPost{}
 
 	// Parse the request
@@ -126,6 +159,10 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	switch req.Type {
 	case "bridge":
 		dbNetType = db.NetworkTypeBridge
+	case "ovn":
+		dbNetType = db.NetworkTypeOVN
+	case "physical":
+		dbNetType = db.NetworkTypePhysical
 	default:
 		return response.BadRequest(fmt.Errorf("Unrecognised network type"))
 	}
@@ -136,7 +173,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	if isClusterNotification(r) {
 		// This is an internal request which triggers the actual creation of the network across all nodes
 		// after they have been previously defined.
-		err = doNetworksCreate(d, req, true)
+		err = doNetworksCreate(d, projectName, req, true)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -154,7 +191,20 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			return tx.CreatePendingNetwork(targetNode, req.Name, dbNetType, req.Config)
+			// Only check the name when the network is first defined on a member.
+			_, err := tx.GetNetworkID(projectName, req.Name)
+			if err != db.ErrNoSuchObject {
+				return err
+			}
+
+			return networkCheckNameAvailable(tx, projectName, req.Name, req.Type)
+		})
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.CreatePendingNetwork(targetNode, projectName, req.Name, dbNetType, req.Config)
 		})
 		if err != nil {
 			if err == db.ErrAlreadyDefined {
@@ -172,7 +222,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if count > 1 {
-		err = networksPostCluster(d, req)
+		err = networksPostCluster(d, projectName, req)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -187,23 +237,28 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	networks, err := networkGetInterfaces(d.cluster)
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return networkCheckNameAvailable(tx, projectName, req.Name, req.Type)
+	})
 	if err != nil {
-		return response.InternalError(err)
+		return response.BadRequest(err)
 	}
 
-	if shared.StringInSlice(req.Name, networks) {
-		return response.BadRequest(fmt.Errorf("The network already exists"))
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return project.AllowNetworkCreation(tx, projectName, req.Name, req.Config)
+	})
+	if err != nil {
+		return response.SmartError(err)
 	}
 
 	// Create the database entry.
-	_, err = d.cluster.CreateNetwork(req.Name, req.Description, dbNetType, req.Config)
+	_, err = d.cluster.CreateNetwork(projectName, req.Name, req.Description, dbNetType, req.Config)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
 	}
 
 	// Create network and pass false to clusterNotification so the database record is removed on error.
-	err = doNetworksCreate(d, req, false)
+	err = doNetworksCreate(d, projectName, req, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -211,7 +266,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func networksPostCluster(d *Daemon, req api.NetworksPost) error {
+func networksPostCluster(d *Daemon, projectName string, req api.NetworksPost) error {
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.NodeSpecificNetworkConfig) {
@@ -232,7 +287,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	var networkID int64
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		// Fetch the network ID.
-		networkID, err = tx.GetNetworkID(req.Name)
+		networkID, err = tx.GetNetworkID(projectName, req.Name)
 		if err != nil {
 			return err
 		}
@@ -249,6 +304,11 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 			return err
 		}
 
+		err = project.AllowNetworkCreation(tx, projectName, req.Name, req.Config)
+		if err != nil {
+			return err
+		}
+
 		// Insert the global config keys.
 		return tx.CreateNetworkConfig(networkID, 0, req.Config)
 	})
@@ -275,13 +335,13 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	// network.LoadByName call invoked by doNetworksCreate would fail with
 	// not-found otherwise.
 	createErr := d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		return tx.NetworkCreated(req.Name)
+		return tx.NetworkCreated(projectName, req.Name)
 	})
 	if createErr != nil {
 		goto error
 	}
 
-	err = doNetworksCreate(d, nodeReq, false)
+	err = doNetworksCreate(d, projectName, nodeReq, false)
 	if err != nil {
 		return err
 	}
@@ -297,7 +357,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 			nodeReq.Config[key] = value
 		}
 
-		return client.CreateNetwork(nodeReq)
+		return client.UseProject(projectName).CreateNetwork(nodeReq)
 	})
 	if createErr != nil {
 		goto error
@@ -307,7 +367,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 
 error:
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		return tx.NetworkErrored(req.Name)
+		return tx.NetworkErrored(projectName, req.Name)
 	})
 	if err != nil {
 		return err
@@ -318,9 +378,9 @@ error:
 
 // Create the network on the system. The clusterNotification flag is used to indicate whether creation request
 // is coming from a cluster notification (and if so we should not delete the database record on error).
-func doNetworksCreate(d *Daemon, req api.NetworksPost, clusterNotification bool) error {
+func doNetworksCreate(d *Daemon, projectName string, req api.NetworksPost, clusterNotification bool) error {
 	// Start the network.
-	n, err := network.LoadByName(d.State(), req.Name)
+	n, err := network.LoadByName(d.State(), projectName, req.Name)
 	if err != nil {
 		return err
 	}
@@ -343,11 +403,17 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 
 	name := mux.Vars(r)["name"]
 
-	n, err := doNetworkGet(d, name)
+	networkProject, err := networkProjectFromRequest(d, r, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	n, err := doNetworkGet(d, networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	n.UsedBy = filterUsedBy(d, r, n.UsedBy)
+
 	targetNode := queryParam(r, "target")
 	clustered, err := cluster.Enabled(d.db)
 	if err != nil {
@@ -367,7 +433,89 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, &n, etag)
 }
 
-func doNetworkGet(d *Daemon, name string) (api.Network, error) {
+// networkProjectFromRequest returns the project owning the network with the given name as seen from the project
+// of the request, or an empty string for an unmanaged host interface. A not found error is returned if the network
+// can't be seen from the project. Internal cluster notifications always target the project owning the network.
+func networkProjectFromRequest(d *Daemon, r *http.Request, name string) (string, error) {
+	if isClusterNotification(r) {
+		return projectParam(r), nil
+	}
+
+	var networkProject string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		networkProject, err = networkProjectVisible(tx, projectParam(r), name)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return networkProject, nil
+}
+
+// networkProjectVisible returns the project owning the managed network with the given name as seen from the
+// project, or an empty string if the name can only refer to an unmanaged host interface visible from it.
+func networkProjectVisible(tx *db.ClusterTx, projectName string, name string) (string, error) {
+	networkProject, err := project.NetworkProject(tx, projectName, name)
+	if err != db.ErrNoSuchObject {
+		return networkProject, err
+	}
+
+	visible, err := project.NetworkVisible(tx, projectName, name)
+	if err != nil {
+		return "", err
+	}
+
+	if !visible {
+		return "", db.ErrNoSuchObject
+	}
+
+	return "", nil
+}
+
+// networkCheckNameAvailable returns an error if a network of the given type can't be created with the given name
+// in the project. Bridge and physical networks own the host interface of the same name, so their names must be
+// unique across all projects, while OVN networks only need a unique name within the networks seen by the project.
+func networkCheckNameAvailable(tx *db.ClusterTx, projectName string, name string, netType string) error {
+	hostBacked := shared.StringInSlice(netType, []string{"bridge", "physical"})
+
+	networkProject, err := networkProjectVisible(tx, projectName, name)
+	if err != nil && err != db.ErrNoSuchObject {
+		return err
+	}
+
+	// An unmanaged name is only taken if the host interface exists.
+	_, ifaceErr := net.InterfaceByName(name)
+	if err == nil && (networkProject != "" || ifaceErr == nil) {
+		return fmt.Errorf("The network already exists")
+	}
+
+	if hostBacked && ifaceErr == nil {
+		return fmt.Errorf("A host interface named %q already exists", name)
+	}
+
+	networkTypes, err := tx.GetNetworkTypes(name)
+	if err != nil {
+		return err
+	}
+
+	for otherProject, otherType := range networkTypes {
+		if otherProject == projectName {
+			return fmt.Errorf("The network already exists")
+		}
+
+		if hostBacked || otherType == db.NetworkTypeBridge || otherType == db.NetworkTypePhysical {
+			return fmt.Errorf("Network name %q is already used in project %q", name, otherProject)
+		}
+	}
+
+	return nil
+}
+
+// doNetworkGet returns the network with the given name owned by the project, or the unmanaged host interface with
+// that name if the project is empty.
+func doNetworkGet(d *Daemon, projectName string, name string) (api.Network, error) {
 	// Ignore veth pairs (for performance reasons)
 	if strings.HasPrefix(name, "veth") {
 		return api.Network{}, os.ErrNotExist
@@ -375,7 +523,11 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 
 	// Get some information
 	osInfo, _ := net.InterfaceByName(name)
-	_, dbInfo, _ := d.cluster.GetNetworkInAnyState(name)
+
+	var dbInfo *api.Network
+	if projectName != "" {
+		_, dbInfo, _ = d.cluster.GetNetworkInAnyState(projectName, name)
+	}
 
 	// Sanity check
 	if osInfo == nil && dbInfo == nil {
@@ -415,6 +567,33 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 
 	// Look for containers using the interface
 	if n.Type != "loopback" {
+		// Only the projects seeing this network can use it, as other projects may have their own with this name.
+		usable := map[string]bool{}
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			projects, err := tx.GetProjectNames()
+			if err != nil {
+				return err
+			}
+
+			for _, p := range projects {
+				networkProject, err := networkProjectVisible(tx, p, name)
+				if err == db.ErrNoSuchObject {
+					continue
+				}
+
+				if err != nil {
+					return err
+				}
+
+				usable[p] = networkProject == projectName
+			}
+
+			return nil
+		})
+		if err != nil {
+			return api.Network{}, err
+		}
+
 		// Look at instances.
 		insts, err := instance.LoadFromAllProjects(d.State())
 		if err != nil {
@@ -422,7 +601,7 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 		}
 
 		for _, inst := range insts {
-			if network.IsInUseByInstance(inst, n.Name) {
+			if usable[inst.Project()] && network.IsInUseByInstance(inst, n.Name) {
 				uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, inst.Name())
 				if inst.Project() != project.Default {
 					uri += fmt.Sprintf("?project=%s", inst.Project())
@@ -446,7 +625,7 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 		}
 
 		for _, profile := range profiles {
-			if network.IsInUseByProfile(*db.ProfileToAPI(&profile), n.Name) {
+			if usable[profile.Project] && network.IsInUseByProfile(*db.ProfileToAPI(&profile), n.Name) {
 				uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, profile.Name)
 				if profile.Project != project.Default {
 					uri += fmt.Sprintf("?project=%s", profile.Project)
@@ -466,16 +645,21 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 
 func networkDelete(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
 	state := d.State()
 
 	// Check if the network is pending, if so we just need to delete it from
 	// the database.
-	_, dbNetwork, err := d.cluster.GetNetworkInAnyState(name)
+	_, dbNetwork, err := d.cluster.GetNetworkInAnyState(networkProject, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 	if dbNetwork.Status == "Pending" {
-		err := d.cluster.DeleteNetwork(name)
+		err := d.cluster.DeleteNetwork(networkProject, name)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -483,7 +667,7 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get the existing network
-	n, err := network.LoadByName(state, name)
+	n, err := network.LoadByName(state, networkProject, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -503,7 +687,7 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 		err = notifier(func(client lxd.InstanceServer) error {
-			return client.DeleteNetwork(name)
+			return client.UseProject(networkProject).DeleteNetwork(name)
 		})
 		if err != nil {
 			return response.SmartError(err)
@@ -516,8 +700,8 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	// Cleanup storage
-	if shared.PathExists(shared.VarPath("networks", n.Name())) {
+	// Cleanup storage, only host backed networks have a directory of their own.
+	if n.Type() != "ovn" && shared.PathExists(shared.VarPath("networks", n.Name())) {
 		os.RemoveAll(shared.VarPath("networks", n.Name()))
 	}
 
@@ -541,6 +725,12 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	req := api.NetworkPost{}
 	state := d.State()
 
@@ -551,7 +741,7 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get the existing network
-	n, err := network.LoadByName(state, name)
+	n, err := network.LoadByName(state, networkProject, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -567,13 +757,11 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Check that the name isn't already in use
-	networks, err := networkGetInterfaces(d.cluster)
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return networkCheckNameAvailable(tx, networkProject, req.Name, n.Type())
+	})
 	if err != nil {
-		return response.InternalError(err)
-	}
-
-	if shared.StringInSlice(req.Name, networks) {
-		return response.Conflict(fmt.Errorf("Network %q already exists", req.Name))
+		return response.Conflict(err)
 	}
 
 	// Rename it
@@ -588,8 +776,13 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 func networkPut(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the existing network
-	_, dbInfo, err := d.cluster.GetNetworkInAnyState(name)
+	_, dbInfo, err := d.cluster.GetNetworkInAnyState(networkProject, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -621,14 +814,19 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r))
+	return doNetworkUpdate(d, networkProject, name, dbInfo.Config, req, isClusterNotification(r))
 }
 
 func networkPatch(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the existing network
-	_, dbInfo, err := d.cluster.GetNetworkInAnyState(name)
+	_, dbInfo, err := d.cluster.GetNetworkInAnyState(networkProject, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -672,12 +870,12 @@ func networkPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r))
+	return doNetworkUpdate(d, networkProject, name, dbInfo.Config, req, isClusterNotification(r))
 }
 
-func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req api.NetworkPut, clusterNotification bool) response.Response {
+func doNetworkUpdate(d *Daemon, projectName string, name string, oldConfig map[string]string, req api.NetworkPut, clusterNotification bool) response.Response {
 	// Load the network
-	n, err := network.LoadByName(d.State(), name)
+	n, err := network.LoadByName(d.State(), projectName, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -688,6 +886,15 @@ func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req ap
 		return response.BadRequest(err)
 	}
 
+	if !clusterNotification {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return project.AllowNetworkUpdate(tx, projectName, name, req.Config)
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	err = n.Update(req, clusterNotification)
 	if err != nil {
 		return response.SmartError(err)
@@ -698,10 +905,15 @@ func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req ap
 
 func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
 	project := projectParam(r)
 
 	// Try to get the network
-	n, err := doNetworkGet(d, name)
+	n, err := doNetworkGet(d, networkProject, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -711,133 +923,183 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 		return response.NotFound(errors.New("Leases not found"))
 	}
 
-	leases := []api.NetworkLease{}
-	projectMacs := []string{}
+	leases, err := networkLeases(d, name, project, isClusterNotification(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
 
-	// Get all static leases
+	return response.SyncResponse(true, leases)
+}
+
+func networkLeasesPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	projectName := projectParam(r)
+
+	n, err := network.LoadByName(d.State(), networkProject, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if n.Type() != "bridge" {
+		return response.BadRequest(fmt.Errorf("Network type %q doesn't support lease reservations", n.Type()))
+	}
+
+	// Only the member serving the request updates the database, the others just refresh dnsmasq.
 	if !isClusterNotification(r) {
-		// Get all the instances
-		instances, err := instance.LoadByProject(d.State(), project)
+		req := api.NetworkLeasesPost{}
+		err = json.NewDecoder(r.Body).Decode(&req)
 		if err != nil {
-			return response.SmartError(err)
+			return response.BadRequest(err)
 		}
 
-		for _, inst := range instances {
-			// Go through all its devices (including profiles
-			for k, d := range inst.ExpandedDevices() {
-				// Skip uninteresting entries
-				if d["type"] != "nic" || d.NICType() != "bridged" {
-					continue
-				}
+		err = network.ValidateLeaseReservation(d.State(), n, projectName, &req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
 
-				// Temporarily populate parent from network setting if used.
-				if d["network"] != "" {
-					d["parent"] = d["network"]
-				}
+		_, err = d.cluster.CreateNetworkLeaseReservation(n.ID(), projectName, &req)
+		if err != nil {
+			return response.SmartError(err)
+		}
 
-				if d["parent"] != name {
-					continue
-				}
+		err = notifyNetworkLeasesChange(d, func(client lxd.InstanceServer) error {
+			return client.UseProject(networkProject).CreateNetworkLease(name, req)
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
 
-				// Fill in the hwaddr from volatile
-				if d["hwaddr"] == "" {
-					d["hwaddr"] = inst.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", k)]
-				}
+	err = network.UpdateDNSMasqStatic(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
 
-				// Record the MAC
-				if d["hwaddr"] != "" {
-					projectMacs = append(projectMacs, d["hwaddr"])
-				}
+	return response.EmptySyncResponse
+}
 
-				// Add the lease
-				if d["ipv4.address"] != "" {
-					leases = append(leases, api.NetworkLease{
-						Hostname: inst.Name(),
-						Address:  d["ipv4.address"],
-						Hwaddr:   d["hwaddr"],
-						Type:     "static",
-						Location: inst.Location(),
-					})
-				}
+func networkLeaseDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
 
-				if d["ipv6.address"] != "" {
-					leases = append(leases, api.NetworkLease{
-						Hostname: inst.Name(),
-						Address:  d["ipv6.address"],
-						Hwaddr:   d["hwaddr"],
-						Type:     "static",
-						Location: inst.Location(),
-					})
-				}
-			}
-		}
+	networkProject, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
-	// Local server name
-	var serverName string
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		serverName, err = tx.GetLocalNodeName()
-		return err
-	})
+	n, err := network.LoadByName(d.State(), networkProject, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Get dynamic leases
-	leaseFile := shared.VarPath("networks", name, "dnsmasq.leases")
-	if !shared.PathExists(leaseFile) {
-		return response.SyncResponse(true, leases)
+	// Only the member serving the request updates the database, the others just refresh dnsmasq.
+	if !isClusterNotification(r) {
+		// Addresses are stored in their canonical form.
+		ip := net.ParseIP(mux.Vars(r)["address"])
+		if ip == nil {
+			return response.NotFound(nil)
+		}
+
+		id, leaseProject, _, err := d.cluster.GetNetworkLeaseReservation(n.ID(), ip.String())
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if leaseProject != projectParam(r) {
+			return response.NotFound(nil)
+		}
+
+		err = d.cluster.DeleteNetworkLeaseReservation(id)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifyNetworkLeasesChange(d, func(client lxd.InstanceServer) error {
+			return client.UseProject(networkProject).DeleteNetworkLease(name, ip.String())
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
 	}
 
-	content, err := ioutil.ReadFile(leaseFile)
+	err = network.UpdateDNSMasqStatic(d.State(), name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	for _, lease := range strings.Split(string(content), "\n") {
-		fields := strings.Fields(lease)
-		if len(fields) >= 5 {
-			// Parse the MAC
-			mac := network.GetMACSlice(fields[1])
-			macStr := strings.Join(mac, ":")
+	return response.EmptySyncResponse
+}
 
-			if len(macStr) < 17 && fields[4] != "" {
-				macStr = fields[4][len(fields[4])-17:]
-			}
+// notifyNetworkLeasesChange forwards a change of the lease reservations to the other cluster members.
+func notifyNetworkLeasesChange(d *Daemon, hook func(client lxd.InstanceServer) error) error {
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return err
+	}
 
-			// Look for an existing static entry
-			found := false
-			for _, entry := range leases {
-				if entry.Hwaddr == macStr && entry.Address == fields[2] {
-					found = true
-					break
-				}
-			}
+	return notifier(hook)
+}
 
-			if found {
-				continue
+// networkLeases returns the leases of the instances of the project on the network. Unless clusterNotification is
+// set, the dynamic leases of the other cluster members are included too.
+func networkLeases(d *Daemon, name string, project string, clusterNotification bool) ([]api.NetworkLease, error) {
+	leases := []api.NetworkLease{}
+	projectMacs := []string{}
+
+	// Get all static leases
+	if !clusterNotification {
+		var err error
+		leases, projectMacs, err = network.StaticLeases(d.State(), name, project)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Get dynamic leases
+	dynamicLeases, err := network.DynamicLeases(d.State(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lease := range dynamicLeases {
+		// Look for an existing static entry
+		found := false
+		for _, entry := range leases {
+			if entry.Hwaddr == lease.Hwaddr && entry.Address == lease.Address {
+				found = true
+				break
 			}
+		}
 
-			// Add the lease to the list
-			leases = append(leases, api.NetworkLease{
-				Hostname: fields[3],
-				Address:  fields[2],
-				Hwaddr:   macStr,
-				Type:     "dynamic",
-				Location: serverName,
-			})
+		if found {
+			continue
 		}
+
+		leases = append(leases, lease)
 	}
 
 	// Collect leases from other servers
-	if !isClusterNotification(r) {
+	if !clusterNotification {
+		var networkProject string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			networkProject, err = networkProjectVisible(tx, project, name)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
 		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
 		if err != nil {
-			return response.SmartError(err)
+			return nil, err
 		}
 
 		err = notifier(func(client lxd.InstanceServer) error {
-			memberLeases, err := client.GetNetworkLeases(name)
+			memberLeases, err := client.UseProject(networkProject).GetNetworkLeases(name)
 			if err != nil {
 				return err
 			}
@@ -846,7 +1108,7 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 			return nil
 		})
 		if err != nil {
-			return response.SmartError(err)
+			return nil, err
 		}
 
 		// Filter based on project
@@ -862,7 +1124,7 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 		leases = filteredLeases
 	}
 
-	return response.SyncResponse(true, leases)
+	return leases, nil
 }
 
 func networkStartup(s *state.State) error {
@@ -873,16 +1135,26 @@ func networkStartup(s *state.State) error {
 	}
 
 	// Bring them all up
-	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
-		if err != nil {
-			return err
-		}
+	for networkProject, names := range networks {
+		for _, name := range names {
+			n, err := network.LoadByName(s, networkProject, name)
+			if err != nil {
+				return err
+			}
 
-		err = n.Start()
-		if err != nil {
-			// Don't cause LXD to fail to start entirely on network bring up failure
-			logger.Error("Failed to bring up network", log.Ctx{"err": err, "name": name})
+			entityURL := fmt.Sprintf("/%s/networks/%s", version.APIVersion, name)
+			if networkProject != project.Default {
+				entityURL += fmt.Sprintf("?project=%s", networkProject)
+			}
+
+			err = n.Start()
+			if err != nil {
+				// Don't cause LXD to fail to start entirely on network bring up failure
+				warningRaise(s, "", "network", "startup-failed", entityURL, db.WarningSeverityHigh, fmt.Sprintf("Failed to bring up network %q: %v", name, err))
+				continue
+			}
+
+			warningResolve(s, "", "network", "startup-failed", entityURL)
 		}
 	}
 
@@ -896,16 +1168,18 @@ func networkShutdown(s *state.State) error {
 		return err
 	}
 
-	// Bring them all up
-	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
-		if err != nil {
-			return err
-		}
+	// Bring them all down
+	for networkProject, names := range networks {
+		for _, name := range names {
+			n, err := network.LoadByName(s, networkProject, name)
+			if err != nil {
+				return err
+			}
 
-		err = n.Stop()
-		if err != nil {
-			logger.Error("Failed to bring down network", log.Ctx{"err": err, "name": name})
+			err = n.Stop()
+			if err != nil {
+				logger.Error("Failed to bring down network", log.Ctx{"err": err, "project": networkProject, "name": name})
+			}
 		}
 	}
 
@@ -921,6 +1195,11 @@ func networkStateGet(d *Daemon, r *http.Request) response.Response {
 
 	name := mux.Vars(r)["name"]
 
+	_, err := networkProjectFromRequest(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get some information
 	osInfo, _ := net.InterfaceByName(name)
 
@@ -41,11 +41,21 @@ func networkAutoAttach(cluster *db.Cluster, devName string) error {
 }
 
 func networkGetInterfaces(cluster *db.Cluster) ([]string, error) {
-	networks, err := cluster.GetNetworks()
+	projectNetworks, err := cluster.GetNetworks()
 	if err != nil {
 		return nil, err
 	}
 
+	// Networks of different projects may share a name.
+	networks := []string{}
+	for _, names := range projectNetworks {
+		for _, name := range names {
+			if !shared.StringInSlice(name, networks) {
+				networks = append(networks, name)
+			}
+		}
+	}
+
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
@@ -74,17 +84,47 @@ func networkUpdateForkdnsServersTask(s *state.State, heartbeatData *cluster.APIH
 		return err
 	}
 
-	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
-		if err != nil {
-			return err
+	for networkProject, names := range networks {
+		for _, name := range names {
+			n, err := network.LoadByName(s, networkProject, name)
+			if err != nil {
+				return err
+			}
+
+			if n.Type() == "bridge" && n.Config()["bridge.mode"] == "fan" {
+				err := n.HandleHeartbeat(heartbeatData)
+				if err != nil {
+					return err
+				}
+			}
 		}
+	}
 
-		if n.Type() == "bridge" && n.Config()["bridge.mode"] == "fan" {
-			err := n.HandleHeartbeat(heartbeatData)
+	return nil
+}
+
+// networkUpdateWireguardPeersTask runs on each heartbeat and refreshes the peers of the Wireguard meshes, as members
+// only publish their public key once the network is setup on them.
+func networkUpdateWireguardPeersTask(s *state.State, heartbeatData *cluster.APIHeartbeat) error {
+	// Get a list of managed networks
+	networks, err := s.Cluster.GetNonPendingNetworks()
+	if err != nil {
+		return err
+	}
+
+	for networkProject, names := range networks {
+		for _, name := range names {
+			n, err := network.LoadByName(s, networkProject, name)
 			if err != nil {
 				return err
 			}
+
+			if n.Type() == "bridge" && shared.IsTrue(n.Config()["wireguard.mesh"]) {
+				err := n.HandleHeartbeat(heartbeatData)
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
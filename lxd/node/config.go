@@ -3,9 +3,13 @@ package node
 import (
 	"fmt"
 	"net"
+	"path/filepath"
+	"strings"
 
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/shared"
 	"github.com/pkg/errors"
 )
@@ -51,12 +55,49 @@ func (c *Config) DebugAddress() string {
 	return c.m.GetString("core.debug_address")
 }
 
+// DNSAddress returns the address and port to setup the DNS server on
+func (c *Config) DNSAddress() string {
+	return c.m.GetString("core.dns_address")
+}
+
+// BGPAddress returns the address and port to setup the BGP server on
+func (c *Config) BGPAddress() string {
+	return c.m.GetString("core.bgp_address")
+}
+
+// BGPRouterID returns the router ID of the BGP server
+func (c *Config) BGPRouterID() string {
+	return c.m.GetString("core.bgp_routerid")
+}
+
+// BGPPeers returns the peers of the BGP server
+func (c *Config) BGPPeers() string {
+	return c.m.GetString("core.bgp_peers")
+}
+
+// SocketGroups returns the system groups granted access to the local unix
+// socket, along with their project roles.
+func (c *Config) SocketGroups() map[string]map[string]string {
+	groups, _ := ParseSocketGroups(c.m.GetString("core.socket_groups"))
+	return groups
+}
+
 // MAASMachine returns the MAAS machine this instance is associated with, if
 // any.
 func (c *Config) MAASMachine() string {
 	return c.m.GetString("maas.machine")
 }
 
+// AuditFile returns the path of the file the audit log is written to, if any.
+func (c *Config) AuditFile() string {
+	return c.m.GetString("audit.file")
+}
+
+// AuditSyslog returns whether the audit log is sent to syslog.
+func (c *Config) AuditSyslog() bool {
+	return c.m.GetBool("audit.syslog")
+}
+
 // StorageBackupsVolume returns the name of the pool/volume to use for storing backup tarballs
 func (c *Config) StorageBackupsVolume() string {
 	return c.m.GetString("storage.backups_volume")
@@ -136,6 +177,22 @@ func DebugAddress(node *db.Node) (string, error) {
 	return config.DebugAddress(), nil
 }
 
+// DNSAddress is a convenience for loading the node configuration and
+// returning the value of core.dns_address.
+func DNSAddress(node *db.Node) (string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return config.DNSAddress(), nil
+}
+
 func (c *Config) update(values map[string]interface{}) (map[string]string, error) {
 	changed, err := c.m.Change(values)
 	if err != nil {
@@ -152,6 +209,10 @@ func (c *Config) update(values map[string]interface{}) (map[string]string, error
 
 // ConfigSchema defines available server configuration keys.
 var ConfigSchema = config.Schema{
+	// Audit log sinks of this LXD server
+	"audit.file":   {Validator: validateAuditFile},
+	"audit.syslog": {Type: config.Bool},
+
 	// Network address for this LXD server
 	"core.https_address": {},
 
@@ -161,6 +222,17 @@ var ConfigSchema = config.Schema{
 	// Network address for the debug server
 	"core.debug_address": {},
 
+	// Network address for the DNS server
+	"core.dns_address": {},
+
+	// BGP server settings
+	"core.bgp_address":  {},
+	"core.bgp_routerid": {Validator: validateBGPRouterID},
+	"core.bgp_peers":    {Validator: validateBGPPeers},
+
+	// System groups allowed to access the local unix socket
+	"core.socket_groups": {Validator: validateSocketGroups},
+
 	// MAAS machine this LXD instance is associated with
 	"maas.machine": {},
 
@@ -169,6 +241,18 @@ var ConfigSchema = config.Schema{
 	"storage.images_volume":  {},
 }
 
+func validateAuditFile(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("Audit log file must be an absolute path")
+	}
+
+	return nil
+}
+
 func validateClusterHTTPSAddress(value string) error {
 	if value == "" {
 		return nil // Deleting entry
@@ -182,3 +266,86 @@ func validateClusterHTTPSAddress(value string) error {
 	}
 	return nil
 }
+
+func validateBGPRouterID(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("Router ID must be an IPv4 address")
+	}
+
+	return nil
+}
+
+func validateBGPPeers(value string) error {
+	_, err := bgp.ParsePeers(value)
+	return err
+}
+
+func validateSocketGroups(value string) error {
+	groups, err := ParseSocketGroups(value)
+	if err != nil {
+		return err
+	}
+
+	for group := range groups {
+		_, err := shared.GroupId(group)
+		if err != nil {
+			return fmt.Errorf("Invalid socket group %q: %v", group, err)
+		}
+	}
+
+	return nil
+}
+
+// ParseSocketGroups parses a comma separated list of socket groups, each entry
+// being either a group name, granting that group full access, or in the
+// <group>:<project>=<role> format, restricting the group to the given projects.
+// The returned map holds the project roles of each group, nil for full access.
+func ParseSocketGroups(value string) (map[string]map[string]string, error) {
+	groups := map[string]map[string]string{}
+	if value == "" {
+		return groups, nil
+	}
+
+	unrestricted := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		fields := strings.SplitN(entry, ":", 2)
+		if fields[0] == "" {
+			return nil, fmt.Errorf("Invalid socket group %q, must be in the <group> or <group>:<project>=<role> format", entry)
+		}
+
+		group := fields[0]
+		if len(fields) == 1 {
+			unrestricted[group] = true
+			continue
+		}
+
+		grant := strings.SplitN(fields[1], "=", 2)
+		if len(grant) != 2 || grant[0] == "" {
+			return nil, fmt.Errorf("Invalid socket group %q, must be in the <group> or <group>:<project>=<role> format", entry)
+		}
+
+		_, ok := rbac.ProjectRoles[grant[1]]
+		if !ok {
+			return nil, fmt.Errorf("Invalid role %q for socket group %q", grant[1], group)
+		}
+
+		if groups[group] == nil {
+			groups[group] = map[string]string{}
+		}
+
+		groups[group][grant[0]] = grant[1]
+	}
+
+	for group := range unrestricted {
+		groups[group] = nil
+	}
+
+	return groups, nil
+}
@@ -142,3 +142,21 @@ func TestClusterAddress(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "127.0.0.1:666", address)
 }
+
+// Socket groups are either unrestricted or restricted to some projects.
+func TestParseSocketGroups(t *testing.T) {
+	groups, err := node.ParseSocketGroups("admins, ci:ci=operator,ci:shared=viewer")
+	require.NoError(t, err)
+
+	expected := map[string]map[string]string{
+		"admins": nil,
+		"ci":     {"ci": "operator", "shared": "viewer"},
+	}
+	assert.Equal(t, expected, groups)
+
+	_, err = node.ParseSocketGroups("ci:ci=superuser")
+	assert.EqualError(t, err, `Invalid role "superuser" for socket group "ci"`)
+
+	_, err = node.ParseSocketGroups("ci:ci")
+	assert.Error(t, err)
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/filter"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
@@ -32,7 +36,7 @@ var operationCmd = APIEndpoint{
 var operationsCmd = APIEndpoint{
 	Path: "operations",
 
-	Get: APIEndpointAction{Handler: operationsGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: operationsGet, AccessHandler: allowProjectPermission("containers", "view")},
 }
 
 var operationWait = APIEndpoint{
@@ -113,6 +117,20 @@ func waitForOperations(s *state.State, chCancel chan struct{}) {
 	}
 }
 
+// operationAccessible returns whether the caller has the given permission on the project of an operation.
+// Operations which aren't tied to a project are only accessible to administrators.
+func operationAccessible(d *Daemon, r *http.Request, projectName string, permission string) bool {
+	if d.userIsAdmin(r) {
+		return true
+	}
+
+	if projectName == "" {
+		return false
+	}
+
+	return d.userHasPermission(r, projectName, permission)
+}
+
 // API functions
 func operationGet(d *Daemon, r *http.Request) response.Response {
 	id := mux.Vars(r)["id"]
@@ -122,6 +140,20 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAccessible(d, r, op.Project(), "view") {
+			return response.Forbidden(nil)
+		}
+
+		// Wait for the operation to change if requested.
+		if shared.IsTrue(r.FormValue("wait")) {
+			timeout, err := shared.AtoiEmptyDefault(r.FormValue("timeout"), -1)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+
+			op.WaitChange(timeout)
+		}
+
 		_, body, err = op.Render()
 		if err != nil {
 			return response.SmartError(err)
@@ -132,6 +164,7 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var projectName string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.GetOperationByUUID(id)
 		if err != nil {
@@ -139,12 +172,18 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		projectName = operation.Project
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// The forwarded request is trusted by the other member, so check access here.
+	if !operationAccessible(d, r, projectName, "view") {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -160,6 +199,10 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAccessible(d, r, op.Project(), "view") {
+			return response.Forbidden(nil)
+		}
+
 		if op.Permission() != "" {
 			projectName := op.Project()
 			if projectName == "" {
@@ -181,6 +224,7 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var projectName string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.GetOperationByUUID(id)
 		if err != nil {
@@ -188,12 +232,19 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		projectName = operation.Project
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// The forwarded request is trusted by the other member, so check access here. As the permission needed to
+	// cancel the operation isn't known, require being able to operate instances.
+	if !operationAccessible(d, r, projectName, "operate-containers") {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -207,6 +258,29 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	recursion := util.IsRecursionRequest(r)
 
+	// Parse filter value.
+	var clauses []filter.Clause
+	filterStr := r.FormValue("filter")
+	if filterStr != "" {
+		var err error
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
+	_, _, err := util.Paginate(r, 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Filtering and pagination require the operations themselves, as pages are ordered by creation date.
+	paginated := r.FormValue("limit") != "" || r.FormValue("offset") != ""
+	mustLoadObjects := recursion || clauses != nil || paginated
+
+	// Operations which aren't tied to a project are only listed to administrators.
+	isAdmin := d.userIsAdmin(r)
+
 	localOperationURLs := func() (shared.Jmap, error) {
 		// Get all the operations
 		operations.Lock()
@@ -220,6 +294,11 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			if v.Project() == "" && !isAdmin {
+				continue
+			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -245,6 +324,11 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			if v.Project() == "" && !isAdmin {
+				continue
+			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -286,9 +370,8 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 
 	// Start with local operations
 	var md shared.Jmap
-	var err error
 
-	if recursion {
+	if mustLoadObjects {
 		md, err = localOperations()
 		if err != nil {
 			return response.InternalError(err)
@@ -308,11 +391,16 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 
 	// Return now if not clustered
 	if !clustered {
+		if mustLoadObjects {
+			md = operationsPage(r, md, clauses, recursion)
+		}
+
 		return response.SyncResponse(true, md)
 	}
 
-	// Get all nodes with running operations in this project.
+	// Get all nodes with running operations in this project, along with the operations of the project.
 	var nodes []string
+	projectOps := map[string]bool{}
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 
@@ -321,6 +409,15 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
+		ops, err := tx.GetOperationsOfProject(project)
+		if err != nil {
+			return err
+		}
+
+		for _, op := range ops {
+			projectOps[op.UUID] = true
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -346,25 +443,29 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		// Get operation data
-		ops, err := client.GetOperations()
+		ops, err := client.UseProject(project).GetOperations()
 		if err != nil {
 			return response.SmartError(err)
 		}
 
 		// Merge with existing data
 		for _, op := range ops {
+			if !isAdmin && !projectOps[op.ID] {
+				continue
+			}
+
 			status := strings.ToLower(op.Status)
 
 			_, ok := md[status]
 			if !ok {
-				if recursion {
+				if mustLoadObjects {
 					md[status] = make([]*api.Operation, 0)
 				} else {
 					md[status] = make([]string, 0)
 				}
 			}
 
-			if recursion {
+			if mustLoadObjects {
 				md[status] = append(md[status].([]*api.Operation), &op)
 			} else {
 				md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
@@ -372,9 +473,54 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	if mustLoadObjects {
+		md = operationsPage(r, md, clauses, recursion)
+	}
+
 	return response.SyncResponse(true, md)
 }
 
+// operationsPage returns the page of operations requested with the limit and offset parameters, out of the
+// operations grouped by status which match the given filter clauses. Operations are ordered by creation date and
+// are grouped by status again, either as operations or as URLs.
+func operationsPage(r *http.Request, md shared.Jmap, clauses []filter.Clause, recursion bool) shared.Jmap {
+	ops := []*api.Operation{}
+	for _, entries := range md {
+		for _, op := range entries.([]*api.Operation) {
+			if clauses != nil && !filter.Match(*op, clauses) {
+				continue
+			}
+
+			ops = append(ops, op)
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if !ops[i].CreatedAt.Equal(ops[j].CreatedAt) {
+			return ops[i].CreatedAt.Before(ops[j].CreatedAt)
+		}
+
+		return ops[i].ID < ops[j].ID
+	})
+
+	start, end, _ := util.Paginate(r, len(ops))
+
+	page := shared.Jmap{}
+	for _, op := range ops[start:end] {
+		status := strings.ToLower(op.Status)
+
+		if recursion {
+			entries, _ := page[status].([]*api.Operation)
+			page[status] = append(entries, op)
+		} else {
+			entries, _ := page[status].([]string)
+			page[status] = append(entries, fmt.Sprintf("/1.0/operations/%s", op.ID))
+		}
+	}
+
+	return page
+}
+
 func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	id := mux.Vars(r)["id"]
 	secret := r.FormValue("secret")
@@ -505,6 +651,12 @@ func operationWebsocketGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Migrations from or to another server connect to the member running the operation directly if they can.
+	if r.Header.Get("X-LXD-redirect") == "true" {
+		u := url.URL{Scheme: "wss", Host: address, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		return response.SyncResponseRedirect(u.String())
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
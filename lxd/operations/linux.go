@@ -55,6 +55,8 @@ func getServerName(op *Operation) (string, error) {
 }
 
 func (op *Operation) sendEvent(eventMessage interface{}) {
+	op.notifyChanged()
+
 	if op.events == nil {
 		return
 	}
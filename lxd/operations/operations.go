@@ -92,6 +92,7 @@ type Operation struct {
 	err         string
 	readonly    bool
 	canceler    *cancel.Canceler
+	cancelable  bool
 	description string
 	permission  string
 	dbOpType    db.OperationType
@@ -104,6 +105,14 @@ type Operation struct {
 	// Channels used for error reporting and state tracking of background actions
 	chanDone chan error
 
+	// Channel closed and replaced whenever the operation is updated
+	chanChanged chan struct{}
+	changedLock sync.Mutex
+
+	// Context cancelled when the operation is cancelled or done
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+
 	// Locking for concurent access to the Operation
 	lock sync.Mutex
 
@@ -133,6 +142,8 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	op.url = fmt.Sprintf("/%s/operations/%s", version.APIVersion, op.id)
 	op.resources = opResources
 	op.chanDone = make(chan error)
+	op.chanChanged = make(chan struct{})
+	op.ctx, op.ctxCancel = context.WithCancel(context.Background())
 	op.state = s
 
 	if s != nil {
@@ -199,6 +210,7 @@ func (op *Operation) done() {
 	op.onCancel = nil
 	op.onConnect = nil
 	close(op.chanDone)
+	op.ctxCancel()
 	op.lock.Unlock()
 
 	time.AfterFunc(time.Second*5, func() {
@@ -240,7 +252,12 @@ func (op *Operation) Run() (chan error, error) {
 			err := op.onRun(op)
 			if err != nil {
 				op.lock.Lock()
-				op.status = api.Failure
+				if op.status == api.Cancelling && op.ctx.Err() != nil {
+					// The Run hook aborted because the operation was cancelled through its context.
+					op.status = api.Cancelled
+				} else {
+					op.status = api.Failure
+				}
 				op.err = response.SmartError(err).String()
 				op.lock.Unlock()
 				op.done()
@@ -325,14 +342,21 @@ func (op *Operation) Cancel() (chan error, error) {
 	_, md, _ := op.Render()
 	op.sendEvent(md)
 
-	if op.canceler != nil {
+	if op.canceler != nil && (!op.cancelable || op.canceler.Cancelable()) {
 		err := op.canceler.Cancel()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if !hasOnCancel {
+	if op.cancelable && !hasOnCancel {
+		// Let the Run hook abort the transfer and clean up before reporting the cancellation.
+		op.ctxCancel()
+		go func(op *Operation, chanCancel chan error) {
+			<-op.chanDone
+			chanCancel <- nil
+		}(op, chanCancel)
+	} else if !hasOnCancel {
 		op.lock.Lock()
 		op.status = api.Cancelled
 		op.lock.Unlock()
@@ -391,6 +415,10 @@ func (op *Operation) mayCancel() bool {
 		return true
 	}
 
+	if op.cancelable {
+		return true
+	}
+
 	if op.canceler != nil && op.canceler.Cancelable() {
 		return true
 	}
@@ -466,6 +494,45 @@ func (op *Operation) WaitFinal(timeout int) (bool, error) {
 	return false, nil
 }
 
+// WaitChange waits for the operation to be updated or to be done. If timeout is -1, it will wait
+// indefinitely otherwise it will timeout after {timeout} seconds. It returns whether the operation changed.
+func (op *Operation) WaitChange(timeout int) bool {
+	op.changedLock.Lock()
+	chanChanged := op.chanChanged
+	op.changedLock.Unlock()
+
+	// Check current state
+	if op.status.IsFinal() {
+		return false
+	}
+
+	var chanTimeout <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(time.Duration(timeout) * time.Second)
+		defer timer.Stop()
+		chanTimeout = timer.C
+	}
+
+	select {
+	case <-chanChanged:
+		return true
+
+	case <-op.chanDone:
+		return true
+
+	case <-chanTimeout:
+		return false
+	}
+}
+
+// notifyChanged wakes up the callers of WaitChange.
+func (op *Operation) notifyChanged() {
+	op.changedLock.Lock()
+	close(op.chanChanged)
+	op.chanChanged = make(chan struct{})
+	op.changedLock.Unlock()
+}
+
 // UpdateResources updates the resources of the operation. It returns an error
 // if the operation is not pending or running, or the operation is read-only.
 func (op *Operation) UpdateResources(opResources map[string][]string) error {
@@ -542,6 +609,19 @@ func (op *Operation) SetCanceler(canceler *cancel.Canceler) {
 	op.canceler = canceler
 }
 
+// SetCancelable allows cancelling the operation through the context returned by Context. Its Run hook is then
+// expected to abort and clean up once that context is cancelled.
+func (op *Operation) SetCancelable() {
+	op.lock.Lock()
+	op.cancelable = true
+	op.lock.Unlock()
+}
+
+// Context returns a context which is cancelled when the operation is cancelled or done.
+func (op *Operation) Context() context.Context {
+	return op.ctx
+}
+
 // Permission returns the operation permission.
 func (op *Operation) Permission() string {
 	return op.permission
@@ -557,6 +637,11 @@ func (op *Operation) Status() api.StatusCode {
 	return op.status
 }
 
+// Err returns the error of the operation, if it failed.
+func (op *Operation) Err() string {
+	return op.err
+}
+
 // Type returns the db operation type.
 func (op *Operation) Type() db.OperationType {
 	return op.dbOpType
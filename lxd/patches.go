@@ -98,6 +98,7 @@ var patches = []patch{
 	{name: "storage_rename_custom_volume_add_project", stage: patchPreDaemonStorage, run: patchGenericStorage},
 	{name: "storage_lvm_skipactivation", stage: patchPostDaemonStorage, run: patchGenericStorage},
 	{name: "clustering_drop_database_role", stage: patchPostDaemonStorage, run: patchClusteringDropDatabaseRole},
+	{name: "instances_idmap_allocations", stage: patchPostDaemonStorage, run: patchInstancesIdmapAllocations},
 }
 
 type patch struct {
@@ -308,21 +309,23 @@ func patchNetworkPermissions(name string, d *Daemon) error {
 		return err
 	}
 
-	for _, network := range networks {
-		if !shared.PathExists(shared.VarPath("networks", network)) {
-			continue
-		}
-
-		err = os.Chmod(shared.VarPath("networks", network), 0711)
-		if err != nil {
-			return err
-		}
+	for _, names := range networks {
+		for _, network := range names {
+			if !shared.PathExists(shared.VarPath("networks", network)) {
+				continue
+			}
 
-		if shared.PathExists(shared.VarPath("networks", network, "dnsmasq.hosts")) {
-			err = os.Chmod(shared.VarPath("networks", network, "dnsmasq.hosts"), 0644)
+			err = os.Chmod(shared.VarPath("networks", network), 0711)
 			if err != nil {
 				return err
 			}
+
+			if shared.PathExists(shared.VarPath("networks", network, "dnsmasq.hosts")) {
+				err = os.Chmod(shared.VarPath("networks", network, "dnsmasq.hosts"), 0644)
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -2664,12 +2667,14 @@ func patchNetworkDnsmasqHosts(name string, d *Daemon) error {
 		return err
 	}
 
-	for _, network := range networks {
-		// Remove the old dhcp-hosts file (will be re-generated on startup)
-		if shared.PathExists(shared.VarPath("networks", network, "dnsmasq.hosts")) {
-			err = os.Remove(shared.VarPath("networks", network, "dnsmasq.hosts"))
-			if err != nil {
-				return err
+	for _, names := range networks {
+		for _, network := range names {
+			// Remove the old dhcp-hosts file (will be re-generated on startup)
+			if shared.PathExists(shared.VarPath("networks", network, "dnsmasq.hosts")) {
+				err = os.Remove(shared.VarPath("networks", network, "dnsmasq.hosts"))
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -3444,6 +3449,44 @@ func patchClusteringDropDatabaseRole(name string, d *Daemon) error {
 	})
 }
 
+// patchInstancesIdmapAllocations records the uid/gid ranges used by the existing isolated containers of the
+// local node, so the idmap allocator doesn't hand them out again.
+func patchInstancesIdmapAllocations(name string, d *Daemon) error {
+	containers, err := instance.LoadNodeAll(d.State(), instancetype.Container)
+	if err != nil {
+		return err
+	}
+
+	return d.State().Cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, c := range containers {
+			config := c.ExpandedConfig()
+			if c.IsSnapshot() || c.IsPrivileged() || !shared.IsTrue(config["security.idmap.isolated"]) || config["volatile.idmap.base"] == "" {
+				continue
+			}
+
+			base, err := strconv.ParseInt(config["volatile.idmap.base"], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			size := int64(65536)
+			if config["security.idmap.size"] != "" && config["security.idmap.size"] != "auto" {
+				size, err = strconv.ParseInt(config["security.idmap.size"], 10, 64)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = tx.UpdateInstanceIdmapAllocation(c.Project(), c.Name(), base, size)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // Patches end here
 
 // Here are a couple of legacy patches that were originally in
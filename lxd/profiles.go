@@ -63,7 +63,7 @@ func profilesGet(d *Daemon, r *http.Request) response.Response {
 			Project: projectName,
 		}
 		if recursion {
-			profiles, err := tx.GetProfiles(filter)
+			profiles, err := tx.GetProfilesWithParents(filter)
 			if err != nil {
 				return err
 			}
@@ -132,6 +132,16 @@ func profilesPost(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("The profile already exists")
 		}
 
+		err = profileValidateParents(tx, projectName, req.Name, req.Parents)
+		if err != nil {
+			return err
+		}
+
+		err = project.AllowUserConfig(tx, projectName, req.Config, nil)
+		if err != nil {
+			return err
+		}
+
 		profile := db.Profile{
 			Project:     projectName,
 			Name:        req.Name,
@@ -140,7 +150,11 @@ func profilesPost(d *Daemon, r *http.Request) response.Response {
 			Devices:     req.Devices,
 		}
 		_, err = tx.CreateProfile(profile)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateProfileParents(projectName, req.Name, req.Parents)
 	})
 	if err != nil {
 		return response.SmartError(
@@ -166,20 +180,44 @@ func profileGet(d *Daemon, r *http.Request) response.Response {
 			projectName = project.Default
 		}
 
-		profile, err := tx.GetProfile(projectName, name)
+		profile, err := tx.GetProfileWithParents(projectName, name)
 		if err != nil {
 			return errors.Wrap(err, "Fetch profile")
 		}
 
 		resp = db.ProfileToAPI(profile)
 
+		if !shared.IsTrue(queryParam(r, "expanded")) {
+			return nil
+		}
+
+		// Apply the config and devices of the parents.
+		profiles, err := tx.GetProfilesWithParents(db.ProfileFilter{Project: projectName})
+		if err != nil {
+			return errors.Wrap(err, "Fetch profiles")
+		}
+
+		apiProfiles := map[string]api.Profile{}
+		for _, profile := range profiles {
+			apiProfiles[profile.Name] = *db.ProfileToAPI(&profile)
+		}
+
+		expanded, order, err := db.ExpandProfile(name, apiProfiles)
+		if err != nil {
+			return err
+		}
+
+		resp.Config = expanded.Config
+		resp.Devices = expanded.Devices
+		resp.Expansion = order
+
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	etag := []interface{}{resp.Config, resp.Description, resp.Devices}
+	etag := []interface{}{resp.Config, resp.Description, resp.Devices, resp.Parents}
 	return response.SyncResponseETag(true, resp, etag)
 }
 
@@ -217,7 +255,7 @@ func profilePut(d *Daemon, r *http.Request) response.Response {
 			projectName = project.Default
 		}
 
-		current, err := tx.GetProfile(projectName, name)
+		current, err := tx.GetProfileWithParents(projectName, name)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to retrieve profile='%s'", name)
 		}
@@ -232,7 +270,7 @@ func profilePut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []interface{}{profile.Config, profile.Description, profile.Devices}
+	etag := []interface{}{profile.Config, profile.Description, profile.Devices, profile.Parents}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
 		return response.PreconditionFailed(err)
@@ -243,6 +281,15 @@ func profilePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	if shared.IsTrue(queryParam(r, "dry-run")) {
+		impacts, err := doProfileUpdateDryRun(d, projectName, name, req)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, impacts)
+	}
+
 	err = doProfileUpdate(d, projectName, name, id, profile, req)
 
 	if err == nil && !isClusterNotification(r) {
@@ -283,7 +330,7 @@ func profilePatch(d *Daemon, r *http.Request) response.Response {
 			projectName = project.Default
 		}
 
-		current, err := tx.GetProfile(projectName, name)
+		current, err := tx.GetProfileWithParents(projectName, name)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to retrieve profile='%s'", name)
 		}
@@ -298,7 +345,7 @@ func profilePatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []interface{}{profile.Config, profile.Description, profile.Devices}
+	etag := []interface{}{profile.Config, profile.Description, profile.Devices, profile.Parents}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
 		return response.PreconditionFailed(err)
@@ -328,6 +375,11 @@ func profilePatch(d *Daemon, r *http.Request) response.Response {
 		req.Description = profile.Description
 	}
 
+	// Get Parents
+	if req.Parents == nil {
+		req.Parents = profile.Parents
+	}
+
 	// Get Config
 	if req.Config == nil {
 		req.Config = profile.Config
@@ -434,6 +486,17 @@ func profileDelete(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("Profile is currently in use")
 		}
 
+		profiles, err := tx.GetProfilesWithParents(db.ProfileFilter{Project: projectName})
+		if err != nil {
+			return err
+		}
+
+		for _, child := range profiles {
+			if shared.StringInSlice(name, child.Parents) {
+				return fmt.Errorf("Profile is currently a parent of profile %q", child.Name)
+			}
+		}
+
 		return tx.DeleteProfile(projectName, name)
 	})
 	if err != nil {
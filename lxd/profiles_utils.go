@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
@@ -13,9 +14,15 @@ import (
 	"github.com/pkg/errors"
 )
 
-func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Profile, req api.ProfilePut) error {
+// Check that the given profile update is valid and doesn't violate project limits.
+func doProfileUpdateValidate(d *Daemon, project, name string, req api.ProfilePut) error {
 	// Check project limits.
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := profileValidateParents(tx, project, name, req.Parents)
+		if err != nil {
+			return err
+		}
+
 		return projecthelpers.AllowProfileUpdate(tx, project, name, req)
 	})
 	if err != nil {
@@ -34,6 +41,15 @@ func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Pro
 		return err
 	}
 
+	return nil
+}
+
+func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Profile, req api.ProfilePut) error {
+	err := doProfileUpdateValidate(d, project, name, req)
+	if err != nil {
+		return err
+	}
+
 	containers, err := getProfileContainersInfo(d.cluster, project, name)
 	if err != nil {
 		return errors.Wrapf(err, "failed to query instances associated with profile '%s'", name)
@@ -77,13 +93,18 @@ func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Pro
 
 	// Update the database
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		return tx.UpdateProfile(project, name, db.Profile{
+		err := tx.UpdateProfile(project, name, db.Profile{
 			Project:     project,
 			Name:        name,
 			Description: req.Description,
 			Config:      req.Config,
 			Devices:     req.Devices,
 		})
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateProfileParents(project, name, req.Parents)
 	})
 	if err != nil {
 		return err
@@ -119,6 +140,73 @@ func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Pro
 	return nil
 }
 
+// Like doProfileUpdate but does not update the database, returning instead the
+// effect the update would have on the instances using the profile.
+func doProfileUpdateDryRun(d *Daemon, project, name string, req api.ProfilePut) ([]api.ProfileInstanceImpact, error) {
+	err := doProfileUpdateValidate(d, project, name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := getProfileContainersInfo(d.cluster, project, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to query instances associated with profile '%s'", name)
+	}
+
+	impacts := []api.ProfileInstanceImpact{}
+	for _, args := range containers {
+		projectProfiles, err := d.cluster.GetProjectProfiles(args.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		oldProfiles, err := db.ExpandProfiles(args.Profiles, projectProfiles)
+		if err != nil {
+			return nil, err
+		}
+
+		// Overwrite the current config from the database with the new config, devices and parents.
+		profile := projectProfiles[name]
+		profile.Config = req.Config
+		profile.Devices = req.Devices
+		profile.Parents = req.Parents
+		projectProfiles[name] = profile
+
+		newProfiles, err := db.ExpandProfiles(args.Profiles, projectProfiles)
+		if err != nil {
+			return nil, err
+		}
+
+		oldConfig := db.ExpandInstanceConfig(args.Config, oldProfiles)
+		oldDevices := db.ExpandInstanceDevices(args.Devices, oldProfiles)
+		newConfig := db.ExpandInstanceConfig(args.Config, newProfiles)
+		newDevices := db.ExpandInstanceDevices(args.Devices, newProfiles)
+
+		impact := api.ProfileInstanceImpact{
+			Name:            args.Name,
+			Project:         args.Project,
+			Location:        args.Node,
+			Changed:         !reflect.DeepEqual(oldConfig, newConfig) || !reflect.DeepEqual(oldDevices, newDevices),
+			ExpandedConfig:  newConfig,
+			ExpandedDevices: newDevices.CloneNative(),
+		}
+
+		// Validate the new expanded config and devices as the instance update would.
+		err = instance.ValidConfig(d.os, newConfig, false, true)
+		if err == nil {
+			err = instance.ValidDevices(d.State(), d.cluster, args.Type, newDevices, true)
+		}
+
+		if err != nil {
+			impact.Error = err.Error()
+		}
+
+		impacts = append(impacts, impact)
+	}
+
+	return impacts, nil
+}
+
 // Like doProfileUpdate but does not update the database, since it was already
 // updated by doProfileUpdate itself, called on the notifying node.
 func doProfileUpdateCluster(d *Daemon, project, name string, old api.ProfilePut) error {
@@ -163,18 +251,21 @@ func doProfileUpdateContainer(d *Daemon, name string, old api.ProfilePut, nodeNa
 		return nil
 	}
 
-	profiles, err := d.cluster.GetProfiles(args.Project, args.Profiles)
+	projectProfiles, err := d.cluster.GetProjectProfiles(args.Project)
 	if err != nil {
 		return err
 	}
 
-	for i, profileName := range args.Profiles {
-		if profileName == name {
-			// Overwrite the new config from the database with the old config and devices.
-			profiles[i].Config = old.Config
-			profiles[i].Devices = old.Devices
-			break
-		}
+	// Overwrite the new config from the database with the old config, devices and parents.
+	profile := projectProfiles[name]
+	profile.Config = old.Config
+	profile.Devices = old.Devices
+	profile.Parents = old.Parents
+	projectProfiles[name] = profile
+
+	profiles, err := db.ExpandProfiles(args.Profiles, projectProfiles)
+	if err != nil {
+		return err
 	}
 
 	// Load the instance using the old profile config.
@@ -197,14 +288,81 @@ func doProfileUpdateContainer(d *Daemon, name string, old api.ProfilePut, nodeNa
 	}, true)
 }
 
+// Check that the given parents of a profile exist in its project and don't make it inherit from itself.
+func profileValidateParents(tx *db.ClusterTx, project string, name string, parents []string) error {
+	profiles, err := tx.GetProfilesWithParents(db.ProfileFilter{Project: project})
+	if err != nil {
+		return err
+	}
+
+	apiProfiles := map[string]api.Profile{}
+	for _, profile := range profiles {
+		apiProfiles[profile.Name] = *db.ProfileToAPI(&profile)
+	}
+
+	apiProfiles[name] = api.Profile{Name: name, ProfilePut: api.ProfilePut{Parents: parents}}
+
+	for _, parent := range parents {
+		if parent == name {
+			return fmt.Errorf("Profile %q inherits from itself", name)
+		}
+
+		_, ok := apiProfiles[parent]
+		if !ok {
+			return fmt.Errorf("Parent profile %q doesn't exist", parent)
+		}
+	}
+
+	_, _, err = db.ExpandProfile(name, apiProfiles)
+	return err
+}
+
+// Return the names of the profiles inheriting from the given profile, including itself.
+func profileDescendants(cluster *db.Cluster, project, profile string) ([]string, error) {
+	profiles, err := cluster.GetProjectProfiles(project)
+	if err != nil {
+		return nil, err
+	}
+
+	descendants := []string{}
+	for name := range profiles {
+		_, order, err := db.ExpandProfile(name, profiles)
+		if err != nil {
+			return nil, err
+		}
+
+		if shared.StringInSlice(profile, order) {
+			descendants = append(descendants, name)
+		}
+	}
+
+	return descendants, nil
+}
+
 // Query the db for information about containers associated with the given
-// profile.
+// profile, either directly or through a profile inheriting from it.
 func getProfileContainersInfo(cluster *db.Cluster, project, profile string) ([]db.InstanceArgs, error) {
+	descendants, err := profileDescendants(cluster, project, profile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to query profiles inheriting from '%s'", profile)
+	}
+
 	// Query the db for information about containers associated with the
 	// given profile.
-	names, err := cluster.GetInstancesWithProfile(project, profile)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to query instances with profile '%s'", profile)
+	names := map[string][]string{}
+	for _, descendant := range descendants {
+		descendantNames, err := cluster.GetInstancesWithProfile(project, descendant)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to query instances with profile '%s'", descendant)
+		}
+
+		for ctProject, ctNames := range descendantNames {
+			for _, ctName := range ctNames {
+				if !shared.StringInSlice(ctName, names[ctProject]) {
+					names[ctProject] = append(names[ctProject], ctName)
+				}
+			}
+		}
 	}
 
 	containers := []db.InstanceArgs{}
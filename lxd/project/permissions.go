@@ -2,6 +2,7 @@ package project
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -18,6 +19,11 @@ import (
 // AllowInstanceCreation returns an error if any project-specific limit or
 // restriction is violated when creating a new instance.
 func AllowInstanceCreation(tx *db.ClusterTx, projectName string, req api.InstancesPost) error {
+	err := AllowUserConfig(tx, projectName, req.Config, nil)
+	if err != nil {
+		return err
+	}
+
 	project, profiles, instances, err := fetchProject(tx, projectName, true)
 	if err != nil {
 		return err
@@ -47,6 +53,7 @@ func AllowInstanceCreation(tx *db.ClusterTx, projectName string, req api.Instanc
 		Name:     req.Name,
 		Profiles: req.Profiles,
 		Config:   req.Config,
+		Devices:  req.Devices,
 	})
 
 	// Special case restriction checks on volatile.* keys.
@@ -64,6 +71,28 @@ func AllowInstanceCreation(tx *db.ClusterTx, projectName string, req api.Instanc
 	return nil
 }
 
+// AllowInstanceName returns an error if the given instance name doesn't comply with the naming policy of the
+// project.
+func AllowInstanceName(tx *db.ClusterTx, projectName string, instanceName string) error {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project database object")
+	}
+
+	return InstanceNameAllowed(project.Config, instanceName)
+}
+
+// AllowUserConfig returns an error if the given "user.*" config keys don't comply with the schemas registered in
+// the project. Keys whose value is the same as in the current config aren't checked.
+func AllowUserConfig(tx *db.ClusterTx, projectName string, config map[string]string, currentConfig map[string]string) error {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project database object")
+	}
+
+	return UserConfigAllowed(project.Config, config, currentConfig)
+}
+
 // Check that we have not reached the maximum number of instances for
 // this type.
 func checkInstanceCountLimit(project *api.Project, instanceCount int, instanceType instancetype.Type) error {
@@ -96,7 +125,7 @@ func checkInstanceCountLimit(project *api.Project, instanceCount int, instanceTy
 
 // Check restrictions on setting volatile.* keys.
 func checkRestrictionsOnVolatileConfig(project *api.Project, instanceType instancetype.Type, instanceName string, config, currentConfig map[string]string) error {
-	if project.Config["restrict"] == "false" {
+	if !shared.IsTrue(project.Config["restricted"]) {
 		return nil
 	}
 
@@ -307,6 +336,11 @@ func checkRestrictions(project *api.Project, instances []db.Instance, profiles [
 						return fmt.Errorf("Only managed network devices are allowed")
 					}
 				}
+
+				if device["network"] != "" && !networkAccessAllowed(project, device["network"]) {
+					return fmt.Errorf("Network %q isn't allowed in this project", device["network"])
+				}
+
 				return nil
 			}
 		case "restricted.devices.disk":
@@ -418,6 +452,7 @@ func checkRestrictions(project *api.Project, instances []db.Instance, profiles [
 
 var allAggregateLimits = []string{
 	"limits.cpu",
+	"limits.disk",
 	"limits.memory",
 	"limits.processes",
 }
@@ -436,6 +471,7 @@ var AllRestrictions = []string{
 	"restricted.devices.usb",
 	"restricted.devices.nic",
 	"restricted.devices.disk",
+	"restricted.networks.access",
 }
 
 var defaultRestrictionsValues = map[string]string{
@@ -453,6 +489,23 @@ var defaultRestrictionsValues = map[string]string{
 	"restricted.devices.disk":              "managed",
 }
 
+// Return true if the given managed network can be used by the instances of the project, according to
+// restricted.networks.access.
+func networkAccessAllowed(project *api.Project, networkName string) bool {
+	value := project.Config["restricted.networks.access"]
+	if value == "" {
+		return true
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		if strings.TrimSpace(name) == networkName {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Return true if a low-level container option is forbidden.
 func isContainerLowLevelOptionForbidden(key string) bool {
 	if strings.HasPrefix(key, "security.syscalls") {
@@ -488,10 +541,181 @@ func isVMLowLevelOptionForbidden(key string) bool {
 	return false
 }
 
+// AllowNetworkCreation returns an error if any project-specific limit or
+// restriction is violated when creating a new network.
+func AllowNetworkCreation(tx *db.ClusterTx, projectName string, networkName string, config map[string]string) error {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project database object")
+	}
+
+	if !projectHasLimitsOrRestrictions(project) {
+		return nil
+	}
+
+	networks, err := tx.GetProjectNetworksConfig(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project networks from database")
+	}
+
+	// Skip the network being created if it's already pending.
+	delete(networks, networkName)
+
+	value, ok := project.Config["limits.networks"]
+	if ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("Unexpected 'limits.networks' value: '%s'", value)
+		}
+
+		if len(networks) >= limit {
+			return fmt.Errorf("Reached maximum number of networks in project %s", projectName)
+		}
+	}
+
+	return checkNetworkRestrictions(project, networkName, config)
+}
+
+// AllowNetworkUpdate returns an error if any project-specific restriction is
+// violated when updating an existing network.
+func AllowNetworkUpdate(tx *db.ClusterTx, projectName string, networkName string, config map[string]string) error {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project database object")
+	}
+
+	if !projectHasLimitsOrRestrictions(project) {
+		return nil
+	}
+
+	return checkNetworkRestrictions(project, networkName, config)
+}
+
+// networkSubnetKeys lists the network config keys holding subnets (or
+// addresses in CIDR notation) which must be within the project's
+// restricted.networks.subnets.
+var networkSubnetKeys = []string{
+	"ipv4.address",
+	"ipv6.address",
+	"ipv4.gateway",
+	"ipv6.gateway",
+	"ipv4.routes",
+	"ipv6.routes",
+}
+
+// Check that the subnets used by the network are within the subnets the
+// project is restricted to.
+func checkNetworkRestrictions(project *api.Project, networkName string, config map[string]string) error {
+	if !shared.IsTrue(project.Config["restricted"]) || project.Config["restricted.networks.subnets"] == "" {
+		return nil
+	}
+
+	allowed := []*net.IPNet{}
+	for _, value := range strings.Split(project.Config["restricted.networks.subnets"], ",") {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("Invalid 'restricted.networks.subnets' value: '%s'", value)
+		}
+
+		allowed = append(allowed, subnet)
+	}
+
+	for _, key := range networkSubnetKeys {
+		for _, value := range strings.Split(config[key], ",") {
+			value = strings.TrimSpace(value)
+			if shared.StringInSlice(value, []string{"", "none"}) {
+				continue
+			}
+
+			_, subnet, err := net.ParseCIDR(value)
+			if err != nil {
+				return fmt.Errorf("Invalid '%s' value '%s' on network %s", key, value, networkName)
+			}
+
+			if !subnetWithin(subnet, allowed) {
+				return fmt.Errorf("Subnet '%s' of network %s is not within the subnets allowed in project %s", subnet, networkName, project.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Return true if the given subnet is fully contained in one of the allowed
+// subnets.
+func subnetWithin(subnet *net.IPNet, allowed []*net.IPNet) bool {
+	ones, bits := subnet.Mask.Size()
+	for _, allowedSubnet := range allowed {
+		allowedOnes, allowedBits := allowedSubnet.Mask.Size()
+		if bits == allowedBits && ones >= allowedOnes && allowedSubnet.Contains(subnet.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRestrictedClusterGroups returns the cluster groups the instances of the
+// project are restricted to, or nil if they can be placed on any member.
+func GetRestrictedClusterGroups(tx *db.ClusterTx, projectName string) ([]string, error) {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch project database object")
+	}
+
+	if !shared.IsTrue(project.Config["restricted"]) || project.Config["restricted.cluster.groups"] == "" {
+		return nil, nil
+	}
+
+	groups := []string{}
+	for _, group := range strings.Split(project.Config["restricted.cluster.groups"], ",") {
+		groups = append(groups, strings.TrimSpace(group))
+	}
+
+	return groups, nil
+}
+
+// AllowClusterMember returns an error if the project restricts its instances
+// to cluster groups which the given member isn't part of.
+func AllowClusterMember(tx *db.ClusterTx, projectName string, memberName string) error {
+	groups, err := GetRestrictedClusterGroups(tx, projectName)
+	if err != nil {
+		return err
+	}
+
+	if groups == nil {
+		return nil
+	}
+
+	member, err := tx.GetNodeByName(memberName)
+	if err != nil {
+		return errors.Wrapf(err, "Fetch cluster member %q", memberName)
+	}
+
+	memberGroups, err := tx.GetNodeClusterGroups(member.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Fetch cluster groups of member %q", memberName)
+	}
+
+	for _, group := range memberGroups {
+		if shared.StringInSlice(group, groups) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Cluster member %s isn't part of the cluster groups allowed in project %s", memberName, projectName)
+}
+
 // AllowInstanceUpdate returns an error if any project-specific limit or
 // restriction is violated when updating an existing instance.
 func AllowInstanceUpdate(tx *db.ClusterTx, projectName, instanceName string, req api.InstancePut, currentConfig map[string]string) error {
 	var updatedInstance *db.Instance
+
+	err := AllowUserConfig(tx, projectName, req.Config, currentConfig)
+	if err != nil {
+		return err
+	}
+
 	project, profiles, instances, err := fetchProject(tx, projectName, true)
 	if err != nil {
 		return err
@@ -531,6 +755,16 @@ func AllowInstanceUpdate(tx *db.ClusterTx, projectName, instanceName string, req
 // AllowProfileUpdate checks that project limits and restrictions are not
 // violated when changing a profile.
 func AllowProfileUpdate(tx *db.ClusterTx, projectName, profileName string, req api.ProfilePut) error {
+	current, err := tx.GetProfile(projectName, profileName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch profile database object")
+	}
+
+	err = AllowUserConfig(tx, projectName, req.Config, current.Config)
+	if err != nil {
+		return err
+	}
+
 	project, profiles, instances, err := fetchProject(tx, projectName, true)
 	if err != nil {
 		return err
@@ -546,6 +780,7 @@ func AllowProfileUpdate(tx *db.ClusterTx, projectName, profileName string, req a
 		}
 		profiles[i].Config = req.Config
 		profiles[i].Devices = req.Devices
+		profiles[i].Parents = req.Parents
 	}
 
 	err = checkRestrictionsAndAggregateLimits(tx, project, instances, profiles)
@@ -570,6 +805,17 @@ func AllowProjectUpdate(tx *db.ClusterTx, projectName string, config map[string]
 	aggregateKeys := []string{}
 
 	for _, key := range changed {
+		if key == "limits.networks" || key == "restricted.networks.subnets" || (key == "restricted" && shared.IsTrue(config[key])) {
+			err := validateNetworks(tx, projectName, config, key)
+			if err != nil {
+				return err
+			}
+
+			if key != "restricted" {
+				continue
+			}
+		}
+
 		if strings.HasPrefix(key, "restricted.") {
 			project := &api.Project{
 				Name: projectName,
@@ -598,6 +844,8 @@ func AllowProjectUpdate(tx *db.ClusterTx, projectName string, config map[string]
 			fallthrough
 		case "limits.cpu":
 			fallthrough
+		case "limits.disk":
+			fallthrough
 		case "limits.memory":
 			aggregateKeys = append(aggregateKeys, key)
 
@@ -621,6 +869,48 @@ func AllowProjectUpdate(tx *db.ClusterTx, projectName string, config map[string]
 	return nil
 }
 
+// Check that the networks of the project comply with the new
+// limits.networks and restricted.networks.subnets values.
+func validateNetworks(tx *db.ClusterTx, projectName string, config map[string]string, key string) error {
+	networks, err := tx.GetProjectNetworksConfig(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project networks from database")
+	}
+
+	if key == "limits.networks" {
+		if config[key] == "" {
+			return nil
+		}
+
+		limit, err := strconv.Atoi(config[key])
+		if err != nil {
+			return err
+		}
+
+		if limit < len(networks) {
+			return fmt.Errorf("'%s' is too low: there currently are %d networks in project %s", key, len(networks), projectName)
+		}
+
+		return nil
+	}
+
+	project := &api.Project{
+		Name: projectName,
+		ProjectPut: api.ProjectPut{
+			Config: config,
+		},
+	}
+
+	for name, networkConfig := range networks {
+		err := checkNetworkRestrictions(project, name, networkConfig)
+		if err != nil {
+			return errors.Wrapf(err, "Conflict detected when changing %q in project %q", key, projectName)
+		}
+	}
+
+	return nil
+}
+
 // Check that limits.containers or limits.virtual-machines is equal or above
 // the current count.
 func validateInstanceCountLimit(instances []db.Instance, key, value, project string) error {
@@ -714,7 +1004,7 @@ func fetchProject(tx *db.ClusterTx, projectName string, skipIfNoLimits bool) (*a
 		profilesFilter.Project = Default
 	}
 
-	profiles, err := tx.GetProfiles(profilesFilter)
+	profiles, err := tx.GetProfilesWithParents(profilesFilter)
 	if err != nil {
 		return nil, nil, nil, errors.Wrap(err, "Fetch profiles from database")
 	}
@@ -727,23 +1017,141 @@ func fetchProject(tx *db.ClusterTx, projectName string, skipIfNoLimits bool) (*a
 	return project, profiles, instances, nil
 }
 
+// GetCurrentAllocations returns the current usage of the resources of the given project, along with the limits
+// set on them (-1 when unlimited). Instances which don't set a given limit don't count towards its usage.
+func GetCurrentAllocations(tx *db.ClusterTx, projectName string) (map[string]api.ProjectStateResource, error) {
+	project, profiles, instances, err := fetchProject(tx, projectName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	instances = expandInstancesConfigAndDevices(instances, profiles)
+
+	// Parse the limit set on the project for the given key.
+	getLimit := func(key string, parser func(string) (int64, error)) (int64, error) {
+		value := project.Config[key]
+		if value == "" {
+			return -1, nil
+		}
+
+		limit, err := parser(value)
+		if err != nil {
+			return -1, errors.Wrapf(err, "Parse %q of project %s", key, projectName)
+		}
+
+		return limit, nil
+	}
+
+	parseCount := func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	}
+
+	result := map[string]api.ProjectStateResource{}
+
+	// Instance counts.
+	counts := map[string]int64{"containers": 0, "virtual-machines": 0}
+	for _, instance := range instances {
+		switch instance.Type {
+		case instancetype.Container:
+			counts["containers"]++
+		case instancetype.VM:
+			counts["virtual-machines"]++
+		}
+	}
+
+	for name, count := range counts {
+		limit, err := getLimit(fmt.Sprintf("limits.%s", name), parseCount)
+		if err != nil {
+			return nil, err
+		}
+
+		result[name] = api.ProjectStateResource{Limit: limit, Usage: count}
+	}
+
+	result["instances"] = api.ProjectStateResource{Limit: -1, Usage: int64(len(instances))}
+
+	// Aggregate limits.
+	for _, key := range allAggregateLimits {
+		limit, err := getLimit(key, aggregateLimitConfigValueParsers[key])
+		if err != nil {
+			return nil, err
+		}
+
+		resource := api.ProjectStateResource{Limit: limit}
+		for _, instance := range instances {
+			limits, err := getInstanceLimits(instance, []string{key})
+			if err != nil {
+				continue
+			}
+
+			resource.Usage += limits[key]
+		}
+
+		result[strings.TrimPrefix(key, "limits.")] = resource
+	}
+
+	// Root disk sizes by storage pool.
+	for _, instance := range instances {
+		_, rootDisk, err := shared.GetRootDiskDevice(instance.Devices)
+		if err != nil || rootDisk["pool"] == "" || rootDisk["size"] == "" {
+			continue
+		}
+
+		size, err := units.ParseByteSizeString(rootDisk["size"])
+		if err != nil {
+			continue
+		}
+
+		name := fmt.Sprintf("disk.pool.%s", rootDisk["pool"])
+		resource, ok := result[name]
+		if !ok {
+			resource.Limit = -1
+		}
+
+		resource.Usage += size
+		result[name] = resource
+	}
+
+	// Networks.
+	networks, err := tx.GetProjectNetworksConfig(projectName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch project networks from database")
+	}
+
+	limit, err := getLimit("limits.networks", parseCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result["networks"] = api.ProjectStateResource{Limit: limit, Usage: int64(len(networks))}
+
+	return result, nil
+}
+
 // Expand the configuration and devices of the given instances, taking the give
 // project profiles into account.
 func expandInstancesConfigAndDevices(instances []db.Instance, profiles []db.Profile) []db.Instance {
 	expandedInstances := make([]db.Instance, len(instances))
 
 	// Index of all profiles by name.
-	profilesByName := map[string]db.Profile{}
+	profilesByName := map[string]api.Profile{}
 	for _, profile := range profiles {
-		profilesByName[profile.Name] = profile
+		profilesByName[profile.Name] = *db.ProfileToAPI(&profile)
 	}
 
 	for i, instance := range instances {
 		profiles := make([]api.Profile, len(instance.Profiles))
 
 		for j, name := range instance.Profiles {
-			profile := profilesByName[name]
-			profiles[j] = *db.ProfileToAPI(&profile)
+			// Apply the config and devices of the parents, which were
+			// checked when the profiles got updated.
+			profile, _, err := db.ExpandProfile(name, profilesByName)
+			if err != nil {
+				profiles[j] = profilesByName[name]
+				continue
+			}
+
+			profiles[j] = *profile
 		}
 
 		expandedInstances[i] = instance
@@ -785,11 +1193,25 @@ func getInstanceLimits(instance db.Instance, keys []string) (map[string]int64, e
 	limits := map[string]int64{}
 
 	for _, key := range keys {
-		value, ok := instance.Config[key]
-		if !ok || value == "" {
-			return nil, fmt.Errorf(
-				"Instance %s in project %s has no '%s' config, either directly or via a profile",
-				instance.Name, instance.Project, key)
+		var value string
+		if key == "limits.disk" {
+			// The disk limit applies to the size of the root disk.
+			_, rootDisk, err := shared.GetRootDiskDevice(instance.Devices)
+			if err != nil || rootDisk["size"] == "" {
+				return nil, fmt.Errorf(
+					"Instance %s in project %s has no 'size' on its root disk device, either directly or via a profile",
+					instance.Name, instance.Project)
+			}
+
+			value = rootDisk["size"]
+		} else {
+			var ok bool
+			value, ok = instance.Config[key]
+			if !ok || value == "" {
+				return nil, fmt.Errorf(
+					"Instance %s in project %s has no '%s' config, either directly or via a profile",
+					instance.Name, instance.Project, key)
+			}
 		}
 
 		parser := aggregateLimitConfigValueParsers[key]
@@ -813,6 +1235,9 @@ var aggregateLimitConfigValueParsers = map[string]func(string) (int64, error){
 		}
 		return units.ParseByteSizeString(value)
 	},
+	"limits.disk": func(value string) (int64, error) {
+		return units.ParseByteSizeString(value)
+	},
 	"limits.processes": func(value string) (int64, error) {
 		limit, err := strconv.Atoi(value)
 		if err != nil {
@@ -838,6 +1263,9 @@ var aggregateLimitConfigValuePrinters = map[string]func(int64) string{
 	"limits.memory": func(limit int64) string {
 		return units.GetByteSizeString(limit, 1)
 	},
+	"limits.disk": func(limit int64) string {
+		return units.GetByteSizeString(limit, 1)
+	},
 	"limits.processes": func(limit int64) string {
 		return fmt.Sprintf("%d", limit)
 	},
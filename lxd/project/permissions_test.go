@@ -124,3 +124,195 @@ func TestAllowInstanceCreation_DifferentType(t *testing.T) {
 	err = project.AllowInstanceCreation(tx, "p1", req)
 	assert.NoError(t, err)
 }
+
+// If a disk limit is configured, the sum of the root disk sizes can't exceed it.
+func TestAllowInstanceCreation_DiskAbove(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"limits.disk": "10GB",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.CreateInstance(db.Instance{
+		Project:      "p1",
+		Name:         "c1",
+		Type:         instancetype.Container,
+		Architecture: 1,
+		Node:         "none",
+		Devices: map[string]map[string]string{
+			"root": {"type": "disk", "path": "/", "pool": "default", "size": "6GB"},
+		},
+	})
+	require.NoError(t, err)
+
+	req := api.InstancesPost{
+		Name: "c2",
+		Type: api.InstanceTypeContainer,
+		InstancePut: api.InstancePut{
+			Devices: map[string]map[string]string{
+				"root": {"type": "disk", "path": "/", "pool": "default", "size": "6GB"},
+			},
+		},
+	}
+
+	err = project.AllowInstanceCreation(tx, "p1", req)
+	assert.EqualError(t, err, `Reached maximum aggregate value 10GB for "limits.disk" in project p1`)
+
+	req.Devices["root"]["size"] = "4GB"
+	err = project.AllowInstanceCreation(tx, "p1", req)
+	assert.NoError(t, err)
+}
+
+// If restricted.networks.access is set, only the listed networks can be used by NICs.
+func TestAllowInstanceCreation_RestrictedNetworkAccess(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"restricted":                 "true",
+				"restricted.networks.access": "tenant1, tenant2",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	req := api.InstancesPost{
+		Name: "c1",
+		Type: api.InstanceTypeContainer,
+		InstancePut: api.InstancePut{
+			Devices: map[string]map[string]string{
+				"eth0": {"type": "nic", "network": "lxdbr0"},
+			},
+		},
+	}
+
+	err = project.AllowInstanceCreation(tx, "p1", req)
+	assert.EqualError(t, err, `Invalid device "eth0" on instance "c1" of project "p1": Network "lxdbr0" isn't allowed in this project`)
+
+	req.Devices["eth0"]["network"] = "tenant2"
+	err = project.AllowInstanceCreation(tx, "p1", req)
+	assert.NoError(t, err)
+}
+
+// If a network limit is configured and it matches the current number of networks, the check fails.
+func TestAllowNetworkCreation_Above(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"limits.networks": "1",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = tx.CreatePendingNetwork("none", "p1", "net1", db.NetworkTypeBridge, map[string]string{})
+	require.NoError(t, err)
+
+	err = project.AllowNetworkCreation(tx, "p1", "net2", map[string]string{})
+	assert.EqualError(t, err, "Reached maximum number of networks in project p1")
+
+	// Networks of other projects aren't counted.
+	err = project.AllowNetworkCreation(tx, "default", "net2", map[string]string{})
+	assert.NoError(t, err)
+}
+
+// If the project restricts the subnets networks can use, only networks within them pass the check.
+func TestAllowNetworkCreation_RestrictedSubnets(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"restricted":                  "true",
+				"restricted.networks.subnets": "10.10.0.0/16,fd42:10::/48",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = project.AllowNetworkCreation(tx, "p1", "net1", map[string]string{
+		"ipv4.address": "10.10.1.1/24",
+		"ipv6.address": "fd42:10:0:1::1/64",
+	})
+	assert.NoError(t, err)
+
+	err = project.AllowNetworkCreation(tx, "p1", "net1", map[string]string{
+		"ipv4.address": "10.20.1.1/24",
+		"ipv6.address": "none",
+	})
+	assert.EqualError(t, err, "Subnet '10.20.1.0/24' of network net1 is not within the subnets allowed in project p1")
+
+	err = project.AllowNetworkCreation(tx, "p1", "net1", map[string]string{
+		"ipv4.address": "10.10.1.1/24",
+		"ipv4.routes":  "10.0.0.0/8",
+	})
+	assert.Error(t, err)
+}
+
+// The current allocations of a project are reported along with their limits.
+func TestGetCurrentAllocations(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"limits.containers": "5",
+				"limits.memory":     "4GB",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.CreateInstance(db.Instance{
+		Project:      "p1",
+		Name:         "c1",
+		Type:         instancetype.Container,
+		Architecture: 1,
+		Node:         "none",
+		Config:       map[string]string{"limits.memory": "1GB", "limits.cpu": "2"},
+		Devices: map[string]map[string]string{
+			"root": {"type": "disk", "path": "/", "pool": "default", "size": "6GB"},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.CreateInstance(db.Instance{
+		Project:      "p1",
+		Name:         "v1",
+		Type:         instancetype.VM,
+		Architecture: 1,
+		Node:         "none",
+		Config:       map[string]string{"limits.memory": "2GB"},
+	})
+	require.NoError(t, err)
+
+	resources, err := project.GetCurrentAllocations(tx, "p1")
+	require.NoError(t, err)
+
+	assert.Equal(t, api.ProjectStateResource{Limit: 5, Usage: 1}, resources["containers"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 1}, resources["virtual-machines"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 2}, resources["instances"])
+	assert.Equal(t, api.ProjectStateResource{Limit: 4000000000, Usage: 3000000000}, resources["memory"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 2}, resources["cpu"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 6000000000}, resources["disk"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 6000000000}, resources["disk.pool.default"])
+	assert.Equal(t, api.ProjectStateResource{Limit: -1, Usage: 0}, resources["networks"])
+}
@@ -2,6 +2,7 @@ package project
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -98,3 +99,159 @@ func StorageVolumeProject(c *db.Cluster, projectName string, volumeType int) (st
 
 	return Default, nil
 }
+
+// NetworkProject returns the name of the project owning the network with the given name as seen from the
+// specified project. Projects with the "features.networks" flag enabled only see the networks created in them,
+// while all the other projects share the networks which weren't created in such a project. Returns
+// db.ErrNoSuchObject if no such network can be seen from the project.
+func NetworkProject(tx *db.ClusterTx, projectName string, networkName string) (string, error) {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to load project %q", projectName)
+	}
+
+	networkProjects, err := tx.GetNetworkProjects(networkName)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to load projects of network %q", networkName)
+	}
+
+	if shared.IsTrue(project.Config["features.networks"]) {
+		if shared.StringInSlice(projectName, networkProjects) {
+			return projectName, nil
+		}
+
+		return "", db.ErrNoSuchObject
+	}
+
+	if shared.StringInSlice(projectName, networkProjects) {
+		return projectName, nil
+	}
+
+	for _, networkProjectName := range networkProjects {
+		networkProject, err := tx.GetProject(networkProjectName)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to load project %q", networkProjectName)
+		}
+
+		if !shared.IsTrue(networkProject.Config["features.networks"]) {
+			return networkProjectName, nil
+		}
+	}
+
+	return "", db.ErrNoSuchObject
+}
+
+// NetworkVisible returns whether the network with the given name can be seen and used from the specified project.
+// Unmanaged host interfaces are only visible from projects without the "features.networks" flag.
+func NetworkVisible(tx *db.ClusterTx, projectName string, networkName string) (bool, error) {
+	_, err := NetworkProject(tx, projectName, networkName)
+	if err == nil {
+		return true, nil
+	}
+
+	if err != db.ErrNoSuchObject {
+		return false, err
+	}
+
+	networkProjects, err := tx.GetNetworkProjects(networkName)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to load projects of network %q", networkName)
+	}
+
+	// A managed network exists but isn't visible from this project.
+	if len(networkProjects) > 0 {
+		return false, nil
+	}
+
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to load project %q", projectName)
+	}
+
+	return !shared.IsTrue(project.Config["features.networks"]), nil
+}
+
+// InstanceNameAllowed returns an error if the given instance name doesn't comply with the naming policy set by the
+// "instances.name.prefix", "instances.name.suffix" and "instances.name.pattern" keys of the project config.
+func InstanceNameAllowed(config map[string]string, name string) error {
+	prefix := config["instances.name.prefix"]
+	if prefix != "" && !strings.HasPrefix(name, prefix) {
+		return fmt.Errorf("Instance name %q must start with %q", name, prefix)
+	}
+
+	suffix := config["instances.name.suffix"]
+	if suffix != "" && !strings.HasSuffix(name, suffix) {
+		return fmt.Errorf("Instance name %q must end with %q", name, suffix)
+	}
+
+	pattern := config["instances.name.pattern"]
+	if pattern != "" {
+		// The whole name must match the pattern.
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+		if err != nil {
+			return errors.Wrapf(err, "Invalid instance name pattern %q", pattern)
+		}
+
+		if !re.MatchString(name) {
+			return fmt.Errorf("Instance name %q doesn't match the pattern %q", name, pattern)
+		}
+	}
+
+	return nil
+}
+
+// UserSchemaKeyValid returns an error if the given "schema.user.<key>.type" or "schema.user.<key>.values" project
+// config key or its value are invalid.
+func UserSchemaKeyValid(key string, value string) error {
+	name := strings.TrimPrefix(key, "schema.user.")
+
+	switch {
+	case strings.HasSuffix(name, ".type") && len(name) > len(".type"):
+		return shared.IsOneOf(value, []string{"string", "integer", "boolean"})
+	case strings.HasSuffix(name, ".values") && len(name) > len(".values"):
+		return nil
+	}
+
+	return fmt.Errorf("Invalid project configuration key: %s", key)
+}
+
+// UserConfigAllowed returns an error if any of the given "user.*" config keys doesn't comply with the schema
+// registered for it by the "schema.user.<key>.type" and "schema.user.<key>.values" keys of the project config.
+// Keys which are unset or whose value is the same as in the current config aren't checked.
+func UserConfigAllowed(projectConfig map[string]string, config map[string]string, currentConfig map[string]string) error {
+	for key, value := range config {
+		if !strings.HasPrefix(key, "user.") || value == "" || currentConfig[key] == value {
+			continue
+		}
+
+		schemaKey := fmt.Sprintf("schema.%s", key)
+
+		var err error
+		switch projectConfig[schemaKey+".type"] {
+		case "integer":
+			err = shared.IsInt64(value)
+		case "boolean":
+			err = shared.IsBool(value)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Invalid value for %q", key)
+		}
+
+		values := projectConfig[schemaKey+".values"]
+		if values == "" {
+			continue
+		}
+
+		allowed := []string{}
+		for _, v := range strings.Split(values, ",") {
+			allowed = append(allowed, strings.TrimSpace(v))
+		}
+
+		if !shared.StringInSlice(value, allowed) {
+			return fmt.Errorf("Invalid value for %q: %q isn't one of %s", key, value, strings.Join(allowed, ", "))
+		}
+	}
+
+	return nil
+}
@@ -44,3 +44,42 @@ func ExampleStorageVolume() {
 	// Output: default_test
 	// project_name_test1
 }
+
+func ExampleInstanceNameAllowed() {
+	config := map[string]string{
+		"instances.name.prefix":  "web-",
+		"instances.name.suffix":  "-prod",
+		"instances.name.pattern": "[a-z]+-[0-9]+-[a-z]+",
+	}
+
+	fmt.Println(project.InstanceNameAllowed(config, "web-01-prod"))
+	fmt.Println(project.InstanceNameAllowed(config, "db-01-prod"))
+	fmt.Println(project.InstanceNameAllowed(config, "web-01-dev"))
+	fmt.Println(project.InstanceNameAllowed(config, "web-a1-prod"))
+	fmt.Println(project.InstanceNameAllowed(map[string]string{}, "anything"))
+
+	// Output: <nil>
+	// Instance name "db-01-prod" must start with "web-"
+	// Instance name "web-01-dev" must end with "-prod"
+	// Instance name "web-a1-prod" doesn't match the pattern "[a-z]+-[0-9]+-[a-z]+"
+	// <nil>
+}
+
+func ExampleUserConfigAllowed() {
+	projectConfig := map[string]string{
+		"schema.user.team.values":   "web, db",
+		"schema.user.replicas.type": "integer",
+	}
+
+	fmt.Println(project.UserConfigAllowed(projectConfig, map[string]string{"user.team": "web", "user.replicas": "3"}, nil))
+	fmt.Println(project.UserConfigAllowed(projectConfig, map[string]string{"user.team": "ops"}, nil))
+	fmt.Println(project.UserConfigAllowed(projectConfig, map[string]string{"user.replicas": "three"}, nil))
+	fmt.Println(project.UserConfigAllowed(projectConfig, map[string]string{"user.team": "ops"}, map[string]string{"user.team": "ops"}))
+	fmt.Println(project.UserConfigAllowed(projectConfig, map[string]string{"user.other": "anything"}, nil))
+
+	// Output: <nil>
+	// Invalid value for "user.team": "ops" isn't one of web, db
+	// Invalid value for "user.replicas": Invalid value for an integer: three
+	// <nil>
+	// <nil>
+}
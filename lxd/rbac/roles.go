@@ -0,0 +1,15 @@
+package rbac
+
+// ProjectRoles are the built-in roles which can be granted on a project to a TLS client certificate, along with
+// the permissions they give. They use the same permissions as the external RBAC service.
+var ProjectRoles = map[string][]string{
+	// Read-only access to the project.
+	"viewer": {"view"},
+
+	// Can also start, stop, exec into and snapshot the instances.
+	"operator": {"view", "operate-containers"},
+
+	// Full control over the content of the project. The restrictions, limits and features of the project
+	// itself can only be changed by a server administrator.
+	"admin": {"view", "operate-containers", "manage-containers", "manage-images", "manage-profiles", "manage-storage-volumes", "manage-projects"},
+}
@@ -416,5 +416,38 @@ func GetCPU() (*api.ResourcesCPU, error) {
 
 	cpu.Architecture = strings.TrimRight(string(uname.Machine[:]), "\x00")
 
+	// Get the load average
+	loadAverage, err := cpuGetLoadAverage()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get load average")
+	}
+
+	cpu.LoadAverage = loadAverage
+
 	return &cpu, nil
 }
+
+// cpuGetLoadAverage returns the 1, 5 and 15 minutes load averages from /proc/loadavg.
+func cpuGetLoadAverage() ([]float64, error) {
+	content, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read /proc/loadavg")
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("Invalid /proc/loadavg content: %q", string(content))
+	}
+
+	loadAverage := []float64{}
+	for _, field := range fields[:3] {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse load average %q", field)
+		}
+
+		loadAverage = append(loadAverage, value)
+	}
+
+	return loadAverage, nil
+}
@@ -0,0 +1,170 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetGPUUsage returns the GPU usage of the given processes, keyed by the PCI address of the GPU cards they use.
+// The usage is read from the DRM fdinfo of the processes and, for NVIDIA cards, from nvidia-smi.
+func GetGPUUsage(pids []int64) (map[string]api.InstanceStateGPU, error) {
+	usage := map[string]api.InstanceStateGPU{}
+	processes := map[string]map[int64]struct{}{}
+
+	addProcess := func(pciAddress string, pid int64) {
+		_, ok := processes[pciAddress]
+		if !ok {
+			processes[pciAddress] = map[int64]struct{}{}
+		}
+
+		processes[pciAddress][pid] = struct{}{}
+	}
+
+	// DRM clients are only counted once, as a process can have several file descriptors on the same client.
+	drmClients := map[string]struct{}{}
+	for _, pid := range pids {
+		fdinfoPath := fmt.Sprintf("/proc/%d/fdinfo", pid)
+		entries, err := ioutil.ReadDir(fdinfoPath)
+		if err != nil {
+			// The process exited or its file descriptors can't be inspected.
+			continue
+		}
+
+		for _, entry := range entries {
+			content, err := ioutil.ReadFile(filepath.Join(fdinfoPath, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			fields := gpuParseFdinfo(content)
+			pciAddress := fields["drm-pdev"]
+			if pciAddress == "" || fields["drm-client-id"] == "" {
+				continue
+			}
+
+			client := fmt.Sprintf("%s/%s", pciAddress, fields["drm-client-id"])
+			_, ok := drmClients[client]
+			if ok {
+				continue
+			}
+
+			drmClients[client] = struct{}{}
+			addProcess(pciAddress, pid)
+
+			gpu := usage[pciAddress]
+			for key, value := range fields {
+				if strings.HasPrefix(key, "drm-memory-") {
+					gpu.MemoryUsage += gpuParseFdinfoSize(value)
+				}
+			}
+
+			usage[pciAddress] = gpu
+		}
+	}
+
+	// NVIDIA cards don't report their usage through DRM fdinfo.
+	_, err := exec.LookPath("nvidia-smi")
+	if err == nil {
+		out, err := exec.Command("nvidia-smi", "--query-compute-apps=pid,gpu_bus_id,used_memory", "--format=csv,noheader,nounits").Output()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to query NVIDIA GPU processes")
+		}
+
+		wantedPids := map[int64]struct{}{}
+		for _, pid := range pids {
+			wantedPids[pid] = struct{}{}
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), ",")
+			if len(fields) != 3 {
+				continue
+			}
+
+			pid, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			_, ok := wantedPids[pid]
+			if !ok {
+				continue
+			}
+
+			// nvidia-smi reports 8 digit PCI domains.
+			pciAddress := strings.ToLower(strings.TrimSpace(fields[1]))
+			parts := strings.SplitN(pciAddress, ":", 2)
+			if len(parts) == 2 && len(parts[0]) > 4 {
+				pciAddress = fmt.Sprintf("%s:%s", parts[0][len(parts[0])-4:], parts[1])
+			}
+
+			addProcess(pciAddress, pid)
+
+			gpu := usage[pciAddress]
+			memory, err := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+			if err == nil {
+				gpu.MemoryUsage += memory * 1024 * 1024
+			}
+
+			usage[pciAddress] = gpu
+		}
+	}
+
+	for pciAddress, pids := range processes {
+		gpu := usage[pciAddress]
+		gpu.Processes = int64(len(pids))
+		usage[pciAddress] = gpu
+	}
+
+	return usage, nil
+}
+
+// gpuParseFdinfo returns the "key: value" fields of a fdinfo file.
+func gpuParseFdinfo(content []byte) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		fields[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return fields
+}
+
+// gpuParseFdinfoSize converts a DRM fdinfo memory size (in bytes, or with a KiB or MiB unit) to bytes.
+func gpuParseFdinfoSize(value string) int64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "KiB":
+			size *= 1024
+		case "MiB":
+			size *= 1024 * 1024
+		}
+	}
+
+	return size
+}
@@ -74,3 +74,25 @@ func forwardedResponseIfVolumeIsRemote(d *Daemon, r *http.Request, poolID int64,
 	}
 	return response.ForwardedResponse(client, r)
 }
+
+// forwardedResponseIfNetworkForwardIsRemote redirects a request to the node
+// the network address forward or load balancer with the given location is
+// applied on. If it is local, nothing gets done and nil is returned.
+func forwardedResponseIfNetworkForwardIsRemote(d *Daemon, r *http.Request, location string) response.Response {
+	address, err := cluster.ResolveTarget(d.cluster, location)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address == "" {
+		return nil
+	}
+
+	cert := d.endpoints.NetworkCert()
+	client, err := cluster.Connect(address, cert, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ForwardedResponse(client, r)
+}
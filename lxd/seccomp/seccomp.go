@@ -4,12 +4,15 @@
 package seccomp
 
 import (
+	"bytes"
+	"debug/elf"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -120,69 +123,73 @@ struct lxd_seccomp_data_arch {
 	int nr_mknodat;
 	int nr_setxattr;
 	int nr_mount;
+	int nr_finit_module;
+	int nr_init_module;
 };
 
 #define LXD_SECCOMP_NOTIFY_MKNOD    0
 #define LXD_SECCOMP_NOTIFY_MKNODAT  1
 #define LXD_SECCOMP_NOTIFY_SETXATTR 2
 #define LXD_SECCOMP_NOTIFY_MOUNT 3
+#define LXD_SECCOMP_NOTIFY_FINIT_MODULE 4
+#define LXD_SECCOMP_NOTIFY_INIT_MODULE 5
 
 // ordered by likelihood of usage...
 static const struct lxd_seccomp_data_arch seccomp_notify_syscall_table[] = {
-	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT },
+	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT, LXD_SECCOMP_NOTIFY_FINIT_MODULE, LXD_SECCOMP_NOTIFY_INIT_MODULE },
 #ifdef AUDIT_ARCH_X86_64
-	{ AUDIT_ARCH_X86_64,      133, 259, 188, 165 },
+	{ AUDIT_ARCH_X86_64,      133, 259, 188, 165,  313,  175 },
 #endif
 #ifdef AUDIT_ARCH_I386
-	{ AUDIT_ARCH_I386,         14, 297, 226,  21 },
+	{ AUDIT_ARCH_I386,         14, 297, 226,  21,  350,  128 },
 #endif
 #ifdef AUDIT_ARCH_AARCH64
-	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21 },
+	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21,  273,  105 },
 #endif
 #ifdef AUDIT_ARCH_ARM
-	{ AUDIT_ARCH_ARM,          14, 324, 226,  21 },
+	{ AUDIT_ARCH_ARM,          14, 324, 226,  21,  379,  128 },
 #endif
 #ifdef AUDIT_ARCH_ARMEB
-	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21 },
+	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21,  379,  128 },
 #endif
 #ifdef AUDIT_ARCH_S390
-	{ AUDIT_ARCH_S390,         14, 290, 224,  21 },
+	{ AUDIT_ARCH_S390,         14, 290, 224,  21,  344,  128 },
 #endif
 #ifdef AUDIT_ARCH_S390X
-	{ AUDIT_ARCH_S390X,        14, 290, 224,  21 },
+	{ AUDIT_ARCH_S390X,        14, 290, 224,  21,  344,  128 },
 #endif
 #ifdef AUDIT_ARCH_PPC
-	{ AUDIT_ARCH_PPC,          14, 288, 209,  21 },
+	{ AUDIT_ARCH_PPC,          14, 288, 209,  21,  353,  128 },
 #endif
 #ifdef AUDIT_ARCH_PPC64
-	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21 },
+	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21,  353,  128 },
 #endif
 #ifdef AUDIT_ARCH_PPC64LE
-	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21 },
+	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21,  353,  128 },
 #endif
 #ifdef AUDIT_ARCH_SPARC
-	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167 },
+	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167,  342,  188 },
 #endif
 #ifdef AUDIT_ARCH_SPARC64
-	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167 },
+	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167,  342,  188 },
 #endif
 #ifdef AUDIT_ARCH_MIPS
-	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21 },
+	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21, 4348, 4128 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL
-	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21 },
+	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21, 4348, 4128 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64
-	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160 },
+	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160, 5307, 5168 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64N32
-	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160 },
+	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160, 6312, 6168 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64
-	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160 },
+	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160, 5307, 5168 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64N32
-	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160 },
+	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160, 6312, 6168 },
 #endif
 };
 
@@ -214,6 +221,12 @@ static int seccomp_notify_get_syscall(struct seccomp_notif *req,
 		if (entry->nr_mount == req->data.nr)
 			return LXD_SECCOMP_NOTIFY_MOUNT;
 
+		if (entry->nr_finit_module == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_FINIT_MODULE;
+
+		if (entry->nr_init_module == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_INIT_MODULE;
+
 		break;
 	}
 
@@ -256,6 +269,8 @@ const lxdSeccompNotifyMknod = C.LXD_SECCOMP_NOTIFY_MKNOD
 const lxdSeccompNotifyMknodat = C.LXD_SECCOMP_NOTIFY_MKNODAT
 const lxdSeccompNotifySetxattr = C.LXD_SECCOMP_NOTIFY_SETXATTR
 const lxdSeccompNotifyMount = C.LXD_SECCOMP_NOTIFY_MOUNT
+const lxdSeccompNotifyFinitModule = C.LXD_SECCOMP_NOTIFY_FINIT_MODULE
+const lxdSeccompNotifyInitModule = C.LXD_SECCOMP_NOTIFY_INIT_MODULE
 
 const seccompHeader = `2
 `
@@ -324,6 +339,10 @@ move_mount errno 38
 const seccompNotifyMount = `mount notify [3,0,SCMP_CMP_MASKED_EQ,18446744070422410016]
 `
 
+const seccompNotifyKernelModules = `init_module notify
+finit_module notify
+`
+
 const compatBlockingPolicy = `[%s]
 compat_sys_rt_sigaction errno 38
 stub_x32_rt_sigreturn errno 38
@@ -417,6 +436,10 @@ func InstanceNeedsPolicy(c Instance) bool {
 		}
 	}
 
+	if config["linux.kernel_modules.load"] == "ondemand" {
+		return true
+	}
+
 	// Check for boolean keys that default to true
 	value, ok := config["security.syscalls.deny_default"]
 	if !ok {
@@ -462,6 +485,14 @@ func InstanceNeedsIntercept(s *state.State, c Instance) (bool, error) {
 		needed = true
 	}
 
+	if config["linux.kernel_modules.load"] == "ondemand" {
+		if !lxcSupportSeccompNotify(s) {
+			return needed, fmt.Errorf("System doesn't support syscall interception")
+		}
+
+		needed = true
+	}
+
 	return needed, nil
 }
 
@@ -543,6 +574,10 @@ func seccompGetPolicyContent(s *state.State, c Instance) (string, error) {
 			// multiple syscalls.
 			policy += seccompBlockNewMountAPI
 		}
+
+		if config["linux.kernel_modules.load"] == "ondemand" {
+			policy += seccompNotifyKernelModules
+		}
 	}
 
 	if allowlist != "" {
@@ -622,6 +657,9 @@ type Iovec struct {
 	resp   *C.struct_seccomp_notif_resp
 	cookie *C.char
 	iov    *C.struct_iovec
+
+	// audit holds details about the system call added to its audit event.
+	audit map[string]interface{}
 }
 
 // NewSeccompIovec creates a new seccomp iovec.
@@ -1575,19 +1613,206 @@ func (s *Server) HandleMountSyscall(c Instance, siov *Iovec) int {
 	return 0
 }
 
+// kernelModuleName returns the name of the kernel module stored in the given file, with the dashes the module
+// tools accept in its place replaced by underscores.
+func kernelModuleName(path string) string {
+	name := filepath.Base(path)
+	idx := strings.Index(name, ".ko")
+	if idx > 0 {
+		name = name[:idx]
+	}
+
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// kernelModuleAllowed returns whether the given module is part of the linux.kernel_modules list of the instance.
+func kernelModuleAllowed(config map[string]string, module string) bool {
+	for _, allowed := range strings.Split(config["linux.kernel_modules"], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed != "" && strings.Replace(allowed, "-", "_", -1) == module {
+			return true
+		}
+	}
+
+	return false
+}
+
+// kernelModuleImageMaxSize is the largest kernel module image read from the memory of an init_module caller.
+const kernelModuleImageMaxSize = 64 * 1024 * 1024
+
+// kernelModuleImageName returns the name of the kernel module in the given ELF image as recorded in its
+// .modinfo section, with dashes replaced by underscores.
+func kernelModuleImageName(image []byte) (string, error) {
+	f, err := elf.NewFile(bytes.NewReader(image))
+	if err != nil {
+		return "", err
+	}
+
+	section := f.Section(".modinfo")
+	if section == nil {
+		return "", fmt.Errorf("Missing .modinfo section")
+	}
+
+	data, err := section.Data()
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if bytes.HasPrefix(entry, []byte("name=")) {
+			return strings.Replace(string(entry[len("name="):]), "-", "_", -1), nil
+		}
+	}
+
+	return "", fmt.Errorf("Missing module name in .modinfo section")
+}
+
+// loadKernelModule loads the module on the host if it's part of the linux.kernel_modules list of the instance,
+// refusing it otherwise.
+func (s *Server) loadKernelModule(c Instance, siov *Iovec, module string, ctx log.Ctx) int {
+	ctx["module"] = module
+	siov.audit = map[string]interface{}{"module": module}
+
+	if !kernelModuleAllowed(c.ExpandedConfig(), module) {
+		ctx["err"] = "Kernel module not allowed"
+		logger.Warn("Refused loading kernel module", log.Ctx{"project": c.Project(), "instance": c.Name(), "module": module})
+		return int(-C.EPERM)
+	}
+
+	err := util.LoadModule(module)
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to load kernel module: %s", err)
+		return int(-C.EPERM)
+	}
+
+	return 0
+}
+
+// HandleInitModuleSyscall handles init_module syscalls like finit_module ones. Module tools use it for the
+// compressed modules they decompress themselves (.ko.xz or .ko.zst), so the module is identified by the name
+// recorded in the image passed by the process.
+func (s *Server) HandleInitModuleSyscall(c Instance, siov *Iovec) int {
+	ctx := log.Ctx{"container": c.Name(),
+		"project":              c.Project(),
+		"syscall_number":       siov.req.data.nr,
+		"audit_architecture":   siov.req.data.arch,
+		"seccomp_notify_id":    siov.req.id,
+		"seccomp_notify_flags": siov.req.flags,
+	}
+
+	defer logger.Debug("Handling init_module syscall", ctx)
+
+	// unsigned long len
+	size := uint64(siov.req.data.args[1])
+	if size == 0 || size > kernelModuleImageMaxSize {
+		ctx["err"] = fmt.Sprintf("Invalid kernel module image size: %d", size)
+		return int(-C.EINVAL)
+	}
+
+	// void *module_image
+	image := make([]byte, size)
+	n, err := C.pread(C.int(siov.memFd), unsafe.Pointer(&image[0]), C.size_t(size), C.off_t(siov.req.data.args[0]))
+	if err != nil || uint64(n) != size {
+		ctx["err"] = fmt.Sprintf("Failed to read kernel module image: %v", err)
+		return int(-C.EFAULT)
+	}
+
+	module, err := kernelModuleImageName(image)
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to get kernel module name: %s", err)
+		return int(-C.ENOEXEC)
+	}
+
+	return s.loadKernelModule(c, siov, module, ctx)
+}
+
+// HandleFinitModuleSyscall handles finit_module syscalls by loading the module on the host if it's part of
+// the linux.kernel_modules list of the instance, refusing it otherwise.
+func (s *Server) HandleFinitModuleSyscall(c Instance, siov *Iovec) int {
+	ctx := log.Ctx{"container": c.Name(),
+		"project":              c.Project(),
+		"syscall_number":       siov.req.data.nr,
+		"audit_architecture":   siov.req.data.arch,
+		"seccomp_notify_id":    siov.req.id,
+		"seccomp_notify_flags": siov.req.flags,
+	}
+
+	defer logger.Debug("Handling finit_module syscall", ctx)
+
+	// The module file is only known through the file descriptor passed by the process.
+	modulePath, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", siov.req.pid, int(siov.req.data.args[0])))
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to resolve module file descriptor: %s", err)
+		return int(-C.EBADF)
+	}
+
+	return s.loadKernelModule(c, siov, kernelModuleName(modulePath), ctx)
+}
+
 func (s *Server) handleSyscall(c Instance, siov *Iovec) int {
+	var syscall string
+	var errno int
+
 	switch int(C.seccomp_notify_get_syscall(siov.req, siov.resp)) {
 	case lxdSeccompNotifyMknod:
-		return s.HandleMknodSyscall(c, siov)
+		syscall = "mknod"
+		errno = s.HandleMknodSyscall(c, siov)
 	case lxdSeccompNotifyMknodat:
-		return s.HandleMknodatSyscall(c, siov)
+		syscall = "mknodat"
+		errno = s.HandleMknodatSyscall(c, siov)
 	case lxdSeccompNotifySetxattr:
-		return s.HandleSetxattrSyscall(c, siov)
+		syscall = "setxattr"
+		errno = s.HandleSetxattrSyscall(c, siov)
 	case lxdSeccompNotifyMount:
-		return s.HandleMountSyscall(c, siov)
+		syscall = "mount"
+		errno = s.HandleMountSyscall(c, siov)
+	case lxdSeccompNotifyFinitModule:
+		syscall = "finit_module"
+		errno = s.HandleFinitModuleSyscall(c, siov)
+	case lxdSeccompNotifyInitModule:
+		syscall = "init_module"
+		errno = s.HandleInitModuleSyscall(c, siov)
+	default:
+		return int(-C.EINVAL)
+	}
+
+	s.auditSyscall(c, siov, syscall, errno)
+
+	return errno
+}
+
+// auditSyscall sends a lifecycle event for an intercepted system call if the instance has
+// security.syscalls.intercept.audit enabled. Refused kernel module loads are always reported. The result is
+// "emulated" when LXD performed the system call, "continued" when it was handed back to the kernel and "denied"
+// when it failed.
+func (s *Server) auditSyscall(c Instance, siov *Iovec, syscall string, errno int) {
+	moduleDenied := (syscall == "init_module" || syscall == "finit_module") && errno != 0
+	if !moduleDenied && !shared.IsTrue(c.ExpandedConfig()["security.syscalls.intercept.audit"]) {
+		return
+	}
+
+	result := "emulated"
+	if errno != 0 {
+		result = "denied"
+	} else if uint32(siov.resp.flags)&seccompUserNotifFlagContinue != 0 {
+		result = "continued"
+	}
+
+	context := map[string]interface{}{
+		"syscall": syscall,
+		"pid":     int(siov.req.pid),
+		"result":  result,
+	}
+
+	if errno != 0 {
+		context["errno"] = -errno
+	}
+
+	for k, v := range siov.audit {
+		context[k] = v
 	}
 
-	return int(-C.EINVAL)
+	s.s.Events.SendLifecycle(c.Project(), "container-syscall-intercepted", fmt.Sprintf("/1.0/containers/%s", c.Name()), context)
 }
 
 const seccompUserNotifFlagContinue uint32 = 0x00000001
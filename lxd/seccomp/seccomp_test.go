@@ -4,6 +4,9 @@
 package seccomp
 
 import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
 	"fmt"
 	"testing"
 )
@@ -19,3 +22,76 @@ func TestMountFlagsToOpts(t *testing.T) {
 		t.Fatal(fmt.Errorf("Mount options parsing failed with invalid option string: %s", opts))
 	}
 }
+
+func TestKernelModuleAllowed(t *testing.T) {
+	module := kernelModuleName("/lib/modules/5.15.0/kernel/net/netfilter/nf-conntrack.ko.zst")
+	if module != "nf_conntrack" {
+		t.Fatal(fmt.Errorf("Kernel module name parsing failed with invalid name: %s", module))
+	}
+
+	config := map[string]string{"linux.kernel_modules": "overlay, nf-conntrack"}
+	if !kernelModuleAllowed(config, module) {
+		t.Fatal(fmt.Errorf("Kernel module %s should be allowed", module))
+	}
+
+	if kernelModuleAllowed(config, "btrfs") {
+		t.Fatal(fmt.Errorf("Kernel module btrfs shouldn't be allowed"))
+	}
+}
+
+func TestKernelModuleImageName(t *testing.T) {
+	module, err := kernelModuleImageName(kernelModuleImage("license=GPL\x00name=nf-conntrack\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if module != "nf_conntrack" {
+		t.Fatal(fmt.Errorf("Kernel module image parsing failed with invalid name: %s", module))
+	}
+
+	_, err = kernelModuleImageName(kernelModuleImage("license=GPL\x00"))
+	if err == nil {
+		t.Fatal(fmt.Errorf("Kernel module image without a name should fail"))
+	}
+
+	_, err = kernelModuleImageName([]byte("not a module"))
+	if err == nil {
+		t.Fatal(fmt.Errorf("Invalid kernel module image should fail"))
+	}
+}
+
+// kernelModuleImage returns a minimal 64-bit ELF relocatable object with the given .modinfo section.
+func kernelModuleImage(modinfo string) []byte {
+	shstrtab := "\x00.modinfo\x00.shstrtab\x00"
+	dataOff := uint64(binary.Size(elf.Header64{}))
+
+	header := elf.Header64{
+		Type:      uint16(elf.ET_REL),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     dataOff + uint64(len(modinfo)+len(shstrtab)),
+		Ehsize:    uint16(dataOff),
+		Shentsize: uint16(binary.Size(elf.Section64{})),
+		Shnum:     3,
+		Shstrndx:  2,
+	}
+
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	sections := []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_PROGBITS), Off: dataOff, Size: uint64(len(modinfo)), Addralign: 1},
+		{Name: 10, Type: uint32(elf.SHT_STRTAB), Off: dataOff + uint64(len(modinfo)), Size: uint64(len(shstrtab)), Addralign: 1},
+	}
+
+	buf := bytes.Buffer{}
+	binary.Write(&buf, binary.LittleEndian, header)
+	buf.WriteString(modinfo)
+	buf.WriteString(shstrtab)
+	binary.Write(&buf, binary.LittleEndian, sections)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/rbac"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// setupSocketGroups configures the system groups which, on top of the group owning the local unix socket, are
+// allowed to access it. The socket is made reachable by all users when any such group is configured.
+func (d *Daemon) setupSocketGroups(groups map[string]map[string]string) error {
+	socketGroups := map[uint32]map[string]string{}
+	for name, roles := range groups {
+		gid, err := shared.GroupId(name)
+		if err != nil {
+			// A group removed from the system shouldn't prevent the daemon from starting.
+			logger.Warn("Skipping unknown socket group", log.Ctx{"group": name, "err": err})
+			continue
+		}
+
+		socketGroups[uint32(gid)] = roles
+	}
+
+	d.socketGroups = socketGroups
+
+	return d.endpoints.LocalUpdateAccess(len(socketGroups) > 0)
+}
+
+// socketUserRoles returns the project roles of the client of a local unix socket request, or nil if that client
+// has full access. An error is returned if the client isn't allowed to access the socket at all.
+func (d *Daemon) socketUserRoles(r *http.Request) (map[string]string, error) {
+	// Without any socket group, the socket permissions restrict access to the users allowed to use LXD.
+	if len(d.socketGroups) == 0 {
+		return nil, nil
+	}
+
+	cred, ok := r.Context().Value("ucred").(*unix.Ucred)
+	if !ok {
+		return nil, fmt.Errorf("Missing unix socket credentials")
+	}
+
+	// The root user and the user running LXD have full access.
+	if cred.Uid == 0 || cred.Uid == uint32(os.Getuid()) {
+		return nil, nil
+	}
+
+	// Members of the group owning the socket have full access.
+	socketGID := os.Getgid()
+	if d.config.Group != "" {
+		gid, err := shared.GroupId(d.config.Group)
+		if err == nil {
+			socketGID = gid
+		}
+	}
+
+	gids := []uint32{cred.Gid}
+	u, err := user.LookupId(strconv.FormatUint(uint64(cred.Uid), 10))
+	if err == nil {
+		groupIDs, err := u.GroupIds()
+		if err == nil {
+			for _, groupID := range groupIDs {
+				gid, err := strconv.ParseUint(groupID, 10, 32)
+				if err != nil {
+					continue
+				}
+
+				gids = append(gids, uint32(gid))
+			}
+		}
+	}
+
+	var roles map[string]string
+	for _, gid := range gids {
+		if gid == uint32(socketGID) {
+			return nil, nil
+		}
+
+		groupRoles, ok := d.socketGroups[gid]
+		if !ok {
+			continue
+		}
+
+		if groupRoles == nil {
+			return nil, nil
+		}
+
+		if roles == nil {
+			roles = map[string]string{}
+		}
+
+		// Keep the most permissive role granted by any of the groups.
+		for projectName, role := range groupRoles {
+			if len(rbac.ProjectRoles[role]) > len(rbac.ProjectRoles[roles[projectName]]) {
+				roles[projectName] = role
+			}
+		}
+	}
+
+	if roles == nil {
+		return nil, fmt.Errorf("Access denied for user %d", cred.Uid)
+	}
+
+	return roles, nil
+}
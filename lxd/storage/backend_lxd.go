@@ -255,6 +255,15 @@ func (b *lxdBackend) Unmount() (bool, error) {
 	return b.driver.Unmount()
 }
 
+// Scrub starts an integrity check of the pool and returns its health.
+func (b *lxdBackend) Scrub() (*drivers.PoolHealth, error) {
+	logger := logging.AddContext(b.logger, nil)
+	logger.Debug("Scrub started")
+	defer logger.Debug("Scrub finished")
+
+	return b.driver.Scrub()
+}
+
 // ApplyPatch runs the requested patch at both backend and driver level.
 func (b *lxdBackend) ApplyPatch(name string) error {
 	// Run early backend patches.
@@ -938,12 +947,12 @@ func (b *lxdBackend) imageFiller(fingerprint string, op *operations.Operation) f
 			metadata := make(map[string]interface{})
 			tracker = &ioprogress.ProgressTracker{
 				Handler: func(percent, speed int64) {
-					shared.SetProgressMetadata(metadata, "create_instance_from_image_unpack", "Unpack", percent, 0, speed)
+					shared.SetProgressMetadata(metadata, "create_instance_from_image_unpack", "Unpack", percent, 0, 0, speed)
 					op.UpdateMetadata(metadata)
 				}}
 		}
 		imageFile := shared.VarPath("images", fingerprint)
-		return ImageUnpack(imageFile, vol, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, tracker)
+		return ImageUnpack(b.state, imageFile, vol, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, tracker)
 	}
 }
 
@@ -1107,6 +1116,14 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 			if !revert {
 				return
 			}
+
+			// Keep the partially received volume around so that the transfer can be retried as a refresh.
+			if args.Resumable && b.driver.HasVolume(vol) {
+				b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
+				b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+				return
+			}
+
 			b.DeleteInstance(inst, op)
 		}()
 
@@ -1284,6 +1301,307 @@ func (b *lxdBackend) RenameInstance(inst instance.Instance, newName string, op *
 	return nil
 }
 
+// MoveInstanceToProject renames the instance's root volume and its snapshots on the storage device to match
+// another project and name. The database records of the volumes must already have been moved by the caller, as
+// part of the same transaction as the instance itself.
+func (b *lxdBackend) MoveInstanceToProject(inst instance.Instance, newProject string, newName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "newProject": newProject, "newName": newName})
+	logger.Debug("MoveInstanceToProject started")
+	defer logger.Debug("MoveInstanceToProject finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance cannot be a snapshot")
+	}
+
+	if shared.IsSnapshot(newName) {
+		return fmt.Errorf("New name cannot be a snapshot")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := inst.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Rename the volume and its snapshots on the storage device.
+	volStorageName := project.Instance(inst.Project(), inst.Name())
+	newVolStorageName := project.Instance(newProject, newName)
+	contentType := InstanceContentType(inst)
+
+	// There's no need to pass config as it's not needed when renaming a volume.
+	vol := b.newVolume(volType, contentType, volStorageName, nil)
+
+	err = b.driver.RenameVolume(vol, newVolStorageName, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		newVol := b.newVolume(volType, contentType, newVolStorageName, nil)
+		b.driver.RenameVolume(newVol, volStorageName, op)
+	})
+
+	// Replace the instance symlinks with ones in the new project.
+	err = b.removeInstanceSymlink(inst.Type(), inst.Project(), inst.Name())
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), drivers.GetVolumeMountPath(b.name, volType, volStorageName))
+	})
+
+	err = b.ensureInstanceSymlink(inst.Type(), newProject, newName, drivers.GetVolumeMountPath(b.name, volType, newVolStorageName))
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.removeInstanceSymlink(inst.Type(), newProject, newName)
+	})
+
+	err = b.removeInstanceSnapshotSymlinkIfUnused(inst.Type(), inst.Project(), inst.Name())
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		revert.Add(func() {
+			b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+		})
+
+		err = b.ensureInstanceSnapshotSymlink(inst.Type(), newProject, newName)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// MoveInstanceToPool moves the instance's root volume and its snapshots from another pool into this one. The
+// data is transferred using the migration system, the database records of the volumes are then moved to this
+// pool and finally the volumes are removed from the source pool. The instance's root disk device must be
+// updated to point to this pool by the caller.
+func (b *lxdBackend) MoveInstanceToPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "srcPool": srcPool.Name()})
+	logger.Debug("MoveInstanceToPool started")
+	defer logger.Debug("MoveInstanceToPool finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance cannot be a snapshot")
+	}
+
+	if srcPool.Name() == b.Name() {
+		return fmt.Errorf("Instance is already in storage pool %q", b.Name())
+	}
+
+	// Convert to lxdBackend so we can access the source driver.
+	srcBackend, ok := srcPool.(*lxdBackend)
+	if !ok {
+		return fmt.Errorf("Pool is not an lxdBackend")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	contentType := InstanceContentType(inst)
+
+	_, srcVolRow, err := b.state.Cluster.GetLocalStoragePoolVolume(inst.Project(), inst.Name(), volDBType, srcPool.ID())
+	if err != nil {
+		return err
+	}
+
+	// Drop the keys specific to the source pool's driver and fill in the defaults of this one.
+	volConfig, err := VolumePropertiesTranslate(srcVolRow.Config, b.db.Driver)
+	if err != nil {
+		return err
+	}
+
+	err = VolumeFillDefault(volConfig, &b.db)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := VolumeSnapshotsGet(b.state, inst.Project(), srcPool.Name(), inst.Name(), volDBType)
+	if err != nil {
+		return err
+	}
+
+	snapshotNames := []string{}
+	for _, snapshot := range snapshots {
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+		snapshotNames = append(snapshotNames, snapName)
+	}
+
+	// The volume keeps its name on storage, only the pool changes.
+	volStorageName := project.Instance(inst.Project(), inst.Name())
+	vol := b.newVolume(volType, contentType, volStorageName, volConfig)
+	srcVol := srcBackend.newVolume(volType, contentType, volStorageName, nil)
+
+	if b.driver.HasVolume(vol) {
+		return fmt.Errorf("Cannot create volume, already exists on target")
+	}
+
+	// Negotiate the migration type to use.
+	offeredTypes := srcPool.MigrationTypes(contentType, false)
+	offerHeader := migration.TypesToHeader(offeredTypes...)
+	migrationTypes, err := migration.MatchTypes(offerHeader, FallbackMigrationType(contentType), b.MigrationTypes(contentType, false))
+	if err != nil {
+		return fmt.Errorf("Failed to negotiate move migration type: %v", err)
+	}
+
+	var srcVolumeSize int64
+
+	// For VMs, get source volume size so that target can create the volume the same size.
+	if inst.Type() == instancetype.VM {
+		srcVolumeSize, err = InstanceDiskBlockSize(srcPool, inst, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed getting source disk size")
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Use in-memory pipe pair to simulate a connection between the sender and receiver.
+	aEnd, bEnd := memorypipe.NewPipePair(ctx)
+
+	// Run sender and receiver in separate go routines to prevent deadlocks.
+	aEndErrCh := make(chan error, 1)
+	bEndErrCh := make(chan error, 1)
+	go func() {
+		err := srcPool.MigrateInstance(inst, aEnd, &migration.VolumeSourceArgs{
+			Name:          inst.Name(),
+			Snapshots:     snapshotNames,
+			MigrationType: migrationTypes[0],
+			TrackProgress: true, // Do use a progress tracker on sender.
+		}, op)
+
+		if err != nil {
+			cancel()
+		}
+		aEndErrCh <- err
+	}()
+
+	go func() {
+		// The volume records are still in the source pool, so the receiving driver is used directly.
+		err := b.driver.CreateVolumeFromMigration(vol, bEnd, migration.VolumeTargetArgs{
+			Name:          inst.Name(),
+			Config:        volConfig,
+			Snapshots:     snapshotNames,
+			MigrationType: migrationTypes[0],
+			VolumeSize:    srcVolumeSize,
+			TrackProgress: false, // Do not use a progress tracker on receiver.
+		}, &drivers.VolumeFiller{}, op)
+
+		if err != nil {
+			cancel()
+		}
+		bEndErrCh <- err
+	}()
+
+	revert.Add(func() {
+		for i := len(snapshotNames) - 1; i >= 0; i-- {
+			snapVol := b.newVolume(volType, contentType, drivers.GetSnapshotVolumeName(volStorageName, snapshotNames[i]), nil)
+			if b.driver.HasVolume(snapVol) {
+				b.driver.DeleteVolumeSnapshot(snapVol, op)
+			}
+		}
+
+		if b.driver.HasVolume(vol) {
+			b.driver.DeleteVolume(vol, op)
+		}
+	})
+
+	// Capture errors from the sender and receiver from their result channels.
+	errs := []error{}
+	aEndErr := <-aEndErrCh
+	if aEndErr != nil {
+		errs = append(errs, aEndErr)
+	}
+
+	bEndErr := <-bEndErrCh
+	if bEndErr != nil {
+		errs = append(errs, bEndErr)
+	}
+
+	cancel()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Move instance volume failed: %v", errs)
+	}
+
+	// Move the volume records once the data is in place, the source volumes are only removed afterwards.
+	err = b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.MoveStoragePoolVolume(inst.Project(), inst.Name(), volDBType, srcPool.ID(), b.ID(), volConfig)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to move volume records")
+	}
+
+	revert.Add(func() {
+		b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.MoveStoragePoolVolume(inst.Project(), inst.Name(), volDBType, b.ID(), srcPool.ID(), srcVolRow.Config)
+		})
+	})
+
+	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), srcVol.MountPath())
+	})
+
+	if len(snapshotNames) > 0 {
+		err = b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+
+	// Remove the volumes from the source pool now that nothing references them anymore.
+	for i := len(snapshotNames) - 1; i >= 0; i-- {
+		snapVol := srcBackend.newVolume(volType, contentType, drivers.GetSnapshotVolumeName(volStorageName, snapshotNames[i]), nil)
+		if srcBackend.driver.HasVolume(snapVol) {
+			err = srcBackend.driver.DeleteVolumeSnapshot(snapVol, op)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to delete snapshot %q from storage pool %q", snapshotNames[i], srcPool.Name())
+			}
+		}
+	}
+
+	if srcBackend.driver.HasVolume(srcVol) {
+		err = srcBackend.driver.DeleteVolume(srcVol, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to delete volume from storage pool %q", srcPool.Name())
+		}
+	}
+
+	return nil
+}
+
 // DeleteInstance removes the instance's root volume (all snapshots need to be removed first).
 func (b *lxdBackend) DeleteInstance(inst instance.Instance, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
@@ -1498,11 +1816,15 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 }
 
 // BackupInstance creates an instance backup.
-func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "optimized": optimized, "snapshots": snapshots})
+func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, deltaFrom string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "optimized": optimized, "snapshots": snapshots, "deltaFrom": deltaFrom})
 	logger.Debug("BackupInstance started")
 	defer logger.Debug("BackupInstance finished")
 
+	if deltaFrom != "" && (!optimized || !snapshots) {
+		return fmt.Errorf("Incremental backups require an optimized backup including snapshots")
+	}
+
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
 		return err
@@ -1526,7 +1848,7 @@ func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancew
 	}
 
 	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
-	err = b.driver.BackupVolume(vol, tarWriter, optimized, snapshots, op)
+	err = b.driver.BackupVolume(vol, tarWriter, optimized, snapshots, deltaFrom, op)
 	if err != nil {
 		return err
 	}
@@ -2285,6 +2607,9 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, volName stri
 		config = srcVolRow.Config
 	}
 
+	// A copy of an ephemeral volume isn't owned by the instance the source belongs to.
+	delete(config, "volatile.ephemeral.owner")
+
 	// Use the source volume's description if not supplied.
 	if desc == "" {
 		desc = srcVolRow.Description
@@ -2695,6 +3020,13 @@ func (b *lxdBackend) UpdateCustomVolume(projectName string, volName string, newD
 		return err
 	}
 
+	// The owner of an ephemeral volume is set when LXD creates it and then kept as is.
+	if newConfig["volatile.ephemeral.owner"] == "" && curVol.Config["volatile.ephemeral.owner"] != "" {
+		newConfig["volatile.ephemeral.owner"] = curVol.Config["volatile.ephemeral.owner"]
+	} else if newConfig["volatile.ephemeral.owner"] != curVol.Config["volatile.ephemeral.owner"] {
+		return fmt.Errorf("Custom volume 'volatile.ephemeral.owner' property cannot be changed")
+	}
+
 	// Validate config.
 	newVol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, newConfig)
 	err = b.driver.ValidateVolume(newVol, false)
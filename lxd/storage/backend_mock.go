@@ -67,6 +67,10 @@ func (b *mockBackend) ApplyPatch(name string) error {
 	return nil
 }
 
+func (b *mockBackend) Scrub() (*drivers.PoolHealth, error) {
+	return nil, nil
+}
+
 func (b *mockBackend) CreateInstance(inst instance.Instance, op *operations.Operation) error {
 	return nil
 }
@@ -91,6 +95,14 @@ func (b *mockBackend) RenameInstance(inst instance.Instance, newName string, op
 	return nil
 }
 
+func (b *mockBackend) MoveInstanceToProject(inst instance.Instance, newProject string, newName string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) MoveInstanceToPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) DeleteInstance(inst instance.Instance, op *operations.Operation) error {
 	return nil
 }
@@ -115,7 +127,7 @@ func (b *mockBackend) RefreshInstance(i instance.Instance, src instance.Instance
 	return nil
 }
 
-func (b *mockBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (b *mockBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, deltaFrom string, op *operations.Operation) error {
 	return nil
 }
 
@@ -369,6 +369,36 @@ func (d *btrfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return genericVFSGetResources(d)
 }
 
+// Scrub starts a scrub of the filesystem if one isn't already running and returns the filesystem
+// health based on the device error counters.
+func (d *btrfs) Scrub() (*PoolHealth, error) {
+	poolMntPath := GetPoolMountPath(d.name)
+
+	out, err := shared.RunCommand("btrfs", "scrub", "status", poolMntPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(out, "running") {
+		_, err := shared.RunCommand("btrfs", "scrub", "start", poolMntPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out, err = shared.RunCommand("btrfs", "device", "stats", poolMntPath)
+	if err != nil {
+		return nil, err
+	}
+
+	health, err := btrfsParseDeviceStats(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return health, nil
+}
+
 // MigrationType returns the type of transfer methods to be used when doing migrations between pools in preference order.
 func (d *btrfs) MigrationTypes(contentType ContentType, refresh bool) []migration.Type {
 	rsyncFeatures := []string{"xattrs", "delete", "compress", "bidirectional"}
@@ -485,3 +485,29 @@ func (d *btrfs) receiveSubVolume(r io.Reader, receivePath string) (string, error
 
 	return subVolPath, nil
 }
+
+// btrfsParseDeviceStats parses the output of "btrfs device stats" into a PoolHealth, counting the
+// errors of all the devices backing the filesystem.
+func btrfsParseDeviceStats(stats string) (*PoolHealth, error) {
+	health := PoolHealth{Status: "ok"}
+
+	for _, line := range strings.Split(stats, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		count, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse btrfs device stats %q", line)
+		}
+
+		health.Errors += count
+	}
+
+	if health.Errors > 0 {
+		health.Status = "errors"
+	}
+
+	return &health, nil
+}
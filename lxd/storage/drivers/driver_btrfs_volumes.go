@@ -115,7 +115,18 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
 	}
 
-	if d.HasVolume(vol) {
+	// Incremental backups are applied on top of the existing volume, which must have the base snapshot.
+	isDelta := srcBackup.DeltaFrom != ""
+	if isDelta {
+		if !d.HasVolume(vol) {
+			return nil, nil, fmt.Errorf("Cannot apply incremental backup, volume doesn't exist on target")
+		}
+
+		baseSnapshot, _ := vol.NewSnapshot(srcBackup.DeltaFrom)
+		if !d.HasVolume(baseSnapshot) {
+			return nil, nil, fmt.Errorf("Cannot apply incremental backup, base snapshot %q doesn't exist on target", srcBackup.DeltaFrom)
+		}
+	} else if d.HasVolume(vol) {
 		return nil, nil, fmt.Errorf("Cannot restore volume, already exists on target")
 	}
 
@@ -131,8 +142,10 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 			d.DeleteVolumeSnapshot(snapVol, op)
 		}
 
-		// And lastly the main volume.
-		d.DeleteVolume(vol, op)
+		// And lastly the main volume, unless it existed before the incremental backup was applied.
+		if !isDelta {
+			d.DeleteVolume(vol, op)
+		}
 	}
 	// Only execute the revert function if we have had an error internally.
 	revert.Add(revertHook)
@@ -293,6 +306,14 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		}
 	}
 
+	// The main volume of an incremental backup replaces the existing one.
+	if isDelta {
+		err = d.deleteSubvolume(vol.MountPath(), true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	err = unpackVolume(vol, srcFilePrefix)
 	if err != nil {
 		return nil, nil, err
@@ -937,7 +958,7 @@ func (d *btrfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *m
 
 // BackupVolume copies a volume (and optionally its snapshots) to a specified target path.
 // This driver does not support optimized backups.
-func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, deltaFrom string, op *operations.Operation) error {
 	// Handle the non-optimized tarballs through the generic packer.
 	if !optimized {
 		// Because the generic backup method will not take a consistent backup if files are being modified
@@ -990,6 +1011,14 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 		if err != nil {
 			return err
 		}
+
+		// For incremental backups, only send the snapshots taken after the base snapshot.
+		if deltaFrom != "" {
+			volSnapshots, err = snapshotsAfter(volSnapshots, deltaFrom)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Generate driver restoration header.
@@ -1129,6 +1158,10 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 
 	// Backup snapshots if populated.
 	lastVolPath := "" // Used as parent for differential exports.
+	if deltaFrom != "" {
+		baseVol, _ := vol.NewSnapshot(deltaFrom)
+		lastVolPath = baseVol.MountPath()
+	}
 	for _, snapName := range volSnapshots {
 		snapVol, _ := vol.NewSnapshot(snapName)
 
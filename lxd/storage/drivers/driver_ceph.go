@@ -252,6 +252,7 @@ func (d *ceph) Validate(config map[string]string) error {
 			return shared.IsOneOf(value, cephAllowedFilesystems)
 		},
 		"volume.block.mount_options": shared.IsAny,
+		"trim.schedule":              shared.IsCronSchedule,
 	}
 
 	return d.validatePool(config, rules)
@@ -332,6 +333,37 @@ func (d *ceph) GetResources() (*api.ResourcesStoragePool, error) {
 	return &res, nil
 }
 
+// Scrub returns the health of the Ceph cluster. Placement groups are scrubbed by Ceph itself, so
+// this only reports the number of failing health checks.
+func (d *ceph) Scrub() (*PoolHealth, error) {
+	var stdout bytes.Buffer
+
+	err := shared.RunCommandWithFds(nil, &stdout,
+		"ceph",
+		"--name", fmt.Sprintf("client.%s", d.config["ceph.user.name"]),
+		"--cluster", d.config["ceph.cluster_name"],
+		"health",
+		"-f", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	// Temporary struct for parsing.
+	type cephHealth struct {
+		Status string                     `json:"status"`
+		Checks map[string]json.RawMessage `json:"checks"`
+	}
+
+	// Parse the JSON output.
+	health := cephHealth{}
+	err = json.NewDecoder(&stdout).Decode(&health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PoolHealth{Status: health.Status, Errors: int64(len(health.Checks))}, nil
+}
+
 // MigrationType returns the type of transfer methods to be used when doing migrations between pools in preference order.
 func (d *ceph) MigrationTypes(contentType ContentType, refresh bool) []migration.Type {
 	rsyncFeatures := []string{"delete", "compress", "bidirectional"}
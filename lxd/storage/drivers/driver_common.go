@@ -182,6 +182,11 @@ func (d *common) ApplyPatch(name string) error {
 	return patch()
 }
 
+// Scrub isn't supported by default.
+func (d *common) Scrub() (*PoolHealth, error) {
+	return nil, ErrNotSupported
+}
+
 // moveGPTAltHeader moves the GPT alternative header to the end of the disk device supplied.
 // If the device supplied is not detected as not being a GPT disk then no action is taken and nil is returned.
 // If the required sgdisk command is not available a warning is logged, but no error is returned, as really it is
@@ -441,6 +441,7 @@ func (d *lvm) Validate(config map[string]string) error {
 		"volume.lvm.stripes":      shared.IsUint32,
 		"volume.lvm.stripes.size": shared.IsSize,
 		"lvm.vg.force_reuse":      shared.IsBool,
+		"trim.schedule":           shared.IsCronSchedule,
 	}
 
 	err := d.validatePool(config, rules)
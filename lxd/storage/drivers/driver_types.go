@@ -17,6 +17,12 @@ type Info struct {
 	MountedRoot           bool         // Whether the pool directory itself is a mount.
 }
 
+// PoolHealth represents the health of a storage pool as reported by its backing storage.
+type PoolHealth struct {
+	Status string // Driver specific status of the pool.
+	Errors int64  // Number of errors detected by the backing storage.
+}
+
 // VolumeFiller provides a struct for filling a volume.
 type VolumeFiller struct {
 	Fill func(vol Volume, rootBlockPath string) (int64, error) // Function to fill the volume.
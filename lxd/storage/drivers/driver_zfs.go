@@ -449,6 +449,30 @@ func (d *zfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return &res, nil
 }
 
+// Scrub starts a scrub of the zpool if one isn't already running and returns the zpool health.
+func (d *zfs) Scrub() (*PoolHealth, error) {
+	poolName := strings.Split(d.config["zfs.pool_name"], "/")[0]
+
+	out, err := shared.RunCommand("zpool", "status", "-p", poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	health, scrubbing, err := zfsParsePoolStatus(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scrubbing {
+		_, err := shared.RunCommand("zpool", "scrub", poolName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return health, nil
+}
+
 // MigrationType returns the type of transfer methods to be used when doing migrations between pools in preference order.
 func (d *zfs) MigrationTypes(contentType ContentType, refresh bool) []migration.Type {
 	rsyncFeatures := []string{"xattrs", "delete", "compress", "bidirectional"}
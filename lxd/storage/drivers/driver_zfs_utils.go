@@ -1,14 +1,18 @@
 package drivers
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/shared"
@@ -156,6 +160,92 @@ func (d *zfs) getDatasetProperty(dataset string, key string) (string, error) {
 	return strings.TrimSpace(output), nil
 }
 
+// latestSnapshotGUID returns the GUID of the most recent snapshot of the dataset.
+func (d *zfs) latestSnapshotGUID(dataset string) (uint64, error) {
+	out, err := shared.RunCommand("zfs", "list", "-H", "-p", "-o", "guid", "-t", "snapshot", "-s", "createtxg", "-d", "1", dataset)
+	if err != nil {
+		return 0, err
+	}
+
+	lines := strings.Fields(out)
+	if len(lines) == 0 {
+		return 0, fmt.Errorf("Dataset %q has no snapshots", dataset)
+	}
+
+	return strconv.ParseUint(lines[len(lines)-1], 10, 64)
+}
+
+// checkIncrementalStream checks that the incremental send stream can be received into the dataset of the
+// target without rolling it back. The stream must be an increment from the most recent snapshot of the
+// dataset, which mustn't have been modified since.
+func (d *zfs) checkIncrementalStream(r *bufio.Reader, target string) error {
+	dataset := strings.SplitN(target, "@", 2)[0]
+
+	header, err := r.Peek(zfsStreamBeginSize)
+	if err != nil {
+		return errors.Wrapf(err, "Failed reading send stream header for %q", dataset)
+	}
+
+	fromGUID, err := zfsStreamFromGUID(header)
+	if err != nil {
+		return err
+	}
+
+	if fromGUID == 0 {
+		return fmt.Errorf("Cannot apply incremental backup, it contains a full stream for %q", dataset)
+	}
+
+	latestGUID, err := d.latestSnapshotGUID(dataset)
+	if err != nil {
+		return err
+	}
+
+	if fromGUID != latestGUID {
+		return fmt.Errorf("Cannot apply incremental backup, its base isn't the most recent snapshot of %q", dataset)
+	}
+
+	written, err := d.getDatasetProperty(dataset, "written")
+	if err != nil {
+		return err
+	}
+
+	if written != "0" {
+		return fmt.Errorf("Cannot apply incremental backup, %q has been modified since its most recent snapshot which must be restored first", dataset)
+	}
+
+	return nil
+}
+
+// zfsStreamBeginSize is the length of the start of a send stream needed to find the GUID of the
+// snapshot it's an increment from. This covers the type and payload length of the DRR_BEGIN record,
+// followed by the magic, version info, creation time, type, flags, to and from GUIDs of its payload.
+const zfsStreamBeginSize = 56
+
+// zfsStreamMagic is the magic number of the DRR_BEGIN record at the start of send streams.
+const zfsStreamMagic = 0x2f5bacbac
+
+// zfsStreamFromGUID returns the GUID of the snapshot the send stream starting with the header is an
+// increment from, or 0 for a full stream. Streams are written in the byte order of the sender.
+func zfsStreamFromGUID(header []byte) (uint64, error) {
+	if len(header) < zfsStreamBeginSize {
+		return 0, fmt.Errorf("Send stream header is too short")
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		if order.Uint64(header[8:16]) != zfsStreamMagic {
+			continue
+		}
+
+		if order.Uint32(header[0:4]) != 0 {
+			return 0, fmt.Errorf("Send stream doesn't start with a begin record")
+		}
+
+		return order.Uint64(header[48:56]), nil
+	}
+
+	return 0, fmt.Errorf("Invalid send stream magic")
+}
+
 // version returns the ZFS version based on package or kernel module version.
 func (d *zfs) version() (string, error) {
 	// This function is only really ever relevant on Ubuntu as the only
@@ -316,3 +406,84 @@ func (d *zfs) receiveDataset(dataset string, conn io.ReadWriteCloser, writeWrapp
 
 	return nil
 }
+
+// zfsParsePoolStatus parses the output of "zpool status -p" into a PoolHealth, also returning whether
+// a scrub is currently running. The error count is the sum of the read, write and checksum errors of
+// the leaf devices in the pool plus the number of known data errors. The pool and vdev rows aren't
+// counted as they repeat the errors of the devices below them.
+func zfsParsePoolStatus(status string) (*PoolHealth, bool, error) {
+	health := PoolHealth{}
+	scrubbing := false
+	inConfig := false
+
+	// The config section is a tree of devices, indented by depth.
+	type device struct {
+		depth  int
+		line   string
+		fields []string
+	}
+
+	devices := []device{}
+
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "state:":
+			health.Status = strings.Join(fields[1:], " ")
+			continue
+		case "scan:":
+			scrubbing = strings.Contains(line, "scrub in progress")
+			continue
+		case "config:":
+			inConfig = true
+			continue
+		case "NAME":
+			continue
+		case "errors:":
+			inConfig = false
+			if len(fields) > 2 && fields[2] == "data" {
+				count, err := strconv.ParseInt(fields[1], 10, 64)
+				if err != nil {
+					return nil, false, fmt.Errorf("Failed to parse zpool data errors %q", line)
+				}
+
+				health.Errors += count
+			}
+
+			continue
+		}
+
+		if !inConfig {
+			continue
+		}
+
+		depth := len(line) - len(strings.TrimLeft(line, " \t"))
+		devices = append(devices, device{depth: depth, line: line, fields: fields})
+	}
+
+	for i, dev := range devices {
+		// Skip the rows without counters (like "logs" or available spares) and the non-leaf rows.
+		if len(dev.fields) < 5 || (i+1 < len(devices) && devices[i+1].depth > dev.depth) {
+			continue
+		}
+
+		for _, field := range dev.fields[2:5] {
+			count, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("Failed to parse zpool device errors %q", dev.line)
+			}
+
+			health.Errors += count
+		}
+	}
+
+	if health.Status == "" {
+		return nil, false, fmt.Errorf("Failed to find zpool state")
+	}
+
+	return &health, scrubbing, nil
+}
@@ -0,0 +1,111 @@
+package drivers
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+func Example_zfsParsePoolStatus() {
+	healthy := `  pool: default
+ state: ONLINE
+  scan: scrub repaired 0B in 0 days 00:00:01 with 0 errors on Sun Jul 12 00:24:02 2020
+config:
+
+	NAME                          STATE     READ WRITE CKSUM
+	default                       ONLINE       0     0     0
+	  /var/lib/lxd/disks/default.img  ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+	degraded := `  pool: tank
+ state: DEGRADED
+status: One or more devices has experienced an error resulting in data
+	corruption.  Applications may be affected.
+  scan: scrub in progress since Sun Jul 12 00:24:02 2020
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     4
+	  mirror-0  DEGRADED     0     0     8
+	    sda     ONLINE       0     0    16
+	    sdb     FAULTED      3     1     0  too many errors
+
+errors: 2 data errors, use '-v' for a list
+`
+
+	for _, status := range []string{healthy, degraded, "garbage"} {
+		health, scrubbing, err := zfsParsePoolStatus(status)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		fmt.Println(health.Status, health.Errors, scrubbing)
+	}
+
+	// Output: ONLINE 0 false
+	// DEGRADED 22 true
+	// Failed to find zpool state
+}
+
+func Example_zfsParsePoolStatusMirror() {
+	status := `  pool: tank
+ state: ONLINE
+status: One or more devices has experienced an unrecoverable error.  An
+	attempt was made to correct the error.  Applications are unaffected.
+action: Determine if the device needs to be replaced, and clear the errors
+	using 'zpool clear' or replace the device with 'zpool replace'.
+   see: https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-9P
+  scan: scrub repaired 128K in 00:00:03 with 0 errors on Fri Oct 16 10:12:45 2026
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     7
+	  mirror-0  ONLINE       0     0     7
+	    sdb     ONLINE       0     0     0
+	    sdc     ONLINE       0     0     7
+	logs
+	  sdd       ONLINE       0     0     0
+	spares
+	  sde       AVAIL
+
+errors: No known data errors
+`
+
+	health, scrubbing, err := zfsParsePoolStatus(status)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(health.Status, health.Errors, scrubbing)
+
+	// Output: ONLINE 7 false
+}
+
+func Example_zfsStreamFromGUID() {
+	header := func(order binary.ByteOrder, fromGUID uint64) []byte {
+		b := make([]byte, zfsStreamBeginSize)
+		order.PutUint64(b[8:16], zfsStreamMagic)
+		order.PutUint64(b[40:48], 0x1111)
+		order.PutUint64(b[48:56], fromGUID)
+		return b
+	}
+
+	for _, b := range [][]byte{header(binary.LittleEndian, 0xabcd), header(binary.BigEndian, 0xabcd), header(binary.LittleEndian, 0), make([]byte, zfsStreamBeginSize), {0}} {
+		guid, err := zfsStreamFromGUID(b)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		fmt.Printf("%x\n", guid)
+	}
+
+	// Output: abcd
+	// abcd
+	// 0
+	// Invalid send stream magic
+	// Send stream header is too short
+}
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -252,7 +253,19 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 		return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
 	}
 
-	if d.HasVolume(vol) {
+	// Incremental backups are applied on top of the existing volume, which must have the base snapshot
+	// as its most recent snapshot.
+	isDelta := srcBackup.DeltaFrom != ""
+	if isDelta {
+		if !d.HasVolume(vol) {
+			return nil, nil, fmt.Errorf("Cannot apply incremental backup, volume doesn't exist on target")
+		}
+
+		baseSnapshot, _ := vol.NewSnapshot(srcBackup.DeltaFrom)
+		if !d.HasVolume(baseSnapshot) {
+			return nil, nil, fmt.Errorf("Cannot apply incremental backup, base snapshot %q doesn't exist on target", srcBackup.DeltaFrom)
+		}
+	} else if d.HasVolume(vol) {
 		return nil, nil, fmt.Errorf("Cannot restore volume, already exists on target")
 	}
 
@@ -279,8 +292,10 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 			d.DeleteVolumeSnapshot(snapVol, op)
 		}
 
-		// And lastly the main volume.
-		d.DeleteVolume(vol, op)
+		// And lastly the main volume, unless it existed before the incremental backup was applied.
+		if !isDelta {
+			d.DeleteVolume(vol, op)
+		}
 	}
 
 	// Only execute the revert function if we have had an error internally.
@@ -305,9 +320,26 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 			}
 
 			if hdr.Name == srcFile {
-				// Extract the backup.
-				err = shared.RunCommandWithFds(tr, nil, "zfs", "receive", "-F", target)
+				var r io.Reader = tr
+				args := []string{"receive"}
+				if isDelta {
+					// Incremental streams are received without rolling back the existing
+					// volume, so check upfront that they apply cleanly to it.
+					br := bufio.NewReader(tr)
+					err = d.checkIncrementalStream(br, target)
+					if err != nil {
+						return err
+					}
+
+					r = br
+				} else {
+					args = append(args, "-F")
+				}
 
+				args = append(args, target)
+
+				// Extract the backup.
+				err = shared.RunCommandWithFds(r, nil, "zfs", args...)
 				if err != nil {
 					return err
 				}
@@ -1284,7 +1316,7 @@ func (d *zfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *mig
 }
 
 // BackupVolume creates an exported version of a volume.
-func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, deltaFrom string, op *operations.Operation) error {
 	// Handle the non-optimized tarballs through the generic packer.
 	if !optimized {
 		// For block volumes that are exporting snapshots, we need to activate parent volume first so that
@@ -1357,7 +1389,7 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 	// Backup VM config volumes first.
 	if vol.IsVMBlock() {
 		fsVol := vol.NewVMBlockFilesystemVolume()
-		err := d.BackupVolume(fsVol, tarWriter, optimized, snapshots, op)
+		err := d.BackupVolume(fsVol, tarWriter, optimized, snapshots, deltaFrom, op)
 		if err != nil {
 			return err
 		}
@@ -1413,11 +1445,23 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 			return err
 		}
 
+		// For incremental backups, only send the snapshots taken after the base snapshot using it
+		// as the parent of the first one.
+		if deltaFrom != "" {
+			volSnapshots, err = snapshotsAfter(volSnapshots, deltaFrom)
+			if err != nil {
+				return err
+			}
+
+			baseSnapshot, _ := vol.NewSnapshot(deltaFrom)
+			finalParent = d.dataset(baseSnapshot, false)
+		}
+
 		for i, snapName := range volSnapshots {
 			snapshot, _ := vol.NewSnapshot(snapName)
 
 			// Figure out parent and current subvolumes.
-			parent := ""
+			parent := finalParent
 			if i > 0 {
 				oldSnapshot, _ := vol.NewSnapshot(volSnapshots[i-1])
 				parent = d.dataset(oldSnapshot, false)
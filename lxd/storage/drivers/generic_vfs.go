@@ -288,7 +288,10 @@ func genericVFSCreateVolumeFromMigration(d Driver, initVolume func(vol Volume) (
 			return err
 		}
 
-		revert.Add(func() { d.DeleteVolume(vol, op) })
+		// Resumable transfers keep the received data (including rsync's partial files) for a refresh to reuse.
+		if !volTargetArgs.Resumable {
+			revert.Add(func() { d.DeleteVolume(vol, op) })
+		}
 	}
 
 	recvFSVol := func(volName string, conn io.ReadWriteCloser, path string) error {
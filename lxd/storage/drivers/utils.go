@@ -462,6 +462,17 @@ func resolveMountOptions(options string) (uintptr, string) {
 	return mountFlags, strings.Join(tmp, ",")
 }
 
+// snapshotsAfter returns the snapshot names (ordered oldest first) that come after the base snapshot.
+func snapshotsAfter(snapshots []string, base string) ([]string, error) {
+	for i, snapName := range snapshots {
+		if snapName == base {
+			return snapshots[i+1:], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Base snapshot %q not found", base)
+}
+
 // shrinkFileSystem shrinks a filesystem if it is supported. Ext4 volumes will be unmounted temporarily if needed.
 func shrinkFileSystem(fsType string, devPath string, vol Volume, byteSize int64) error {
 	// The smallest unit that resize2fs accepts in byte size (rather than blocks) is kilobytes.
@@ -35,3 +35,22 @@ func TestGetVolumeMountPath(t *testing.T) {
 	expected = GetPoolMountPath(poolName) + "/virtual-machines/testvol"
 	assert.Equal(t, expected, path)
 }
+
+// Test snapshotsAfter
+func TestSnapshotsAfter(t *testing.T) {
+	snapshots := []string{"snap0", "snap1", "snap2"}
+
+	// Test base snapshot in the middle.
+	after, err := snapshotsAfter(snapshots, "snap1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"snap2"}, after)
+
+	// Test most recent base snapshot.
+	after, err = snapshotsAfter(snapshots, "snap2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, after)
+
+	// Test missing base snapshot.
+	_, err = snapshotsAfter(snapshots, "snap3")
+	assert.Error(t, err)
+}
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,7 +12,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	"gopkg.in/robfig/cron.v2"
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
@@ -249,6 +252,9 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 	"block.mount_options": func(value string) ([]string, error) {
 		return []string{"ceph", "lvm"}, shared.IsAny(value)
 	},
+	"security.shared": func(value string) ([]string, error) {
+		return []string{"ceph"}, shared.IsBool(value)
+	},
 	"security.shifted": func(value string) ([]string, error) {
 		return SupportedPoolTypes, shared.IsBool(value)
 	},
@@ -423,6 +429,7 @@ func validatePoolCommonRules() map[string]func(string) error {
 		"volume.size":             shared.IsSize,
 		"size":                    shared.IsSize,
 		"rsync.bwlimit":           shared.IsAny,
+		"scrub.schedule":          shared.IsCronSchedule,
 	}
 }
 
@@ -441,23 +448,8 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 			_, err := shared.GetSnapshotExpiry(time.Time{}, value)
 			return err
 		},
-		"snapshots.schedule": func(value string) error {
-			if value == "" {
-				return nil
-			}
-
-			if len(strings.Split(value, " ")) != 5 {
-				return fmt.Errorf("Schedule must be of the form: <minute> <hour> <day-of-month> <month> <day-of-week>")
-			}
-
-			_, err := cron.Parse(fmt.Sprintf("* %s", value))
-			if err != nil {
-				return errors.Wrap(err, "Error parsing schedule")
-			}
-
-			return nil
-		},
-		"snapshots.pattern": shared.IsAny,
+		"snapshots.schedule": shared.IsCronSchedule,
+		"snapshots.pattern":  shared.IsAny,
 	}
 
 	// block.mount_options is only relevant for drivers that are block backed and when there
@@ -470,8 +462,10 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 		rules["block.filesystem"] = shared.IsAny
 	}
 
-	// security.shifted and security.unmapped are only relevant for custom volumes.
+	// security.shifted, security.unmapped and security.shared are only relevant for custom volumes.
 	if vol.Type() == drivers.VolumeTypeCustom {
+		rules["volatile.ephemeral.owner"] = shared.IsAny
+		rules["security.shared"] = shared.IsBool
 		rules["security.shifted"] = shared.IsBool
 		rules["security.unmapped"] = shared.IsBool
 	}
@@ -484,6 +478,235 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 	return rules
 }
 
+// imageLayerBase returns the fingerprint of the base image of the given layered image file, or an empty string
+// if it isn't a layered image. As LXD writes metadata.yaml first, only the entries preceding the root
+// filesystem are looked at.
+func imageLayerBase(imageFile string) (string, error) {
+	f, err := os.Open(imageFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, ext, unpacker, err := shared.DetectCompressionFile(f)
+	if err != nil {
+		return "", err
+	}
+
+	if ext == ".squashfs" || ext == ".qcow2" {
+		return "", nil
+	}
+
+	tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), f, unpacker)
+	if err != nil {
+		return "", err
+	}
+	defer cancelFunc()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if strings.HasPrefix(name, "rootfs") {
+			return "", nil
+		}
+
+		if name != "metadata.yaml" {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return "", err
+		}
+
+		meta := api.ImageMetadata{}
+		err = yaml.Unmarshal(content, &meta)
+		if err != nil {
+			return "", err
+		}
+
+		return meta.BaseImage, nil
+	}
+}
+
+// imageLayerBaseValidate checks that the base image of a layered image is a valid fingerprint of an image which
+// exists in the same project as the layered image.
+func imageLayerBaseValidate(s *state.State, fingerprint string, baseImage string) error {
+	if len(baseImage) != 64 {
+		return fmt.Errorf("Invalid base image fingerprint %q", baseImage)
+	}
+
+	for _, c := range baseImage {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return fmt.Errorf("Invalid base image fingerprint %q", baseImage)
+		}
+	}
+
+	exists, err := s.Cluster.ImageBaseExists(fingerprint, baseImage)
+	if err != nil {
+		return errors.Wrapf(err, "Failed checking base image %q", baseImage)
+	}
+
+	if !exists {
+		return fmt.Errorf("Base image %q of layered image %q isn't available in its project", baseImage, fingerprint)
+	}
+
+	return nil
+}
+
+// imageLayerRemoveWhiteout removes the given whiteout path from the root filesystem open as rootfd. The path is
+// resolved one component at a time without following any symlink, so that an image can't point it outside of
+// its root filesystem.
+func imageLayerRemoveWhiteout(rootfd int, whiteout string) error {
+	if filepath.IsAbs(whiteout) {
+		return fmt.Errorf("Whiteout paths must be relative")
+	}
+
+	components := []string{}
+	for _, component := range strings.Split(whiteout, "/") {
+		if component == "" || component == "." {
+			continue
+		}
+
+		if component == ".." {
+			return fmt.Errorf("Whiteout paths can't contain %q", "..")
+		}
+
+		components = append(components, component)
+	}
+
+	if len(components) == 0 {
+		return fmt.Errorf("Whiteout path is empty")
+	}
+
+	dirfd := rootfd
+	for _, component := range components[:len(components)-1] {
+		fd, err := unix.Openat(dirfd, component, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if dirfd != rootfd {
+			unix.Close(dirfd)
+		}
+
+		if err != nil {
+			// Nothing to remove if a parent is missing, or isn't a directory.
+			if err == unix.ENOENT || err == unix.ENOTDIR || err == unix.ELOOP {
+				return nil
+			}
+
+			return err
+		}
+
+		dirfd = fd
+	}
+
+	if dirfd != rootfd {
+		defer unix.Close(dirfd)
+	}
+
+	return removeAllAt(dirfd, components[len(components)-1])
+}
+
+// removeAllAt removes the given entry of the directory open as dirfd and any children it contains. Symlinks are
+// removed rather than followed.
+func removeAllAt(dirfd int, name string) error {
+	err := unix.Unlinkat(dirfd, name, 0)
+	if err == nil || err == unix.ENOENT {
+		return nil
+	}
+
+	if err != unix.EISDIR && err != unix.EPERM {
+		return err
+	}
+
+	fd, err := unix.Openat(dirfd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+
+	dir := os.NewFile(uintptr(fd), name)
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range names {
+		err = removeAllAt(fd, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = unix.Unlinkat(dirfd, name, unix.AT_REMOVEDIR)
+	if err != nil && err != unix.ENOENT {
+		return err
+	}
+
+	return nil
+}
+
+// imageLayerApply removes the paths listed in the whiteouts file of a layered image unpacked in destPath from
+// its root filesystem. The base image reference is then dropped from the metadata as the root filesystem is now
+// complete.
+func imageLayerApply(destPath string) error {
+	rootfsPath := filepath.Join(destPath, "rootfs")
+	whiteoutsPath := filepath.Join(destPath, "whiteouts")
+
+	content, err := ioutil.ReadFile(whiteoutsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	rootfs, err := os.Open(rootfsPath)
+	if err != nil {
+		return err
+	}
+	defer rootfs.Close()
+
+	for _, whiteout := range strings.Split(string(content), "\n") {
+		if whiteout == "" {
+			continue
+		}
+
+		err = imageLayerRemoveWhiteout(int(rootfs.Fd()), whiteout)
+		if err != nil {
+			return errors.Wrapf(err, "Failed removing whiteout path %q", whiteout)
+		}
+	}
+
+	err = os.Remove(whiteoutsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	metadataPath := filepath.Join(destPath, "metadata.yaml")
+	content, err = ioutil.ReadFile(metadataPath)
+	if err != nil {
+		return err
+	}
+
+	meta := api.ImageMetadata{}
+	err = yaml.Unmarshal(content, &meta)
+	if err != nil {
+		return err
+	}
+
+	meta.BaseImage = ""
+	content, err = yaml.Marshal(&meta)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(metadataPath, content, 0644)
+}
+
 // ImageUnpack unpacks a filesystem image into the destination path.
 // There are several formats that images can come in:
 // Container Format A: Separate metadata tarball and root squashfs file.
@@ -491,10 +714,16 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 //	- Unpack root squashfs file into mountPath/rootfs.
 // Container Format B: Combined tarball containing metadata files and root squashfs.
 //	- Unpack combined tarball into mountPath.
-// VM Format A: Separate metadata tarball and root qcow2 file.
+// Container Format C: Layered tarball only containing the changes from a base image.
+//	- Check the base image is known in a project of the layered image.
+//	- Unpack the base image, then the layered tarball into mountPath.
+//	- Remove the paths listed in its whiteouts file from mountPath/rootfs.
+// VM Format A: Separate metadata tarball and root qcow2 (or raw) file.
 // 	- Unpack metadata tarball into mountPath.
 //	- Check rootBlockPath is a file and convert qcow2 file into raw format in rootBlockPath.
-func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blockBackend, runningInUserns bool, tracker *ioprogress.ProgressTracker) (int64, error) {
+// VM Format B: Combined tarball containing metadata files and root qcow2 (or raw) rootfs.img file.
+//	- Unpack combined tarball into a temporary directory and convert rootfs.img into raw format in rootBlockPath.
+func ImageUnpack(s *state.State, imageFile string, vol drivers.Volume, destBlockFile string, blockBackend, runningInUserns bool, tracker *ioprogress.ProgressTracker) (int64, error) {
 	// For all formats, first unpack the metadata (or combined) tarball into destPath.
 	imageRootfsFile := imageFile + ".rootfs"
 	destPath := vol.MountPath()
@@ -503,12 +732,48 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 	if destBlockFile == "" {
 		rootfsPath := filepath.Join(destPath, "rootfs")
 
+		// Layered images only contain the changes from their base image, which gets unpacked first.
+		baseImage := ""
+		if !shared.PathExists(imageRootfsFile) {
+			var err error
+			baseImage, err = imageLayerBase(imageFile)
+			if err != nil {
+				return -1, err
+			}
+		}
+
+		if baseImage != "" {
+			// The base image comes from the image metadata, so make sure it's an image this one may use.
+			err := imageLayerBaseValidate(s, filepath.Base(imageFile), baseImage)
+			if err != nil {
+				return -1, err
+			}
+
+			_, err = ImageUnpack(s, filepath.Join(filepath.Dir(imageFile), baseImage), vol, "", blockBackend, runningInUserns, tracker)
+			if err != nil {
+				return -1, errors.Wrapf(err, "Failed unpacking base image %q", baseImage)
+			}
+
+			// The templates of the layer replace those of the base image.
+			err = os.RemoveAll(filepath.Join(destPath, "templates"))
+			if err != nil {
+				return -1, err
+			}
+		}
+
 		// Unpack the main image file.
 		err := shared.Unpack(imageFile, destPath, blockBackend, runningInUserns, tracker)
 		if err != nil {
 			return -1, err
 		}
 
+		if baseImage != "" {
+			err = imageLayerApply(destPath)
+			if err != nil {
+				return -1, err
+			}
+		}
+
 		// Check for separate root file.
 		if shared.PathExists(imageRootfsFile) {
 			err = os.MkdirAll(rootfsPath, 0755)
@@ -544,8 +809,8 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 		return -1, fmt.Errorf("Root block path isn't a file: %s", destBlockFile)
 	}
 
-	// convertBlockImage converts the qcow2 block image file into a raw block device. If needed it will attempt
-	// to enlarge the destination volume to accommodate the unpacked qcow2 image file.
+	// convertBlockImage converts the qcow2 (or raw) block image file into a raw block device. If needed it will
+	// attempt to enlarge the destination volume to accommodate the unpacked image file.
 	convertBlockImage := func(v drivers.Volume, imgPath string, dstPath string) (int64, error) {
 		// Get info about qcow2 file.
 		imgJSON, err := shared.RunCommand("qemu-img", "info", "--output=json", imgPath)
@@ -563,7 +828,8 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 			return -1, err
 		}
 
-		if imgInfo.Format != "qcow2" {
+		// Images converted on import can already be in raw format.
+		if !shared.StringInSlice(imgInfo.Format, []string{"qcow2", "raw"}) {
 			return -1, fmt.Errorf("Unexpected image format %q", imgInfo.Format)
 		}
 
@@ -594,10 +860,10 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 			}
 		}
 
-		// Convert the qcow2 format to a raw block device using qemu's dd mode to avoid issues with
+		// Convert the image to a raw block device using qemu's dd mode to avoid issues with
 		// loop backed storage pools. Use the MinBlockBoundary block size to speed up conversion.
-		logger.Debugf("Converting qcow2 image %q to raw disk %q", imgPath, dstPath)
-		_, err = shared.RunCommand("qemu-img", "dd", "-f", "qcow2", "-O", "raw", fmt.Sprintf("bs=%d", drivers.MinBlockBoundary), fmt.Sprintf("if=%s", imgPath), fmt.Sprintf("of=%s", dstPath))
+		logger.Debugf("Converting %s image %q to raw disk %q", imgInfo.Format, imgPath, dstPath)
+		_, err = shared.RunCommand("qemu-img", "dd", "-f", imgInfo.Format, "-O", "raw", fmt.Sprintf("bs=%d", drivers.MinBlockBoundary), fmt.Sprintf("if=%s", imgPath), fmt.Sprintf("of=%s", dstPath))
 		if err != nil {
 			return -1, errors.Wrapf(err, "Failed converting image to raw at %q", dstPath)
 		}
@@ -614,7 +880,7 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 			return -1, err
 		}
 
-		// Convert the qcow2 format to a raw block device.
+		// Convert the image to a raw block device.
 		imgSize, err = convertBlockImage(vol, imageRootfsFile, destBlockFile)
 		if err != nil {
 			return -1, err
@@ -635,13 +901,13 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, blo
 
 		imgPath := filepath.Join(tempDir, "rootfs.img")
 
-		// Convert the qcow2 format to a raw block device.
+		// Convert the image to a raw block device.
 		imgSize, err = convertBlockImage(vol, imgPath, destBlockFile)
 		if err != nil {
 			return -1, err
 		}
 
-		// Delete the qcow2.
+		// Delete the unpacked image file.
 		err = os.Remove(imgPath)
 		if err != nil {
 			return -1, errors.Wrapf(err, "Failed to remove %q", imgPath)
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Whiteouts are removed from the root filesystem without following the symlinks it contains.
+func TestImageLayerApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-image-layer-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outside := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(outside, "secret"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outside, "secret", "file"), []byte("keep"), 0644))
+
+	destPath := filepath.Join(dir, "image")
+	rootfsPath := filepath.Join(destPath, "rootfs")
+	require.NoError(t, os.MkdirAll(filepath.Join(rootfsPath, "etc", "removed"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfsPath, "etc", "removed", "file"), []byte(""), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(rootfsPath, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(rootfsPath, "link")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(destPath, "metadata.yaml"), []byte("base_image: abc\n"), 0644))
+
+	whiteouts := "etc/removed\nescape/secret\nlink\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(destPath, "whiteouts"), []byte(whiteouts), 0644))
+
+	require.NoError(t, imageLayerApply(destPath))
+
+	// The whiteouts inside the root filesystem are gone, including the symlink itself.
+	assert.NoFileExists(t, filepath.Join(rootfsPath, "etc", "removed", "file"))
+	assert.DirExists(t, filepath.Join(rootfsPath, "etc"))
+	_, err = os.Lstat(filepath.Join(rootfsPath, "link"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Nothing was removed through the symlinks.
+	assert.FileExists(t, filepath.Join(outside, "secret", "file"))
+
+	content, err := ioutil.ReadFile(filepath.Join(destPath, "metadata.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "abc")
+}
+
+// Whiteout paths which are absolute or go up the tree are refused.
+func TestImageLayerApply_InvalidPaths(t *testing.T) {
+	for _, whiteout := range []string{"/etc/passwd", "../outside", "etc/../../outside"} {
+		dir, err := ioutil.TempDir("", "lxd-image-layer-")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "rootfs"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(""), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "whiteouts"), []byte(whiteout), 0644))
+
+		assert.Error(t, imageLayerApply(dir), whiteout)
+	}
+}
+
+// Base images must be referenced by fingerprint.
+func TestImageLayerBaseValidate_Fingerprint(t *testing.T) {
+	for _, baseImage := range []string{"../../../etc", "abc", "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789"} {
+		assert.Error(t, imageLayerBaseValidate(nil, "layer", baseImage), baseImage)
+	}
+}
@@ -70,7 +70,7 @@ func storagePoolsGet(d *Daemon, r *http.Request) response.Response {
 			if err != nil {
 				return response.SmartError(err)
 			}
-			pl.UsedBy = poolUsedBy
+			pl.UsedBy = filterUsedBy(d, r, poolUsedBy)
 
 			resultMap = append(resultMap, *pl)
 		}
@@ -326,7 +326,7 @@ func storagePoolGet(d *Daemon, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
-	pool.UsedBy = poolUsedBy
+	pool.UsedBy = filterUsedBy(d, r, poolUsedBy)
 
 	targetNode := queryParam(r, "target")
 
@@ -343,7 +343,7 @@ func storagePoolGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	etag := []interface{}{pool.Name, pool.Driver, pool.Config}
+	etag := []interface{}{pool.Name, pool.Driver, pool.Description, pool.Config}
 
 	return response.SyncResponseETag(true, &pool, etag)
 }
@@ -379,7 +379,7 @@ func storagePoolPut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []interface{}{dbInfo.Name, dbInfo.Driver, config}
+	etag := []interface{}{dbInfo.Name, dbInfo.Driver, dbInfo.Description, config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -454,7 +454,7 @@ func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []interface{}{dbInfo.Name, dbInfo.Driver, config}
+	etag := []interface{}{dbInfo.Name, dbInfo.Driver, dbInfo.Description, config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
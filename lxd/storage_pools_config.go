@@ -55,6 +55,12 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	// valid drivers: btrfs, dir, lvm, zfs
 	"source": shared.IsAny,
 
+	// valid drivers: btrfs, ceph, zfs
+	"scrub.schedule": shared.IsAny,
+
+	// valid drivers: ceph, lvm
+	"trim.schedule": shared.IsAny,
+
 	// Using it as an indicator whether we created the pool or are just
 	// re-using it. Note that the valid drivers only list ceph for now. This
 	// approach is however generalizable. It's just that we currently don't
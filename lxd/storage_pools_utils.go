@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"gopkg.in/robfig/cron.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 )
 
 func storagePoolUpdate(state *state.State, name, newDescription string, newConfig map[string]string, withDB bool) error {
@@ -174,3 +186,144 @@ func dbStoragePoolDeleteAndUpdateCache(s *state.State, poolName string) error {
 
 	return err
 }
+
+// storagePoolsScheduled returns the names of the storage pools whose cron schedule in the given config key
+// is due in the current minute.
+func storagePoolsScheduled(d *Daemon, key string) ([]string, error) {
+	poolNames, err := d.cluster.GetStoragePoolNames()
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// Truncate the time now back to the start of the minute, so that the next
+	// scheduled time can be compared with it.
+	now := time.Now().Truncate(time.Minute)
+
+	scheduled := []string{}
+	for _, poolName := range poolNames {
+		_, poolInfo, err := d.cluster.GetStoragePool(poolName)
+		if err != nil {
+			logger.Error("Failed to get storage pool", log.Ctx{"pool": poolName, "err": err})
+			continue
+		}
+
+		schedule := poolInfo.Config[key]
+		if schedule == "" {
+			continue
+		}
+
+		// Extend our schedule to one that is accepted by the used cron parser
+		sched, err := cron.Parse(fmt.Sprintf("* %s", schedule))
+		if err != nil {
+			continue
+		}
+
+		if !now.Equal(sched.Next(now).Truncate(time.Minute)) {
+			continue
+		}
+
+		scheduled = append(scheduled, poolName)
+	}
+
+	return scheduled, nil
+}
+
+// autoScrubStoragePoolsTask checks the health of the storage pools that have a scrub.schedule,
+// starting a scrub of their backing storage when supported by the driver.
+func autoScrubStoragePoolsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		poolNames, err := storagePoolsScheduled(d, "scrub.schedule")
+		if err != nil {
+			logger.Error("Failed to get storage pools", log.Ctx{"err": err})
+			return
+		}
+
+		for _, poolName := range poolNames {
+			err = storagePoolScrub(d, poolName)
+			if err != nil {
+				logger.Error("Failed to scrub storage pool", log.Ctx{"pool": poolName, "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
+
+// autoTrimStoragePoolsTask discards the unused blocks of the running containers on the storage pools
+// that have a trim.schedule, so that deleted data is returned to thin-provisioned pools.
+func autoTrimStoragePoolsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		poolNames, err := storagePoolsScheduled(d, "trim.schedule")
+		if err != nil {
+			logger.Error("Failed to get storage pools", log.Ctx{"err": err})
+			return
+		}
+
+		if len(poolNames) == 0 {
+			return
+		}
+
+		insts, err := instance.LoadNodeAll(d.State(), instancetype.Container)
+		if err != nil {
+			logger.Error("Failed to load instances", log.Ctx{"err": err})
+			return
+		}
+
+		for _, inst := range insts {
+			if !inst.IsRunning() {
+				continue
+			}
+
+			poolName, err := inst.StoragePool()
+			if err != nil || !shared.StringInSlice(poolName, poolNames) {
+				continue
+			}
+
+			// fstrim only needs a path inside the mounted filesystem of the volume.
+			_, err = shared.RunCommand("fstrim", inst.RootfsPath())
+			if err != nil {
+				logger.Error("Failed to trim instance volume", log.Ctx{"pool": poolName, "project": inst.Project(), "instance": inst.Name(), "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
+
+// storagePoolScrub checks the health of a storage pool and reports the errors found.
+func storagePoolScrub(d *Daemon, poolName string) error {
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return err
+	}
+
+	health, err := pool.Scrub()
+	if err != nil {
+		if err == storageDrivers.ErrNotSupported {
+			logger.Debug("Storage pool doesn't support scrubbing", log.Ctx{"pool": poolName})
+			return nil
+		}
+
+		return err
+	}
+
+	entityURL := fmt.Sprintf("/%s/storage-pools/%s", version.APIVersion, poolName)
+
+	if health.Errors == 0 {
+		logger.Debug("Storage pool is healthy", log.Ctx{"pool": poolName, "status": health.Status})
+		warningResolve(d.State(), "", "storage", "pool-errors", entityURL)
+		return nil
+	}
+
+	warningRaise(d.State(), "", "storage", "pool-errors", entityURL, db.WarningSeverityHigh, fmt.Sprintf("Storage pool %q reported %d errors (%s)", poolName, health.Errors, health.Status))
+	d.events.SendLifecycle("", "storage-pool-errors", fmt.Sprintf("/1.0/storage-pools/%s", poolName), map[string]interface{}{
+		"status": health.Status,
+		"errors": health.Errors,
+	})
+
+	return nil
+}
@@ -7,11 +7,13 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/filter"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
@@ -24,6 +26,7 @@ import (
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
+	"github.com/pkg/errors"
 )
 
 var storagePoolVolumesCmd = APIEndpoint{
@@ -89,6 +92,22 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := util.IsRecursionRequest(r)
 
+	// Parse filter value.
+	var clauses []filter.Clause
+	filterStr := r.FormValue("filter")
+	if filterStr != "" {
+		var err error
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
+	_, _, err := util.Paginate(r, 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Retrieve ID of the storage pool (and check if the storage pool exists).
 	poolID, err := d.cluster.GetStoragePoolID(poolName)
 	if err != nil {
@@ -137,6 +156,22 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Sort the volumes by type and name so that pages are stable.
+	sort.Slice(volumes, func(i, j int) bool {
+		if volumes[i].Type != volumes[j].Type {
+			return volumes[i].Type < volumes[j].Type
+		}
+
+		return volumes[i].Name < volumes[j].Name
+	})
+
+	if clauses != nil {
+		volumes = storagePoolVolumesFilter(volumes, clauses)
+	}
+
+	start, end, _ := util.Paginate(r, len(volumes))
+	volumes = volumes[start:end]
+
 	resultString := []string{}
 	for _, volume := range volumes {
 		apiEndpoint, err := storagePoolVolumeTypeNameToAPIEndpoint(volume.Type)
@@ -191,6 +226,20 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, volumes)
 }
 
+// storagePoolVolumesFilter returns the volumes which match the given filter clauses.
+func storagePoolVolumesFilter(volumes []*api.StorageVolume, clauses []filter.Clause) []*api.StorageVolume {
+	filtered := []*api.StorageVolume{}
+	for _, volume := range volumes {
+		if !filter.Match(*volume, clauses) {
+			continue
+		}
+
+		filtered = append(filtered, volume)
+	}
+
+	return filtered
+}
+
 // /1.0/storage-pools/{name}/volumes/{type}
 // List all storage volumes of a given volume type for a given storage pool.
 func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) response.Response {
@@ -202,6 +251,22 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := util.IsRecursionRequest(r)
 
+	// Parse filter value.
+	var clauses []filter.Clause
+	filterStr := r.FormValue("filter")
+	if filterStr != "" {
+		var err error
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
+	_, _, err := util.Paginate(r, 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Convert the volume type name to our internal integer representation.
 	volumeType, err := storagePools.VolumeTypeNameToType(volumeTypeName)
 	if err != nil {
@@ -230,6 +295,29 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Sort the volumes by name so that pages are stable.
+	sort.Strings(volumes)
+
+	// Filtering requires loading the volumes.
+	if clauses != nil {
+		filtered := []string{}
+		for _, volume := range volumes {
+			_, vol, err := d.cluster.GetLocalStoragePoolVolume(projectName, volume, volumeType, poolID)
+			if err != nil {
+				continue
+			}
+
+			if filter.Match(*vol, clauses) {
+				filtered = append(filtered, volume)
+			}
+		}
+
+		volumes = filtered
+	}
+
+	start, end, _ := util.Paginate(r, len(volumes))
+	volumes = volumes[start:end]
+
 	resultString := []string{}
 	resultMap := []*api.StorageVolume{}
 	for _, volume := range volumes {
@@ -365,6 +453,11 @@ func doVolumeCreateOrCopy(d *Daemon, projectName, poolName string, req *api.Stor
 		return response.SmartError(err)
 	}
 
+	// Only LXD records which instance an ephemeral volume belongs to.
+	if req.Config["volatile.ephemeral.owner"] != "" {
+		return response.BadRequest(fmt.Errorf("The volatile.ephemeral.owner key can't be set on custom volumes"))
+	}
+
 	run = func(op *operations.Operation) error {
 		if req.Source.Name == "" {
 			return pool.CreateCustomVolume(projectName, req.Name, req.Description, req.Config, contentType, op)
@@ -671,6 +764,11 @@ func storagePoolVolumeTypePostMigration(state *state.State, projectName, poolNam
 		return response.InternalError(err)
 	}
 
+	err = ws.setBandwidthLimit(state, "")
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resources := map[string][]string{}
 	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
 
@@ -855,7 +953,7 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request, volumeTypeName string)
 	}
 	volume.UsedBy = volumeUsedBy
 
-	etag := []interface{}{volumeName, volume.Type, volume.Config}
+	etag := []interface{}{volumeName, volume.Type, volume.Description, volume.Config}
 
 	return response.SyncResponseETag(true, volume, etag)
 }
@@ -929,7 +1027,7 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request, volumeTypeName string)
 	}
 
 	// Validate the ETag
-	etag := []interface{}{volumeName, vol.Type, vol.Config}
+	etag := []interface{}{volumeName, vol.Type, vol.Description, vol.Config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -1059,7 +1157,7 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request, volumeTypeName strin
 	}
 
 	// Validate the ETag.
-	etag := []interface{}{volumeName, vol.Type, vol.Config}
+	etag := []interface{}{volumeName, vol.Type, vol.Description, vol.Config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
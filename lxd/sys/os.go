@@ -40,10 +40,9 @@ type OS struct {
 	VarDir   string // Data directory (e.g. /var/lib/lxd/).
 
 	// Daemon environment
-	Architectures   []int           // Cache of detected system architectures
-	BackingFS       string          // Backing filesystem of $LXD_DIR/containers
-	ExecPath        string          // Absolute path to the LXD executable
-	IdmapSet        *idmap.IdmapSet // Information about user/group ID mapping
+	Architectures   []int  // Cache of detected system architectures
+	BackingFS       string // Backing filesystem of $LXD_DIR/containers
+	ExecPath        string // Absolute path to the LXD executable
 	InotifyWatch    InotifyInfo
 	LxcPath         string // Path to the $LXD_DIR/containers directory
 	MockMode        bool   // If true some APIs will be mocked (for testing)
@@ -52,6 +51,10 @@ type OS struct {
 	UnprivUser      string
 	UnprivUID       int
 
+	// Information about user/group ID mapping, replaced as a whole when it changes
+	idmapSet     *idmap.IdmapSet
+	idmapSetLock sync.RWMutex
+
 	// Apparmor features
 	AppArmorAdmin     bool
 	AppArmorAvailable bool
@@ -63,6 +66,7 @@ type OS struct {
 	CGInfo cgroup.Info
 
 	// Kernel features
+	IdmappedMounts          bool
 	NetnsGetifaddrs         bool
 	PidFds                  bool
 	SeccompListener         bool
@@ -118,7 +122,7 @@ func (s *OS) Init() error {
 		break
 	}
 
-	s.IdmapSet = util.GetIdmapSet()
+	s.idmapSet = util.GetIdmapSet()
 	s.ExecPath = util.GetExecPath()
 	s.RunningInUserNS = shared.RunningInUserNS()
 
@@ -127,3 +131,20 @@ func (s *OS) Init() error {
 
 	return nil
 }
+
+// IdmapSet returns the user/group ID mapping of the host. The returned set is shared and mustn't be modified, it
+// gets replaced through SetIdmapSet instead.
+func (s *OS) IdmapSet() *idmap.IdmapSet {
+	s.idmapSetLock.RLock()
+	defer s.idmapSetLock.RUnlock()
+
+	return s.idmapSet
+}
+
+// SetIdmapSet replaces the user/group ID mapping of the host.
+func (s *OS) SetIdmapSet(set *idmap.IdmapSet) {
+	s.idmapSetLock.Lock()
+	defer s.idmapSetLock.Unlock()
+
+	s.idmapSet = set
+}
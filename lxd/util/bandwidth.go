@@ -0,0 +1,124 @@
+package util
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/units"
+)
+
+// BandwidthLimitedWriter wraps an io.Writer, throttling writes to at most Limit bytes per second.
+// A Limit of zero or less disables throttling.
+type BandwidthLimitedWriter struct {
+	io.Writer
+	Limit int64
+
+	// Clock used to spread out the writes, time.Now and time.Sleep if unset.
+	Now   func() time.Time
+	Sleep func(time.Duration)
+
+	start time.Time
+	total int64
+}
+
+// Write in BandwidthLimitedWriter is the same as io.Write, except that it sleeps as needed to keep the
+// average throughput since the first write under the limit.
+func (w *BandwidthLimitedWriter) Write(p []byte) (int, error) {
+	if w.Limit <= 0 {
+		return w.Writer.Write(p)
+	}
+
+	now := w.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	sleep := w.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	if w.start.IsZero() {
+		w.start = now()
+	}
+
+	// Write in chunks of a tenth of the limit so that the stream stays smooth.
+	chunkSize := int(w.Limit / 10)
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	written := 0
+	for written < len(p) {
+		end := written + chunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		n, err := w.Writer.Write(p[written:end])
+		written += n
+		w.total += int64(n)
+		if err != nil {
+			return written, err
+		}
+
+		expected := time.Duration(float64(w.total) / float64(w.Limit) * float64(time.Second))
+		elapsed := now().Sub(w.start)
+		if elapsed < expected {
+			sleep(expected - elapsed)
+		}
+	}
+
+	return written, nil
+}
+
+// BandwidthLimitedReadWriteCloser wraps an io.ReadWriteCloser, throttling writes to at most Limit bytes
+// per second. Reads aren't throttled.
+type BandwidthLimitedReadWriteCloser struct {
+	io.ReadWriteCloser
+
+	writer *BandwidthLimitedWriter
+}
+
+// NewBandwidthLimitedReadWriteCloser returns conn throttled to limit bytes per second, or conn itself if
+// limit is zero or less.
+func NewBandwidthLimitedReadWriteCloser(conn io.ReadWriteCloser, limit int64) io.ReadWriteCloser {
+	if limit <= 0 {
+		return conn
+	}
+
+	return &BandwidthLimitedReadWriteCloser{
+		ReadWriteCloser: conn,
+		writer:          &BandwidthLimitedWriter{Writer: conn, Limit: limit},
+	}
+}
+
+// Write in BandwidthLimitedReadWriteCloser is the same as io.Write but throttled.
+func (c *BandwidthLimitedReadWriteCloser) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+// BandwidthLimit parses the requested and maximum bandwidth limits, expressed in bytes per second with
+// optional suffixes, and returns the lowest of those that are set. Zero is returned if neither is set.
+func BandwidthLimit(requested string, max string) (int64, error) {
+	limit := int64(0)
+
+	for _, value := range []string{requested, max} {
+		if value == "" {
+			continue
+		}
+
+		n, err := units.ParseByteSizeString(value)
+		if err != nil {
+			return -1, errors.Wrapf(err, "Invalid bandwidth limit %q", value)
+		}
+
+		if n > 0 && (limit == 0 || n < limit) {
+			limit = n
+		}
+	}
+
+	return limit, nil
+}
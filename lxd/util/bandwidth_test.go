@@ -0,0 +1,100 @@
+package util_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock records the requested sleeps and advances its time by them.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// Writes are spread out so that the throughput stays under the limit.
+func TestBandwidthLimitedWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	w := &util.BandwidthLimitedWriter{Writer: buf, Limit: 10000, Now: clock.Now, Sleep: clock.Sleep}
+
+	n, err := w.Write(make([]byte, 5000))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5000, n)
+	assert.Equal(t, 5000, buf.Len())
+
+	// Each chunk is a tenth of the limit, so it's followed by a tenth of a second of sleep.
+	expected := []time.Duration{}
+	for i := 0; i < 5; i++ {
+		expected = append(expected, 100*time.Millisecond)
+	}
+
+	assert.Equal(t, expected, clock.sleeps)
+}
+
+// Time already spent writing is deducted from the sleeps, across writes.
+func TestBandwidthLimitedWriter_SlowWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	w := &util.BandwidthLimitedWriter{Writer: buf, Limit: 10000, Now: clock.Now, Sleep: clock.Sleep}
+
+	_, err := w.Write(make([]byte, 1000))
+	require.NoError(t, err)
+
+	clock.now = clock.now.Add(150 * time.Millisecond)
+	_, err = w.Write(make([]byte, 2000))
+	require.NoError(t, err)
+
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 50 * time.Millisecond}, clock.sleeps)
+}
+
+// A zero limit doesn't throttle anything.
+func TestBandwidthLimitedWriter_Unlimited(t *testing.T) {
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	w := &util.BandwidthLimitedWriter{Writer: buf, Now: clock.Now, Sleep: clock.Sleep}
+
+	n, err := w.Write(make([]byte, 1024*1024))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1024*1024, n)
+	assert.Empty(t, clock.sleeps)
+}
+
+// The lowest of the requested and maximum limits wins.
+func TestBandwidthLimit(t *testing.T) {
+	cases := []struct {
+		requested string
+		max       string
+		limit     int64
+	}{
+		{"", "", 0},
+		{"1MB", "", 1000000},
+		{"", "1MB", 1000000},
+		{"1MB", "2MB", 1000000},
+		{"2MB", "1MB", 1000000},
+	}
+
+	for _, c := range cases {
+		limit, err := util.BandwidthLimit(c.requested, c.max)
+		require.NoError(t, err)
+		assert.Equal(t, c.limit, limit)
+	}
+
+	_, err := util.BandwidthLimit("fast", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// binfmtHandler describes how binfmt_misc recognizes the ELF binaries of an architecture and which qemu-user
+// binary runs them.
+type binfmtHandler struct {
+	magic       string
+	mask        string
+	interpreter string
+}
+
+// binfmtHandlers are the architectures which can be emulated through qemu-user, with the ELF magic and mask
+// used by qemu-binfmt-conf.sh.
+var binfmtHandlers = map[int]binfmtHandler{
+	osarch.ARCH_32BIT_INTEL_X86: {
+		magic:       `\x7fELF\x01\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x03\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xfe\xfe\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff`,
+		interpreter: "qemu-i386-static",
+	},
+	osarch.ARCH_64BIT_INTEL_X86: {
+		magic:       `\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xfe\xfe\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff`,
+		interpreter: "qemu-x86_64-static",
+	},
+	osarch.ARCH_32BIT_ARMV7_LITTLE_ENDIAN: {
+		magic:       `\x7fELF\x01\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x28\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff`,
+		interpreter: "qemu-arm-static",
+	},
+	osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN: {
+		magic:       `\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xb7\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff`,
+		interpreter: "qemu-aarch64-static",
+	},
+	osarch.ARCH_64BIT_POWERPC_LITTLE_ENDIAN: {
+		magic:       `\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x15\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\x00`,
+		interpreter: "qemu-ppc64le-static",
+	},
+	osarch.ARCH_64BIT_S390_BIG_ENDIAN: {
+		magic:       `\x7fELF\x02\x02\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x16`,
+		mask:        `\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff`,
+		interpreter: "qemu-s390x-static",
+	},
+	osarch.ARCH_64BIT_RISCV_LITTLE_ENDIAN: {
+		magic:       `\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xf3\x00`,
+		mask:        `\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff`,
+		interpreter: "qemu-riscv64-static",
+	},
+}
+
+// BinfmtArchitectures parses a comma separated list of architectures to emulate through binfmt_misc.
+func BinfmtArchitectures(value string) ([]int, error) {
+	archs := []int{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		arch, err := osarch.ArchitectureId(name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := binfmtHandlers[arch]
+		if !ok {
+			return nil, fmt.Errorf("Emulation of architecture %q isn't supported", name)
+		}
+
+		archs = append(archs, arch)
+	}
+
+	return archs, nil
+}
+
+// BinfmtInterpreter returns the path of the qemu-user static binary emulating the given architecture.
+func BinfmtInterpreter(arch int) (string, error) {
+	handler, ok := binfmtHandlers[arch]
+	if !ok {
+		return "", fmt.Errorf("Emulation of architecture %d isn't supported", arch)
+	}
+
+	path, err := exec.LookPath(handler.interpreter)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't find %q, qemu-user static binaries are needed for emulation: %v", handler.interpreter, err)
+	}
+
+	return path, nil
+}
+
+// BinfmtRegistration returns the binfmt_misc registration of the given architecture using the interpreter at
+// path. The interpreter is opened on registration ("F" flag) so that it doesn't need to exist in the instance.
+func BinfmtRegistration(arch int, path string) (string, error) {
+	handler, ok := binfmtHandlers[arch]
+	if !ok {
+		return "", fmt.Errorf("Emulation of architecture %d isn't supported", arch)
+	}
+
+	name, err := osarch.ArchitectureName(arch)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(":lxd-qemu-%s:M::%s:%s:%s:F", name, handler.magic, handler.mask, path), nil
+}
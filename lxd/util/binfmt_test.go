@@ -0,0 +1,26 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Architecture aliases are accepted and empty entries are ignored.
+func TestBinfmtArchitectures(t *testing.T) {
+	archs, err := util.BinfmtArchitectures("arm64, armhf,")
+	require.NoError(t, err)
+	assert.Equal(t, []int{osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN, osarch.ARCH_32BIT_ARMV7_LITTLE_ENDIAN}, archs)
+
+	_, err = util.BinfmtArchitectures("mips")
+	assert.EqualError(t, err, `Emulation of architecture "mips" isn't supported`)
+}
+
+func TestBinfmtRegistration(t *testing.T) {
+	registration, err := util.BinfmtRegistration(osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN, "/usr/bin/qemu-aarch64-static")
+	require.NoError(t, err)
+	assert.Equal(t, `:lxd-qemu-aarch64:M::\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xb7\x00:\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff:/usr/bin/qemu-aarch64-static:F`, registration)
+}
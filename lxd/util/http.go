@@ -59,25 +59,32 @@ func EtagHash(data interface{}) (string, error) {
 }
 
 // EtagCheck validates the hash of the current state with the hash
-// provided by the client
+// provided by the client. The If-Match header may hold a comma separated
+// list of (possibly weak) ETags, or "*" to match any state.
 func EtagCheck(r *http.Request, data interface{}) error {
 	match := r.Header.Get("If-Match")
 	if match == "" {
 		return nil
 	}
 
-	match = strings.Trim(match, "\"")
-
 	hash, err := EtagHash(data)
 	if err != nil {
 		return err
 	}
 
-	if hash != match {
-		return fmt.Errorf("ETag doesn't match: %s vs %s", hash, match)
+	for _, entry := range strings.Split(match, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "*" {
+			return nil
+		}
+
+		entry = strings.Trim(strings.TrimPrefix(entry, "W/"), "\"")
+		if entry == hash {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("ETag doesn't match: %s vs %s", hash, match)
 }
 
 // HTTPClient returns an http.Client using the given certificate and proxy.
@@ -191,6 +198,41 @@ func IsRecursionRequest(r *http.Request) bool {
 	return recursion != 0
 }
 
+// Paginate returns the start and end indexes of the page of a collection of the given size which was requested
+// with the "offset" and "limit" form values. All the entries are returned when neither is set.
+func Paginate(r *http.Request, count int) (int, int, error) {
+	start := 0
+	end := count
+
+	offsetStr := r.FormValue("offset")
+	if offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return -1, -1, fmt.Errorf("Invalid offset %q", offsetStr)
+		}
+
+		if offset < count {
+			start = offset
+		} else {
+			start = count
+		}
+	}
+
+	limitStr := r.FormValue("limit")
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return -1, -1, fmt.Errorf("Invalid limit %q", limitStr)
+		}
+
+		if start+limit < count {
+			end = start + limit
+		}
+	}
+
+	return start, end, nil
+}
+
 // ListenAddresses returns a list of host:port combinations at which
 // this machine can be reached
 func ListenAddresses(value string) ([]string, error) {
@@ -0,0 +1,79 @@
+package util_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	cases := []struct {
+		query string
+		start int
+		end   int
+	}{
+		{"", 0, 10},
+		{"limit=3", 0, 3},
+		{"offset=4", 4, 10},
+		{"offset=4&limit=3", 4, 7},
+		{"offset=8&limit=5", 8, 10},
+		{"offset=12&limit=5", 10, 10},
+		{"limit=0", 0, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/1.0/instances?"+c.query, nil)
+			start, end, err := util.Paginate(r, 10)
+			require.NoError(t, err)
+			assert.Equal(t, c.start, start)
+			assert.Equal(t, c.end, end)
+		})
+	}
+}
+
+func TestPaginate_Error(t *testing.T) {
+	for _, query := range []string{"limit=-1", "limit=foo", "offset=-1", "offset=foo"} {
+		t.Run(query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/1.0/instances?"+query, nil)
+			_, _, err := util.Paginate(r, 10)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEtagCheck(t *testing.T) {
+	data := []interface{}{"foo", map[string]string{"a": "b"}}
+	hash, err := util.EtagHash(data)
+	require.NoError(t, err)
+
+	cases := map[string]bool{
+		"":                        true,
+		"*":                       true,
+		hash:                      true,
+		`"` + hash + `"`:          true,
+		`W/"` + hash + `"`:        true,
+		`"other", "` + hash + `"`: true,
+		"other":                   false,
+		`"other", W/"unrelated"`:  false,
+	}
+
+	for match, ok := range cases {
+		t.Run(match, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/1.0/profiles/default", nil)
+			if match != "" {
+				r.Header.Set("If-Match", match)
+			}
+
+			err := util.EtagCheck(r, data)
+			if ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
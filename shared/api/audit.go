@@ -0,0 +1,28 @@
+package api
+
+import (
+	"time"
+)
+
+// AuditEntry represents a mutating API request recorded in the audit log
+//
+// API extension: audit_log
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Location  string    `json:"location" yaml:"location"`
+
+	// Identity of the caller
+	Username string `json:"username" yaml:"username"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Address  string `json:"address" yaml:"address"`
+
+	// Request
+	Method  string `json:"method" yaml:"method"`
+	URL     string `json:"url" yaml:"url"`
+	Project string `json:"project" yaml:"project"`
+	Request string `json:"request" yaml:"request"`
+
+	// Result
+	StatusCode int    `json:"status_code" yaml:"status_code"`
+	Error      string `json:"error" yaml:"error"`
+}
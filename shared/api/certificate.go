@@ -1,11 +1,39 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
 // CertificatesPost represents the fields of a new LXD certificate
 type CertificatesPost struct {
 	CertificatePut `yaml:",inline"`
 
 	Certificate string `json:"certificate" yaml:"certificate"`
 	Password    string `json:"password" yaml:"password"`
+
+	// API extension: certificate_token
+	Token bool `json:"token" yaml:"token"`
+}
+
+// CertificateAddToken represents the fields contained within an encoded certificate add token.
+//
+// API extension: certificate_token
+type CertificateAddToken struct {
+	ClientName  string   `json:"client_name" yaml:"client_name"`
+	Secret      string   `json:"secret" yaml:"secret"`
+	Fingerprint string   `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string `json:"addresses" yaml:"addresses"`
+}
+
+// String encodes the certificate add token as JSON and then base64.
+func (t *CertificateAddToken) String() string {
+	tokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(tokenJSON)
 }
 
 // CertificatePut represents the modifiable fields of a LXD certificate
@@ -14,6 +42,14 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// Roles of the certificate in each project it's restricted to ("viewer", "operator" or "admin")
+	// API extension: certificate_project_roles
+	Projects map[string]string `json:"projects" yaml:"projects"`
+
+	// Whether the certificate is restricted to its projects, it has full access otherwise
+	// API extension: certificate_project
+	Restricted bool `json:"restricted" yaml:"restricted"`
 }
 
 // Certificate represents a LXD certificate
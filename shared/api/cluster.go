@@ -1,5 +1,10 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
 // Cluster represents high-level information about a LXD cluster.
 //
 // API extension: clustering
@@ -39,6 +44,36 @@ type ClusterPut struct {
 	// API extension: clustering_join
 	ServerAddress   string `json:"server_address" yaml:"server_address"`
 	ClusterPassword string `json:"cluster_password" yaml:"cluster_password"`
+
+	// API extension: clustering_join_token
+	ClusterToken string `json:"cluster_token" yaml:"cluster_token"`
+}
+
+// ClusterMembersPost represents the fields required to request a join token for a new cluster member.
+//
+// API extension: clustering_join_token
+type ClusterMembersPost struct {
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// ClusterMemberJoinToken represents the fields contained within an encoded cluster member join token.
+//
+// API extension: clustering_join_token
+type ClusterMemberJoinToken struct {
+	ServerName  string   `json:"server_name" yaml:"server_name"`
+	Secret      string   `json:"secret" yaml:"secret"`
+	Fingerprint string   `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string `json:"addresses" yaml:"addresses"`
+}
+
+// String encodes the cluster member join token as JSON and then base64.
+func (t *ClusterMemberJoinToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
 }
 
 // ClusterMemberPost represents the fields required to rename a LXD node.
@@ -48,6 +83,35 @@ type ClusterMemberPost struct {
 	ServerName string `json:"server_name" yaml:"server_name"`
 }
 
+// ClusterMemberStatePost represents the fields required to evacuate or restore a cluster member.
+//
+// API extension: clustering_evacuation
+type ClusterMemberStatePost struct {
+	Action string `json:"action" yaml:"action"`
+}
+
+// ClusterUpgrade represents the progress of a rolling upgrade of the cluster.
+//
+// API extension: clustering_upgrade
+type ClusterUpgrade struct {
+	// Whether some members run a different version than others
+	InProgress bool `json:"in_progress" yaml:"in_progress"`
+
+	Members []ClusterMemberVersion `json:"members" yaml:"members"`
+}
+
+// ClusterMemberVersion represents the database schema and API extensions versions of a cluster member.
+//
+// API extension: clustering_upgrade
+type ClusterMemberVersion struct {
+	ServerName    string `json:"server_name" yaml:"server_name"`
+	Schema        int    `json:"schema" yaml:"schema"`
+	APIExtensions int    `json:"api_extensions" yaml:"api_extensions"`
+
+	// Either "Upgraded" or "Pending"
+	Status string `json:"status" yaml:"status"`
+}
+
 // ClusterMember represents the a LXD node in the cluster.
 //
 // API extension: clustering
@@ -59,6 +123,9 @@ type ClusterMember struct {
 	Database   bool   `json:"database" yaml:"database"`
 	Status     string `json:"status" yaml:"status"`
 	Message    string `json:"message" yaml:"message"`
+
+	// API extension: cluster_groups
+	Groups []string `json:"groups" yaml:"groups"`
 }
 
 // Writable converts a full Profile struct into a ProfilePut struct (filters read-only fields)
@@ -78,4 +145,11 @@ type ClusterMemberPut struct {
 
 	// API extension: clustering_failure_domains
 	FailureDomain string `json:"failure_domain" yaml:"failure_domain"`
+
+	// Server configuration keys specific to the member and user.* labels
+	// API extension: clustering_member_config
+	Config map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
+
+	// API extension: clustering_description
+	Description string `json:"description" yaml:"description"`
 }
@@ -0,0 +1,32 @@
+package api
+
+// ClusterGroupsPost represents the fields of a new LXD cluster group
+//
+// API extension: cluster_groups
+type ClusterGroupsPost struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// ClusterGroupPut represents the modifiable fields of a LXD cluster group
+//
+// API extension: cluster_groups
+type ClusterGroupPut struct {
+	Description string   `json:"description" yaml:"description"`
+	Members     []string `json:"members" yaml:"members"`
+}
+
+// ClusterGroup represents a LXD cluster group
+//
+// API extension: cluster_groups
+type ClusterGroup struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full ClusterGroup struct into a ClusterGroupPut struct (filters read-only fields)
+func (group *ClusterGroup) Writable() ClusterGroupPut {
+	return group.ClusterGroupPut
+}
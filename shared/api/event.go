@@ -13,6 +13,10 @@ type Event struct {
 
 	// API extension: event_location
 	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+
+	// Project the event belongs to, set when listening to all projects
+	// API extension: certificate_project
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
 }
 
 // EventLogging represents a logging type event entry (admin only)
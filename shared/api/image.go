@@ -25,6 +25,9 @@ type ImagesPost struct {
 
 	// API extension: image_create_aliases
 	Aliases []ImageAlias `json:"aliases" yaml:"aliases"`
+
+	// API extension: image_publish_diff
+	Diff bool `json:"diff" yaml:"diff"`
 }
 
 // ImagesPostSource represents the source of a new LXD image
@@ -43,6 +46,10 @@ type ImagesPostSource struct {
 	// For type "image"
 	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
 	Secret      string `json:"secret" yaml:"secret"`
+
+	// For type "oci", how the entrypoint of the image is run ("wrapper" or "config")
+	// API extension: image_import_oci
+	Entrypoint string `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
 }
 
 // ImagePut represents the modifiable fields of a LXD image
@@ -56,6 +63,12 @@ type ImagePut struct {
 
 	// API extension: image_profiles
 	Profiles []string `json:"profiles" yaml:"profiles"`
+
+	// API extension: image_alias_auto_update
+	Pinned bool `json:"pinned" yaml:"pinned"`
+
+	// API extension: image_annotations
+	Annotations map[string]string `json:"annotations" yaml:"annotations"`
 }
 
 // Image represents a LXD image
@@ -76,6 +89,9 @@ type Image struct {
 	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
 	LastUsedAt time.Time `json:"last_used_at" yaml:"last_used_at"`
 	UploadedAt time.Time `json:"uploaded_at" yaml:"uploaded_at"`
+
+	// API extension: image_publish_diff
+	BaseImage string `json:"base_image,omitempty" yaml:"base_image,omitempty"`
 }
 
 // Writable converts a full Image struct into a ImagePut struct (filters read-only fields)
@@ -114,6 +130,15 @@ type ImageAliasesEntryPost struct {
 type ImageAliasesEntryPut struct {
 	Description string `json:"description" yaml:"description"`
 	Target      string `json:"target" yaml:"target"`
+
+	// API extension: image_alias_auto_update
+	AutoUpdate   bool         `json:"auto_update" yaml:"auto_update"`
+	UpdateSource *ImageSource `json:"update_source,omitempty" yaml:"update_source,omitempty"`
+
+	// Fingerprint of the image to use for each architecture, the target being used for its own
+	// architecture and for the architectures not listed
+	// API extension: image_alias_architectures
+	Architectures map[string]string `json:"architectures,omitempty" yaml:"architectures,omitempty"`
 }
 
 // ImageAliasesEntry represents a LXD image alias
@@ -133,6 +158,9 @@ type ImageMetadata struct {
 	ExpiryDate   int64                             `json:"expiry_date" yaml:"expiry_date"`
 	Properties   map[string]string                 `json:"properties" yaml:"properties"`
 	Templates    map[string]*ImageMetadataTemplate `json:"templates" yaml:"templates"`
+
+	// API extension: image_publish_diff
+	BaseImage string `json:"base_image,omitempty" yaml:"base_image,omitempty"`
 }
 
 // ImageMetadataTemplate represents a template entry in image metadata
@@ -28,6 +28,16 @@ type InstancesPost struct {
 	Type         InstanceType   `json:"type" yaml:"type"`
 }
 
+// InstancesPut represents the fields available for a bulk state change of LXD instances.
+//
+// API extension: instance_bulk_state_change
+type InstancesPut struct {
+	State *InstanceStatePut `json:"state" yaml:"state"`
+
+	// Names of the instances to act on, all the instances of the project if empty.
+	Instances []string `json:"instances" yaml:"instances"`
+}
+
 // InstancePost represents the fields required to rename/move a LXD instance.
 //
 // API extension: instances
@@ -38,6 +48,15 @@ type InstancePost struct {
 	InstanceOnly  bool                `json:"instance_only" yaml:"instance_only"`
 	ContainerOnly bool                `json:"container_only" yaml:"container_only"` // Deprecated, use InstanceOnly.
 	Target        *InstancePostTarget `json:"target" yaml:"target"`
+
+	// API extension: instance_pool_move
+	Pool string `json:"pool" yaml:"pool"`
+
+	// API extension: migration_bandwidth_limit
+	BandwidthLimit string `json:"bandwidth_limit" yaml:"bandwidth_limit"`
+
+	// API extension: instance_project_move
+	Project string `json:"project" yaml:"project"`
 }
 
 // InstancePostTarget represents the migration target host and operation.
@@ -134,4 +153,7 @@ type InstanceSource struct {
 	ContainerOnly bool              `json:"container_only,omitempty" yaml:"container_only,omitempty"` // Deprecated, use InstanceOnly.
 	Refresh       bool              `json:"refresh,omitempty" yaml:"refresh,omitempty"`
 	Project       string            `json:"project,omitempty" yaml:"project,omitempty"`
+
+	// API extension: migration_resume
+	Resumable bool `json:"resumable,omitempty" yaml:"resumable,omitempty"`
 }
@@ -14,6 +14,15 @@ type InstanceBackupsPost struct {
 
 	// API extension: backup_compression_algorithm
 	CompressionAlgorithm string `json:"compression_algorithm" yaml:"compression_algorithm"`
+
+	// API extension: backup_delta
+	DeltaFrom string `json:"delta_from" yaml:"delta_from"`
+
+	// API extension: backup_s3
+	Target string `json:"target" yaml:"target"`
+
+	// API extension: migration_bandwidth_limit
+	BandwidthLimit string `json:"bandwidth_limit" yaml:"bandwidth_limit"`
 }
 
 // InstanceBackup represents a LXD instance backup.
@@ -22,6 +22,21 @@ type InstanceState struct {
 	Pid        int64                           `json:"pid" yaml:"pid"`
 	Processes  int64                           `json:"processes" yaml:"processes"`
 	CPU        InstanceStateCPU                `json:"cpu" yaml:"cpu"`
+
+	// API extension: proxy_metrics
+	Proxies map[string]InstanceStateProxy `json:"proxies" yaml:"proxies"`
+
+	// API extension: instance_state_gpu
+	GPU map[string]InstanceStateGPU `json:"gpu" yaml:"gpu"`
+}
+
+// InstanceStateGPU represents the usage of a GPU card, keyed by its PCI address, by the processes of a LXD
+// instance.
+//
+// API extension: instance_state_gpu
+type InstanceStateGPU struct {
+	Processes   int64 `json:"processes" yaml:"processes"`
+	MemoryUsage int64 `json:"memory_usage" yaml:"memory_usage"`
 }
 
 // InstanceStateDisk represents the disk information section of a LXD instance's state.
@@ -81,4 +96,20 @@ type InstanceStateNetworkCounters struct {
 	BytesSent       int64 `json:"bytes_sent" yaml:"bytes_sent"`
 	PacketsReceived int64 `json:"packets_received" yaml:"packets_received"`
 	PacketsSent     int64 `json:"packets_sent" yaml:"packets_sent"`
+
+	// API extension: network_metrics
+	ErrorsReceived         int64 `json:"errors_received" yaml:"errors_received"`
+	ErrorsSent             int64 `json:"errors_sent" yaml:"errors_sent"`
+	PacketsDroppedInbound  int64 `json:"packets_dropped_inbound" yaml:"packets_dropped_inbound"`
+	PacketsDroppedOutbound int64 `json:"packets_dropped_outbound" yaml:"packets_dropped_outbound"`
+}
+
+// InstanceStateProxy represents the connection counters of a proxy device in a LXD instance's state.
+//
+// API extension: proxy_metrics
+type InstanceStateProxy struct {
+	ConnectionsTotal  int64 `json:"connections_total" yaml:"connections_total"`
+	ConnectionsActive int64 `json:"connections_active" yaml:"connections_active"`
+	BytesReceived     int64 `json:"bytes_received" yaml:"bytes_received"`
+	BytesSent         int64 `json:"bytes_sent" yaml:"bytes_sent"`
 }
@@ -61,6 +61,15 @@ type NetworkLease struct {
 	Location string `json:"location" yaml:"location"`
 }
 
+// NetworkLeasesPost represents the fields of a new DHCP lease reservation
+//
+// API extension: network_lease_reservations
+type NetworkLeasesPost struct {
+	Hostname string `json:"hostname" yaml:"hostname"`
+	Hwaddr   string `json:"hwaddr" yaml:"hwaddr"`
+	Address  string `json:"address" yaml:"address"`
+}
+
 // NetworkState represents the network state
 type NetworkState struct {
 	Addresses []NetworkStateAddress `json:"addresses" yaml:"addresses"`
@@ -89,6 +98,12 @@ type NetworkStateCounters struct {
 	BytesSent       int64 `json:"bytes_sent" yaml:"bytes_sent"`
 	PacketsReceived int64 `json:"packets_received" yaml:"packets_received"`
 	PacketsSent     int64 `json:"packets_sent" yaml:"packets_sent"`
+
+	// API extension: network_metrics
+	ErrorsReceived         int64 `json:"errors_received" yaml:"errors_received"`
+	ErrorsSent             int64 `json:"errors_sent" yaml:"errors_sent"`
+	PacketsDroppedInbound  int64 `json:"packets_dropped_inbound" yaml:"packets_dropped_inbound"`
+	PacketsDroppedOutbound int64 `json:"packets_dropped_outbound" yaml:"packets_dropped_outbound"`
 }
 
 // NetworkStateBond represents bond specific state
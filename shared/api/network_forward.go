@@ -0,0 +1,54 @@
+package api
+
+// NetworkForwardPort represents a port specification in a network address forward.
+//
+// API extension: network_forward
+type NetworkForwardPort struct {
+	Description string `json:"description" yaml:"description"`
+
+	// Protocol of the forwarded traffic ("tcp" or "udp").
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Comma separated list of ports and port ranges to listen on.
+	ListenPort string `json:"listen_port" yaml:"listen_port"`
+
+	// Comma separated list of ports and port ranges to forward to (defaults to the listen ports).
+	TargetPort string `json:"target_port" yaml:"target_port"`
+
+	TargetAddress string `json:"target_address" yaml:"target_address"`
+}
+
+// NetworkForwardPut represents the modifiable fields of a LXD network address forward
+//
+// API extension: network_forward
+type NetworkForwardPut struct {
+	Description string               `json:"description" yaml:"description"`
+	Config      map[string]string    `json:"config" yaml:"config"`
+	Ports       []NetworkForwardPort `json:"ports" yaml:"ports"`
+}
+
+// NetworkForwardsPost represents the fields of a new LXD network address forward
+//
+// API extension: network_forward
+type NetworkForwardsPost struct {
+	NetworkForwardPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// NetworkForward represents a LXD network address forward
+//
+// API extension: network_forward
+type NetworkForward struct {
+	NetworkForwardPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+
+	// Cluster member the forward is applied on.
+	Location string `json:"location" yaml:"location"`
+}
+
+// Writable converts a full NetworkForward struct into a NetworkForwardPut struct (filters read-only fields)
+func (f *NetworkForward) Writable() NetworkForwardPut {
+	return f.NetworkForwardPut
+}
@@ -0,0 +1,83 @@
+package api
+
+// NetworkLoadBalancerBackend represents a backend of a network load balancer.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerBackend struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+
+	TargetAddress string `json:"target_address" yaml:"target_address"`
+
+	// Port to send the traffic to (defaults to the listen port).
+	TargetPort string `json:"target_port" yaml:"target_port"`
+}
+
+// NetworkLoadBalancerPort represents a port specification in a network load balancer.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPort struct {
+	Description string `json:"description" yaml:"description"`
+
+	// Protocol of the balanced traffic ("tcp" or "udp").
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Comma separated list of ports and port ranges to listen on.
+	ListenPort string `json:"listen_port" yaml:"listen_port"`
+
+	// Names of the backends to spread the traffic over.
+	TargetBackend []string `json:"target_backend" yaml:"target_backend"`
+}
+
+// NetworkLoadBalancerPut represents the modifiable fields of a LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPut struct {
+	Description string                       `json:"description" yaml:"description"`
+	Config      map[string]string            `json:"config" yaml:"config"`
+	Backends    []NetworkLoadBalancerBackend `json:"backends" yaml:"backends"`
+	Ports       []NetworkLoadBalancerPort    `json:"ports" yaml:"ports"`
+}
+
+// NetworkLoadBalancersPost represents the fields of a new LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancersPost struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// NetworkLoadBalancer represents a LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancer struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+
+	// Cluster member the load balancer is applied on.
+	Location string `json:"location" yaml:"location"`
+}
+
+// Writable converts a full NetworkLoadBalancer struct into a NetworkLoadBalancerPut struct (filters read-only fields)
+func (lb *NetworkLoadBalancer) Writable() NetworkLoadBalancerPut {
+	return lb.NetworkLoadBalancerPut
+}
+
+// NetworkLoadBalancerState represents the runtime state of a LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerState struct {
+	BackendHealth map[string]NetworkLoadBalancerStateBackendHealth `json:"backend_health" yaml:"backend_health"`
+}
+
+// NetworkLoadBalancerStateBackendHealth represents the health of a network load balancer backend
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerStateBackendHealth struct {
+	Address string `json:"address" yaml:"address"`
+
+	// One of "online", "offline" or "unknown" (health checks disabled or not possible).
+	Status string `json:"status" yaml:"status"`
+}
@@ -0,0 +1,34 @@
+package api
+
+// NetworkZonesPost represents the fields of a new LXD network zone
+//
+// API extension: network_dns
+type NetworkZonesPost struct {
+	NetworkZonePut `yaml:",inline"`
+
+	// The name of the zone (DNS domain name).
+	Name string `json:"name" yaml:"name"`
+}
+
+// NetworkZonePut represents the modifiable fields of a LXD network zone
+//
+// API extension: network_dns
+type NetworkZonePut struct {
+	Description string            `json:"description" yaml:"description"`
+	Config      map[string]string `json:"config" yaml:"config"`
+}
+
+// NetworkZone represents a LXD network zone
+//
+// API extension: network_dns
+type NetworkZone struct {
+	NetworkZonePut `yaml:",inline"`
+
+	Name   string   `json:"name" yaml:"name"`
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// Writable converts a full NetworkZone struct into a NetworkZonePut struct (filters read-only fields)
+func (zone *NetworkZone) Writable() NetworkZonePut {
+	return zone.NetworkZonePut
+}
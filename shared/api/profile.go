@@ -17,6 +17,9 @@ type ProfilePut struct {
 	Config      map[string]string            `json:"config" yaml:"config"`
 	Description string                       `json:"description" yaml:"description"`
 	Devices     map[string]map[string]string `json:"devices" yaml:"devices"`
+
+	// API extension: profile_parents
+	Parents []string `json:"parents" yaml:"parents"`
 }
 
 // Profile represents a LXD profile
@@ -27,6 +30,27 @@ type Profile struct {
 
 	// API extension: profile_usedby
 	UsedBy []string `json:"used_by" yaml:"used_by"`
+
+	// API extension: profile_parents
+	Expansion []string `json:"expansion,omitempty" yaml:"expansion,omitempty"`
+}
+
+// ProfileInstanceImpact represents the effect of a profile update on an instance using the profile
+//
+// API extension: profile_update_dry_run
+type ProfileInstanceImpact struct {
+	Name     string `json:"name" yaml:"name"`
+	Project  string `json:"project" yaml:"project"`
+	Location string `json:"location" yaml:"location"`
+
+	// Whether the expanded config or devices of the instance change
+	Changed bool `json:"changed" yaml:"changed"`
+
+	// Validation error of the new expanded config and devices, if any
+	Error string `json:"error" yaml:"error"`
+
+	ExpandedConfig  map[string]string            `json:"expanded_config" yaml:"expanded_config"`
+	ExpandedDevices map[string]map[string]string `json:"expanded_devices" yaml:"expanded_devices"`
 }
 
 // Writable converts a full Profile struct into a ProfilePut struct (filters read-only fields)
@@ -7,6 +7,20 @@ type ProjectsPost struct {
 	ProjectPut `yaml:",inline"`
 
 	Name string `json:"name" yaml:"name"`
+
+	// API extension: projects_default_profile_source
+	DefaultProfileSource *ProjectProfileSource `json:"default_profile_source,omitempty" yaml:"default_profile_source,omitempty"`
+}
+
+// ProjectProfileSource represents the profile whose content seeds the default profile of a new LXD project
+//
+// API extension: projects_default_profile_source
+type ProjectProfileSource struct {
+	// Project of the source profile, "default" if empty
+	Project string `json:"project" yaml:"project"`
+
+	// Name of the source profile, "default" if empty
+	Profile string `json:"profile" yaml:"profile"`
 }
 
 // ProjectPost represents the fields required to rename a LXD project
@@ -40,3 +54,22 @@ type Project struct {
 func (project *Project) Writable() ProjectPut {
 	return project.ProjectPut
 }
+
+// ProjectState represents the current running state of a LXD project
+//
+// API extension: project_usage
+type ProjectState struct {
+	// Allocated and used resources, keyed by resource name
+	Resources map[string]ProjectStateResource `json:"resources" yaml:"resources"`
+}
+
+// ProjectStateResource represents the state of a particular resource in a LXD project
+//
+// API extension: project_usage
+type ProjectStateResource struct {
+	// Limit for the resource, -1 if none is set
+	Limit int64 `json:"limit" yaml:"limit"`
+
+	// Current usage of the resource
+	Usage int64 `json:"usage" yaml:"usage"`
+}
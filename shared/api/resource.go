@@ -29,6 +29,9 @@ type ResourcesCPU struct {
 
 	Sockets []ResourcesCPUSocket `json:"sockets" yaml:"sockets"`
 	Total   uint64               `json:"total" yaml:"total"`
+
+	// API extension: instances_placement_scheduler
+	LoadAverage []float64 `json:"load_average,omitempty" yaml:"load_average,omitempty"`
 }
 
 // ResourcesCPUSocket represents a CPU socket on the system
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+)
+
+// WarningPut represents the modifiable fields of a LXD warning
+//
+// API extension: warnings
+type WarningPut struct {
+	Status string `json:"status" yaml:"status"`
+}
+
+// Warning represents a problem raised by a LXD subsystem
+//
+// API extension: warnings
+type Warning struct {
+	WarningPut `yaml:",inline"`
+
+	UUID     string `json:"uuid" yaml:"uuid"`
+	Location string `json:"location" yaml:"location"`
+	Project  string `json:"project" yaml:"project"`
+
+	// Origin and nature of the problem
+	Module    string `json:"module" yaml:"module"`
+	Type      string `json:"type" yaml:"type"`
+	EntityURL string `json:"entity_url" yaml:"entity_url"`
+	Severity  string `json:"severity" yaml:"severity"`
+
+	// Occurrences of the problem
+	Count       int       `json:"count" yaml:"count"`
+	FirstSeenAt time.Time `json:"first_seen_at" yaml:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" yaml:"last_seen_at"`
+	LastMessage string    `json:"last_message" yaml:"last_message"`
+}
+
+// Writable converts a full Warning struct into a WarningPut struct (filters read-only fields)
+func (warning *Warning) Writable() WarningPut {
+	return warning.WarningPut
+}
@@ -0,0 +1,38 @@
+package cancel
+
+import (
+	"context"
+	"io"
+)
+
+// Reader is a wrapper around io.Reader which fails once its context is cancelled
+type Reader struct {
+	io.Reader
+	Context context.Context
+}
+
+// Read in Reader is the same as io.Read unless the context was cancelled
+func (r *Reader) Read(p []byte) (int, error) {
+	err := r.Context.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Reader.Read(p)
+}
+
+// Writer is a wrapper around io.Writer which fails once its context is cancelled
+type Writer struct {
+	io.Writer
+	Context context.Context
+}
+
+// Write in Writer is the same as io.Write unless the context was cancelled
+func (w *Writer) Write(p []byte) (int, error) {
+	err := w.Context.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Writer.Write(p)
+}
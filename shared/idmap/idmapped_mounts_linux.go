@@ -0,0 +1,119 @@
+// +build linux,cgo
+
+package idmap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// SupportsIdmappedMounts returns whether the running kernel supports idmapped mounts (Linux 5.12 and later).
+// Support for idmapped mounts also depends on the filesystem, which is checked by CanIdmapMount.
+func SupportsIdmappedMounts() bool {
+	// The call fails with EBADF rather than ENOSYS when the system call exists.
+	err := unix.MountSetattr(-1, "", 0, &unix.MountAttr{})
+	return err != unix.ENOSYS
+}
+
+// CanIdmapMount returns whether the filesystem mounted at path supports idmapped mounts. This depends on the
+// filesystem and its version (ZFS only supports them from 2.2), so it's found out by idmapping a detached copy
+// of the mount with the user namespace of a short lived process.
+func CanIdmapMount(path string) bool {
+	cmd := exec.Command("cat")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 1000000, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 1000000, Size: 1}},
+	}
+
+	// The process lives until its input is closed.
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return false
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		stdin.Close()
+		return false
+	}
+
+	defer cmd.Wait()
+	defer stdin.Close()
+
+	userns, err := os.Open(fmt.Sprintf("/proc/%d/ns/user", cmd.Process.Pid))
+	if err != nil {
+		return false
+	}
+	defer userns.Close()
+
+	treeFd, err := unix.OpenTree(unix.AT_FDCWD, path, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC)
+	if err != nil {
+		return false
+	}
+	defer unix.Close(treeFd)
+
+	attr := unix.MountAttr{
+		Attr_set:  unix.MOUNT_ATTR_IDMAP,
+		Userns_fd: uint64(userns.Fd()),
+	}
+
+	return unix.MountSetattr(treeFd, "", unix.AT_EMPTY_PATH, &attr) == nil
+}
+
+// IdmapMount replaces the mount at path (and the mounts below it) with an idmapped mount whose ownership is
+// shifted according to the user namespace found at usernsPath, e.g. "/proc/<pid>/ns/user".
+func IdmapMount(path string, usernsPath string) error {
+	userns, err := os.Open(usernsPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening user namespace %q", usernsPath)
+	}
+	defer userns.Close()
+
+	// Clone the mount tree so it can be idmapped while detached.
+	treeFd, err := unix.OpenTree(unix.AT_FDCWD, path, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+	if err != nil {
+		return errors.Wrapf(err, "Failed cloning mount %q", path)
+	}
+	defer unix.Close(treeFd)
+
+	attr := unix.MountAttr{
+		Attr_set:  unix.MOUNT_ATTR_IDMAP,
+		Userns_fd: uint64(userns.Fd()),
+	}
+
+	err = unix.MountSetattr(treeFd, "", unix.AT_EMPTY_PATH|unix.AT_RECURSIVE, &attr)
+	if err != nil {
+		return errors.Wrapf(err, "Failed idmapping mount %q", path)
+	}
+
+	// Replace the original mount with the idmapped one.
+	err = unix.Unmount(path, unix.MNT_DETACH)
+	if err != nil {
+		return errors.Wrapf(err, "Failed unmounting %q", path)
+	}
+
+	err = unix.MoveMount(treeFd, "", unix.AT_FDCWD, path, unix.MOVE_MOUNT_F_EMPTY_PATH)
+	if err != nil {
+		return errors.Wrapf(err, "Failed attaching idmapped mount to %q", path)
+	}
+
+	return nil
+}
+
+// IdmapStorageType represents how the ownership of a mount is shifted for an unprivileged container.
+type IdmapStorageType string
+
+// IdmapStorageNone indicates the ownership of the mount isn't shifted.
+const IdmapStorageNone = IdmapStorageType("none")
+
+// IdmapStorageShiftfs indicates the ownership of the mount is shifted with shiftfs.
+const IdmapStorageShiftfs = IdmapStorageType("shiftfs")
+
+// IdmapStorageIdmapped indicates the ownership of the mount is shifted with an idmapped mount.
+const IdmapStorageIdmapped = IdmapStorageType("idmapped")
@@ -130,6 +130,25 @@ func IsSize(value string) error {
 	return nil
 }
 
+// IsCronSchedule checks if string is a valid shortened cron expression
+// (<minute> <hour> <day-of-month> <month> <day-of-week>).
+func IsCronSchedule(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if len(strings.Split(value, " ")) != 5 {
+		return fmt.Errorf("Schedule must be of the form: <minute> <hour> <day-of-month> <month> <day-of-week>")
+	}
+
+	_, err := cron.Parse(fmt.Sprintf("* %s", value))
+	if err != nil {
+		return errors.Wrap(err, "Error parsing schedule")
+	}
+
+	return nil
+}
+
 // IsDeviceID validates string is four lowercase hex characters suitable as Vendor or Device ID.
 func IsDeviceID(value string) error {
 	if value == "" {
@@ -381,6 +400,22 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"boot.stop.priority":         IsInt64,
 	"boot.host_shutdown_timeout": IsInt64,
 
+	"cluster.evacuate": func(value string) error {
+		return IsOneOf(value, []string{"auto", "migrate", "stateful-migrate", "stop"})
+	},
+
+	"backups.schedule":         IsCronSchedule,
+	"backups.schedule.stopped": IsBool,
+	"backups.optimized":        IsBool,
+	"backups.target": func(value string) error {
+		return IsOneOf(value, []string{"s3"})
+	},
+	"backups.expiry": func(value string) error {
+		// Validate expression
+		_, err := GetSnapshotExpiry(time.Time{}, value)
+		return err
+	},
+
 	"limits.cpu": func(value string) error {
 		if value == "" {
 			return nil
@@ -478,7 +513,11 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 
 	"limits.processes": IsInt64,
 
-	"linux.kernel_modules": IsAny,
+	"linux.binfmt_misc.emulate": IsAny,
+	"linux.kernel_modules":      IsAny,
+	"linux.kernel_modules.load": func(value string) error {
+		return IsOneOf(value, []string{"boot", "ondemand"})
+	},
 
 	"migration.incremental.memory":            IsBool,
 	"migration.incremental.memory.iterations": IsUint32,
@@ -489,6 +528,11 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"nvidia.require.cuda":        IsAny,
 	"nvidia.require.driver":      IsAny,
 
+	"oci.entrypoint": IsAny,
+	"oci.cwd":        IsAny,
+	"oci.uid":        IsUint32,
+	"oci.gid":        IsUint32,
+
 	"security.nesting":       IsBool,
 	"security.privileged":    IsBool,
 	"security.devlxd":        IsBool,
@@ -510,6 +554,7 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"security.syscalls.deny_default":            IsBool,
 	"security.syscalls.deny_compat":             IsBool,
 	"security.syscalls.deny":                    IsAny,
+	"security.syscalls.intercept.audit":         IsBool,
 	"security.syscalls.intercept.mknod":         IsBool,
 	"security.syscalls.intercept.mount":         IsBool,
 	"security.syscalls.intercept.mount.allowed": IsAny,
@@ -518,22 +563,7 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"security.syscalls.intercept.setxattr":      IsBool,
 	"security.syscalls.whitelist":               IsAny,
 
-	"snapshots.schedule": func(value string) error {
-		if value == "" {
-			return nil
-		}
-
-		if len(strings.Split(value, " ")) != 5 {
-			return fmt.Errorf("Schedule must be of the form: <minute> <hour> <day-of-month> <month> <day-of-week>")
-		}
-
-		_, err := cron.Parse(fmt.Sprintf("* %s", value))
-		if err != nil {
-			return errors.Wrap(err, "Error parsing schedule")
-		}
-
-		return nil
-	},
+	"snapshots.schedule":         IsCronSchedule,
 	"snapshots.schedule.stopped": IsBool,
 	"snapshots.pattern":          IsAny,
 	"snapshots.expiry": func(value string) error {
@@ -557,6 +587,7 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"volatile.idmap.current":    IsAny,
 	"volatile.idmap.next":       IsAny,
 	"volatile.apply_quota":      IsAny,
+	"volatile.evacuate.origin":  IsAny,
 }
 
 // ConfigKeyChecker returns a function that will check whether or not
@@ -618,6 +649,14 @@ func ConfigKeyChecker(key string) (func(value string) error, error) {
 		if strings.HasSuffix(key, ".driver") {
 			return IsAny, nil
 		}
+
+		if strings.HasSuffix(key, ".gpu.slice") {
+			return IsAny, nil
+		}
+
+		if strings.HasSuffix(key, ".guid") {
+			return IsAny, nil
+		}
 	}
 
 	if strings.HasPrefix(key, "environment.") {
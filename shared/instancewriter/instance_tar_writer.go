@@ -2,6 +2,8 @@ package instancewriter
 
 import (
 	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"os"
 	"strings"
@@ -17,6 +19,7 @@ type InstanceTarWriter struct {
 	tarWriter *tar.Writer
 	idmapSet  *idmap.IdmapSet
 	linkMap   map[uint64]string
+	checksums map[string]string
 }
 
 // NewInstanceTarWriter returns a ContainerTarWriter for the provided target Writer and id map.
@@ -28,6 +31,14 @@ func NewInstanceTarWriter(writer io.Writer, idmapSet *idmap.IdmapSet) *InstanceT
 	return ctw
 }
 
+// EnableChecksums makes the writer record the SHA256 checksum of the content of the regular files written from
+// then on, as returned by Checksums. Hashing is skipped otherwise.
+func (ctw *InstanceTarWriter) EnableChecksums() {
+	if ctw.checksums == nil {
+		ctw.checksums = map[string]string{}
+	}
+}
+
 // ResetHardLinkMap resets the hard link map. Use when copying multiple instances (or snapshots) into a tarball.
 // So that the hard link map doesn't work across different instances/snapshots.
 func (ctw *InstanceTarWriter) ResetHardLinkMap() {
@@ -123,7 +134,7 @@ func (ctw *InstanceTarWriter) WriteFile(name string, srcPath string, fi os.FileI
 			r = io.LimitReader(r, fi.Size())
 		}
 
-		_, err = io.Copy(ctw.tarWriter, r)
+		err = ctw.copyContent(hdr.Name, r)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to copy file content %q", srcPath)
 		}
@@ -145,8 +156,36 @@ func (ctw *InstanceTarWriter) WriteFileFromReader(src io.Reader, fi os.FileInfo)
 		return errors.Wrap(err, "Failed to write tar header")
 	}
 
-	_, err = io.Copy(ctw.tarWriter, src)
-	return err
+	return ctw.copyContent(hdr.Name, src)
+}
+
+// copyContent writes the content of a file to the tarball, recording its SHA256 checksum if enabled.
+func (ctw *InstanceTarWriter) copyContent(name string, src io.Reader) error {
+	if ctw.checksums == nil {
+		_, err := io.Copy(ctw.tarWriter, src)
+		return err
+	}
+
+	hash := sha256.New()
+
+	_, err := io.Copy(io.MultiWriter(ctw.tarWriter, hash), src)
+	if err != nil {
+		return err
+	}
+
+	ctw.checksums[name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Checksums returns the SHA256 checksums of the content of the regular files written since EnableChecksums was
+// called, keyed by name.
+func (ctw *InstanceTarWriter) Checksums() map[string]string {
+	checksums := make(map[string]string, len(ctw.checksums))
+	for name, checksum := range ctw.checksums {
+		checksums[name] = checksum
+	}
+
+	return checksums
 }
 
 // Close finishes writing the tarball.
@@ -13,4 +13,7 @@ type ProgressData struct {
 
 	// Total number of bytes (for files)
 	TotalBytes int64
+
+	// Transfer speed in bytes per second
+	Speed int64
 }
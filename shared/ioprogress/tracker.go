@@ -15,6 +15,11 @@ type ProgressTracker struct {
 	last       *time.Time
 }
 
+// Transferred returns the number of bytes tracked so far
+func (pt *ProgressTracker) Transferred() int64 {
+	return pt.total
+}
+
 func (pt *ProgressTracker) update(n int) {
 	// Skip the rest if no handler attached
 	if pt.Handler == nil {
@@ -502,50 +502,58 @@ func AllocatePort() (int, error) {
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
+// NetworkGetCounters returns the counters of the given interface from /proc/net/dev.
 func NetworkGetCounters(ifName string) api.NetworkStateCounters {
-	counters := api.NetworkStateCounters{}
-	// Get counters
-	content, err := ioutil.ReadFile("/proc/net/dev")
-	if err == nil {
-		for _, line := range strings.Split(string(content), "\n") {
-			fields := strings.Fields(line)
+	allCounters, err := NetworkGetAllCounters()
+	if err != nil {
+		return api.NetworkStateCounters{}
+	}
 
-			if len(fields) != 17 {
-				continue
-			}
+	return allCounters[ifName]
+}
 
-			intName := strings.TrimSuffix(fields[0], ":")
-			if intName != ifName {
-				continue
-			}
+// NetworkGetAllCounters returns the counters of all the interfaces of the host, keyed by interface name. They are
+// all read from a single pass over /proc/net/dev.
+func NetworkGetAllCounters() (map[string]api.NetworkStateCounters, error) {
+	content, err := ioutil.ReadFile("/proc/net/dev")
+	if err != nil {
+		return nil, err
+	}
 
-			rxBytes, err := strconv.ParseInt(fields[1], 10, 64)
-			if err != nil {
-				continue
-			}
+	allCounters := map[string]api.NetworkStateCounters{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
 
-			rxPackets, err := strconv.ParseInt(fields[2], 10, 64)
-			if err != nil {
-				continue
-			}
+		if len(fields) != 17 {
+			continue
+		}
 
-			txBytes, err := strconv.ParseInt(fields[9], 10, 64)
+		// Receive fields are bytes, packets, errs and drop then the same for transmit from the 9th field.
+		values := make([]int64, len(fields))
+		valid := true
+		for _, i := range []int{1, 2, 3, 4, 9, 10, 11, 12} {
+			values[i], err = strconv.ParseInt(fields[i], 10, 64)
 			if err != nil {
-				continue
+				valid = false
+				break
 			}
+		}
 
-			txPackets, err := strconv.ParseInt(fields[10], 10, 64)
-			if err != nil {
-				continue
-			}
+		if !valid {
+			continue
+		}
 
-			counters.BytesSent = txBytes
-			counters.BytesReceived = rxBytes
-			counters.PacketsSent = txPackets
-			counters.PacketsReceived = rxPackets
-			break
+		allCounters[strings.TrimSuffix(fields[0], ":")] = api.NetworkStateCounters{
+			BytesReceived:          values[1],
+			PacketsReceived:        values[2],
+			ErrorsReceived:         values[3],
+			PacketsDroppedInbound:  values[4],
+			BytesSent:              values[9],
+			PacketsSent:            values[10],
+			ErrorsSent:             values[11],
+			PacketsDroppedOutbound: values[12],
 		}
 	}
 
-	return counters
+	return allCounters, nil
 }
@@ -22,6 +22,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/flosch/pongo2"
@@ -991,23 +992,37 @@ func EscapePathFstab(path string) string {
 	return r.Replace(path)
 }
 
-func SetProgressMetadata(metadata map[string]interface{}, stage, displayPrefix string, percent, processed, speed int64) {
+// ProgressMetadata returns the structured progress of an operation stage, as sent to API callers in the
+// "progress" key of the operation metadata. Unknown values are left out.
+func ProgressMetadata(stage string, percent, processed, total, speed int64) map[string]string {
 	progress := make(map[string]string)
-	// stage, percent, speed sent for API callers.
 	progress["stage"] = stage
 	if processed > 0 {
 		progress["processed"] = strconv.FormatInt(processed, 10)
 	}
 
+	if total > 0 {
+		progress["total"] = strconv.FormatInt(total, 10)
+	}
+
 	if percent > 0 {
 		progress["percent"] = strconv.FormatInt(percent, 10)
 	}
 
 	progress["speed"] = strconv.FormatInt(speed, 10)
-	metadata["progress"] = progress
+
+	return progress
+}
+
+// SetProgressMetadata records the progress of an operation stage in its metadata.
+func SetProgressMetadata(metadata map[string]interface{}, stage, displayPrefix string, percent, processed, total, speed int64) {
+	// stage, percent, processed, total and speed sent for API callers.
+	metadata["progress"] = ProgressMetadata(stage, percent, processed, total, speed)
 
 	// <stage>_progress with formatted text sent for lxc cli.
-	if percent > 0 {
+	if percent > 0 && processed > 0 && total > 0 {
+		metadata[stage+"_progress"] = fmt.Sprintf("%s: %d%% (%s/%s, %s/s)", displayPrefix, percent, units.GetByteSizeString(processed, 2), units.GetByteSizeString(total, 2), units.GetByteSizeString(speed, 2))
+	} else if percent > 0 {
 		metadata[stage+"_progress"] = fmt.Sprintf("%s: %d%% (%s/s)", displayPrefix, percent, units.GetByteSizeString(speed, 2))
 	} else if processed > 0 {
 		metadata[stage+"_progress"] = fmt.Sprintf("%s: %s (%s/s)", displayPrefix, units.GetByteSizeString(processed, 2), units.GetByteSizeString(speed, 2))
@@ -1016,11 +1031,177 @@ func SetProgressMetadata(metadata map[string]interface{}, stage, displayPrefix s
 	}
 }
 
+// Tunables of DownloadFileHash, variables so that tests can lower them.
+var (
+	// downloadChunkSize is the size of the ranges fetched in parallel, files no larger than it being
+	// downloaded as a single stream.
+	downloadChunkSize int64 = 32 * 1024 * 1024
+
+	// downloadChunkWorkers is the number of ranges fetched concurrently.
+	downloadChunkWorkers = 4
+
+	// downloadRetries is the number of times an interrupted transfer is resumed before giving up.
+	downloadRetries = 5
+)
+
+// DownloadFileHash downloads the file at the given URL into target, checking its hash if hashFunc is set.
+//
+// When the server supports range requests, files larger than a chunk are fetched as parallel chunks if the
+// target is also an io.WriterAt and io.ReaderAt (such as an *os.File), and interrupted transfers are resumed
+// where they stopped rather than restarted.
 func DownloadFileHash(httpClient *http.Client, useragent string, progress func(progress ioprogress.ProgressData), canceler *cancel.Canceler, filename string, url string, hash string, hashFunc hash.Hash, target io.WriteSeeker) (int64, error) {
 	// Always seek to the beginning
 	target.Seek(0, 0)
 
-	// Prepare the download request
+	tracker := &downloadTracker{filename: filename, progress: progress}
+
+	chunkTarget, ok := target.(downloadChunkTarget)
+	if ok {
+		length, ranges, err := downloadProbe(httpClient, useragent, canceler, url)
+		if err == nil && ranges && length > downloadChunkSize {
+			tracker.length = length
+			tracker.start()
+			defer tracker.stop()
+
+			return downloadFileChunks(httpClient, useragent, canceler, url, hash, hashFunc, chunkTarget, length, tracker)
+		}
+	}
+
+	tracker.start()
+	defer tracker.stop()
+
+	return downloadFileStream(httpClient, useragent, canceler, url, hash, hashFunc, target, tracker)
+}
+
+// downloadChunkTarget is a download target which chunks can be written to in any order, and read back from
+// to check the hash of the file.
+type downloadChunkTarget interface {
+	io.WriterAt
+	io.ReaderAt
+}
+
+// downloadTracker reports the progress of a download once per second.
+type downloadTracker struct {
+	filename    string
+	progress    func(progress ioprogress.ProgressData)
+	length      int64
+	transferred int64
+	done        chan struct{}
+	lock        sync.Mutex
+}
+
+// add records that n more bytes got transferred. A negative count undoes transfers which are restarted.
+func (t *downloadTracker) add(n int64) {
+	t.lock.Lock()
+	t.transferred += n
+	t.lock.Unlock()
+}
+
+func (t *downloadTracker) start() {
+	if t.progress == nil {
+		return
+	}
+
+	t.done = make(chan struct{})
+	startedAt := time.Now()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-ticker.C:
+			}
+
+			t.lock.Lock()
+			transferred := t.transferred
+			length := t.length
+			t.lock.Unlock()
+
+			speed := int64(float64(transferred) / time.Since(startedAt).Seconds())
+			data := ioprogress.ProgressData{
+				TransferredBytes: transferred,
+				TotalBytes:       length,
+				Speed:            speed,
+			}
+
+			if length > 0 {
+				data.Percentage = int(transferred * 100 / length)
+				data.Text = fmt.Sprintf("%d%% (%s/s)", data.Percentage, units.GetByteSizeString(speed, 2))
+			} else {
+				data.Text = fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(transferred, 2), units.GetByteSizeString(speed, 2))
+			}
+
+			if t.filename != "" {
+				data.Text = fmt.Sprintf("%s: %s", t.filename, data.Text)
+			}
+
+			t.progress(data)
+		}
+	}()
+}
+
+func (t *downloadTracker) stop() {
+	if t.done != nil {
+		close(t.done)
+	}
+}
+
+// downloadWriter writes a download into its target from the given offset, recording progress as it goes.
+type downloadWriter struct {
+	target  io.WriterAt
+	offset  int64
+	tracker *downloadTracker
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	n, err := w.target.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	w.tracker.add(int64(n))
+
+	return n, err
+}
+
+// downloadCanceled returns whether the error comes from the download being canceled.
+func downloadCanceled(err error) bool {
+	return strings.HasSuffix(err.Error(), "net/http: request canceled")
+}
+
+// downloadProbe returns the length of the file at the given URL and whether the server supports range
+// requests for it.
+func downloadProbe(httpClient *http.Client, useragent string, canceler *cancel.Canceler, url string) (int64, bool, error) {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return -1, false, err
+	}
+
+	if useragent != "" {
+		req.Header.Set("User-Agent", useragent)
+	}
+
+	r, doneCh, err := cancel.CancelableDownload(canceler, httpClient, req)
+	if err != nil {
+		return -1, false, err
+	}
+	defer r.Body.Close()
+	defer close(doneCh)
+
+	if r.StatusCode != http.StatusOK {
+		return -1, false, fmt.Errorf("Unable to fetch %s: %s", url, r.Status)
+	}
+
+	return r.ContentLength, r.Header.Get("Accept-Ranges") == "bytes", nil
+}
+
+// errDownloadNoRange is returned when the server answers a range request with the whole file.
+var errDownloadNoRange = fmt.Errorf("Server doesn't support range requests")
+
+// downloadRange copies the bytes of the file at the given URL from offset start up to end (inclusive, or
+// the end of the file if negative) into w. It returns the length of the content being sent by the server.
+func downloadRange(httpClient *http.Client, useragent string, canceler *cancel.Canceler, url string, start int64, end int64, w io.Writer) (int64, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return -1, err
@@ -1030,7 +1211,15 @@ func DownloadFileHash(httpClient *http.Client, useragent string, progress func(p
 		req.Header.Set("User-Agent", useragent)
 	}
 
-	// Perform the request
+	ranged := start > 0 || end >= 0
+	if ranged {
+		if end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+		}
+	}
+
 	r, doneCh, err := cancel.CancelableDownload(canceler, httpClient, req)
 	if err != nil {
 		return -1, err
@@ -1038,48 +1227,185 @@ func DownloadFileHash(httpClient *http.Client, useragent string, progress func(p
 	defer r.Body.Close()
 	defer close(doneCh)
 
-	if r.StatusCode != http.StatusOK {
+	if ranged && r.StatusCode == http.StatusOK {
+		return -1, errDownloadNoRange
+	}
+
+	if (ranged && r.StatusCode != http.StatusPartialContent) || (!ranged && r.StatusCode != http.StatusOK) {
 		return -1, fmt.Errorf("Unable to fetch %s: %s", url, r.Status)
 	}
 
-	// Handle the data
-	body := r.Body
-	if progress != nil {
-		body = &ioprogress.ProgressReader{
-			ReadCloser: r.Body,
-			Tracker: &ioprogress.ProgressTracker{
-				Length: r.ContentLength,
-				Handler: func(percent int64, speed int64) {
-					if filename != "" {
-						progress(ioprogress.ProgressData{Text: fmt.Sprintf("%s: %d%% (%s/s)", filename, percent, units.GetByteSizeString(speed, 2))})
-					} else {
-						progress(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
-					}
-				},
-			},
-		}
+	n, err := io.Copy(w, r.Body)
+	if err != nil {
+		return r.ContentLength, err
 	}
 
-	var size int64
+	if r.ContentLength >= 0 && n != r.ContentLength {
+		return r.ContentLength, io.ErrUnexpectedEOF
+	}
+
+	return r.ContentLength, nil
+}
 
+// downloadFileStream downloads the file as a single stream, resuming it from where it stopped on failure.
+func downloadFileStream(httpClient *http.Client, useragent string, canceler *cancel.Canceler, url string, hash string, hashFunc hash.Hash, target io.WriteSeeker, tracker *downloadTracker) (int64, error) {
+	var written int64
+	counter := &downloadCounter{written: &written, tracker: tracker}
+
+	w := io.MultiWriter(target, counter)
 	if hashFunc != nil {
-		size, err = io.Copy(io.MultiWriter(target, hashFunc), body)
-		if err != nil {
+		w = io.MultiWriter(target, hashFunc, counter)
+	}
+
+	var err error
+	for attempt := 0; attempt <= downloadRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		var length int64
+		start := written
+		length, err = downloadRange(httpClient, useragent, canceler, url, start, -1, w)
+		if length >= 0 && attempt == 0 {
+			tracker.lock.Lock()
+			tracker.length = length
+			tracker.lock.Unlock()
+		}
+
+		if err == nil {
+			break
+		}
+
+		if downloadCanceled(err) {
 			return -1, err
 		}
 
+		// Start over if the server can't resume the transfer.
+		if err == errDownloadNoRange {
+			tracker.add(-written)
+			written = 0
+
+			_, err = target.Seek(0, 0)
+			if err != nil {
+				return -1, err
+			}
+
+			if hashFunc != nil {
+				hashFunc.Reset()
+			}
+		}
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	if hashFunc != nil {
 		result := fmt.Sprintf("%x", hashFunc.Sum(nil))
 		if result != hash {
 			return -1, fmt.Errorf("Hash mismatch for %s: %s != %s", url, result, hash)
 		}
-	} else {
-		size, err = io.Copy(target, body)
+	}
+
+	return written, nil
+}
+
+// downloadCounter counts the bytes written by a download stream.
+type downloadCounter struct {
+	written *int64
+	tracker *downloadTracker
+}
+
+func (c *downloadCounter) Write(p []byte) (int, error) {
+	*c.written += int64(len(p))
+	c.tracker.add(int64(len(p)))
+
+	return len(p), nil
+}
+
+// downloadFileChunks downloads the file as parallel chunks, each being resumed from where it stopped on failure.
+func downloadFileChunks(httpClient *http.Client, useragent string, canceler *cancel.Canceler, url string, hash string, hashFunc hash.Hash, target downloadChunkTarget, length int64, tracker *downloadTracker) (int64, error) {
+	offsets := make(chan int64, (length+downloadChunkSize-1)/downloadChunkSize)
+	for offset := int64(0); offset < length; offset += downloadChunkSize {
+		offsets <- offset
+	}
+	close(offsets)
+
+	var chunkErr error
+	var chunkErrLock sync.Mutex
+	failed := func() bool {
+		chunkErrLock.Lock()
+		defer chunkErrLock.Unlock()
+
+		return chunkErr != nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < downloadChunkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for offset := range offsets {
+				if failed() {
+					return
+				}
+
+				end := offset + downloadChunkSize - 1
+				if end >= length {
+					end = length - 1
+				}
+
+				err := downloadChunk(httpClient, useragent, canceler, url, offset, end, target, tracker)
+				if err != nil {
+					chunkErrLock.Lock()
+					if chunkErr == nil {
+						chunkErr = err
+					}
+					chunkErrLock.Unlock()
+
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if chunkErr != nil {
+		return -1, chunkErr
+	}
+
+	if hashFunc != nil {
+		_, err := io.Copy(hashFunc, io.NewSectionReader(target, 0, length))
 		if err != nil {
 			return -1, err
 		}
+
+		result := fmt.Sprintf("%x", hashFunc.Sum(nil))
+		if result != hash {
+			return -1, fmt.Errorf("Hash mismatch for %s: %s != %s", url, result, hash)
+		}
+	}
+
+	return length, nil
+}
+
+// downloadChunk downloads the given range of the file into the target, resuming from where it stopped on failure.
+func downloadChunk(httpClient *http.Client, useragent string, canceler *cancel.Canceler, url string, start int64, end int64, target io.WriterAt, tracker *downloadTracker) error {
+	w := &downloadWriter{target: target, offset: start, tracker: tracker}
+
+	var err error
+	for attempt := 0; attempt <= downloadRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		_, err = downloadRange(httpClient, useragent, canceler, url, w.offset, end, w)
+		if err == nil || err == errDownloadNoRange || downloadCanceled(err) {
+			return err
+		}
 	}
 
-	return size, nil
+	return err
 }
 
 func ParseNumberFromFile(file string) (int64, error) {
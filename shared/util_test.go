@@ -3,10 +3,14 @@ package shared
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -174,3 +178,96 @@ func TestGetSnapshotExpiry(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, time.Time{}, expiryDate)
 }
+
+func TestSetProgressMetadata(t *testing.T) {
+	metadata := map[string]interface{}{}
+	SetProgressMetadata(metadata, "download", "Download", 50, 512*1024, 1024*1024, 1024)
+	assert.Equal(t, map[string]string{
+		"stage":     "download",
+		"percent":   "50",
+		"processed": "524288",
+		"total":     "1048576",
+		"speed":     "1024",
+	}, metadata["progress"])
+	assert.Equal(t, "Download: 50% (524.29kB/1.05MB, 1.02kB/s)", metadata["download_progress"])
+
+	metadata = map[string]interface{}{}
+	SetProgressMetadata(metadata, "create_backup", "Backup", 0, 2048, 0, 1024)
+	assert.Equal(t, map[string]string{
+		"stage":     "create_backup",
+		"processed": "2048",
+		"speed":     "1024",
+	}, metadata["progress"])
+	assert.Equal(t, "Backup: 2.05kB (1.02kB/s)", metadata["create_backup_progress"])
+}
+
+// truncatedResponseWriter fails writes past the given number of bytes, simulating a dropped connection.
+type truncatedResponseWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (w *truncatedResponseWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		p = p[:w.remaining]
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.remaining -= n
+	if err == nil && w.remaining == 0 {
+		err = fmt.Errorf("Connection dropped")
+	}
+
+	return n, err
+}
+
+func TestDownloadFileHash(t *testing.T) {
+	content := make([]byte, 10000)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	hash := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	cases := []struct {
+		name      string
+		chunkSize int64
+		truncate  bool
+	}{
+		{"stream", 32 * 1024 * 1024, false},
+		{"stream resumed", 32 * 1024 * 1024, true},
+		{"chunks", 1024, false},
+		{"chunks resumed", 1024, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func(size int64) { downloadChunkSize = size }(downloadChunkSize)
+			downloadChunkSize = c.chunkSize
+
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					if atomic.AddInt32(&requests, 1) == 1 && c.truncate {
+						w = &truncatedResponseWriter{ResponseWriter: w, remaining: 500}
+					}
+				}
+
+				http.ServeContent(w, r, "image", time.Now(), bytes.NewReader(content))
+			}))
+			defer server.Close()
+
+			target, err := ioutil.TempFile("", "lxd_download_")
+			require.NoError(t, err)
+			defer os.Remove(target.Name())
+			defer target.Close()
+
+			size, err := DownloadFileHash(http.DefaultClient, "", nil, nil, "", server.URL, hash, sha256.New(), target)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), size)
+
+			result, err := ioutil.ReadFile(target.Name())
+			require.NoError(t, err)
+			assert.Equal(t, content, result)
+		})
+	}
+}
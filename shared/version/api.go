@@ -219,6 +219,96 @@ var APIExtensions = []string{
 	"clustering_failure_domains",
 	"resources_gpu_mdev",
 	"console_vga_type",
+	"instance_pool_move",
+	"storage_volume_shared_attach",
+	"storage_pool_scrub",
+	"disk_ephemeral",
+	"storage_discard",
+	"compression_zstd",
+	"backup_delta",
+	"backup_s3",
+	"backup_schedule",
+	"backup_verify",
+	"migration_resume",
+	"migration_bandwidth_limit",
+	"network_type_ovn",
+	"network_acl",
+	"network_forward",
+	"network_load_balancer",
+	"network_dns",
+	"nic_limits",
+	"nic_routed_live_addresses",
+	"network_lease_reservations",
+	"network_bridge_vlan",
+	"network_ipv6_prefix_delegation",
+	"nic_p2p_filtering",
+	"nic_queues_offload",
+	"nic_sriov_vf_reservations",
+	"network_bridge_wireguard_mesh",
+	"network_bgp",
+	"network_metrics",
+	"network_dhcp_builtin",
+	"proxy_metrics",
+	"instance_nic_bond",
+	"network_type_physical",
+	"projects_limits_networks",
+	"network_bridge_multicast",
+	"cluster_groups",
+	"instances_placement_scheduler",
+	"clustering_evacuation",
+	"clustering_join_token",
+	"clustering_healing",
+	"clustering_database_roles",
+	"clustering_heartbeat_interval",
+	"clustering_member_config",
+	"clustering_upgrade",
+	"clustering_description",
+	"certificate_project_roles",
+	"certificate_project",
+	"certificate_token",
+	"audit_log",
+	"metrics",
+	"api_filter_pagination",
+	"event_filtering",
+	"warnings",
+	"operation_progress",
+	"instance_bulk_state_change",
+	"console_vnc_type",
+	"exec_recording_websocket",
+	"devlxd_devices_cloud_init",
+	"unix_socket_groups",
+	"instance_operation_wait_change",
+	"etag_everywhere",
+	"projects_limits_disk",
+	"projects_restricted_networks_access",
+	"projects_networks",
+	"projects_instances_naming",
+	"project_usage",
+	"instance_project_move",
+	"projects_default_profile_source",
+	"projects_force_delete",
+	"image_alias_auto_update",
+	"image_delta_transfer",
+	"images_simplestreams",
+	"image_publish_diff",
+	"images_cache_size",
+	"image_import_oci",
+	"image_alias_architectures",
+	"image_annotations",
+	"image_import_conversion",
+	"image_export_format",
+	"profile_parents",
+	"profile_update_dry_run",
+	"projects_user_schema",
+	"gpu_slices",
+	"instance_state_gpu",
+	"infiniband_sriov_guid",
+	"unix_udev_match",
+	"idmapped_mounts",
+	"instances_idmap_allocator",
+	"container_syscall_intercept_audit",
+	"container_kernel_modules_load",
+	"container_binfmt_misc_emulation",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
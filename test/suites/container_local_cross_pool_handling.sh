@@ -94,6 +94,19 @@ test_container_local_cross_pool_handling() {
         lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-${driver}1" container/c2/snap0
         lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-${driver}1" container/c2/snap1
         lxc delete -f c2
+
+        # Move an instance to another pool keeping its name and snapshots.
+        lxc init testimage c1
+        lxc snapshot c1
+        created_at="$(lxc query /1.0/instances/c1 | jq -r .created_at)"
+        lxc move c1 -s "lxdtest-$(basename "${LXD_DIR}")-${driver}1"
+        [ "$(lxc query /1.0/instances/c1 | jq -r .created_at)" = "${created_at}" ]
+        lxc config device get c1 root pool | grep -q "lxdtest-$(basename "${LXD_DIR}")-${driver}1"
+        lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-${driver}1" container/c1
+        lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-${driver}1" container/c1/snap0
+        lxc start c1
+        ! lxc move c1 -s "lxdtest-$(basename "${LXD_DIR}")-dir" || false
+        lxc delete -f c1
       fi
     done
   )
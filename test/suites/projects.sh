@@ -161,6 +161,12 @@ test_projects_copy() {
   lxc --project bar stop c1 -f
   lxc --project bar move c1 c1 --target-project foo
   lxc --project foo start c1
+
+  # Moving onto a name already used in the target project fails
+  lxc --project foo stop c1 -f
+  lxc --project bar init testimage c1
+  ! lxc --project foo move c1 c1 --target-project bar || false
+  lxc --project bar delete c1
   lxc --project foo delete c1 -f
 
   # Clean things up